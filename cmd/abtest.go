@@ -0,0 +1,205 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxCampaignVariants is the maximum number of variants in a campaign's A/B test.
+	maxCampaignVariants = 10
+
+	// maxABWaitHours is the maximum time to wait after sending an A/B test to pick a winner.
+	maxABWaitHours = 24 * 30
+)
+
+// handleGetCampaignABTest returns the A/B test settings of a campaign and its
+// variants with the stats of their test sends.
+func handleGetCampaignABTest(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := getCampaignABTest(id, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateCampaignABTest replaces the A/B test settings and variants of a campaign.
+func handleUpdateCampaignABTest(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var t models.CampaignABTest
+	if err := c.Bind(&t); err != nil {
+		return err
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	// The test can only be changed before the campaign starts.
+	if camp.ABPhase != "" || (camp.Status != models.CampaignStatusDraft && camp.Status != models.CampaignStatusScheduled) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.abTestStarted"))
+	}
+
+	if err := validateCampaignABTest(&t, app); err != nil {
+		return err
+	}
+
+	// Runs of recurring campaigns are started on their own.
+	if camp.Recurrence != "" && t.Percent > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.recurringUnsupported"))
+	}
+
+	if camp.STOWindowHours > 0 && t.Percent > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.stoUnsupported"))
+	}
+
+	if camp.SendAtLocal && t.Percent > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.sendAtLocalUnsupported"))
+	}
+
+	if _, err := app.core.SetCampaignABTest(id, t); err != nil {
+		return err
+	}
+
+	out, err := getCampaignABTest(id, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handlePickCampaignABWinner picks a variant as the winner of a campaign's A/B
+// test ahead of the automatic selection.
+func handlePickCampaignABWinner(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req struct {
+		VariantID int `json:"variant_id"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+	if req.VariantID < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "variant_id"))
+	}
+
+	if err := app.core.SetCampaignABWinner(id, req.VariantID); err != nil {
+		return err
+	}
+
+	out, err := getCampaignABTest(id, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// getCampaignABTest returns the A/B test of a campaign.
+func getCampaignABTest(id int, app *App) (models.CampaignABTest, error) {
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return models.CampaignABTest{}, err
+	}
+
+	vars, err := app.core.GetCampaignVariants(id)
+	if err != nil {
+		return models.CampaignABTest{}, err
+	}
+
+	return models.CampaignABTest{
+		Percent:   camp.ABTestPercent,
+		Metric:    camp.ABMetric,
+		WaitHours: camp.ABWaitHours,
+		Phase:     camp.ABPhase,
+		PhaseAt:   camp.ABPhaseAt,
+		Variants:  vars,
+	}, nil
+}
+
+// pickABTestWinners picks the winners of the A/B tests whose wait time is up,
+// which resumes sending the campaigns to the rest of their subscribers.
+func pickABTestWinners(app *App) {
+	ids, err := app.core.GetABTestDueCampaigns()
+	if err != nil {
+		return
+	}
+
+	for _, id := range ids {
+		v, err := app.core.PickABTestWinner(id)
+		if err != nil {
+			app.log.Printf("error picking A/B test winner of campaign %d: %v", id, err)
+			continue
+		}
+
+		app.log.Printf("picked variant '%s' as the A/B test winner of campaign %d", v.Name, id)
+	}
+}
+
+// validateCampaignABTest validates and cleans up a campaign's A/B test.
+func validateCampaignABTest(t *models.CampaignABTest, app *App) error {
+	if t.Percent < 0 || t.Percent > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ab_test_percent"))
+	}
+
+	if t.Metric != models.CampaignABMetricOpens && t.Metric != models.CampaignABMetricClicks {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ab_metric"))
+	}
+
+	if t.WaitHours < 1 || t.WaitHours > maxABWaitHours {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ab_wait_hours"))
+	}
+
+	// A test that's enabled needs at least two variants.
+	if len(t.Variants) > maxCampaignVariants || (t.Percent > 0 && len(t.Variants) < 2) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("campaigns.abTestVariantsCount", "min", "2", "max", strconv.Itoa(maxCampaignVariants)))
+	}
+
+	for i, v := range t.Variants {
+		v.Name = strings.TrimSpace(v.Name)
+		if !strHasLen(v.Name, 1, stdInputMaxLen) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+		}
+
+		v.Subject = strings.TrimSpace(v.Subject)
+		if !strHasLen(v.Subject, 1, stdInputMaxLen) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "subject"))
+		}
+
+		t.Variants[i] = v
+	}
+
+	return nil
+}
@@ -4,19 +4,45 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
 )
 
 type serverConfig struct {
-	Messengers   []string   `json:"messengers"`
-	Langs        []i18nLang `json:"langs"`
-	Lang         string     `json:"lang"`
-	Update       *AppUpdate `json:"update"`
-	NeedsRestart bool       `json:"needs_restart"`
-	Version      string     `json:"version"`
+	Messengers    []string   `json:"messengers"`
+	Langs         []i18nLang `json:"langs"`
+	Lang          string     `json:"lang"`
+	Update        *AppUpdate `json:"update"`
+	NeedsRestart  bool       `json:"needs_restart"`
+	SendingPaused bool       `json:"sending_paused"`
+	CaptureMode   bool       `json:"capture_mode"`
+	Version       string     `json:"version"`
+
+	// Username of the requesting user.
+	Username string `json:"username"`
+
+	// Display time zone preferred by the user (empty for the browser's)
+	// and the time zone stats are reported in.
+	Timezone          string `json:"timezone"`
+	ReportingTimezone string `json:"reporting_timezone"`
+
+	ContentQAEnabled   bool `json:"content_qa_enabled"`
+	EntitlementEnabled bool `json:"entitlement_enabled"`
+	StripeEnabled      bool `json:"stripe_enabled"`
+	PreviewsEnabled    bool `json:"previews_enabled"`
+	SpamCheckEnabled   bool `json:"spam_check_enabled"`
+	MaxMessageSize     int  `json:"max_message_size"`
+	SizeCheckEnabled   bool `json:"size_check_enabled"`
+
+	Branding struct {
+		ProductName string `json:"product_name"`
+		LogoURL     string `json:"logo_url"`
+		Footer      string `json:"footer"`
+	} `json:"branding"`
 }
 
 // handleGetServerConfig returns general server config.
@@ -52,6 +78,57 @@ func handleGetServerConfig(c echo.Context) error {
 	out.Update = app.update
 	app.Unlock()
 	out.Version = versionString
+	out.Username = getUsername(c)
+	out.ReportingTimezone = app.constants.ReportingTimezone.String()
+	if p, err := app.core.GetUserPreferences(out.Username); err == nil {
+		out.Timezone = p.Timezone
+	}
+	out.SendingPaused = app.manager.IsPaused()
+	out.CaptureMode = app.constants.CaptureMode
+	out.ContentQAEnabled = app.constants.ContentQA.Enabled
+	out.EntitlementEnabled = app.constants.Entitlement.Enabled
+	out.StripeEnabled = app.constants.Stripe.Enabled
+	out.PreviewsEnabled = app.constants.Previews.Enabled
+	out.SpamCheckEnabled = app.spamCheck != nil
+	out.MaxMessageSize = app.constants.Attachments.MaxMessageSize
+	out.SizeCheckEnabled = app.constants.sizeCheckEnabled()
+	out.Branding.ProductName = app.constants.productName()
+	out.Branding.LogoURL = app.constants.Appearance.LogoURL
+	out.Branding.Footer = app.constants.Appearance.Footer
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetUserPreferences returns the preferences of the user.
+func handleGetUserPreferences(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetUserPreferences(getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateUserPreferences saves the preferences of the user.
+func handleUpdateUserPreferences(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var p models.UserPreferences
+	if err := c.Bind(&p); err != nil {
+		return err
+	}
+
+	p.Timezone = strings.TrimSpace(p.Timezone)
+	if !isTimezone(p.Timezone) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "timezone"))
+	}
+
+	out, err := app.core.UpdateUserPreferences(getUsername(c), p)
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(http.StatusOK, okResp{out})
 }
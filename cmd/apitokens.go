@@ -0,0 +1,293 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knadh/listmonk/internal/ratelimit"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// apiTokenLen is the length of generated API tokens.
+	apiTokenLen = 40
+
+	// apiTokenCtxKey is the request context key of the API token a request
+	// is authenticated with.
+	apiTokenCtxKey = "api_token"
+
+	// apiTokenScopeAny is the scope of routes that any token can access. They
+	// filter their responses by the token's scopes.
+	apiTokenScopeAny = "*"
+)
+
+var (
+	// apiTokenScopes are the scopes that can be granted to API tokens.
+	apiTokenScopes = []string{
+		"subscribers:read", "subscribers:write",
+		"lists:read", "lists:write",
+		"campaigns:read", "campaigns:write", "campaigns:send",
+		"templates:read", "templates:write",
+		"media:read", "media:write",
+		"bounces:read", "bounces:write",
+		"stats:read",
+		"tx:send",
+	}
+
+	// apiTokenRoleScopes are the scopes that can be granted to the tokens of
+	// each role. Read-only tokens are limited to the :read scopes, so the scope
+	// checks on requests deny them all mutations.
+	apiTokenRoleScopes = map[string][]string{
+		models.APITokenRoleFull: apiTokenScopes,
+		models.APITokenRoleReadOnly: {
+			"subscribers:read", "lists:read", "campaigns:read", "templates:read",
+			"media:read", "bounces:read", "stats:read",
+		},
+	}
+
+	// apiTokenResources maps API route prefixes to the resources of the scopes
+	// that grant access to them. GET requests require the resource's :read scope
+	// and others, its :write scope. Routes that aren't mapped here (eg: settings,
+	// tokens) can't be accessed with API tokens.
+	apiTokenResources = []struct {
+		prefix   string
+		resource string
+	}{
+		{"/api/subscribers", "subscribers"},
+		{"/api/import", "subscribers"},
+		{"/api/lists", "lists"},
+		{"/api/campaigns", "campaigns"},
+		{"/api/sends", "campaigns"},
+		{"/api/sequences", "campaigns"},
+		{"/api/templates", "templates"},
+		{"/api/header-presets", "templates"},
+		{"/api/media", "media"},
+		{"/api/bounces", "bounces"},
+		{"/api/dashboard", "stats"},
+	}
+
+	// apiTokenRoutes are the scopes of specific routes, eg: routes that send
+	// messages and require a :send scope instead of the resource's :write scope,
+	// or POST routes that only read.
+	apiTokenRoutes = map[string]string{
+		http.MethodPut + " /api/campaigns/:id/status":              "campaigns:send",
+		http.MethodPost + " /api/campaigns/:id/test":               "campaigns:send",
+		http.MethodPost + " /api/campaigns/:id/sends/retry":        "campaigns:send",
+		http.MethodPost + " /api/campaigns/:id/abtest/winner":      "campaigns:send",
+		http.MethodPost + " /api/campaigns/:id/resend":             "campaigns:send",
+		http.MethodPost + " /api/lists/:id/repermission":           "campaigns:send",
+		http.MethodPost + " /api/tx":                               "tx:send",
+		http.MethodPost + " /api/subscribers/batch":                "subscribers:read",
+		http.MethodPost + " /api/subscribers/status-rules/preview": "subscribers:read",
+		http.MethodPost + " /api/campaigns/:id/preview":            "campaigns:read",
+		http.MethodPost + " /api/campaigns/:id/text":               "campaigns:read",
+		http.MethodPost + " /api/campaigns/:id/content":            "campaigns:read",
+		http.MethodPost + " /api/campaigns/:id/qa":                 "campaigns:read",
+		http.MethodPost + " /api/campaigns/:id/spamcheck":          "campaigns:read",
+		http.MethodPost + " /api/templates/preview":                "templates:read",
+		http.MethodGet + " /api/search":                            apiTokenScopeAny,
+	}
+)
+
+// apiRateLimits are the rate limits of API tokens by role, and of specific
+// API users, which override their role limits.
+type apiRateLimits struct {
+	roles   map[string]ratelimit.Limit
+	users   map[string]ratelimit.Limit
+	limiter *ratelimit.Limiter
+}
+
+// allow takes a request from the bucket of a token's user and role. A user's
+// tokens of different roles have separate buckets.
+func (r *apiRateLimits) allow(t models.APIToken) (time.Duration, bool) {
+	lim, ok := r.users[t.Username]
+	if !ok {
+		lim = r.roles[t.Role]
+	}
+
+	return r.limiter.Allow(t.Username+":"+t.Role, lim)
+}
+
+// handleGetAPITokens returns all API tokens.
+func handleGetAPITokens(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetAPITokens()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateAPIToken creates an API token for a user. The token is only
+// returned in the response and only its hash is stored.
+func handleCreateAPIToken(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.APIToken
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	o.Name = strings.TrimSpace(o.Name)
+	if !strHasLen(o.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+
+	// The username can't have a colon as it's the BasicAuth separator.
+	o.Username = strings.TrimSpace(o.Username)
+	if !strHasLen(o.Username, 1, 200) || strings.Contains(o.Username, ":") {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "username"))
+	}
+
+	if len(o.Scopes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("apiTokens.noScopes"))
+	}
+	if o.Role == "" {
+		o.Role = models.APITokenRoleFull
+	}
+	roleScopes, ok := apiTokenRoleScopes[o.Role]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "role"))
+	}
+
+	for _, s := range o.Scopes {
+		if !inArray(s, apiTokenScopes) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("apiTokens.invalidScope", "name", s))
+		}
+
+		if !inArray(s, roleScopes) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("apiTokens.readOnlyScope", "name", s))
+		}
+	}
+
+	if o.ExpiresAt.Valid && o.ExpiresAt.Time.Before(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "expires_at"))
+	}
+
+	tok, err := generateRandomString(apiTokenLen)
+	if err != nil {
+		app.log.Printf("error generating API token: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, app.i18n.T("globals.messages.internalError"))
+	}
+
+	out, err := app.core.CreateAPIToken(o, hashAPIToken(tok))
+	if err != nil {
+		return err
+	}
+	recordAudit(c, models.AuditAPITokenCreate, auditEntityAPIToken, out.ID, nil, out)
+	out.Token = tok
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteAPIToken deletes (revokes) an API token.
+func handleDeleteAPIToken(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteAPIToken(id); err != nil {
+		return err
+	}
+	recordAudit(c, models.AuditAPITokenDelete, auditEntityAPIToken, id, nil, nil)
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleRotateAPIToken regenerates an API token and returns the new token once.
+// The old token stops working immediately.
+func handleRotateAPIToken(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	tok, err := generateRandomString(apiTokenLen)
+	if err != nil {
+		app.log.Printf("error generating API token: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, app.i18n.T("globals.messages.internalError"))
+	}
+
+	out, err := app.core.RotateAPIToken(id, hashAPIToken(tok))
+	if err != nil {
+		return err
+	}
+	recordAudit(c, models.AuditAPITokenRotate, auditEntityAPIToken, out.ID, nil, out)
+	out.Token = tok
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// authAPIToken authenticates a request with a user's API token and checks that
+// the token's scopes grant access to the requested route.
+func authAPIToken(username, token string, c echo.Context, app *App) (bool, error) {
+	t, ok, err := app.core.UseAPIToken(username, hashAPIToken(token))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if !app.ipFilter.Allowed(c.RealIP(), true) {
+		return false, echo.NewHTTPError(http.StatusForbidden, app.i18n.T("settings.security.ipNotAllowed"))
+	}
+
+	// Rate limit the requests of the user's tokens of the same role.
+	if d, ok := app.rateLimit.allow(t); !ok {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(d.Seconds())+1))
+		return false, echo.NewHTTPError(http.StatusTooManyRequests, app.i18n.T("apiTokens.rateLimited"))
+	}
+
+	scope := apiTokenScope(c.Request().Method, c.Path())
+	if scope == "" {
+		return false, echo.NewHTTPError(http.StatusForbidden, app.i18n.T("apiTokens.notAllowed"))
+	}
+	if scope != apiTokenScopeAny && !inArray(scope, t.Scopes) {
+		return false, echo.NewHTTPError(http.StatusForbidden, app.i18n.Ts("apiTokens.scopeRequired", "name", scope))
+	}
+
+	c.Set(apiTokenCtxKey, t)
+	return true, nil
+}
+
+// apiTokenScope returns the scope that's required to access a route with an
+// API token. An empty string means the route can't be accessed with tokens.
+func apiTokenScope(method, path string) string {
+	if s, ok := apiTokenRoutes[method+" "+path]; ok {
+		return s
+	}
+
+	for _, r := range apiTokenResources {
+		if path != r.prefix && !strings.HasPrefix(path, r.prefix+"/") {
+			continue
+		}
+
+		if method == http.MethodGet || method == http.MethodHead {
+			return r.resource + ":read"
+		}
+		return r.resource + ":write"
+	}
+
+	return ""
+}
+
+// hashAPIToken returns the hex SHA-256 hash of an API token. Tokens are random
+// and long, so a slow password hash isn't necessary.
+func hashAPIToken(tok string) string {
+	h := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/knadh/listmonk/internal/clamav"
+	"github.com/knadh/listmonk/internal/httpscan"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sizeActionWarn   = "warn"
+	sizeActionReject = "reject"
+
+	scannerClamAV = "clamav"
+	scannerHTTP   = "http"
+
+	// Max. number of unique images in a campaign that are fetched to measure
+	// their weight, and the timeout for fetching each.
+	maxWeighedImages   = 50
+	imageWeighTimeout  = time.Second * 10
+	maxImageWeighBytes = 50 * 1024 * 1024
+)
+
+var regexpImgSrc = regexp.MustCompile(`(?i)<img\b[^>]*?\ssrc\s*=\s*["']([^"']+)["']`)
+
+// messageSize is the size of a campaign's rendered e-mail message including
+// its attachments, its rendered HTML body, and the total weight of the images
+// it references against the configured size budgets.
+type messageSize struct {
+	Size     int    `json:"size"`
+	Budget   int    `json:"budget"`
+	Exceeded bool   `json:"exceeded"`
+	Action   string `json:"action"`
+
+	BodySize     int  `json:"body_size"`
+	BodyBudget   int  `json:"body_budget"`
+	BodyExceeded bool `json:"body_exceeded"`
+
+	ImageWeight   int           `json:"image_weight"`
+	ImageBudget   int           `json:"image_budget"`
+	ImageExceeded bool          `json:"image_exceeded"`
+	Images        []imageWeight `json:"images"`
+}
+
+// imageWeight is the size of an image referenced in a campaign.
+type imageWeight struct {
+	URL   string `json:"url"`
+	Size  int    `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// sizeCheckEnabled returns true if any of the campaign size budgets are set.
+func (c *constants) sizeCheckEnabled() bool {
+	return c.Attachments.MaxMessageSize > 0 || c.Attachments.MaxBodySize > 0 || c.Attachments.MaxImageWeight > 0
+}
+
+// handleGetCampaignSize returns the size of a campaign's rendered e-mail message.
+func handleGetCampaignSize(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := getCampaignMessageSize(id, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// getCampaignMessageSize renders a campaign for a dummy subscriber as a full
+// e-mail message with its attachments (encoded) and returns its size.
+func getCampaignMessageSize(id int, app *App) (messageSize, error) {
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return messageSize{}, err
+	}
+
+	if err := loadLiveCampaignContent(&camp, app); err != nil {
+		return messageSize{}, err
+	}
+
+	atts, err := getCampaignAttachments(camp, app)
+	if err != nil {
+		return messageSize{}, err
+	}
+
+	msg, err := renderCampaignPreview(&camp, app)
+	if err != nil {
+		return messageSize{}, err
+	}
+
+	b, err := makeRawCampaignMessage(camp, msg, atts)
+	if err != nil {
+		return messageSize{}, echo.NewHTTPError(http.StatusInternalServerError,
+			app.i18n.Ts("templates.errorRendering", "error", err.Error()))
+	}
+
+	var (
+		budget = app.constants.Attachments.MaxMessageSize * 1024
+		out    = messageSize{
+			Size:   len(b),
+			Budget: budget,
+			Action: app.constants.Attachments.SizeAction,
+
+			BodySize:   len(msg.Body()),
+			BodyBudget: app.constants.Attachments.MaxBodySize * 1024,
+
+			ImageBudget: app.constants.Attachments.MaxImageWeight * 1024,
+			Images:      []imageWeight{},
+		}
+	)
+	out.Exceeded = budget > 0 && out.Size > budget
+	out.BodyExceeded = out.BodyBudget > 0 && out.BodySize > out.BodyBudget
+
+	// Fetching images is expensive and is only done if there's an image budget.
+	if out.ImageBudget > 0 && camp.ContentType != models.CampaignContentTypePlain {
+		out.Images = weighImages(msg.Body())
+		for _, im := range out.Images {
+			out.ImageWeight += im.Size
+		}
+		out.ImageExceeded = out.ImageWeight > out.ImageBudget
+	}
+
+	return out, nil
+}
+
+// getCampaignAttachments fetches the blobs of a campaign's attached media.
+func getCampaignAttachments(camp models.Campaign, app *App) ([]models.Attachment, error) {
+	var media []struct {
+		ID int `json:"id"`
+	}
+	if len(camp.Media) > 0 {
+		if err := json.Unmarshal(camp.Media, &media); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	out := make([]models.Attachment, 0, len(media))
+	for _, m := range media {
+		med, err := app.core.GetMedia(m.ID, "", app.media)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := app.media.GetBlob(med.URL)
+		if err != nil {
+			app.log.Printf("error fetching attachment %d: %v", m.ID, err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError,
+				app.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.media}", "error", err.Error()))
+		}
+		out = append(out, models.Attachment{Name: med.Filename, Content: b})
+	}
+
+	return out, nil
+}
+
+// weighImages fetches the unique images referenced in an HTML body and returns
+// their sizes. Inline data: URIs are counted by their length. Images that can't
+// be fetched are returned with an error and a size of 0.
+func weighImages(body []byte) []imageWeight {
+	var (
+		out  = []imageWeight{}
+		seen = map[string]bool{}
+	)
+	for _, m := range regexpImgSrc.FindAllSubmatch(body, -1) {
+		u := html.UnescapeString(strings.TrimSpace(string(m[1])))
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		if strings.HasPrefix(u, "data:") {
+			name := u
+			if len(name) > 64 {
+				name = name[:64] + "..."
+			}
+			out = append(out, imageWeight{URL: name, Size: len(u)})
+			continue
+		}
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			continue
+		}
+
+		if len(out) >= maxWeighedImages {
+			break
+		}
+		out = append(out, imageWeight{URL: u})
+	}
+
+	var (
+		wg sync.WaitGroup
+		hc = &http.Client{Timeout: imageWeighTimeout}
+	)
+	for i := range out {
+		if out[i].Size > 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(im *imageWeight) {
+			defer wg.Done()
+
+			n, err := fetchImageSize(im.URL, hc)
+			if err != nil {
+				im.Error = err.Error()
+				return
+			}
+			im.Size = n
+		}(&out[i])
+	}
+	wg.Wait()
+
+	return out
+}
+
+// fetchImageSize downloads an image and returns its size in bytes.
+func fetchImageSize(u string, hc *http.Client) (int, error) {
+	resp, err := hc.Get(u)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s", resp.Status)
+	}
+
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxImageWeighBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// preflightMessageSize checks a campaign's message size, body size, and image weight
+// against the size budgets before it's started or scheduled. If a budget is exceeded,
+// it returns an error if the size action is 'reject' and only logs a warning otherwise.
+func preflightMessageSize(id int, app *App) error {
+	if !app.constants.sizeCheckEnabled() {
+		return nil
+	}
+
+	s, err := getCampaignMessageSize(id, app)
+	if err != nil {
+		return err
+	}
+
+	msgs := []string{}
+	if s.Exceeded {
+		msgs = append(msgs, app.i18n.Ts("campaigns.sizeExceeded",
+			"size", strconv.Itoa(s.Size/1024), "budget", strconv.Itoa(s.Budget/1024)))
+	}
+	if s.BodyExceeded {
+		msgs = append(msgs, app.i18n.Ts("campaigns.bodySizeExceeded",
+			"size", strconv.Itoa(s.BodySize/1024), "budget", strconv.Itoa(s.BodyBudget/1024)))
+	}
+	if s.ImageExceeded {
+		msgs = append(msgs, app.i18n.Ts("campaigns.imageWeightExceeded",
+			"size", strconv.Itoa(s.ImageWeight/1024), "budget", strconv.Itoa(s.ImageBudget/1024)))
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if s.Action != sizeActionReject {
+		app.log.Printf("WARNING: campaign %d exceeds size budgets: %s", id, strings.Join(msgs, " "))
+		return nil
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, strings.Join(msgs, " "))
+}
+
+// preflightAttachments checks that a campaign's messenger can deliver its attachments
+// and that their total size is within the max attachments size before it's started or
+// scheduled. Unlike the size budgets, the max attachments size is always enforced.
+func preflightAttachments(id int, app *App) error {
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	atts, err := getCampaignAttachments(camp, app)
+	if err != nil {
+		return err
+	}
+	if len(atts) == 0 {
+		return nil
+	}
+
+	if !app.manager.MessengerSupportsAttachments(camp.Messenger) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.messengerNoAttachments", "name", camp.Messenger))
+	}
+
+	max := app.constants.Attachments.MaxSize * 1024
+	if max == 0 {
+		return nil
+	}
+
+	size := 0
+	for _, a := range atts {
+		size += len(a.Content)
+	}
+	if size > max {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.attachmentsTooLarge",
+			"size", strconv.Itoa(size/1024), "budget", strconv.Itoa(max/1024)))
+	}
+
+	return nil
+}
+
+// fileScanner scans uploaded files for malware with ClamAV or an HTTP scanner.
+type fileScanner interface {
+	Scan(io.Reader) (clamav.Result, error)
+}
+
+// httpScanner wraps the HTTP scanner client as a fileScanner.
+type httpScanner struct {
+	c *httpscan.Client
+}
+
+// Scan scans a file with the HTTP scanner.
+func (s httpScanner) Scan(r io.Reader) (clamav.Result, error) {
+	res, err := s.c.Scan(r)
+	if err != nil {
+		return clamav.Result{}, err
+	}
+
+	return clamav.Result{Infected: res.Infected, Signature: res.Signature}, nil
+}
+
+// scanFile scans an uploaded file with the configured scanner (if it's enabled)
+// and returns the result.
+func scanFile(name string, r io.Reader, app *App) (clamav.Result, error) {
+	if app.scanner == nil {
+		return clamav.Result{}, nil
+	}
+
+	res, err := app.scanner.Scan(r)
+	if err != nil {
+		app.log.Printf("error scanning file %s: %v", name, err)
+		return res, echo.NewHTTPError(http.StatusBadGateway, app.i18n.Ts("media.errorScanning", "error", err.Error()))
+	}
+
+	return res, nil
+}
+
+// scanAttachment scans an uploaded file (if scanning is enabled) and returns
+// an error if it's infected.
+func scanAttachment(name string, r io.Reader, app *App) error {
+	res, err := scanFile(name, r, app)
+	if err != nil {
+		return err
+	}
+
+	if res.Infected {
+		app.log.Printf("rejected infected file %s: %s", name, res.Signature)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity,
+			app.i18n.Ts("media.infectedFile", "name", name, "signature", res.Signature))
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
+)
+
+// Audit log entity types.
+const (
+	auditEntitySettings   = "settings"
+	auditEntityCampaign   = "campaign"
+	auditEntitySubscriber = "subscriber"
+	auditEntityAPIToken   = "api_token"
+)
+
+// handleQueryAuditLog returns paginated audit log entries, optionally filtered by
+// actor, action, and entity.
+func handleQueryAuditLog(c echo.Context) error {
+	var (
+		app         = c.Get("app").(*App)
+		pg          = app.paginator.NewFromURL(c.Request().URL.Query())
+		entityID, _ = strconv.Atoi(c.QueryParam("entity_id"))
+	)
+
+	res, total, err := app.core.QueryAuditLog(strings.TrimSpace(c.QueryParam("actor")), c.QueryParam("action"),
+		c.QueryParam("entity_type"), entityID, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// recordAudit records an admin action by the request's user in the audit log.
+// before and after are snapshots of the entity before and after the action (nil
+// if it didn't exist). Errors are logged and don't fail the action.
+func recordAudit(c echo.Context, action, entityType string, entityID int, before, after interface{}) {
+	app := c.Get("app").(*App)
+
+	e := models.AuditEntry{
+		Actor:      getUsername(c),
+		Action:     action,
+		EntityType: entityType,
+		IP:         c.RealIP(),
+	}
+	if entityID > 0 {
+		e.EntityID = null.IntFrom(entityID)
+	}
+
+	var err error
+	if before != nil {
+		if e.Before, err = json.Marshal(before); err != nil {
+			app.log.Printf("error marshalling audit log data (%s): %v", action, err)
+		}
+	}
+	if after != nil {
+		if e.After, err = json.Marshal(after); err != nil {
+			app.log.Printf("error marshalling audit log data (%s): %v", action, err)
+		}
+	}
+	e.Changes = auditChanges(e.Before, e.After)
+
+	_ = app.core.RecordAudit(e)
+}
+
+// auditChanges returns the sorted top level keys of two JSON objects whose
+// values differ, including keys that are only in one of them.
+func auditChanges(before, after []byte) []string {
+	var b, a map[string]json.RawMessage
+	_ = json.Unmarshal(before, &b)
+	_ = json.Unmarshal(after, &a)
+
+	out := []string{}
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !bytes.Equal(v, w) {
+			out = append(out, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+
+	return out
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	autocompleteLimit    = 10
+	maxAutocompleteLimit = 50
+	maxAutocompleteQuery = 200
+)
+
+// handleAutocomplete returns ranked typeahead suggestions for the admin pickers.
+// Only the ID, name, and a bit of secondary info of every item is returned to keep
+// the payloads small.
+func handleAutocomplete(c echo.Context) error {
+	var (
+		app       = c.Get("app").(*App)
+		typ       = c.Param("type")
+		searchStr = strings.TrimSpace(c.QueryParam("q"))
+		tplType   = c.QueryParam("template_type")
+		limit, _  = strconv.Atoi(c.QueryParam("limit"))
+	)
+
+	if r := []rune(searchStr); len(r) > maxAutocompleteQuery {
+		searchStr = string(r[:maxAutocompleteQuery])
+	}
+	if limit < 1 {
+		limit = autocompleteLimit
+	} else if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+
+	if tplType != "" && tplType != models.TemplateTypeCampaign && tplType != models.TemplateTypeTx {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "template_type"))
+	}
+
+	out, err := app.core.Autocomplete(typ, searchStr, tplType, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
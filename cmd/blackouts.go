@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetListBlackouts returns the blackouts of a list that haven't ended,
+// or all of them with ?all=true.
+func handleGetListBlackouts(c echo.Context) error {
+	var (
+		app    = c.Get("app").(*App)
+		id, _  = strconv.Atoi(c.Param("id"))
+		all, _ = strconv.ParseBool(c.QueryParam("all"))
+	)
+
+	out, err := app.core.GetListBlackouts(id, all)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateListBlackout creates a blackout date range on a list. Campaigns
+// scheduled to the list are held during the range and go out after it ends.
+func handleCreateListBlackout(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var b models.ListBlackout
+	if err := c.Bind(&b); err != nil {
+		return err
+	}
+
+	if !strHasLen(b.Name, 0, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+	if b.StartsAt.IsZero() || !b.EndsAt.After(b.StartsAt) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ends_at"))
+	}
+
+	// Check that the list exists.
+	list, err := app.core.GetList(id, "")
+	if err != nil {
+		return err
+	}
+
+	b.ListID = id
+	b.ListName = list.Name
+	if b.ID, err = app.core.CreateListBlackout(b); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{b})
+}
+
+// handleDeleteListBlackout deletes a blackout of a list.
+func handleDeleteListBlackout(c echo.Context) error {
+	var (
+		app    = c.Get("app").(*App)
+		id, _  = strconv.Atoi(c.Param("id"))
+		bID, _ = strconv.Atoi(c.Param("blackoutID"))
+	)
+
+	if id < 1 || bID < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteListBlackout(bID, id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleGetCampaignBlackouts returns the blackouts of a campaign's lists that its
+// scheduled send time (or now, if it isn't scheduled) falls in. A campaign scheduled
+// into a blackout is held until the blackout ends.
+func handleGetCampaignBlackouts(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignBlackouts(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	defaultProductName = "listmonk"
+	defaultBrandColor  = "#0055d4"
+)
+
+var regexpBrandColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// productName returns the white-label product name, or listmonk.
+func (c *constants) productName() string {
+	if c.Appearance.ProductName != "" {
+		return c.Appearance.ProductName
+	}
+	return defaultProductName
+}
+
+// brandColor returns the white-label primary color, or listmonk's.
+func (c *constants) brandColor() string {
+	if c.Appearance.Color != "" {
+		return c.Appearance.Color
+	}
+	return defaultBrandColor
+}
+
+// logoURL returns the white-label logo URL, or the site logo URL (which may be empty).
+func (c *constants) logoURL() string {
+	if c.Appearance.LogoURL != "" {
+		return c.Appearance.LogoURL
+	}
+	return c.LogoURL
+}
+
+// brandingCSS returns the CSS that applies the white-label primary color
+// to the admin UI or the public pages. It's prepended to the custom CSS
+// so that the custom CSS can override it.
+func brandingCSS(color string, admin bool) []byte {
+	if color == "" {
+		return nil
+	}
+
+	if admin {
+		return []byte(fmt.Sprintf(`a, .has-text-link, .tabs li.is-active a { color: %[1]s; }
+.button.is-primary, .tag.is-primary, .pagination-link.is-current { background-color: %[1]s; border-color: %[1]s; }
+.tabs li.is-active a { border-bottom-color: %[1]s; }
+.menu-list .router-link-exact-active { border-right-color: %[1]s; }
+
+`, color))
+	}
+
+	return []byte(fmt.Sprintf(`a { color: %[1]s; text-decoration-color: %[1]s; }
+.button { background: %[1]s; }
+.button.button-outline { background: #fff; border-color: %[1]s; color: %[1]s; }
+.button.button-outline:hover { background-color: %[1]s; color: #fff; }
+input:focus { border-color: %[1]s; }
+
+`, color))
+}
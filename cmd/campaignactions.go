@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxCampaignActions is the maximum number of post-campaign actions in a campaign.
+	maxCampaignActions = 10
+
+	// maxCampaignActionDelay is the maximum number of hours (30 days) after a campaign
+	// finishes that its actions can be delayed by to let opens and clicks come in.
+	maxCampaignActionDelay = 24 * 30
+)
+
+// handleGetCampaignActions returns the post-campaign actions of a campaign.
+func handleGetCampaignActions(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignActions(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateCampaignActions replaces the post-campaign actions of a campaign with the set in the request.
+func handleUpdateCampaignActions(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var acts []models.CampaignAction
+	if err := c.Bind(&acts); err != nil {
+		return err
+	}
+
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	acts, err := validateCampaignActions(acts, app)
+	if err != nil {
+		return err
+	}
+
+	out, err := app.core.SetCampaignActions(id, acts)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runCampaignActions runs the post-campaign actions of finished campaigns whose delay is up.
+func runCampaignActions(app *App) {
+	acts, err := app.core.GetDueCampaignActions()
+	if err != nil {
+		return
+	}
+
+	for _, a := range acts {
+		n, err := app.core.RunCampaignAction(a.ID)
+		if err != nil {
+			continue
+		}
+		app.log.Printf("campaign %d action '%s' (%s) applied to %d subscribers", a.CampaignID, a.Action, a.Condition, n)
+	}
+}
+
+// validateCampaignActions validates and cleans up a campaign's post-campaign actions.
+func validateCampaignActions(acts []models.CampaignAction, app *App) ([]models.CampaignAction, error) {
+	if len(acts) > maxCampaignActions {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.actionsTooMany", "num", strconv.Itoa(maxCampaignActions)))
+	}
+
+	for i, a := range acts {
+		switch a.Condition {
+		case models.CampaignActionOpened, models.CampaignActionNotOpened:
+			a.URL = ""
+		case models.CampaignActionClicked, models.CampaignActionNotClicked:
+			// An empty URL matches any link in the campaign.
+			a.URL = strings.TrimSpace(a.URL)
+			if a.URL != "" && !isHTTPURL(a.URL) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "url"))
+			}
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "condition"))
+		}
+
+		switch a.Action {
+		case models.CampaignActionAddTag:
+			a.Tag = strings.TrimSpace(a.Tag)
+			a.ListID.Valid = false
+			if !strHasLen(a.Tag, 1, 100) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "tag"))
+			}
+		case models.CampaignActionAddList, models.CampaignActionMoveList:
+			a.Tag = ""
+			if !a.ListID.Valid || a.ListID.Int < 1 {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "list_id"))
+			}
+			if _, err := app.core.GetList(int(a.ListID.Int), ""); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "action"))
+		}
+
+		if a.DelayHours < 0 || a.DelayHours > maxCampaignActionDelay {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "delay_hours"))
+		}
+
+		acts[i] = a
+	}
+
+	return acts, nil
+}
@@ -2,18 +2,27 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"html/template"
+	"io"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/knadh/listmonk/internal/feed"
+	"github.com/knadh/listmonk/internal/manager"
+	"github.com/knadh/listmonk/internal/previews"
 	"github.com/knadh/listmonk/models"
+	"github.com/knadh/smtppool"
 	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
 	"gopkg.in/volatiletech/null.v6"
@@ -47,9 +56,46 @@ type campaignContentReq struct {
 	To   string `json:"to"`
 }
 
+const (
+	// campContentFetchTimeout is the timeout for fetching a campaign's body from its content URL.
+	campContentFetchTimeout = time.Second * 15
+
+	// campContentMaxSize is the maximum size (bytes) of a campaign body fetched from a content URL.
+	campContentMaxSize = 5 * 1024 * 1024
+
+	// campExportPDFClient is the preview service client that renders PDF exports.
+	campExportPDFClient = "pdf"
+
+	// Max. number of unique images that are embedded in an HTML export, and the
+	// max. size (bytes) of each.
+	campExportMaxImages    = 50
+	campExportImageMaxSize = 10 * 1024 * 1024
+
+	// campMaxDependsDelayMins is the maximum delay (30 days) after the campaign a campaign depends on.
+	campMaxDependsDelayMins = 60 * 24 * 30
+
+	// campMaxDependsDepth is the maximum length of a chain of campaign dependencies.
+	campMaxDependsDepth = 50
+
+	// campMaxSTOWindowHours is the maximum window (7 days) within which a campaign with
+	// send-time optimization is sent to each subscriber at their best hour.
+	campMaxSTOWindowHours = 24 * 7
+
+	// campSendHoursDays is the number of days of campaign views from which the
+	// best send hours of subscribers are computed.
+	campSendHoursDays = 180
+
+	// campMaxSendRate is the maximum per-campaign throttle (messages per minute).
+	campMaxSendRate = 100000
+
+	// campMaxSendWindows is the maximum number of send windows of a campaign.
+	campMaxSendWindows = 14
+)
+
 var (
 	regexFromAddress = regexp.MustCompile(`((.+?)\s)?<(.+?)@(.+?)>`)
 	regexSlug        = regexp.MustCompile(`[^\p{L}\p{M}\p{N}]`)
+	regexSendWindow  = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
 )
 
 // handleGetCampaigns handles retrieval of campaigns.
@@ -110,6 +156,41 @@ func handleGetCampaign(c echo.Context) error {
 		out.Body = ""
 	}
 
+	setETag(c, out.Version)
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetCampaignAudience returns the number of subscribers a campaign would
+// be sent to if it were started right now and a sample of them.
+func handleGetCampaignAudience(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		id, _    = strconv.Atoi(c.Param("id"))
+		limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// Check that the campaign exists.
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	subs, total, err := app.core.GetCampaignAudience(id, limit)
+	if err != nil {
+		return err
+	}
+
+	out := struct {
+		Total       int                 `json:"total"`
+		Subscribers []models.Subscriber `json:"subscribers"`
+	}{total, subs}
+
 	return c.JSON(http.StatusOK, okResp{out})
 }
 
@@ -134,30 +215,291 @@ func handlePreviewCampaign(c echo.Context) error {
 	if c.Request().Method == http.MethodPost {
 		camp.ContentType = c.FormValue("content_type")
 		camp.Body = c.FormValue("body")
+	} else if camp.ContentURL != "" && camp.ContentChecksum == "" {
+		// The body is sourced from a remote URL and hasn't been frozen yet. Preview the live content.
+		b, err := fetchCampaignContent(camp.ContentURL, &http.Client{Timeout: campContentFetchTimeout})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("campaigns.errorFetchingContent", "error", err.Error()))
+		}
+		camp.Body = string(b)
+	}
+
+	if err := loadLiveCampaignFeed(&camp, app); err != nil {
+		return err
+	}
+
+	msg, err := renderCampaignPreview(&camp, app)
+	if err != nil {
+		return err
+	}
+
+	if camp.ContentType == models.CampaignContentTypePlain {
+		return c.String(http.StatusOK, string(msg.Body()))
+	}
+
+	return c.HTML(http.StatusOK, string(msg.Body()))
+}
+
+// handleExportCampaign exports a campaign's message, rendered for a dummy subscriber,
+// as a standalone HTML file (?format=html) or as a PDF (?format=pdf) that's
+// generated by the preview service.
+func handleExportCampaign(c echo.Context) error {
+	var (
+		app    = c.Get("app").(*App)
+		id, _  = strconv.Atoi(c.Param("id"))
+		format = c.QueryParam("format")
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "format"))
+	}
+	if format == "pdf" && !app.constants.Previews.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.previewsDisabled"))
+	}
+
+	camp, err := app.core.GetCampaignForPreview(id, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := loadLiveCampaignContent(&camp, app); err != nil {
+		return err
+	}
+
+	msg, err := renderCampaignPreview(&camp, app)
+	if err != nil {
+		return err
 	}
 
+	// Plain text messages are wrapped in an HTML document.
+	body := string(msg.Body())
+	if camp.ContentType == models.CampaignContentTypePlain {
+		body = fmt.Sprintf("<!doctype html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body><pre>%s</pre></body>\n</html>\n",
+			template.HTMLEscapeString(msg.Subject()), template.HTMLEscapeString(body))
+	}
+
+	if format == "html" {
+		body = inlineImages(body, &http.Client{Timeout: imageWeighTimeout}, app)
+
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign-%d.html"`, id))
+		return c.Blob(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+	}
+
+	// The preview service renders the PDF as the "pdf" client.
+	res, err := app.previews.Render(previews.Message{
+		CampaignID: id,
+		UUID:       camp.UUID,
+		Subject:    msg.Subject(),
+		FromEmail:  camp.FromEmail,
+		Body:       body,
+		Clients:    []string{campExportPDFClient},
+	})
+	if err != nil {
+		app.log.Printf("error exporting campaign as PDF: %v", err)
+		return echo.NewHTTPError(http.StatusBadGateway,
+			app.i18n.Ts("campaigns.previewsError", "error", err.Error()))
+	}
+
+	for _, p := range res {
+		if p.Client == campExportPDFClient && p.ContentType == "application/pdf" {
+			c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign-%d.pdf"`, id))
+			c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+			return c.Blob(http.StatusOK, p.ContentType, p.Image)
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusBadGateway, app.i18n.T("campaigns.exportPDFUnsupported"))
+}
+
+// inlineImages embeds the remote images referenced by <img> tags in an HTML body
+// as data: URIs so that the body renders without network access. Images that
+// can't be fetched or aren't images keep their URLs.
+func inlineImages(body string, hc *http.Client, app *App) string {
+	var (
+		uris = map[string]string{}
+		seen = map[string]bool{}
+	)
+	for _, m := range regexpImgSrc.FindAllStringSubmatch(body, -1) {
+		u := html.UnescapeString(strings.TrimSpace(m[1]))
+		if seen[u] || (!strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://")) {
+			continue
+		}
+		if len(seen) >= campExportMaxImages {
+			break
+		}
+		seen[u] = true
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for u := range seen {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+
+			d, err := fetchImageDataURI(u, hc)
+			if err != nil {
+				app.log.Printf("error embedding image %s in export: %v", u, err)
+				return
+			}
+
+			mu.Lock()
+			uris[u] = d
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+
+	if len(uris) == 0 {
+		return body
+	}
+
+	// Replace the src of every <img> tag whose image was fetched.
+	return regexpImgSrc.ReplaceAllStringFunc(body, func(tag string) string {
+		i := regexpImgSrc.FindStringSubmatchIndex(tag)
+		d, ok := uris[html.UnescapeString(strings.TrimSpace(tag[i[2]:i[3]]))]
+		if !ok {
+			return tag
+		}
+		return tag[:i[2]] + d + tag[i[3]:]
+	})
+}
+
+// fetchImageDataURI downloads an image and returns it as a base64 data: URI.
+func fetchImageDataURI(u string, hc *http.Client) (string, error) {
+	resp, err := hc.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s", resp.Status)
+	}
+
+	// Read one byte more than the limit to detect oversized images.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, campExportImageMaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(b) > campExportImageMaxSize {
+		return "", fmt.Errorf("image exceeds %d bytes", campExportImageMaxSize)
+	}
+
+	// Sniff the type if the server doesn't send an image type.
+	typ, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if !strings.HasPrefix(typ, "image/") {
+		typ, _, _ = strings.Cut(http.DetectContentType(b), ";")
+	}
+	if !strings.HasPrefix(typ, "image/") {
+		return "", fmt.Errorf("not an image: %s", typ)
+	}
+
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// renderCampaignPreview compiles and renders a campaign's message for a dummy subscriber.
+func renderCampaignPreview(camp *models.Campaign, app *App) (manager.CampaignMessage, error) {
 	// Use a dummy campaign ID to prevent views and clicks from {{ TrackView }}
 	// and {{ TrackLink }} being registered on preview.
 	camp.UUID = dummySubscriber.UUID
-	if err := camp.CompileTemplate(app.manager.TemplateFuncs(&camp)); err != nil {
+	app.manager.SetLinkUTM(camp)
+	if err := camp.CompileTemplate(app.manager.TemplateFuncs(camp)); err != nil {
 		app.log.Printf("error compiling template: %v", err)
-		return echo.NewHTTPError(http.StatusBadRequest,
+		return manager.CampaignMessage{}, echo.NewHTTPError(http.StatusBadRequest,
 			app.i18n.Ts("templates.errorCompiling", "error", err.Error()))
 	}
 
 	// Render the message body.
-	msg, err := app.manager.NewCampaignMessage(&camp, dummySubscriber)
+	msg, err := app.manager.NewCampaignMessage(camp, dummySubscriber)
 	if err != nil {
 		app.log.Printf("error rendering message: %v", err)
-		return echo.NewHTTPError(http.StatusBadRequest,
+		return manager.CampaignMessage{}, echo.NewHTTPError(http.StatusBadRequest,
 			app.i18n.Ts("templates.errorRendering", "error", err.Error()))
 	}
 
-	if camp.ContentType == models.CampaignContentTypePlain {
-		return c.String(http.StatusOK, string(msg.Body()))
+	return msg, nil
+}
+
+// loadLiveCampaignContent fetches the body and the feed entries of a campaign
+// that are sourced from remote URLs and haven't been frozen yet so that the
+// live content is rendered.
+func loadLiveCampaignContent(camp *models.Campaign, app *App) error {
+	if camp.ContentURL != "" && camp.ContentChecksum == "" {
+		b, err := fetchCampaignContent(camp.ContentURL, &http.Client{Timeout: campContentFetchTimeout})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("campaigns.errorFetchingContent", "error", err.Error()))
+		}
+		camp.Body = string(b)
 	}
 
-	return c.HTML(http.StatusOK, string(msg.Body()))
+	return loadLiveCampaignFeed(camp, app)
+}
+
+// loadLiveCampaignFeed fetches the entries of a campaign's feed if they
+// haven't been frozen yet.
+func loadLiveCampaignFeed(camp *models.Campaign, app *App) error {
+	if camp.FeedURL == "" || camp.FeedItems != nil {
+		return nil
+	}
+
+	items, err := fetchCampaignFeed(camp, &http.Client{Timeout: campContentFetchTimeout})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("campaigns.errorFetchingFeed", "error", err.Error()))
+	}
+	camp.FeedItems = items
+
+	return nil
+}
+
+// makeRawCampaignMessage returns the raw e-mail message of a rendered campaign
+// with the given attachments.
+func makeRawCampaignMessage(camp models.Campaign, msg manager.CampaignMessage, atts []models.Attachment) ([]byte, error) {
+	e := smtppool.Email{
+		From:    camp.FromEmail,
+		To:      []string{dummySubscriber.Email},
+		Subject: msg.Subject(),
+		Headers: textproto.MIMEHeader{},
+	}
+
+	switch camp.ContentType {
+	case models.CampaignContentTypePlain:
+		e.Text = msg.Body()
+	default:
+		e.HTML = msg.Body()
+		e.Text = msg.AltBody()
+	}
+
+	// The preset's headers come first so that the campaign's own headers follow them.
+	for _, hdrs := range []models.Headers{camp.PresetHeaders, camp.Headers} {
+		for _, set := range hdrs {
+			for hdr, val := range set {
+				e.Headers.Add(hdr, val)
+			}
+		}
+	}
+
+	for _, a := range atts {
+		e.Attachments = append(e.Attachments, smtppool.Attachment{
+			Filename: a.Name,
+			Header:   a.Header,
+			Content:  a.Content,
+		})
+	}
+
+	return e.Bytes()
 }
 
 // handleCampaignContent handles campaign content (body) format conversions.
@@ -214,6 +556,13 @@ func handleCreateCampaign(c echo.Context) error {
 		o.Messenger = "email"
 	}
 
+	// Apply Markdown front-matter, if any.
+	if out, err := applyCampaignFrontMatter(o, app); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else {
+		o = out
+	}
+
 	// Validate.
 	if c, err := validateCampaignFields(o, app); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -263,6 +612,25 @@ func handleUpdateCampaign(c echo.Context) error {
 		return err
 	}
 
+	// Optimistic locking. The version the update is based on can be sent in the
+	// If-Match header or the version field. If neither is sent, it's the current version.
+	if v, ok, err := getIfMatchVersion(c, app); err != nil {
+		return err
+	} else if ok {
+		o.Version = v
+	}
+	if o.Version != cm.Version {
+		return makeVersionConflict(app.i18n.Ts("globals.messages.versionConflict", "name", "{globals.terms.campaign}"),
+			cm.Version, cm, o.Campaign)
+	}
+
+	// Apply Markdown front-matter, if any.
+	if out, err := applyCampaignFrontMatter(o, app); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else {
+		o = out
+	}
+
 	if c, err := validateCampaignFields(o, app); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	} else {
@@ -296,10 +664,52 @@ func handleUpdateCampaignStatus(c echo.Context) error {
 		return err
 	}
 
+	// Run the content QA hooks, the spam check, and the size check before the campaign is started or scheduled.
+	if o.Status == models.CampaignStatusRunning || o.Status == models.CampaignStatusScheduled {
+		if err := preflightContentQA(id, app); err != nil {
+			return err
+		}
+		if err := preflightSpamCheck(id, app); err != nil {
+			return err
+		}
+		if err := preflightMessageSize(id, app); err != nil {
+			return err
+		}
+		if err := preflightAttachments(id, app); err != nil {
+			return err
+		}
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	// Campaigns count against the quota of the user who starts or schedules
+	// them. Resuming a paused campaign doesn't.
+	var (
+		user   = getUsername(c)
+		charge = false
+	)
+	if o.Status == models.CampaignStatusRunning || o.Status == models.CampaignStatusScheduled {
+		if camp.Status == models.CampaignStatusDraft {
+			if err := checkCampaignQuota(user, camp, app); err != nil {
+				return err
+			}
+			charge = true
+		}
+	}
+
 	out, err := app.core.UpdateCampaignStatus(id, o.Status)
 	if err != nil {
 		return err
 	}
+	recordAudit(c, models.AuditCampaignStatus, auditEntityCampaign, id,
+		map[string]string{"name": camp.Name, "status": camp.Status}, map[string]string{"name": out.Name, "status": out.Status})
+
+	if charge {
+		_ = app.core.RecordQuotaUsage(user, out.ID, out.ToSend)
+	}
 
 	if o.Status == models.CampaignStatusPaused || o.Status == models.CampaignStatusCancelled {
 		app.manager.StopCampaign(id)
@@ -455,6 +865,18 @@ func handleTestCampaign(c echo.Context) error {
 	camp.ContentType = req.ContentType
 	camp.Headers = req.Headers
 	camp.TemplateID = req.TemplateID
+
+	// The header preset may have been changed in the editor.
+	if req.HeaderPresetID != camp.HeaderPresetID {
+		camp.HeaderPresetID, camp.PresetHeaders = req.HeaderPresetID, nil
+		if req.HeaderPresetID.Valid {
+			p, err := app.core.GetHeaderPreset(req.HeaderPresetID.Int)
+			if err != nil {
+				return err
+			}
+			camp.PresetHeaders = p.Headers
+		}
+	}
 	for _, id := range req.MediaIDs {
 		if id > 0 {
 			camp.MediaIDs = append(camp.MediaIDs, int64(id))
@@ -510,6 +932,20 @@ func handleGetCampaignViewAnalytics(c echo.Context) error {
 		return c.JSON(http.StatusOK, okResp{out})
 	}
 
+	// Per-variant stats of the campaigns' A/B tests.
+	if typ == "variants" {
+		out := []models.CampaignVariant{}
+		for _, id := range ids {
+			vars, err := app.core.GetCampaignVariants(id)
+			if err != nil {
+				return err
+			}
+			out = append(out, vars...)
+		}
+
+		return c.JSON(http.StatusOK, okResp{out})
+	}
+
 	// View, click, bounce stats.
 	out, err := app.core.GetCampaignAnalyticsCounts(ids, typ, from, to)
 	if err != nil {
@@ -521,6 +957,7 @@ func handleGetCampaignViewAnalytics(c echo.Context) error {
 
 // sendTestMessage takes a campaign and a subscriber and sends out a sample campaign message.
 func sendTestMessage(sub models.Subscriber, camp *models.Campaign, app *App) error {
+	app.manager.SetLinkUTM(camp)
 	if err := camp.CompileTemplate(app.manager.TemplateFuncs(camp)); err != nil {
 		app.log.Printf("error compiling template: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError,
@@ -548,6 +985,10 @@ func validateCampaignFields(c campaignReq, app *App) (campaignReq, error) {
 		}
 	}
 
+	if err := checkFromAddress(c.FromEmail, app); err != nil {
+		return c, err
+	}
+
 	if !strHasLen(c.Name, 1, stdInputMaxLen) {
 		return c, errors.New(app.i18n.T("campaigns.fieldInvalidName"))
 	}
@@ -564,13 +1005,105 @@ func validateCampaignFields(c campaignReq, app *App) (campaignReq, error) {
 		}
 	}
 
+	// Schedules are stored with the time zone they were made in, which
+	// defaults to the reporting time zone.
+	c.SendAtTimezone = strings.TrimSpace(c.SendAtTimezone)
+	if !isTimezone(c.SendAtTimezone) {
+		return c, errors.New(app.i18n.T("campaigns.fieldInvalidTimezone"))
+	}
+	if c.SendAtTimezone == "" && (c.SendAt.Valid || len(c.SendWindows) > 0) {
+		c.SendAtTimezone = app.constants.ReportingTimezone.String()
+	}
+
+	if c.SendRate < 0 || c.SendRate > campMaxSendRate {
+		return c, errors.New(app.i18n.Ts("campaigns.fieldInvalidSendRate", "max", strconv.Itoa(campMaxSendRate)))
+	}
+	if err := validateSendWindows(c.SendWindows, app); err != nil {
+		return c, err
+	}
+
 	if len(c.ListIDs) == 0 {
 		return c, errors.New(app.i18n.T("campaigns.fieldInvalidListIDs"))
 	}
 
+	// A list can't be both targeted and excluded.
+	var (
+		excl = make(pq.Int64Array, 0, len(c.ExcludeListIDs))
+		seen = make(map[int64]bool, len(c.ExcludeListIDs))
+	)
+	for _, id := range c.ExcludeListIDs {
+		if id < 1 {
+			return c, errors.New(app.i18n.T("campaigns.fieldInvalidExcludeLists"))
+		}
+		for _, l := range c.ListIDs {
+			if int64(l) == id {
+				return c, errors.New(app.i18n.T("campaigns.fieldInvalidExcludeLists"))
+			}
+		}
+		if !seen[id] {
+			excl = append(excl, id)
+			seen[id] = true
+		}
+	}
+	c.ExcludeListIDs = excl
+
 	if !app.manager.HasMessenger(c.Messenger) {
 		return c, errors.New(app.i18n.Ts("campaigns.fieldInvalidMessenger", "name", c.Messenger))
 	}
+	if len(c.MediaIDs) > 0 && !app.manager.MessengerSupportsAttachments(c.Messenger) {
+		return c, errors.New(app.i18n.Ts("campaigns.messengerNoAttachments", "name", c.Messenger))
+	}
+
+	c.ReplyTo = strings.TrimSpace(c.ReplyTo)
+	if c.ReplyTo != "" && !regexFromAddress.Match([]byte(c.ReplyTo)) {
+		if _, err := app.importer.SanitizeEmail(c.ReplyTo); err != nil {
+			return c, errors.New(app.i18n.T("campaigns.fieldInvalidReplyTo"))
+		}
+	}
+
+	c.UTMCampaign = strings.TrimSpace(c.UTMCampaign)
+	if len(c.UTMCampaign) > stdInputMaxLen {
+		return c, errors.New(app.i18n.T("campaigns.fieldInvalidUTMCampaign"))
+	}
+
+	c.ReturnPath = strings.ToLower(strings.TrimSpace(c.ReturnPath))
+	if c.ReturnPath != "" && !isValidReturnPath(c.ReturnPath) {
+		return c, errors.New(app.i18n.T("campaigns.fieldInvalidReturnPath"))
+	}
+
+	c.ContentURL = strings.TrimSpace(c.ContentURL)
+	if c.ContentURL != "" {
+		if !isHTTPURL(c.ContentURL) {
+			return c, errors.New(app.i18n.T("campaigns.fieldInvalidContentURL"))
+		}
+	}
+
+	c.FeedURL = strings.TrimSpace(c.FeedURL)
+	if c.FeedURL != "" {
+		if !isHTTPURL(c.FeedURL) {
+			return c, errors.New(app.i18n.T("campaigns.fieldInvalidFeedURL"))
+		}
+	}
+
+	if c.STOWindowHours < 0 || c.STOWindowHours > campMaxSTOWindowHours {
+		return c, errors.New(app.i18n.Ts("campaigns.fieldInvalidSTO", "max", strconv.Itoa(campMaxSTOWindowHours)))
+	}
+
+	// Subscribers of an A/B test's test group are all sent to at once.
+	if c.STOWindowHours > 0 && c.ABTestPercent > 0 {
+		return c, errors.New(app.i18n.T("campaigns.stoUnsupported"))
+	}
+
+	// Local time sending needs a schedule whose wall-clock time is sent at
+	// in the subscribers' time zones.
+	if c.SendAtLocal {
+		if !c.SendAt.Valid {
+			return c, errors.New(app.i18n.T("campaigns.sendAtLocalNoSchedule"))
+		}
+		if c.STOWindowHours > 0 || c.ABTestPercent > 0 || c.Recurrence != "" {
+			return c, errors.New(app.i18n.T("campaigns.sendAtLocalUnsupported"))
+		}
+	}
 
 	camp := models.Campaign{Body: c.Body, TemplateBody: tplTag}
 	if err := c.CompileTemplate(app.manager.TemplateFuncs(&camp)); err != nil {
@@ -581,6 +1114,22 @@ func validateCampaignFields(c campaignReq, app *App) (campaignReq, error) {
 		c.Headers = make([]map[string]string, 0)
 	}
 
+	if c.HeaderPresetID.Valid {
+		if _, err := app.core.GetHeaderPreset(c.HeaderPresetID.Int); err != nil {
+			return c, errors.New(app.i18n.Ts("globals.messages.notFound", "name", "{headerPresets.preset}"))
+		}
+	}
+
+	if err := validateCampaignDependency(c, app); err != nil {
+		return c, err
+	}
+
+	if out, err := validateCampaignRecurrence(c, app); err != nil {
+		return c, err
+	} else {
+		c = out
+	}
+
 	if len(c.ArchiveMeta) == 0 {
 		c.ArchiveMeta = json.RawMessage("{}")
 	}
@@ -600,6 +1149,36 @@ func validateCampaignFields(c campaignReq, app *App) (campaignReq, error) {
 	return c, nil
 }
 
+// validateCampaignDependency validates the campaign a campaign depends on (starts after).
+// The chain of dependencies is walked to reject cycles.
+func validateCampaignDependency(c campaignReq, app *App) error {
+	if !c.DependsOn.Valid {
+		return nil
+	}
+
+	if c.DependsDelayMins < 0 || c.DependsDelayMins > campMaxDependsDelayMins {
+		return errors.New(app.i18n.T("campaigns.fieldInvalidDependsDelay"))
+	}
+
+	id := c.DependsOn.Int
+	for n := 0; n < campMaxDependsDepth; n++ {
+		if id == c.ID {
+			return errors.New(app.i18n.T("campaigns.fieldInvalidDependsOn"))
+		}
+
+		dep, err := app.core.GetCampaign(id, "", "")
+		if err != nil {
+			return errors.New(app.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.campaign}"))
+		}
+		if !dep.DependsOn.Valid {
+			return nil
+		}
+		id = dep.DependsOn.Int
+	}
+
+	return errors.New(app.i18n.T("campaigns.fieldInvalidDependsOn"))
+}
+
 // isCampaignalMutable tells if a campaign's in a state where it's
 // properties can be mutated.
 func isCampaignalMutable(status string) bool {
@@ -647,3 +1226,72 @@ func makeOptinCampaignMessage(o campaignReq, app *App) (campaignReq, error) {
 	o.Body = b.String()
 	return o, nil
 }
+
+// fetchCampaignContent fetches a campaign body from a remote URL, for instance,
+// a page published by a static site generator or a raw file in a git repository.
+func fetchCampaignContent(u string, h *http.Client) ([]byte, error) {
+	resp, err := h.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", u, resp.StatusCode)
+	}
+
+	// Read one byte more than the limit to detect oversized content.
+	b, err := io.ReadAll(io.LimitReader(resp.Body, campContentMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > campContentMaxSize {
+		return nil, fmt.Errorf("content at %s exceeds %d bytes", u, campContentMaxSize)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, fmt.Errorf("content at %s is empty", u)
+	}
+
+	return b, nil
+}
+
+// fetchCampaignFeed fetches and parses a campaign's RSS/Atom feed. If the
+// campaign is a run of a recurring campaign, only the entries published
+// since the previous run are returned.
+func fetchCampaignFeed(c *models.Campaign, h *http.Client) (models.FeedItems, error) {
+	b, err := fetchCampaignContent(c.FeedURL, h)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := feed.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing feed at %s: %v", c.FeedURL, err)
+	}
+
+	if c.FeedSince.Valid {
+		items = feed.Since(items, c.FeedSince.Time)
+	}
+
+	return items, nil
+}
+
+// validateSendWindows validates the days and HH:MM times of a campaign's send windows.
+func validateSendWindows(w models.SendWindows, app *App) error {
+	if len(w) > campMaxSendWindows {
+		return errors.New(app.i18n.Ts("campaigns.fieldInvalidSendWindows", "max", strconv.Itoa(campMaxSendWindows)))
+	}
+
+	for _, s := range w {
+		if len(s.Days) == 0 || !regexSendWindow.MatchString(s.Start) || !regexSendWindow.MatchString(s.End) || s.Start == s.End {
+			return errors.New(app.i18n.Ts("campaigns.fieldInvalidSendWindows", "max", strconv.Itoa(campMaxSendWindows)))
+		}
+		for _, d := range s.Days {
+			if d < 0 || d > 6 {
+				return errors.New(app.i18n.Ts("campaigns.fieldInvalidSendWindows", "max", strconv.Itoa(campMaxSendWindows)))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetCapturedMessages returns messages stored by the capture messenger,
+// or a single message with its body, text, and raw MIME.
+func handleGetCapturedMessages(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		pg  = app.paginator.NewFromURL(c.Request().URL.Query())
+
+		id, _     = strconv.Atoi(c.Param("id"))
+		campID, _ = strconv.Atoi(c.QueryParam("campaign_id"))
+		query     = c.FormValue("query")
+	)
+
+	// Fetch one message.
+	if id > 0 {
+		out, err := app.core.GetCapturedMessage(id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, okResp{out})
+	}
+
+	res, total, err := app.core.QueryCapturedMessages(campID, query, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Query:   query,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetCapturedMessageBody renders the HTML body of a captured message, or
+// with ?raw, returns the raw MIME message as an .eml file.
+func handleGetCapturedMessageBody(c echo.Context) error {
+	var (
+		app    = c.Get("app").(*App)
+		id, _  = strconv.Atoi(c.Param("id"))
+		raw, _ = strconv.ParseBool(c.QueryParam("raw"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCapturedMessage(id)
+	if err != nil {
+		return err
+	}
+
+	if raw {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=message-%d.eml", id))
+		return c.Blob(http.StatusOK, "message/rfc822", []byte(out.Raw))
+	}
+
+	if out.ContentType == models.CampaignContentTypePlain {
+		return c.String(http.StatusOK, out.Body)
+	}
+
+	return c.HTML(http.StatusOK, out.Body)
+}
+
+// handleDeleteCapturedMessages deletes one or more captured messages, or all of them with ?all.
+func handleDeleteCapturedMessages(c echo.Context) error {
+	var (
+		app    = c.Get("app").(*App)
+		pID    = c.Param("id")
+		all, _ = strconv.ParseBool(c.QueryParam("all"))
+		IDs    = []int{}
+	)
+
+	// Is it an /:id call?
+	if pID != "" {
+		id, _ := strconv.Atoi(pID)
+		if id < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+		}
+		IDs = append(IDs, id)
+	} else if !all {
+		// Multiple IDs.
+		i, err := parseStringIDs(c.Request().URL.Query()["id"])
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidID", "error", err.Error()))
+		}
+
+		if len(i) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidID"))
+		}
+		IDs = i
+	}
+
+	if err := app.core.DeleteCapturedMessages(IDs); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	notifTplMention = "comment-mention"
+
+	// maxCommentLen is the maximum length (characters) of a campaign comment.
+	maxCommentLen = 10000
+)
+
+// regexMention matches @username mentions that are not part of e-mail addresses.
+var regexMention = regexp.MustCompile(`(?:^|[^\w.@])@([a-zA-Z0-9_.\-]{1,64})`)
+
+type commentReq struct {
+	ParentID *int   `json:"parent_id"`
+	Body     string `json:"body"`
+}
+
+// mentionNotif is the data of @mention notifications.
+type mentionNotif struct {
+	CampaignID   int
+	CampaignName string
+	CommentID    int
+	Author       string
+	Body         string
+}
+
+// handleGetCampaignComments returns the comments of a campaign.
+func handleGetCampaignComments(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	// Comments are only accessible on campaigns that are accessible (not in the trash).
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	out, err := app.core.GetCampaignComments(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateCampaignComment adds a comment or a reply to a campaign and
+// notifies the users @mentioned in it.
+func handleCreateCampaignComment(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req commentReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	body, err := validateComment(req.Body, app)
+	if err != nil {
+		return err
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	author := getUsername(c)
+	mentions := getMentions(body, author)
+
+	out, err := app.core.InsertCampaignComment(id, req.ParentID, author, body, mentions)
+	if err != nil {
+		return err
+	}
+
+	notifyMentions(mentions, camp.Name, out.ID, out.CampaignID, author, body, app)
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateCampaignComment updates a comment. Only the comment's author can
+// update it. Users newly @mentioned in the update are notified.
+func handleUpdateCampaignComment(c echo.Context) error {
+	var (
+		app          = c.Get("app").(*App)
+		id, _        = strconv.Atoi(c.Param("id"))
+		commentID, _ = strconv.Atoi(c.Param("commentID"))
+	)
+
+	if id < 1 || commentID < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req commentReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	body, err := validateComment(req.Body, app)
+	if err != nil {
+		return err
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	cur, err := app.core.GetCampaignComment(commentID, id)
+	if err != nil {
+		return err
+	}
+
+	author := getUsername(c)
+	mentions := getMentions(body, author)
+
+	out, err := app.core.UpdateCampaignComment(commentID, id, author, body, mentions)
+	if err != nil {
+		return err
+	}
+
+	var newMentions []string
+	for _, m := range mentions {
+		if !inArray(m, cur.Mentions) {
+			newMentions = append(newMentions, m)
+		}
+	}
+	notifyMentions(newMentions, camp.Name, out.ID, out.CampaignID, author, body, app)
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteCampaignComment deletes a comment and its replies. Only the
+// comment's author can delete it.
+func handleDeleteCampaignComment(c echo.Context) error {
+	var (
+		app          = c.Get("app").(*App)
+		id, _        = strconv.Atoi(c.Param("id"))
+		commentID, _ = strconv.Atoi(c.Param("commentID"))
+	)
+
+	if id < 1 || commentID < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	if err := app.core.DeleteCampaignComment(commentID, id, getUsername(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateComment validates and returns the trimmed body of a comment.
+func validateComment(body string, app *App) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.missingFields", "name", "body"))
+	}
+	if utf8.RuneCountInString(body) > maxCommentLen {
+		return "", echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "body"))
+	}
+
+	return body, nil
+}
+
+// getMentions returns the unique usernames @mentioned in a comment
+// excluding the author.
+func getMentions(body, author string) []string {
+	out := []string{}
+	for _, m := range regexMention.FindAllStringSubmatch(body, -1) {
+		// Trailing periods are punctuation, eg: "Thanks @john."
+		u := strings.TrimRight(m[1], ".")
+		if u == "" || u == author || inArray(u, out) {
+			continue
+		}
+		out = append(out, u)
+	}
+
+	return out
+}
+
+// notifyMentions notifies the users @mentioned in a campaign comment.
+func notifyMentions(usernames []string, campName string, commentID, campID int, author, body string, app *App) {
+	if len(usernames) == 0 {
+		return
+	}
+
+	app.notifyUsers(usernames, models.NotificationMention,
+		app.i18n.Ts("comments.mentionSubject", "name", author, "campaign", campName), body,
+		notifTplMention, mentionNotif{
+			CampaignID:   campID,
+			CampaignName: campName,
+			CommentID:    commentID,
+			Author:       author,
+			Body:         body,
+		})
+}
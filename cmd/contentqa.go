@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/internal/contentqa"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleCheckCampaignContent runs the content QA hooks on a campaign and returns
+// the warnings. Optional subject, body, and content_type params in the request
+// are checked instead of the ones in the DB, allowing unsaved content to be checked.
+func handleCheckCampaignContent(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	if v := c.FormValue("subject"); v != "" {
+		camp.Subject = v
+	}
+	if v := c.FormValue("body"); v != "" {
+		camp.Body = v
+		camp.ContentType = c.FormValue("content_type")
+	}
+
+	out, err := runContentQA(camp, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runContentQA posts a campaign's content to the QA hooks configured for its lists
+// (or the global hook for lists that don't have one) and returns the combined warnings.
+// Hooks that fail to respond are reported as warnings and don't block the campaign.
+func runContentQA(camp models.Campaign, app *App) ([]contentqa.Warning, error) {
+	out := []contentqa.Warning{}
+	if !app.constants.ContentQA.Enabled {
+		return out, nil
+	}
+
+	var campLists []contentqa.List
+	if len(camp.Lists) > 0 {
+		if err := json.Unmarshal(camp.Lists, &campLists); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError,
+				app.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.lists}", "error", err.Error()))
+		}
+	}
+
+	ids := make([]int, 0, len(campLists))
+	for _, l := range campLists {
+		ids = append(ids, l.ID)
+	}
+
+	// Collect the unique hooks for the campaign's lists.
+	var (
+		hooks = []string{}
+		seen  = map[string]bool{}
+	)
+	if len(ids) > 0 {
+		lists, err := app.core.GetListsByOptin(ids, "")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range lists {
+			u := l.ContentQAURL
+			if u == "" {
+				u = app.constants.ContentQA.URL
+			}
+			if u != "" && !seen[u] {
+				seen[u] = true
+				hooks = append(hooks, u)
+			}
+		}
+	} else if app.constants.ContentQA.URL != "" {
+		hooks = append(hooks, app.constants.ContentQA.URL)
+	}
+
+	content := contentqa.Content{
+		CampaignID:  camp.ID,
+		UUID:        camp.UUID,
+		Name:        camp.Name,
+		Subject:     camp.Subject,
+		FromEmail:   camp.FromEmail,
+		ContentType: camp.ContentType,
+		Body:        camp.Body,
+		AltBody:     camp.AltBody.String,
+		Lists:       campLists,
+	}
+
+	for _, u := range hooks {
+		ws, err := app.contentQA.Check(u, content)
+		if err != nil {
+			app.log.Printf("error running content QA hook (%s): %v", u, err)
+			out = append(out, contentqa.Warning{
+				Type:     "hook",
+				Severity: contentqa.SeverityWarning,
+				Message:  app.i18n.Ts("campaigns.qaHookError", "error", err.Error()),
+			})
+			continue
+		}
+
+		out = append(out, ws...)
+	}
+
+	return out, nil
+}
+
+// preflightContentQA runs the content QA hooks on a campaign before it's started
+// or scheduled and returns an error if any of the hooks report an error.
+func preflightContentQA(id int, app *App) error {
+	if !app.constants.ContentQA.Enabled {
+		return nil
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	ws, err := runContentQA(camp, app)
+	if err != nil {
+		return err
+	}
+	if !contentqa.HasErrors(ws) {
+		return nil
+	}
+
+	msgs := []string{}
+	for _, w := range ws {
+		if w.Severity == contentqa.SeverityError {
+			msgs = append(msgs, w.Message)
+		}
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest,
+		app.i18n.Ts("campaigns.qaFailed", "error", strings.Join(msgs, "; ")))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/knadh/listmonk/internal/dnscheck"
+	"github.com/knadh/listmonk/internal/tlsrpt"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// dnsCheckTimeout is the timeout for a single DNS lookup in the deliverability checks.
+	dnsCheckTimeout = time.Second * 5
+
+	// tlsReportMaxSize is the max. size of an uploaded TLS report file.
+	tlsReportMaxSize = 10 * 1024 * 1024
+)
+
+// dnsCheckRunning indicates whether a DNS deliverability check is in progress.
+var dnsCheckRunning atomic.Bool
+
+// handleGetDNSChecks returns the latest DNS deliverability check results.
+func handleGetDNSChecks(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetDNSChecks()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRunDNSChecks runs the DNS deliverability checks and returns the results.
+func handleRunDNSChecks(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := runDNSChecks(app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runDNSChecks validates the SPF, DKIM, DMARC, and MX records of the configured
+// sending domains (or the domain of the default from address if there are none)
+// and the tracking domain CNAME, and saves the results.
+func runDNSChecks(app *App) ([]models.DNSCheck, error) {
+	if !dnsCheckRunning.CompareAndSwap(false, true) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("settings.deliverability.running"))
+	}
+	defer dnsCheckRunning.Store(false)
+
+	var (
+		d       = app.constants.Deliverability
+		checker = dnscheck.New(dnsCheckTimeout)
+		out     = []models.DNSCheck{}
+	)
+
+	for _, dom := range sendingDomains(app) {
+		out = append(out, makeDNSCheck(dom.Domain, checker.CheckDomain(dom.Domain, dom.DKIMSelectors)))
+	}
+
+	// Tracking domain (root URL) CNAME.
+	if u, err := url.Parse(app.constants.RootURL); err == nil {
+		if h := u.Hostname(); h != "" && h != "localhost" && net.ParseIP(h) == nil {
+			out = append(out, makeDNSCheck(h, []dnscheck.Result{checker.CheckCNAME(h, d.TrackingCNAME)}))
+		}
+	}
+
+	return app.core.SetDNSChecks(out)
+}
+
+// handleGetTLSReports returns the TLS reports of the last n days.
+func handleGetTLSReports(c echo.Context) error {
+	var (
+		app     = c.Get("app").(*App)
+		days, _ = strconv.Atoi(c.QueryParam("days"))
+	)
+
+	if days < 1 || days > 365 {
+		days = 30
+	}
+
+	out, err := app.core.GetTLSReports(days)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleImportTLSReports imports uploaded SMTP TLS reports (TLS-RPT). Each file
+// can be a JSON report, a gzipped JSON report, or the raw e-mail the report was
+// delivered in. Only the results of the sending domains are saved.
+func handleImportTLSReports(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("globals.messages.invalidData", "error", err.Error()))
+	}
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("globals.messages.invalidData", "error", "file"))
+	}
+
+	var (
+		domains = sendingDomains(app)
+		out     = struct {
+			Imported int `json:"imported"`
+			Skipped  int `json:"skipped"`
+		}{}
+	)
+	for _, f := range files {
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(io.LimitReader(src, tlsReportMaxSize))
+		src.Close()
+		if err != nil {
+			return err
+		}
+
+		reports, err := tlsrpt.Parse(b)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("settings.deliverability.invalidTLSReport", "name", f.Filename, "error", err.Error()))
+		}
+
+		for _, r := range reports {
+			for _, p := range r.Policies {
+				dom := strings.TrimSuffix(strings.ToLower(p.Policy.Domain), ".")
+				if !isSendingDomain(dom, domains) {
+					out.Skipped++
+					continue
+				}
+
+				fails, _ := json.Marshal(p.FailureDetails)
+				if p.FailureDetails == nil {
+					fails = []byte("[]")
+				}
+
+				ok, err := app.core.InsertTLSReport(models.TLSReport{
+					ReportID:     r.ReportID,
+					Organization: r.OrganizationName,
+					PolicyDomain: dom,
+					PolicyType:   p.Policy.Type,
+					StartDate:    r.DateRange.Start,
+					EndDate:      r.DateRange.End,
+					SuccessCount: p.Summary.Successful,
+					FailureCount: p.Summary.Failed,
+					Failures:     fails,
+				})
+				if err != nil {
+					return err
+				}
+				if ok {
+					out.Imported++
+				} else {
+					out.Skipped++
+				}
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// sendingDomains returns the configured sending domains, or the domain of the
+// default from address if there are none.
+func sendingDomains(app *App) []deliverabilityDomain {
+	if d := app.constants.Deliverability.Domains; len(d) > 0 {
+		return d
+	}
+
+	if dom := getEmailDomain(app.constants.FromEmail); dom != "" {
+		return []deliverabilityDomain{{Domain: dom}}
+	}
+
+	return nil
+}
+
+// isSendingDomain checks whether a domain is one of the sending domains or a
+// subdomain of one.
+func isSendingDomain(dom string, domains []deliverabilityDomain) bool {
+	for _, d := range domains {
+		if dom == d.Domain || strings.HasSuffix(dom, "."+d.Domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// makeDNSCheck returns the check of a domain with the worst status of its results.
+func makeDNSCheck(domain string, res []dnscheck.Result) models.DNSCheck {
+	status := dnscheck.StatusPass
+	for _, r := range res {
+		if r.Status == dnscheck.StatusFail {
+			status = dnscheck.StatusFail
+			break
+		}
+		if r.Status == dnscheck.StatusWarn {
+			status = dnscheck.StatusWarn
+		}
+	}
+
+	b, _ := json.Marshal(res)
+	return models.DNSCheck{Domain: domain, Status: status, Results: b}
+}
+
+// getEmailDomain returns the domain of an e-mail address, eg: "Name <a@site.com>".
+func getEmailDomain(s string) string {
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return ""
+	}
+
+	at := strings.LastIndex(a.Address, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return strings.ToLower(a.Address[at+1:])
+}
+
+// validateDeliverabilityDomains cleans up the sending domains and their DKIM selectors in the settings.
+func validateDeliverabilityDomains(set *models.Settings) error {
+	for i, d := range set.DeliverabilityDomains {
+		d.Domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d.Domain)), ".")
+		if d.Domain == "" || strings.ContainsAny(d.Domain, " @/") {
+			return errors.New(d.Domain)
+		}
+
+		sels := make([]string, 0, len(d.DKIMSelectors))
+		for _, s := range d.DKIMSelectors {
+			if s = strings.TrimSpace(s); s != "" {
+				sels = append(sels, s)
+			}
+		}
+		d.DKIMSelectors = sels
+
+		set.DeliverabilityDomains[i] = d
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/internal/dnscheck"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// domainVerifyPrefix is the subdomain on which the TXT record with a sending
+// domain's verification token is published, eg: _listmonk.site.com.
+const domainVerifyPrefix = "_listmonk."
+
+// handleGetSendingDomains returns the sending domain registry.
+func handleGetSendingDomains(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetSendingDomains()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateSendingDomain adds a domain to the sending domain registry.
+// It has to be verified before it can be used in campaigns.
+func handleCreateSendingDomain(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.SendingDomain
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	o.Domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(o.Domain)), ".")
+	if !regexpDomain.MatchString(o.Domain) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "domain"))
+	}
+
+	addrs, err := validateDomainAddresses(o.Domain, o.Addresses, app)
+	if err != nil {
+		return err
+	}
+	o.Addresses = addrs
+
+	tok, err := generateRandomString(32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	o.Token = "listmonk-verification=" + tok
+
+	out, err := app.core.CreateSendingDomain(o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateSendingDomain updates the allowed from addresses of a sending domain.
+func handleUpdateSendingDomain(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var o models.SendingDomain
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	cur, err := app.core.GetSendingDomain(id)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := validateDomainAddresses(cur.Domain, o.Addresses, app)
+	if err != nil {
+		return err
+	}
+
+	out, err := app.core.UpdateSendingDomain(id, addrs)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleVerifySendingDomain verifies the ownership of a sending domain by looking up
+// its verification token in the TXT records of _listmonk.{domain}.
+func handleVerifySendingDomain(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	d, err := app.core.GetSendingDomain(id)
+	if err != nil {
+		return err
+	}
+
+	res := dnscheck.New(dnsCheckTimeout).CheckTXT(domainVerifyPrefix+d.Domain, d.Token)
+	out, err := app.core.SetSendingDomainVerified(id, res.Status == dnscheck.StatusPass)
+	if err != nil {
+		return err
+	}
+
+	if !out.Verified {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("domains.verifyFailed", "name", domainVerifyPrefix+d.Domain, "error", res.Message))
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteSendingDomain removes a domain from the sending domain registry.
+func handleDeleteSendingDomain(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteSendingDomain(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateDomainAddresses validates and lowercases the allowed from addresses of
+// a sending domain which should all be on the domain.
+func validateDomainAddresses(domain string, addrs []string, app *App) ([]string, error) {
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || inArray(a, out) {
+			continue
+		}
+
+		if em, err := app.importer.SanitizeEmail(a); err != nil || em != a || getEmailDomain(a) != domain {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("domains.invalidAddress", "name", a, "domain", domain))
+		}
+		out = append(out, a)
+	}
+
+	return out, nil
+}
+
+// checkFromAddress checks a campaign's from address against the sending domain
+// registry. If the registry is empty, any from address is allowed. Otherwise, the
+// address should be on a verified domain and be one of the domain's allowed
+// addresses, if it has any.
+func checkFromAddress(from string, app *App) error {
+	domains, err := app.core.GetSendingDomains()
+	if err != nil {
+		return err
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+
+	a, err := mail.ParseAddress(from)
+	if err != nil {
+		return errors.New(app.i18n.T("campaigns.fieldInvalidFromEmail"))
+	}
+
+	var (
+		addr = strings.ToLower(a.Address)
+		dom  = getEmailDomain(from)
+	)
+	for _, d := range domains {
+		if d.Domain != dom {
+			continue
+		}
+
+		if !d.Verified {
+			return errors.New(app.i18n.Ts("domains.notVerified", "name", dom))
+		}
+		if len(d.Addresses) > 0 && !inArray(addr, d.Addresses) {
+			return errors.New(app.i18n.Ts("domains.addressNotAllowed", "name", addr))
+		}
+
+		return nil
+	}
+
+	return errors.New(app.i18n.Ts("domains.domainNotAllowed", "name", dom))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/knadh/listmonk/internal/entitlement"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// entitlementBatchSize is the number of subscribers sent to an entitlement hook in one request.
+const entitlementBatchSize = 500
+
+// entitlementRunning indicates whether an entitlement check run is in progress.
+var entitlementRunning atomic.Bool
+
+// handleRunEntitlementChecks re-checks the entitlements of the subscribers of all
+// premium lists in the background instead of waiting for the next scheduled run.
+func handleRunEntitlementChecks(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if !app.constants.Entitlement.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.entitlementDisabled"))
+	}
+	if entitlementRunning.Load() {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.entitlementRunning"))
+	}
+
+	go func() {
+		if _, err := runEntitlementChecks(app); err != nil {
+			app.log.Printf("error running entitlement checks: %v", err)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// checkListAccess checks whether a public subscription to the given lists is allowed.
+// Lists that have reached their subscriber cap, and premium lists whose entitlement
+// hook doesn't entitle the e-mail, reject the subscription. Hooks that fail to
+// respond also reject it.
+func checkListAccess(listUUIDs []string, email, name string, app *App) error {
+	lists, err := app.core.GetListsByIDs(nil, listUUIDs)
+	if err != nil {
+		return err
+	}
+
+	// Subscriber caps.
+	capped := []int{}
+	for _, l := range lists {
+		if l.MaxSubscribers > 0 {
+			capped = append(capped, l.ID)
+		}
+	}
+	if len(capped) > 0 {
+		counts, err := app.core.GetListCapCounts(capped, email)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range lists {
+			if l.MaxSubscribers > 0 && counts[l.ID] >= l.MaxSubscribers {
+				return echo.NewHTTPError(http.StatusForbidden, app.i18n.Ts("public.listFull", "name", l.Name))
+			}
+		}
+	}
+
+	if !app.constants.Entitlement.Enabled {
+		return nil
+	}
+
+	// Entitlement hooks.
+	for _, l := range lists {
+		if l.EntitlementURL == "" {
+			continue
+		}
+
+		ok, err := app.entitlement.Check(l.EntitlementURL, entitlementList(l), []entitlement.Subscriber{{Email: email, Name: name}})
+		if err != nil {
+			app.log.Printf("error checking entitlement for list %s (%s): %v", l.Name, l.EntitlementURL, err)
+			return echo.NewHTTPError(http.StatusServiceUnavailable, app.i18n.T("public.errorProcessingRequest"))
+		}
+		if !ok[strings.ToLower(email)] {
+			return echo.NewHTTPError(http.StatusForbidden, app.i18n.Ts("public.notEntitled", "name", l.Name))
+		}
+	}
+
+	return nil
+}
+
+// runEntitlementChecks sends the subscribers of all premium lists to the lists'
+// entitlement hooks in batches and unsubscribes the ones whose entitlements have
+// lapsed. It returns the number of subscriptions that were unsubscribed. If a hook
+// fails to respond, the remaining subscribers of its list are left as-is.
+func runEntitlementChecks(app *App) (int, error) {
+	if !entitlementRunning.CompareAndSwap(false, true) {
+		return 0, errors.New(app.i18n.T("lists.entitlementRunning"))
+	}
+	defer entitlementRunning.Store(false)
+
+	lists, err := app.core.GetLists("")
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, l := range lists {
+		if l.EntitlementURL == "" {
+			continue
+		}
+
+		n, err := checkListEntitlements(l, app)
+		total += n
+		if err != nil {
+			app.log.Printf("error checking entitlements for list %s: %v", l.Name, err)
+			continue
+		}
+	}
+
+	app.log.Printf("entitlement checks: unsubscribed %d subscriptions", total)
+	return total, nil
+}
+
+// checkListEntitlements checks the entitlements of a premium list's subscribers and
+// unsubscribes the ones that aren't entitled. It returns the number of unsubscriptions.
+func checkListEntitlements(l models.List, app *App) (int, error) {
+	var (
+		lastID = 0
+		total  = 0
+	)
+	for {
+		subs, err := app.core.GetEntitledSubscribers(l.ID, lastID, entitlementBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(subs) == 0 {
+			return total, nil
+		}
+		lastID = subs[len(subs)-1].ID
+
+		batch := make([]entitlement.Subscriber, 0, len(subs))
+		for _, s := range subs {
+			attribs, _ := json.Marshal(s.Attribs)
+			batch = append(batch, entitlement.Subscriber{
+				UUID:    s.UUID,
+				Email:   s.Email,
+				Name:    s.Name,
+				Attribs: attribs,
+			})
+		}
+
+		ok, err := app.entitlement.Check(l.EntitlementURL, entitlementList(l), batch)
+		if err != nil {
+			return total, err
+		}
+
+		lapsed := []int{}
+		for _, s := range subs {
+			if !ok[strings.ToLower(s.Email)] {
+				lapsed = append(lapsed, s.ID)
+			}
+		}
+		if len(lapsed) > 0 {
+			if err := app.core.UnsubscribeLists(lapsed, []int{l.ID}, nil); err != nil {
+				return total, err
+			}
+			total += len(lapsed)
+		}
+
+		if len(subs) < entitlementBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func entitlementList(l models.List) entitlement.List {
+	return entitlement.List{ID: l.ID, UUID: l.UUID, Name: l.Name}
+}
@@ -47,6 +47,4 @@ func handleEventStream(c echo.Context) error {
 			return nil
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/knadh/listmonk/internal/ga4"
+)
+
+// pushGA4Event forwards a campaign open or click to Google Analytics 4, if it's enabled.
+func pushGA4Event(name, campUUID, subUUID, url string, app *App) {
+	if app.ga4 == nil || campUUID == dummyUUID {
+		return
+	}
+
+	// The events of a subscriber are grouped under a pseudonymous client ID
+	// derived from their UUID. Without individual tracking, every event
+	// is from a new client.
+	var clientID string
+	if subUUID != "" && subUUID != dummyUUID {
+		sum := sha256.Sum256([]byte(subUUID))
+		clientID = hex.EncodeToString(sum[:16])
+	} else {
+		uu, err := uuid.NewV4()
+		if err != nil {
+			return
+		}
+		clientID = uu.String()
+	}
+
+	if !app.ga4.Push(ga4.Event{Name: name, ClientID: clientID, CampaignUUID: campUUID, URL: url}) {
+		app.log.Printf("GA4 event queue is full. dropping %s event", name)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Query param with which the signed goal token of the campaign and subscriber
+	// is passed on to the landing pages of page visit and conversion goals.
+	goalParamToken = "lm_g"
+
+	// maxCampaignGoals is the maximum number of goals (funnel steps) in a campaign.
+	maxCampaignGoals = 10
+)
+
+var regexGoalKey = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+
+// handleGetCampaignGoals returns the ordered goals of a campaign.
+func handleGetCampaignGoals(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignGoals(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateCampaignGoals replaces the goals of a campaign with the ordered set in the request.
+func handleUpdateCampaignGoals(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var goals []models.CampaignGoal
+	if err := c.Bind(&goals); err != nil {
+		return err
+	}
+
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	goals, err := validateCampaignGoals(goals, app)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	out, err := app.core.SetCampaignGoals(id, goals)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetCampaignGoalFunnel returns the funnel report of a campaign's goals.
+func handleGetCampaignGoalFunnel(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignGoalFunnel(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRegisterGoalPixel records a page visit goal which comes in the form of a
+// pixel image request from a campaign's landing page. Regardless of errors, this
+// handler always renders the pixel image bytes.
+func handleRegisterGoalPixel(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if err := registerGoalEvent(c, app); err != nil {
+		app.log.Printf("error registering campaign goal: %s", err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	return c.Blob(http.StatusOK, "image/png", pixelPNG)
+}
+
+// handleRegisterGoalConversion records a conversion goal, for instance, posted
+// by a landing page or an external system after a purchase or a signup.
+func handleRegisterGoalConversion(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if err := registerGoalEvent(c, app); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// registerGoalEvent records a page visit or conversion goal event given
+// the signed goal token and the goal key in the request URI.
+func registerGoalEvent(c echo.Context, app *App) error {
+	key := c.Param("key")
+	if !regexGoalKey.MatchString(key) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "key"))
+	}
+
+	campID, subID, ok := parseGoalToken(c.Param("token"), app.constants.Security.SigningKey)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "token"))
+	}
+
+	// If individual tracking is disabled, do not record the subscriber ID.
+	if !app.constants.Privacy.IndividualTracking {
+		subID = 0
+	}
+
+	return app.core.RegisterCampaignGoalEvent(campID, subID, key)
+}
+
+// validateCampaignGoals validates and sanitizes a campaign's goals.
+func validateCampaignGoals(goals []models.CampaignGoal, app *App) ([]models.CampaignGoal, error) {
+	if len(goals) > maxCampaignGoals {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.goalsTooMany", "num", strconv.Itoa(maxCampaignGoals)))
+	}
+
+	keys := map[string]bool{}
+	for i, g := range goals {
+		g.Name = strings.TrimSpace(g.Name)
+		if !strHasLen(g.Name, 1, stdInputMaxLen) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+		}
+
+		switch g.Type {
+		case models.GoalTypeLink:
+			g.URL = strings.TrimSpace(g.URL)
+			g.Key = ""
+			if !isHTTPURL(g.URL) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "url"))
+			}
+
+		case models.GoalTypePixel, models.GoalTypeConversion:
+			// The optional landing page to which the goal token is passed on.
+			g.URL = strings.TrimSpace(g.URL)
+			if g.URL != "" && !isHTTPURL(g.URL) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "url"))
+			}
+
+			g.Key = strings.TrimSpace(g.Key)
+			if !regexGoalKey.MatchString(g.Key) || keys[g.Key] {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "key"))
+			}
+			keys[g.Key] = true
+
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "type"))
+		}
+
+		goals[i] = g
+	}
+
+	return goals, nil
+}
+
+// appendGoalToken adds the signed goal token of a campaign and subscriber to a
+// link's destination URL if it's one of the campaign's goal landing pages so that
+// the page can record page visit and conversion goals. The token is scoped to goals
+// and doesn't carry the subscriber's UUID, which grants access to their preferences.
+func appendGoalToken(u string, goalURLs []string, campID, subID int, key string) string {
+	p, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	ok := false
+	for _, g := range goalURLs {
+		if matchGoalURL(p, g) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return u
+	}
+
+	// Append to the existing query as-is without re-encoding it.
+	q := url.Values{}
+	q.Set(goalParamToken, makeGoalToken(campID, subID, key))
+	if p.RawQuery != "" {
+		p.RawQuery += "&"
+	}
+	p.RawQuery += q.Encode()
+
+	return p.String()
+}
+
+// matchGoalURL checks whether a URL is on the same host as a goal's landing
+// page URL and its path is (under) the landing page's path.
+func matchGoalURL(u *url.URL, goalURL string) bool {
+	g, err := url.Parse(goalURL)
+	if err != nil || g.Host == "" {
+		return false
+	}
+
+	if !strings.EqualFold(u.Scheme, g.Scheme) || !strings.EqualFold(u.Host, g.Host) {
+		return false
+	}
+
+	path := strings.TrimSuffix(g.Path, "/")
+	return u.Path == path || strings.HasPrefix(u.Path, path+"/")
+}
+
+// makeGoalToken returns the signed token with which campaign goal events are
+// recorded for a campaign and an (optional) subscriber.
+func makeGoalToken(campID, subID int, key string) string {
+	s := fmt.Sprintf("%d.%d", campID, subID)
+	return base64.RawURLEncoding.EncodeToString([]byte(s)) + "." + signString("campaign-goal:"+s, key)
+}
+
+// parseGoalToken verifies a goal token and returns the campaign and subscriber IDs in it.
+func parseGoalToken(token, key string) (int, int, bool) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, 0, false
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	s := string(b)
+	if !hmac.Equal([]byte(sig), []byte(signString("campaign-goal:"+s, key))) {
+		return 0, 0, false
+	}
+
+	c, sub, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0, 0, false
+	}
+	campID, err1 := strconv.Atoi(c)
+	subID, err2 := strconv.Atoi(sub)
+	if err1 != nil || err2 != nil || campID < 1 || subID < 0 {
+		return 0, 0, false
+	}
+
+	return campID, subID, true
+}
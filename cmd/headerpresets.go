@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxPresetHeaders is the maximum number of headers in a header preset.
+const maxPresetHeaders = 50
+
+var (
+	// regexHeaderName matches valid header field names (RFC 5322).
+	regexHeaderName = regexp.MustCompile("^[!-9;-~]+$")
+
+	// presetHeaderDenylist are headers that can't be set by presets as they're
+	// either set by listmonk per message or alter the structure, routing,
+	// or authentication of messages.
+	presetHeaderDenylist = map[string]bool{
+		"From":                           true,
+		"Sender":                         true,
+		"To":                             true,
+		"Cc":                             true,
+		"Bcc":                            true,
+		"Subject":                        true,
+		"Date":                           true,
+		"Message-Id":                     true,
+		"In-Reply-To":                    true,
+		"References":                     true,
+		"Mime-Version":                   true,
+		"Content-Type":                   true,
+		"Content-Transfer-Encoding":      true,
+		"Content-Disposition":            true,
+		"Return-Path":                    true,
+		"Received":                       true,
+		"Dkim-Signature":                 true,
+		"Arc-Seal":                       true,
+		"Arc-Message-Signature":          true,
+		"Arc-Authentication-Results":     true,
+		"Authentication-Results":         true,
+		"List-Unsubscribe":               true,
+		"List-Unsubscribe-Post":          true,
+		"Bounces-To":                     true,
+		models.EmailHeaderCampaignUUID:   true,
+		models.EmailHeaderSubscriberUUID: true,
+	}
+)
+
+// handleGetHeaderPresets returns all header presets.
+func handleGetHeaderPresets(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetHeaderPresets()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateHeaderPreset creates a header preset.
+func handleCreateHeaderPreset(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.HeaderPreset
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateHeaderPreset(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.CreateHeaderPreset(o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateHeaderPreset updates a header preset.
+func handleUpdateHeaderPreset(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var o models.HeaderPreset
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateHeaderPreset(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.UpdateHeaderPreset(id, o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteHeaderPreset deletes a header preset.
+func handleDeleteHeaderPreset(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteHeaderPreset(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateHeaderPreset validates and sanitizes a header preset's fields. Header
+// names are canonicalized and checked against the denylist, and values can't
+// contain line breaks.
+func validateHeaderPreset(o *models.HeaderPreset, app *App) error {
+	o.Name = strings.TrimSpace(o.Name)
+	if !strHasLen(o.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+
+	if len(o.Headers) == 0 || len(o.Headers) > maxPresetHeaders {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "headers"))
+	}
+
+	out := make(models.Headers, 0, len(o.Headers))
+	for _, set := range o.Headers {
+		h := make(map[string]string, len(set))
+		for k, v := range set {
+			k = strings.TrimSpace(k)
+			if !regexHeaderName.MatchString(k) || len(k) > 200 {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("headerPresets.invalidHeader", "name", k))
+			}
+
+			k = textproto.CanonicalMIMEHeaderKey(k)
+			if presetHeaderDenylist[k] {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("headerPresets.deniedHeader", "name", k))
+			}
+
+			v = strings.TrimSpace(v)
+			if strings.ContainsAny(v, "\r\n") || len(v) > 1000 {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("headerPresets.invalidHeader", "name", k))
+			}
+
+			h[k] = v
+		}
+
+		if len(h) > 0 {
+			out = append(out, h)
+		}
+	}
+	o.Headers = out
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// hygieneDNSWorkers is the number of concurrent DNS lookups when checking for dead domains.
+	hygieneDNSWorkers = 20
+
+	// hygieneDNSTimeout is the timeout for the DNS lookups of a single domain.
+	hygieneDNSTimeout = time.Second * 5
+)
+
+// hygieneRunning indicates whether a list hygiene run is in progress.
+var hygieneRunning atomic.Bool
+
+// handleGetListHygiene returns the list hygiene report of a single list (ID in the URI)
+// or all lists.
+func handleGetListHygiene(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	out, err := app.core.GetListHygiene(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRunListHygiene regenerates the list hygiene report in the background
+// instead of waiting for the next scheduled run.
+func handleRunListHygiene(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if !app.constants.Hygiene.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.hygieneDisabled"))
+	}
+	if hygieneRunning.Load() {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.hygieneRunning"))
+	}
+
+	go func() {
+		if _, err := runListHygiene(app); err != nil {
+			app.log.Printf("error running list hygiene: %v", err)
+		}
+	}()
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleListHygieneAction unsubscribes or blocklists the subscribers in
+// a hygiene category of a list.
+func handleListHygieneAction(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req struct {
+		Category string `json:"category"`
+		Action   string `json:"action"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	switch req.Category {
+	case models.HygieneCategoryInvalidSyntax, models.HygieneCategoryRoleAccount,
+		models.HygieneCategoryDeadDomain, models.HygieneCategorySoftBouncer:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "category"))
+	}
+
+	if req.Action != models.HygieneActionUnsubscribe && req.Action != models.HygieneActionBlocklist {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "action"))
+	}
+
+	n, err := app.core.ApplyListHygieneAction(id, req.Category, req.Action)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// runListHygiene regenerates the list hygiene report of all lists and returns the
+// number of entries in it. If domain checks are enabled, the e-mail domains of all
+// subscribers are looked up on DNS to find domains that can't receive e-mails.
+func runListHygiene(app *App) (int, error) {
+	if !hygieneRunning.CompareAndSwap(false, true) {
+		return 0, errors.New(app.i18n.T("lists.hygieneRunning"))
+	}
+	defer hygieneRunning.Store(false)
+
+	h := app.constants.Hygiene
+
+	dead := []string{}
+	if h.CheckDomains {
+		domains, err := app.core.GetSubscriberDomains()
+		if err != nil {
+			return 0, err
+		}
+		dead = findDeadDomains(domains)
+	}
+
+	n, err := app.core.RefreshListHygiene(app.constants.Privacy.RoleAccounts, dead, h.SoftBounceCount, h.SoftBounceDays)
+	if err != nil {
+		return 0, err
+	}
+
+	app.log.Printf("list hygiene: %d entries, %d dead domains", n, len(dead))
+	return n, nil
+}
+
+// findDeadDomains looks up the given domains concurrently and returns
+// the ones that can't receive e-mails.
+func findDeadDomains(domains []string) []string {
+	var (
+		out = []string{}
+		ch  = make(chan string)
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+	)
+
+	for i := 0; i < hygieneDNSWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for d := range ch {
+				if isDeadDomain(d) {
+					mu.Lock()
+					out = append(out, d)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, d := range domains {
+		if d != "" {
+			ch <- d
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	return out
+}
+
+// isDeadDomain checks whether a domain has neither MX records nor an A/AAAA record
+// (implicit MX), or has a null MX (RFC 7505). Lookups that fail for reasons other
+// than the domain not existing (eg: timeouts) are not considered dead.
+func isDeadDomain(d string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), hygieneDNSTimeout)
+	defer cancel()
+
+	mx, err := net.DefaultResolver.LookupMX(ctx, d)
+	if err == nil && len(mx) > 0 {
+		return len(mx) == 1 && mx[0].Host == "."
+	}
+	if err != nil && !isDNSNotFound(err) {
+		return false
+	}
+
+	_, err = net.DefaultResolver.LookupHost(ctx, d)
+	return isDNSNotFound(err)
+}
+
+func isDNSNotFound(err error) bool {
+	var e *net.DNSError
+	return errors.As(err, &e) && e.IsNotFound
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/knadh/listmonk/internal/subimporter"
 	"github.com/knadh/listmonk/models"
@@ -50,10 +51,30 @@ func handleImportSubscribers(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("import.invalidSubStatus"))
 	}
 
+	// Imports from trusted sources are pre-confirmed.
+	opt.ConfirmedBy = confirmedByImport
+	if src, ok := getTrustedSource(c, app); ok && opt.Mode == subimporter.ModeSubscribe {
+		opt.SubStatus = models.SubscriptionStatusConfirmed
+		opt.ConfirmedBy = src
+	}
+
+	if opt.Mode == subimporter.ModeSubscribe {
+		if err := checkSubscriberQuota(getUsername(c), app); err != nil {
+			return err
+		}
+	}
+
 	if len(opt.Delim) != 1 {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("import.invalidDelim"))
 	}
 
+	// Address filter of the lists being imported into.
+	mode, err := getAddressFilter(opt.ListIDs, nil, app)
+	if err != nil {
+		return err
+	}
+	opt.AddressFilter = mode
+
 	file, err := c.FormFile("file")
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
@@ -80,6 +101,7 @@ func handleImportSubscribers(c echo.Context) error {
 
 	// Start the importer session.
 	opt.Filename = file.Filename
+	opt.Source = subSourceImport + time.Now().Format("20060102T150405") + ":" + file.Filename
 	impSess, err := app.importer.NewSession(opt)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError,
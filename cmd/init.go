@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,14 +33,28 @@ import (
 	"github.com/knadh/listmonk/internal/bounce"
 	"github.com/knadh/listmonk/internal/bounce/mailbox"
 	"github.com/knadh/listmonk/internal/captcha"
+	"github.com/knadh/listmonk/internal/cdc"
+	"github.com/knadh/listmonk/internal/clamav"
+	"github.com/knadh/listmonk/internal/contentqa"
 	"github.com/knadh/listmonk/internal/core"
+	"github.com/knadh/listmonk/internal/entitlement"
+	"github.com/knadh/listmonk/internal/events"
+	"github.com/knadh/listmonk/internal/ga4"
+	"github.com/knadh/listmonk/internal/httpscan"
 	"github.com/knadh/listmonk/internal/i18n"
+	"github.com/knadh/listmonk/internal/ipfilter"
+	"github.com/knadh/listmonk/internal/lockout"
 	"github.com/knadh/listmonk/internal/manager"
 	"github.com/knadh/listmonk/internal/media"
 	"github.com/knadh/listmonk/internal/media/providers/filesystem"
 	"github.com/knadh/listmonk/internal/media/providers/s3"
 	"github.com/knadh/listmonk/internal/messenger/email"
 	"github.com/knadh/listmonk/internal/messenger/postback"
+	"github.com/knadh/listmonk/internal/passwd"
+	"github.com/knadh/listmonk/internal/previews"
+	"github.com/knadh/listmonk/internal/ratelimit"
+	"github.com/knadh/listmonk/internal/reputation"
+	"github.com/knadh/listmonk/internal/spamcheck"
 	"github.com/knadh/listmonk/internal/subimporter"
 	"github.com/knadh/listmonk/models"
 	"github.com/knadh/stuffbin"
@@ -52,8 +68,19 @@ const (
 
 	// Root URI of the admin frontend.
 	adminRoot = "/admin"
+
+	// Failed login attempts older than this don't count towards a lockout,
+	// and the max. duration that doubling lockouts can go up to.
+	loginAttemptWindow = time.Minute * 15
+	loginMaxLockout    = time.Hour * 24
 )
 
+// deliverabilityDomain is a sending domain whose DNS records are checked.
+type deliverabilityDomain struct {
+	Domain        string   `koanf:"domain"`
+	DKIMSelectors []string `koanf:"dkim_selectors"`
+}
+
 // constants contains static, constant config values required by the app.
 type constants struct {
 	SiteName                      string   `koanf:"site_name"`
@@ -68,21 +95,121 @@ type constants struct {
 	SendOptinConfirmation         bool     `koanf:"send_optin_confirmation"`
 	Lang                          string   `koanf:"lang"`
 	DBBatchSize                   int      `koanf:"batch_size"`
+	TrashRetentionDays            int      `koanf:"trash_retention_days"`
+	CaptureMode                   bool     `koanf:"capture_mode"`
 	Privacy                       struct {
 		IndividualTracking bool            `koanf:"individual_tracking"`
+		AnonymousLinks     bool            `koanf:"anonymous_links"`
 		AllowPreferences   bool            `koanf:"allow_preferences"`
 		AllowBlocklist     bool            `koanf:"allow_blocklist"`
 		AllowExport        bool            `koanf:"allow_export"`
 		AllowWipe          bool            `koanf:"allow_wipe"`
 		RecordOptinIP      bool            `koanf:"record_optin_ip"`
+		AllowSnooze        bool            `koanf:"allow_snooze"`
+		OptinReplyAddress  string          `koanf:"optin_reply_address"`
+		ExportLinkExpiry   time.Duration   `koanf:"export_link_expiry"`
+		ConfirmEmailChange bool            `koanf:"confirm_email_change"`
 		Exportable         map[string]bool `koanf:"-"`
 		DomainBlocklist    []string        `koanf:"-"`
+		RoleAccounts       []string        `koanf:"role_accounts"`
+		SpamTrapPatterns   []string        `koanf:"spamtrap_patterns"`
+
+		// Map of trusted source tokens to source names.
+		TrustedSources map[string]string `koanf:"-"`
 	} `koanf:"privacy"`
 	Security struct {
 		EnableCaptcha bool   `koanf:"enable_captcha"`
 		CaptchaKey    string `koanf:"captcha_key"`
 		CaptchaSecret string `koanf:"captcha_secret"`
+		SigningKey    string `koanf:"signing_key"`
 	} `koanf:"security"`
+	Sunset struct {
+		Enabled      bool   `koanf:"enabled"`
+		InactiveDays int    `koanf:"inactive_days"`
+		GraceDays    int    `koanf:"grace_days"`
+		ListID       int    `koanf:"list_id"`
+		Action       string `koanf:"action"`
+		Interval     string `koanf:"interval"`
+	} `koanf:"sunset"`
+	ContentQA struct {
+		Enabled bool   `koanf:"enabled"`
+		URL     string `koanf:"url"`
+	} `koanf:"content_qa"`
+	Entitlement struct {
+		Enabled  bool   `koanf:"enabled"`
+		Interval string `koanf:"interval"`
+	} `koanf:"entitlement"`
+	Stripe struct {
+		Enabled       bool   `koanf:"enabled"`
+		WebhookSecret string `koanf:"webhook_secret"`
+
+		// Map of Stripe product IDs to the IDs of the lists they're mapped to.
+		Products map[string][]int `koanf:"-"`
+	} `koanf:"stripe"`
+	Hygiene struct {
+		Enabled         bool   `koanf:"enabled"`
+		Interval        string `koanf:"interval"`
+		CheckDomains    bool   `koanf:"check_domains"`
+		SoftBounceCount int    `koanf:"soft_bounce_count"`
+		SoftBounceDays  int    `koanf:"soft_bounce_days"`
+	} `koanf:"hygiene"`
+	Maintenance struct {
+		Enabled      bool   `koanf:"enabled"`
+		Interval     string `koanf:"interval"`
+		MediaDays    int    `koanf:"media_days"`
+		FixIntegrity bool   `koanf:"fix_integrity"`
+	} `koanf:"maintenance"`
+	Previews struct {
+		Enabled bool     `koanf:"enabled"`
+		Clients []string `koanf:"clients"`
+	} `koanf:"previews"`
+	Deliverability struct {
+		Domains         []deliverabilityDomain `koanf:"domains"`
+		TrackingCNAME   string                 `koanf:"tracking_cname"`
+		Recheck         bool                   `koanf:"recheck"`
+		RecheckInterval string                 `koanf:"recheck_interval"`
+	} `koanf:"deliverability"`
+	SpamCheck struct {
+		Enabled   bool    `koanf:"enabled"`
+		Threshold float64 `koanf:"threshold"`
+	} `koanf:"spam_check"`
+	Reputation struct {
+		Enabled         bool    `koanf:"enabled"`
+		Interval        string  `koanf:"interval"`
+		AlertReputation string  `koanf:"alert_reputation"`
+		AlertSpamRate   float64 `koanf:"alert_spam_rate"`
+	} `koanf:"reputation"`
+	Notifications struct {
+		SlackEnabled    bool     `koanf:"slack_enabled"`
+		SlackWebhookURL string   `koanf:"slack_webhook_url"`
+		SlackEvents     []string `koanf:"slack_events"`
+		BounceThreshold int      `koanf:"bounce_threshold"`
+	} `koanf:"notifications"`
+	Attachments struct {
+		MaxMessageSize int    `koanf:"max_message_size"`
+		MaxBodySize    int    `koanf:"max_body_size"`
+		MaxImageWeight int    `koanf:"max_image_weight"`
+		MaxSize        int    `koanf:"max_size"`
+		SizeAction     string `koanf:"size_action"`
+	} `koanf:"attachments"`
+
+	// UTM parameters appended to the links in campaigns.
+	UTM struct {
+		Enabled bool   `koanf:"enabled"`
+		Source  string `koanf:"source"`
+		Medium  string `koanf:"medium"`
+	} `koanf:"utm"`
+
+	// Archive of the content of sent campaign messages.
+	SentArchive struct {
+		Mode          string `koanf:"mode"`
+		RetentionDays int    `koanf:"retention_days"`
+	} `koanf:"sent_archive"`
+
+	// Retention of the campaign send log.
+	SendLog struct {
+		RetentionDays int `koanf:"retention_days"`
+	} `koanf:"send_log"`
 	AdminUsername []byte `koanf:"admin_username"`
 	AdminPassword []byte `koanf:"admin_password"`
 
@@ -91,15 +218,26 @@ type constants struct {
 		AdminJS   []byte `koanf:"admin.custom_js"`
 		PublicCSS []byte `koanf:"public.custom_css"`
 		PublicJS  []byte `koanf:"public.custom_js"`
+
+		// White-label branding of the admin UI, system e-mails, and public pages.
+		ProductName string `koanf:"branding.product_name"`
+		LogoURL     string `koanf:"branding.logo_url"`
+		Color       string `koanf:"branding.color"`
+		Footer      string `koanf:"branding.footer"`
 	}
 
-	UnsubURL     string
-	LinkTrackURL string
-	ViewTrackURL string
-	OptinURL     string
-	MessageURL   string
-	ArchiveURL   string
-	AssetVersion string
+	UnsubURL       string
+	LinkTrackURL   string
+	ShortLinkURL   string
+	ViewTrackURL   string
+	SurveyURL      string
+	OptinURL       string
+	MessageURL     string
+	TxSubURL       string
+	SubExportURL   string
+	EmailChangeURL string
+	ArchiveURL     string
+	AssetVersion   string
 
 	MediaUpload struct {
 		Provider   string
@@ -110,6 +248,9 @@ type constants struct {
 	BounceSESEnabled      bool
 	BounceSendgridEnabled bool
 	BouncePostmarkEnabled bool
+
+	// Time zone (app.reporting_timezone) stats are reported in.
+	ReportingTimezone *time.Location
 }
 
 type notifTpls struct {
@@ -137,6 +278,7 @@ func initFlags() {
 	f.String("i18n-dir", "", "(optional) path to directory with i18n language files")
 	f.Bool("yes", false, "assume 'yes' to prompts during --install/upgrade")
 	f.Bool("passive", false, "run in passive mode where campaigns are not processed")
+	f.Bool("hash-password", false, "generate an Argon2id hash of a password read from stdin for app.admin_password")
 	if err := f.Parse(os.Args[1:]); err != nil {
 		lo.Fatalf("error loading flags: %v", err)
 	}
@@ -339,6 +481,22 @@ func prepareQueries(qMap goyesql.Queries, db *sqlx.DB, ko *koanf.Koanf) *models.
 	}
 	qMap["get-campaign-link-counts"].Query = fmt.Sprintf(qMap["get-campaign-link-counts"].Query, linkSel)
 
+	// Sending campaigns and previewing their audience share the same targeting.
+	for _, k := range []string{"next-campaign-subscribers", "get-campaign-audience"} {
+		qMap[k].Query = strings.ReplaceAll(qMap[k].Query, "%audience%", qMap["campaign-audience"].Query)
+	}
+
+	// Without the pg_trgm extension (which may require a superuser to install), the typo
+	// tolerant subscriber search can't be prepared. Fall back to a plain ILIKE search.
+	var hasTrgm bool
+	if err := db.Get(&hasTrgm, `SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`); err != nil {
+		lo.Fatalf("error checking Postgres extensions: %v", err)
+	}
+	if !hasTrgm {
+		lo.Println("WARNING: the pg_trgm extension is not installed. Subscriber search falls back to ILIKE and is not typo tolerant.")
+		qMap["search-subscribers"].Query = qMap["search-subscribers-ilike"].Query
+	}
+
 	// Scan and prepare all queries.
 	var q models.Queries
 	if err := goyesqlx.ScanToStruct(&q, qMap, db.Unsafe()); err != nil {
@@ -373,6 +531,26 @@ func initSettings(query string, db *sqlx.DB, ko *koanf.Koanf) {
 	}
 }
 
+// initSigningKey generates and saves the key for signing subscriber links
+// in transactional messages if one doesn't exist yet.
+func initSigningKey(q *models.Queries, ko *koanf.Koanf) {
+	if ko.String("security.signing_key") != "" {
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		lo.Fatalf("error generating signing key: %v", err)
+	}
+	key := hex.EncodeToString(b)
+
+	s, _ := json.Marshal(map[string]string{"security.signing_key": key})
+	if _, err := q.UpdateSettings.Exec(s); err != nil {
+		lo.Fatalf("error saving signing key: %v", err)
+	}
+	ko.Set("security.signing_key", key)
+}
+
 func initConstants() *constants {
 	// Read constants.
 	var c constants
@@ -385,6 +563,51 @@ func initConstants() *constants {
 	if err := ko.Unmarshal("security", &c.Security); err != nil {
 		lo.Fatalf("error loading app.security config: %v", err)
 	}
+	if err := ko.Unmarshal("sunset", &c.Sunset); err != nil {
+		lo.Fatalf("error loading sunset config: %v", err)
+	}
+	if err := ko.Unmarshal("content_qa", &c.ContentQA); err != nil {
+		lo.Fatalf("error loading content_qa config: %v", err)
+	}
+	if err := ko.Unmarshal("stripe", &c.Stripe); err != nil {
+		lo.Fatalf("error loading stripe config: %v", err)
+	}
+	if err := ko.Unmarshal("entitlement", &c.Entitlement); err != nil {
+		lo.Fatalf("error loading entitlement config: %v", err)
+	}
+	if err := ko.Unmarshal("hygiene", &c.Hygiene); err != nil {
+		lo.Fatalf("error loading hygiene config: %v", err)
+	}
+	if err := ko.Unmarshal("maintenance", &c.Maintenance); err != nil {
+		lo.Fatalf("error loading maintenance config: %v", err)
+	}
+	if err := ko.Unmarshal("previews", &c.Previews); err != nil {
+		lo.Fatalf("error loading previews config: %v", err)
+	}
+	if err := ko.Unmarshal("deliverability", &c.Deliverability); err != nil {
+		lo.Fatalf("error loading deliverability config: %v", err)
+	}
+	if err := ko.Unmarshal("spam_check", &c.SpamCheck); err != nil {
+		lo.Fatalf("error loading spam_check config: %v", err)
+	}
+	if err := ko.Unmarshal("reputation", &c.Reputation); err != nil {
+		lo.Fatalf("error loading reputation config: %v", err)
+	}
+	if err := ko.Unmarshal("notifications", &c.Notifications); err != nil {
+		lo.Fatalf("error loading app.notifications config: %v", err)
+	}
+	if err := ko.Unmarshal("attachments", &c.Attachments); err != nil {
+		lo.Fatalf("error loading attachments config: %v", err)
+	}
+	if err := ko.Unmarshal("utm", &c.UTM); err != nil {
+		lo.Fatalf("error loading utm config: %v", err)
+	}
+	if err := ko.Unmarshal("sent_archive", &c.SentArchive); err != nil {
+		lo.Fatalf("error loading sent_archive config: %v", err)
+	}
+	if err := ko.Unmarshal("send_log", &c.SendLog); err != nil {
+		lo.Fatalf("error loading send_log config: %v", err)
+	}
 	if err := ko.UnmarshalWithConf("appearance", &c.Appearance, koanf.UnmarshalConf{FlatPaths: true}); err != nil {
 		lo.Fatalf("error loading app.appearance config: %v", err)
 	}
@@ -396,6 +619,30 @@ func initConstants() *constants {
 	c.MediaUpload.Extensions = ko.Strings("upload.extensions")
 	c.Privacy.DomainBlocklist = ko.Strings("privacy.domain_blocklist")
 
+	c.ReportingTimezone = time.UTC
+	if tz := ko.String("app.reporting_timezone"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			lo.Printf("invalid app.reporting_timezone '%s'. Using UTC: %v", tz, err)
+		} else {
+			c.ReportingTimezone = loc
+		}
+	}
+
+	c.Privacy.TrustedSources = map[string]string{}
+	for _, s := range ko.Slices("privacy.trusted_sources") {
+		if tok := s.String("token"); tok != "" {
+			c.Privacy.TrustedSources[tok] = s.String("name")
+		}
+	}
+
+	c.Stripe.Products = map[string][]int{}
+	for _, s := range ko.Slices("stripe.products") {
+		if p, id := s.String("product_id"), s.Int("list_id"); p != "" && id > 0 {
+			c.Stripe.Products[p] = append(c.Stripe.Products[p], id)
+		}
+	}
+
 	// Static URLS.
 	// url.com/subscription/{campaign_uuid}/{subscriber_uuid}
 	c.UnsubURL = fmt.Sprintf("%s/subscription/%%s/%%s", c.RootURL)
@@ -406,15 +653,28 @@ func initConstants() *constants {
 	// url.com/link/{campaign_uuid}/{subscriber_uuid}/{link_uuid}
 	c.LinkTrackURL = fmt.Sprintf("%s/link/%%s/%%s/%%s", c.RootURL)
 
+	// url.com/l/{slug}
+	c.ShortLinkURL = fmt.Sprintf("%s/l/%%s", c.RootURL)
+
 	// url.com/link/{campaign_uuid}/{subscriber_uuid}
 	c.MessageURL = fmt.Sprintf("%s/campaign/%%s/%%s", c.RootURL)
 
+	// url.com/subscription/tx/{subscriber_uuid}/{signature}
+	c.TxSubURL = fmt.Sprintf("%s/subscription/tx/%%s/%%s", c.RootURL)
+
+	// url.com/subscription/data/{export_uuid}/{signature}
+	c.SubExportURL = fmt.Sprintf("%s/subscription/data/%%s/%%s", c.RootURL)
+	c.EmailChangeURL = fmt.Sprintf("%s/subscription/email/%%s/%%s", c.RootURL)
+
 	// url.com/archive
 	c.ArchiveURL = c.RootURL + "/archive"
 
 	// url.com/campaign/{campaign_uuid}/{subscriber_uuid}/px.png
 	c.ViewTrackURL = fmt.Sprintf("%s/campaign/%%s/%%s/px.png", c.RootURL)
 
+	// url.com/survey/{campaign_uuid}/{subscriber_uuid}/{key}
+	c.SurveyURL = fmt.Sprintf("%s/survey/%%s/%%s/%%s", c.RootURL)
+
 	c.BounceWebhooksEnabled = ko.Bool("bounce.webhooks_enabled")
 	c.BounceSESEnabled = ko.Bool("bounce.ses_enabled")
 	c.BounceSendgridEnabled = ko.Bool("bounce.sendgrid_enabled")
@@ -445,7 +705,20 @@ func initI18n(lang string, fs stuffbin.FileSystem) *i18n.I18n {
 // initCampaignManager initializes the campaign manager.
 func initCampaignManager(q *models.Queries, cs *constants, app *App) *manager.Manager {
 	campNotifCB := func(subject string, data interface{}) error {
-		return app.sendNotification(cs.NotifyEmails, subject, notifTplCampaign, data)
+		// Campaigns paused due to too many errors indicate that the messenger is down.
+		var (
+			typ    = models.NotificationCampaign
+			reason = ""
+		)
+		if d, ok := data.(map[string]interface{}); ok {
+			reason, _ = d["Reason"].(string)
+			if reason == manager.NotifReasonSendErrors {
+				typ = models.NotificationMessenger
+			}
+		}
+
+		app.notify(typ, subject, reason, notifTplCampaign, data)
+		return nil
 	}
 
 	if ko.Int("app.concurrency") < 1 {
@@ -466,14 +739,22 @@ func initCampaignManager(q *models.Queries, cs *constants, app *App) *manager.Ma
 		MaxSendErrors:         ko.Int("app.max_send_errors"),
 		FromEmail:             cs.FromEmail,
 		IndividualTracking:    ko.Bool("privacy.individual_tracking"),
+		AnonymousLinks:        ko.Bool("privacy.anonymous_links"),
 		UnsubURL:              cs.UnsubURL,
 		OptinURL:              cs.OptinURL,
 		LinkTrackURL:          cs.LinkTrackURL,
+		ShortLinkURL:          cs.ShortLinkURL,
+		ShortLinks:            ko.Bool("app.short_links"),
+		UTMEnabled:            cs.UTM.Enabled,
+		UTM:                   models.UTM{Source: cs.UTM.Source, Medium: cs.UTM.Medium},
+		SentArchive:           cs.SentArchive.Mode,
 		ViewTrackURL:          cs.ViewTrackURL,
+		SurveyURL:             cs.SurveyURL,
 		MessageURL:            cs.MessageURL,
 		ArchiveURL:            cs.ArchiveURL,
 		RootURL:               cs.RootURL,
 		UnsubHeader:           ko.Bool("privacy.unsubscribe_header"),
+		MinifyHTML:            ko.Bool("attachments.minify_html"),
 		SlidingWindow:         ko.Bool("app.message_sliding_window"),
 		SlidingWindowDuration: ko.Duration("app.message_sliding_window_duration"),
 		SlidingWindowRate:     ko.Int("app.message_sliding_window_rate"),
@@ -490,7 +771,7 @@ func initTxTemplates(m *manager.Manager, app *App) {
 
 	for _, t := range tpls {
 		tpl := t
-		if err := tpl.Compile(app.manager.GenericTemplateFuncs()); err != nil {
+		if err := tpl.Compile(txTemplateFuncs(app)); err != nil {
 			lo.Printf("error compiling transactional template %d: %v", tpl.ID, err)
 			continue
 		}
@@ -503,6 +784,8 @@ func initImporter(q *models.Queries, db *sqlx.DB, core *core.Core, app *App) *su
 	return subimporter.New(
 		subimporter.Options{
 			DomainBlocklist:    app.constants.Privacy.DomainBlocklist,
+			RoleAccounts:       app.constants.Privacy.RoleAccounts,
+			SpamTrapPatterns:   app.constants.Privacy.SpamTrapPatterns,
 			UpsertStmt:         q.UpsertSubscriber.Stmt,
 			BlocklistStmt:      q.UpsertBlocklistSubscriber.Stmt,
 			UpdateListDateStmt: q.UpdateListsDate.Stmt,
@@ -510,14 +793,15 @@ func initImporter(q *models.Queries, db *sqlx.DB, core *core.Core, app *App) *su
 				// Refresh cached subscriber counts and stats.
 				core.RefreshMatViews(true)
 
-				app.sendNotification(app.constants.NotifyEmails, subject, notifTplImport, data)
+				app.notify(models.NotificationImport, subject, "", notifTplImport, data)
 				return nil
 			},
 		}, db.DB, app.i18n)
 }
 
-// initSMTPMessenger initializes the SMTP messenger.
-func initSMTPMessenger(m *manager.Manager) manager.Messenger {
+// initSMTPMessengers initializes the SMTP messenger and the messengers of the
+// named SMTP servers.
+func initSMTPMessengers(m *manager.Manager) []manager.Messenger {
 	var (
 		mapKeys = ko.MapKeys("smtp")
 		servers = make([]email.Server, 0, len(mapKeys))
@@ -554,7 +838,13 @@ func initSMTPMessenger(m *manager.Manager) manager.Messenger {
 		lo.Fatalf("error loading e-mail messenger: %v", err)
 	}
 
-	return msgr
+	out := []manager.Messenger{msgr}
+	for _, n := range msgr.Named() {
+		out = append(out, n)
+		lo.Printf("loaded SMTP messenger: %s", n.Name())
+	}
+
+	return out
 }
 
 // initPostbackMessengers initializes and returns all the enabled
@@ -680,6 +970,8 @@ func initBounceManager(app *App) *bounce.Manager {
 			ko.String("bounce.postmark.password"),
 		},
 		RecordBounceCB: app.core.RecordBounce,
+		RecordReplyCB:  app.core.RecordCampaignReply,
+		RecordOptinCB:  confirmOptinReplyHook(app),
 	}
 
 	// For now, only one mailbox is supported.
@@ -709,6 +1001,59 @@ func initBounceManager(app *App) *bounce.Manager {
 	return b
 }
 
+// initCDC initializes the change data capture publisher that streams row changes
+// from a logical replication slot to the event stream and the optional webhook.
+func initCDC(db *sqlx.DB, app *App) *cdc.CDC {
+	// Guard against unbounded WAL retention on configs that predate max_lag_mb.
+	maxLag := int64(1024)
+	if ko.Exists("cdc.max_lag_mb") {
+		maxLag = ko.Int64("cdc.max_lag_mb")
+	}
+
+	c, err := cdc.New(cdc.Opt{
+		Slot:           ko.String("cdc.slot"),
+		Tables:         ko.Strings("cdc.tables"),
+		Interval:       ko.Duration("cdc.interval"),
+		BatchSize:      ko.Int("cdc.batch_size"),
+		MaxBatchBytes:  ko.Int("cdc.max_batch_size_mb") * 1024 * 1024,
+		MaxLag:         maxLag * 1024 * 1024,
+		WebhookURL:     ko.String("cdc.webhook_url"),
+		WebhookTimeout: ko.Duration("cdc.webhook_timeout"),
+		PublishCB: func(ch cdc.Change) {
+			_ = app.events.Publish(events.Event{
+				Type:    events.TypeChange,
+				Message: ch.Table + " " + ch.Op,
+				Data:    ch,
+			})
+		},
+	}, db, app.log)
+	if err != nil {
+		lo.Fatalf("error initializing CDC: %v", err)
+	}
+
+	return c
+}
+
+// initGA4 initializes the forwarder of campaign opens and clicks to Google Analytics 4.
+func initGA4(app *App) *ga4.GA4 {
+	g, err := ga4.New(ga4.Opt{
+		MeasurementID: ko.String("ga4.measurement_id"),
+		APISecret:     ko.String("ga4.api_secret"),
+		Source:        ko.String("ga4.source"),
+		Medium:        ko.String("ga4.medium"),
+		CampaignNameCB: func(uuid string) (string, error) {
+			c, err := app.core.GetCampaign(0, uuid, "")
+			return c.Name, err
+		},
+	}, lo)
+	if err != nil {
+		lo.Printf("error initializing GA4: %v", err)
+		return nil
+	}
+
+	return g
+}
+
 func initAbout(q *models.Queries, db *sqlx.DB) about {
 	var (
 		mem runtime.MemStats
@@ -767,7 +1112,7 @@ func initHTTPServer(app *App) *echo.Echo {
 		templates:           tpl,
 		SiteName:            app.constants.SiteName,
 		RootURL:             app.constants.RootURL,
-		LogoURL:             app.constants.LogoURL,
+		LogoURL:             app.constants.logoURL(),
 		FaviconURL:          app.constants.FaviconURL,
 		AssetVersion:        app.constants.AssetVersion,
 		EnablePublicSubPage: app.constants.EnablePublicSubPage,
@@ -812,20 +1157,402 @@ func initCaptcha() *captcha.Captcha {
 	})
 }
 
-func initCron(core *core.Core) {
-	c := cron.New()
-	_, err := c.Add(ko.MustString("app.cache_slow_queries_interval"), func() {
-		lo.Println("refreshing slow query cache")
-		_ = core.RefreshMatViews(true)
-		lo.Println("done refreshing slow query cache")
+func initContentQA() *contentqa.QA {
+	return contentqa.New(contentqa.Opt{
+		Timeout: ko.Duration("content_qa.timeout"),
+	})
+}
+
+func initEntitlement() *entitlement.Client {
+	return entitlement.New(entitlement.Opt{
+		Timeout: ko.Duration("entitlement.timeout"),
+	})
+}
+
+func initPreviews() *previews.Client {
+	return previews.New(previews.Opt{
+		URL:     ko.String("previews.url"),
+		APIKey:  ko.String("previews.api_key"),
+		Timeout: ko.Duration("previews.timeout"),
+	})
+}
+
+// initSpamCheck initializes the pre-send spam checker if it's enabled.
+func initSpamCheck() *spamcheck.Checker {
+	if !ko.Bool("spam_check.enabled") {
+		return nil
+	}
+
+	c, err := spamcheck.New(spamcheck.Opt{
+		Type:     ko.String("spam_check.type"),
+		URL:      ko.String("spam_check.url"),
+		Password: ko.String("spam_check.password"),
+		Timeout:  ko.Duration("spam_check.timeout"),
+	})
+	if err != nil {
+		lo.Printf("error initializing spam check: %v", err)
+		return nil
+	}
+
+	return c
+}
+
+// initLockout initializes the login lockout guard.
+func initLockout() *lockout.Guard {
+	if ko.Int("security.login_max_attempts") < 1 {
+		return nil
+	}
+
+	return lockout.New(lockout.Opt{
+		MaxAttempts: ko.Int("security.login_max_attempts"),
+		Window:      loginAttemptWindow,
+		Lockout:     ko.Duration("security.login_lockout"),
+		MaxLockout:  loginMaxLockout,
+	})
+}
+
+// initPasswdParams returns the Argon2id cost parameters for hashing passwords.
+func initPasswdParams() passwd.Params {
+	p := passwd.DefaultParams
+	if v := ko.Int("app.argon2_memory"); v > 0 {
+		p.Memory = uint32(v)
+	}
+	if v := ko.Int("app.argon2_iterations"); v > 0 {
+		p.Iterations = uint32(v)
+	}
+	if v := ko.Int("app.argon2_parallelism"); v > 0 && v < 256 {
+		p.Parallelism = uint8(v)
+	}
+
+	return p
+}
+
+// initAdminPasswd initializes the verifier of the admin password.
+func initAdminPasswd() *adminPasswd {
+	h := ko.String("app.admin_password")
+	if passwd.IsHash(h) {
+		if _, _, err := passwd.Verify("", h); err != nil {
+			lo.Fatalf("error loading app.admin_password: %v", err)
+		}
+	}
+
+	return &adminPasswd{
+		hash:   h,
+		params: initPasswdParams(),
+		log:    lo,
+	}
+}
+
+// initIPFilter initializes the admin and API IP allow and deny lists.
+func initIPFilter() *ipfilter.Filter {
+	f, err := ipfilter.New(ipfilter.Opt{
+		Deny:     ko.Strings("security.ip_denylist"),
+		Allow:    ko.Strings("security.ip_allowlist"),
+		APIAllow: ko.Strings("security.api_ip_allowlist"),
+	})
+	if err != nil {
+		lo.Fatalf("error initializing IP filter: %v", err)
+	}
+
+	return f
+}
+
+// initAPIRateLimit initializes the rate limits of API tokens by role and
+// of specific API users.
+func initAPIRateLimit() *apiRateLimits {
+	var users []struct {
+		Username string `koanf:"username"`
+		Rate     int    `koanf:"rate"`
+		Burst    int    `koanf:"burst"`
+	}
+	if err := ko.Unmarshal("security.api_rate_limits", &users); err != nil {
+		lo.Fatalf("error loading API rate limits: %v", err)
+	}
+
+	r := &apiRateLimits{
+		roles: map[string]ratelimit.Limit{
+			models.APITokenRoleFull: {
+				Rate:  ko.Int("security.api_rate_limit"),
+				Burst: ko.Int("security.api_rate_burst"),
+			},
+			models.APITokenRoleReadOnly: {
+				Rate:  ko.Int("security.api_rate_limit_read_only"),
+				Burst: ko.Int("security.api_rate_burst_read_only"),
+			},
+		},
+		users:   make(map[string]ratelimit.Limit, len(users)),
+		limiter: ratelimit.New(),
+	}
+	for _, u := range users {
+		r.users[u.Username] = ratelimit.Limit{Rate: u.Rate, Burst: u.Burst}
+	}
+
+	return r
+}
+
+// initScanner initializes the ClamAV or HTTP scanner for scanning uploaded
+// attachments and media.
+func initScanner() fileScanner {
+	if !ko.Bool("attachments.clamav_enabled") {
+		return nil
+	}
+
+	if ko.String("attachments.scanner") == scannerHTTP {
+		c, err := httpscan.New(httpscan.Opt{
+			URL:     ko.String("attachments.scanner_url"),
+			Token:   ko.String("attachments.scanner_token"),
+			Timeout: ko.Duration("attachments.clamav_timeout"),
+		})
+		if err != nil {
+			lo.Printf("error initializing HTTP scanner: %v", err)
+			return nil
+		}
+
+		return httpScanner{c: c}
+	}
+
+	c, err := clamav.New(clamav.Opt{
+		Address: ko.String("attachments.clamav_address"),
+		Timeout: ko.Duration("attachments.clamav_timeout"),
 	})
 	if err != nil {
-		lo.Printf("error initializing slow cache query cron: %v", err)
+		lo.Printf("error initializing ClamAV: %v", err)
+		return nil
+	}
+
+	if err := c.Ping(); err != nil {
+		lo.Printf("WARNING: ClamAV is not reachable at %s: %v", ko.String("attachments.clamav_address"), err)
+	}
+
+	return c
+}
+
+// initReputation initializes the enabled sender reputation metric providers.
+func initReputation() []reputation.Provider {
+	if !ko.Bool("reputation.enabled") {
+		return nil
+	}
+
+	var out []reputation.Provider
+	if ko.Bool("reputation.postmaster_enabled") {
+		p, err := reputation.NewPostmaster(reputation.PostmasterOpt{
+			ClientID:     ko.String("reputation.postmaster_client_id"),
+			ClientSecret: ko.String("reputation.postmaster_client_secret"),
+			RefreshToken: ko.String("reputation.postmaster_refresh_token"),
+			Domains:      ko.Strings("reputation.postmaster_domains"),
+		})
+		if err != nil {
+			lo.Printf("error initializing Google Postmaster Tools: %v", err)
+		} else {
+			out = append(out, p)
+		}
+	}
+
+	if ko.Bool("reputation.snds_enabled") {
+		p, err := reputation.NewSNDS(reputation.SNDSOpt{Key: ko.String("reputation.snds_key")})
+		if err != nil {
+			lo.Printf("error initializing Microsoft SNDS: %v", err)
+		} else {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+func initCron(app *App) {
+	c := cron.New()
+
+	var slowQueryID cron.ID
+	if ko.Bool("app.cache_slow_queries") {
+		id, err := c.Add(ko.MustString("app.cache_slow_queries_interval"), func() {
+			lo.Println("refreshing slow query cache")
+			_ = app.core.RefreshMatViews(true)
+			lo.Println("done refreshing slow query cache")
+		})
+		if err != nil {
+			lo.Printf("error initializing slow cache query cron: %v", err)
+		}
+		slowQueryID = id
+	}
+
+	if app.constants.Sunset.Enabled {
+		_, err := c.Add(app.constants.Sunset.Interval, func() {
+			lo.Println("running subscriber sunset policy")
+			if _, err := runSunset(app); err != nil {
+				lo.Printf("error running subscriber sunset policy: %v", err)
+			}
+		})
+		if err != nil {
+			lo.Printf("error initializing subscriber sunset cron: %v", err)
+		}
+	}
+
+	if app.constants.Hygiene.Enabled {
+		_, err := c.Add(app.constants.Hygiene.Interval, func() {
+			lo.Println("running list hygiene")
+			if _, err := runListHygiene(app); err != nil {
+				lo.Printf("error running list hygiene: %v", err)
+			}
+		})
+		if err != nil {
+			lo.Printf("error initializing list hygiene cron: %v", err)
+		}
+	}
+
+	if app.constants.Entitlement.Enabled {
+		_, err := c.Add(app.constants.Entitlement.Interval, func() {
+			lo.Println("running premium list entitlement checks")
+			if _, err := runEntitlementChecks(app); err != nil {
+				lo.Printf("error running entitlement checks: %v", err)
+			}
+		})
+		if err != nil {
+			lo.Printf("error initializing entitlement check cron: %v", err)
+		}
+	}
+
+	if app.constants.Deliverability.Recheck {
+		_, err := c.Add(app.constants.Deliverability.RecheckInterval, func() {
+			lo.Println("running DNS deliverability checks")
+			if _, err := runDNSChecks(app); err != nil {
+				lo.Printf("error running DNS deliverability checks: %v", err)
+			}
+		})
+		if err != nil {
+			lo.Printf("error initializing DNS deliverability check cron: %v", err)
+		}
+	}
+
+	if app.constants.Reputation.Enabled && len(app.reputation) > 0 {
+		_, err := c.Add(app.constants.Reputation.Interval, func() {
+			lo.Println("fetching sender reputation metrics")
+			if _, err := syncReputation(app); err != nil {
+				lo.Printf("error fetching sender reputation metrics: %v", err)
+			}
+		})
+		if err != nil {
+			lo.Printf("error initializing sender reputation cron: %v", err)
+		}
+	}
+
+	if app.constants.Maintenance.Enabled {
+		_, err := c.Add(app.constants.Maintenance.Interval, func() {
+			lo.Println("running scheduled maintenance")
+			runMaintenance(app)
+		})
+		if err != nil {
+			lo.Printf("error initializing maintenance cron: %v", err)
+		}
+	}
+
+	// Permanently delete trashed items past the retention period.
+	if app.constants.TrashRetentionDays > 0 {
+		if _, err := c.Add("0 4 * * *", func() {
+			lo.Println("purging trash")
+			if _, err := purgeTrash(app); err != nil {
+				lo.Printf("error purging trash: %v", err)
+			}
+		}); err != nil {
+			lo.Printf("error initializing trash purge cron: %v", err)
+		}
+	}
+
+	// Delete archived sent messages past the retention period.
+	if m := app.constants.SentArchive.Mode; m != "" && m != models.SentArchiveOff && app.constants.SentArchive.RetentionDays > 0 {
+		if _, err := c.Add("30 4 * * *", func() {
+			lo.Println("purging sent message archive")
+			if _, err := purgeSentMessages(app); err != nil {
+				lo.Printf("error purging sent message archive: %v", err)
+			}
+		}); err != nil {
+			lo.Printf("error initializing sent message archive purge cron: %v", err)
+		}
+	}
+
+	// Delete campaign send log entries past the retention period.
+	if app.constants.SendLog.RetentionDays > 0 {
+		if _, err := c.Add("45 4 * * *", func() {
+			lo.Println("purging campaign send log")
+			if _, err := purgeCampaignSends(app); err != nil {
+				lo.Printf("error purging campaign send log: %v", err)
+			}
+		}); err != nil {
+			lo.Printf("error initializing campaign send log purge cron: %v", err)
+		}
+	}
+
+	// Alert if the number of bounces in the past hour exceeds the threshold.
+	if app.constants.Notifications.BounceThreshold > 0 {
+		if _, err := c.Add("*/15 * * * *", func() {
+			checkBounceThreshold(app)
+		}); err != nil {
+			lo.Printf("error initializing bounce threshold cron: %v", err)
+		}
+	}
+
+	// Unsubscribe non-confirmers from lists whose re-permission deadline has passed.
+	if _, err := c.Add("*/10 * * * *", func() {
+		finishListRepermissions(app)
+	}); err != nil {
+		lo.Printf("error initializing list re-permission cron: %v", err)
+	}
+
+	// Restore snoozed subscriptions whose snooze has ended.
+	if _, err := c.Add("*/30 * * * *", func() {
+		if n, err := app.core.ResumeSnoozedSubscriptions(); err == nil && n > 0 {
+			lo.Printf("resumed %d snoozed subscriptions", n)
+		}
+	}); err != nil {
+		lo.Printf("error initializing snooze resume cron: %v", err)
+	}
+
+	// Apply the enabled subscriber status rules.
+	if _, err := c.Add("20 * * * *", func() {
+		runStatusRules(app)
+	}); err != nil {
+		lo.Printf("error initializing status rules cron: %v", err)
+	}
+
+	// Pick the winners of campaign A/B tests whose wait time is up.
+	if _, err := c.Add("*/5 * * * *", func() {
+		pickABTestWinners(app)
+	}); err != nil {
+		lo.Printf("error initializing A/B test winner cron: %v", err)
+	}
+
+	// Run the post-campaign actions of finished campaigns.
+	if _, err := c.Add("*/5 * * * *", func() {
+		runCampaignActions(app)
+	}); err != nil {
+		lo.Printf("error initializing campaign actions cron: %v", err)
+	}
+
+	// Recompute the best send hours of subscribers for send-time optimization.
+	if _, err := c.Add("30 2 * * *", func() {
+		if n, err := app.core.RefreshSubscriberSendHours(campSendHoursDays); err == nil {
+			lo.Printf("refreshed the send hours of %d subscribers", n)
+		}
+	}); err != nil {
+		lo.Printf("error initializing subscriber send hours cron: %v", err)
+	}
+
+	// Create the runs of recurring campaigns that are due.
+	if _, err := c.Add("* * * * *", func() {
+		runRecurringCampaigns(app)
+	}); err != nil {
+		lo.Printf("error initializing recurring campaign cron: %v", err)
+	}
+
+	if len(c.Entries()) == 0 {
 		return
 	}
 
 	c.Start()
-	lo.Printf("IMPORTANT: database slow query caching is enabled. Aggregate numbers and stats will not be realtime. Next refresh at: %v", c.Entries()[0].Next)
+
+	if slowQueryID != 0 {
+		lo.Printf("IMPORTANT: database slow query caching is enabled. Aggregate numbers and stats will not be realtime. Next refresh at: %v", c.Entry(slowQueryID).Next)
+	}
 }
 
 func awaitReload(sigChan chan os.Signal, closerWait chan bool, closer func()) chan bool {
@@ -878,7 +1605,16 @@ func initTplFuncs(i *i18n.I18n, cs *constants) template.FuncMap {
 			return cs.RootURL
 		},
 		"LogoURL": func() string {
-			return cs.LogoURL
+			return cs.logoURL()
+		},
+		"ProductName": func() string {
+			return cs.productName()
+		},
+		"BrandColor": func() string {
+			return cs.brandColor()
+		},
+		"BrandFooter": func() template.HTML {
+			return template.HTML(cs.Appearance.Footer)
 		},
 		"Date": func(layout string) string {
 			if layout == "" {
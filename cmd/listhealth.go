@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Defaults and max. of the number of intervals of history and forecast.
+	churnPeriodsDefault    = 12
+	churnPeriodsMax        = 104
+	forecastPeriodsDefault = 6
+	forecastPeriodsMax     = 52
+)
+
+// forecastPoint is a forecast number of subscribers at the end of an interval.
+type forecastPoint struct {
+	Date  time.Time `json:"date"`
+	Total int       `json:"total"`
+}
+
+// handleGetListHealth returns the churn rate and net growth of a list in each of
+// the last n intervals (week, month), and a forecast of its subscriber count.
+func handleGetListHealth(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		id, _    = strconv.Atoi(c.Param("id"))
+		interval = c.QueryParam("interval")
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	switch interval {
+	case "":
+		interval = "month"
+	case "week", "month":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "interval"))
+	}
+
+	periods, err := getQueryInt(c, "periods", churnPeriodsDefault, 2, churnPeriodsMax)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "periods"))
+	}
+	numForecast, err := getQueryInt(c, "forecast", forecastPeriodsDefault, 0, forecastPeriodsMax)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "forecast"))
+	}
+
+	// Check that the list exists.
+	if _, err := app.core.GetList(id, ""); err != nil {
+		return err
+	}
+
+	points, err := app.core.GetListChurn(id, periods, interval)
+	if err != nil {
+		return err
+	}
+
+	// Derive the net growth, end totals, and churn rates of each interval,
+	// and the totals for the whole period.
+	var (
+		subs, unsubs int
+		totals       = make([]float64, len(points))
+	)
+	for i := range points {
+		p := &points[i]
+		p.Net = p.Subscribed - p.Unsubscribed
+		if p.EndTotal = p.StartTotal + p.Net; p.EndTotal < 0 {
+			p.EndTotal = 0
+		}
+		if p.StartTotal > 0 {
+			p.ChurnRate = roundFloat(float64(p.Unsubscribed)/float64(p.StartTotal), 4)
+		}
+
+		subs += p.Subscribed
+		unsubs += p.Unsubscribed
+		totals[i] = float64(p.EndTotal)
+	}
+
+	out := struct {
+		Interval  string             `json:"interval"`
+		Net       int                `json:"net"`
+		ChurnRate float64            `json:"churn_rate"`
+		Points    []models.ListChurn `json:"points"`
+		Forecast  []forecastPoint    `json:"forecast"`
+	}{
+		Interval: interval,
+		Net:      subs - unsubs,
+		Points:   points,
+		Forecast: []forecastPoint{},
+	}
+
+	// Churn over the whole period is the unsubscriptions against the subscribers
+	// at the start plus the ones that joined during it.
+	if len(points) > 0 {
+		if base := points[0].StartTotal + subs; base > 0 {
+			out.ChurnRate = roundFloat(float64(unsubs)/float64(base), 4)
+		}
+
+		last := points[len(points)-1].Date
+		for i, v := range forecastLinear(totals, numForecast) {
+			d := last.AddDate(0, i+1, 0)
+			if interval == "week" {
+				d = last.AddDate(0, 0, 7*(i+1))
+			}
+			out.Forecast = append(out.Forecast, forecastPoint{Date: d, Total: v})
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// forecastLinear fits a least squares line to a series and extends it by n
+// points. Forecasts don't go below 0.
+func forecastLinear(series []float64, n int) []int {
+	out := make([]int, 0, n)
+	if len(series) < 2 || n == 0 {
+		return out
+	}
+
+	var (
+		num               = float64(len(series))
+		sumX, sumY, sumXY float64
+		sumXX             float64
+	)
+	for i, y := range series {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	var (
+		slope     = (num*sumXY - sumX*sumY) / (num*sumXX - sumX*sumX)
+		intercept = (sumY - slope*sumX) / num
+	)
+	for i := 0; i < n; i++ {
+		v := intercept + slope*float64(len(series)+i)
+		out = append(out, int(math.Max(math.Round(v), 0)))
+	}
+
+	return out
+}
+
+// getQueryInt returns an integer query param, or the default if it's not set.
+func getQueryInt(c echo.Context, key string, def, min, max int) (int, error) {
+	v := c.QueryParam(key)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, echo.ErrBadRequest
+	}
+
+	return n, nil
+}
+
+// roundFloat rounds a float to the given number of decimal places.
+func roundFloat(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
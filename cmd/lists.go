@@ -2,13 +2,23 @@ package main
 
 import (
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/knadh/listmonk/internal/subimporter"
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
+	null "gopkg.in/volatiletech/null.v6"
 )
 
+// maxListFields is the max. number of attribute fields on a list's public forms.
+const maxListFields = 20
+
+var regexListFieldKey = regexp.MustCompile(`^[a-zA-Z0-9_]{1,64}$`)
+
 // handleGetLists retrieves lists with additional metadata like subscriber counts. This may be slow.
 func handleGetLists(c echo.Context) error {
 	var (
@@ -20,6 +30,8 @@ func handleGetLists(c echo.Context) error {
 		orderBy    = c.FormValue("order_by")
 		typ        = c.FormValue("type")
 		optin      = c.FormValue("optin")
+		folder     = c.FormValue("folder")
+		status     = c.FormValue("status")
 		order      = c.FormValue("order")
 		minimal, _ = strconv.ParseBool(c.FormValue("minimal"))
 		listID, _  = strconv.Atoi(c.Param("id"))
@@ -61,7 +73,11 @@ func handleGetLists(c echo.Context) error {
 	}
 
 	// Full list query.
-	res, total, err := app.core.QueryLists(query, typ, optin, tags, orderBy, order, pg.Offset, pg.Limit)
+	if status != "" && status != models.ListStatusActive && status != models.ListStatusArchived {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	res, total, err := app.core.QueryLists(query, typ, optin, tags, folder, status, orderBy, order, pg.Offset, pg.Limit)
 	if err != nil {
 		return err
 	}
@@ -99,6 +115,33 @@ func handleCreateList(c echo.Context) error {
 	if !strHasLen(l.Name, 1, stdInputMaxLen) {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.invalidName"))
 	}
+	l.ContentQAURL = strings.TrimSpace(l.ContentQAURL)
+	if l.ContentQAURL != "" && !isHTTPURL(l.ContentQAURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "content_qa_url"))
+	}
+	if !isValidAddressFilter(l.AddressFilter) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "address_filter"))
+	}
+	l.ReturnPath = strings.ToLower(strings.TrimSpace(l.ReturnPath))
+	if l.ReturnPath != "" && !isValidReturnPath(l.ReturnPath) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "return_path"))
+	}
+	if len(l.Folder) > stdInputMaxLen {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "folder"))
+	}
+	l.EntitlementURL = strings.TrimSpace(l.EntitlementURL)
+	if l.EntitlementURL != "" && !isHTTPURL(l.EntitlementURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "entitlement_url"))
+	}
+	if l.MaxSubscribers < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "max_subscribers"))
+	}
+	if err := validateListFields(&l, app); err != nil {
+		return err
+	}
+	if err := validateListParent(0, &l, app); err != nil {
+		return err
+	}
 
 	out, err := app.core.CreateList(l)
 	if err != nil {
@@ -119,8 +162,12 @@ func handleUpdateList(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
 	}
 
-	// Incoming params.
-	var l models.List
+	// Read the incoming params into the existing list fields from the DB so that
+	// fields that are not in the request (eg: content_qa_url) retain their values.
+	l, err := app.core.GetList(id, "")
+	if err != nil {
+		return err
+	}
 	if err := c.Bind(&l); err != nil {
 		return err
 	}
@@ -129,6 +176,33 @@ func handleUpdateList(c echo.Context) error {
 	if !strHasLen(l.Name, 1, stdInputMaxLen) {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.invalidName"))
 	}
+	l.ContentQAURL = strings.TrimSpace(l.ContentQAURL)
+	if l.ContentQAURL != "" && !isHTTPURL(l.ContentQAURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "content_qa_url"))
+	}
+	if !isValidAddressFilter(l.AddressFilter) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "address_filter"))
+	}
+	l.ReturnPath = strings.ToLower(strings.TrimSpace(l.ReturnPath))
+	if l.ReturnPath != "" && !isValidReturnPath(l.ReturnPath) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "return_path"))
+	}
+	if len(l.Folder) > stdInputMaxLen {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "folder"))
+	}
+	l.EntitlementURL = strings.TrimSpace(l.EntitlementURL)
+	if l.EntitlementURL != "" && !isHTTPURL(l.EntitlementURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "entitlement_url"))
+	}
+	if l.MaxSubscribers < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "max_subscribers"))
+	}
+	if err := validateListFields(&l, app); err != nil {
+		return err
+	}
+	if err := validateListParent(id, &l, app); err != nil {
+		return err
+	}
 
 	out, err := app.core.UpdateList(id, l)
 	if err != nil {
@@ -160,3 +234,290 @@ func handleDeleteLists(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, okResp{true})
 }
+
+// handleBulkLists applies a bulk action to multiple lists: adding or removing
+// tags, moving them to a folder, changing the opt-in mode, archiving,
+// unarchiving, or deleting them.
+func handleBulkLists(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		req struct {
+			IDs    []int    `json:"ids"`
+			Action string   `json:"action"`
+			Tags   []string `json:"tags"`
+			Folder string   `json:"folder"`
+			Optin  string   `json:"optin"`
+		}
+	)
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if len(req.IDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	for _, id := range req.IDs {
+		if id < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+		}
+	}
+
+	switch req.Action {
+	case models.ListActionTag, models.ListActionUntag:
+		if len(req.Tags) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "tags"))
+		}
+	case models.ListActionFolder:
+		if len(req.Folder) > stdInputMaxLen {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "folder"))
+		}
+	case models.ListActionOptin:
+		if req.Optin != models.ListOptinSingle && req.Optin != models.ListOptinDouble {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "optin"))
+		}
+	case models.ListActionArchive, models.ListActionUnarchive:
+	case models.ListActionDelete:
+		if err := app.core.DeleteLists(req.IDs); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, okResp{true})
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "action"))
+	}
+
+	if err := app.core.BulkUpdateLists(req.IDs, req.Action, req.Tags, req.Folder, req.Optin); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleMergeList merges another list into the list in the URI. Subscriptions are
+// combined, unfinished campaigns targeting the other list are retargeted, and the
+// other list is archived.
+func handleMergeList(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+		req   struct {
+			SourceID int `json:"source_id"`
+		}
+	)
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if id < 1 || req.SourceID < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	if id == req.SourceID {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.mergeSameList"))
+	}
+
+	// Check that the source list exists.
+	src, err := app.core.GetList(req.SourceID, "")
+	if err != nil {
+		return err
+	}
+
+	out, err := app.core.MergeLists(id, req.SourceID)
+	if err != nil {
+		return err
+	}
+
+	app.log.Printf("merged list %d (%s) into list %d: %d subscriptions, %d campaigns retargeted",
+		src.ID, src.Name, id, out.Subscribers, out.Campaigns)
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetListFolders returns the names of all list folders.
+func handleGetListFolders(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetListFolders()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetListGrowth returns the new subscriptions to a list grouped by their
+// source (eg: form, admin, import) over time, and the totals of each source.
+func handleGetListGrowth(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		id, _    = strconv.Atoi(c.Param("id"))
+		from     = c.QueryParam("from")
+		to       = c.QueryParam("to")
+		interval = c.QueryParam("interval")
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	// Default to the last 30 days.
+	now := time.Now()
+	if to == "" {
+		to = now.Format("2006-01-02")
+	}
+	if from == "" {
+		from = now.AddDate(0, 0, -30).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", from); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("analytics.invalidDates"))
+	}
+	if _, err := time.Parse("2006-01-02", to); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("analytics.invalidDates"))
+	}
+
+	switch interval {
+	case "":
+		interval = "day"
+	case "day", "week", "month":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "interval"))
+	}
+
+	// Check that the list exists.
+	if _, err := app.core.GetList(id, ""); err != nil {
+		return err
+	}
+
+	points, err := app.core.GetListGrowth(id, from, to, interval)
+	if err != nil {
+		return err
+	}
+
+	// Totals of each source in the period.
+	type sourceTotal struct {
+		Source string `json:"source"`
+		Count  int    `json:"count"`
+		Active int    `json:"active"`
+	}
+	var (
+		sources = []sourceTotal{}
+		idx     = map[string]int{}
+	)
+	for _, p := range points {
+		i, ok := idx[p.Source]
+		if !ok {
+			i = len(sources)
+			idx[p.Source] = i
+			sources = append(sources, sourceTotal{Source: p.Source})
+		}
+		sources[i].Count += p.Count
+		sources[i].Active += p.Active
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Count > sources[j].Count
+	})
+
+	out := struct {
+		From     string              `json:"from"`
+		To       string              `json:"to"`
+		Interval string              `json:"interval"`
+		Sources  []sourceTotal       `json:"sources"`
+		Points   []models.ListGrowth `json:"points"`
+	}{from, to, interval, sources, points}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// isValidAddressFilter checks whether a list's address filter mode is valid.
+// An empty mode is allowed and retains the existing (or default) mode.
+func isValidAddressFilter(mode string) bool {
+	switch mode {
+	case "", models.ListAddressFilterNone, models.ListAddressFilterFlag, models.ListAddressFilterReject:
+		return true
+	}
+	return false
+}
+
+// validateListFields validates and cleans up the attribute fields of a list's
+// public forms.
+func validateListFields(l *models.List, app *App) error {
+	if len(l.FormFields) > maxListFields {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "form_fields"))
+	}
+
+	keys := make(map[string]bool, len(l.FormFields))
+	for i, f := range l.FormFields {
+		f.Key = strings.TrimSpace(f.Key)
+		f.Label = strings.TrimSpace(f.Label)
+
+		// Attributes that listmonk sets itself can't be set by subscribers.
+		if !regexListFieldKey.MatchString(f.Key) || keys[f.Key] ||
+			f.Key == subimporter.AttribAddressFlag || f.Key == subimporter.AttribAddressFilterOverride {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("lists.invalidFieldKey", "name", f.Key))
+		}
+		keys[f.Key] = true
+
+		if f.Label == "" {
+			f.Label = f.Key
+		}
+		if len(f.Label) > stdInputMaxLen {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "label"))
+		}
+
+		switch f.Type {
+		case models.ListFieldText, models.ListFieldNumber, models.ListFieldCheckbox:
+			f.Options = nil
+		case models.ListFieldSelect:
+			opts := make([]string, 0, len(f.Options))
+			for _, o := range f.Options {
+				if o = strings.TrimSpace(o); o != "" {
+					opts = append(opts, o)
+				}
+			}
+			if len(opts) == 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("lists.fieldNoOptions", "name", f.Key))
+			}
+			f.Options = opts
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "type"))
+		}
+
+		l.FormFields[i] = f
+	}
+
+	if l.FormFields == nil {
+		l.FormFields = models.ListFields{}
+	}
+
+	return nil
+}
+
+// validateListParent checks that a list's parent exists and isn't the list itself
+// or one of its child lists, which would create a cycle. A parent ID of 0 is
+// treated as no parent.
+func validateListParent(id int, l *models.List, app *App) error {
+	if !l.ParentID.Valid || l.ParentID.Int == 0 {
+		l.ParentID = null.Int{}
+		return nil
+	}
+
+	parentID := int(l.ParentID.Int)
+	if _, err := app.core.GetList(parentID, ""); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "parent_id"))
+	}
+	if id < 1 {
+		return nil
+	}
+
+	ids, err := app.core.GetListDescendantIDs(id)
+	if err != nil {
+		return err
+	}
+	for _, d := range ids {
+		if d == parentID {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.invalidParent"))
+		}
+	}
+
+	return nil
+}
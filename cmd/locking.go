@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// versionConflict is the payload of the error returned when an object being
+// updated has been modified (by someone else) since the version the update is based on.
+type versionConflict struct {
+	Message string `json:"message"`
+	Data    struct {
+		// Current version and state of the object.
+		Version int         `json:"version"`
+		Current interface{} `json:"current"`
+
+		// Fields that differ between the current object and the update.
+		Fields []fieldDiff `json:"fields"`
+	} `json:"data"`
+}
+
+type fieldDiff struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	Yours   interface{} `json:"yours"`
+}
+
+// Fields that are not compared in version conflicts.
+var conflictSkipFields = map[string]bool{
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// setETag sets an object's version as the ETag header in the response
+// which can be sent back in the If-Match header of an update.
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// getIfMatchVersion returns the version in the If-Match header of a request, if any.
+// Both strong ("3") and weak (W/"3") ETags are accepted.
+func getIfMatchVersion(c echo.Context, app *App) (int, bool, error) {
+	h := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, false, nil
+	}
+
+	h = strings.Trim(strings.TrimPrefix(h, "W/"), `"`)
+	v, err := strconv.Atoi(h)
+	if err != nil || v < 1 {
+		return 0, false, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "If-Match"))
+	}
+
+	return v, true, nil
+}
+
+// makeVersionConflict returns a 409 error with the current state of an object and
+// the fields that differ between it and the update the client tried to make.
+func makeVersionConflict(msg string, version int, current, yours interface{}) error {
+	out := versionConflict{Message: msg}
+	out.Data.Version = version
+	out.Data.Current = current
+	out.Data.Fields = diffFields(current, yours)
+
+	return echo.NewHTTPError(http.StatusConflict, out)
+}
+
+// diffFields returns the JSON fields whose values differ between two objects.
+func diffFields(a, b interface{}) []fieldDiff {
+	var am, bm map[string]interface{}
+	if err := toJSONMap(a, &am); err != nil {
+		return []fieldDiff{}
+	}
+	if err := toJSONMap(b, &bm); err != nil {
+		return []fieldDiff{}
+	}
+
+	out := []fieldDiff{}
+	for k, v := range bm {
+		if conflictSkipFields[k] {
+			continue
+		}
+
+		if cur, ok := am[k]; ok && !reflect.DeepEqual(cur, v) {
+			out = append(out, fieldDiff{Field: k, Current: cur, Yours: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Field < out[j].Field
+	})
+
+	return out
+}
+
+func toJSONMap(v interface{}, out *map[string]interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, out)
+}
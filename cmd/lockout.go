@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/internal/lockout"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetLockouts returns the IPs and usernames with failed login attempts or
+// active lockouts.
+func handleGetLockouts(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out := []lockout.Lockout{}
+	if app.lockout != nil {
+		out = app.lockout.GetAll()
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUnlockLogins clears the failed attempts and lockout of a key
+// (eg: ip:1.2.3.4, user:admin) or of all keys if no key is given.
+func handleUnlockLogins(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		key = c.QueryParam("key")
+	)
+
+	if app.lockout == nil {
+		return c.JSON(http.StatusOK, okResp{true})
+	}
+
+	if key == "" {
+		for _, l := range app.lockout.GetAll() {
+			app.lockout.Unlock(l.Key)
+		}
+		return c.JSON(http.StatusOK, okResp{true})
+	}
+
+	if !app.lockout.Unlock(key) {
+		return echo.NewHTTPError(http.StatusNotFound, app.i18n.Ts("globals.messages.notFound", "name", key))
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
@@ -18,11 +18,21 @@ import (
 	"github.com/knadh/listmonk/internal/bounce"
 	"github.com/knadh/listmonk/internal/buflog"
 	"github.com/knadh/listmonk/internal/captcha"
+	"github.com/knadh/listmonk/internal/cdc"
+	"github.com/knadh/listmonk/internal/contentqa"
 	"github.com/knadh/listmonk/internal/core"
+	"github.com/knadh/listmonk/internal/entitlement"
 	"github.com/knadh/listmonk/internal/events"
+	"github.com/knadh/listmonk/internal/ga4"
 	"github.com/knadh/listmonk/internal/i18n"
+	"github.com/knadh/listmonk/internal/ipfilter"
+	"github.com/knadh/listmonk/internal/lockout"
 	"github.com/knadh/listmonk/internal/manager"
 	"github.com/knadh/listmonk/internal/media"
+	"github.com/knadh/listmonk/internal/messenger/capture"
+	"github.com/knadh/listmonk/internal/previews"
+	"github.com/knadh/listmonk/internal/reputation"
+	"github.com/knadh/listmonk/internal/spamcheck"
 	"github.com/knadh/listmonk/internal/subimporter"
 	"github.com/knadh/listmonk/models"
 	"github.com/knadh/paginator"
@@ -36,24 +46,35 @@ const (
 // App contains the "global" components that are
 // passed around, especially through HTTP handlers.
 type App struct {
-	core       *core.Core
-	fs         stuffbin.FileSystem
-	db         *sqlx.DB
-	queries    *models.Queries
-	constants  *constants
-	manager    *manager.Manager
-	importer   *subimporter.Importer
-	messengers map[string]manager.Messenger
-	media      media.Store
-	i18n       *i18n.I18n
-	bounce     *bounce.Manager
-	paginator  *paginator.Paginator
-	captcha    *captcha.Captcha
-	events     *events.Events
-	notifTpls  *notifTpls
-	about      about
-	log        *log.Logger
-	bufLog     *buflog.BufLog
+	core        *core.Core
+	fs          stuffbin.FileSystem
+	db          *sqlx.DB
+	queries     *models.Queries
+	constants   *constants
+	manager     *manager.Manager
+	importer    *subimporter.Importer
+	messengers  map[string]manager.Messenger
+	media       media.Store
+	i18n        *i18n.I18n
+	bounce      *bounce.Manager
+	paginator   *paginator.Paginator
+	captcha     *captcha.Captcha
+	contentQA   *contentqa.QA
+	entitlement *entitlement.Client
+	previews    *previews.Client
+	spamCheck   *spamcheck.Checker
+	reputation  []reputation.Provider
+	ga4         *ga4.GA4
+	scanner     fileScanner
+	lockout     *lockout.Guard
+	adminPasswd *adminPasswd
+	ipFilter    *ipfilter.Filter
+	rateLimit   *apiRateLimits
+	events      *events.Events
+	notifTpls   *notifTpls
+	about       about
+	log         *log.Logger
+	bufLog      *buflog.BufLog
 
 	// Channel for passing reload signals.
 	chReload chan os.Signal
@@ -123,6 +144,15 @@ func init() {
 		lo.Fatalf("error loading config from env: %v", err)
 	}
 
+	// Hash a password for app.admin_password.
+	if ko.Bool("hash-password") {
+		if err := hashPassword(os.Stdin, initPasswdParams()); err != nil {
+			lo.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Connect to the database, load the filesystem to read SQL queries.
 	db = initDB()
 	fs = initFS(appDir, frontendDir, ko.String("static-dir"), ko.String("i18n-dir"))
@@ -160,21 +190,34 @@ func init() {
 
 	// Prepare queries.
 	queries = prepareQueries(qMap, db, ko)
+
+	// Generate the key for signing subscriber links if there isn't one.
+	initSigningKey(queries, ko)
 }
 
 func main() {
 	// Initialize the main app controller that wraps all of the app's
 	// components. This is passed around HTTP handlers.
 	app := &App{
-		fs:         fs,
-		db:         db,
-		constants:  initConstants(),
-		media:      initMediaStore(),
-		messengers: make(map[string]manager.Messenger),
-		log:        lo,
-		bufLog:     bufLog,
-		captcha:    initCaptcha(),
-		events:     evStream,
+		fs:          fs,
+		db:          db,
+		constants:   initConstants(),
+		media:       initMediaStore(),
+		messengers:  make(map[string]manager.Messenger),
+		log:         lo,
+		bufLog:      bufLog,
+		captcha:     initCaptcha(),
+		contentQA:   initContentQA(),
+		entitlement: initEntitlement(),
+		previews:    initPreviews(),
+		spamCheck:   initSpamCheck(),
+		reputation:  initReputation(),
+		scanner:     initScanner(),
+		lockout:     initLockout(),
+		adminPasswd: initAdminPasswd(),
+		ipFilter:    initIPFilter(),
+		rateLimit:   initAPIRateLimit(),
+		events:      evStream,
 
 		paginator: paginator.New(paginator.Opt{
 			DefaultPerPage: 20,
@@ -192,6 +235,7 @@ func main() {
 		Constants: core.Constants{
 			SendOptinConfirmation: app.constants.SendOptinConfirmation,
 			CacheSlowQueries:      ko.Bool("app.cache_slow_queries"),
+			ReportingTimezone:     app.constants.ReportingTimezone,
 		},
 		Queries: queries,
 		DB:      db,
@@ -218,26 +262,62 @@ func main() {
 		go app.bounce.Run()
 	}
 
-	// Initialize the default SMTP (`email`) messenger.
-	app.messengers[emailMsgr] = initSMTPMessenger(app.manager)
+	if ko.Bool("cdc.enabled") {
+		go initCDC(db, app).Run()
+	} else if slot := ko.String("cdc.slot"); slot != "" {
+		// Drop the slot of a previously enabled CDC so that it doesn't retain WAL indefinitely.
+		if ok, err := cdc.DropSlot(db, slot); err != nil {
+			lo.Printf("error dropping CDC replication slot %s: %v", slot, err)
+		} else if ok {
+			lo.Printf("dropped the CDC replication slot %s as CDC is disabled", slot)
+		}
+	}
+
+	if ko.Bool("ga4.enabled") {
+		if app.ga4 = initGA4(app); app.ga4 != nil {
+			go app.ga4.Run()
+		}
+	}
+
+	// Initialize the default SMTP (`email`) messenger and the messengers of
+	// the named SMTP servers.
+	for _, m := range initSMTPMessengers(app.manager) {
+		app.messengers[m.Name()] = m
+	}
 
 	// Initialize any additional postback messengers.
 	for _, m := range initPostbackMessengers(app.manager) {
 		app.messengers[m.Name()] = m
 	}
 
+	// In capture mode, all messengers are replaced with capture messengers
+	// that store messages instead of delivering them.
+	if app.constants.CaptureMode {
+		for name, m := range app.messengers {
+			m.Close()
+			app.messengers[name] = capture.New(name, app.core.InsertCapturedMessage)
+		}
+		lo.Println("capture mode is enabled. Messages will be captured and not delivered")
+	}
+
+	// The built-in capture messenger that campaigns can use explicitly.
+	app.messengers[capture.Name] = capture.New(capture.Name, app.core.InsertCapturedMessage)
+
 	// Attach all messengers to the campaign manager.
 	for _, m := range app.messengers {
 		app.manager.AddMessenger(m)
 	}
 
+	// Retain the global send pause across restarts.
+	if ko.Bool("app.sending_paused") {
+		app.manager.Pause()
+	}
+
 	// Load system information.
 	app.about = initAbout(queries, db)
 
 	// Start cronjobs.
-	if cOpt.Constants.CacheSlowQueries {
-		initCron(app.core)
-	}
+	initCron(app)
 
 	// Start the campaign workers. The campaign batches (fetch from DB, push out
 	// messages) get processed at the specified interval.
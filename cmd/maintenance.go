@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
 )
 
@@ -90,3 +91,117 @@ func handleGCCampaignAnalytics(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, okResp{true})
 }
+
+// handleGCMedia deletes media uploaded before a date that aren't referenced
+// anywhere.
+func handleGCMedia(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	t, err := time.Parse(time.RFC3339, c.FormValue("before_date"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+	}
+
+	n, err := deleteDanglingMedia(t, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// handleGCTokens deletes expired API tokens, subscriber data export links,
+// and e-mail change confirmations.
+func handleGCTokens(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.DeleteExpiredTokens()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetIntegrityReport returns the counts of records with inconsistent
+// counters or states.
+func handleGetIntegrityReport(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetIntegrityReport()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleFixIntegrity fixes the inconsistencies in the integrity report and
+// returns the counts of records fixed.
+func handleFixIntegrity(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.FixIntegrity()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// deleteDanglingMedia deletes unused media uploaded before the given date
+// from the DB and the media store.
+func deleteDanglingMedia(before time.Time, app *App) (int, error) {
+	fnames, err := app.core.DeleteDanglingMedia(before, app.constants.MediaUpload.Provider)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, f := range fnames {
+		app.media.Delete(f)
+		app.media.Delete(thumbPrefix + f)
+	}
+
+	return len(fnames), nil
+}
+
+// runMaintenance runs the scheduled maintenance routines.
+func runMaintenance(app *App) {
+	if t, err := app.core.DeleteExpiredTokens(); err == nil {
+		if n := t.APITokens + t.SubscriberExports + t.EmailChanges; n > 0 {
+			app.log.Printf("maintenance: deleted %d expired API tokens, %d export links, %d e-mail change confirmations",
+				t.APITokens, t.SubscriberExports, t.EmailChanges)
+		}
+	}
+
+	if days := app.constants.Maintenance.MediaDays; days > 0 {
+		if n, err := deleteDanglingMedia(time.Now().AddDate(0, 0, -days), app); err == nil && n > 0 {
+			app.log.Printf("maintenance: deleted %d unused media", n)
+		}
+	}
+
+	// Fix the inconsistencies, or only report them.
+	var (
+		r   models.IntegrityReport
+		err error
+	)
+	if app.constants.Maintenance.FixIntegrity {
+		r, err = app.core.FixIntegrity()
+	} else {
+		r, err = app.core.GetIntegrityReport()
+	}
+	if err != nil {
+		return
+	}
+
+	if r != (models.IntegrityReport{}) {
+		verb := "found"
+		if app.constants.Maintenance.FixIntegrity {
+			verb = "fixed"
+		}
+		app.log.Printf("maintenance: %s integrity issues: %d campaign counts, %d campaign send counts, %d sequence steps, %d blocklisted subscriptions",
+			verb, r.CampaignCounts, r.CampaignSends, r.SequenceSteps, r.BlocklistedSubscriptions)
+	}
+}
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/knadh/listmonk/internal/core"
@@ -11,6 +17,14 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	// linkSlugLen is the length of the random base62 slugs of short links.
+	linkSlugLen = 7
+
+	// linkSlugAttempts is the number of random slugs tried on collisions.
+	linkSlugAttempts = 5
+)
+
 // store implements DataSource over the primary
 // database.
 type store struct {
@@ -25,6 +39,7 @@ func newManagerStore(q *models.Queries, c *core.Core, m media.Store) *store {
 		queries: q,
 		core:    c,
 		media:   m,
+		h:       &http.Client{Timeout: campContentFetchTimeout},
 	}
 }
 
@@ -60,6 +75,71 @@ func (s *store) UpdateCampaignStatus(campID int, status string) error {
 	return err
 }
 
+// FreezeCampaignContent fetches a campaign's body from its content URL and
+// writes it to the DB along with its checksum so that the content doesn't
+// change for the rest of the campaign's run.
+func (s *store) FreezeCampaignContent(c *models.Campaign) error {
+	b, err := fetchCampaignContent(c.ContentURL, s.h)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(b)
+	checksum := hex.EncodeToString(sum[:])
+	if _, err := s.queries.UpdateCampaignContent.Exec(c.ID, string(b), checksum); err != nil {
+		return err
+	}
+
+	c.Body = string(b)
+	c.ContentChecksum = checksum
+	return nil
+}
+
+// FreezeCampaignFeed fetches the entries of a campaign's RSS/Atom feed and
+// writes them to the DB so that they don't change for the rest of the
+// campaign's run.
+func (s *store) FreezeCampaignFeed(c *models.Campaign) error {
+	items, err := fetchCampaignFeed(c, s.h)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.queries.UpdateCampaignFeed.Exec(c.ID, items); err != nil {
+		return err
+	}
+
+	c.FeedItems = items
+	return nil
+}
+
+// QueueCampaignSubscribers queues subscribers of a campaign with send-time
+// optimization to be sent at their best hours.
+func (s *store) QueueCampaignSubscribers(campID int, subIDs []int64) error {
+	_, err := s.queries.QueueCampaignSubscribers.Exec(campID, pq.Int64Array(subIDs))
+	return err
+}
+
+// SetCampaignSTOQueued marks all the subscribers of a campaign as queued.
+func (s *store) SetCampaignSTOQueued(campID int) error {
+	_, err := s.queries.SetCampaignSTOQueued.Exec(campID)
+	return err
+}
+
+// NextQueuedSubscribers dequeues a batch of a campaign's queued subscribers
+// whose send time is due.
+func (s *store) NextQueuedSubscribers(campID, limit int) ([]models.Subscriber, error) {
+	var out []models.Subscriber
+	err := s.queries.NextQueuedSubscribers.Select(&out, campID, limit)
+	return out, err
+}
+
+// GetCampaignQueueCount returns the number of a campaign's queued subscribers.
+func (s *store) GetCampaignQueueCount(campID int) (int, error) {
+	var out int
+	err := s.queries.GetCampaignQueueCount.Get(&out, campID)
+	return out, err
+}
+
 // UpdateCampaignCounts updates a campaign's status.
 func (s *store) UpdateCampaignCounts(campID int, toSend int, sent int, lastSubID int) error {
 	_, err := s.queries.UpdateCampaignCounts.Exec(campID, toSend, sent, lastSubID)
@@ -102,6 +182,113 @@ func (s *store) CreateLink(url string) (string, error) {
 	return out, nil
 }
 
+// CreateLinkSlug returns the short link slug of a link (UUID) in a campaign (UUID),
+// creating one with a random slug if it doesn't exist. On the rare slug collision,
+// it's retried with another random slug.
+func (s *store) CreateLinkSlug(linkUUID, campUUID string) (string, error) {
+	for i := 0; i < linkSlugAttempts; i++ {
+		slug, err := generateRandomString(linkSlugLen)
+		if err != nil {
+			return "", err
+		}
+
+		var out string
+		if err := s.queries.CreateLinkSlug.Get(&out, linkUUID, campUUID, slug); err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return "", err
+		}
+
+		return out, nil
+	}
+
+	return "", fmt.Errorf("could not create a unique slug after %d attempts", linkSlugAttempts)
+}
+
+// NextSequenceMessages exits subscribers who've met the exit conditions of drip
+// sequences, enrolls new subscribers of the sequences' lists, and returns the
+// sequence steps that are due to be sent.
+func (s *store) NextSequenceMessages(limit int) ([]models.SequenceMessage, error) {
+	if _, err := s.queries.ExitSequenceSubscribers.Exec(); err != nil {
+		return nil, err
+	}
+	if _, err := s.queries.EnrollSequenceSubscribers.Exec(); err != nil {
+		return nil, err
+	}
+
+	var out []models.SequenceMessage
+	err := s.queries.NextSequenceMessages.Select(&out, limit)
+	return out, err
+}
+
+// AdvanceSequenceSubscribers advances subscribers past the sequence steps that have been queued for them.
+func (s *store) AdvanceSequenceSubscribers(msgs []models.SequenceMessage) error {
+	var (
+		seqIDs = make(pq.Int64Array, len(msgs))
+		subIDs = make(pq.Int64Array, len(msgs))
+		steps  = make(pq.Int64Array, len(msgs))
+		nums   = make(pq.Int64Array, len(msgs))
+	)
+	for i, m := range msgs {
+		seqIDs[i] = int64(m.SequenceID)
+		subIDs[i] = int64(m.ID)
+		steps[i] = int64(m.Step)
+		nums[i] = int64(m.NumSteps)
+	}
+
+	_, err := s.queries.AdvanceSequenceSubscribers.Exec(seqIDs, subIDs, steps, nums)
+	return err
+}
+
+// DeferSequenceSubscribers parks subscribers at the sequence steps that failed to be
+// queued for them until they're retried after the given duration.
+func (s *store) DeferSequenceSubscribers(msgs []models.SequenceMessage, after time.Duration) error {
+	var (
+		seqIDs = make(pq.Int64Array, len(msgs))
+		subIDs = make(pq.Int64Array, len(msgs))
+		steps  = make(pq.Int64Array, len(msgs))
+	)
+	for i, m := range msgs {
+		seqIDs[i] = int64(m.SequenceID)
+		subIDs[i] = int64(m.ID)
+		steps[i] = int64(m.Step)
+	}
+
+	_, err := s.queries.DeferSequenceSubscribers.Exec(seqIDs, subIDs, steps, int(after.Minutes()))
+	return err
+}
+
+// HoldMessage stores an arbitrary message that's pushed while sending is paused.
+func (s *store) HoldMessage(msg models.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.queries.HoldMessage.Exec(b)
+	return err
+}
+
+// NextHeldMessages removes and returns the oldest held messages.
+func (s *store) NextHeldMessages(limit int) ([]models.Message, error) {
+	var rows []json.RawMessage
+	if err := s.queries.NextHeldMessages.Select(&rows, limit); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Message, 0, len(rows))
+	for _, r := range rows {
+		var m models.Message
+		if err := json.Unmarshal(r, &m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
 // RecordBounce records a bounce event and returns the bounce count.
 func (s *store) RecordBounce(b models.Bounce) (int64, int, error) {
 	var res = struct {
@@ -129,3 +316,79 @@ func (s *store) DeleteSubscriber(id int64) error {
 	_, err := s.queries.DeleteSubscribers.Exec(pq.Int64Array{id})
 	return err
 }
+
+// LogCampaignSends writes the queueing and delivery statuses of campaign
+// messages to the send log.
+func (s *store) LogCampaignSends(sends []models.CampaignSend) error {
+	var (
+		campIDs   = make(pq.Int64Array, len(sends))
+		subIDs    = make(pq.Int64Array, len(sends))
+		statuses  = make(pq.StringArray, len(sends))
+		responses = make(pq.StringArray, len(sends))
+		queued    = make(pq.StringArray, len(sends))
+		times     = make(pq.StringArray, len(sends))
+	)
+	for i, l := range sends {
+		campIDs[i] = int64(l.CampaignID)
+		subIDs[i] = int64(l.SubscriberID)
+		statuses[i] = l.Status
+		responses[i] = l.Response
+		queued[i] = l.QueuedAt.Time.Format(time.RFC3339Nano)
+
+		t := l.QueuedAt.Time
+		if l.SentAt.Valid {
+			t = l.SentAt.Time
+		} else if l.DeferredAt.Valid {
+			t = l.DeferredAt.Time
+		}
+		times[i] = t.Format(time.RFC3339Nano)
+	}
+
+	_, err := s.queries.LogCampaignSends.Exec(campIDs, subIDs, statuses, responses, queued, times)
+	return err
+}
+
+// ArchiveSentMessages records the content of sent campaign messages in the sent message archive.
+func (s *store) ArchiveSentMessages(msgs []models.SentMessage) error {
+	var (
+		campIDs   = make(pq.Int64Array, len(msgs))
+		subIDs    = make(pq.Int64Array, len(msgs))
+		emails    = make(pq.StringArray, len(msgs))
+		subjects  = make(pq.StringArray, len(msgs))
+		types     = make(pq.StringArray, len(msgs))
+		bodies    = make(pq.StringArray, len(msgs))
+		altBodies = make(pq.StringArray, len(msgs))
+		hashes    = make(pq.StringArray, len(msgs))
+		versions  = make(pq.Int64Array, len(msgs))
+		times     = make(pq.StringArray, len(msgs))
+	)
+	for i, m := range msgs {
+		campIDs[i] = int64(m.CampaignID.Int)
+		subIDs[i] = int64(m.SubscriberID)
+		emails[i] = m.Email
+		subjects[i] = m.Subject
+		types[i] = m.ContentType
+		bodies[i] = m.Body
+		altBodies[i] = m.AltBody
+		hashes[i] = m.BodyHash
+		versions[i] = int64(m.CampaignVersion)
+		times[i] = m.SentAt.Time.Format(time.RFC3339Nano)
+	}
+
+	_, err := s.queries.InsertSentMessages.Exec(campIDs, subIDs, emails, subjects, types, bodies, altBodies,
+		hashes, versions, times)
+	return err
+}
+
+// GetCampaignVariants fetches the A/B test variants of a campaign.
+func (s *store) GetCampaignVariants(campID int) ([]models.CampaignVariant, error) {
+	var out []models.CampaignVariant
+	err := s.queries.GetCampaignVariants.Select(&out, campID)
+	return out, err
+}
+
+// SetCampaignABWaiting marks a campaign's A/B test as sent.
+func (s *store) SetCampaignABWaiting(campID int) error {
+	_, err := s.queries.SetCampaignABWaiting.Exec(campID)
+	return err
+}
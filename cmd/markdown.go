@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
+	"gopkg.in/yaml.v3"
+)
+
+// campaignFrontMatter represents the YAML front-matter block that can be
+// placed at the top of a Markdown campaign body. Lists and templates
+// may be referenced either by their IDs or names.
+type campaignFrontMatter struct {
+	Name      string   `yaml:"name"`
+	Subject   string   `yaml:"subject"`
+	FromEmail string   `yaml:"from_email"`
+	Lists     []string `yaml:"lists"`
+	Tags      []string `yaml:"tags"`
+	Template  string   `yaml:"template"`
+	Messenger string   `yaml:"messenger"`
+	SendAt    string   `yaml:"send_at"`
+	AltBody   string   `yaml:"altbody"`
+}
+
+// mdMaxSize is the maximum size (bytes) of an uploaded Markdown campaign document.
+const mdMaxSize = 2 * 1024 * 1024
+
+var (
+	// regexMDImage matches Markdown image references: ![alt](src "title")
+	regexMDImage = regexp.MustCompile(`(!\[[^\]]*\]\(\s*<?)([^)\s>]+)(>?(?:\s+"[^"]*")?\s*\))`)
+
+	// regexHTMLImage matches the src attribute of inline HTML <img> tags.
+	regexHTMLImage = regexp.MustCompile(`(?i)(<img\s[^>]*?src\s*=\s*["'])([^"']+)(["'])`)
+
+	regexURLScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.\-]*:`)
+)
+
+// handleImportMarkdownCampaign creates a campaign from an uploaded Markdown document
+// and bundle of assets (multipart). Campaign properties are read from the document's
+// front-matter and optionally, a `params` JSON field with the same structure as a
+// regular campaign create request. Relative image references in the document
+// are resolved against the uploaded assets which are added to the media store.
+func handleImportMarkdownCampaign(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("globals.messages.invalidData", "error", err.Error()))
+	}
+
+	// Optional campaign params.
+	o := campaignReq{}
+	if v := form.Value["params"]; len(v) > 0 && v[0] != "" {
+		if err := json.Unmarshal([]byte(v[0]), &o); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidData", "error", err.Error()))
+		}
+	}
+
+	// Read the Markdown document.
+	files := form.File["file"]
+	if len(files) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("media.invalidFile", "error", "file"))
+	}
+	body, err := readMultipartFile(files[0], mdMaxSize)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("media.invalidFile", "error", err.Error()))
+	}
+
+	o.Body = string(body)
+	o.ContentType = models.CampaignContentTypeMarkdown
+	if o.Type == "" {
+		o.Type = models.CampaignTypeRegular
+	}
+	if o.Messenger == "" {
+		o.Messenger = "email"
+	}
+
+	if out, err := applyCampaignFrontMatter(o, app); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else {
+		o = out
+	}
+
+	// Upload the referenced assets to the media store and rewrite the references.
+	uploaded := []int{}
+	cleanUp := func() {
+		for _, id := range uploaded {
+			if fname, err := app.core.DeleteMedia(id); err == nil {
+				app.media.Delete(fname)
+				app.media.Delete(thumbPrefix + fname)
+			}
+		}
+	}
+
+	body, err = bundleMarkdownAssets([]byte(o.Body), form.File["assets"], func(f *multipart.FileHeader) (string, error) {
+		m, err := uploadMedia(f, app)
+		if err != nil {
+			return "", err
+		}
+		uploaded = append(uploaded, m.ID)
+		return m.URL, nil
+	})
+	if err != nil {
+		cleanUp()
+
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	o.Body = string(body)
+
+	// Validate.
+	if c, err := validateCampaignFields(o, app); err != nil {
+		cleanUp()
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else {
+		o = c
+	}
+
+	if o.ArchiveTemplateID == 0 {
+		o.ArchiveTemplateID = o.TemplateID
+	}
+
+	out, err := app.core.CreateCampaign(o.Campaign, o.ListIDs, o.MediaIDs)
+	if err != nil {
+		cleanUp()
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// applyCampaignFrontMatter parses and strips the YAML front-matter block from
+// a Markdown campaign's body and applies the values in it to the campaign.
+// Values in the front-matter take precedence over the ones in the request.
+func applyCampaignFrontMatter(o campaignReq, app *App) (campaignReq, error) {
+	if o.ContentType != models.CampaignContentTypeMarkdown {
+		return o, nil
+	}
+
+	fm, body, ok, err := parseFrontMatter(o.Body)
+	if err != nil {
+		return o, errors.New(app.i18n.Ts("campaigns.fieldInvalidFrontMatter", "error", err.Error()))
+	}
+	if !ok {
+		return o, nil
+	}
+	o.Body = body
+
+	if fm.Name != "" {
+		o.Name = fm.Name
+	}
+	if fm.Subject != "" {
+		o.Subject = fm.Subject
+		if o.Name == "" {
+			o.Name = fm.Subject
+		}
+	}
+	if fm.FromEmail != "" {
+		o.FromEmail = fm.FromEmail
+	}
+	if fm.Messenger != "" {
+		o.Messenger = fm.Messenger
+	}
+	if fm.AltBody != "" {
+		o.AltBody = null.StringFrom(fm.AltBody)
+	}
+	if len(fm.Tags) > 0 {
+		o.Tags = fm.Tags
+	}
+
+	if fm.SendAt != "" {
+		t, err := time.Parse(time.RFC3339, fm.SendAt)
+		if err != nil {
+			return o, errors.New(app.i18n.T("campaigns.fieldInvalidSendAt"))
+		}
+		o.SendAt = null.TimeFrom(t)
+		o.SendLater = true
+	}
+
+	// Resolve list IDs and names.
+	if len(fm.Lists) > 0 {
+		lists, err := app.core.GetLists("")
+		if err != nil {
+			return o, err
+		}
+
+		ids := make([]int, 0, len(fm.Lists))
+		for _, ref := range fm.Lists {
+			id, ok := resolveFrontMatterRef(ref, lists, func(l models.List) (int, string) { return l.ID, l.Name })
+			if !ok {
+				return o, errors.New(app.i18n.Ts("globals.messages.notFound", "name", ref))
+			}
+			ids = append(ids, id)
+		}
+		o.ListIDs = ids
+	}
+
+	// Resolve the template ID or name.
+	if fm.Template != "" {
+		tpls, err := app.core.GetTemplates(models.TemplateTypeCampaign, true)
+		if err != nil {
+			return o, err
+		}
+
+		id, ok := resolveFrontMatterRef(fm.Template, tpls, func(t models.Template) (int, string) { return t.ID, t.Name })
+		if !ok {
+			return o, errors.New(app.i18n.Ts("globals.messages.notFound", "name", fm.Template))
+		}
+		o.TemplateID = id
+	}
+
+	return o, nil
+}
+
+// parseFrontMatter splits a leading `---` delimited YAML block from a document.
+// The bool indicates whether a front-matter block was found.
+func parseFrontMatter(s string) (campaignFrontMatter, string, bool, error) {
+	var fm campaignFrontMatter
+
+	doc := strings.TrimPrefix(s, "\ufeff")
+	doc = strings.ReplaceAll(doc, "\r\n", "\n")
+	if !strings.HasPrefix(doc, "---\n") {
+		return fm, s, false, nil
+	}
+
+	// Find the closing delimiter.
+	rest := doc[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return fm, s, false, nil
+	}
+
+	// The closing delimiter should be on its own line.
+	after := rest[end+len("\n---"):]
+	if after != "" && after[0] != '\n' {
+		return fm, s, false, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(rest[:end]), &fm); err != nil {
+		return fm, s, false, err
+	}
+
+	return fm, strings.TrimLeft(after, "\n"), true, nil
+}
+
+// resolveFrontMatterRef looks up a list or template referenced in
+// front-matter by its ID or (case-insensitive) name.
+func resolveFrontMatterRef[T any](ref string, items []T, get func(T) (int, string)) (int, bool) {
+	ref = strings.TrimSpace(ref)
+	id, _ := strconv.Atoi(ref)
+
+	for _, item := range items {
+		itemID, name := get(item)
+		if (id > 0 && itemID == id) || strings.EqualFold(name, ref) {
+			return itemID, true
+		}
+	}
+
+	return 0, false
+}
+
+// bundleMarkdownAssets rewrites relative image references in a Markdown document
+// to the URLs of the matching uploaded assets. The upload callback is invoked once
+// per referenced asset and should return the public URL of the stored file.
+func bundleMarkdownAssets(body []byte, assets []*multipart.FileHeader, upload func(*multipart.FileHeader) (string, error)) ([]byte, error) {
+	// Index assets by their cleaned path and base name.
+	files := make(map[string]*multipart.FileHeader, len(assets)*2)
+	for _, f := range assets {
+		p := path.Clean(strings.TrimPrefix(f.Filename, "./"))
+		files[p] = f
+		if _, ok := files[path.Base(p)]; !ok {
+			files[path.Base(p)] = f
+		}
+	}
+
+	var (
+		urls   = map[*multipart.FileHeader]string{}
+		outErr error
+	)
+
+	replace := func(re *regexp.Regexp, b []byte) []byte {
+		return re.ReplaceAllFunc(b, func(m []byte) []byte {
+			if outErr != nil {
+				return m
+			}
+
+			parts := re.FindSubmatch(m)
+			src := string(parts[2])
+			if !isRelativeAssetRef(src) {
+				return m
+			}
+
+			p := path.Clean(strings.TrimPrefix(src, "./"))
+			f, ok := files[p]
+			if !ok {
+				f, ok = files[path.Base(p)]
+			}
+			if !ok {
+				outErr = errors.New("asset not found: " + src)
+				return m
+			}
+
+			u, ok := urls[f]
+			if !ok {
+				var err error
+				if u, err = upload(f); err != nil {
+					outErr = err
+					return m
+				}
+				urls[f] = u
+			}
+
+			out := make([]byte, 0, len(m)+len(u))
+			out = append(out, parts[1]...)
+			out = append(out, u...)
+			return append(out, parts[3]...)
+		})
+	}
+
+	body = replace(regexMDImage, body)
+	body = replace(regexHTMLImage, body)
+	if outErr != nil {
+		return nil, outErr
+	}
+
+	return body, nil
+}
+
+// isRelativeAssetRef checks whether an image reference is a relative path
+// and not a URL, an absolute path, an anchor, or a template expression.
+func isRelativeAssetRef(s string) bool {
+	if s == "" || strings.HasPrefix(s, "/") || strings.HasPrefix(s, "#") ||
+		strings.Contains(s, "{{") || regexURLScheme.MatchString(s) {
+		return false
+	}
+	return true
+}
+
+// readMultipartFile reads the contents of an uploaded file up to max bytes.
+func readMultipartFile(f *multipart.FileHeader, max int64) ([]byte, error) {
+	if f.Size > max {
+		return nil, errors.New("file too large")
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, io.LimitReader(src, max)); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
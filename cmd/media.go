@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/disintegration/imaging"
+	"github.com/knadh/listmonk/internal/media"
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
 )
@@ -25,20 +27,28 @@ var (
 
 // handleUploadMedia handles media file uploads.
 func handleUploadMedia(c echo.Context) error {
-	var (
-		app     = c.Get("app").(*App)
-		cleanUp = false
-	)
+	app := c.Get("app").(*App)
+
 	file, err := c.FormFile("file")
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
 			app.i18n.Ts("media.invalidFile", "error", err.Error()))
 	}
 
+	m, err := uploadMedia(file, app)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, okResp{m})
+}
+
+// uploadMedia validates an uploaded file, scans it, and stores it. Files that are
+// flagged by the scanner are quarantined instead of being stored.
+func uploadMedia(file *multipart.FileHeader, app *App) (media.Media, error) {
 	// Read file contents in memory
 	src, err := file.Open()
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError,
+		return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
 			app.i18n.Ts("media.errorReadingFile", "error", err.Error()))
 	}
 	defer src.Close()
@@ -49,30 +59,66 @@ func handleUploadMedia(c echo.Context) error {
 		contentType = file.Header.Get("Content-Type")
 	)
 	if !isASCII(file.Filename) {
-		return echo.NewHTTPError(http.StatusUnprocessableEntity,
+		return media.Media{}, echo.NewHTTPError(http.StatusUnprocessableEntity,
 			app.i18n.Ts("media.invalidFileName", "name", file.Filename))
 	}
 
 	// Validate file extension.
 	if !inArray("*", app.constants.MediaUpload.Extensions) {
 		if ok := inArray(ext, app.constants.MediaUpload.Extensions); !ok {
-			return echo.NewHTTPError(http.StatusBadRequest,
+			return media.Media{}, echo.NewHTTPError(http.StatusBadRequest,
 				app.i18n.Ts("media.unsupportedFileType", "type", ext))
 		}
 	}
 
+	// Scan the file for malware.
+	res, err := scanFile(file.Filename, src, app)
+	if err != nil {
+		return media.Media{}, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
+			app.i18n.Ts("media.errorReadingFile", "error", err.Error()))
+	}
+
+	if res.Infected {
+		b, err := io.ReadAll(src)
+		if err != nil {
+			return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
+				app.i18n.Ts("media.errorReadingFile", "error", err.Error()))
+		}
+		if _, err := app.core.QuarantineMedia(file.Filename, contentType, res.Signature, b); err != nil {
+			return media.Media{}, err
+		}
+
+		app.log.Printf("quarantined flagged media %s: %s", file.Filename, res.Signature)
+		return media.Media{}, echo.NewHTTPError(http.StatusUnprocessableEntity,
+			app.i18n.Ts("media.quarantined", "name", file.Filename, "signature", res.Signature))
+	}
+
+	return storeMedia(file.Filename, contentType, src, app)
+}
+
+// storeMedia writes a file (and its thumbnail for images) to the media store,
+// and records it in the DB.
+func storeMedia(fileName, contentType string, src io.ReadSeeker, app *App) (media.Media, error) {
+	var (
+		cleanUp = false
+		ext     = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	)
+
 	// Sanitize filename.
-	fName := makeFilename(file.Filename)
+	fName := makeFilename(fileName)
 
 	// Add a random suffix to the filename to ensure uniqueness.
 	suffix, _ := generateRandomString(6)
 	fName = appendSuffixToFilename(fName, suffix)
 
 	// Upload the file.
-	fName, err = app.media.Put(fName, contentType, src)
+	fName, err := app.media.Put(fName, contentType, src)
 	if err != nil {
 		app.log.Printf("error uploading file: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError,
+		return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
 			app.i18n.Ts("media.errorUploading", "error", err.Error()))
 	}
 
@@ -96,11 +142,17 @@ func handleUploadMedia(c echo.Context) error {
 	// Create thumbnail from file for non-vector formats.
 	isImage := inArray(ext, imageExts)
 	if isImage {
-		thumbFile, w, h, err := processImage(file)
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			cleanUp = true
+			return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
+				app.i18n.Ts("media.errorReadingFile", "error", err.Error()))
+		}
+
+		thumbFile, w, h, err := processImage(src)
 		if err != nil {
 			cleanUp = true
 			app.log.Printf("error resizing image: %v", err)
-			return echo.NewHTTPError(http.StatusInternalServerError,
+			return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
 				app.i18n.Ts("media.errorResizing", "error", err.Error()))
 		}
 		width = w
@@ -111,7 +163,7 @@ func handleUploadMedia(c echo.Context) error {
 		if err != nil {
 			cleanUp = true
 			app.log.Printf("error saving thumbnail: %v", err)
-			return echo.NewHTTPError(http.StatusInternalServerError,
+			return media.Media{}, echo.NewHTTPError(http.StatusInternalServerError,
 				app.i18n.Ts("media.errorSavingThumbnail", "error", err.Error()))
 		}
 		thumbfName = tf
@@ -131,9 +183,9 @@ func handleUploadMedia(c echo.Context) error {
 	m, err := app.core.InsertMedia(fName, thumbfName, contentType, meta, app.constants.MediaUpload.Provider, app.media)
 	if err != nil {
 		cleanUp = true
-		return err
+		return media.Media{}, err
 	}
-	return c.JSON(http.StatusOK, okResp{m})
+	return m, nil
 }
 
 // handleGetMedia handles retrieval of uploaded media.
@@ -191,15 +243,69 @@ func handleDeleteMedia(c echo.Context) error {
 	return c.JSON(http.StatusOK, okResp{true})
 }
 
-// processImage reads the image file and returns thumbnail bytes and
-// the original image's width, and height.
-func processImage(file *multipart.FileHeader) (*bytes.Reader, int, int, error) {
-	src, err := file.Open()
+// handleGetQuarantinedMedia returns the quarantined media items.
+func handleGetQuarantinedMedia(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetQuarantinedMedia()
 	if err != nil {
-		return nil, 0, 0, err
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleReleaseQuarantinedMedia moves a quarantined media item (eg: a false
+// positive) to the media store.
+func handleReleaseQuarantinedMedia(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	q, err := app.core.GetQuarantinedMediaItem(id)
+	if err != nil {
+		return err
+	}
+
+	m, err := storeMedia(q.Filename, q.ContentType, bytes.NewReader(q.Body), app)
+	if err != nil {
+		return err
+	}
+
+	if err := app.core.DeleteQuarantinedMedia(id); err != nil {
+		return err
+	}
+
+	app.log.Printf("released quarantined media %s (%s)", q.Filename, q.Signature)
+	return c.JSON(http.StatusOK, okResp{m})
+}
+
+// handleDeleteQuarantinedMedia deletes a quarantined media item.
+func handleDeleteQuarantinedMedia(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteQuarantinedMedia(id); err != nil {
+		return err
 	}
-	defer src.Close()
 
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// processImage reads the image and returns thumbnail bytes and
+// the original image's width, and height.
+func processImage(src io.Reader) (*bytes.Reader, int, int, error) {
 	img, err := imaging.Decode(src)
 	if err != nil {
 		return nil, 0, 0, err
@@ -2,10 +2,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/textproto"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
 )
 
 const (
@@ -13,10 +21,27 @@ const (
 	notifTplCampaign     = "campaign-status"
 	notifSubscriberOptin = "subscriber-optin"
 	notifSubscriberData  = "subscriber-data"
+	notifEmailChange     = "subscriber-email-change"
+	notifEmailChangeOld  = "subscriber-email-change-notice"
+	notifTplBounce       = "bounce-alert"
+
+	slackTimeout = time.Second * 10
 )
 
 var (
 	reTitle = regexp.MustCompile(`(?s)<title\s*data-i18n\s*>(.+?)</title>`)
+
+	notifTypes = []string{
+		models.NotificationCampaign,
+		models.NotificationImport,
+		models.NotificationBounce,
+		models.NotificationMessenger,
+		models.NotificationMention,
+	}
+	notifChannels = []string{models.NotificationChannelFeed, models.NotificationChannelEmail}
+
+	// Time of the last bounce threshold alert.
+	lastBounceAlert time.Time
 )
 
 // notifData represents params commonly used across different notification
@@ -28,6 +53,12 @@ type notifData struct {
 
 // sendNotification sends out an e-mail notification to admins.
 func (app *App) sendNotification(toEmails []string, subject, tplName string, data interface{}) error {
+	return app.sendNotificationHeaders(toEmails, subject, tplName, data, nil)
+}
+
+// sendNotificationHeaders sends out an e-mail notification with the given
+// additional e-mail headers.
+func (app *App) sendNotificationHeaders(toEmails []string, subject, tplName string, data interface{}, hdr textproto.MIMEHeader) error {
 	if len(toEmails) == 0 {
 		return nil
 	}
@@ -48,6 +79,7 @@ func (app *App) sendNotification(toEmails []string, subject, tplName string, dat
 	m.Subject = subject
 	m.Body = body
 	m.Messenger = emailMsgr
+	m.Headers = hdr
 	if err := app.manager.PushMessage(m); err != nil {
 		app.log.Printf("error sending admin notification (%s): %v", subject, err)
 		return err
@@ -65,3 +97,274 @@ func getTplSubject(subject string, body []byte) (string, []byte) {
 
 	return strings.TrimSpace(string(m[1])), reTitle.ReplaceAll(body, []byte(""))
 }
+
+// notify records a notification in the in-app feed and sends it by e-mail to the
+// admin notification e-mails and to the users subscribed to the notification type,
+// and to the Slack webhook if it's enabled for the type.
+func (app *App) notify(typ, subject, body, tplName string, data interface{}) {
+	_ = app.core.InsertNotification(makeNotification("", typ, subject, body, data))
+
+	users, _ := app.core.GetNotificationEmails(typ, nil)
+	_ = app.sendNotification(dedupEmails(append(app.constants.NotifyEmails, users...)), subject, tplName, data)
+
+	app.postNotificationSlack(typ, subject, body)
+}
+
+// notifyUsers records a notification addressed to the given users in their feeds
+// and sends it by e-mail to the ones who have opted to receive it by e-mail.
+func (app *App) notifyUsers(usernames []string, typ, subject, body, tplName string, data interface{}) {
+	if len(usernames) == 0 {
+		return
+	}
+
+	for _, u := range usernames {
+		_ = app.core.InsertNotification(makeNotification(u, typ, subject, body, data))
+	}
+
+	emails, _ := app.core.GetNotificationEmails(typ, usernames)
+	_ = app.sendNotification(dedupEmails(emails), subject, tplName, data)
+
+	app.postNotificationSlack(typ, subject, body)
+}
+
+// postNotificationSlack posts a notification to the Slack webhook if it's enabled for the type.
+func (app *App) postNotificationSlack(typ, subject, body string) {
+	ns := app.constants.Notifications
+	if !ns.SlackEnabled || ns.SlackWebhookURL == "" || !inArray(typ, ns.SlackEvents) {
+		return
+	}
+
+	text := subject
+	if body != "" {
+		text += "\n" + body
+	}
+	go func() {
+		if err := postSlack(ns.SlackWebhookURL, text); err != nil {
+			app.log.Printf("error posting notification to Slack: %v", err)
+		}
+	}()
+}
+
+func makeNotification(username, typ, subject, body string, data interface{}) models.Notification {
+	n := models.Notification{Type: typ, Title: subject, Body: body, Data: models.JSON{}, Username: username}
+	if b, err := json.Marshal(data); err == nil {
+		_ = json.Unmarshal(b, &n.Data)
+	}
+
+	return n
+}
+
+// dedupEmails removes empty and duplicate (case insensitive) e-mails from a list.
+func dedupEmails(emails []string) []string {
+	var (
+		out  = make([]string, 0, len(emails))
+		seen = map[string]bool{}
+	)
+	for _, e := range emails {
+		k := strings.ToLower(strings.TrimSpace(e))
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, e)
+	}
+
+	return out
+}
+
+// postSlack posts a message to a Slack incoming webhook.
+func postSlack(url, text string) error {
+	b, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	h := &http.Client{Timeout: slackTimeout}
+	resp, err := h.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-OK response from Slack: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// checkBounceThreshold sends a notification if the number of bounces recorded in
+// the past hour exceeds the configured threshold. Bounces that have already been
+// alerted on are not counted again.
+func checkBounceThreshold(app *App) {
+	var (
+		now   = time.Now()
+		since = now.Add(-time.Hour)
+	)
+	if lastBounceAlert.After(since) {
+		since = lastBounceAlert
+	}
+
+	num, err := app.core.CountRecentBounces(since)
+	if err != nil {
+		return
+	}
+
+	threshold := app.constants.Notifications.BounceThreshold
+	if num < threshold {
+		return
+	}
+	lastBounceAlert = now
+
+	var (
+		n = strconv.Itoa(num)
+		t = strconv.Itoa(threshold)
+	)
+	app.notify(models.NotificationBounce, app.i18n.T("notifications.bounceSubject"),
+		app.i18n.Ts("notifications.bounceBody", "num", n, "threshold", t),
+		notifTplBounce, struct {
+			Count     string
+			Threshold string
+		}{n, t})
+}
+
+// handleGetNotifications returns the user's notification feed.
+func handleGetNotifications(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		pg       = app.paginator.NewFromURL(c.Request().URL.Query())
+		username = getUsername(c)
+	)
+
+	types, err := getFeedTypes(username, app)
+	if err != nil {
+		return err
+	}
+
+	res, total, err := app.core.GetNotifications(username, types, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	unread, err := app.core.CountUnreadNotifications(username, types)
+	if err != nil {
+		return err
+	}
+
+	var out struct {
+		models.PageResults
+		Unread int `json:"unread"`
+	}
+	out.Results = res
+	out.Total = total
+	out.Page = pg.Page
+	out.PerPage = pg.PerPage
+	out.Unread = unread
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetUnreadNotifications returns the number of unread notifications in the user's feed.
+func handleGetUnreadNotifications(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		username = getUsername(c)
+	)
+
+	types, err := getFeedTypes(username, app)
+	if err != nil {
+		return err
+	}
+
+	out, err := app.core.CountUnreadNotifications(username, types)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Unread int `json:"unread"`
+	}{out}})
+}
+
+// handleMarkNotificationsRead marks the given notifications, or all of them if
+// no IDs are given, as read by the user.
+func handleMarkNotificationsRead(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var req struct {
+		IDs []int `json:"ids"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := app.core.MarkNotificationsRead(getUsername(c), req.IDs); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleGetNotificationPrefs returns the user's notification preferences.
+func handleGetNotificationPrefs(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetNotificationPrefs(getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateNotificationPrefs saves the user's notification preferences.
+func handleUpdateNotificationPrefs(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.NotificationPrefs
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	o.Email = strings.TrimSpace(o.Email)
+	if o.Email != "" {
+		if a, err := mail.ParseAddress(o.Email); err != nil || a.Address != o.Email {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("subscribers.invalidEmail"))
+		}
+	}
+
+	for typ, channels := range o.Events {
+		if !inArray(typ, notifTypes) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", typ))
+		}
+		for _, ch := range channels {
+			if !inArray(ch, notifChannels) {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", ch))
+			}
+		}
+	}
+
+	out, err := app.core.UpsertNotificationPrefs(getUsername(c), o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// getFeedTypes returns the notification types the user has subscribed to in the feed.
+func getFeedTypes(username string, app *App) ([]string, error) {
+	prefs, err := app.core.GetNotificationPrefs(username)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(notifTypes))
+	for _, t := range notifTypes {
+		if prefs.Events.Has(t, models.NotificationChannelFeed) {
+			out = append(out, t)
+		}
+	}
+
+	return out, nil
+}
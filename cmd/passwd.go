@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/knadh/listmonk/internal/passwd"
+)
+
+// adminPasswd verifies the BasicAuth admin password, which is either
+// plaintext or an Argon2id hash (app.admin_password) in the config.
+type adminPasswd struct {
+	hash   string
+	params passwd.Params
+	log    *log.Logger
+
+	// SHA256 of the last verified password so that the Argon2id hash
+	// isn't computed on every request the admin makes.
+	mu       sync.RWMutex
+	verified []byte
+
+	rehash sync.Once
+}
+
+// check checks a password against the admin password.
+func (a *adminPasswd) check(password string) bool {
+	if !passwd.IsHash(a.hash) {
+		if subtle.ConstantTimeCompare([]byte(password), []byte(a.hash)) != 1 {
+			return false
+		}
+
+		a.logRehash("is stored in plaintext")
+		return true
+	}
+
+	sum := sha256.Sum256([]byte(password))
+	a.mu.RLock()
+	v := a.verified
+	a.mu.RUnlock()
+	if v != nil && subtle.ConstantTimeCompare(sum[:], v) == 1 {
+		return true
+	}
+
+	ok, p, err := passwd.Verify(password, a.hash)
+	if err != nil || !ok {
+		return false
+	}
+
+	a.mu.Lock()
+	a.verified = sum[:]
+	a.mu.Unlock()
+
+	if p != a.params {
+		a.logRehash("is hashed with outdated cost parameters")
+	}
+	return true
+}
+
+// logRehash logs a warning to replace the admin password in the config with
+// a hash generated with --hash-password, once after the first successful login.
+// The hash itself is never logged as the logs are viewable in the admin.
+func (a *adminPasswd) logRehash(reason string) {
+	a.rehash.Do(func() {
+		a.log.Printf("WARNING: app.admin_password %s. Replace it in the config with the Argon2id hash generated with --hash-password", reason)
+	})
+}
+
+// hashPassword reads a password from r and prints its Argon2id hash
+// for app.admin_password.
+func hashPassword(r io.Reader, p passwd.Params) error {
+	fmt.Print("password: ")
+	pwd, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	pwd = strings.TrimRight(pwd, "\r\n")
+	if pwd == "" {
+		return fmt.Errorf("empty password")
+	}
+
+	h, err := passwd.Hash(pwd, p)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println(h)
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/knadh/listmonk/internal/previews"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+var regexPreviewClient = regexp.MustCompile(`^[a-z0-9_\-.]{1,64}$`)
+
+// handleGetCampaignPreviews returns the e-mail client preview screenshots of a campaign.
+func handleGetCampaignPreviews(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignPreviews(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{makePreviewURLs(out, app)})
+}
+
+// handleGetCampaignPreviewImage serves the preview screenshot of a campaign in an e-mail client.
+func handleGetCampaignPreviewImage(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignPreviewImage(id, c.Param("client"))
+	if err != nil {
+		return err
+	}
+
+	// Never serve anything but images from the service on the admin's origin.
+	if !previews.IsImageType(out.ContentType) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, app.i18n.Ts("globals.messages.invalidFields", "name", "content_type"))
+	}
+
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Blob(http.StatusOK, out.ContentType, out.Image)
+}
+
+// handleGenerateCampaignPreviews renders a campaign, submits it to the configured
+// screenshot service, and stores the preview images it returns for each e-mail client.
+func handleGenerateCampaignPreviews(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if !app.constants.Previews.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.previewsDisabled"))
+	}
+
+	camp, err := app.core.GetCampaignForPreview(id, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := loadLiveCampaignContent(&camp, app); err != nil {
+		return err
+	}
+
+	msg, err := renderCampaignPreview(&camp, app)
+	if err != nil {
+		return err
+	}
+
+	res, err := app.previews.Render(previews.Message{
+		CampaignID: id,
+		UUID:       camp.UUID,
+		Subject:    msg.Subject(),
+		FromEmail:  camp.FromEmail,
+		Body:       string(msg.Body()),
+		Clients:    app.constants.Previews.Clients,
+	})
+	if err != nil {
+		app.log.Printf("error generating campaign previews: %v", err)
+		return echo.NewHTTPError(http.StatusBadGateway,
+			app.i18n.Ts("campaigns.previewsError", "error", err.Error()))
+	}
+
+	// Skip clients with invalid or duplicate names, and responses that aren't
+	// images (which are served on the admin's origin), in the service's response.
+	var (
+		list = make([]models.CampaignPreview, 0, len(res))
+		seen = map[string]bool{}
+	)
+	for _, p := range res {
+		if !regexPreviewClient.MatchString(p.Client) || seen[p.Client] {
+			continue
+		}
+		if !previews.IsImageType(p.ContentType) {
+			app.log.Printf("skipping campaign preview of %s with unsupported type %s", p.Client, p.ContentType)
+			continue
+		}
+		seen[p.Client] = true
+
+		list = append(list, models.CampaignPreview{
+			Client:      p.Client,
+			ContentType: p.ContentType,
+			Image:       p.Image,
+		})
+	}
+
+	out, err := app.core.SetCampaignPreviews(id, list)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{makePreviewURLs(out, app)})
+}
+
+// makePreviewURLs sets the image URLs on the given previews.
+func makePreviewURLs(out []models.CampaignPreview, app *App) []models.CampaignPreview {
+	for i, p := range out {
+		out[i].URL = fmt.Sprintf("%s/api/campaigns/%d/previews/%s", app.constants.RootURL, p.CampaignID, url.PathEscape(p.Client))
+	}
+	return out
+}
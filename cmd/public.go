@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"database/sql"
 	"fmt"
 	"html/template"
@@ -11,9 +12,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/knadh/listmonk/internal/ga4"
 	"github.com/knadh/listmonk/internal/i18n"
 	"github.com/knadh/listmonk/internal/manager"
+	"github.com/knadh/listmonk/internal/subimporter"
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
 	"github.com/lib/pq"
@@ -21,8 +25,15 @@ import (
 
 const (
 	tplMessage = "message"
+
+	// emailChangeExpiry is the validity of e-mail change confirmation links.
+	emailChangeExpiry = time.Hour * 48
 )
 
+// snoozeDays are the number of days subscribers can pause their subscriptions
+// for instead of unsubscribing.
+var snoozeDays = []int{30, 60, 90}
+
 // tplRenderer wraps a template.tplRenderer for echo.
 type tplRenderer struct {
 	templates           *template.Template
@@ -60,11 +71,14 @@ type unsubTpl struct {
 	publicTpl
 	Subscriber       models.Subscriber
 	Subscriptions    []models.Subscription
+	Fields           []formField
 	SubUUID          string
 	AllowBlocklist   bool
 	AllowExport      bool
 	AllowWipe        bool
 	AllowPreferences bool
+	AllowSnooze      bool
+	SnoozeDays       []int
 	ShowManage       bool
 }
 
@@ -84,9 +98,19 @@ type msgTpl struct {
 type subFormTpl struct {
 	publicTpl
 	Lists      []models.List
+	Fields     []formField
 	CaptchaKey string
 }
 
+// formField is a list's attribute field on a public page with its current value
+// and the UUIDs of the lists on the page that collect it.
+type formField struct {
+	models.ListField
+	ListUUIDs string
+	Value     string
+	Checked   bool
+}
+
 var (
 	pixelPNG = drawTransparentImage(3, 14)
 )
@@ -205,6 +229,8 @@ func handleSubscriptionPage(c echo.Context) error {
 	out.AllowExport = app.constants.Privacy.AllowExport
 	out.AllowWipe = app.constants.Privacy.AllowWipe
 	out.AllowPreferences = app.constants.Privacy.AllowPreferences
+	out.AllowSnooze = app.constants.Privacy.AllowSnooze
+	out.SnoozeDays = snoozeDays
 
 	s, err := app.core.GetSubscriber(0, subUUID, "")
 	if err != nil {
@@ -237,6 +263,18 @@ func handleSubscriptionPage(c echo.Context) error {
 
 			out.Subscriptions = append(out.Subscriptions, s)
 		}
+
+		for _, f := range listFormFields(subscribedLists(out.Subscriptions), true) {
+			ff := formField{ListField: f}
+			switch v := out.Subscriber.Attribs[f.Key].(type) {
+			case bool:
+				ff.Checked = v
+			case nil:
+			default:
+				ff.Value = fmt.Sprintf("%v", v)
+			}
+			out.Fields = append(out.Fields, ff)
+		}
 	}
 
 	return c.Render(http.StatusOK, "subscription", out)
@@ -253,9 +291,11 @@ func handleSubscriptionPrefs(c echo.Context) error {
 
 		req struct {
 			Name      string   `form:"name" json:"name"`
+			Email     string   `form:"email" json:"email"`
 			ListUUIDs []string `form:"l" json:"list_uuids"`
 			Blocklist bool     `form:"blocklist" json:"blocklist"`
 			Manage    bool     `form:"manage" json:"manage"`
+			Snooze    int      `form:"snooze" json:"snooze"`
 		}
 	)
 
@@ -265,10 +305,35 @@ func handleSubscriptionPrefs(c echo.Context) error {
 			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("globals.messages.invalidData")))
 	}
 
-	// Simple unsubscribe.
+	// Pause the subscriptions instead of unsubscribing. They're restored
+	// automatically when the snooze ends.
+	if req.Snooze > 0 && !req.Manage {
+		if !app.constants.Privacy.AllowSnooze || !isSnoozeDays(req.Snooze) {
+			return c.Render(http.StatusBadRequest, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.invalidFeature")))
+		}
+
+		if err := app.core.SnoozeSubscription(subUUID, campUUID, req.Snooze); err != nil {
+			return c.Render(http.StatusInternalServerError, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.errorProcessingRequest")))
+		}
+
+		until := time.Now().AddDate(0, 0, req.Snooze).Format("2 Jan 2006")
+		return c.Render(http.StatusOK, tplMessage,
+			makeMsgTpl(app.i18n.T("public.snoozedTitle"), "", app.i18n.Ts("public.snoozedInfo", "date", until)))
+	}
+
+	// Simple unsubscribe. Signed links from transactional messages don't
+	// have a campaign and unsubscribe from all lists.
 	blocklist := app.constants.Privacy.AllowBlocklist && req.Blocklist
 	if !req.Manage || blocklist {
-		if err := app.core.UnsubscribeByCampaign(subUUID, campUUID, blocklist); err != nil {
+		var err error
+		if campUUID != "" {
+			err = app.core.UnsubscribeByCampaign(subUUID, campUUID, blocklist)
+		} else {
+			err = app.core.UnsubscribeSubscriber(subUUID, blocklist)
+		}
+		if err != nil {
 			return c.Render(http.StatusInternalServerError, tplMessage,
 				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.errorProcessingRequest")))
 		}
@@ -299,7 +364,44 @@ func handleSubscriptionPrefs(c echo.Context) error {
 	}
 	sub.Name = req.Name
 
-	// Update name.
+	subs, err := app.core.GetSubscriptions(0, subUUID, false)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("public.errorFetchingLists"))
+	}
+
+	// Update the attributes of the editable fields of the subscribed lists.
+	attribs, err := readFormFields(c, listFormFields(subscribedLists(subs), true), nil, app)
+	if err != nil {
+		return c.Render(http.StatusBadRequest, tplMessage,
+			makeMsgTpl(app.i18n.T("public.errorTitle"), "", fmt.Sprintf("%s", err.(*echo.HTTPError).Message)))
+	}
+	if len(attribs) > 0 {
+		if sub.Attribs == nil {
+			sub.Attribs = models.JSON{}
+		}
+		for k, v := range attribs {
+			sub.Attribs[k] = v
+		}
+	}
+
+	// If the e-mail has changed, either change it right away or, if changes
+	// have to be confirmed, send a confirmation link to the new address.
+	var newEmail string
+	if req.Email != "" && !strings.EqualFold(strings.TrimSpace(req.Email), sub.Email) {
+		em, err := app.importer.SanitizeEmail(req.Email)
+		if err != nil {
+			return c.Render(http.StatusBadRequest, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", err.Error()))
+		}
+
+		if app.constants.Privacy.ConfirmEmailChange {
+			newEmail = em
+		} else {
+			sub.Email = em
+		}
+	}
+
+	// Update name (and e-mail).
 	if _, err := app.core.UpdateSubscriber(sub.ID, sub); err != nil {
 		return c.Render(http.StatusInternalServerError, tplMessage,
 			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.errorProcessingRequest")))
@@ -312,11 +414,6 @@ func handleSubscriptionPrefs(c echo.Context) error {
 		reqUUIDs[u] = struct{}{}
 	}
 
-	subs, err := app.core.GetSubscriptions(0, subUUID, false)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("public.errorFetchingLists"))
-	}
-
 	unsubUUIDs := make([]string, 0, len(req.ListUUIDs))
 	for _, s := range subs {
 		if s.Type == models.ListTypePrivate {
@@ -334,6 +431,16 @@ func handleSubscriptionPrefs(c echo.Context) error {
 
 	}
 
+	if newEmail != "" {
+		if err := requestEmailChange(sub, newEmail, app); err != nil {
+			return c.Render(http.StatusInternalServerError, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.errorProcessingRequest")))
+		}
+
+		return c.Render(http.StatusOK, tplMessage,
+			makeMsgTpl(app.i18n.T("public.emailChangeSentTitle"), "", app.i18n.T("public.emailChangeSent")))
+	}
+
 	return c.Render(http.StatusOK, tplMessage,
 		makeMsgTpl(app.i18n.T("globals.messages.done"), "", app.i18n.T("public.prefsSaved")))
 }
@@ -433,6 +540,20 @@ func handleSubscriptionFormPage(c echo.Context) error {
 	out.Title = app.i18n.T("public.sub")
 	out.Lists = lists
 
+	// Attribute fields of the lists. A field shared by lists is shown once and
+	// is shown when any of its lists is selected.
+	pos := map[string]int{}
+	for _, l := range lists {
+		for _, f := range l.FormFields {
+			if i, ok := pos[f.Key]; ok {
+				out.Fields[i].ListUUIDs += " " + l.UUID
+				continue
+			}
+			pos[f.Key] = len(out.Fields)
+			out.Fields = append(out.Fields, formField{ListField: f, ListUUIDs: l.UUID})
+		}
+	}
+
 	if app.constants.Security.EnableCaptcha {
 		out.CaptchaKey = app.constants.Security.CaptchaKey
 	}
@@ -516,16 +637,72 @@ func handleLinkRedirect(c echo.Context) error {
 		subUUID  = c.Param("subUUID")
 	)
 
+	return redirectLink(c, app, linkUUID, campUUID, subUUID)
+}
+
+// handleShortLinkRedirect resolves a short link slug, optionally with the compact
+// subscriber UUID, to its link and campaign, registers the click, and redirects
+// to the link's URL.
+func handleShortLinkRedirect(c echo.Context) error {
+	var (
+		app     = c.Get("app").(*App)
+		slug    = c.Param("slug")
+		subUUID = ""
+	)
+
+	if s := c.Param("sub"); s != "" {
+		u, err := manager.ParseShortUUID(s)
+		if err != nil {
+			return c.Render(http.StatusBadRequest, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.invalidLink")))
+		}
+		subUUID = u
+	}
+
+	linkUUID, campUUID, err := app.core.GetLinkSlug(slug)
+	if err != nil {
+		e := err.(*echo.HTTPError)
+		return c.Render(e.Code, tplMessage, makeMsgTpl(app.i18n.T("public.errorTitle"), "", e.Message.(string)))
+	}
+
+	return redirectLink(c, app, linkUUID, campUUID, subUUID)
+}
+
+// redirectLink registers a click on a tracked link in a campaign and redirects
+// to the link's URL.
+func redirectLink(c echo.Context, app *App, linkUUID, campUUID, subUUID string) error {
 	// If individual tracking is disabled, do not record the subscriber ID.
-	if !app.constants.Privacy.IndividualTracking {
+	if !app.constants.Privacy.IndividualTracking || app.constants.Privacy.AnonymousLinks {
 		subUUID = ""
 	}
 
-	url, err := app.core.RegisterCampaignLinkClick(linkUUID, campUUID, subUUID)
+	l, err := app.core.RegisterCampaignLinkClick(linkUUID, campUUID, subUUID)
 	if err != nil {
 		e := err.(*echo.HTTPError)
 		return c.Render(e.Code, tplMessage, makeMsgTpl(app.i18n.T("public.errorTitle"), "", e.Error()))
 	}
+	url := l.URL
+	pushGA4Event(ga4.EventClick, campUUID, subUUID, url, app)
+
+	// Tracked links are stored without the UTM parameters, which are appended here.
+	def := models.UTM{Source: app.constants.UTM.Source, Medium: app.constants.UTM.Medium}
+	if u := l.UTMParams(app.constants.UTM.Enabled, def); u != nil {
+		url = u.AppendTo(url)
+	}
+
+	// With anonymous links, clicks are only counted in aggregate and nothing
+	// about the subscriber or the referring page (besides the campaign's UTM
+	// parameters, which are the same for everyone) is passed on to the destination.
+	if app.constants.Privacy.AnonymousLinks {
+		c.Response().Header().Set("Referrer-Policy", "no-referrer")
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.Redirect(http.StatusTemporaryRedirect, url)
+	}
+
+	// Pass the signed goal token on to the campaign's goal landing pages.
+	if len(l.GoalURLs) > 0 {
+		url = appendGoalToken(url, l.GoalURLs, l.CampaignID, int(l.SubscriberID.Int), app.constants.Security.SigningKey)
+	}
 
 	return c.Redirect(http.StatusTemporaryRedirect, url)
 }
@@ -550,6 +727,8 @@ func handleRegisterCampaignView(c echo.Context) error {
 	if campUUID != dummyUUID && subUUID != dummyUUID {
 		if err := app.core.RegisterCampaignView(campUUID, subUUID); err != nil {
 			app.log.Printf("error registering campaign view: %s", err)
+		} else {
+			pushGA4Event(ga4.EventOpen, campUUID, subUUID, "", app)
 		}
 	}
 
@@ -558,9 +737,9 @@ func handleRegisterCampaignView(c echo.Context) error {
 }
 
 // handleSelfExportSubscriberData pulls the subscriber's profile, list subscriptions,
-// campaign views and clicks and produces a JSON report that is then e-mailed
-// to the subscriber. This is a privacy feature and the data that's exported
-// is dependent on the configuration.
+// campaign views and clicks and produces a JSON report in the background, a signed
+// and expiring download link to which is then e-mailed to the subscriber. This is
+// a privacy feature and the data that's exported is dependent on the configuration.
 func handleSelfExportSubscriberData(c echo.Context) error {
 	var (
 		app     = c.Get("app").(*App)
@@ -572,22 +751,46 @@ func handleSelfExportSubscriberData(c echo.Context) error {
 			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.Ts("public.invalidFeature")))
 	}
 
+	go func() {
+		if err := sendSubscriberDataLink(subUUID, app); err != nil {
+			app.log.Printf("error sending subscriber data export link: %v", err)
+		}
+	}()
+
+	return c.Render(http.StatusOK, tplMessage,
+		makeMsgTpl(app.i18n.T("public.dataSentTitle"), "", app.i18n.T("public.dataSent")))
+}
+
+// sendSubscriberDataLink generates a subscriber's data export and e-mails
+// them a signed link to download it that expires after the configured duration.
+func sendSubscriberDataLink(subUUID string, app *App) error {
 	// Get the subscriber's data. A single query that gets the profile,
 	// list subscriptions, campaign views, and link clicks. Names of
 	// private lists are replaced with "Private list".
 	data, b, err := exportSubscriberData(0, subUUID, app.constants.Privacy.Exportable, app)
 	if err != nil {
-		app.log.Printf("error exporting subscriber data: %s", err)
-		return c.Render(http.StatusInternalServerError, tplMessage,
-			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.Ts("public.errorProcessingRequest")))
+		return err
+	}
+
+	expiresAt := time.Now().Add(app.constants.Privacy.ExportLinkExpiry)
+	exportUUID, err := app.core.CreateSubscriberExport(subUUID, b, expiresAt)
+	if err != nil {
+		return err
 	}
 
-	// Prepare the attachment e-mail.
+	// Prepare the e-mail with the download link.
 	var msg bytes.Buffer
-	if err := app.notifTpls.tpls.ExecuteTemplate(&msg, notifSubscriberData, data); err != nil {
+	if err := app.notifTpls.tpls.ExecuteTemplate(&msg, notifSubscriberData, struct {
+		models.SubscriberExportProfile
+		DownloadURL string
+		ExpiresAt   string
+	}{
+		SubscriberExportProfile: data,
+		DownloadURL:             fmt.Sprintf(app.constants.SubExportURL, exportUUID, signExportUUID(exportUUID, app.constants.Security.SigningKey)),
+		ExpiresAt:               expiresAt.Format(time.RFC1123),
+	}); err != nil {
 		app.log.Printf("error compiling notification template '%s': %v", notifSubscriberData, err)
-		return c.Render(http.StatusInternalServerError, tplMessage,
-			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.Ts("public.errorProcessingRequest")))
+		return err
 	}
 
 	var (
@@ -596,29 +799,111 @@ func handleSelfExportSubscriberData(c echo.Context) error {
 	)
 	subject, body = getTplSubject(subject, body)
 
-	// Send the data as a JSON attachment to the subscriber.
-	const fname = "data.json"
-	if err := app.messengers[emailMsgr].Push(models.Message{
+	return app.manager.PushMessage(models.Message{
 		ContentType: app.notifTpls.contentType,
 		From:        app.constants.FromEmail,
 		To:          []string{data.Email},
 		Subject:     subject,
 		Body:        body,
-		Attachments: []models.Attachment{
-			{
-				Name:    fname,
-				Content: b,
-				Header:  manager.MakeAttachmentHeader(fname, "base64", "application/json"),
-			},
-		},
+		Messenger:   emailMsgr,
+	})
+}
+
+// handleDownloadSubscriberData serves a subscriber's data export from a
+// signed download link until it expires.
+func handleDownloadSubscriberData(c echo.Context) error {
+	var (
+		app        = c.Get("app").(*App)
+		exportUUID = c.Param("exportUUID")
+		sig        = signExportUUID(exportUUID, app.constants.Security.SigningKey)
+	)
+
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(c.Param("sig"))) != 1 {
+		return c.Render(http.StatusBadRequest, tplMessage,
+			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.invalidLink")))
+	}
+
+	b, err := app.core.GetSubscriberExport(exportUUID)
+	if err != nil {
+		if e, ok := err.(*echo.HTTPError); ok && e.Code == http.StatusNotFound {
+			return c.Render(http.StatusNotFound, tplMessage,
+				makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.dataLinkExpired")))
+		}
+		return c.Render(http.StatusInternalServerError, tplMessage,
+			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.Ts("public.errorProcessingRequest")))
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="data.json"`)
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, b)
+}
+
+// requestEmailChange records a pending change of a subscriber's e-mail and e-mails
+// a link to confirm it to the new address, and a notice to the old one. The
+// change takes effect only when it's confirmed.
+func requestEmailChange(sub models.Subscriber, email string, app *App) error {
+	expiresAt := time.Now().Add(emailChangeExpiry)
+	changeUUID, err := app.core.CreateEmailChange(sub.ID, email, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if err := app.sendNotification([]string{email}, app.i18n.T("email.emailChange.title"), notifEmailChange, struct {
+		Email      string
+		ConfirmURL string
+		ExpiresAt  string
+	}{
+		Email:      email,
+		ConfirmURL: fmt.Sprintf(app.constants.EmailChangeURL, changeUUID, signEmailChangeUUID(changeUUID, app.constants.Security.SigningKey)),
+		ExpiresAt:  expiresAt.Format(time.RFC1123),
 	}); err != nil {
-		app.log.Printf("error e-mailing subscriber profile: %s", err)
+		return err
+	}
+
+	return app.sendNotification([]string{sub.Email}, app.i18n.T("email.emailChange.title"), notifEmailChangeOld, nil)
+}
+
+// handleConfirmEmailChange applies a pending subscriber e-mail change from the
+// signed link e-mailed to the new address.
+func handleConfirmEmailChange(c echo.Context) error {
+	var (
+		app        = c.Get("app").(*App)
+		changeUUID = c.Param("changeUUID")
+		sig        = signEmailChangeUUID(changeUUID, app.constants.Security.SigningKey)
+	)
+
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(c.Param("sig"))) != 1 {
+		return c.Render(http.StatusBadRequest, tplMessage,
+			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.invalidLink")))
+	}
+
+	if _, err := app.core.ConfirmEmailChange(changeUUID); err != nil {
+		if e, ok := err.(*echo.HTTPError); ok {
+			switch e.Code {
+			case http.StatusNotFound:
+				return c.Render(http.StatusNotFound, tplMessage,
+					makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.emailChangeExpired")))
+			case http.StatusConflict:
+				return c.Render(http.StatusConflict, tplMessage,
+					makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("subscribers.emailExists")))
+			}
+		}
 		return c.Render(http.StatusInternalServerError, tplMessage,
 			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.Ts("public.errorProcessingRequest")))
 	}
 
 	return c.Render(http.StatusOK, tplMessage,
-		makeMsgTpl(app.i18n.T("public.dataSentTitle"), "", app.i18n.T("public.dataSent")))
+		makeMsgTpl(app.i18n.T("public.emailChangedTitle"), "", app.i18n.T("public.emailChanged")))
+}
+
+// signEmailChangeUUID returns the HMAC signature of a subscriber e-mail change UUID.
+func signEmailChangeUUID(changeUUID, key string) string {
+	return signString("email-change:"+changeUUID, key)
+}
+
+// signExportUUID returns the HMAC signature of a subscriber data export UUID.
+func signExportUUID(exportUUID, key string) string {
+	return signString("subscriber-export:"+exportUUID, key)
 }
 
 // handleWipeSubscriberData allows a subscriber to delete their data. The
@@ -667,6 +952,13 @@ func processSubForm(c echo.Context) (bool, error) {
 			Name          string   `form:"name" json:"name"`
 			Email         string   `form:"email" json:"email"`
 			FormListUUIDs []string `form:"l" json:"list_uuids"`
+
+			// Optional tag of the form or widget the subscription came from.
+			Source string `form:"source" json:"source"`
+
+			// Values of the lists' attribute fields in JSON requests. Form
+			// requests post them as a.{key} fields.
+			Attribs map[string]interface{} `form:"-" json:"attribs"`
 		}
 	)
 
@@ -703,12 +995,56 @@ func processSubForm(c echo.Context) (bool, error) {
 
 	listUUIDs := pq.StringArray(req.FormListUUIDs)
 
+	// Attributes collected by the lists' form fields.
+	lists, err := app.core.GetListsByIDs(nil, req.FormListUUIDs)
+	if err != nil {
+		return false, err
+	}
+	attribs, err := readFormFields(c, listFormFields(lists, false), req.Attribs, app)
+	if err != nil {
+		return false, err
+	}
+
+	// Flag or reject role and spam-trap addresses as configured on the lists.
+	mode, err := getAddressFilter(nil, req.FormListUUIDs, app)
+	if err != nil {
+		return false, err
+	}
+	newSub, err := app.importer.FilterAddress(subimporter.SubReq{Subscriber: models.Subscriber{
+		Name:    req.Name,
+		Email:   req.Email,
+		Status:  models.SubscriberStatusEnabled,
+		Attribs: attribs,
+	}}, mode)
+	if err != nil {
+		// Existing subscribers may have been exempted with the override attribute.
+		sub, e := app.core.GetSubscriber(0, "", req.Email)
+		if e != nil || sub.Attribs[subimporter.AttribAddressFilterOverride] != true {
+			return false, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	// Subscriber caps and entitlements of premium lists.
+	if err := checkListAccess(req.FormListUUIDs, req.Email, req.Name, app); err != nil {
+		return false, err
+	}
+
+	// Subscriptions from trusted sources (eg: checkout flows that have already
+	// verified the address) are pre-confirmed and skip the opt-in e-mail.
+	confirmedBy, preconfirm := getTrustedSource(c, app)
+
+	source := subSourceForm
+	if preconfirm {
+		source = subSourceTrusted + confirmedBy
+	} else if tag := strings.TrimSpace(req.Source); tag != "" {
+		if len(tag) > stdInputMaxLen {
+			return false, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "source"))
+		}
+		source = subSourceForm + ":" + tag
+	}
+
 	// Insert the subscriber into the DB.
-	_, hasOptin, err := app.core.InsertSubscriber(models.Subscriber{
-		Name:   req.Name,
-		Email:  req.Email,
-		Status: models.SubscriberStatusEnabled,
-	}, nil, listUUIDs, false)
+	_, hasOptin, err := app.core.InsertSubscriber(newSub.Subscriber, nil, listUUIDs, preconfirm, confirmedBy, source)
 	if err != nil {
 		// Subscriber already exists. Update subscriptions.
 		if e, ok := err.(*echo.HTTPError); ok && e.Code == http.StatusConflict {
@@ -717,7 +1053,15 @@ func processSubForm(c echo.Context) (bool, error) {
 				return false, err
 			}
 
-			_, hasOptin, err := app.core.UpdateSubscriberWithLists(sub.ID, sub, nil, listUUIDs, false, false)
+			// Apply the filter to the existing record which may carry the override attribute.
+			if s, err := app.importer.FilterAddress(subimporter.SubReq{Subscriber: sub}, mode); err == nil {
+				sub = s.Subscriber
+			}
+
+			// The collected attributes are only set on new subscribers. As the public form
+			// is unauthenticated, existing subscribers can only change their attributes
+			// on the (signed) preferences page.
+			_, hasOptin, err := app.core.UpdateSubscriberWithLists(sub.ID, sub, nil, listUUIDs, preconfirm, confirmedBy, source, false)
 			if err != nil {
 				return false, err
 			}
@@ -730,3 +1074,113 @@ func processSubForm(c echo.Context) (bool, error) {
 
 	return hasOptin, nil
 }
+
+// isSnoozeDays checks whether a number of days is one of the snooze options.
+func isSnoozeDays(days int) bool {
+	for _, d := range snoozeDays {
+		if d == days {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subscribedLists returns the lists of the subscriptions that aren't
+// unsubscribed and aren't private.
+func subscribedLists(subs []models.Subscription) []models.List {
+	out := make([]models.List, 0, len(subs))
+	for _, s := range subs {
+		if s.Type == models.ListTypePrivate || s.SubscriptionStatus.String == models.SubscriptionStatusUnsubscribed {
+			continue
+		}
+		out = append(out, s.List)
+	}
+
+	return out
+}
+
+// listFormFields returns the attribute fields of the given lists in order. A
+// field whose key is already taken by an earlier list is skipped. If editable
+// is true, only the fields that are editable on the preferences page are returned.
+func listFormFields(lists []models.List, editable bool) []models.ListField {
+	var (
+		out  = []models.ListField{}
+		keys = map[string]bool{}
+	)
+	for _, l := range lists {
+		for _, f := range l.FormFields {
+			if keys[f.Key] || (editable && !f.Editable) {
+				continue
+			}
+			keys[f.Key] = true
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// readFormFields reads and validates the values of attribute fields in a request,
+// either from attribs (JSON requests) or from the a.{key} form fields. Empty
+// optional fields are skipped.
+func readFormFields(c echo.Context, fields []models.ListField, attribs map[string]interface{}, app *App) (models.JSON, error) {
+	out := models.JSON{}
+	for _, f := range fields {
+		var val string
+		if attribs != nil {
+			switch v := attribs[f.Key].(type) {
+			case string:
+				val = v
+			case float64:
+				val = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				val = strconv.FormatBool(v)
+			}
+		} else {
+			val = c.FormValue("a." + f.Key)
+		}
+		val = strings.TrimSpace(val)
+
+		// A checkbox is either checked or not. A required checkbox (eg: consent)
+		// has to be checked.
+		if f.Type == models.ListFieldCheckbox {
+			checked := val == "true" || val == "on" || val == "1"
+			if f.Required && !checked {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("public.fieldRequired", "name", f.Label))
+			}
+			out[f.Key] = checked
+			continue
+		}
+
+		if val == "" {
+			if f.Required {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("public.fieldRequired", "name", f.Label))
+			}
+			continue
+		}
+		if len(val) > stdInputMaxLen {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("public.fieldInvalid", "name", f.Label))
+		}
+
+		switch f.Type {
+		case models.ListFieldNumber:
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("public.fieldInvalid", "name", f.Label))
+			}
+			out[f.Key] = n
+
+		case models.ListFieldSelect:
+			if !inArray(val, f.Options) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("public.fieldInvalid", "name", f.Label))
+			}
+			out[f.Key] = val
+
+		default:
+			out[f.Key] = val
+		}
+	}
+
+	return out, nil
+}
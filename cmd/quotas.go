@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetQuotas returns all user quotas.
+func handleGetQuotas(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetQuotas()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpsertQuota creates or updates the quota of a user. An empty
+// username sets the default quota of users that don't have one.
+func handleUpsertQuota(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.Quota
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	o.Username = strings.TrimSpace(o.Username)
+	if len(o.Username) > 200 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "username"))
+	}
+	if o.CampaignsPerMonth < 0 || o.MessagesPerDay < 0 || o.MaxSubscribers < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("quotas.invalidLimit"))
+	}
+
+	out, err := app.core.UpsertQuota(o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteQuota deletes a user quota.
+func handleDeleteQuota(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteQuota(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleGetQuotaUsage returns the quota of the current user and their usage against it.
+func handleGetQuotaUsage(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	user := getUsername(c)
+	q, ok, err := app.core.GetUserQuota(user)
+	if err != nil {
+		return err
+	}
+
+	usage, err := app.core.GetQuotaUsage(user)
+	if err != nil {
+		return err
+	}
+
+	out := struct {
+		Username string            `json:"username"`
+		Quota    *models.Quota     `json:"quota"`
+		Usage    models.QuotaUsage `json:"usage"`
+	}{Username: user, Usage: usage}
+	if ok {
+		out.Quota = &q
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// checkCampaignQuota checks whether starting (or scheduling) a campaign
+// would exceed the campaigns per month or messages per day quota of the user.
+func checkCampaignQuota(username string, cm models.Campaign, app *App) error {
+	q, ok, err := app.core.GetUserQuota(username)
+	if err != nil || !ok {
+		return err
+	}
+	if q.CampaignsPerMonth == 0 && q.MessagesPerDay == 0 {
+		return nil
+	}
+
+	u, err := app.core.GetQuotaUsage(username)
+	if err != nil {
+		return err
+	}
+
+	if q.CampaignsPerMonth > 0 && u.CampaignsMonth >= q.CampaignsPerMonth {
+		return echo.NewHTTPError(http.StatusForbidden,
+			app.i18n.Ts("quotas.campaignsExceeded", "limit", strconv.Itoa(q.CampaignsPerMonth)))
+	}
+	if q.MessagesPerDay > 0 && u.MessagesDay+cm.ToSend > q.MessagesPerDay {
+		return echo.NewHTTPError(http.StatusForbidden,
+			app.i18n.Ts("quotas.messagesExceeded", "limit", strconv.Itoa(q.MessagesPerDay),
+				"used", strconv.Itoa(u.MessagesDay), "count", strconv.Itoa(cm.ToSend)))
+	}
+
+	return nil
+}
+
+// checkSubscriberQuota checks whether the subscriber database has reached
+// the max subscribers quota of the user.
+func checkSubscriberQuota(username string, app *App) error {
+	q, ok, err := app.core.GetUserQuota(username)
+	if err != nil || !ok || q.MaxSubscribers == 0 {
+		return err
+	}
+
+	u, err := app.core.GetQuotaUsage(username)
+	if err != nil {
+		return err
+	}
+
+	if u.Subscribers >= q.MaxSubscribers {
+		return echo.NewHTTPError(http.StatusForbidden,
+			app.i18n.Ts("quotas.subscribersExceeded", "limit", strconv.Itoa(q.MaxSubscribers)))
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdgvda/cron"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetCampaignRuns returns the runs of a recurring campaign with their stats.
+func handleGetCampaignRuns(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		pg    = app.paginator.NewFromURL(c.Request().URL.Query())
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	res, total, err := app.core.GetCampaignRuns(id, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+	if len(res) == 0 {
+		out.Results = []models.Campaign{}
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runRecurringCampaigns creates the runs of the recurring campaigns that are due.
+// Each run is a clone of the campaign that's sent and has its own stats.
+func runRecurringCampaigns(app *App) {
+	camps, err := app.core.GetDueRecurringCampaigns()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, c := range camps {
+		next, err := nextCampaignRecurrence(c.Recurrence, c.SendAtTimezone, now)
+		if err != nil {
+			app.log.Printf("error parsing recurrence of campaign %s: %v", c.Name, err)
+			continue
+		}
+
+		id, err := app.core.CreateCampaignRun(c.ID, next)
+		if err != nil || id == 0 {
+			continue
+		}
+
+		app.log.Printf("created run %d of recurring campaign %s. next run at %s", id, c.Name, next.Format(time.RFC3339))
+	}
+}
+
+// nextCampaignRecurrence returns the first time after t in a campaign's
+// recurrence (cron expression) in the campaign's time zone.
+func nextCampaignRecurrence(rec, tz string, t time.Time) (time.Time, error) {
+	if tz != "" {
+		rec = "CRON_TZ=" + tz + " " + rec
+	}
+
+	s, err := cron.ParseStandard(rec)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := s.Next(t)
+	if next.IsZero() {
+		return time.Time{}, errors.New("recurrence has no next run")
+	}
+
+	return next, nil
+}
+
+// validateCampaignRecurrence validates a recurring campaign and sets its send_at
+// to its first run, which is after send_at if it's set.
+func validateCampaignRecurrence(c campaignReq, app *App) (campaignReq, error) {
+	c.Recurrence = strings.Join(strings.Fields(c.Recurrence), " ")
+	if c.Recurrence == "" {
+		return c, nil
+	}
+
+	// Runs are started on their own, so recurring campaigns can't be opt-in
+	// campaigns, depend on other campaigns, or have A/B tests.
+	if c.Type == models.CampaignTypeOptin || c.DependsOn.Valid || c.ABTestPercent > 0 {
+		return c, errors.New(app.i18n.T("campaigns.recurringUnsupported"))
+	}
+
+	// Descriptors such as @every aren't supported, only cron expressions.
+	if len(strings.Fields(c.Recurrence)) != 5 {
+		return c, errors.New(app.i18n.T("campaigns.fieldInvalidRecurrence"))
+	}
+
+	// Recurrences are in the campaign's time zone.
+	if c.SendAtTimezone == "" {
+		c.SendAtTimezone = app.constants.ReportingTimezone.String()
+	}
+
+	from := time.Now()
+	if c.SendAt.Valid && c.SendAt.Time.After(from) {
+		// The first run can be at send_at itself.
+		from = c.SendAt.Time.Add(-time.Second)
+	}
+
+	next, err := nextCampaignRecurrence(c.Recurrence, c.SendAtTimezone, from)
+	if err != nil {
+		return c, errors.New(app.i18n.T("campaigns.fieldInvalidRecurrence"))
+	}
+
+	c.SendAt.SetValid(next)
+	c.SendLater = true
+
+	return c, nil
+}
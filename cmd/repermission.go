@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// repermissionReq represents a request to start a list re-permission.
+type repermissionReq struct {
+	Deadline   time.Time `json:"deadline"`
+	Subject    string    `json:"subject"`
+	TemplateID int       `json:"template_id"`
+	Messenger  string    `json:"messenger"`
+}
+
+// handleGetListRepermissions returns the re-permission runs of a list
+// (ID in the URI) along with their progress.
+func handleGetListRepermissions(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	out, err := app.core.GetListRepermissions(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleStartListRepermission starts a re-permission run on a single opt-in list.
+// The list is converted to double opt-in, its subscriptions are marked unconfirmed,
+// and a draft opt-in campaign asking subscribers to confirm before the deadline is
+// created. Subscribers who haven't confirmed by the deadline are unsubscribed.
+func handleStartListRepermission(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req repermissionReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if req.Deadline.Before(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "deadline"))
+	}
+
+	list, err := app.core.GetList(id, "")
+	if err != nil {
+		return err
+	}
+	if list.Optin != models.ListOptinSingle {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("lists.repermissionNotSingle"))
+	}
+
+	// Prepare and validate the re-permission campaign before touching the list.
+	camp, err := makeRepermissionCampaign(list, req, app)
+	if err != nil {
+		return err
+	}
+
+	// The draft campaign is created first so that the list is only touched
+	// once it exists. If the run can't be started, the campaign is deleted.
+	out, err := app.core.CreateCampaign(camp.Campaign, camp.ListIDs, camp.MediaIDs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := app.core.StartListRepermission(id, req.Deadline, out.ID); err != nil {
+		if err := app.core.DeleteCampaign(out.ID); err != nil {
+			app.log.Printf("error deleting campaign of failed list re-permission: %v", err)
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCancelListRepermission cancels the running re-permission of a list.
+func handleCancelListRepermission(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.CancelListRepermission(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleFinishListRepermission finishes the running re-permission of a list without
+// waiting for the deadline and unsubscribes the subscribers who haven't confirmed.
+func handleFinishListRepermission(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	n, err := app.core.FinishListRepermissions(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// makeRepermissionCampaign prepares the draft opt-in campaign of a re-permission
+// run that asks the subscribers of a list to confirm their subscription.
+func makeRepermissionCampaign(list models.List, req repermissionReq, app *App) (campaignReq, error) {
+	o := campaignReq{
+		Campaign: models.Campaign{
+			Name:        app.i18n.Ts("lists.repermissionCampaignName", "name", list.Name),
+			Subject:     req.Subject,
+			Type:        models.CampaignTypeOptin,
+			ContentType: models.CampaignContentTypeRichtext,
+			Messenger:   req.Messenger,
+			TemplateID:  req.TemplateID,
+		},
+		ListIDs: []int{list.ID},
+	}
+	if o.Subject == "" {
+		o.Subject = app.i18n.Ts("lists.repermissionSubject", "name", list.Name)
+	}
+	if o.Messenger == "" {
+		o.Messenger = "email"
+	}
+
+	listIDs := url.Values{}
+	listIDs.Add("l", list.UUID)
+	optinURLAttr := template.HTMLAttr(fmt.Sprintf(`href="{{ OptinURL }}%s"`, listIDs.Encode()))
+
+	var b bytes.Buffer
+	if err := app.notifTpls.tpls.ExecuteTemplate(&b, "repermission-campaign", struct {
+		Lists        []models.List
+		OptinURLAttr template.HTMLAttr
+		Deadline     string
+	}{[]models.List{list}, optinURLAttr, req.Deadline.Format("2 Jan 2006")}); err != nil {
+		app.log.Printf("error compiling 'repermission-campaign' template: %v", err)
+		return o, echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("templates.errorCompiling", "error", err.Error()))
+	}
+	o.Body = b.String()
+
+	o, err := validateCampaignFields(o, app)
+	if err != nil {
+		return o, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	o.ArchiveTemplateID = o.TemplateID
+
+	return o, nil
+}
+
+// finishListRepermissions unsubscribes the subscribers who haven't confirmed from
+// lists whose re-permission deadline has passed.
+func finishListRepermissions(app *App) {
+	n, err := app.core.FinishListRepermissions(0)
+	if err != nil {
+		app.log.Printf("error finishing list re-permissions: %v", err)
+		return
+	}
+
+	if n > 0 {
+		app.log.Printf("list re-permission: unsubscribed %d unconfirmed subscriptions", n)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/knadh/listmonk/internal/reputation"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	notifTplReputation = "reputation-alert"
+
+	// reputationSyncDays is the number of past days fetched from the providers on every sync.
+	// Postmaster Tools data is usually delayed by a couple of days and may be revised.
+	reputationSyncDays = 7
+
+	maxReputationDays = 365
+)
+
+var (
+	// reputationSyncing indicates whether a reputation sync is in progress.
+	reputationSyncing atomic.Bool
+
+	// reputationAlerted records the provider/source/date metrics that have
+	// already been alerted on to not notify repeatedly.
+	reputationAlerted sync.Map
+)
+
+// reputationAlert represents a source whose reputation has dipped below the thresholds.
+type reputationAlert struct {
+	Provider   string
+	Source     string
+	Date       string
+	Reputation string
+	SpamRate   string
+}
+
+// handleGetReputation returns the daily sender reputation metrics of the past N days.
+func handleGetReputation(c echo.Context) error {
+	var (
+		app     = c.Get("app").(*App)
+		days, _ = strconv.Atoi(c.QueryParam("days"))
+	)
+
+	if days < 1 || days > maxReputationDays {
+		days = 30
+	}
+
+	to := time.Now()
+	out, err := app.core.GetReputationMetrics(c.QueryParam("provider"), to.AddDate(0, 0, -days), to)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleSyncReputation fetches the latest reputation metrics from the providers.
+func handleSyncReputation(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if len(app.reputation) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("reputation.notEnabled"))
+	}
+
+	n, err := syncReputation(app)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// syncReputation fetches the metrics of the past few days from all the enabled
+// providers, saves them, and notifies the admins of the sources whose reputation
+// has dipped below the configured thresholds. It returns the number of metrics saved.
+func syncReputation(app *App) (int, error) {
+	if !reputationSyncing.CompareAndSwap(false, true) {
+		return 0, errors.New(app.i18n.T("reputation.syncing"))
+	}
+	defer reputationSyncing.Store(false)
+
+	var (
+		now  = time.Now().UTC()
+		to   = now.AddDate(0, 0, -1)
+		from = now.AddDate(0, 0, -reputationSyncDays)
+
+		metrics []reputation.Metric
+		errs    []string
+	)
+	for _, p := range app.reputation {
+		m, err := p.Fetch(from, to)
+		if err != nil {
+			// Save whatever was fetched before the error.
+			app.log.Printf("error fetching reputation metrics: %v", err)
+			errs = append(errs, err.Error())
+		}
+		metrics = append(metrics, m...)
+	}
+
+	if len(metrics) > 0 {
+		out := make([]models.ReputationMetric, 0, len(metrics))
+		for _, m := range metrics {
+			dErrs, _ := json.Marshal(m.DeliveryErrors)
+			meta := m.Meta
+			if len(meta) == 0 {
+				meta = json.RawMessage(`{}`)
+			}
+
+			out = append(out, models.ReputationMetric{
+				Provider:       m.Provider,
+				Source:         m.Source,
+				Date:           m.Date,
+				Reputation:     m.Reputation,
+				SpamRate:       m.SpamRate,
+				DeliveryErrors: dErrs,
+				Meta:           []byte(meta),
+			})
+		}
+
+		if err := app.core.UpsertReputationMetrics(out); err != nil {
+			return 0, err
+		}
+
+		checkReputationAlerts(metrics, app)
+	}
+
+	if len(errs) > 0 {
+		return len(metrics), errors.New(strings.Join(errs, "; "))
+	}
+
+	return len(metrics), nil
+}
+
+// checkReputationAlerts notifies the admins of the sources whose latest
+// reputation is below the alert level or whose spam rate is above the alert rate.
+func checkReputationAlerts(metrics []reputation.Metric, app *App) {
+	var (
+		cs     = app.constants.Reputation
+		minRep = reputation.Rank(cs.AlertReputation)
+	)
+
+	// Latest metric of every source.
+	latest := map[string]reputation.Metric{}
+	for _, m := range metrics {
+		key := m.Provider + "|" + m.Source
+		if l, ok := latest[key]; !ok || m.Date.After(l.Date) {
+			latest[key] = m
+		}
+	}
+
+	var alerts []reputationAlert
+	for key, m := range latest {
+		rank := reputation.Rank(m.Reputation)
+		if !(rank > 0 && rank < minRep) && !(cs.AlertSpamRate > 0 && m.SpamRate > cs.AlertSpamRate) {
+			continue
+		}
+
+		date := m.Date.Format("2006-01-02")
+		if _, ok := reputationAlerted.LoadOrStore(key+"|"+date, true); ok {
+			continue
+		}
+
+		alerts = append(alerts, reputationAlert{
+			Provider:   m.Provider,
+			Source:     m.Source,
+			Date:       date,
+			Reputation: m.Reputation,
+			SpamRate:   strconv.FormatFloat(m.SpamRate*100, 'f', 2, 64) + "%",
+		})
+	}
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	app.log.Printf("sender reputation alert for %d source(s)", len(alerts))
+	_ = app.sendNotification(app.constants.NotifyEmails, app.i18n.T("reputation.alertSubject"), notifTplReputation, struct {
+		Alerts []reputationAlert
+	}{alerts})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxResendDays is the maximum number of days after a campaign finished to resend it.
+	maxResendDays = 30
+)
+
+// handleResendCampaign creates a follow-up of a finished campaign that's only
+// sent to the subscribers it was sent to who haven't opened it.
+func handleResendCampaign(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Subject string `json:"subject"`
+		Days    int    `json:"days"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	// Opens are only known per subscriber with individual tracking.
+	if !app.constants.Privacy.IndividualTracking {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.resendNoTracking"))
+	}
+
+	if req.Days < 0 || req.Days > maxResendDays {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "days"))
+	}
+
+	req.Subject = strings.TrimSpace(req.Subject)
+	if len(req.Subject) > 5000 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.fieldInvalidSubject"))
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = app.i18n.Ts("campaigns.resendName", "name", camp.Name)
+	}
+	if !strHasLen(req.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.fieldInvalidName"))
+	}
+
+	out, err := app.core.CreateCampaignResend(id, req.Name, req.Subject, req.Days)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSavedViewParams is the maximum size (bytes) of a saved view's JSON params.
+const maxSavedViewParams = 8192
+
+// handleGetSavedViews returns the saved views visible to the user,
+// optionally filtered by ?collection=.
+func handleGetSavedViews(c echo.Context) error {
+	var (
+		app        = c.Get("app").(*App)
+		collection = c.QueryParam("collection")
+	)
+
+	if collection != "" && !isSavedViewCollection(collection) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "collection"))
+	}
+
+	out, err := app.core.GetSavedViews(collection, getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetSavedView returns a saved view.
+func handleGetSavedView(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetSavedView(id, getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateSavedView saves a named filter/sort configuration of a collection.
+func handleCreateSavedView(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.SavedView
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+
+	if !isSavedViewCollection(o.Collection) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "collection"))
+	}
+	if err := validateSavedView(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.CreateSavedView(o, getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateSavedView updates a saved view's name, params, and sharing.
+// The collection of a view can't be changed.
+func handleUpdateSavedView(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var o models.SavedView
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateSavedView(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.UpdateSavedView(id, o, getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteSavedView deletes a saved view.
+func handleDeleteSavedView(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteSavedView(id, getUsername(c)); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateSavedView validates and sanitizes a saved view's fields.
+func validateSavedView(o *models.SavedView, app *App) error {
+	o.Name = strings.TrimSpace(o.Name)
+	if !strHasLen(o.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+
+	if o.Params == nil {
+		o.Params = models.JSON{}
+	}
+	if b, err := json.Marshal(o.Params); err != nil || len(b) > maxSavedViewParams {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "params"))
+	}
+
+	return nil
+}
+
+func isSavedViewCollection(s string) bool {
+	switch s {
+	case models.SavedViewSubscribers, models.SavedViewCampaigns, models.SavedViewBounces:
+		return true
+	}
+
+	return false
+}
+
+// getUsername returns the username of the (BasicAuth) authenticated user of a request.
+func getUsername(c echo.Context) string {
+	u, _, _ := c.Request().BasicAuth()
+	return u
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	searchLimit    = 5
+	maxSearchLimit = 20
+	maxSearchQuery = 200
+)
+
+// searchTypes are the types searched by the global search by default.
+var searchTypes = []string{models.SearchCampaigns, models.SearchTemplates, models.SearchSubscribers, models.SearchLists}
+
+// handleGlobalSearch searches campaigns (name, subject, body), templates, subscribers
+// (e-mail prefix), and lists in one call for the command palette. Requests made with
+// API tokens only get the types the tokens have :read scopes for.
+func handleGlobalSearch(c echo.Context) error {
+	var (
+		app          = c.Get("app").(*App)
+		searchStr    = strings.TrimSpace(c.QueryParam("q"))
+		withTrash, _ = strconv.ParseBool(c.QueryParam("trash"))
+		limit, _     = strconv.Atoi(c.QueryParam("limit"))
+	)
+
+	if searchStr == "" {
+		return c.JSON(http.StatusOK, okResp{[]models.SearchResult{}})
+	}
+	if r := []rune(searchStr); len(r) > maxSearchQuery {
+		searchStr = string(r[:maxSearchQuery])
+	}
+	if limit < 1 {
+		limit = searchLimit
+	} else if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	types := searchTypes
+	if t := c.QueryParam("types"); t != "" {
+		types = nil
+		for _, typ := range strings.Split(t, ",") {
+			typ = strings.TrimSpace(typ)
+			if !inArray(typ, searchTypes) {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "types"))
+			}
+			if !inArray(typ, types) {
+				types = append(types, typ)
+			}
+		}
+	}
+
+	// Filter out the types an API token can't read.
+	if t, ok := c.Get(apiTokenCtxKey).(models.APIToken); ok {
+		allowed := make([]string, 0, len(types))
+		for _, typ := range types {
+			if inArray(typ+":read", t.Scopes) {
+				allowed = append(allowed, typ)
+			}
+		}
+		types = allowed
+	}
+
+	out, err := app.core.GlobalSearch(searchStr, types, withTrash, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
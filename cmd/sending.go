@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type sendingStatus struct {
+	Paused bool `json:"paused"`
+}
+
+// handleGetSendingStatus returns the state of the global send pause.
+func handleGetSendingStatus(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	return c.JSON(http.StatusOK, okResp{sendingStatus{Paused: app.manager.IsPaused()}})
+}
+
+// handleUpdateSendingStatus pauses or resumes all sending (the global kill switch).
+// Pausing immediately halts all running campaigns and holds transactional messages.
+// On resuming, campaigns continue from their checkpoint and the held messages are sent.
+func handleUpdateSendingStatus(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var req struct {
+		Paused bool `json:"paused"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := app.core.SetSendingPaused(req.Paused); err != nil {
+		return err
+	}
+
+	if req.Paused {
+		app.manager.Pause()
+		app.log.Printf("all sending paused by %s", getUsername(c))
+	} else {
+		app.manager.Resume()
+		app.log.Printf("sending resumed by %s", getUsername(c))
+	}
+
+	return handleGetSendingStatus(c)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// maxRetrySends is the maximum number of failed sends that can be retried at once.
+const maxRetrySends = 10000
+
+// handleGetCampaignSends returns the per-recipient send log of a campaign,
+// optionally filtered by a subscriber e-mail (query) and status. Without a
+// campaign ID in the path, it returns the sends of all campaigns or the one
+// in ?campaign_id, eg: failed sends across campaigns.
+func handleGetCampaignSends(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		pg  = app.paginator.NewFromURL(c.Request().URL.Query())
+
+		id, _  = strconv.Atoi(c.Param("id"))
+		query  = c.FormValue("query")
+		status = c.FormValue("status")
+	)
+
+	if c.Param("id") == "" {
+		id, _ = strconv.Atoi(c.QueryParam("campaign_id"))
+	} else if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	if !isSendStatus(status) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	if id > 0 {
+		if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+			return err
+		}
+	}
+
+	res, total, err := app.core.QueryCampaignSends(id, query, status, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleExportCampaignSends streams the send log of a campaign as CSV.
+func handleExportCampaignSends(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+
+		id, _  = strconv.Atoi(c.Param("id"))
+		query  = c.FormValue("query")
+		status = c.FormValue("status")
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+	if !isSendStatus(status) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	if _, err := app.core.GetCampaign(id, "", ""); err != nil {
+		return err
+	}
+
+	var (
+		h  = c.Response().Header()
+		wr = csv.NewWriter(c.Response())
+	)
+
+	h.Set(echo.HeaderContentType, echo.MIMEOctetStream)
+	h.Set("Content-type", "text/csv")
+	h.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=campaign-%d-sends.csv", id))
+	h.Set("Content-Transfer-Encoding", "binary")
+	h.Set("Cache-Control", "no-cache")
+	wr.Write([]string{"subscriber_uuid", "email", "status", "queued_at", "sent_at", "deferred_at",
+		"bounced_at", "opened_at", "clicked_at", "response"})
+
+	// Iterate in batches until there are no more sends to export.
+	for offset := 0; ; offset += app.constants.DBBatchSize {
+		out, _, err := app.core.QueryCampaignSends(id, query, status, offset, app.constants.DBBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			break
+		}
+
+		for _, r := range out {
+			if err := wr.Write([]string{r.SubscriberUUID, r.Email, r.Status, csvTime(r.QueuedAt), csvTime(r.SentAt),
+				csvTime(r.DeferredAt), csvTime(r.BouncedAt), csvTime(r.OpenedAt), csvTime(r.ClickedAt), r.Response}); err != nil {
+				app.log.Printf("error streaming CSV export: %v", err)
+				return nil
+			}
+		}
+
+		// Flush CSV to stream after each batch.
+		wr.Flush()
+	}
+
+	return nil
+}
+
+// handleRetryCampaignSends re-queues the failed (deferred) sends of a campaign,
+// all of them, or the ones to the given subscribers.
+func handleRetryCampaignSends(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var req struct {
+		SubscriberIDs []int `json:"subscriber_ids"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if app.manager.IsPaused() {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("sending.pausedNotice"))
+	}
+
+	camp, err := app.core.GetCampaign(id, "", "")
+	if err != nil {
+		return err
+	}
+
+	// Only campaigns that have been started have sends to retry.
+	switch camp.Status {
+	case models.CampaignStatusRunning, models.CampaignStatusPaused,
+		models.CampaignStatusFinished, models.CampaignStatusCancelled:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.retryNotStarted"))
+	}
+
+	if err := camp.CompileTemplate(app.manager.TemplateFuncs(&camp)); err != nil {
+		app.log.Printf("error compiling template: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			app.i18n.Ts("templates.errorCompiling", "error", err.Error()))
+	}
+
+	// The failed sends are marked queued as they're fetched so that repeated
+	// retries don't re-queue the same subscribers.
+	subs, err := app.core.QueueFailedSendSubscribers(id, req.SubscriberIDs, maxRetrySends)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.retryNoFailed"))
+	}
+
+	if err := app.manager.RequeueCampaignMessages(&camp, subs); err != nil {
+		app.log.Printf("error re-queuing failed sends of campaign %d: %v", id, err)
+
+		// Mark the sends failed again so that they can be retried.
+		ids := make([]int, len(subs))
+		for i, s := range subs {
+			ids[i] = s.ID
+		}
+		_ = app.core.DeferCampaignSends(id, ids)
+
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			app.i18n.Ts("campaigns.retryError", "error", err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, okResp{len(subs)})
+}
+
+// isSendStatus checks whether a string is a valid send log status filter.
+func isSendStatus(s string) bool {
+	switch s {
+	case "", models.SendStatusQueued, models.SendStatusSent, models.SendStatusDeferred, models.SendStatusBounced:
+		return true
+	}
+	return false
+}
+
+// csvTime returns the string representation of a nullable time for CSV exports.
+func csvTime(t null.Time) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.String()
+}
+
+// purgeCampaignSends deletes the send log entries that are older than
+// the retention period.
+func purgeCampaignSends(app *App) (int, error) {
+	n, err := app.core.DeleteCampaignSends(time.Now().AddDate(0, 0, -app.constants.SendLog.RetentionDays))
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		app.log.Printf("purged %d entries from the campaign send log", n)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetSentMessages returns the archived messages sent to subscribers,
+// or a single message with its body.
+func handleGetSentMessages(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		pg  = app.paginator.NewFromURL(c.Request().URL.Query())
+
+		id, _     = strconv.Atoi(c.Param("id"))
+		subID, _  = strconv.Atoi(c.QueryParam("subscriber_id"))
+		campID, _ = strconv.Atoi(c.QueryParam("campaign_id"))
+	)
+
+	// Fetch one message.
+	if id > 0 {
+		out, err := app.core.GetSentMessage(id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, okResp{out})
+	}
+
+	res, total, err := app.core.QuerySentMessages(subID, campID, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out := models.PageResults{
+		Results: res,
+		Total:   total,
+		Page:    pg.Page,
+		PerPage: pg.PerPage,
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetSentMessageBody renders the archived body of a sent message.
+func handleGetSentMessageBody(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetSentMessage(id)
+	if err != nil {
+		return err
+	}
+
+	if out.ContentType == models.CampaignContentTypePlain {
+		return c.String(http.StatusOK, out.Body)
+	}
+
+	return c.HTML(http.StatusOK, out.Body)
+}
+
+// purgeSentMessages deletes the archived sent messages that are older
+// than the retention period.
+func purgeSentMessages(app *App) (int, error) {
+	n, err := app.core.DeleteSentMessages(time.Now().AddDate(0, 0, -app.constants.SentArchive.RetentionDays))
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		app.log.Printf("purged %d message(s) from the sent message archive", n)
+	}
+
+	return n, nil
+}
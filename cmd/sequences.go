@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// maxSequenceSteps is the maximum number of steps in a drip sequence.
+	maxSequenceSteps = 50
+
+	// maxSequenceDelayMins is the maximum delay (365 days) of a sequence step.
+	maxSequenceDelayMins = 60 * 24 * 365
+)
+
+// handleGetSequences returns all drip sequences.
+func handleGetSequences(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetSequences()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetSequence returns a drip sequence.
+func handleGetSequence(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetSequence(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateSequence creates a drip sequence.
+func handleCreateSequence(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.Sequence
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateSequence(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.CreateSequence(o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateSequence updates a drip sequence.
+func handleUpdateSequence(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var o models.Sequence
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateSequence(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.UpdateSequence(id, o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteSequence deletes a drip sequence.
+func handleDeleteSequence(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteSequence(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// validateSequence validates a drip sequence's fields. The list and the
+// campaigns of the steps should exist.
+func validateSequence(o *models.Sequence, app *App) error {
+	o.Name = strings.TrimSpace(o.Name)
+	if !strHasLen(o.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+
+	switch o.Status {
+	case "":
+		o.Status = models.SequenceStatusActive
+	case models.SequenceStatusActive, models.SequenceStatusPaused:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "status"))
+	}
+
+	if _, err := app.core.GetList(o.ListID, ""); err != nil {
+		return err
+	}
+
+	if len(o.Steps) == 0 || len(o.Steps) > maxSequenceSteps {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("sequences.invalidSteps", "num", strconv.Itoa(maxSequenceSteps)))
+	}
+
+	for _, s := range o.Steps {
+		if s.DelayMins < 0 || s.DelayMins > maxSequenceDelayMins {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "delay_mins"))
+		}
+
+		camp, err := app.core.GetCampaign(s.CampaignID, "", "")
+		if err != nil {
+			return err
+		}
+		if camp.Type == models.CampaignTypeOptin {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("sequences.invalidCampaign", "name", camp.Name))
+		}
+	}
+
+	return nil
+}
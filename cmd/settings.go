@@ -3,9 +3,11 @@ package main
 import (
 	"bytes"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,12 +19,20 @@ import (
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/providers/rawbytes"
 	"github.com/knadh/koanf/v2"
+	"github.com/knadh/listmonk/internal/ipfilter"
 	"github.com/knadh/listmonk/internal/messenger/email"
+	"github.com/knadh/listmonk/internal/reputation"
+	"github.com/knadh/listmonk/internal/spamcheck"
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
 )
 
-const pwdMask = "•"
+const (
+	pwdMask = "•"
+
+	// minTrustedSourceTokenLen is the minimum length of a trusted source token.
+	minTrustedSourceTokenLen = 16
+)
 
 type aboutHost struct {
 	OS       string `json:"os"`
@@ -46,6 +56,9 @@ type about struct {
 
 var (
 	reAlphaNum = regexp.MustCompile(`[^a-z0-9\-]`)
+
+	// regexGA4MeasurementID matches the measurement ID of a GA4 web data stream.
+	regexGA4MeasurementID = regexp.MustCompile(`^G-[A-Z0-9]{4,20}$`)
 )
 
 // handleGetSettings returns settings from the DB.
@@ -57,7 +70,18 @@ func handleGetSettings(c echo.Context) error {
 		return err
 	}
 
-	// Empty out passwords.
+	return c.JSON(http.StatusOK, okResp{maskSettings(s)})
+}
+
+// maskSettings returns a copy of the settings with the passwords and secrets
+// replaced with masks of the same length.
+func maskSettings(s models.Settings) models.Settings {
+	// Copy the slices so that the original settings aren't modified.
+	s.SMTP = append(s.SMTP[:0:0], s.SMTP...)
+	s.BounceBoxes = append(s.BounceBoxes[:0:0], s.BounceBoxes...)
+	s.Messengers = append(s.Messengers[:0:0], s.Messengers...)
+	s.PrivacyTrustedSources = append(s.PrivacyTrustedSources[:0:0], s.PrivacyTrustedSources...)
+
 	for i := 0; i < len(s.SMTP); i++ {
 		s.SMTP[i].Password = strings.Repeat(pwdMask, utf8.RuneCountInString(s.SMTP[i].Password))
 	}
@@ -69,10 +93,22 @@ func handleGetSettings(c echo.Context) error {
 	}
 	s.UploadS3AwsSecretAccessKey = strings.Repeat(pwdMask, utf8.RuneCountInString(s.UploadS3AwsSecretAccessKey))
 	s.SendgridKey = strings.Repeat(pwdMask, utf8.RuneCountInString(s.SendgridKey))
+	s.PreviewsAPIKey = strings.Repeat(pwdMask, utf8.RuneCountInString(s.PreviewsAPIKey))
+	s.SpamCheckPassword = strings.Repeat(pwdMask, utf8.RuneCountInString(s.SpamCheckPassword))
+	s.ReputationPostmasterClientSecret = strings.Repeat(pwdMask, utf8.RuneCountInString(s.ReputationPostmasterClientSecret))
+	s.ReputationPostmasterRefreshToken = strings.Repeat(pwdMask, utf8.RuneCountInString(s.ReputationPostmasterRefreshToken))
+	s.ReputationSNDSKey = strings.Repeat(pwdMask, utf8.RuneCountInString(s.ReputationSNDSKey))
 	s.SecurityCaptchaSecret = strings.Repeat(pwdMask, utf8.RuneCountInString(s.SecurityCaptchaSecret))
+	s.StripeWebhookSecret = strings.Repeat(pwdMask, utf8.RuneCountInString(s.StripeWebhookSecret))
+	s.GA4APISecret = strings.Repeat(pwdMask, utf8.RuneCountInString(s.GA4APISecret))
+	s.AttachmentsScannerToken = strings.Repeat(pwdMask, utf8.RuneCountInString(s.AttachmentsScannerToken))
+	s.NotificationsSlackWebhookURL = strings.Repeat(pwdMask, utf8.RuneCountInString(s.NotificationsSlackWebhookURL))
 	s.BouncePostmark.Password = strings.Repeat(pwdMask, utf8.RuneCountInString(s.BouncePostmark.Password))
+	for i := 0; i < len(s.PrivacyTrustedSources); i++ {
+		s.PrivacyTrustedSources[i].Token = strings.Repeat(pwdMask, utf8.RuneCountInString(s.PrivacyTrustedSources[i].Token))
+	}
 
-	return c.JSON(http.StatusOK, okResp{s})
+	return s
 }
 
 // handleUpdateSettings returns settings from the DB.
@@ -93,13 +129,33 @@ func handleUpdateSettings(c echo.Context) error {
 		return err
 	}
 
-	// There should be at least one SMTP block that's enabled.
-	has := false
+	// There should be at least one SMTP block that's enabled. Named SMTP
+	// servers are also messengers (email-{name}) and their names are unique.
+	var (
+		has       = false
+		smtpNames = map[string]bool{}
+	)
 	for i, s := range set.SMTP {
 		if s.Enabled {
 			has = true
 		}
 
+		if s.Name != "" {
+			name := reAlphaNum.ReplaceAllString(strings.ToLower(strings.TrimSpace(s.Name)), "")
+			if name == "" || smtpNames[name] {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					app.i18n.Ts("settings.duplicateMessengerName", "name", emailMsgr+"-"+name))
+			}
+			smtpNames[name] = true
+			set.SMTP[i].Name = name
+		}
+
+		set.SMTP[i].SourceIP = strings.TrimSpace(s.SourceIP)
+		if set.SMTP[i].SourceIP != "" && net.ParseIP(set.SMTP[i].SourceIP) == nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidFields", "name", "source_ip"))
+		}
+
 		// Assign a UUID. The frontend only sends a password when the user explicitly
 		// changes the password. In other cases, the existing password in the DB
 		// is copied while updating the settings and the UUID is used to match
@@ -112,6 +168,26 @@ func handleUpdateSettings(c echo.Context) error {
 		// This is a common mistake when copy-pasting SMTP settings.
 		set.SMTP[i].Host = strings.TrimSpace(s.Host)
 
+		// Validate the per-recipient-domain limits. A domain can only be
+		// in one limit group on a server.
+		doms := map[string]bool{}
+		for j, l := range s.DomainLimits {
+			if l.Rate < 0 || l.MaxConns < 0 || len(l.Domains) == 0 {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					app.i18n.Ts("globals.messages.invalidFields", "name", "domain_limits"))
+			}
+
+			for k, d := range l.Domains {
+				d = strings.ToLower(strings.TrimSpace(d))
+				if d == "" || doms[d] {
+					return echo.NewHTTPError(http.StatusBadRequest,
+						app.i18n.Ts("settings.smtp.invalidDomainLimit", "name", d))
+				}
+				doms[d] = true
+				set.SMTP[i].DomainLimits[j].Domains[k] = d
+			}
+		}
+
 		// If there's no password coming in from the frontend, copy the existing
 		// password by matching the UUID.
 		if s.Password == "" {
@@ -160,6 +236,9 @@ func handleUpdateSettings(c echo.Context) error {
 	// Validate and sanitize postback Messenger names. Duplicates are disallowed
 	// and "email" is a reserved name.
 	names := map[string]bool{emailMsgr: true}
+	for n := range smtpNames {
+		names[emailMsgr+"-"+n] = true
+	}
 
 	for i, m := range set.Messengers {
 		// UUID to keep track of password changes similar to the SMTP logic above.
@@ -188,6 +267,52 @@ func handleUpdateSettings(c echo.Context) error {
 		names[name] = true
 	}
 
+	if set.AppTrashRetentionDays < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "app.trash_retention_days"))
+	}
+
+	set.AppReportingTimezone = strings.TrimSpace(set.AppReportingTimezone)
+	if set.AppReportingTimezone == "" {
+		set.AppReportingTimezone = "UTC"
+	}
+	if !isTimezone(set.AppReportingTimezone) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "app.reporting_timezone"))
+	}
+
+	// Trusted sources. Names and tokens should be unique.
+	var (
+		srcNames  = map[string]bool{}
+		srcTokens = map[string]bool{}
+	)
+	for i, s := range set.PrivacyTrustedSources {
+		// UUID to keep track of token changes similar to the SMTP logic above.
+		if s.UUID == "" {
+			set.PrivacyTrustedSources[i].UUID = uuid.Must(uuid.NewV4()).String()
+		}
+
+		if s.Token == "" {
+			for _, c := range cur.PrivacyTrustedSources {
+				if s.UUID == c.UUID {
+					set.PrivacyTrustedSources[i].Token = c.Token
+				}
+			}
+		}
+
+		name := strings.TrimSpace(s.Name)
+		if !strHasLen(name, 1, stdInputMaxLen) || srcNames[name] {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("settings.privacy.invalidTrustedSource", "name", name))
+		}
+
+		tok := set.PrivacyTrustedSources[i].Token
+		if len(tok) < minTrustedSourceTokenLen || srcTokens[tok] {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("settings.privacy.invalidTrustedSourceToken", "name", name))
+		}
+
+		set.PrivacyTrustedSources[i].Name = name
+		srcNames[name] = true
+		srcTokens[tok] = true
+	}
+
 	// S3 password?
 	if set.UploadS3AwsSecretAccessKey == "" {
 		set.UploadS3AwsSecretAccessKey = cur.UploadS3AwsSecretAccessKey
@@ -201,6 +326,71 @@ func handleUpdateSettings(c echo.Context) error {
 	if set.SecurityCaptchaSecret == "" {
 		set.SecurityCaptchaSecret = cur.SecurityCaptchaSecret
 	}
+	if set.StripeWebhookSecret == "" {
+		set.StripeWebhookSecret = cur.StripeWebhookSecret
+	}
+	if set.GA4APISecret == "" {
+		set.GA4APISecret = cur.GA4APISecret
+	}
+	if set.AttachmentsScannerToken == "" {
+		set.AttachmentsScannerToken = cur.AttachmentsScannerToken
+	}
+
+	// Login lockout.
+	if set.SecurityLoginMaxAttempts < 0 {
+		set.SecurityLoginMaxAttempts = 0
+	}
+	if set.SecurityLoginMaxAttempts > 0 {
+		if d, err := time.ParseDuration(set.SecurityLoginLockout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "security.login_lockout"))
+		}
+	}
+
+	// IP allow and deny lists. Reject lists that would lock the admin saving
+	// them out.
+	ipOpt := ipfilter.Opt{
+		Deny:     trimIPList(set.SecurityIPDenylist),
+		Allow:    trimIPList(set.SecurityIPAllowlist),
+		APIAllow: trimIPList(set.SecurityAPIIPAllowlist),
+	}
+	ipf, err := ipfilter.New(ipOpt)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("settings.security.invalidIP", "error", err.Error()))
+	}
+	if !ipf.Allowed(c.RealIP(), false) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("settings.security.ipSelfLockout", "ip", c.RealIP()))
+	}
+	set.SecurityIPDenylist, set.SecurityIPAllowlist, set.SecurityAPIIPAllowlist = ipOpt.Deny, ipOpt.Allow, ipOpt.APIAllow
+
+	// API rate limits.
+	if set.SecurityAPIRateLimit < 0 {
+		set.SecurityAPIRateLimit = 0
+	}
+	if set.SecurityAPIRateBurst < 0 {
+		set.SecurityAPIRateBurst = 0
+	}
+	if set.SecurityAPIRateLimitRO < 0 {
+		set.SecurityAPIRateLimitRO = 0
+	}
+	if set.SecurityAPIRateBurstRO < 0 {
+		set.SecurityAPIRateBurstRO = 0
+	}
+	rateUsers := map[string]bool{}
+	for i, r := range set.SecurityAPIRateLimits {
+		r.Username = strings.TrimSpace(r.Username)
+		if r.Username == "" || rateUsers[r.Username] {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "security.api_rate_limits"))
+		}
+		rateUsers[r.Username] = true
+
+		if r.Rate < 0 {
+			r.Rate = 0
+		}
+		if r.Burst < 0 {
+			r.Burst = 0
+		}
+		set.SecurityAPIRateLimits[i] = r
+	}
 
 	for n, v := range set.UploadExtensions {
 		set.UploadExtensions[n] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "."))
@@ -216,6 +406,45 @@ func handleUpdateSettings(c echo.Context) error {
 	}
 	set.DomainBlocklist = doms
 
+	// Role accounts and spam-trap patterns for the address filter.
+	roles := make([]string, 0, len(set.PrivacyRoleAccounts))
+	for _, r := range set.PrivacyRoleAccounts {
+		r = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(r)), "@")
+		if r != "" {
+			roles = append(roles, r)
+		}
+	}
+	set.PrivacyRoleAccounts = roles
+
+	traps := make([]string, 0, len(set.PrivacySpamTrapPatterns))
+	for _, p := range set.PrivacySpamTrapPatterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidFields", "name", "spamtrap_patterns")+": "+err.Error())
+		}
+		traps = append(traps, p)
+	}
+	set.PrivacySpamTrapPatterns = traps
+
+	// Opt-in reply address.
+	set.PrivacyOptinReplyAddress = strings.TrimSpace(set.PrivacyOptinReplyAddress)
+	if set.PrivacyOptinReplyAddress != "" {
+		em, err := app.importer.SanitizeEmail(set.PrivacyOptinReplyAddress)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidFields", "name", "optin_reply_address"))
+		}
+		set.PrivacyOptinReplyAddress = em
+	}
+	if d, err := time.ParseDuration(set.PrivacyExportLinkExpiry); err != nil || d < time.Minute {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("globals.messages.invalidFields", "name", "privacy.export_link_expiry"))
+	}
+
 	// Validate slow query caching cron.
 	if set.CacheSlowQueries {
 		if _, err := cron.ParseStandard(set.CacheSlowQueriesInterval); err != nil {
@@ -223,10 +452,278 @@ func handleUpdateSettings(c echo.Context) error {
 		}
 	}
 
+	// Validate the sunset policy.
+	if set.SunsetEnabled {
+		if _, err := cron.ParseStandard(set.SunsetInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidData")+": sunset cron: "+err.Error())
+		}
+		if set.SunsetInactiveDays < 1 || set.SunsetGraceDays < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("settings.sunset.invalidDays"))
+		}
+		if set.SunsetAction != models.SunsetActionUnsubscribe && set.SunsetAction != models.SunsetActionBlocklist {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "sunset.action"))
+		}
+	}
+
+	if set.HygieneEnabled {
+		if _, err := cron.ParseStandard(set.HygieneInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidData")+": hygiene cron: "+err.Error())
+		}
+		if set.HygieneSoftBounceCount < 1 || set.HygieneSoftBounceDays < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "hygiene"))
+		}
+	}
+
+	if set.MaintenanceEnabled {
+		if _, err := cron.ParseStandard(set.MaintenanceInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidData")+": maintenance cron: "+err.Error())
+		}
+	}
+	if set.MaintenanceMediaDays < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "maintenance.media_days"))
+	}
+
+	if set.ContentQAEnabled {
+		set.ContentQAURL = strings.TrimSpace(set.ContentQAURL)
+		if set.ContentQAURL != "" && !isHTTPURL(set.ContentQAURL) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "content_qa.url"))
+		}
+		if d, err := time.ParseDuration(set.ContentQATimeout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "content_qa.timeout"))
+		}
+	}
+
+	if set.EntitlementEnabled {
+		if _, err := cron.ParseStandard(set.EntitlementInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidData")+": entitlement cron: "+err.Error())
+		}
+		if d, err := time.ParseDuration(set.EntitlementTimeout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "entitlement.timeout"))
+		}
+	}
+
+	// Stripe product to list mappings.
+	if set.StripeEnabled && set.StripeWebhookSecret == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "stripe.webhook_secret"))
+	}
+	for i, p := range set.StripeProducts {
+		p.ProductID = strings.TrimSpace(p.ProductID)
+		if p.ProductID == "" || p.ListID < 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "stripe.products"))
+		}
+		set.StripeProducts[i].ProductID = p.ProductID
+	}
+
+	// Google Analytics 4 event forwarding.
+	set.GA4MeasurementID = strings.ToUpper(strings.TrimSpace(set.GA4MeasurementID))
+	set.GA4Source = strings.TrimSpace(set.GA4Source)
+	set.GA4Medium = strings.TrimSpace(set.GA4Medium)
+	if set.GA4Enabled {
+		if !regexGA4MeasurementID.MatchString(set.GA4MeasurementID) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ga4.measurement_id"))
+		}
+		if set.GA4APISecret == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "ga4.api_secret"))
+		}
+	}
+	if set.GA4Source == "" {
+		set.GA4Source = "listmonk"
+	}
+	if set.GA4Medium == "" {
+		set.GA4Medium = "email"
+	}
+
+	// UTM parameters appended to the links in campaigns.
+	set.UTMSource = strings.TrimSpace(set.UTMSource)
+	set.UTMMedium = strings.TrimSpace(set.UTMMedium)
+	if set.UTMSource == "" {
+		set.UTMSource = "listmonk"
+	}
+	if set.UTMMedium == "" {
+		set.UTMMedium = "email"
+	}
+
+	// Archive of sent messages.
+	switch set.SentArchiveMode {
+	case models.SentArchiveOff, models.SentArchiveContent:
+	case "":
+		set.SentArchiveMode = models.SentArchiveOff
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "sent_archive.mode"))
+	}
+	if set.SentArchiveRetentionDays < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "sent_archive.retention_days"))
+	}
+	if set.SendLogRetentionDays < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "send_log.retention_days"))
+	}
+
+	if set.PreviewsAPIKey == "" {
+		set.PreviewsAPIKey = cur.PreviewsAPIKey
+	}
+	clients := make([]string, 0, len(set.PreviewsClients))
+	for _, c := range set.PreviewsClients {
+		c = strings.TrimSpace(strings.ToLower(c))
+		if c == "" {
+			continue
+		}
+		if !regexPreviewClient.MatchString(c) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "previews.clients"))
+		}
+		clients = append(clients, c)
+	}
+	set.PreviewsClients = clients
+	if set.PreviewsEnabled {
+		set.PreviewsURL = strings.TrimSpace(set.PreviewsURL)
+		if !isHTTPURL(set.PreviewsURL) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "previews.url"))
+		}
+		if len(set.PreviewsClients) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "previews.clients"))
+		}
+		if d, err := time.ParseDuration(set.PreviewsTimeout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "previews.timeout"))
+		}
+	}
+
+	if set.SpamCheckPassword == "" {
+		set.SpamCheckPassword = cur.SpamCheckPassword
+	}
+	if set.SpamCheckEnabled {
+		set.SpamCheckURL = strings.TrimSpace(set.SpamCheckURL)
+		switch set.SpamCheckType {
+		case spamcheck.TypeRspamd:
+			if !isHTTPURL(set.SpamCheckURL) {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "spam_check.url"))
+			}
+		case spamcheck.TypeSpamAssassin:
+			if _, _, err := net.SplitHostPort(set.SpamCheckURL); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "spam_check.url"))
+			}
+		default:
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "spam_check.type"))
+		}
+		if set.SpamCheckThreshold <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "spam_check.threshold"))
+		}
+		if d, err := time.ParseDuration(set.SpamCheckTimeout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "spam_check.timeout"))
+		}
+	}
+
+	if err := validateDeliverabilityDomains(&set); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("globals.messages.invalidFields", "name", "deliverability.domains: "+err.Error()))
+	}
+	set.DeliverabilityTrackingCNAME = strings.TrimSpace(set.DeliverabilityTrackingCNAME)
+	if set.DeliverabilityRecheck {
+		if _, err := cron.ParseStandard(set.DeliverabilityRecheckInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidFields", "name", "deliverability.recheck_interval"))
+		}
+	}
+
+	if set.ReputationPostmasterClientSecret == "" {
+		set.ReputationPostmasterClientSecret = cur.ReputationPostmasterClientSecret
+	}
+	if set.ReputationPostmasterRefreshToken == "" {
+		set.ReputationPostmasterRefreshToken = cur.ReputationPostmasterRefreshToken
+	}
+	if set.ReputationSNDSKey == "" {
+		set.ReputationSNDSKey = cur.ReputationSNDSKey
+	}
+	pmDoms := make([]string, 0, len(set.ReputationPostmasterDomains))
+	for _, d := range set.ReputationPostmasterDomains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			pmDoms = append(pmDoms, d)
+		}
+	}
+	set.ReputationPostmasterDomains = pmDoms
+	if set.ReputationEnabled {
+		if _, err := cron.ParseStandard(set.ReputationInterval); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "reputation.interval"))
+		}
+		if !reputation.IsValid(set.ReputationAlertReputation) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "reputation.alert_reputation"))
+		}
+		if set.ReputationAlertSpamRate < 0 || set.ReputationAlertSpamRate > 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "reputation.alert_spam_rate"))
+		}
+		if set.ReputationPostmasterEnabled && len(set.ReputationPostmasterDomains) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "reputation.postmaster_domains"))
+		}
+	}
+
+	if set.NotificationsSlackWebhookURL == "" {
+		set.NotificationsSlackWebhookURL = cur.NotificationsSlackWebhookURL
+	}
+	if set.NotificationsSlackEnabled && !isHTTPURL(set.NotificationsSlackWebhookURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "notifications.slack_webhook_url"))
+	}
+	if set.NotificationsSlackEvents == nil {
+		set.NotificationsSlackEvents = []string{}
+	}
+	for _, t := range set.NotificationsSlackEvents {
+		if !inArray(t, notifTypes) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "notifications.slack_events"))
+		}
+	}
+	if set.NotificationsBounceThreshold < 0 {
+		set.NotificationsBounceThreshold = 0
+	}
+
+	set.BrandingProductName = strings.TrimSpace(set.BrandingProductName)
+	set.BrandingLogoURL = strings.TrimSpace(set.BrandingLogoURL)
+	if set.BrandingLogoURL != "" && !isHTTPURL(set.BrandingLogoURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "appearance.branding.logo_url"))
+	}
+	set.BrandingColor = strings.TrimSpace(set.BrandingColor)
+	if set.BrandingColor != "" && !regexpBrandColor.MatchString(set.BrandingColor) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "appearance.branding.color"))
+	}
+
+	if set.AttachmentsScanner != scannerHTTP {
+		set.AttachmentsScanner = scannerClamAV
+	}
+	if set.AttachmentsClamAVEnabled {
+		if set.AttachmentsScanner == scannerHTTP {
+			set.AttachmentsScannerURL = strings.TrimSpace(set.AttachmentsScannerURL)
+			if !isHTTPURL(set.AttachmentsScannerURL) {
+				return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "attachments.scanner_url"))
+			}
+		} else {
+			set.AttachmentsClamAVAddress = strings.TrimSpace(set.AttachmentsClamAVAddress)
+			if !strings.HasPrefix(set.AttachmentsClamAVAddress, "/") {
+				if _, _, err := net.SplitHostPort(set.AttachmentsClamAVAddress); err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "attachments.clamav_address"))
+				}
+			}
+		}
+		if d, err := time.ParseDuration(set.AttachmentsClamAVTimeout); err != nil || d < time.Second {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "attachments.clamav_timeout"))
+		}
+	}
+	if set.AttachmentsMaxMessageSize < 0 {
+		set.AttachmentsMaxMessageSize = 0
+	}
+	if set.AttachmentsMaxBodySize < 0 {
+		set.AttachmentsMaxBodySize = 0
+	}
+	if set.AttachmentsMaxImageWeight < 0 {
+		set.AttachmentsMaxImageWeight = 0
+	}
+	if set.AttachmentsSizeAction != sizeActionWarn && set.AttachmentsSizeAction != sizeActionReject {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "attachments.size_action"))
+	}
+
 	// Update the settings in the DB.
 	if err := app.core.UpdateSettings(set); err != nil {
 		return err
 	}
+	recordAudit(c, models.AuditSettingsUpdate, auditEntitySettings, 0, maskSettings(cur), maskSettings(set))
+
+	// Apply the IP lists right away as the reload may be deferred.
+	_ = app.ipFilter.Set(ipOpt)
 
 	// If there are any active campaigns, don't do an auto reload and
 	// warn the user on the frontend.
@@ -249,6 +746,34 @@ func handleUpdateSettings(c echo.Context) error {
 	return c.JSON(http.StatusOK, okResp{true})
 }
 
+// handleGetMessengerVolume returns the sending volume of each messenger, with
+// the source IPs of the SMTP messengers, for the campaigns started in the last
+// n days.
+func handleGetMessengerVolume(c echo.Context) error {
+	var (
+		app     = c.Get("app").(*App)
+		days, _ = strconv.Atoi(c.QueryParam("days"))
+	)
+
+	if days < 1 || days > 365 {
+		days = 30
+	}
+
+	out, err := app.core.GetMessengerVolume(days)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range out {
+		out[i].SourceIPs = []string{}
+		if m, ok := app.messengers[v.Messenger].(interface{ SourceIPs() []string }); ok {
+			out[i].SourceIPs = m.SourceIPs()
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
 // handleGetLogs returns the log entries stored in the log buffer.
 func handleGetLogs(c echo.Context) error {
 	app := c.Get("app").(*App)
@@ -327,3 +852,15 @@ func handleGetAboutInfo(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, out)
 }
+
+// trimIPList returns the non-empty, trimmed entries of an IP list.
+func trimIPList(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
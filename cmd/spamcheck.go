@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/knadh/listmonk/internal/spamcheck"
+	"github.com/labstack/echo/v4"
+)
+
+// spamCheckResult is the spam score of a campaign.
+type spamCheckResult struct {
+	spamcheck.Result
+
+	Threshold float64 `json:"threshold"`
+	IsSpam    bool    `json:"is_spam"`
+}
+
+// handleCheckCampaignSpam renders a campaign, submits it to the configured spam
+// checker (Rspamd or SpamAssassin), and returns the score and the triggered rules.
+func handleCheckCampaignSpam(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if app.spamCheck == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("campaigns.spamCheckDisabled"))
+	}
+
+	out, err := runSpamCheck(id, app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runSpamCheck renders a campaign as a full e-mail message for a dummy subscriber
+// and scores it with the spam checker.
+func runSpamCheck(id int, app *App) (spamCheckResult, error) {
+	camp, err := app.core.GetCampaignForPreview(id, 0)
+	if err != nil {
+		return spamCheckResult{}, err
+	}
+
+	if err := loadLiveCampaignContent(&camp, app); err != nil {
+		return spamCheckResult{}, err
+	}
+
+	msg, err := renderCampaignPreview(&camp, app)
+	if err != nil {
+		return spamCheckResult{}, err
+	}
+
+	b, err := makeRawCampaignMessage(camp, msg, nil)
+	if err != nil {
+		return spamCheckResult{}, echo.NewHTTPError(http.StatusInternalServerError,
+			app.i18n.Ts("campaigns.spamCheckError", "error", err.Error()))
+	}
+
+	res, err := app.spamCheck.Check(b)
+	if err != nil {
+		app.log.Printf("error running spam check on campaign %d: %v", id, err)
+		return spamCheckResult{}, echo.NewHTTPError(http.StatusBadGateway,
+			app.i18n.Ts("campaigns.spamCheckError", "error", err.Error()))
+	}
+
+	return spamCheckResult{
+		Result:    res,
+		Threshold: app.constants.SpamCheck.Threshold,
+		IsSpam:    res.Score >= app.constants.SpamCheck.Threshold,
+	}, nil
+}
+
+// preflightSpamCheck scores a campaign before it's started or scheduled and
+// returns an error if the score is at or above the threshold.
+func preflightSpamCheck(id int, app *App) error {
+	if app.spamCheck == nil {
+		return nil
+	}
+
+	res, err := runSpamCheck(id, app)
+	if err != nil {
+		return err
+	}
+	if !res.IsSpam {
+		return nil
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest,
+		app.i18n.Ts("campaigns.spamCheckFailed", "score", fmt.Sprintf("%.2f", res.Score),
+			"threshold", fmt.Sprintf("%.2f", res.Threshold)))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// statusRulePreviewLimit is the max. number of matching subscribers returned in
+// a status rule preview.
+const statusRulePreviewLimit = 20
+
+// handleGetStatusRules returns all subscriber status rules.
+func handleGetStatusRules(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetStatusRules()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleCreateStatusRule creates a subscriber status rule.
+func handleCreateStatusRule(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.StatusRule
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateStatusRule(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.CreateStatusRule(o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateStatusRule updates a subscriber status rule.
+func handleUpdateStatusRule(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	var o models.StatusRule
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateStatusRule(&o, app); err != nil {
+		return err
+	}
+
+	out, err := app.core.UpdateStatusRule(id, o)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleDeleteStatusRule deletes a subscriber status rule.
+func handleDeleteStatusRule(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	if err := app.core.DeleteStatusRule(id); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handlePreviewStatusRule does a dry run of a (saved or unsaved) status rule and
+// returns the number of subscribers it would change and a sample of them.
+func handlePreviewStatusRule(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var o models.StatusRule
+	if err := c.Bind(&o); err != nil {
+		return err
+	}
+	if err := validateStatusRule(&o, app); err != nil {
+		return err
+	}
+
+	subs, total, err := app.core.PreviewStatusRule(o, statusRulePreviewLimit)
+	if err != nil {
+		return err
+	}
+
+	out := struct {
+		Total       int                 `json:"total"`
+		Subscribers []models.Subscriber `json:"subscribers"`
+	}{total, subs}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRunStatusRule applies a status rule immediately instead of waiting for
+// the next scheduled run.
+func handleRunStatusRule(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	r, err := app.core.GetStatusRule(id)
+	if err != nil {
+		return err
+	}
+
+	n, err := app.core.ApplyStatusRule(r)
+	if err != nil {
+		return err
+	}
+	app.log.Printf("status rule '%s' applied to %d subscribers", r.Name, n)
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Affected int `json:"affected"`
+	}{n}})
+}
+
+// runStatusRules applies all enabled subscriber status rules.
+func runStatusRules(app *App) {
+	rules, err := app.core.GetStatusRules()
+	if err != nil {
+		return
+	}
+
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+
+		n, err := app.core.ApplyStatusRule(r)
+		if err != nil {
+			continue
+		}
+		if n > 0 {
+			app.log.Printf("status rule '%s' applied to %d subscribers", r.Name, n)
+		}
+	}
+}
+
+// validateStatusRule validates and cleans up a status rule.
+func validateStatusRule(r *models.StatusRule, app *App) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if !strHasLen(r.Name, 1, stdInputMaxLen) {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "name"))
+	}
+
+	switch r.Condition {
+	case models.StatusRuleUnconfirmed:
+		r.BounceType, r.WindowDays = "", 0
+	case models.StatusRuleBounces:
+		if r.BounceType != "" && r.BounceType != models.BounceTypeSoft &&
+			r.BounceType != models.BounceTypeHard && r.BounceType != models.BounceTypeComplaint {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "bounce_type"))
+		}
+		if r.WindowDays < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "window_days"))
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "condition"))
+	}
+
+	if r.Threshold < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "threshold"))
+	}
+
+	if r.Action != models.StatusRuleUnsubscribe && r.Action != models.StatusRuleBlocklist {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "action"))
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/knadh/listmonk/internal/stripe"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// stripeMaxBodySize is the max. size of a Stripe webhook payload.
+	stripeMaxBodySize = 1024 * 1024
+
+	// stripeMaxReconcileItems is the max. number of discrepancies in a reconciliation report.
+	stripeMaxReconcileItems = 5000
+
+	// confirmedByStripe and subSourceStripe are recorded on subscriptions made by
+	// Stripe webhooks. Paying customers are pre-confirmed.
+	confirmedByStripe = "stripe"
+	subSourceStripe   = "stripe"
+)
+
+// handleStripeWebhook processes Stripe webhook events. Customer, checkout, and
+// invoice events record customers' e-mails, subscription events record the statuses
+// of the subscribed products, and refunds end the subscriptions of the refunded
+// invoices. The customer's subscriptions to the lists mapped to the products are
+// then synced. Events that aren't processed are acknowledged and ignored.
+func handleStripeWebhook(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, stripeMaxBodySize))
+	if err != nil {
+		app.log.Printf("error reading stripe webhook body: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.internalError"))
+	}
+
+	ev, err := stripe.ParseEvent(body, c.Request().Header.Get("Stripe-Signature"), app.constants.Stripe.WebhookSecret, stripe.DefaultTolerance)
+	if err != nil {
+		app.log.Printf("error processing stripe webhook: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+	}
+
+	// Customers whose subscriptions are to be synced.
+	var customers []string
+
+	switch ev.Type {
+	case stripe.EventCustomerCreated, stripe.EventCustomerUpdated:
+		var o stripe.Customer
+		if err := json.Unmarshal(ev.Data.Object, &o); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+		if err := upsertStripeCustomer(o.ID, o.Email, app); err != nil {
+			return err
+		}
+		customers = append(customers, o.ID)
+
+	case stripe.EventCheckoutCompleted:
+		var o stripe.CheckoutSession
+		if err := json.Unmarshal(ev.Data.Object, &o); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+
+		email := o.CustomerDetails.Email
+		if email == "" {
+			email = o.CustomerEmail
+		}
+		if err := upsertStripeCustomer(o.Customer, email, app); err != nil {
+			return err
+		}
+		customers = append(customers, o.Customer)
+
+	case stripe.EventInvoicePaid:
+		var o stripe.Invoice
+		if err := json.Unmarshal(ev.Data.Object, &o); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+		if err := upsertStripeCustomer(o.Customer, o.CustomerEmail, app); err != nil {
+			return err
+		}
+		customers = append(customers, o.Customer)
+
+	case stripe.EventSubscriptionCreated, stripe.EventSubscriptionUpdated, stripe.EventSubscriptionDeleted:
+		var o stripe.Subscription
+		if err := json.Unmarshal(ev.Data.Object, &o); err != nil || o.ID == "" || o.Customer == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+
+		// Deleted subscriptions carry their last status, which is usually already
+		// 'canceled', but may not be for subscriptions that are deleted immediately.
+		status := o.Status
+		if ev.Type == stripe.EventSubscriptionDeleted {
+			status = stripe.StatusCanceled
+		}
+
+		if err := app.core.UpsertStripeSubscription(o.ID, o.Customer, o.Products(), status, o.LatestInvoice, time.Unix(ev.Created, 0)); err != nil {
+			return err
+		}
+		customers = append(customers, o.Customer)
+
+	case stripe.EventChargeRefunded:
+		var o stripe.Charge
+		if err := json.Unmarshal(ev.Data.Object, &o); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+
+		// Only full refunds of subscription invoices end the subscriptions.
+		if !o.Refunded || o.Invoice == "" {
+			break
+		}
+
+		ids, err := app.core.RefundStripeInvoice(o.Invoice)
+		if err != nil {
+			return err
+		}
+		customers = append(customers, ids...)
+	}
+
+	for _, id := range customers {
+		if err := syncStripeCustomer(id, app); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handleGetStripeReconciliation returns the discrepancies between the Stripe
+// subscriptions of the mapped products and the subscriptions of their lists.
+func handleGetStripeReconciliation(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetStripeReconciliation(app.constants.Stripe.Products, stripeMaxReconcileItems)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// upsertStripeCustomer records a Stripe customer's (sanitized) e-mail. Invalid
+// e-mails are recorded as empty so that the customer's subscriptions are held
+// until a valid e-mail is received.
+func upsertStripeCustomer(id, email string, app *App) error {
+	if id == "" {
+		return nil
+	}
+
+	if email != "" {
+		em, err := app.importer.SanitizeEmail(email)
+		if err != nil {
+			app.log.Printf("invalid e-mail on stripe customer %s: %v", id, err)
+		}
+		email = em
+	}
+
+	return app.core.UpsertStripeCustomer(id, email)
+}
+
+// syncStripeCustomer applies the changes to a Stripe customer's subscription statuses
+// since they were last synced to the lists mapped to the subscribed products. Entitled
+// (active, trialing) products subscribe the customer's e-mail to their lists and ended
+// (canceled, unpaid, refunded) products unsubscribe it, unless another entitled product
+// maps to the same list. Customers whose e-mail isn't known yet are synced later.
+func syncStripeCustomer(customerID string, app *App) error {
+	subs, err := app.core.GetStripeCustomerSubscriptions(customerID)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 || subs[0].Email == "" {
+		return nil
+	}
+
+	var (
+		email    = subs[0].Email
+		entitled = map[int]bool{}
+		subIDs   = map[int]bool{}
+		unsubIDs = map[int]bool{}
+	)
+	for _, s := range subs {
+		lists := app.constants.Stripe.Products[s.ProductID]
+		if stripe.IsEntitled(s.Status) {
+			for _, id := range lists {
+				entitled[id] = true
+			}
+		}
+
+		if s.Status == s.SyncedStatus {
+			continue
+		}
+		for _, id := range lists {
+			if stripe.IsEntitled(s.Status) {
+				subIDs[id] = true
+			} else if stripe.IsEnded(s.Status) {
+				unsubIDs[id] = true
+			}
+		}
+	}
+
+	if len(subIDs) > 0 {
+		listIDs := make([]int, 0, len(subIDs))
+		for id := range subIDs {
+			listIDs = append(listIDs, id)
+		}
+
+		sub := models.Subscriber{
+			Email:  email,
+			Name:   strings.Split(email, "@")[0],
+			Status: models.SubscriberStatusEnabled,
+		}
+		if _, _, err := app.core.InsertSubscriber(sub, listIDs, nil, true, confirmedByStripe, subSourceStripe); err != nil {
+			// Subscriber already exists. Add the subscriptions.
+			e, ok := err.(*echo.HTTPError)
+			if !ok || e.Code != http.StatusConflict {
+				return err
+			}
+
+			sub, err := app.core.GetSubscriber(0, "", email)
+			if err != nil {
+				return err
+			}
+			if _, _, err := app.core.UpdateSubscriberWithLists(sub.ID, sub, listIDs, nil, true, confirmedByStripe, subSourceStripe, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	unsub := make([]int, 0, len(unsubIDs))
+	for id := range unsubIDs {
+		if !entitled[id] {
+			unsub = append(unsub, id)
+		}
+	}
+	if len(unsub) > 0 {
+		sub, err := app.core.GetSubscriber(0, "", email)
+		if err == nil {
+			if err := app.core.UnsubscribeLists([]int{sub.ID}, unsub, nil); err != nil {
+				return err
+			}
+		} else if e, ok := err.(*echo.HTTPError); !ok || e.Code != http.StatusBadRequest {
+			return err
+		}
+	}
+
+	if err := app.core.MarkStripeSubscriptionsSynced(customerID); err != nil {
+		return err
+	}
+
+	app.log.Printf("synced stripe customer %s (%s): subscribed %d, unsubscribed %d lists", customerID, email, len(subIDs), len(unsub))
+	return nil
+}
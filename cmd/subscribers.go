@@ -1,11 +1,13 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"strconv"
 	"strings"
@@ -17,6 +19,25 @@ import (
 
 const (
 	dummyUUID = "00000000-0000-0000-0000-000000000000"
+
+	// hdrSourceToken is the request header that carries a trusted source's token.
+	hdrSourceToken = "X-Listmonk-Source-Token"
+
+	// confirmedByAdmin and confirmedByImport are the sources recorded in the
+	// meta of subscriptions pre-confirmed from the admin (API) and imports.
+	confirmedByAdmin  = "admin"
+	confirmedByImport = "import"
+
+	// Sources recorded on new subscriptions for growth analytics. Trusted sources
+	// are recorded as source:{name}, tagged public forms (eg: widgets) as form:{tag},
+	// and imports as import:{run}.
+	subSourceAdmin   = "admin"
+	subSourceForm    = "form"
+	subSourceTrusted = "source:"
+	subSourceImport  = "import:"
+
+	// subBatchMaxItems is the maximum number of UUIDs and e-mails in a batch query.
+	subBatchMaxItems = 5000
 )
 
 // subQueryReq is a "catch all" struct for reading various
@@ -30,6 +51,14 @@ type subQueryReq struct {
 	Status        string `json:"status"`
 }
 
+// subBatchReq is a request to fetch selected columns of subscribers by their
+// UUIDs or e-mails.
+type subBatchReq struct {
+	UUIDs   []string `json:"uuids"`
+	Emails  []string `json:"emails"`
+	Columns []string `json:"columns"`
+}
+
 // subProfileData represents a subscriber's collated data in JSON
 // for export.
 type subProfileData struct {
@@ -47,6 +76,9 @@ type subOptin struct {
 	OptinURL string
 	UnsubURL string
 	Lists    []models.List
+
+	// Opt-in can be confirmed by replying to the e-mail.
+	OptinByReply bool
 }
 
 var (
@@ -101,12 +133,144 @@ func handleQuerySubscribers(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
 	}
 
-	res, total, err := app.core.QuerySubscribers(query, listIDs, subStatus, order, orderBy, pg.Offset, pg.Limit)
+	// Only select specific columns (eg: ?columns=email,attribs.city) of subscribers?
+	var cols []string
+	for _, v := range c.QueryParams()["columns"] {
+		for _, col := range strings.Split(v, ",") {
+			if col = strings.TrimSpace(col); col != "" {
+				cols = append(cols, col)
+			}
+		}
+	}
+
+	if len(cols) > 0 {
+		res, total, err := app.core.QuerySubscriberColumns(cols, query, listIDs, subStatus, order, orderBy, pg.Offset, pg.Limit)
+		if err != nil {
+			return err
+		}
+		out.Results = res
+		out.Total = total
+	} else {
+		res, total, err := app.core.QuerySubscribers(query, listIDs, subStatus, order, orderBy, pg.Offset, pg.Limit)
+		if err != nil {
+			return err
+		}
+		out.Results = res
+		out.Total = total
+	}
+
+	out.Query = query
+	out.Page = pg.Page
+	out.PerPage = pg.PerPage
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetSubscribersBatch returns selected columns of the subscribers with
+// the given UUIDs or e-mails. It's meant for integrations that periodically read
+// known subscribers in bulk without paging through all of them.
+func handleGetSubscribersBatch(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var req subBatchReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	n := len(req.UUIDs) + len(req.Emails)
+	if n == 0 || n > subBatchMaxItems {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			app.i18n.Ts("subscribers.invalidBatch", "num", strconv.Itoa(subBatchMaxItems)))
+	}
+
+	for _, u := range req.UUIDs {
+		if !reUUID.MatchString(u) {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.invalidFields", "name", "uuids"))
+		}
+	}
+
+	for i, e := range req.Emails {
+		req.Emails[i] = strings.TrimSpace(e)
+	}
+
+	if len(req.Columns) == 0 {
+		req.Columns = []string{"uuid", "email", "name", "status"}
+	}
+
+	out, err := app.core.GetSubscriberColumnsBatch(req.Columns, req.UUIDs, req.Emails)
 	if err != nil {
 		return err
 	}
 
-	out.Query = query
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleGetSubscriberColumns returns the subscribers table columns of the user.
+func handleGetSubscriberColumns(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetSubscriberColumns(getUsername(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleUpdateSubscriberColumns saves the subscribers table columns of the user.
+// An empty list of columns resets them to the defaults.
+func handleUpdateSubscriberColumns(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	var req struct {
+		Columns []string `json:"columns"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	cols := make([]string, 0, len(req.Columns))
+	for _, col := range req.Columns {
+		if col = strings.TrimSpace(col); col != "" {
+			cols = append(cols, col)
+		}
+	}
+
+	out, err := app.core.UpdateSubscriberColumns(getUsername(c), cols)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleSearchSubscribers handles typo tolerant full-text search of subscribers
+// by name, e-mail, and attribute values.
+func handleSearchSubscribers(c echo.Context) error {
+	var (
+		app       = c.Get("app").(*App)
+		pg        = app.paginator.NewFromURL(c.Request().URL.Query())
+		searchStr = strings.TrimSpace(c.FormValue("q"))
+		subStatus = c.FormValue("subscription_status")
+		out       models.PageResults
+	)
+
+	if searchStr == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("globals.messages.missingFields", "name", "q"))
+	}
+
+	// Limit the subscribers to specific lists?
+	listIDs, err := getQueryInts("list_id", c.QueryParams())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	res, total, err := app.core.SearchSubscribers(searchStr, listIDs, subStatus, pg.Offset, pg.Limit)
+	if err != nil {
+		return err
+	}
+
+	out.Query = searchStr
 	out.Results = res
 	out.Total = total
 	out.Page = pg.Page
@@ -201,8 +365,24 @@ func handleCreateSubscriber(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	// Flag or reject role and spam-trap addresses as configured on the lists.
+	mode, err := getAddressFilter(req.Lists, req.ListUUIDs, app)
+	if err != nil {
+		return err
+	}
+	if req, err = app.importer.FilterAddress(req, mode); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Subscriptions from trusted sources are pre-confirmed.
+	confirmedBy, source := confirmedByAdmin, subSourceAdmin
+	if src, ok := getTrustedSource(c, app); ok {
+		req.PreconfirmSubs = true
+		confirmedBy, source = src, subSourceTrusted+src
+	}
+
 	// Insert the subscriber into the DB.
-	sub, _, err := app.core.InsertSubscriber(req.Subscriber, req.Lists, req.ListUUIDs, req.PreconfirmSubs)
+	sub, _, err := app.core.InsertSubscriber(req.Subscriber, req.Lists, req.ListUUIDs, req.PreconfirmSubs, confirmedBy, source)
 	if err != nil {
 		return err
 	}
@@ -241,11 +421,45 @@ func handleUpdateSubscriber(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("subscribers.invalidName"))
 	}
 
-	out, _, err := app.core.UpdateSubscriberWithLists(id, req.Subscriber, req.Lists, nil, req.PreconfirmSubs, true)
+	if err := app.importer.ValidateTimezone(req.Attribs); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Subscriptions from trusted sources are pre-confirmed.
+	confirmedBy, source := confirmedByAdmin, subSourceAdmin
+	if src, ok := getTrustedSource(c, app); ok {
+		req.PreconfirmSubs = true
+		confirmedBy, source = src, subSourceTrusted+src
+	}
+
+	// If e-mail changes have to be confirmed, retain the current e-mail and
+	// send a confirmation link to the new one.
+	var (
+		newEmail string
+		cur      models.Subscriber
+	)
+	if app.constants.Privacy.ConfirmEmailChange {
+		s, err := app.core.GetSubscriber(id, "", "")
+		if err != nil {
+			return err
+		}
+		if s.Email != req.Email {
+			newEmail, cur = req.Email, s
+			req.Email = s.Email
+		}
+	}
+
+	out, _, err := app.core.UpdateSubscriberWithLists(id, req.Subscriber, req.Lists, nil, req.PreconfirmSubs, confirmedBy, source, true)
 	if err != nil {
 		return err
 	}
 
+	if newEmail != "" {
+		if err := requestEmailChange(cur, newEmail, app); err != nil {
+			return err
+		}
+	}
+
 	return c.JSON(http.StatusOK, okResp{out})
 }
 
@@ -396,10 +610,24 @@ func handleDeleteSubscribers(c echo.Context) error {
 		subIDs = i
 	}
 
+	// Snapshot a single subscriber for the audit log.
+	var before interface{} = map[string][]int{"ids": subIDs}
+	if len(subIDs) == 1 {
+		if sub, err := app.core.GetSubscriber(subIDs[0], "", ""); err == nil {
+			before = sub
+		}
+	}
+
 	if err := app.core.DeleteSubscribers(subIDs, nil); err != nil {
 		return err
 	}
 
+	entityID := 0
+	if len(subIDs) == 1 {
+		entityID = subIDs[0]
+	}
+	recordAudit(c, models.AuditSubscriberDelete, auditEntitySubscriber, entityID, before, nil)
+
 	return c.JSON(http.StatusOK, okResp{true})
 }
 
@@ -418,6 +646,7 @@ func handleDeleteSubscribersByQuery(c echo.Context) error {
 	if err := app.core.DeleteSubscribersByQuery(req.Query, req.ListIDs); err != nil {
 		return err
 	}
+	recordAudit(c, models.AuditSubscriberDeleteQ, auditEntitySubscriber, 0, req, nil)
 
 	return c.JSON(http.StatusOK, okResp{true})
 }
@@ -622,8 +851,17 @@ func sendOptinConfirmationHook(app *App) func(sub models.Subscriber, listIDs []i
 		out.OptinURL = fmt.Sprintf(app.constants.OptinURL, sub.UUID, qListIDs.Encode())
 		out.UnsubURL = fmt.Sprintf(app.constants.UnsubURL, dummyUUID, sub.UUID)
 
+		// If there's an opt-in reply address, set the plus-tagged Reply-To
+		// so that replies to the e-mail confirm the subscription.
+		var hdr textproto.MIMEHeader
+		if addr := makeOptinReplyAddress(app.constants.Privacy.OptinReplyAddress, sub.UUID); addr != "" {
+			hdr = textproto.MIMEHeader{}
+			hdr.Set(models.EmailHeaderReplyTo, addr)
+			out.OptinByReply = true
+		}
+
 		// Send the e-mail.
-		if err := app.sendNotification([]string{sub.Email}, app.i18n.T("subscribers.optinSubject"), notifSubscriberOptin, out); err != nil {
+		if err := app.sendNotificationHeaders([]string{sub.Email}, app.i18n.T("subscribers.optinSubject"), notifSubscriberOptin, out, hdr); err != nil {
 			app.log.Printf("error sending opt-in e-mail for subscriber %d (%s): %s", sub.ID, sub.UUID, err)
 			return 0, err
 		}
@@ -631,3 +869,99 @@ func sendOptinConfirmationHook(app *App) func(sub models.Subscriber, listIDs []i
 		return len(lists), nil
 	}
 }
+
+// confirmOptinReplyHook returns an enclosed callback that confirms the pending
+// double opt-in subscriptions of a subscriber who replied to the opt-in
+// confirmation e-mail. This is plugged into the bounce manager's mailbox scanner.
+func confirmOptinReplyHook(app *App) func(r models.OptinReply) error {
+	return func(r models.OptinReply) error {
+		if app.constants.Privacy.OptinReplyAddress == "" {
+			return nil
+		}
+
+		sub, err := app.core.GetSubscriber(0, r.SubscriberUUID, "")
+		if err != nil {
+			return err
+		}
+
+		// Only the subscriber can confirm their subscriptions.
+		if !strings.EqualFold(sub.Email, r.Email) {
+			app.log.Printf("ignoring opt-in reply for subscriber %d from a different address (%s)", sub.ID, r.Email)
+			return nil
+		}
+
+		lists, err := app.core.GetSubscriberLists(sub.ID, "", nil, nil, models.SubscriptionStatusUnconfirmed, models.ListOptinDouble)
+		if err != nil {
+			return err
+		}
+		if len(lists) == 0 {
+			return nil
+		}
+
+		listUUIDs := make([]string, 0, len(lists))
+		for _, l := range lists {
+			listUUIDs = append(listUUIDs, l.UUID)
+		}
+
+		return app.core.ConfirmOptionSubscription(sub.UUID, listUUIDs, models.JSON{"optin_method": "reply"})
+	}
+}
+
+// makeOptinReplyAddress returns the opt-in reply address plus-tagged with the
+// subscriber's UUID, eg: confirm+optin-$subUUID@site.com.
+func makeOptinReplyAddress(addr, subUUID string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return addr[:at] + "+optin-" + subUUID + addr[at:]
+}
+
+// getAddressFilter returns the strictest address filter mode (models.ListAddressFilter*)
+// of the given lists.
+func getAddressFilter(listIDs []int, listUUIDs []string, app *App) (string, error) {
+	if len(listIDs) == 0 {
+		listIDs = nil
+	}
+	if len(listUUIDs) == 0 {
+		listUUIDs = nil
+	}
+	if listIDs == nil && listUUIDs == nil {
+		return models.ListAddressFilterNone, nil
+	}
+
+	lists, err := app.core.GetListsByIDs(listIDs, listUUIDs)
+	if err != nil {
+		return "", err
+	}
+
+	mode := models.ListAddressFilterNone
+	for _, l := range lists {
+		switch l.AddressFilter {
+		case models.ListAddressFilterReject:
+			return l.AddressFilter, nil
+		case models.ListAddressFilterFlag:
+			mode = l.AddressFilter
+		}
+	}
+
+	return mode, nil
+}
+
+// getTrustedSource returns the name of the trusted source whose token is
+// in the request's source token header, if any.
+func getTrustedSource(c echo.Context, app *App) (string, bool) {
+	tok := c.Request().Header.Get(hdrSourceToken)
+	if tok == "" {
+		return "", false
+	}
+
+	for t, name := range app.constants.Privacy.TrustedSources {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(tok)) == 1 {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sunsetResult represents the outcome of a single run of the sunset policy.
+type sunsetResult struct {
+	Enrolled  int `json:"enrolled"`
+	Recovered int `json:"recovered"`
+	Removed   int `json:"removed"`
+}
+
+// handleGetSunsetStats returns the number of subscribers enrolled, recovered,
+// and removed by the sunset (win-back) policy.
+func handleGetSunsetStats(c echo.Context) error {
+	var (
+		app = c.Get("app").(*App)
+		s   = c.FormValue("since")
+	)
+
+	var since time.Time
+	if s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				app.i18n.Ts("globals.messages.invalidFields", "name", "since"))
+		}
+		since = t
+	}
+
+	out, err := app.core.GetSunsetStats(since)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRunSunset runs the sunset policy immediately instead of waiting
+// for the next scheduled run.
+func handleRunSunset(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	if !app.constants.Sunset.Enabled {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("subscribers.sunsetDisabled"))
+	}
+
+	out, err := runSunset(app)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// runSunset runs one pass of the sunset (win-back) policy. Enrolled subscribers
+// who have engaged since enrolment are marked as recovered, those who haven't
+// engaged within the grace period are unsubscribed or blocklisted, and finally,
+// subscribers who haven't engaged in the configured number of days are enrolled
+// into the re-engagement list.
+func runSunset(app *App) (sunsetResult, error) {
+	var (
+		out sunsetResult
+		s   = app.constants.Sunset
+	)
+
+	// Without subscriber-level tracking, views and clicks can't be attributed to
+	// subscribers and everyone would appear inactive.
+	if !app.constants.Privacy.IndividualTracking {
+		return out, echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("subscribers.sunsetNeedsTracking"))
+	}
+
+	n, err := app.core.RecoverSunsetSubscribers(s.ListID)
+	if err != nil {
+		return out, err
+	}
+	out.Recovered = n
+
+	n, err = app.core.RemoveSunsetSubscribers(s.GraceDays, s.Action)
+	if err != nil {
+		return out, err
+	}
+	out.Removed = n
+
+	n, err = app.core.EnrollSunsetSubscribers(s.InactiveDays, s.ListID)
+	if err != nil {
+		return out, err
+	}
+	out.Enrolled = n
+
+	app.log.Printf("sunset policy: %d enrolled, %d recovered, %d removed", out.Enrolled, out.Recovered, out.Removed)
+	return out, nil
+}
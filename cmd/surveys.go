@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
+)
+
+const (
+	// surveyMaxScore is the highest score of rating questions that are scored 1 to n.
+	surveyMaxScore = 10
+
+	// surveyMaxAnswerLen is the max length of a choice answer.
+	surveyMaxAnswerLen = 200
+)
+
+var regexSurveyKey = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+
+// handleGetCampaignSurveys returns the results of the survey questions in a campaign.
+func handleGetCampaignSurveys(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	out, err := app.core.GetCampaignSurveys(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleSurveyResponse records a subscriber's answer to a survey question in a
+// campaign. The links are generated by the {{ SurveyRating }}, {{ SurveyChoice }},
+// and {{ SurveyURL }} template tags. Rating questions send a score (s) and
+// choices an answer (a).
+func handleSurveyResponse(c echo.Context) error {
+	var (
+		app      = c.Get("app").(*App)
+		campUUID = c.Param("campUUID")
+		subUUID  = c.Param("subUUID")
+		key      = c.Param("key")
+		answer   = strings.TrimSpace(c.QueryParam("a"))
+		score    null.Int
+	)
+
+	if s := c.QueryParam("s"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > surveyMaxScore {
+			answer = ""
+		} else {
+			answer = s
+			score = null.IntFrom(n)
+		}
+	}
+
+	if !regexSurveyKey.MatchString(key) || !strHasLen(answer, 1, surveyMaxAnswerLen) {
+		return c.Render(http.StatusBadRequest, tplMessage,
+			makeMsgTpl(app.i18n.T("public.errorTitle"), "", app.i18n.T("public.invalidLink")))
+	}
+
+	// Exclude dummy hits from template previews.
+	if campUUID != dummyUUID {
+		// If individual tracking is disabled, do not record the subscriber ID.
+		if !app.constants.Privacy.IndividualTracking || subUUID == dummyUUID {
+			subUUID = ""
+		}
+
+		if err := app.core.RegisterSurveyResponse(campUUID, subUUID, key, answer, score); err != nil {
+			e := err.(*echo.HTTPError)
+			return c.Render(e.Code, tplMessage, makeMsgTpl(app.i18n.T("public.errorTitle"), "", e.Message.(string)))
+		}
+	}
+
+	return c.Render(http.StatusOK, tplMessage,
+		makeMsgTpl(app.i18n.T("public.surveyThanksTitle"), "", app.i18n.T("public.surveyThanks")))
+}
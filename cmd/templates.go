@@ -10,6 +10,7 @@ import (
 
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
 )
 
 const (
@@ -47,6 +48,7 @@ func handleGetTemplates(c echo.Context) error {
 			return err
 		}
 
+		setETag(c, out.Version)
 		return c.JSON(http.StatusOK, okResp{out})
 	}
 
@@ -120,7 +122,7 @@ func handlePreviewTemplate(c echo.Context) error {
 		out = msg.Body()
 	} else {
 		// Compile transactional template.
-		if err := tpl.Compile(app.manager.GenericTemplateFuncs()); err != nil {
+		if err := tpl.Compile(txTemplateFuncs(app)); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
 
@@ -155,13 +157,14 @@ func handleCreateTemplate(c echo.Context) error {
 
 	var f template.FuncMap
 
-	// Subject is only relevant for fixed tx templates. For campaigns,
-	// the subject changes per campaign and is on models.Campaign.
+	// Subject and header presets are only relevant for fixed tx templates. For campaigns,
+	// they change per campaign and are on models.Campaign.
 	if o.Type == models.TemplateTypeCampaign {
 		o.Subject = ""
+		o.HeaderPresetID = null.Int{}
 		f = app.manager.TemplateFuncs(nil)
 	} else {
-		f = app.manager.GenericTemplateFuncs()
+		f = txTemplateFuncs(app)
 	}
 
 	// Compile the template and validate.
@@ -170,7 +173,7 @@ func handleCreateTemplate(c echo.Context) error {
 	}
 
 	// Create the template the in the DB.
-	out, err := app.core.CreateTemplate(o.Name, o.Type, o.Subject, []byte(o.Body))
+	out, err := app.core.CreateTemplate(o.Name, o.Type, o.Subject, []byte(o.Body), o.HeaderPresetID)
 	if err != nil {
 		return err
 	}
@@ -200,19 +203,41 @@ func handleUpdateTemplate(c echo.Context) error {
 		return err
 	}
 
+	// Optimistic locking. The version the update is based on can be sent in the
+	// If-Match header or the version field. If neither is sent, there's no check.
+	if v, ok, err := getIfMatchVersion(c, app); err != nil {
+		return err
+	} else if ok {
+		o.Version = v
+	}
+	if o.Version > 0 {
+		cur, err := app.core.GetTemplate(id, false)
+		if err != nil {
+			return err
+		}
+
+		if o.Version != cur.Version {
+			yours := o
+			yours.ID, yours.Type, yours.IsDefault = cur.ID, cur.Type, cur.IsDefault
+			return makeVersionConflict(app.i18n.Ts("globals.messages.versionConflict", "name", "{globals.terms.template}"),
+				cur.Version, cur, yours)
+		}
+	}
+
 	if err := validateTemplate(o, app); err != nil {
 		return err
 	}
 
 	var f template.FuncMap
 
-	// Subject is only relevant for fixed tx templates. For campaigns,
-	// the subject changes per campaign and is on models.Campaign.
+	// Subject and header presets are only relevant for fixed tx templates. For campaigns,
+	// they change per campaign and are on models.Campaign.
 	if o.Type == models.TemplateTypeCampaign {
 		o.Subject = ""
+		o.HeaderPresetID = null.Int{}
 		f = app.manager.TemplateFuncs(nil)
 	} else {
-		f = app.manager.GenericTemplateFuncs()
+		f = txTemplateFuncs(app)
 	}
 
 	// Compile the template and validate.
@@ -220,7 +245,7 @@ func handleUpdateTemplate(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	out, err := app.core.UpdateTemplate(id, o.Name, o.Subject, []byte(o.Body))
+	out, err := app.core.UpdateTemplate(id, o.Name, o.Subject, []byte(o.Body), o.HeaderPresetID, o.Version)
 	if err != nil {
 		return err
 	}
@@ -289,5 +314,11 @@ func validateTemplate(o models.Template, app *App) error {
 			app.i18n.Ts("globals.messages.missingFields", "name", "subject"))
 	}
 
+	if o.HeaderPresetID.Valid {
+		if _, err := app.core.GetHeaderPreset(o.HeaderPresetID.Int); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
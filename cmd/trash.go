@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// handleGetTrash returns the trashed campaigns, lists, and templates
+// along with the time at which they'll be permanently deleted.
+func handleGetTrash(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	out, err := app.core.GetTrash()
+	if err != nil {
+		return err
+	}
+
+	if days := app.constants.TrashRetentionDays; days > 0 {
+		for i, t := range out {
+			out[i].PurgeAt = null.TimeFrom(t.DeletedAt.AddDate(0, 0, days))
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{out})
+}
+
+// handleRestoreTrash restores a trashed campaign, list, or template.
+func handleRestoreTrash(c echo.Context) error {
+	var (
+		app   = c.Get("app").(*App)
+		typ   = c.Param("type")
+		id, _ = strconv.Atoi(c.Param("id"))
+	)
+
+	if id < 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidID"))
+	}
+
+	switch typ {
+	case models.TrashTypeCampaign, models.TrashTypeList, models.TrashTypeTemplate:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+	}
+
+	if err := app.core.RestoreTrash(typ, id); err != nil {
+		return err
+	}
+
+	// Re-cache restored transactional templates.
+	if typ == models.TrashTypeTemplate {
+		tpl, err := app.core.GetTemplate(id, false)
+		if err != nil {
+			return err
+		}
+
+		if tpl.Type == models.TemplateTypeTx {
+			if err := tpl.Compile(txTemplateFuncs(app)); err != nil {
+				app.log.Printf("error compiling transactional template %d: %v", tpl.ID, err)
+			} else {
+				app.manager.CacheTpl(tpl.ID, &tpl)
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, okResp{true})
+}
+
+// handlePurgeTrash permanently deletes all trashed items or the ones
+// trashed before an optional date.
+func handlePurgeTrash(c echo.Context) error {
+	app := c.Get("app").(*App)
+
+	before := time.Now()
+	if d := c.FormValue("before_date"); d != "" {
+		t, err := time.Parse(time.RFC3339, d)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, app.i18n.T("globals.messages.invalidData"))
+		}
+		before = t
+	}
+
+	n, err := app.core.PurgeTrash(before)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, okResp{struct {
+		Count int `json:"count"`
+	}{n}})
+}
+
+// purgeTrash permanently deletes the items that have been in the trash
+// for longer than the retention period.
+func purgeTrash(app *App) (int, error) {
+	n, err := app.core.PurgeTrash(time.Now().AddDate(0, 0, -app.constants.TrashRetentionDays))
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		app.log.Printf("purged %d item(s) from the trash", n)
+	}
+
+	return n, nil
+}
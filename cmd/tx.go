@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"io"
 	"net/http"
 	"net/textproto"
@@ -55,6 +57,10 @@ func handleSendTxMessage(c echo.Context) error {
 					app.i18n.Ts("globals.messages.invalidFields", "name", fmt.Sprintf("file: %s", err.Error())))
 			}
 
+			if err := scanAttachment(f.Filename, bytes.NewReader(b), app); err != nil {
+				return err
+			}
+
 			m.Attachments = append(m.Attachments, models.Attachment{
 				Name:    f.Filename,
 				Header:  manager.MakeAttachmentHeader(f.Filename, "base64", f.Header.Get("Content-Type")),
@@ -80,6 +86,16 @@ func handleSendTxMessage(c echo.Context) error {
 			app.i18n.Ts("globals.messages.notFound", "name", fmt.Sprintf("template %d", m.TemplateID)))
 	}
 
+	// Headers of the template's header preset precede the message's own headers.
+	var presetHeaders models.Headers
+	if tpl.HeaderPresetID.Valid {
+		p, err := app.core.GetHeaderPreset(tpl.HeaderPresetID.Int)
+		if err != nil {
+			return err
+		}
+		presetHeaders = p.Headers
+	}
+
 	var (
 		num      = len(m.SubscriberEmails)
 		isEmails = true
@@ -138,15 +154,23 @@ func handleSendTxMessage(c echo.Context) error {
 		}
 
 		// Optional headers.
-		if len(m.Headers) != 0 {
-			msg.Headers = make(textproto.MIMEHeader, len(m.Headers))
-			for _, set := range m.Headers {
-				for hdr, val := range set {
-					msg.Headers.Add(hdr, val)
+		if len(presetHeaders) != 0 || len(m.Headers) != 0 || m.UnsubscribeHeader {
+			msg.Headers = make(textproto.MIMEHeader, len(presetHeaders)+len(m.Headers)+2)
+			for _, hdrs := range []models.Headers{presetHeaders, m.Headers} {
+				for _, set := range hdrs {
+					for hdr, val := range set {
+						msg.Headers.Add(hdr, val)
+					}
 				}
 			}
 		}
 
+		// RFC 8058 one-click unsubscribe headers.
+		if m.UnsubscribeHeader {
+			msg.Headers.Set("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+			msg.Headers.Set("List-Unsubscribe", `<`+makeTxSubURL(sub.UUID, app)+`>`)
+		}
+
 		if err := app.manager.PushMessage(msg); err != nil {
 			app.log.Printf("error sending message (%s): %v", msg.Subject, err)
 			return err
@@ -202,6 +226,46 @@ func validateTxMessage(m models.TxMessage, app *App) (models.TxMessage, error) {
 	} else if !app.manager.HasMessenger(m.Messenger) {
 		return m, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.fieldInvalidMessenger", "name", m.Messenger))
 	}
+	if len(m.Attachments) > 0 && !app.manager.MessengerSupportsAttachments(m.Messenger) {
+		return m, echo.NewHTTPError(http.StatusBadRequest, app.i18n.Ts("campaigns.messengerNoAttachments", "name", m.Messenger))
+	}
 
 	return m, nil
 }
+
+// txTemplateFuncs returns the template functions available to transactional
+// templates. In addition to the generic functions, these generate signed
+// subscription management links for arbitrary subscribers,
+// eg: {{ UnsubscribeURL .Subscriber }}.
+func txTemplateFuncs(app *App) template.FuncMap {
+	f := template.FuncMap{
+		"UnsubscribeURL": func(sub models.Subscriber) string {
+			return makeTxSubURL(sub.UUID, app)
+		},
+		"ManageURL": func(sub models.Subscriber) string {
+			return makeTxSubURL(sub.UUID, app) + "?manage=true"
+		},
+		// The one-click opt-out URL unsubscribes on a POST without a confirmation
+		// (RFC 8058) and is meant for List-Unsubscribe headers.
+		"OptOutURL": func(sub models.Subscriber) string {
+			return makeTxSubURL(sub.UUID, app)
+		},
+	}
+
+	for k, v := range app.manager.GenericTemplateFuncs() {
+		f[k] = v
+	}
+
+	return f
+}
+
+// makeTxSubURL returns the signed subscription management URL for a subscriber.
+func makeTxSubURL(subUUID string, app *App) string {
+	return fmt.Sprintf(app.constants.TxSubURL, subUUID, signSubUUID(subUUID, app.constants.Security.SigningKey))
+}
+
+// signSubUUID returns the HMAC signature of a subscriber UUID used in
+// subscription links sent in transactional messages.
+func signSubUUID(subUUID, key string) string {
+	return signString("tx-subscription:"+subUUID, key)
+}
@@ -2,17 +2,26 @@ package main
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
 var (
 	regexpSpaces = regexp.MustCompile(`[\s]+`)
+
+	// Domain name, eg: bounce.site.com.
+	regexpDomain = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9\-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
 )
 
 // inArray checks if a string is present in a list of strings.
@@ -130,3 +139,40 @@ func isASCII(s string) bool {
 	}
 	return true
 }
+
+// isHTTPURL checks if a string is an absolute http(s) URL.
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isTimezone checks if a string is empty or an IANA time zone name, eg: Europe/Berlin.
+func isTimezone(s string) bool {
+	if s == "" {
+		return true
+	}
+	if s == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(s)
+	return err == nil
+}
+
+// isValidReturnPath checks if a string is a valid envelope sender (Return-Path),
+// which can be a domain, eg: bounce.site.com, or a plain e-mail address.
+func isValidReturnPath(s string) bool {
+	if !strings.Contains(s, "@") {
+		return regexpDomain.MatchString(s)
+	}
+
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Name == "" && a.Address == s
+}
+
+// signString returns the URL safe HMAC-SHA256 signature of a string.
+func signString(s, key string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -153,6 +153,199 @@ export const deleteList = (id) => http.delete(
   { loading: models.lists },
 );
 
+export const bulkUpdateLists = (data) => http.put(
+  '/api/lists/bulk',
+  data,
+  { loading: models.lists },
+);
+
+export const mergeList = (id, sourceId) => http.post(
+  `/api/lists/${id}/merge`,
+  { source_id: sourceId },
+  { loading: models.lists },
+);
+
+export const getListFolders = () => http.get(
+  '/api/lists/folders',
+  { camelCase: false },
+);
+
+export const getListHygiene = (id) => http.get(
+  id ? `/api/lists/${id}/hygiene` : '/api/lists/hygiene',
+  { loading: models.lists, camelCase: false },
+);
+
+export const runListHygiene = () => http.post('/api/lists/hygiene/run');
+
+export const getListGrowth = (id, params) => http.get(
+  `/api/lists/${id}/growth`,
+  { params, camelCase: false },
+);
+
+export const getListHealth = (id, params) => http.get(
+  `/api/lists/${id}/health`,
+  { params, camelCase: false },
+);
+
+export const applyListHygieneAction = (id, data) => http.post(
+  `/api/lists/${id}/hygiene`,
+  data,
+  { loading: models.lists },
+);
+
+export const getListRepermissions = (id) => http.get(
+  `/api/lists/${id}/repermission`,
+  { loading: models.lists, camelCase: false },
+);
+
+export const startListRepermission = (id, data) => http.post(
+  `/api/lists/${id}/repermission`,
+  data,
+  { loading: models.lists },
+);
+
+export const finishListRepermission = (id) => http.post(
+  `/api/lists/${id}/repermission/finish`,
+  {},
+  { loading: models.lists },
+);
+
+export const cancelListRepermission = (id) => http.delete(
+  `/api/lists/${id}/repermission`,
+  { loading: models.lists },
+);
+
+export const getListBlackouts = (id, params) => http.get(
+  `/api/lists/${id}/blackouts`,
+  { params, loading: models.lists, camelCase: false },
+);
+
+export const createListBlackout = (id, data) => http.post(
+  `/api/lists/${id}/blackouts`,
+  data,
+  { loading: models.lists },
+);
+
+export const deleteListBlackout = (id, blackoutID) => http.delete(
+  `/api/lists/${id}/blackouts/${blackoutID}`,
+  { loading: models.lists },
+);
+
+// Header presets.
+export const getHeaderPresets = async () => http.get(
+  '/api/header-presets',
+  { camelCase: false },
+);
+
+export const createHeaderPreset = async (data) => http.post(
+  '/api/header-presets',
+  data,
+  { camelCase: false },
+);
+
+export const updateHeaderPreset = async (id, data) => http.put(
+  `/api/header-presets/${id}`,
+  data,
+  { camelCase: false },
+);
+
+export const deleteHeaderPreset = async (id) => http.delete(`/api/header-presets/${id}`);
+
+// Drip sequences.
+export const getSequences = async () => http.get(
+  '/api/sequences',
+  { camelCase: false },
+);
+
+export const createSequence = async (data) => http.post(
+  '/api/sequences',
+  data,
+  { camelCase: false },
+);
+
+export const updateSequence = async (id, data) => http.put(
+  `/api/sequences/${id}`,
+  data,
+  { camelCase: false },
+);
+
+export const deleteSequence = async (id) => http.delete(`/api/sequences/${id}`);
+
+// Subscriber status rules.
+export const getStatusRules = async () => http.get(
+  '/api/subscribers/status-rules',
+  { camelCase: false },
+);
+
+export const createStatusRule = async (data) => http.post(
+  '/api/subscribers/status-rules',
+  data,
+  { camelCase: false },
+);
+
+export const updateStatusRule = async (id, data) => http.put(
+  `/api/subscribers/status-rules/${id}`,
+  data,
+  { camelCase: false },
+);
+
+export const deleteStatusRule = async (id) => http.delete(`/api/subscribers/status-rules/${id}`);
+
+export const previewStatusRule = async (data) => http.post(
+  '/api/subscribers/status-rules/preview',
+  data,
+  { camelCase: false },
+);
+
+export const runStatusRule = async (id) => http.post(`/api/subscribers/status-rules/${id}/run`);
+
+// Saved views (filters) of collections. The params are saved as-is.
+export const getSavedViews = async (params) => http.get(
+  '/api/views',
+  { params, camelCase: false },
+);
+
+export const createSavedView = async (data) => http.post(
+  '/api/views',
+  data,
+  { camelCase: false },
+);
+
+export const deleteSavedView = async (id) => http.delete(`/api/views/${id}`);
+
+// Notifications.
+export const getNotifications = async (params) => http.get(
+  '/api/notifications',
+  { params, camelCase: false },
+);
+
+export const getUnreadNotifications = async () => http.get('/api/notifications/unread');
+
+export const markNotificationsRead = async (data) => http.put('/api/notifications/read', data);
+
+export const getNotificationPrefs = async () => http.get(
+  '/api/notifications/prefs',
+  { camelCase: false },
+);
+
+export const updateNotificationPrefs = async (data) => http.put(
+  '/api/notifications/prefs',
+  data,
+  { camelCase: false },
+);
+
+// Typeahead suggestions for pickers. typ = subscribers|lists|templates|tags.
+export const getAutocomplete = async (typ, params) => http.get(
+  `/api/autocomplete/${typ}`,
+  { params, camelCase: false },
+);
+
+// Global search across campaigns, templates, subscribers, and lists.
+export const globalSearch = async (params) => http.get(
+  '/api/search',
+  { params, camelCase: false },
+);
+
 // Subscribers.
 export const getSubscribers = async (params) => http.get(
   '/api/subscribers',
@@ -169,6 +362,17 @@ export const getSubscriber = async (id) => http.get(
   { loading: models.subscribers },
 );
 
+export const getSubscriberColumns = async () => http.get(
+  '/api/subscribers/columns',
+  { camelCase: false },
+);
+
+export const updateSubscriberColumns = async (columns) => http.put(
+  '/api/subscribers/columns',
+  { columns },
+  { camelCase: false },
+);
+
 export const getSubscriberBounces = async (id) => http.get(
   `/api/subscribers/${id}/bounces`,
   { loading: models.bounces },
@@ -189,6 +393,30 @@ export const deleteBounces = async (params) => http.delete(
   { params, loading: models.bounces },
 );
 
+// Captured messages.
+export const getCapturedMessages = async (params) => http.get(
+  '/api/captures',
+  { params, camelCase: false },
+);
+
+export const getCapturedMessage = async (id) => http.get(
+  `/api/captures/${id}`,
+  { camelCase: false },
+);
+
+export const deleteCapturedMessage = async (id) => http.delete(`/api/captures/${id}`);
+
+export const deleteCapturedMessages = async (params) => http.delete(
+  '/api/captures',
+  { params },
+);
+
+// Archive of sent messages.
+export const getSentMessages = async (params) => http.get(
+  '/api/sent-messages',
+  { params, camelCase: false },
+);
+
 export const createSubscriber = (data) => http.post(
   '/api/subscribers',
   data,
@@ -319,6 +547,141 @@ export const testCampaign = async (data) => http.post(
   { loading: models.campaigns },
 );
 
+export const checkCampaignContent = async (id) => http.post(
+  `/api/campaigns/${id}/qa`,
+  {},
+  { camelCase: false },
+);
+
+export const getCampaignSize = async (id) => http.get(
+  `/api/campaigns/${id}/size`,
+  { camelCase: false },
+);
+
+export const getCampaignAudience = async (id) => http.get(
+  `/api/campaigns/${id}/audience/preview`,
+  { camelCase: false },
+);
+
+export const getCampaignBlackouts = async (id) => http.get(
+  `/api/campaigns/${id}/blackouts`,
+  { camelCase: false },
+);
+
+export const checkCampaignSpam = async (id) => http.post(
+  `/api/campaigns/${id}/spamcheck`,
+  {},
+  { camelCase: false },
+);
+
+export const getCampaignGoals = async (id) => http.get(
+  `/api/campaigns/${id}/goals`,
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const updateCampaignGoals = async (id, data) => http.put(
+  `/api/campaigns/${id}/goals`,
+  data,
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const getCampaignActions = async (id) => http.get(
+  `/api/campaigns/${id}/actions`,
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const updateCampaignActions = async (id, data) => http.put(
+  `/api/campaigns/${id}/actions`,
+  data,
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const getCampaignSurveys = async (id) => http.get(
+  `/api/campaigns/${id}/surveys`,
+  { camelCase: false },
+);
+
+export const getCampaignGoalFunnel = async (id) => http.get(
+  `/api/campaigns/${id}/goals/funnel`,
+  { camelCase: false },
+);
+
+export const getCampaignABTest = async (id) => http.get(
+  `/api/campaigns/${id}/abtest`,
+  { camelCase: false },
+);
+
+export const updateCampaignABTest = async (id, data) => http.put(
+  `/api/campaigns/${id}/abtest`,
+  data,
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const pickCampaignABWinner = async (id, variantID) => http.post(
+  `/api/campaigns/${id}/abtest/winner`,
+  { variant_id: variantID },
+  { loading: models.campaigns, camelCase: false },
+);
+
+export const getCampaignComments = async (id) => http.get(
+  `/api/campaigns/${id}/comments`,
+  { camelCase: false },
+);
+
+export const createCampaignComment = async (id, data) => http.post(
+  `/api/campaigns/${id}/comments`,
+  data,
+  { camelCase: false },
+);
+
+export const updateCampaignComment = async (id, commentID, data) => http.put(
+  `/api/campaigns/${id}/comments/${commentID}`,
+  data,
+  { camelCase: false },
+);
+
+export const deleteCampaignComment = async (id, commentID) => http.delete(
+  `/api/campaigns/${id}/comments/${commentID}`,
+);
+
+export const getCampaignSends = async (id, params) => http.get(
+  `/api/campaigns/${id}/sends`,
+  { params, camelCase: false },
+);
+
+export const getCampaignRuns = async (id, params) => http.get(
+  `/api/campaigns/${id}/runs`,
+  { params, camelCase: false },
+);
+
+export const resendCampaign = async (id, data) => http.post(
+  `/api/campaigns/${id}/resend`,
+  data,
+  { loading: models.campaigns },
+);
+
+export const getSends = async (params) => http.get(
+  '/api/sends',
+  { params, camelCase: false },
+);
+
+export const retryCampaignSends = async (id, data) => http.post(
+  `/api/campaigns/${id}/sends/retry`,
+  data,
+  { loading: models.campaigns },
+);
+
+export const getCampaignPreviews = async (id) => http.get(
+  `/api/campaigns/${id}/previews`,
+  { camelCase: false },
+);
+
+export const generateCampaignPreviews = async (id) => http.post(
+  `/api/campaigns/${id}/previews`,
+  {},
+  { loading: models.campaigns, camelCase: false },
+);
+
 export const updateCampaign = async (id, data) => http.put(
   `/api/campaigns/${id}`,
   data,
@@ -360,6 +723,22 @@ export const deleteMedia = (id) => http.delete(
   { loading: models.media },
 );
 
+export const getQuarantinedMedia = async () => http.get(
+  '/api/media/quarantine',
+  { loading: models.media },
+);
+
+export const releaseQuarantinedMedia = (id) => http.put(
+  `/api/media/quarantine/${id}`,
+  {},
+  { loading: models.media },
+);
+
+export const deleteQuarantinedMedia = (id) => http.delete(
+  `/api/media/quarantine/${id}`,
+  { loading: models.media },
+);
+
 // Templates.
 export const createTemplate = async (data) => http.post(
   '/api/templates',
@@ -390,11 +769,109 @@ export const deleteTemplate = async (id) => http.delete(
 );
 
 // Settings.
+export const updateUserPreferences = async (data) => http.put(
+  '/api/preferences',
+  data,
+  { camelCase: false },
+);
+
 export const getServerConfig = async () => http.get(
   '/api/config',
   { loading: models.serverConfig, store: models.serverConfig, camelCase: false },
 );
 
+// Sending domains.
+export const getSendingDomains = async () => http.get(
+  '/api/domains',
+  { camelCase: false },
+);
+
+export const createSendingDomain = async (data) => http.post(
+  '/api/domains',
+  data,
+  { camelCase: false },
+);
+
+export const updateSendingDomain = async (id, data) => http.put(
+  `/api/domains/${id}`,
+  data,
+  { camelCase: false },
+);
+
+export const verifySendingDomain = async (id) => http.post(
+  `/api/domains/${id}/verify`,
+  {},
+  { camelCase: false },
+);
+
+export const deleteSendingDomain = async (id) => http.delete(`/api/domains/${id}`);
+
+// Quotas.
+export const getQuotas = async () => http.get(
+  '/api/quotas',
+  { camelCase: false },
+);
+
+export const getQuotaUsage = async () => http.get(
+  '/api/quotas/usage',
+  { camelCase: false },
+);
+
+export const upsertQuota = async (data) => http.put(
+  '/api/quotas',
+  data,
+  { camelCase: false },
+);
+
+export const deleteQuota = async (id) => http.delete(`/api/quotas/${id}`);
+
+// Login lockouts.
+export const getLockouts = async () => http.get(
+  '/api/auth/lockouts',
+  { camelCase: false },
+);
+
+export const unlockLogins = async (key) => http.delete(
+  '/api/auth/lockouts',
+  { params: key ? { key } : {} },
+);
+
+// API tokens.
+export const getAPITokens = async () => http.get(
+  '/api/auth/tokens',
+  { camelCase: false },
+);
+
+export const createAPIToken = async (data) => http.post(
+  '/api/auth/tokens',
+  data,
+  { camelCase: false },
+);
+
+export const rotateAPIToken = async (id) => http.post(
+  `/api/auth/tokens/${id}/rotate`,
+  {},
+  { camelCase: false },
+);
+
+export const deleteAPIToken = async (id) => http.delete(`/api/auth/tokens/${id}`);
+
+// Audit log.
+export const getAuditLog = async (params) => http.get(
+  '/api/audit',
+  { params, camelCase: false },
+);
+
+// Stripe.
+export const getStripeReconciliation = async () => http.get(
+  '/api/stripe/reconciliation',
+  { camelCase: false },
+);
+
+export const getSendingStatus = async () => http.get('/api/sending');
+
+export const updateSendingStatus = async (data) => http.put('/api/sending', data);
+
 export const getSettings = async () => http.get(
   '/api/settings',
   { loading: models.settings, store: models.settings, camelCase: false },
@@ -406,6 +883,43 @@ export const updateSettings = async (data) => http.put(
   { loading: models.settings },
 );
 
+export const getDNSChecks = async () => http.get(
+  '/api/settings/deliverability',
+  { camelCase: false },
+);
+
+export const runDNSChecks = async () => http.post(
+  '/api/settings/deliverability/check',
+  {},
+  { camelCase: false },
+);
+
+export const getMessengerVolume = async (days) => http.get(
+  '/api/settings/smtp/volume',
+  { params: { days }, camelCase: false },
+);
+
+export const getTLSReports = async (days) => http.get(
+  '/api/settings/deliverability/tls-reports',
+  { params: { days }, camelCase: false },
+);
+
+export const importTLSReports = async (data) => http.post(
+  '/api/settings/deliverability/tls-reports',
+  data,
+);
+
+export const getReputation = async (params) => http.get(
+  '/api/settings/reputation',
+  { params, camelCase: false },
+);
+
+export const syncReputation = async () => http.post(
+  '/api/settings/reputation/sync',
+  {},
+  { camelCase: false },
+);
+
 export const testSMTP = async (data) => http.post(
   '/api/settings/smtp/test',
   data,
@@ -440,3 +954,41 @@ export const deleteGCSubscriptions = async (beforeDate) => http.delete(
   '/api/maintenance/subscriptions/unconfirmed',
   { loading: models.maintenance, params: { before_date: beforeDate } },
 );
+
+export const deleteGCMedia = async (beforeDate) => http.delete(
+  '/api/maintenance/media/dangling',
+  { loading: models.maintenance, params: { before_date: beforeDate } },
+);
+
+export const deleteGCTokens = async () => http.delete(
+  '/api/maintenance/tokens/expired',
+  { loading: models.maintenance, camelCase: false },
+);
+
+export const getIntegrityReport = async () => http.get(
+  '/api/maintenance/integrity',
+  { loading: models.maintenance, camelCase: false },
+);
+
+export const fixIntegrity = async () => http.put(
+  '/api/maintenance/integrity',
+  {},
+  { loading: models.maintenance, camelCase: false },
+);
+
+// Trash.
+export const getTrash = async () => http.get(
+  '/api/trash',
+  { loading: models.maintenance, camelCase: false },
+);
+
+export const restoreTrash = async (typ, id) => http.put(
+  `/api/trash/${typ}/${id}/restore`,
+  {},
+  { loading: models.maintenance },
+);
+
+export const purgeTrash = async () => http.delete(
+  '/api/maintenance/trash',
+  { loading: models.maintenance },
+);
@@ -24,10 +24,13 @@ router.beforeEach((to, from, next) => {
   }
 });
 
+// White-label product name in page titles.
+const productName = () => (store.state.serverConfig.branding || {}).product_name || 'listmonk';
+
 router.afterEach((to) => {
   Vue.nextTick(() => {
     const t = to.meta.title && i18n.te(to.meta.title) ? `${i18n.tc(to.meta.title, 0)} /` : '';
-    document.title = `${t} listmonk`;
+    document.title = `${t} ${productName()}`;
   });
 });
 
@@ -39,12 +42,13 @@ function initConfig(app) {
       i18n.setLocaleMessage(i18n.locale, lang);
 
       Vue.prototype.$utils = new Utils(i18n);
+      Vue.prototype.$utils.setTimezone(data.timezone);
       Vue.prototype.$api = api;
 
       // Set the page title after i18n has loaded.
       const to = router.history.current;
       const t = to.meta.title ? `${i18n.tc(to.meta.title, 0)} /` : '';
-      document.title = `${t} listmonk`;
+      document.title = `${t} ${productName()}`;
 
       if (app) {
         app.$mount('#app');
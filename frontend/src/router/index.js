@@ -53,6 +53,12 @@ const routes = [
     meta: { title: 'globals.terms.bounces', group: 'subscribers' },
     component: () => import('../views/Bounces.vue'),
   },
+  {
+    path: '/subscribers/status-rules',
+    name: 'statusRules',
+    meta: { title: 'statusRules.rules', group: 'subscribers' },
+    component: () => import('../views/StatusRules.vue'),
+  },
   {
     path: '/subscribers/lists/:listID',
     name: 'subscribers_list',
@@ -89,6 +95,24 @@ const routes = [
     meta: { title: 'analytics.title', group: 'campaigns' },
     component: () => import('../views/CampaignAnalytics.vue'),
   },
+  {
+    path: '/campaigns/failed-sends',
+    name: 'failedSends',
+    meta: { title: 'campaigns.failedSends', group: 'campaigns' },
+    component: () => import('../views/FailedSends.vue'),
+  },
+  {
+    path: '/campaigns/header-presets',
+    name: 'headerPresets',
+    meta: { title: 'headerPresets.presets', group: 'campaigns' },
+    component: () => import('../views/HeaderPresets.vue'),
+  },
+  {
+    path: '/campaigns/sequences',
+    name: 'sequences',
+    meta: { title: 'sequences.sequences', group: 'campaigns' },
+    component: () => import('../views/Sequences.vue'),
+  },
   {
     path: '/campaigns/:id',
     name: 'campaign',
@@ -107,12 +131,48 @@ const routes = [
     meta: { title: 'logs.title', group: 'settings' },
     component: () => import('../views/Logs.vue'),
   },
+  {
+    path: '/settings/domains',
+    name: 'domains',
+    meta: { title: 'domains.domains', group: 'settings' },
+    component: () => import('../views/SendingDomains.vue'),
+  },
+  {
+    path: '/settings/captures',
+    name: 'captures',
+    meta: { title: 'captures.messages', group: 'settings' },
+    component: () => import('../views/Captures.vue'),
+  },
+  {
+    path: '/settings/quotas',
+    name: 'quotas',
+    meta: { title: 'quotas.quotas', group: 'settings' },
+    component: () => import('../views/Quotas.vue'),
+  },
+  {
+    path: '/settings/api-tokens',
+    name: 'apiTokens',
+    meta: { title: 'apiTokens.tokens', group: 'settings' },
+    component: () => import('../views/APITokens.vue'),
+  },
+  {
+    path: '/settings/audit',
+    name: 'auditLog',
+    meta: { title: 'audit.log', group: 'settings' },
+    component: () => import('../views/AuditLog.vue'),
+  },
   {
     path: '/settings/maintenance',
     name: 'maintenance',
     meta: { title: 'maintenance.title', group: 'settings' },
     component: () => import('../views/Maintenance.vue'),
   },
+  {
+    path: '/notifications',
+    name: 'notifications',
+    meta: { title: 'notifications.name' },
+    component: () => import('../views/Notifications.vue'),
+  },
 ];
 
 const router = new VueRouter({
@@ -4,10 +4,14 @@ import {
 } from 'buefy';
 import dayjs from 'dayjs';
 import relativeTime from 'dayjs/plugin/relativeTime';
+import timezone from 'dayjs/plugin/timezone';
 import updateLocale from 'dayjs/plugin/updateLocale';
+import utc from 'dayjs/plugin/utc';
 
 dayjs.extend(updateLocale);
 dayjs.extend(relativeTime);
+dayjs.extend(utc);
+dayjs.extend(timezone);
 
 const reEmail = /(.+?)@(.+?)/ig;
 const prefKey = 'listmonk_pref';
@@ -27,6 +31,7 @@ export default class Utils {
   constructor(i18n) {
     this.i18n = i18n;
     this.intlNumFormat = new Intl.NumberFormat();
+    this.timezone = '';
 
     if (i18n) {
       dayjs.updateLocale('en', {
@@ -51,13 +56,24 @@ export default class Utils {
 
   getDate = (d) => dayjs(d);
 
+  // Sets the time zone dates are displayed in. Empty for the browser's time zone.
+  setTimezone = (tz) => {
+    this.timezone = tz || '';
+  };
+
+  // Returns the IANA time zone names supported by the browser.
+  getTimezones = () => {
+    const out = Intl.supportedValuesOf ? Intl.supportedValuesOf('timeZone') : [];
+    return out.includes('UTC') ? out : ['UTC', ...out];
+  };
+
   // Parses an ISO timestamp to a simpler form.
   niceDate = (stamp, showTime) => {
     if (!stamp) {
       return '';
     }
 
-    const d = dayjs(stamp);
+    const d = this.timezone ? dayjs(stamp).tz(this.timezone) : dayjs(stamp);
     const day = this.i18n.t(`globals.days.${d.day() + 1}`);
     const month = this.i18n.t(`globals.months.${d.month() + 1}`);
     let out = d.format(`[${day},] DD [${month}] YYYY`);
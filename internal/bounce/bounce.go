@@ -19,9 +19,9 @@ const (
 )
 
 // Mailbox represents a POP/IMAP mailbox client that can scan messages and pass
-// them to a given channel.
+// them to the given bounce, campaign reply, and opt-in reply channels.
 type Mailbox interface {
-	Scan(limit int, ch chan models.Bounce) error
+	Scan(limit int, ch chan models.Bounce, replies chan models.CampaignReply, optins chan models.OptinReply) error
 }
 
 // Opt represents bounce processing options.
@@ -40,11 +40,15 @@ type Opt struct {
 	}
 
 	RecordBounceCB func(models.Bounce) error
+	RecordReplyCB  func(models.CampaignReply) error
+	RecordOptinCB  func(models.OptinReply) error
 }
 
 // Manager handles e-mail bounces.
 type Manager struct {
 	queue    chan models.Bounce
+	replies  chan models.CampaignReply
+	optins   chan models.OptinReply
 	mailbox  Mailbox
 	SES      *webhooks.SES
 	Sendgrid *webhooks.Sendgrid
@@ -66,6 +70,8 @@ func New(opt Opt, q *Queries, lo *log.Logger) (*Manager, error) {
 		opt:     opt,
 		queries: q,
 		queue:   make(chan models.Bounce, 1000),
+		replies: make(chan models.CampaignReply, 1000),
+		optins:  make(chan models.OptinReply, 1000),
 		log:     lo,
 	}
 
@@ -122,6 +128,32 @@ func (m *Manager) Run() {
 			if err := m.opt.RecordBounceCB(b); err != nil {
 				continue
 			}
+
+		case r, ok := <-m.replies:
+			if !ok {
+				return
+			}
+
+			if r.CreatedAt.IsZero() {
+				r.CreatedAt = time.Now()
+			}
+
+			if m.opt.RecordReplyCB != nil {
+				m.opt.RecordReplyCB(r)
+			}
+
+		case o, ok := <-m.optins:
+			if !ok {
+				return
+			}
+
+			if o.CreatedAt.IsZero() {
+				o.CreatedAt = time.Now()
+			}
+
+			if m.opt.RecordOptinCB != nil {
+				m.opt.RecordOptinCB(o)
+			}
 		}
 	}
 }
@@ -129,7 +161,7 @@ func (m *Manager) Run() {
 // runMailboxScanner runs a blocking loop that scans the mailbox at given intervals.
 func (m *Manager) runMailboxScanner() {
 	for {
-		if err := m.mailbox.Scan(1000, m.queue); err != nil {
+		if err := m.mailbox.Scan(1000, m.queue, m.replies, m.optins); err != nil {
 			m.log.Printf("error scanning bounce mailbox: %v", err)
 		}
 
@@ -3,6 +3,7 @@ package mailbox
 import (
 	"encoding/json"
 	"io"
+	"net/mail"
 	"regexp"
 	"strings"
 	"time"
@@ -37,6 +38,15 @@ var (
 	}
 
 	reHdrReceived = regexp.MustCompile(`(?m)(?:^` + models.EmailHeaderReceived + `:\s+?)(.*)`)
+
+	// Campaign UUID in a plus-tagged reply-to address, eg: replies+$uuid@site.com
+	reReplyTag = regexp.MustCompile(`\+([a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12})@`)
+
+	// Subscriber UUID in a plus-tagged opt-in reply address, eg: confirm+optin-$uuid@site.com
+	reOptinTag = regexp.MustCompile(`\+optin-([a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12})@`)
+
+	// Recipient headers to look for plus-tagged reply-to addresses in.
+	replyRecipientHeaders = []string{models.EmailHeaderDeliveredTo, "X-Original-To", models.EmailHeaderTo}
 )
 
 // NewPOP returns a new instance of the POP mailbox client.
@@ -54,8 +64,12 @@ func NewPOP(opt Opt) *POP {
 
 // Scan scans the mailbox and pushes the downloaded messages into the given channel.
 // The messages that are downloaded are deleted from the server. If limit > 0,
-// all messages on the server are downloaded and deleted.
-func (p *POP) Scan(limit int, ch chan models.Bounce) error {
+// all messages on the server are downloaded and deleted. Replies to campaigns
+// (messages sent to plus-tagged campaign reply-to addresses) are pushed into
+// the replies channel and replies to opt-in confirmation e-mails are pushed into
+// the optins channel. Messages that can't be pushed into a full channel are
+// left on the server to be picked up by the next scan.
+func (p *POP) Scan(limit int, ch chan models.Bounce, replies chan models.CampaignReply, optins chan models.OptinReply) error {
 	c, err := p.client.NewConn()
 	if err != nil {
 		return err
@@ -84,7 +98,8 @@ func (p *POP) Scan(limit int, ch chan models.Bounce) error {
 		count = limit
 	}
 
-	// Download messages.
+	// Download messages. Messages that couldn't be queued are not deleted.
+	keep := map[int]bool{}
 	for id := 1; id <= count; id++ {
 		// Retrieve the raw bytes of the message.
 		b, err := c.RetrRaw(id)
@@ -147,6 +162,45 @@ func (p *POP) Scan(limit int, ch chan models.Bounce) error {
 			date = time.Now()
 		}
 
+		// A message that's sent to a plus-tagged reply-to address and doesn't carry
+		// the campaign header of the original message (as bounces do) is a reply.
+		if hdr[models.EmailHeaderCampaignUUID] == "" {
+			from := m.Header.Get(models.EmailHeaderFrom)
+			if a, err := mail.ParseAddress(from); err == nil {
+				from = a.Address
+			}
+			from = strings.ToLower(strings.TrimSpace(from))
+
+			if uu := findTag(reOptinTag, m.Header); uu != "" {
+				select {
+				case optins <- models.OptinReply{
+					SubscriberUUID: uu,
+					Email:          from,
+					Source:         p.opt.Host,
+					CreatedAt:      date,
+				}:
+				default:
+					keep[id] = true
+				}
+				continue
+			}
+
+			if uu := findTag(reReplyTag, m.Header); uu != "" {
+				select {
+				case replies <- models.CampaignReply{
+					CampaignUUID: uu,
+					Email:        from,
+					MessageID:    strings.TrimSpace(m.Header.Get(models.EmailHeaderMessageId)),
+					Source:       p.opt.Host,
+					CreatedAt:    date,
+				}:
+				default:
+					keep[id] = true
+				}
+				continue
+			}
+		}
+
 		// Additional bounce e-mail metadata.
 		meta, _ := json.Marshal(struct {
 			From        string   `json:"from"`
@@ -172,11 +226,16 @@ func (p *POP) Scan(limit int, ch chan models.Bounce) error {
 			Meta:           meta,
 		}:
 		default:
+			keep[id] = true
 		}
 	}
 
 	// Delete the downloaded messages.
 	for id := 1; id <= count; id++ {
+		if keep[id] {
+			continue
+		}
+
 		if err := c.Dele(id); err != nil {
 			return err
 		}
@@ -184,3 +243,14 @@ func (p *POP) Scan(limit int, ch chan models.Bounce) error {
 
 	return nil
 }
+
+// findTag returns the UUID from a plus-tagged reply-to address matching the
+// given regexp in the recipient headers of a message, if there's one.
+func findTag(re *regexp.Regexp, h message.Header) string {
+	for _, k := range replyRecipientHeaders {
+		if m := re.FindStringSubmatch(strings.ToLower(h.Get(k))); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,335 @@
+// Package cdc implements an optional change data capture (CDC) publisher that
+// reads row changes of tables from a Postgres logical replication slot and
+// publishes them to a callback and a webhook. Unlike triggers, decoding the WAL
+// adds no overhead to writes on hot tables.
+//
+// Changes are decoded with Postgres' built-in test_decoding output plugin and
+// read over a regular connection with pg_logical_slot_peek_changes(). The slot
+// is only advanced after a batch has been published, so delivery is
+// at-least-once. The server should run with wal_level=logical and the DB user
+// should have the REPLICATION attribute (or be a superuser).
+//
+// A slot retains the WAL on the server until it's advanced, which is why the
+// slot is skipped ahead when it lags behind by more than Opt.MaxLag (eg: when
+// the webhook is down for long), and dropped with DropSlot() when CDC is disabled.
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	OpInsert   = "insert"
+	OpUpdate   = "update"
+	OpDelete   = "delete"
+	OpTruncate = "truncate"
+
+	plugin = "test_decoding"
+)
+
+// Opt represents CDC options.
+type Opt struct {
+	// Name of the logical replication slot. It's created if it doesn't exist.
+	Slot string
+
+	// Tables whose changes are published. Unqualified names are in the public schema.
+	Tables []string
+
+	// Interval at which the slot is polled for changes and the maximum
+	// number of changes read in one go. Decoding always returns whole
+	// transactions, so the changes of large transactions are published in
+	// chunks of BatchSize changes or MaxBatchBytes of decoded data.
+	Interval      time.Duration
+	BatchSize     int
+	MaxBatchBytes int
+
+	// Maximum WAL (bytes) that the slot may retain on the server. If the slot
+	// lags behind by more, the pending changes are skipped to release the WAL.
+	// 0 disables the check.
+	MaxLag int64
+
+	// Optional HTTP endpoint to which batches of changes are POSTed as JSON.
+	WebhookURL     string
+	WebhookTimeout time.Duration
+
+	// PublishCB is called for every change after it's been posted to the webhook.
+	PublishCB func(Change)
+}
+
+// Change represents a single row change.
+type Change struct {
+	LSN   string `json:"lsn"`
+	XID   int64  `json:"xid"`
+	Table string `json:"table"`
+	Op    string `json:"op"`
+
+	// Column values of the new row (or of the key of the deleted row).
+	// Values are in their Postgres text representation except for numbers and
+	// booleans. Unchanged TOAST values (large texts) of updated rows are omitted.
+	Data map[string]interface{} `json:"data"`
+
+	// Column values of the old row's key if the key changed in an update.
+	OldKey map[string]interface{} `json:"old_key,omitempty"`
+}
+
+// webhookReq is the payload that's POSTed to the webhook.
+type webhookReq struct {
+	Changes []Change `json:"changes"`
+}
+
+// CDC reads changes from a logical replication slot and publishes them.
+type CDC struct {
+	opt    Opt
+	db     *sqlx.DB
+	tables map[string]bool
+	client *http.Client
+	log    *log.Logger
+}
+
+type slotRow struct {
+	LSN  string `db:"lsn"`
+	XID  int64  `db:"xid"`
+	Data string `db:"data"`
+}
+
+// New returns a new instance of the CDC publisher.
+func New(opt Opt, db *sqlx.DB, lo *log.Logger) (*CDC, error) {
+	if opt.Slot == "" {
+		return nil, errors.New("cdc: replication slot name is empty")
+	}
+	if len(opt.Tables) == 0 {
+		return nil, errors.New("cdc: no tables to capture")
+	}
+	if opt.Interval < time.Second {
+		opt.Interval = time.Second
+	}
+	if opt.BatchSize < 1 {
+		opt.BatchSize = 1000
+	}
+	if opt.MaxBatchBytes < 1 {
+		opt.MaxBatchBytes = 5 * 1024 * 1024
+	}
+	if opt.WebhookTimeout < time.Second {
+		opt.WebhookTimeout = 10 * time.Second
+	}
+
+	tables := make(map[string]bool, len(opt.Tables))
+	for _, t := range opt.Tables {
+		if !strings.Contains(t, ".") {
+			t = "public." + t
+		}
+		tables[t] = true
+	}
+
+	return &CDC{
+		opt:    opt,
+		db:     db,
+		tables: tables,
+		client: &http.Client{Timeout: opt.WebhookTimeout},
+		log:    lo,
+	}, nil
+}
+
+// Run is a blocking function that creates the replication slot if it doesn't
+// exist and polls it for changes at the configured interval.
+func (c *CDC) Run() {
+	if err := c.createSlot(); err != nil {
+		c.log.Printf("error creating CDC replication slot %s: %v", c.opt.Slot, err)
+		return
+	}
+
+	t := time.NewTicker(c.opt.Interval)
+	defer t.Stop()
+
+	for range t.C {
+		if err := c.checkLag(); err != nil {
+			c.log.Printf("error checking CDC replication slot lag: %v", err)
+		}
+
+		// Keep reading until the slot is drained.
+		for {
+			n, err := c.process()
+			if err != nil {
+				c.log.Printf("error processing CDC changes: %v", err)
+				break
+			}
+			if n < c.opt.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// createSlot creates the logical replication slot if it doesn't exist.
+func (c *CDC) createSlot() error {
+	_, err := c.db.Exec(`SELECT pg_create_logical_replication_slot($1, $2)
+		WHERE NOT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)`, c.opt.Slot, plugin)
+	return err
+}
+
+// DropSlot drops the logical replication slot created by CDC if it exists and
+// isn't in use so that it doesn't retain WAL on the server after CDC is disabled.
+func DropSlot(db *sqlx.DB, slot string) (bool, error) {
+	res, err := db.Exec(`SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots
+		WHERE slot_name = $1 AND plugin = $2 AND NOT active`, slot, plugin)
+	if err != nil {
+		return false, err
+	}
+
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// checkLag skips the slot ahead to the current WAL position if the WAL it
+// retains exceeds the max lag. The skipped changes are not published.
+func (c *CDC) checkLag() error {
+	if c.opt.MaxLag <= 0 {
+		return nil
+	}
+
+	var lag int64
+	if err := c.db.Get(&lag, `SELECT COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn), 0)::BIGINT
+		FROM pg_replication_slots WHERE slot_name = $1`, c.opt.Slot); err != nil {
+		return err
+	}
+	if lag <= c.opt.MaxLag {
+		return nil
+	}
+
+	c.log.Printf("CDC replication slot %s is %d bytes behind (max %d). Skipping the pending changes to release the WAL",
+		c.opt.Slot, lag, c.opt.MaxLag)
+	_, err := c.db.Exec(`SELECT pg_replication_slot_advance($1, pg_current_wal_lsn())`, c.opt.Slot)
+	return err
+}
+
+// process reads a batch of changes from the slot, publishes the changes of
+// the captured tables, and advances the slot past them. It returns the number
+// of decoded rows read from the slot.
+func (c *CDC) process() (int, error) {
+	// Decoding always returns whole transactions, so the last row is a COMMIT
+	// whose LSN the slot can safely be advanced to. The rows are streamed rather
+	// than loaded at once as a single large transaction can have any number of rows.
+	rows, err := c.db.Queryx(`SELECT lsn::TEXT AS lsn, xid::TEXT::BIGINT AS xid, data
+		FROM pg_logical_slot_peek_changes($1, NULL, $2, 'include-xids', '0', 'skip-empty-xacts', '1')`,
+		c.opt.Slot, c.opt.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var (
+		n    = 0
+		last string
+		out  []Change
+		size = 0
+	)
+	for rows.Next() {
+		var r slotRow
+		if err := rows.StructScan(&r); err != nil {
+			return 0, err
+		}
+		n++
+		last = r.LSN
+
+		ch, ok, err := parseChange(r.Data)
+		if err != nil {
+			c.log.Printf("error parsing CDC change at %s: %v", r.LSN, err)
+			continue
+		}
+		if !ok || !c.tables[ch.Table] {
+			continue
+		}
+
+		ch.LSN = r.LSN
+		ch.XID = r.XID
+		out = append(out, ch)
+		size += len(r.Data)
+
+		// Publish large transactions in chunks.
+		if len(out) >= c.opt.BatchSize || size >= c.opt.MaxBatchBytes {
+			if err := c.publish(out); err != nil {
+				return 0, err
+			}
+			out = out[:0]
+			size = 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	if err := c.publish(out); err != nil {
+		return 0, err
+	}
+
+	if _, err := c.db.Exec(`SELECT pg_replication_slot_advance($1, $2::PG_LSN)`, c.opt.Slot, last); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// publish posts a batch of changes to the webhook and the callback.
+func (c *CDC) publish(changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if c.opt.WebhookURL != "" {
+		if err := c.postWebhook(changes); err != nil {
+			// The slot isn't advanced and the batch is retried on the next tick.
+			return err
+		}
+	}
+
+	if c.opt.PublishCB != nil {
+		for _, ch := range changes {
+			c.opt.PublishCB(ch)
+		}
+	}
+
+	return nil
+}
+
+// postWebhook POSTs a batch of changes to the webhook.
+func (c *CDC) postWebhook(changes []Change) error {
+	b, err := json.Marshal(webhookReq{Changes: changes})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.opt.WebhookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	r, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Drain and close the body to let the Transport reuse the connection
+		io.Copy(io.Discard, r.Body)
+		r.Body.Close()
+	}()
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf("non-OK response from CDC webhook: %d", r.StatusCode)
+	}
+
+	return nil
+}
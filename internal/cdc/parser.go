@@ -0,0 +1,174 @@
+package cdc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	nullVal      = "null"
+	unchangedVal = "unchanged-toast-datum"
+
+	markerOldKey   = "old-key:"
+	markerNewTuple = "new-tuple:"
+)
+
+// parseChange parses a row change decoded by the test_decoding plugin.
+// eg: table public.subscribers: UPDATE: id[integer]:1 name[text]:'John'
+// Transaction boundaries (BEGIN, COMMIT) return false.
+func parseChange(s string) (Change, bool, error) {
+	if !strings.HasPrefix(s, "table ") {
+		return Change{}, false, nil
+	}
+	s = s[len("table "):]
+
+	i := strings.Index(s, ": ")
+	if i < 0 {
+		return Change{}, false, errors.New("table name not found")
+	}
+	out := Change{Table: strings.ReplaceAll(s[:i], `"`, "")}
+	s = s[i+2:]
+
+	i = strings.Index(s, ":")
+	if i < 0 {
+		return Change{}, false, errors.New("operation not found")
+	}
+	op, s := s[:i], strings.TrimSpace(s[i+1:])
+
+	switch op {
+	case "INSERT":
+		out.Op = OpInsert
+	case "UPDATE":
+		out.Op = OpUpdate
+	case "DELETE":
+		out.Op = OpDelete
+	case "TRUNCATE":
+		out.Op = OpTruncate
+		return out, true, nil
+	default:
+		return Change{}, false, errors.New("unknown operation: " + op)
+	}
+
+	// Deletes of rows without a replica identity have no data.
+	if strings.HasPrefix(s, "(no-tuple data)") {
+		return out, true, nil
+	}
+
+	// Updates that change the key have the old key followed by the new row.
+	if strings.HasPrefix(s, markerOldKey) {
+		i := strings.Index(s, markerNewTuple)
+		if i < 0 {
+			return Change{}, false, errors.New("new tuple not found")
+		}
+
+		old, err := parseColumns(s[len(markerOldKey):i])
+		if err != nil {
+			return Change{}, false, err
+		}
+		out.OldKey = old
+		s = s[i+len(markerNewTuple):]
+	}
+
+	data, err := parseColumns(s)
+	if err != nil {
+		return Change{}, false, err
+	}
+	out.Data = data
+
+	return out, true, nil
+}
+
+// parseColumns parses space separated name[type]:value columns.
+func parseColumns(s string) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			break
+		}
+
+		// Column name.
+		i := strings.IndexByte(s, '[')
+		if i < 0 {
+			return nil, errors.New("column type not found")
+		}
+		name := strings.ReplaceAll(s[:i], `"`, "")
+		s = s[i+1:]
+
+		// Type. Array types have brackets of their own, eg: text[].
+		i = strings.Index(s, "]:")
+		if i < 0 {
+			return nil, errors.New("column value not found: " + name)
+		}
+		typ := s[:i]
+		s = s[i+2:]
+
+		// Quoted value with quotes escaped by doubling them.
+		if strings.HasPrefix(s, "'") {
+			var (
+				b   strings.Builder
+				end = -1
+			)
+			for j := 1; j < len(s); j++ {
+				if s[j] != '\'' {
+					b.WriteByte(s[j])
+					continue
+				}
+				if j+1 < len(s) && s[j+1] == '\'' {
+					b.WriteByte('\'')
+					j++
+					continue
+				}
+				end = j
+				break
+			}
+			if end < 0 {
+				return nil, errors.New("unterminated value: " + name)
+			}
+
+			out[name] = b.String()
+			s = s[end+1:]
+			continue
+		}
+
+		// Unquoted value.
+		val := s
+		if i := strings.IndexByte(s, ' '); i >= 0 {
+			val, s = s[:i], s[i:]
+		} else {
+			s = ""
+		}
+
+		switch val {
+		case nullVal:
+			out[name] = nil
+		case unchangedVal:
+		default:
+			out[name] = convertValue(typ, val)
+		}
+	}
+
+	return out, nil
+}
+
+// convertValue converts unquoted numbers and booleans to their Go types.
+func convertValue(typ, val string) interface{} {
+	switch typ {
+	case "smallint", "integer", "bigint":
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return n
+		}
+	case "real", "double precision":
+		if n, err := strconv.ParseFloat(val, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+
+	return val
+}
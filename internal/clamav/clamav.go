@@ -0,0 +1,161 @@
+// Package clamav implements a minimal clamd client that scans files
+// with the INSTREAM command over a UNIX or TCP socket.
+package clamav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// chunkSize is the size of the chunks streamed to clamd. It should be
+	// less than clamd's StreamMaxLength.
+	chunkSize = 64 * 1024
+)
+
+// Opt represents the clamd client options.
+type Opt struct {
+	// Address is the path to clamd's UNIX socket, eg: /var/run/clamav/clamd.ctl
+	// or its TCP host:port, eg: localhost:3310.
+	Address string
+	Timeout time.Duration
+}
+
+// Result is the result of a scan.
+type Result struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature"`
+}
+
+// Client is the clamd client.
+type Client struct {
+	o Opt
+}
+
+// New returns a new instance of the clamd client.
+func New(o Opt) (*Client, error) {
+	if o.Address == "" {
+		return nil, errors.New("clamav: no address")
+	}
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 30
+	}
+
+	return &Client{o: o}, nil
+}
+
+// Scan streams the given data to clamd and returns the scan result.
+func (c *Client) Scan(r io.Reader) (Result, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, err
+	}
+
+	// Stream the data in chunks, each prefixed by its size as a 4 byte
+	// big endian integer, and terminated by a zero length chunk.
+	var (
+		buf  = make([]byte, chunkSize)
+		size = make([]byte, 4)
+	)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return Result{}, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Result{}, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return Result{}, err
+	}
+
+	resp, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return Result{}, err
+	}
+
+	return parseResponse(resp)
+}
+
+// Ping checks whether clamd is reachable.
+func (c *Client) Ping() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+
+	resp, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimRight(resp, "\x00") != "PONG" {
+		return fmt.Errorf("clamav: unexpected response: %s", resp)
+	}
+
+	return nil
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.o.Address, "/") {
+		network = "unix"
+	}
+
+	conn, err := net.DialTimeout(network, c.o.Address, c.o.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(c.o.Timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// parseResponse parses a clamd INSTREAM response, eg: "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND".
+func parseResponse(resp string) (Result, error) {
+	resp = strings.TrimSpace(strings.TrimRight(resp, "\x00"))
+	resp = strings.TrimPrefix(resp, "stream:")
+	resp = strings.TrimSpace(resp)
+
+	switch {
+	case resp == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(resp, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(resp, " FOUND")}, nil
+	case strings.HasSuffix(resp, " ERROR"):
+		return Result{}, fmt.Errorf("clamav: %s", strings.TrimSuffix(resp, " ERROR"))
+	}
+
+	return Result{}, fmt.Errorf("clamav: unexpected response: %s", resp)
+}
@@ -0,0 +1,128 @@
+// Package contentqa implements a client for external content QA hooks.
+// A hook is an HTTP endpoint that receives a campaign's content and
+// returns a list of warnings (spelling, banned words, claim compliance etc.)
+package contentqa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+
+	// maxRespSize is the maximum size of a hook's response body.
+	maxRespSize = 1024 * 1024
+)
+
+// Opt represents the QA client options.
+type Opt struct {
+	Timeout time.Duration
+}
+
+// List is a campaign list sent to the hook.
+type List struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Content represents the campaign content that's posted to a hook.
+type Content struct {
+	CampaignID  int    `json:"campaign_id"`
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	Subject     string `json:"subject"`
+	FromEmail   string `json:"from_email"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+	AltBody     string `json:"altbody"`
+	Lists       []List `json:"lists"`
+}
+
+// Warning is a single issue reported by a hook.
+type Warning struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Excerpt  string `json:"excerpt"`
+}
+
+type hookResp struct {
+	Warnings []Warning `json:"warnings"`
+}
+
+// QA is the content QA hook client.
+type QA struct {
+	o      Opt
+	client *http.Client
+}
+
+// New returns a new instance of the QA hook client.
+func New(o Opt) *QA {
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 5
+	}
+
+	return &QA{
+		o: o,
+		client: &http.Client{
+			Timeout: o.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost:   10,
+				MaxConnsPerHost:       100,
+				ResponseHeaderTimeout: o.Timeout,
+				IdleConnTimeout:       o.Timeout,
+			},
+		}}
+}
+
+// Check posts the given content to a hook URL and returns the warnings it reports.
+func (q *QA) Check(hookURL string, c Content) ([]Warning, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.client.Post(hookURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hook returned %d", resp.StatusCode)
+	}
+
+	var r hookResp
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error parsing hook response: %v", err)
+	}
+
+	for i, w := range r.Warnings {
+		if w.Severity != SeverityError {
+			r.Warnings[i].Severity = SeverityWarning
+		}
+	}
+
+	return r.Warnings, nil
+}
+
+// HasErrors checks whether any of the given warnings are of the error severity.
+func HasErrors(ws []Warning) bool {
+	for _, w := range ws {
+		if w.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetCampaignVariants returns the A/B test variants of a campaign with the stats of their test sends.
+func (c *Core) GetCampaignVariants(campID int) ([]models.CampaignVariant, error) {
+	out := []models.CampaignVariant{}
+	if err := c.q.GetCampaignVariants.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign variants: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.variants}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// SetCampaignABTest replaces the A/B test settings and variants of a campaign.
+// An A/B test can't be changed once it has started.
+func (c *Core) SetCampaignABTest(campID int, t models.CampaignABTest) ([]models.CampaignVariant, error) {
+	var (
+		names    = make([]string, len(t.Variants))
+		subjects = make([]string, len(t.Variants))
+		bodies   = make([]string, len(t.Variants))
+	)
+	for i, v := range t.Variants {
+		names[i] = v.Name
+		subjects[i] = v.Subject
+		bodies[i] = v.Body
+	}
+
+	if _, err := c.q.SetCampaignVariants.Exec(campID, pq.Array(names), pq.Array(subjects), pq.Array(bodies),
+		t.Percent, t.Metric, t.WaitHours); err != nil {
+		c.log.Printf("error updating campaign variants: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.variants}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaignVariants(campID)
+}
+
+// GetABTestDueCampaigns returns the IDs of running campaigns whose A/B test
+// has been sent and whose wait time to pick a winner is up.
+func (c *Core) GetABTestDueCampaigns() ([]int, error) {
+	var out []int
+	if err := c.q.GetABTestDueCampaigns.Select(&out); err != nil {
+		c.log.Printf("error fetching A/B test campaigns: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.campaigns}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// PickABTestWinner picks the variant with the best open or click rate (by the
+// campaign's A/B test metric) as the winner of a campaign's A/B test. Ties go to
+// the first variant.
+func (c *Core) PickABTestWinner(campID int) (models.CampaignVariant, error) {
+	camp, err := c.GetCampaign(campID, "", "")
+	if err != nil {
+		return models.CampaignVariant{}, err
+	}
+
+	vars, err := c.GetCampaignVariants(campID)
+	if err != nil {
+		return models.CampaignVariant{}, err
+	}
+	if len(vars) == 0 {
+		return models.CampaignVariant{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{campaigns.variants}"))
+	}
+
+	var (
+		win  = vars[0]
+		best = -1.0
+	)
+	for _, v := range vars {
+		n := v.Opens
+		if camp.ABMetric == models.CampaignABMetricClicks {
+			n = v.Clicks
+		}
+
+		rate := 0.0
+		if v.Sent > 0 {
+			rate = float64(n) / float64(v.Sent)
+		}
+		if rate > best {
+			win, best = v, rate
+		}
+	}
+
+	return win, c.SetCampaignABWinner(campID, win.ID)
+}
+
+// SetCampaignABWinner marks a variant as the winner of a campaign's A/B test that
+// has been sent and resumes sending the winner to the rest of the subscribers.
+func (c *Core) SetCampaignABWinner(campID, variantID int) error {
+	res, err := c.q.SetCampaignABWinner.Exec(campID, variantID)
+	if err != nil {
+		c.log.Printf("error updating campaign A/B test winner: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.variants}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("campaigns.abTestNotWaiting"))
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetAPITokens returns all API tokens (without the tokens).
+func (c *Core) GetAPITokens() ([]models.APIToken, error) {
+	out := []models.APIToken{}
+	if err := c.q.GetAPITokens.Select(&out); err != nil {
+		c.log.Printf("error fetching API tokens: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{apiTokens.tokens}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateAPIToken creates an API token with the hash of its token.
+func (c *Core) CreateAPIToken(t models.APIToken, tokenHash string) (models.APIToken, error) {
+	var out models.APIToken
+	if err := c.q.CreateAPIToken.Get(&out, t.Name, t.Username, tokenHash, t.Scopes, t.ExpiresAt, t.Role); err != nil {
+		c.log.Printf("error creating API token: %v", err)
+		return models.APIToken{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{apiTokens.token}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// DeleteAPIToken deletes (revokes) an API token.
+func (c *Core) DeleteAPIToken(id int) error {
+	res, err := c.q.DeleteAPIToken.Exec(id)
+	if err != nil {
+		c.log.Printf("error deleting API token: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{apiTokens.token}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{apiTokens.token}"))
+	}
+
+	return nil
+}
+
+// RotateAPIToken replaces the hash of an API token, keeping its user, scopes,
+// and expiry.
+func (c *Core) RotateAPIToken(id int, tokenHash string) (models.APIToken, error) {
+	var out models.APIToken
+	if err := c.q.RotateAPIToken.Get(&out, id, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("globals.messages.notFound", "name", "{apiTokens.token}"))
+		}
+
+		c.log.Printf("error rotating API token: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{apiTokens.token}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// UseAPIToken returns a user's unexpired API token by its hash and records its
+// use. If there's no such token, the returned bool is false.
+func (c *Core) UseAPIToken(username, tokenHash string) (models.APIToken, bool, error) {
+	var out models.APIToken
+	if err := c.q.UseAPIToken.Get(&out, username, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return out, false, nil
+		}
+
+		c.log.Printf("error fetching API token: %v", err)
+		return out, false, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{apiTokens.token}", "error", pqErrMsg(err)))
+	}
+
+	return out, true, nil
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// RecordAudit inserts an entry into the audit log.
+func (c *Core) RecordAudit(e models.AuditEntry) error {
+	if _, err := c.q.InsertAuditLog.Exec(e.Actor, e.Action, e.EntityType, e.EntityID,
+		nullJSON(e.Before), nullJSON(e.After), e.Changes, e.IP); err != nil {
+		c.log.Printf("error recording audit log (%s): %v", e.Action, err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{audit.log}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// QueryAuditLog returns audit log entries, newest first, optionally filtered
+// by actor, action, and entity.
+func (c *Core) QueryAuditLog(actor, action, entityType string, entityID, offset, limit int) ([]models.AuditEntry, int, error) {
+	out := []models.AuditEntry{}
+	if err := c.q.QueryAuditLog.Select(&out, actor, action, entityType, entityID, offset, limit); err != nil {
+		c.log.Printf("error fetching audit log: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{audit.log}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// nullJSON returns nil for an empty JSON blob so that it's stored as NULL, and
+// the blob as a string otherwise as []byte is sent to JSONB in the binary format.
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
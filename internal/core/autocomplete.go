@@ -0,0 +1,52 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// Autocomplete returns ranked typeahead suggestions of the given type (subscribers,
+// lists, templates, tags, campaigns) that match the search string. tplType optionally
+// filters templates by type.
+func (c *Core) Autocomplete(typ, searchStr, tplType string, limit int) ([]models.AutocompleteItem, error) {
+	var (
+		out  = []models.AutocompleteItem{}
+		args = []interface{}{searchStr, likeEscaper.Replace(searchStr), limit}
+		name string
+		err  error
+	)
+
+	switch typ {
+	case models.AutocompleteSubscribers:
+		// Prefix searches with an empty string would scan all the subscribers.
+		if searchStr == "" {
+			return out, nil
+		}
+		name = "{globals.terms.subscribers}"
+		err = c.q.AutocompleteSubscribers.Select(&out, args...)
+	case models.AutocompleteLists:
+		name = "{globals.terms.lists}"
+		err = c.q.AutocompleteLists.Select(&out, args...)
+	case models.AutocompleteTemplates:
+		name = "{globals.terms.templates}"
+		err = c.q.AutocompleteTemplates.Select(&out, append(args, tplType)...)
+	case models.AutocompleteTags:
+		name = "{globals.terms.tags}"
+		err = c.q.AutocompleteTags.Select(&out, args...)
+	case models.AutocompleteCampaigns:
+		name = "{globals.terms.campaigns}"
+		err = c.q.AutocompleteCampaigns.Select(&out, args...)
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("globals.messages.invalidFields", "name", "type"))
+	}
+
+	if err != nil {
+		c.log.Printf("error fetching %s autocomplete: %v", typ, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", name, "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
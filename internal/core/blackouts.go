@@ -0,0 +1,64 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetListBlackouts returns the blackouts of a list (or all lists if listID is 0).
+// Blackouts that have ended are only returned if all is true.
+func (c *Core) GetListBlackouts(listID int, all bool) ([]models.ListBlackout, error) {
+	out := []models.ListBlackout{}
+	if err := c.q.GetListBlackouts.Select(&out, listID, all); err != nil {
+		c.log.Printf("error fetching list blackouts: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{lists.blackouts}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateListBlackout creates a blackout on a list and returns its ID.
+func (c *Core) CreateListBlackout(b models.ListBlackout) (int, error) {
+	var id int
+	if err := c.q.CreateListBlackout.Get(&id, b.ListID, strings.TrimSpace(b.Name), b.StartsAt, b.EndsAt); err != nil {
+		c.log.Printf("error creating list blackout: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{lists.blackout}", "error", pqErrMsg(err)))
+	}
+
+	return id, nil
+}
+
+// DeleteListBlackout deletes a blackout of a list.
+func (c *Core) DeleteListBlackout(id, listID int) error {
+	res, err := c.q.DeleteListBlackout.Exec(id, listID)
+	if err != nil {
+		c.log.Printf("error deleting list blackout: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{lists.blackout}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{lists.blackout}"))
+	}
+
+	return nil
+}
+
+// GetCampaignBlackouts returns the blackouts of a campaign's lists that its send time
+// (or the current time, if it isn't scheduled) falls in.
+func (c *Core) GetCampaignBlackouts(campID int) ([]models.ListBlackout, error) {
+	out := []models.ListBlackout{}
+	if err := c.q.GetCampaignBlackouts.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign blackouts: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{lists.blackouts}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
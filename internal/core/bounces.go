@@ -1,7 +1,6 @@
 package core
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -101,3 +100,13 @@ func (c *Core) DeleteBounces(ids []int) error {
 	}
 	return nil
 }
+
+// RecordCampaignReply records a reply to a campaign.
+func (c *Core) RecordCampaignReply(r models.CampaignReply) error {
+	if _, err := c.q.RecordCampaignReply.Exec(r.CampaignUUID, r.Email, r.MessageID, r.CreatedAt); err != nil {
+		c.log.Printf("error recording campaign reply: %v", err)
+		return err
+	}
+
+	return nil
+}
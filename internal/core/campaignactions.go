@@ -0,0 +1,84 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetCampaignActions returns the post-campaign actions of a campaign.
+func (c *Core) GetCampaignActions(campID int) ([]models.CampaignAction, error) {
+	out := []models.CampaignAction{}
+	if err := c.q.GetCampaignActions.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign actions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.actions}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// SetCampaignActions replaces the post-campaign actions of a campaign with the given set.
+// Actions with an ID are updated in place and retain their run status.
+func (c *Core) SetCampaignActions(campID int, acts []models.CampaignAction) ([]models.CampaignAction, error) {
+	var (
+		ids        = make([]int, len(acts))
+		conditions = make([]string, len(acts))
+		urls       = make([]string, len(acts))
+		actions    = make([]string, len(acts))
+		tags       = make([]string, len(acts))
+		listIDs    = make([]int, len(acts))
+		delays     = make([]int, len(acts))
+	)
+	for i, a := range acts {
+		ids[i] = a.ID
+		conditions[i] = a.Condition
+		urls[i] = a.URL
+		actions[i] = a.Action
+		tags[i] = a.Tag
+		listIDs[i] = int(a.ListID.Int)
+		delays[i] = a.DelayHours
+	}
+
+	if _, err := c.q.SetCampaignActions.Exec(campID, pq.Array(ids), pq.Array(conditions), pq.Array(urls),
+		pq.Array(actions), pq.Array(tags), pq.Array(listIDs), pq.Array(delays)); err != nil {
+		c.log.Printf("error updating campaign actions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.actions}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaignActions(campID)
+}
+
+// GetDueCampaignActions returns the post-campaign actions of finished campaigns
+// that haven't run and whose delay is up.
+func (c *Core) GetDueCampaignActions() ([]models.CampaignAction, error) {
+	out := []models.CampaignAction{}
+	if err := c.q.GetDueCampaignActions.Select(&out); err != nil {
+		c.log.Printf("error fetching due campaign actions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.actions}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// RunCampaignAction runs a post-campaign action and returns the number of
+// subscribers it was applied to. Actions that have already run are skipped.
+func (c *Core) RunCampaignAction(id int) (int, error) {
+	var n int
+	if err := c.q.RunCampaignAction.Get(&n, id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+
+		c.log.Printf("error running campaign action %d: %v", id, err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
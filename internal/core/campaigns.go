@@ -47,6 +47,8 @@ func (c *Core) QueryCampaigns(searchStr string, statuses, tags []string, orderBy
 		if out[i].Tags == nil {
 			out[i].Tags = []string{}
 		}
+
+		setSendAtZone(&out[i])
 	}
 
 	// Lazy load stats.
@@ -112,6 +114,8 @@ func (c *Core) getCampaign(id int, uuid, archiveSlug string, tplType string) (mo
 		if out[i].Tags == nil {
 			out[i].Tags = []string{}
 		}
+
+		setSendAtZone(&out[i])
 	}
 
 	// Lazy load stats.
@@ -141,6 +145,24 @@ func (c *Core) GetCampaignForPreview(id, tplID int) (models.Campaign, error) {
 	return out, nil
 }
 
+// GetCampaignAudience returns the number of subscribers a campaign would be sent
+// to right now and a sample of them.
+func (c *Core) GetCampaignAudience(id, limit int) ([]models.Subscriber, int, error) {
+	out := []models.Subscriber{}
+	if err := c.q.GetCampaignAudience.Select(&out, id, limit); err != nil {
+		c.log.Printf("error fetching campaign audience: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
 // GetArchivedCampaigns retrieves campaigns with a template body.
 func (c *Core) GetArchivedCampaigns(offset, limit int) (models.Campaigns, int, error) {
 	var out models.Campaigns
@@ -189,6 +211,23 @@ func (c *Core) CreateCampaign(o models.Campaign, listIDs []int, mediaIDs []int)
 		o.ArchiveTemplateID,
 		o.ArchiveMeta,
 		pq.Array(mediaIDs),
+		o.ContentURL,
+		o.ReplyTo,
+		o.ReplyTracking,
+		o.ReturnPath,
+		o.HeaderPresetID,
+		o.SendAtTimezone,
+		o.DependsOn,
+		o.DependsDelayMins,
+		o.Recurrence,
+		o.FeedURL,
+		o.STOWindowHours,
+		o.SendAtLocal,
+		o.SendRate,
+		o.SendWindows,
+		o.ExcludeListIDs,
+		o.UTMEnabled,
+		o.UTMCampaign,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return models.Campaign{}, echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("campaigns.noSubs"))
@@ -208,8 +247,10 @@ func (c *Core) CreateCampaign(o models.Campaign, listIDs []int, mediaIDs []int)
 }
 
 // UpdateCampaign updates a campaign.
+// The update only goes through if o.Version matches the campaign's current version.
 func (c *Core) UpdateCampaign(id int, o models.Campaign, listIDs []int, mediaIDs []int, sendLater bool) (models.Campaign, error) {
-	_, err := c.q.UpdateCampaign.Exec(id,
+	var newID int
+	err := c.q.UpdateCampaign.Get(&newID, id,
 		o.Name,
 		o.Subject,
 		o.FromEmail,
@@ -227,8 +268,31 @@ func (c *Core) UpdateCampaign(id int, o models.Campaign, listIDs []int, mediaIDs
 		o.ArchiveSlug,
 		o.ArchiveTemplateID,
 		o.ArchiveMeta,
-		pq.Array(mediaIDs))
+		pq.Array(mediaIDs),
+		o.ContentURL,
+		o.ReplyTo,
+		o.ReplyTracking,
+		o.ReturnPath,
+		o.Version,
+		o.HeaderPresetID,
+		o.SendAtTimezone,
+		o.DependsOn,
+		o.DependsDelayMins,
+		o.Recurrence,
+		o.FeedURL,
+		o.STOWindowHours,
+		o.SendAtLocal,
+		o.SendRate,
+		o.SendWindows,
+		o.ExcludeListIDs,
+		o.UTMEnabled,
+		o.UTMCampaign)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Campaign{}, echo.NewHTTPError(http.StatusConflict,
+				c.i18n.Ts("globals.messages.versionConflict", "name", "{globals.terms.campaign}"))
+		}
+
 		c.log.Printf("error updating campaign: %v", err)
 		return models.Campaign{}, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.campaign}", "error", pqErrMsg(err)))
@@ -259,7 +323,8 @@ func (c *Core) UpdateCampaignStatus(id int, status string) (models.Campaign, err
 		if cm.Status != models.CampaignStatusDraft {
 			errMsg = c.i18n.T("campaigns.onlyDraftAsScheduled")
 		}
-		if !cm.SendAt.Valid {
+		// Campaigns that depend on another campaign are started by it.
+		if !cm.SendAt.Valid && !cm.DependsOn.Valid {
 			errMsg = c.i18n.T("campaigns.needsSendAt")
 		}
 
@@ -267,6 +332,10 @@ func (c *Core) UpdateCampaignStatus(id int, status string) (models.Campaign, err
 		if cm.Status != models.CampaignStatusPaused && cm.Status != models.CampaignStatusDraft {
 			errMsg = c.i18n.T("campaigns.onlyPausedDraft")
 		}
+		// Recurring campaigns are only scheduled. Their runs are started.
+		if cm.Recurrence != "" {
+			errMsg = c.i18n.T("campaigns.recurringCantStart")
+		}
 	case models.CampaignStatusPaused:
 		if cm.Status != models.CampaignStatusRunning {
 			errMsg = c.i18n.T("campaigns.onlyActivePause")
@@ -328,6 +397,19 @@ func (c *Core) DeleteCampaign(id int) error {
 	return nil
 }
 
+// GetMessengerVolume returns the sending volume of each messenger for the
+// campaigns started in the last n days.
+func (c *Core) GetMessengerVolume(days int) ([]models.MessengerVolume, error) {
+	out := []models.MessengerVolume{}
+	if err := c.q.GetMessengerVolume.Select(&out, days); err != nil {
+		c.log.Printf("error fetching messenger volume: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.campaign}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
 // GetRunningCampaignStats returns the progress stats of running campaigns.
 func (c *Core) GetRunningCampaignStats() ([]models.CampaignStats, error) {
 	out := []models.CampaignStats{}
@@ -365,12 +447,17 @@ func (c *Core) GetCampaignAnalyticsCounts(campIDs []int, typ, fromDate, toDate s
 	}
 
 	out := []models.CampaignAnalyticsCount{}
-	if err := stmt.Select(&out, pq.Array(campIDs), fromDate, toDate); err != nil {
+	if err := stmt.Select(&out, pq.Array(campIDs), fromDate, toDate, c.consts.ReportingTimezone.String()); err != nil {
 		c.log.Printf("error fetching campaign %s: %v", typ, err)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.analytics}", "error", pqErrMsg(err)))
 	}
 
+	// Timestamps are the start of the hour or day in the reporting time zone.
+	for i := range out {
+		out[i].Timestamp = out[i].Timestamp.In(c.consts.ReportingTimezone)
+	}
+
 	return out, nil
 }
 
@@ -400,19 +487,38 @@ func (c *Core) RegisterCampaignView(campUUID, subUUID string) error {
 	return nil
 }
 
-// RegisterCampaignLinkClick registers a subscriber's link click on a campaign.
-func (c *Core) RegisterCampaignLinkClick(linkUUID, campUUID, subUUID string) (string, error) {
-	var url string
-	if err := c.q.RegisterLinkClick.Get(&url, linkUUID, campUUID, subUUID); err != nil {
+// RegisterCampaignLinkClick registers a subscriber's link click on a campaign and
+// returns the link's URL with the campaign's goals and UTM tagging.
+func (c *Core) RegisterCampaignLinkClick(linkUUID, campUUID, subUUID string) (models.LinkRedirect, error) {
+	var out models.LinkRedirect
+	if err := c.q.RegisterLinkClick.Get(&out, linkUUID, campUUID, subUUID); err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Column == "link_id" {
-			return "", echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("public.invalidLink"))
+			return out, echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("public.invalidLink"))
 		}
 
 		c.log.Printf("error registering link click: %s", err)
-		return "", echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("public.errorProcessingRequest"))
+		return out, echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("public.errorProcessingRequest"))
 	}
 
-	return url, nil
+	return out, nil
+}
+
+// GetLinkSlug returns the link and campaign UUIDs of a short link slug.
+func (c *Core) GetLinkSlug(slug string) (string, string, error) {
+	var out struct {
+		LinkUUID string `db:"link_uuid"`
+		CampUUID string `db:"campaign_uuid"`
+	}
+	if err := c.q.GetLinkSlug.Get(&out, slug); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", echo.NewHTTPError(http.StatusNotFound, c.i18n.Ts("public.invalidLink"))
+		}
+
+		c.log.Printf("error fetching link slug: %s", err)
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("public.errorProcessingRequest"))
+	}
+
+	return out.LinkUUID, out.CampUUID, nil
 }
 
 // DeleteCampaignViews deletes campaign views older than a given date.
@@ -434,3 +540,14 @@ func (c *Core) DeleteCampaignLinkClicks(before time.Time) error {
 
 	return nil
 }
+
+// setSendAtZone sets a campaign's send_at in the time zone it was scheduled in.
+func setSendAtZone(c *models.Campaign) {
+	if !c.SendAt.Valid || c.SendAtTimezone == "" {
+		return
+	}
+
+	if loc, err := time.LoadLocation(c.SendAtTimezone); err == nil {
+		c.SendAt.Time = c.SendAt.Time.In(loc)
+	}
+}
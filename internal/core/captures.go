@@ -0,0 +1,71 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// InsertCapturedMessage stores a message captured by the capture messenger.
+func (c *Core) InsertCapturedMessage(m models.CapturedMessage) error {
+	if _, err := c.q.InsertCapturedMessage.Exec(m.Messenger, m.CampaignID, m.SubscriberID, m.FromEmail,
+		m.To, m.Subject, m.ContentType, m.Body, m.AltBody, m.Headers, m.Raw); err != nil {
+		c.log.Printf("error inserting captured message: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// QueryCapturedMessages returns captured messages without their bodies, optionally
+// filtered by a campaign and a subject or recipient substring.
+func (c *Core) QueryCapturedMessages(campID int, query string, offset, limit int) ([]models.CapturedMessage, int, error) {
+	out := []models.CapturedMessage{}
+	if err := c.q.QueryCapturedMessages.Select(&out, campID, query, offset, limit); err != nil {
+		c.log.Printf("error fetching captured messages: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{captures.messages}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// GetCapturedMessage returns a captured message.
+func (c *Core) GetCapturedMessage(id int) (models.CapturedMessage, error) {
+	var out models.CapturedMessage
+	if err := c.q.GetCapturedMessage.Get(&out, id); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("globals.messages.notFound", "name", "{captures.message}"))
+		}
+
+		c.log.Printf("error fetching captured message: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{captures.message}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// DeleteCapturedMessages deletes the given captured messages, or all of them if no IDs are given.
+func (c *Core) DeleteCapturedMessages(ids []int) error {
+	if ids == nil {
+		ids = []int{}
+	}
+
+	if _, err := c.q.DeleteCapturedMessages.Exec(pq.Array(ids)); err != nil {
+		c.log.Printf("error deleting captured messages: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{captures.messages}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetCampaignComments returns the comments of a campaign ordered by thread.
+func (c *Core) GetCampaignComments(campID int) ([]models.CampaignComment, error) {
+	out := []models.CampaignComment{}
+	if err := c.q.GetCampaignComments.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign comments: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{comments.comments}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetCampaignComment returns a comment of a campaign.
+func (c *Core) GetCampaignComment(id, campID int) (models.CampaignComment, error) {
+	var out models.CampaignComment
+	if err := c.q.GetCampaignComment.Get(&out, id, campID); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusNotFound,
+				c.i18n.Ts("globals.messages.notFound", "name", "{comments.comment}"))
+		}
+
+		c.log.Printf("error fetching campaign comment: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{comments.comment}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// InsertCampaignComment adds a comment to a campaign. If parentID is set, the
+// comment is added as a reply to the parent's thread.
+func (c *Core) InsertCampaignComment(campID int, parentID *int, author, body string, mentions []string) (models.CampaignComment, error) {
+	var id int
+	if err := c.q.InsertCampaignComment.Get(&id, campID, parentID, author, body, pq.StringArray(mentions)); err != nil {
+		if err == sql.ErrNoRows {
+			return models.CampaignComment{}, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("globals.messages.notFound", "name", "{comments.comment}"))
+		}
+
+		c.log.Printf("error inserting campaign comment: %v", err)
+		return models.CampaignComment{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{comments.comment}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaignComment(id, campID)
+}
+
+// UpdateCampaignComment updates a comment. Only the comment's author can update it.
+func (c *Core) UpdateCampaignComment(id, campID int, author, body string, mentions []string) (models.CampaignComment, error) {
+	res, err := c.q.UpdateCampaignComment.Exec(id, campID, author, body, pq.StringArray(mentions))
+	if err != nil {
+		c.log.Printf("error updating campaign comment: %v", err)
+		return models.CampaignComment{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{comments.comment}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.CampaignComment{}, echo.NewHTTPError(http.StatusNotFound,
+			c.i18n.Ts("globals.messages.notFound", "name", "{comments.comment}"))
+	}
+
+	return c.GetCampaignComment(id, campID)
+}
+
+// DeleteCampaignComment deletes a comment and its replies. Only the comment's author can delete it.
+func (c *Core) DeleteCampaignComment(id, campID int, author string) error {
+	res, err := c.q.DeleteCampaignComment.Exec(id, campID, author)
+	if err != nil {
+		c.log.Printf("error deleting campaign comment: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{comments.comment}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusNotFound,
+			c.i18n.Ts("globals.messages.notFound", "name", "{comments.comment}"))
+	}
+
+	return nil
+}
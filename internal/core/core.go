@@ -10,6 +10,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/knadh/listmonk/internal/i18n"
@@ -45,6 +46,10 @@ type Constants struct {
 		Action string
 	}
 	CacheSlowQueries bool
+
+	// Time zone that stats are bucketed into days, weeks etc. in, and that
+	// campaign schedules without a time zone default to.
+	ReportingTimezone *time.Location
 }
 
 // Hooks contains external function hooks that are required by the core package.
@@ -67,6 +72,9 @@ var (
 	campQuerySortFields = []string{"name", "status", "created_at", "updated_at"}
 	subQuerySortFields  = []string{"email", "status", "name", "created_at", "updated_at"}
 	listQuerySortFields = []string{"name", "status", "created_at", "updated_at", "subscriber_count"}
+
+	// Escapes the wildcards in LIKE patterns.
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 )
 
 // New returns a new instance of the core.
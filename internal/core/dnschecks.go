@@ -0,0 +1,70 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetDNSChecks returns the latest DNS deliverability check results.
+func (c *Core) GetDNSChecks() ([]models.DNSCheck, error) {
+	out := []models.DNSCheck{}
+	if err := c.q.GetDNSChecks.Select(&out); err != nil {
+		c.log.Printf("error fetching DNS checks: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{settings.deliverability.name}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// SetDNSChecks replaces the stored DNS deliverability check results.
+func (c *Core) SetDNSChecks(checks []models.DNSCheck) ([]models.DNSCheck, error) {
+	var (
+		domains  = make([]string, len(checks))
+		statuses = make([]string, len(checks))
+		results  = make([]string, len(checks))
+	)
+	for i, ch := range checks {
+		domains[i] = ch.Domain
+		statuses[i] = ch.Status
+		results[i] = string(ch.Results)
+	}
+
+	if _, err := c.q.SetDNSChecks.Exec(pq.Array(domains), pq.Array(statuses), pq.Array(results)); err != nil {
+		c.log.Printf("error saving DNS checks: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.deliverability.name}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetDNSChecks()
+}
+
+// InsertTLSReport saves a policy domain's results from a TLS report. It returns
+// false if the report was already imported.
+func (c *Core) InsertTLSReport(r models.TLSReport) (bool, error) {
+	res, err := c.q.InsertTLSReport.Exec(r.ReportID, r.Organization, r.PolicyDomain, r.PolicyType,
+		r.StartDate, r.EndDate, r.SuccessCount, r.FailureCount, string(r.Failures))
+	if err != nil {
+		c.log.Printf("error inserting TLS report: %v", err)
+		return false, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{settings.deliverability.tlsReports}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// GetTLSReports returns the TLS reports whose period ended within the last n days.
+func (c *Core) GetTLSReports(days int) ([]models.TLSReport, error) {
+	out := []models.TLSReport{}
+	if err := c.q.GetTLSReports.Select(&out, days); err != nil {
+		c.log.Printf("error fetching TLS reports: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{settings.deliverability.tlsReports}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
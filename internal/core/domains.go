@@ -0,0 +1,94 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetSendingDomains returns all the domains in the sending domain registry.
+func (c *Core) GetSendingDomains() ([]models.SendingDomain, error) {
+	out := []models.SendingDomain{}
+	if err := c.q.GetSendingDomains.Select(&out); err != nil {
+		c.log.Printf("error fetching sending domains: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{domains.domains}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetSendingDomain returns a sending domain.
+func (c *Core) GetSendingDomain(id int) (models.SendingDomain, error) {
+	var out models.SendingDomain
+	if err := c.q.GetSendingDomain.Get(&out, id); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusNotFound,
+				c.i18n.Ts("globals.messages.notFound", "name", "{domains.domain}"))
+		}
+
+		c.log.Printf("error fetching sending domain: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{domains.domain}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateSendingDomain adds a domain to the sending domain registry.
+func (c *Core) CreateSendingDomain(d models.SendingDomain) (models.SendingDomain, error) {
+	var newID int
+	if err := c.q.CreateSendingDomain.Get(&newID, d.Domain, pq.StringArray(d.Addresses), d.Token); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.SendingDomain{}, echo.NewHTTPError(http.StatusConflict, c.i18n.T("domains.domainExists"))
+		}
+
+		c.log.Printf("error creating sending domain: %v", err)
+		return models.SendingDomain{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{domains.domain}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetSendingDomain(newID)
+}
+
+// UpdateSendingDomain updates the allowed from addresses of a sending domain.
+func (c *Core) UpdateSendingDomain(id int, addresses []string) (models.SendingDomain, error) {
+	res, err := c.q.UpdateSendingDomain.Exec(id, pq.StringArray(addresses))
+	if err != nil {
+		c.log.Printf("error updating sending domain: %v", err)
+		return models.SendingDomain{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{domains.domain}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.SendingDomain{}, echo.NewHTTPError(http.StatusNotFound,
+			c.i18n.Ts("globals.messages.notFound", "name", "{domains.domain}"))
+	}
+
+	return c.GetSendingDomain(id)
+}
+
+// SetSendingDomainVerified sets the verification status of a sending domain.
+func (c *Core) SetSendingDomainVerified(id int, verified bool) (models.SendingDomain, error) {
+	if _, err := c.q.VerifySendingDomain.Exec(id, verified); err != nil {
+		c.log.Printf("error updating sending domain verification: %v", err)
+		return models.SendingDomain{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{domains.domain}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetSendingDomain(id)
+}
+
+// DeleteSendingDomain removes a domain from the sending domain registry.
+func (c *Core) DeleteSendingDomain(id int) error {
+	if _, err := c.q.DeleteSendingDomain.Exec(id); err != nil {
+		c.log.Printf("error deleting sending domain: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{domains.domain}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
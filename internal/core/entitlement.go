@@ -0,0 +1,43 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetListCapCounts returns a map of the IDs of the given lists that have a subscriber
+// cap to their number of subscriptions, not counting the subscriber with the given e-mail.
+func (c *Core) GetListCapCounts(listIDs []int, email string) (map[int]int, error) {
+	var res []struct {
+		ID    int `db:"id"`
+		Count int `db:"count"`
+	}
+	if err := c.q.GetListCapCounts.Select(&res, pq.Array(listIDs), email); err != nil {
+		c.log.Printf("error fetching list subscriber counts: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
+	}
+
+	out := make(map[int]int, len(res))
+	for _, r := range res {
+		out[r.ID] = r.Count
+	}
+
+	return out, nil
+}
+
+// GetEntitledSubscribers returns a page of subscribers subscribed to a list
+// after the given subscriber ID, ordered by ID.
+func (c *Core) GetEntitledSubscribers(listID, afterID, limit int) ([]models.Subscriber, error) {
+	out := []models.Subscriber{}
+	if err := c.q.GetEntitledSubscribers.Select(&out, listID, afterID, limit); err != nil {
+		c.log.Printf("error fetching list subscribers for entitlement: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetCampaignGoals returns the ordered goals of a campaign.
+func (c *Core) GetCampaignGoals(campID int) ([]models.CampaignGoal, error) {
+	out := []models.CampaignGoal{}
+	if err := c.q.GetCampaignGoals.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign goals: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.goals}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// SetCampaignGoals replaces the goals of a campaign with the given ordered set.
+// Goals with an ID are updated in place and retain their recorded events.
+func (c *Core) SetCampaignGoals(campID int, goals []models.CampaignGoal) ([]models.CampaignGoal, error) {
+	var (
+		ids   = make([]int, len(goals))
+		names = make([]string, len(goals))
+		types = make([]string, len(goals))
+		urls  = make([]string, len(goals))
+		keys  = make([]string, len(goals))
+	)
+	for i, g := range goals {
+		ids[i] = g.ID
+		names[i] = g.Name
+		types[i] = g.Type
+		urls[i] = g.URL
+		keys[i] = g.Key
+	}
+
+	if _, err := c.q.SetCampaignGoals.Exec(campID, pq.Array(ids), pq.Array(names), pq.Array(types), pq.Array(urls), pq.Array(keys)); err != nil {
+		c.log.Printf("error updating campaign goals: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.goals}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaignGoals(campID)
+}
+
+// RegisterCampaignGoalEvent records a page visit or conversion event for the goal
+// (key) of a campaign. subID may be 0 to record an anonymous event.
+func (c *Core) RegisterCampaignGoalEvent(campID, subID int, key string) error {
+	res, err := c.q.RegisterCampaignGoalEvent.Exec(campID, subID, key)
+	if err != nil {
+		c.log.Printf("error registering campaign goal event: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("public.errorProcessingRequest"))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, c.i18n.Ts("globals.messages.notFound", "name", "{campaigns.goal}"))
+	}
+
+	return nil
+}
+
+// GetCampaignGoalFunnel returns the funnel report of a campaign's goals.
+func (c *Core) GetCampaignGoalFunnel(campID int) ([]models.CampaignGoalStep, error) {
+	out := []models.CampaignGoalStep{}
+	if err := c.q.GetCampaignGoalFunnel.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign goal funnel: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.goals}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
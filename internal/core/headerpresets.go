@@ -0,0 +1,95 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetHeaderPresets returns all header presets.
+func (c *Core) GetHeaderPresets() ([]models.HeaderPreset, error) {
+	out := []models.HeaderPreset{}
+	if err := c.q.GetHeaderPresets.Select(&out, 0); err != nil {
+		c.log.Printf("error fetching header presets: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{headerPresets.presets}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetHeaderPreset returns a header preset.
+func (c *Core) GetHeaderPreset(id int) (models.HeaderPreset, error) {
+	var out []models.HeaderPreset
+	if err := c.q.GetHeaderPresets.Select(&out, id); err != nil {
+		c.log.Printf("error fetching header preset: %v", err)
+		return models.HeaderPreset{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{headerPresets.preset}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.HeaderPreset{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{headerPresets.preset}"))
+	}
+
+	return out[0], nil
+}
+
+// CreateHeaderPreset creates a header preset.
+func (c *Core) CreateHeaderPreset(p models.HeaderPreset) (models.HeaderPreset, error) {
+	var newID int
+	if err := c.q.CreateHeaderPreset.Get(&newID, strings.TrimSpace(p.Name), p.Headers); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.HeaderPreset{}, echo.NewHTTPError(http.StatusConflict, c.i18n.T("headerPresets.nameExists"))
+		}
+
+		c.log.Printf("error creating header preset: %v", err)
+		return models.HeaderPreset{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{headerPresets.preset}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetHeaderPreset(newID)
+}
+
+// UpdateHeaderPreset updates a header preset. The change applies to the campaigns
+// and tx templates the preset is attached to, including running campaigns.
+func (c *Core) UpdateHeaderPreset(id int, p models.HeaderPreset) (models.HeaderPreset, error) {
+	res, err := c.q.UpdateHeaderPreset.Exec(id, strings.TrimSpace(p.Name), p.Headers)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.HeaderPreset{}, echo.NewHTTPError(http.StatusConflict, c.i18n.T("headerPresets.nameExists"))
+		}
+
+		c.log.Printf("error updating header preset: %v", err)
+		return models.HeaderPreset{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{headerPresets.preset}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.HeaderPreset{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{headerPresets.preset}"))
+	}
+
+	return c.GetHeaderPreset(id)
+}
+
+// DeleteHeaderPreset deletes a header preset. It's detached from the campaigns
+// and tx templates it's attached to.
+func (c *Core) DeleteHeaderPreset(id int) error {
+	res, err := c.q.DeleteHeaderPreset.Exec(id)
+	if err != nil {
+		c.log.Printf("error deleting header preset: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{headerPresets.preset}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{headerPresets.preset}"))
+	}
+
+	return nil
+}
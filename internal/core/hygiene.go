@@ -0,0 +1,60 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetSubscriberDomains returns the distinct e-mail domains of all non-blocklisted subscribers.
+func (c *Core) GetSubscriberDomains() ([]string, error) {
+	out := []string{}
+	if err := c.q.GetSubscriberDomains.Select(&out); err != nil {
+		c.log.Printf("error fetching subscriber domains: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// RefreshListHygiene regenerates the list hygiene report for all lists given the role
+// account local parts, dead domains, and the chronic soft-bounce threshold. It returns
+// the number of entries in the report.
+func (c *Core) RefreshListHygiene(roleAccounts, deadDomains []string, softBounceCount, softBounceDays int) (int, error) {
+	n := 0
+	if err := c.q.RefreshListHygiene.Get(&n, pq.Array(roleAccounts), pq.Array(deadDomains), softBounceCount, softBounceDays); err != nil {
+		c.log.Printf("error refreshing list hygiene: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{lists.hygiene}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// GetListHygiene returns the list hygiene report of a list, or all lists if listID is 0.
+func (c *Core) GetListHygiene(listID int) ([]models.ListHygiene, error) {
+	out := []models.ListHygiene{}
+	if err := c.q.GetListHygiene.Select(&out, listID); err != nil {
+		c.log.Printf("error fetching list hygiene: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{lists.hygiene}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// ApplyListHygieneAction unsubscribes (from the list) or blocklists the subscribers
+// in a hygiene category of a list and returns the number of subscribers affected.
+func (c *Core) ApplyListHygieneAction(listID int, category, action string) (int, error) {
+	n := 0
+	if err := c.q.ApplyListHygieneAction.Get(&n, listID, category, action); err != nil {
+		c.log.Printf("error applying list hygiene action: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
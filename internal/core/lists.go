@@ -2,6 +2,7 @@ package core
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/knadh/listmonk/models"
@@ -36,7 +37,7 @@ func (c *Core) GetLists(typ string) ([]models.List, error) {
 
 // QueryLists gets multiple lists based on multiple query params. Along with the  paginated and sliced
 // results, the total number of lists in the DB is returned.
-func (c *Core) QueryLists(searchStr, typ, optin string, tags []string, orderBy, order string, offset, limit int) ([]models.List, int, error) {
+func (c *Core) QueryLists(searchStr, typ, optin string, tags []string, folder, status, orderBy, order string, offset, limit int) ([]models.List, int, error) {
 	_ = c.refreshCache(matListSubStats, false)
 
 	if tags == nil {
@@ -47,7 +48,7 @@ func (c *Core) QueryLists(searchStr, typ, optin string, tags []string, orderBy,
 		out            = []models.List{}
 		queryStr, stmt = makeSearchQuery(searchStr, orderBy, order, c.q.QueryLists, listQuerySortFields)
 	)
-	if err := c.db.Select(&out, stmt, 0, "", queryStr, typ, optin, pq.StringArray(tags), offset, limit); err != nil {
+	if err := c.db.Select(&out, stmt, 0, "", queryStr, typ, optin, pq.StringArray(tags), offset, limit, folder, status); err != nil {
 		c.log.Printf("error fetching lists: %v", err)
 		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
@@ -117,6 +118,18 @@ func (c *Core) GetListsByOptin(ids []int, optinType string) ([]models.List, erro
 	return out, nil
 }
 
+// GetListsByIDs returns lists by a list of IDs or UUIDs.
+func (c *Core) GetListsByIDs(ids []int, uuids []string) ([]models.List, error) {
+	out := []models.List{}
+	if err := c.q.GetListsByOptin.Select(&out, "", pq.Array(ids), pq.StringArray(uuids)); err != nil {
+		c.log.Printf("error fetching lists: %s", pqErrMsg(err))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
 // CreateList creates a new list.
 func (c *Core) CreateList(l models.List) (models.List, error) {
 	uu, err := uuid.NewV4()
@@ -132,11 +145,14 @@ func (c *Core) CreateList(l models.List) (models.List, error) {
 	if l.Optin == "" {
 		l.Optin = models.ListOptinSingle
 	}
+	if l.AddressFilter == "" {
+		l.AddressFilter = models.ListAddressFilterNone
+	}
 
 	// Insert and read ID.
 	var newID int
 	l.UUID = uu.String()
-	if err := c.q.CreateList.Get(&newID, l.UUID, l.Name, l.Type, l.Optin, pq.StringArray(normalizeTags(l.Tags)), l.Description); err != nil {
+	if err := c.q.CreateList.Get(&newID, l.UUID, l.Name, l.Type, l.Optin, pq.StringArray(normalizeTags(l.Tags)), l.Description, l.ContentQAURL, l.AddressFilter, l.ReturnPath, l.ParentID, strings.TrimSpace(l.Folder), l.EntitlementURL, l.MaxSubscribers, l.FormFields); err != nil {
 		c.log.Printf("error creating list: %v", err)
 		return models.List{}, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.list}", "error", pqErrMsg(err)))
@@ -147,7 +163,7 @@ func (c *Core) CreateList(l models.List) (models.List, error) {
 
 // UpdateList updates a given list.
 func (c *Core) UpdateList(id int, l models.List) (models.List, error) {
-	res, err := c.q.UpdateList.Exec(id, l.Name, l.Type, l.Optin, pq.StringArray(normalizeTags(l.Tags)), l.Description)
+	res, err := c.q.UpdateList.Exec(id, l.Name, l.Type, l.Optin, pq.StringArray(normalizeTags(l.Tags)), l.Description, l.ContentQAURL, l.AddressFilter, l.ReturnPath, l.ParentID, strings.TrimSpace(l.Folder), l.EntitlementURL, l.MaxSubscribers, l.FormFields)
 	if err != nil {
 		c.log.Printf("error updating list: %v", err)
 		return models.List{}, echo.NewHTTPError(http.StatusInternalServerError,
@@ -162,6 +178,64 @@ func (c *Core) UpdateList(id int, l models.List) (models.List, error) {
 	return c.GetList(id, "")
 }
 
+// GetListDescendantIDs returns the IDs of a list and all its child lists, recursively.
+func (c *Core) GetListDescendantIDs(id int) ([]int, error) {
+	out := []int{}
+	if err := c.q.GetListDescendantIDs.Select(&out, id); err != nil {
+		c.log.Printf("error fetching child lists: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// BulkUpdateLists applies a bulk action (models.ListAction*) to the given lists.
+// Deletion is done with DeleteLists.
+func (c *Core) BulkUpdateLists(ids []int, action string, tags []string, folder, optin string) error {
+	tags = normalizeTags(tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	if _, err := c.q.BulkUpdateLists.Exec(pq.Array(ids), action, pq.StringArray(tags), strings.TrimSpace(folder), optin); err != nil {
+		c.log.Printf("error updating lists: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// MergeLists merges the source list into the target list and archives the source list.
+func (c *Core) MergeLists(targetID, sourceID int) (models.ListMerge, error) {
+	var out models.ListMerge
+	if err := c.q.MergeLists.Get(&out, targetID, sourceID); err != nil {
+		c.log.Printf("error merging lists: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.lists}", "error", pqErrMsg(err)))
+	}
+
+	if !out.Found {
+		return out, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.list}"))
+	}
+
+	return out, nil
+}
+
+// GetListFolders returns the names of all list folders.
+func (c *Core) GetListFolders() ([]string, error) {
+	out := []string{}
+	if err := c.q.GetListFolders.Select(&out); err != nil {
+		c.log.Printf("error fetching list folders: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{lists.folders}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
 // DeleteList deletes a list.
 func (c *Core) DeleteList(id int) error {
 	return c.DeleteLists([]int{id})
@@ -176,3 +250,37 @@ func (c *Core) DeleteLists(ids []int) error {
 	}
 	return nil
 }
+
+// GetListGrowth returns the new subscriptions to a list between two dates grouped
+// by source and interval (day, week, month).
+func (c *Core) GetListGrowth(id int, from, to, interval string) ([]models.ListGrowth, error) {
+	out := []models.ListGrowth{}
+	if err := c.q.GetListGrowth.Select(&out, id, from, to, interval, c.consts.ReportingTimezone.String()); err != nil {
+		c.log.Printf("error fetching list growth: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.list}", "error", pqErrMsg(err)))
+	}
+
+	for i := range out {
+		out[i].Date = out[i].Date.In(c.consts.ReportingTimezone)
+	}
+
+	return out, nil
+}
+
+// GetListChurn returns the subscriptions, unsubscriptions, and subscriber counts of a
+// list in each of the last n intervals (week, month).
+func (c *Core) GetListChurn(id, n int, interval string) ([]models.ListChurn, error) {
+	out := []models.ListChurn{}
+	if err := c.q.GetListChurn.Select(&out, id, n, interval, c.consts.ReportingTimezone.String()); err != nil {
+		c.log.Printf("error fetching list churn: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.list}", "error", pqErrMsg(err)))
+	}
+
+	for i := range out {
+		out[i].Date = out[i].Date.In(c.consts.ReportingTimezone)
+	}
+
+	return out, nil
+}
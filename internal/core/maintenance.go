@@ -0,0 +1,61 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// DeleteDanglingMedia deletes the media of a provider uploaded before the given
+// date that aren't referenced anywhere, and returns the filenames
+// of the deleted items.
+func (c *Core) DeleteDanglingMedia(before time.Time, provider string) ([]string, error) {
+	out := []string{}
+	if err := c.q.DeleteDanglingMedia.Select(&out, before, provider); err != nil {
+		c.log.Printf("error deleting dangling media: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.media}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// DeleteExpiredTokens deletes expired API tokens, subscriber data export links,
+// and e-mail change confirmations.
+func (c *Core) DeleteExpiredTokens() (models.ExpiredTokens, error) {
+	var out models.ExpiredTokens
+	if err := c.q.DeleteExpiredTokens.Get(&out); err != nil {
+		c.log.Printf("error deleting expired tokens: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{apiTokens.tokens}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetIntegrityReport returns the counts of records with inconsistent counters or states.
+func (c *Core) GetIntegrityReport() (models.IntegrityReport, error) {
+	var out models.IntegrityReport
+	if err := c.q.GetIntegrityReport.Get(&out); err != nil {
+		c.log.Printf("error checking integrity: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{maintenance.integrity.name}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// FixIntegrity fixes the inconsistencies in the integrity report and returns
+// the counts of records fixed.
+func (c *Core) FixIntegrity() (models.IntegrityReport, error) {
+	var out models.IntegrityReport
+	if err := c.q.FixIntegrity.Get(&out); err != nil {
+		c.log.Printf("error fixing integrity: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{maintenance.integrity.name}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
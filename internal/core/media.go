@@ -94,3 +94,60 @@ func (c *Core) DeleteMedia(id int) (string, error) {
 
 	return fname, nil
 }
+
+// GetQuarantinedMedia returns all quarantined media items without their bodies.
+func (c *Core) GetQuarantinedMedia() ([]media.Quarantined, error) {
+	out := []media.Quarantined{}
+	if err := c.q.GetQuarantinedMedia.Select(&out, 0); err != nil {
+		c.log.Printf("error fetching quarantined media: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.media}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetQuarantinedMediaItem returns a quarantined media item with its body.
+func (c *Core) GetQuarantinedMediaItem(id int) (media.Quarantined, error) {
+	var out []media.Quarantined
+	if err := c.q.GetQuarantinedMedia.Select(&out, id); err != nil {
+		c.log.Printf("error fetching quarantined media: %v", err)
+		return media.Quarantined{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.media}", "error", pqErrMsg(err)))
+	}
+	if len(out) == 0 {
+		return media.Quarantined{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.media}"))
+	}
+
+	return out[0], nil
+}
+
+// QuarantineMedia records a flagged media item and its body in the quarantine.
+func (c *Core) QuarantineMedia(fileName, contentType, signature string, body []byte) (int, error) {
+	var id int
+	if err := c.q.InsertQuarantinedMedia.Get(&id, fileName, contentType, signature, len(body), body); err != nil {
+		c.log.Printf("error quarantining media: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.media}", "error", pqErrMsg(err)))
+	}
+
+	return id, nil
+}
+
+// DeleteQuarantinedMedia deletes a quarantined media item.
+func (c *Core) DeleteQuarantinedMedia(id int) error {
+	res, err := c.q.DeleteQuarantinedMedia.Exec(id)
+	if err != nil {
+		c.log.Printf("error deleting quarantined media: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{globals.terms.media}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.media}"))
+	}
+
+	return nil
+}
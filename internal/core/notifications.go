@@ -0,0 +1,133 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// InsertNotification adds a notification to the in-app notification feed.
+func (c *Core) InsertNotification(n models.Notification) error {
+	if n.Data == nil {
+		n.Data = models.JSON{}
+	}
+
+	var id int
+	if err := c.q.InsertNotification.Get(&id, n.Type, n.Title, n.Body, n.Data, n.Username); err != nil {
+		c.log.Printf("error inserting notification: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetNotifications returns paginated notifications of the given types along with
+// their read status for the given user, and the total count.
+func (c *Core) GetNotifications(username string, types []string, offset, limit int) ([]models.Notification, int, error) {
+	out := []models.Notification{}
+	if err := c.q.GetNotifications.Select(&out, username, pq.StringArray(types), offset, limit); err != nil {
+		c.log.Printf("error fetching notifications: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{notifications.name}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// CountUnreadNotifications returns the number of notifications of the given
+// types that the user hasn't read.
+func (c *Core) CountUnreadNotifications(username string, types []string) (int, error) {
+	var n int
+	if err := c.q.CountUnreadNotifications.Get(&n, username, pq.StringArray(types)); err != nil {
+		c.log.Printf("error counting notifications: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{notifications.name}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// MarkNotificationsRead marks the given notifications, or all of them if
+// no IDs are given, as read by the user.
+func (c *Core) MarkNotificationsRead(username string, ids []int) error {
+	if ids == nil {
+		ids = []int{}
+	}
+
+	if _, err := c.q.MarkNotificationsRead.Exec(username, pq.Array(ids)); err != nil {
+		c.log.Printf("error marking notifications read: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{notifications.name}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// GetNotificationPrefs returns a user's notification preferences. Users who haven't
+// saved their preferences get all the notifications in the feed and none by e-mail.
+func (c *Core) GetNotificationPrefs(username string) (models.NotificationPrefs, error) {
+	var out models.NotificationPrefs
+	if err := c.q.GetNotificationPrefs.Get(&out, username); err != nil {
+		if err == sql.ErrNoRows {
+			return models.NotificationPrefs{Events: models.NotificationEvents{}}, nil
+		}
+
+		c.log.Printf("error fetching notification preferences: %v", err)
+		return models.NotificationPrefs{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{notifications.prefs}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// UpsertNotificationPrefs saves a user's notification preferences.
+func (c *Core) UpsertNotificationPrefs(username string, p models.NotificationPrefs) (models.NotificationPrefs, error) {
+	if p.Events == nil {
+		p.Events = models.NotificationEvents{}
+	}
+
+	if _, err := c.q.UpsertNotificationPrefs.Exec(username, p.Email, p.Events); err != nil {
+		c.log.Printf("error saving notification preferences: %v", err)
+		return models.NotificationPrefs{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{notifications.prefs}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetNotificationPrefs(username)
+}
+
+// GetNotificationEmails returns the e-mail addresses of the users who have
+// opted to receive the given notification type by e-mail, optionally
+// limited to the given usernames.
+func (c *Core) GetNotificationEmails(typ string, usernames []string) ([]string, error) {
+	if usernames == nil {
+		usernames = []string{}
+	}
+
+	var out []string
+	if err := c.q.GetNotificationEmails.Select(&out, typ, pq.StringArray(usernames)); err != nil {
+		c.log.Printf("error fetching notification e-mails: %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CountRecentBounces returns the number of bounces recorded since the given time.
+func (c *Core) CountRecentBounces(since time.Time) (int, error) {
+	var n int
+	if err := c.q.CountRecentBounces.Get(&n, since); err != nil {
+		c.log.Printf("error counting bounces: %v", err)
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetUserPreferences returns the preferences of the given user.
+func (c *Core) GetUserPreferences(username string) (models.UserPreferences, error) {
+	var out models.UserPreferences
+	if err := c.q.GetUserPreferences.Get(&out, username); err != nil {
+		if err == sql.ErrNoRows {
+			return models.UserPreferences{}, nil
+		}
+
+		c.log.Printf("error fetching user preferences: %v", err)
+		return models.UserPreferences{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{settings.preferences}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// UpdateUserPreferences saves the preferences of the given user.
+func (c *Core) UpdateUserPreferences(username string, p models.UserPreferences) (models.UserPreferences, error) {
+	if _, err := c.q.UpsertUserPreferences.Exec(username, p.Timezone); err != nil {
+		c.log.Printf("error updating user preferences: %v", err)
+		return models.UserPreferences{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.preferences}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetUserPreferences(username)
+}
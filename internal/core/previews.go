@@ -0,0 +1,61 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetCampaignPreviews returns the e-mail client previews of a campaign without the images.
+func (c *Core) GetCampaignPreviews(campID int) ([]models.CampaignPreview, error) {
+	out := []models.CampaignPreview{}
+	if err := c.q.GetCampaignPreviews.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign previews: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.previews}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetCampaignPreviewImage returns a campaign's preview for a given e-mail client with the image.
+func (c *Core) GetCampaignPreviewImage(campID int, client string) (models.CampaignPreview, error) {
+	var out models.CampaignPreview
+	if err := c.q.GetCampaignPreviewImage.Get(&out, campID, client); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusNotFound,
+				c.i18n.Ts("globals.messages.notFound", "name", "{campaigns.preview}"))
+		}
+
+		c.log.Printf("error fetching campaign preview: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.preview}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// SetCampaignPreviews replaces the e-mail client previews of a campaign.
+func (c *Core) SetCampaignPreviews(campID int, previews []models.CampaignPreview) ([]models.CampaignPreview, error) {
+	var (
+		clients = make([]string, len(previews))
+		types   = make([]string, len(previews))
+		images  = make([][]byte, len(previews))
+	)
+	for i, p := range previews {
+		clients[i] = p.Client
+		types[i] = p.ContentType
+		images[i] = p.Image
+	}
+
+	if _, err := c.q.SetCampaignPreviews.Exec(campID, pq.Array(clients), pq.Array(types), pq.Array(images)); err != nil {
+		c.log.Printf("error updating campaign previews: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.previews}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaignPreviews(campID)
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetQuotas returns all user quotas.
+func (c *Core) GetQuotas() ([]models.Quota, error) {
+	out := []models.Quota{}
+	if err := c.q.GetQuotas.Select(&out); err != nil {
+		c.log.Printf("error fetching quotas: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{quotas.quotas}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetUserQuota returns the quota of a user, or the default quota if the user doesn't
+// have one. If there's neither, the returned bool is false.
+func (c *Core) GetUserQuota(username string) (models.Quota, bool, error) {
+	var out models.Quota
+	if err := c.q.GetUserQuota.Get(&out, username); err != nil {
+		if err == sql.ErrNoRows {
+			return out, false, nil
+		}
+
+		c.log.Printf("error fetching quota: %v", err)
+		return out, false, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{quotas.quota}", "error", pqErrMsg(err)))
+	}
+
+	return out, true, nil
+}
+
+// UpsertQuota creates or updates the quota of a user.
+func (c *Core) UpsertQuota(q models.Quota) (models.Quota, error) {
+	if err := c.q.UpsertQuota.Get(&q.ID, q.Username, q.CampaignsPerMonth, q.MessagesPerDay, q.MaxSubscribers); err != nil {
+		c.log.Printf("error updating quota: %v", err)
+		return models.Quota{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{quotas.quota}", "error", pqErrMsg(err)))
+	}
+
+	return q, nil
+}
+
+// DeleteQuota deletes a user quota.
+func (c *Core) DeleteQuota(id int) error {
+	if _, err := c.q.DeleteQuota.Exec(id); err != nil {
+		c.log.Printf("error deleting quota: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{quotas.quota}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// GetQuotaUsage returns a user's usage against their quota.
+func (c *Core) GetQuotaUsage(username string) (models.QuotaUsage, error) {
+	var out models.QuotaUsage
+	if err := c.q.GetQuotaUsage.Get(&out, username); err != nil {
+		c.log.Printf("error fetching quota usage: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{quotas.usage}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// RecordQuotaUsage records a campaign started by a user against their quota.
+func (c *Core) RecordQuotaUsage(username string, campID, messages int) error {
+	if _, err := c.q.InsertQuotaUsage.Exec(username, campID, messages); err != nil {
+		c.log.Printf("error recording quota usage: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{quotas.usage}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
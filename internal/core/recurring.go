@@ -0,0 +1,80 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetDueRecurringCampaigns returns the scheduled recurring campaigns whose next run is due.
+func (c *Core) GetDueRecurringCampaigns() ([]models.Campaign, error) {
+	var out []models.Campaign
+	if err := c.q.GetDueRecurringCampaigns.Select(&out); err != nil {
+		c.log.Printf("error fetching recurring campaigns: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.campaigns}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateCampaignRun clones a due recurring campaign into a run that's scheduled
+// right away and moves the recurring campaign to its next run. It returns the ID
+// of the run, or 0 if the campaign wasn't due (eg: another instance created the run).
+func (c *Core) CreateCampaignRun(id int, nextAt time.Time) (int, error) {
+	uu, err := uuid.NewV4()
+	if err != nil {
+		c.log.Printf("error generating UUID: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUUID", "error", err.Error()))
+	}
+
+	var out []int
+	if err := c.q.CreateCampaignRun.Select(&out, id, uu, nextAt); err != nil {
+		c.log.Printf("error creating campaign run: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.campaign}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return 0, nil
+	}
+
+	return out[0], nil
+}
+
+// GetCampaignRuns returns the paginated runs of a recurring campaign, latest
+// first, with their stats.
+func (c *Core) GetCampaignRuns(id, offset, limit int) (models.Campaigns, int, error) {
+	var out models.Campaigns
+	if err := c.q.GetCampaignRuns.Select(&out, id, offset, limit); err != nil {
+		c.log.Printf("error fetching campaign runs: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.campaigns}", "error", pqErrMsg(err)))
+	}
+
+	for i := 0; i < len(out); i++ {
+		if out[i].Tags == nil {
+			out[i].Tags = []string{}
+		}
+
+		setSendAtZone(&out[i])
+	}
+
+	// Lazy load stats.
+	if err := out.LoadStats(c.q.GetCampaignStats); err != nil {
+		c.log.Printf("error fetching campaign stats: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.campaigns}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// StartListRepermission converts a list to double opt-in, marks its subscriptions
+// as unconfirmed, and records a re-permission run with the given deadline and
+// opt-in campaign. It returns the ID of the run.
+func (c *Core) StartListRepermission(listID int, deadline time.Time, campID int) (int, error) {
+	var id int
+	if err := c.q.StartListRepermission.Get(&id, listID, deadline, campID); err != nil {
+		c.log.Printf("error starting list re-permission: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{lists.repermission}", "error", pqErrMsg(err)))
+	}
+
+	return id, nil
+}
+
+// GetListRepermissions returns the re-permission runs of a list, or all lists if listID is 0.
+func (c *Core) GetListRepermissions(listID int) ([]models.ListRepermission, error) {
+	out := []models.ListRepermission{}
+	if err := c.q.GetListRepermissions.Select(&out, listID); err != nil {
+		c.log.Printf("error fetching list re-permissions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{lists.repermission}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CancelListRepermission cancels the running re-permission of a list. Subscriptions that
+// haven't been confirmed are left as-is and the list remains double opt-in.
+func (c *Core) CancelListRepermission(listID int) error {
+	res, err := c.q.CancelListRepermission.Exec(listID)
+	if err != nil {
+		c.log.Printf("error cancelling list re-permission: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{lists.repermission}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{lists.repermission}"))
+	}
+
+	return nil
+}
+
+// FinishListRepermissions unsubscribes the subscribers who haven't confirmed from lists
+// whose re-permission deadline has passed, or from the given list right away if listID
+// is > 0. It returns the number of subscriptions that were unsubscribed.
+func (c *Core) FinishListRepermissions(listID int) (int, error) {
+	var n int
+	if err := c.q.FinishListRepermissions.Get(&n, listID); err != nil {
+		c.log.Printf("error finishing list re-permissions: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{lists.repermission}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetReputationMetrics returns the daily sender reputation metrics between two dates,
+// optionally filtered by provider.
+func (c *Core) GetReputationMetrics(provider string, from, to time.Time) ([]models.ReputationMetric, error) {
+	out := []models.ReputationMetric{}
+	if err := c.q.GetReputationMetrics.Select(&out, provider, from, to); err != nil {
+		c.log.Printf("error fetching reputation metrics: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{reputation.name}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// UpsertReputationMetrics inserts or updates daily sender reputation metrics.
+func (c *Core) UpsertReputationMetrics(metrics []models.ReputationMetric) error {
+	var (
+		providers = make([]string, len(metrics))
+		sources   = make([]string, len(metrics))
+		dates     = make([]string, len(metrics))
+		reps      = make([]string, len(metrics))
+		rates     = make([]float64, len(metrics))
+		errs      = make([]string, len(metrics))
+		metas     = make([]string, len(metrics))
+	)
+	for i, m := range metrics {
+		providers[i] = m.Provider
+		sources[i] = m.Source
+		dates[i] = m.Date.Format("2006-01-02")
+		reps[i] = m.Reputation
+		rates[i] = m.SpamRate
+		errs[i] = string(m.DeliveryErrors)
+		metas[i] = string(m.Meta)
+	}
+
+	if _, err := c.q.UpsertReputationMetrics.Exec(pq.Array(providers), pq.Array(sources), pq.Array(dates),
+		pq.Array(reps), pq.Array(rates), pq.Array(errs), pq.Array(metas)); err != nil {
+		c.log.Printf("error saving reputation metrics: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{reputation.name}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
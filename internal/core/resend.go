@@ -0,0 +1,35 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// CreateCampaignResend creates a follow-up of a finished campaign that's only sent
+// to the subscribers the campaign was sent to who haven't opened it. The follow-up
+// is scheduled the given number of days after the campaign finished.
+func (c *Core) CreateCampaignResend(id int, name, subject string, days int) (models.Campaign, error) {
+	uu, err := uuid.NewV4()
+	if err != nil {
+		c.log.Printf("error generating UUID: %v", err)
+		return models.Campaign{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUUID", "error", err.Error()))
+	}
+
+	var newID int
+	if err := c.q.CreateCampaignResend.Get(&newID, id, uu, name, subject, days); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Campaign{}, echo.NewHTTPError(http.StatusBadRequest, c.i18n.T("campaigns.resendNotFinished"))
+		}
+
+		c.log.Printf("error creating campaign resend: %v", err)
+		return models.Campaign{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.campaign}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetCampaign(newID, "", "")
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetSavedViews returns the saved views visible to the given user, optionally
+// filtered by collection.
+func (c *Core) GetSavedViews(collection, username string) ([]models.SavedView, error) {
+	out := []models.SavedView{}
+	if err := c.q.GetSavedViews.Select(&out, collection, username); err != nil {
+		c.log.Printf("error fetching saved views: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{views.views}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetSavedView returns a saved view visible to the given user.
+func (c *Core) GetSavedView(id int, username string) (models.SavedView, error) {
+	var out []models.SavedView
+	if err := c.q.GetSavedView.Select(&out, id, username); err != nil {
+		c.log.Printf("error fetching saved view: %v", err)
+		return models.SavedView{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{views.view}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.SavedView{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{views.view}"))
+	}
+
+	return out[0], nil
+}
+
+// CreateSavedView creates a saved view for the given user.
+func (c *Core) CreateSavedView(v models.SavedView, username string) (models.SavedView, error) {
+	var newID int
+	if err := c.q.CreateSavedView.Get(&newID, v.Name, v.Collection, v.Params, username, v.Shared); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "idx_saved_views_name" {
+			return models.SavedView{}, echo.NewHTTPError(http.StatusConflict, c.i18n.T("views.nameExists"))
+		}
+
+		c.log.Printf("error creating saved view: %v", err)
+		return models.SavedView{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{views.view}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetSavedView(newID, username)
+}
+
+// UpdateSavedView updates a saved view. Only the view's creator can update it.
+func (c *Core) UpdateSavedView(id int, v models.SavedView, username string) (models.SavedView, error) {
+	res, err := c.q.UpdateSavedView.Exec(id, username, v.Name, v.Params, v.Shared)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "idx_saved_views_name" {
+			return models.SavedView{}, echo.NewHTTPError(http.StatusConflict, c.i18n.T("views.nameExists"))
+		}
+
+		c.log.Printf("error updating saved view: %v", err)
+		return models.SavedView{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{views.view}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.SavedView{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{views.view}"))
+	}
+
+	return c.GetSavedView(id, username)
+}
+
+// DeleteSavedView deletes a saved view. Only the view's creator can delete it.
+func (c *Core) DeleteSavedView(id int, username string) error {
+	res, err := c.q.DeleteSavedView.Exec(id, username)
+	if err != nil {
+		c.log.Printf("error deleting saved view: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{views.view}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{views.view}"))
+	}
+
+	return nil
+}
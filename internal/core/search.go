@@ -0,0 +1,53 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GlobalSearch searches campaigns, templates, subscribers, and lists of the given
+// types for a term and returns up to limit results of every type, grouped by type.
+// Trashed campaigns, templates, and lists are included if withTrash is true.
+func (c *Core) GlobalSearch(searchStr string, types []string, withTrash bool, limit int) ([]models.SearchResult, error) {
+	var (
+		out  = []models.SearchResult{}
+		args = []interface{}{searchStr, likeEscaper.Replace(searchStr), limit}
+	)
+
+	for _, typ := range types {
+		var (
+			res  []models.SearchResult
+			name string
+			err  error
+		)
+
+		switch typ {
+		case models.SearchCampaigns:
+			name = "{globals.terms.campaigns}"
+			err = c.q.GlobalSearchCampaigns.Select(&res, append(args, withTrash)...)
+		case models.SearchTemplates:
+			name = "{globals.terms.templates}"
+			err = c.q.GlobalSearchTemplates.Select(&res, append(args, withTrash)...)
+		case models.SearchSubscribers:
+			name = "{globals.terms.subscribers}"
+			err = c.q.GlobalSearchSubscribers.Select(&res, args...)
+		case models.SearchLists:
+			name = "{globals.terms.lists}"
+			err = c.q.GlobalSearchLists.Select(&res, append(args, withTrash)...)
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("globals.messages.invalidFields", "name", "types"))
+		}
+
+		if err != nil {
+			c.log.Printf("error searching %s: %v", typ, err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError,
+				c.i18n.Ts("globals.messages.errorFetching", "name", name, "error", pqErrMsg(err)))
+		}
+
+		out = append(out, res...)
+	}
+
+	return out, nil
+}
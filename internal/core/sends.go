@@ -0,0 +1,70 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// QueryCampaignSends returns the send log of a campaign, optionally filtered by
+// a subscriber e-mail substring and send status.
+func (c *Core) QueryCampaignSends(campID int, email, status string, offset, limit int) ([]models.CampaignSend, int, error) {
+	out := []models.CampaignSend{}
+	if err := c.q.QueryCampaignSends.Select(&out, campID, email, status, offset, limit); err != nil {
+		c.log.Printf("error fetching campaign sends: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.sendLog}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// QueueFailedSendSubscribers marks the failed sends of a campaign, optionally limited
+// to the given subscriber IDs, as queued and returns their subscribers for re-queueing.
+func (c *Core) QueueFailedSendSubscribers(campID int, subIDs []int, limit int) (models.Subscribers, error) {
+	if subIDs == nil {
+		subIDs = []int{}
+	}
+
+	out := models.Subscribers{}
+	if err := c.q.QueueFailedSendSubscribers.Select(&out, campID, pq.Array(subIDs), limit); err != nil {
+		c.log.Printf("error fetching failed send subscribers: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// DeferCampaignSends marks the queued sends of a campaign to the given subscribers as failed.
+func (c *Core) DeferCampaignSends(campID int, subIDs []int) error {
+	if _, err := c.q.DeferCampaignSends.Exec(campID, pq.Array(subIDs)); err != nil {
+		c.log.Printf("error updating campaign sends: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{campaigns.sendLog}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// DeleteCampaignSends deletes the send log entries queued before the given time
+// and returns the number of deleted entries.
+func (c *Core) DeleteCampaignSends(before time.Time) (int, error) {
+	res, err := c.q.DeleteCampaignSends.Exec(before)
+	if err != nil {
+		c.log.Printf("error deleting campaign sends: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{campaigns.sendLog}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
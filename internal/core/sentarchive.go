@@ -0,0 +1,59 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// QuerySentMessages returns archived sent messages without their bodies,
+// optionally filtered by a subscriber and a campaign.
+func (c *Core) QuerySentMessages(subID, campID int, offset, limit int) ([]models.SentMessage, int, error) {
+	out := []models.SentMessage{}
+	if err := c.q.QuerySentMessages.Select(&out, subID, campID, offset, limit); err != nil {
+		c.log.Printf("error fetching sent messages: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{sentArchive.messages}", "error", pqErrMsg(err)))
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
+
+// GetSentMessage returns an archived sent message.
+func (c *Core) GetSentMessage(id int) (models.SentMessage, error) {
+	var out models.SentMessage
+	if err := c.q.GetSentMessage.Get(&out, id); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("globals.messages.notFound", "name", "{sentArchive.message}"))
+		}
+
+		c.log.Printf("error fetching sent message: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{sentArchive.message}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// DeleteSentMessages deletes the archived sent messages sent before the given time
+// and returns the number of deleted messages.
+func (c *Core) DeleteSentMessages(before time.Time) (int, error) {
+	res, err := c.q.DeleteSentMessages.Exec(before)
+	if err != nil {
+		c.log.Printf("error deleting sent messages: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{sentArchive.messages}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
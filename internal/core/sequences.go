@@ -0,0 +1,107 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetSequences returns all drip sequences.
+func (c *Core) GetSequences() ([]models.Sequence, error) {
+	out := []models.Sequence{}
+	if err := c.q.GetSequences.Select(&out, 0); err != nil {
+		c.log.Printf("error fetching sequences: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{sequences.sequences}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetSequence returns a drip sequence.
+func (c *Core) GetSequence(id int) (models.Sequence, error) {
+	var out []models.Sequence
+	if err := c.q.GetSequences.Select(&out, id); err != nil {
+		c.log.Printf("error fetching sequence: %v", err)
+		return models.Sequence{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{sequences.sequence}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.Sequence{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{sequences.sequence}"))
+	}
+
+	return out[0], nil
+}
+
+// CreateSequence creates a drip sequence. Subscribers who subscribe to its list
+// from now on enter it.
+func (c *Core) CreateSequence(s models.Sequence) (models.Sequence, error) {
+	campIDs, delays := sequenceStepArrays(s.Steps)
+
+	var newID int
+	if err := c.q.CreateSequence.Get(&newID, strings.TrimSpace(s.Name), s.ListID, s.Status, s.ExitOnClick,
+		pq.Array(campIDs), pq.Array(delays)); err != nil {
+		c.log.Printf("error creating sequence: %v", err)
+		return models.Sequence{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{sequences.sequence}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetSequence(newID)
+}
+
+// UpdateSequence updates a drip sequence and replaces its steps. Subscribers
+// in the sequence continue from the number of steps they've been sent.
+func (c *Core) UpdateSequence(id int, s models.Sequence) (models.Sequence, error) {
+	campIDs, delays := sequenceStepArrays(s.Steps)
+
+	var out []int
+	if err := c.q.UpdateSequence.Select(&out, id, strings.TrimSpace(s.Name), s.ListID, s.Status, s.ExitOnClick,
+		pq.Array(campIDs), pq.Array(delays)); err != nil {
+		c.log.Printf("error updating sequence: %v", err)
+		return models.Sequence{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{sequences.sequence}", "error", pqErrMsg(err)))
+	}
+
+	if len(out) == 0 {
+		return models.Sequence{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{sequences.sequence}"))
+	}
+
+	return c.GetSequence(id)
+}
+
+// DeleteSequence deletes a drip sequence along with the progress of its subscribers.
+func (c *Core) DeleteSequence(id int) error {
+	res, err := c.q.DeleteSequence.Exec(id)
+	if err != nil {
+		c.log.Printf("error deleting sequence: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{sequences.sequence}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{sequences.sequence}"))
+	}
+
+	return nil
+}
+
+// sequenceStepArrays returns the campaign IDs and delays of sequence steps in order.
+func sequenceStepArrays(steps models.SequenceSteps) ([]int, []int) {
+	var (
+		campIDs = make([]int, 0, len(steps))
+		delays  = make([]int, 0, len(steps))
+	)
+	for _, s := range steps {
+		campIDs = append(campIDs, s.CampaignID)
+		delays = append(delays, s.DelayMins)
+	}
+
+	return campIDs, delays
+}
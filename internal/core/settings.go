@@ -48,3 +48,17 @@ func (c *Core) UpdateSettings(s models.Settings) error {
 
 	return nil
 }
+
+// SetSendingPaused saves the state of the global send pause (kill switch) so that
+// it's retained across restarts. It's not a part of the settings UI as toggling
+// it doesn't require a restart.
+func (c *Core) SetSendingPaused(paused bool) error {
+	b, _ := json.Marshal(map[string]bool{"app.sending_paused": paused})
+	if _, err := c.q.UpdateSettings.Exec(b); err != nil {
+		c.log.Printf("error updating send pause: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.settings}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetStatusRules returns all subscriber status rules.
+func (c *Core) GetStatusRules() ([]models.StatusRule, error) {
+	out := []models.StatusRule{}
+	if err := c.q.GetStatusRules.Select(&out); err != nil {
+		c.log.Printf("error fetching status rules: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{statusRules.rules}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetStatusRule returns a subscriber status rule.
+func (c *Core) GetStatusRule(id int) (models.StatusRule, error) {
+	var out models.StatusRule
+	if err := c.q.GetStatusRule.Get(&out, id); err != nil {
+		if err == sql.ErrNoRows {
+			return out, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("globals.messages.notFound", "name", "{statusRules.rule}"))
+		}
+
+		c.log.Printf("error fetching status rule: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{statusRules.rule}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateStatusRule creates a subscriber status rule.
+func (c *Core) CreateStatusRule(r models.StatusRule) (models.StatusRule, error) {
+	var id int
+	if err := c.q.CreateStatusRule.Get(&id, r.Name, r.Enabled, r.Condition, r.Threshold, r.BounceType, r.WindowDays, r.Action); err != nil {
+		c.log.Printf("error creating status rule: %v", err)
+		return models.StatusRule{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{statusRules.rule}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetStatusRule(id)
+}
+
+// UpdateStatusRule updates a subscriber status rule.
+func (c *Core) UpdateStatusRule(id int, r models.StatusRule) (models.StatusRule, error) {
+	res, err := c.q.UpdateStatusRule.Exec(id, r.Name, r.Enabled, r.Condition, r.Threshold, r.BounceType, r.WindowDays, r.Action)
+	if err != nil {
+		c.log.Printf("error updating status rule: %v", err)
+		return models.StatusRule{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{statusRules.rule}", "error", pqErrMsg(err)))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.StatusRule{}, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{statusRules.rule}"))
+	}
+
+	return c.GetStatusRule(id)
+}
+
+// DeleteStatusRule deletes a subscriber status rule.
+func (c *Core) DeleteStatusRule(id int) error {
+	if _, err := c.q.DeleteStatusRule.Exec(id); err != nil {
+		c.log.Printf("error deleting status rule: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{statusRules.rule}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// PreviewStatusRule returns the total number of subscribers that match a status
+// rule and a sample of them, without changing anything.
+func (c *Core) PreviewStatusRule(r models.StatusRule, limit int) ([]models.Subscriber, int, error) {
+	out, total, err := c.applyStatusRule(r, true, limit)
+	if err != nil {
+		c.log.Printf("error previewing status rule: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, total, nil
+}
+
+// ApplyStatusRule applies a status rule's action to the matching subscribers and
+// returns their number.
+func (c *Core) ApplyStatusRule(r models.StatusRule) (int, error) {
+	// A single row is enough to get the total.
+	_, n, err := c.applyStatusRule(r, false, 1)
+	if err != nil {
+		c.log.Printf("error applying status rule %d: %v", r.ID, err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// applyStatusRule runs a status rule, or only matches its subscribers if dryRun
+// is true, and returns a sample of the matching subscribers with their total.
+func (c *Core) applyStatusRule(r models.StatusRule, dryRun bool, limit int) ([]models.Subscriber, int, error) {
+	out := []models.Subscriber{}
+	if err := c.q.ApplyStatusRule.Select(&out, r.Condition, r.Threshold, r.BounceType, r.WindowDays, r.Action, r.ID, dryRun, limit); err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	if len(out) > 0 {
+		total = out[0].Total
+	}
+
+	return out, total, nil
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// UpsertStripeCustomer records the e-mail of a Stripe customer.
+func (c *Core) UpsertStripeCustomer(id, email string) error {
+	if _, err := c.q.UpsertStripeCustomer.Exec(id, email); err != nil {
+		c.log.Printf("error upserting stripe customer: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// UpsertStripeSubscription records the status of the products of a Stripe subscription
+// as of the given event time. Products that are no longer on the subscription are canceled.
+func (c *Core) UpsertStripeSubscription(id, customerID string, productIDs []string, status, invoice string, eventAt time.Time) error {
+	if _, err := c.q.UpsertStripeSubscription.Exec(id, customerID, pq.StringArray(productIDs), status, invoice, eventAt); err != nil {
+		c.log.Printf("error upserting stripe subscription: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// RefundStripeInvoice marks the subscriptions whose latest invoice has been refunded
+// and returns the IDs of their customers.
+func (c *Core) RefundStripeInvoice(invoice string) ([]string, error) {
+	var out []string
+	if err := c.q.RefundStripeInvoice.Select(&out, invoice); err != nil {
+		c.log.Printf("error refunding stripe invoice: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// GetStripeCustomerSubscriptions returns the subscription records of a Stripe customer.
+func (c *Core) GetStripeCustomerSubscriptions(customerID string) ([]models.StripeSubscription, error) {
+	out := []models.StripeSubscription{}
+	if err := c.q.GetStripeCustomerSubscriptions.Select(&out, customerID); err != nil {
+		c.log.Printf("error fetching stripe subscriptions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// MarkStripeSubscriptionsSynced records that the current statuses of a Stripe
+// customer's subscriptions have been applied to the lists.
+func (c *Core) MarkStripeSubscriptionsSynced(customerID string) error {
+	if _, err := c.q.MarkStripeSubscriptionsSynced.Exec(customerID); err != nil {
+		c.log.Printf("error marking stripe subscriptions synced: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// GetStripeReconciliation compares the Stripe subscriptions of the given products
+// with the subscriptions of the lists they're mapped to and returns the discrepancies.
+func (c *Core) GetStripeReconciliation(products map[string][]int, limit int) ([]models.StripeReconciliation, error) {
+	var (
+		productIDs []string
+		listIDs    []int
+	)
+	for p, ids := range products {
+		for _, id := range ids {
+			productIDs = append(productIDs, p)
+			listIDs = append(listIDs, id)
+		}
+	}
+
+	out := []models.StripeReconciliation{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+
+	if err := c.q.GetStripeReconciliation.Select(&out, pq.StringArray(productIDs), pq.Array(listIDs), limit); err != nil {
+		c.log.Printf("error fetching stripe reconciliation: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{settings.stripe.subscriptions}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// maxSubscriberColumns is the maximum number of columns that can be selected.
+const maxSubscriberColumns = 30
+
+var (
+	// Columns of the subscribers table when a user hasn't chosen any.
+	defaultSubscriberColumns = []string{"status", "email", "name", "lists", "created_at", "updated_at"}
+
+	// Subscriber fields that can be selected as columns and their SQL expressions.
+	subColumnFields = map[string]string{
+		"id":         "subscribers.id",
+		"uuid":       "subscribers.uuid::TEXT",
+		"email":      "subscribers.email",
+		"name":       "subscribers.name",
+		"status":     "subscribers.status::TEXT",
+		"created_at": "subscribers.created_at",
+		"updated_at": "subscribers.updated_at",
+		"lists": `COALESCE((SELECT JSON_AGG(JSON_BUILD_OBJECT(
+			'id', l.id, 'name', l.name, 'optin', l.optin,
+			'subscription_status', sl.status,
+			'subscription_snoozed_until', sl.snoozed_until) ORDER BY l.name)
+			FROM subscriber_lists sl JOIN lists l ON (l.id = sl.list_id)
+			WHERE sl.subscriber_id = subscribers.id AND l.deleted_at IS NULL), '[]')`,
+	}
+
+	// Attribute paths, eg: attribs.city or attribs.address.zip.
+	regexSubAttribColumn = regexp.MustCompile(`^attribs(\.[a-zA-Z0-9_\-]{1,64}){1,5}$`)
+)
+
+// GetSubscriberColumns returns the subscribers table columns chosen by the given user,
+// or the default columns if the user hasn't chosen any.
+func (c *Core) GetSubscriberColumns(username string) (models.SubscriberColumns, error) {
+	var cols pq.StringArray
+	if err := c.q.GetSubscriberColumns.Get(&cols, username); err != nil {
+		if err == sql.ErrNoRows {
+			return models.SubscriberColumns{Columns: defaultSubscriberColumns}, nil
+		}
+
+		c.log.Printf("error fetching subscriber columns: %v", err)
+		return models.SubscriberColumns{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{subscribers.columns}", "error", pqErrMsg(err)))
+	}
+
+	return models.SubscriberColumns{Columns: cols, Custom: true}, nil
+}
+
+// UpdateSubscriberColumns saves the subscribers table columns of the given user.
+// An empty list of columns resets them to the defaults.
+func (c *Core) UpdateSubscriberColumns(username string, cols []string) (models.SubscriberColumns, error) {
+	if _, err := c.subColumnExps(cols); err != nil {
+		return models.SubscriberColumns{}, err
+	}
+
+	if _, err := c.q.UpsertSubscriberColumns.Exec(username, pq.Array(cols)); err != nil {
+		c.log.Printf("error updating subscriber columns: %v", err)
+		return models.SubscriberColumns{}, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{subscribers.columns}", "error", pqErrMsg(err)))
+	}
+
+	return c.GetSubscriberColumns(username)
+}
+
+// QuerySubscriberColumns is QuerySubscribers that only selects the given columns
+// (field names and attribute paths) of subscribers instead of whole subscribers.
+// Each result row is a map of the columns and always has the subscriber's id.
+func (c *Core) QuerySubscriberColumns(cols []string, query string, listIDs []int, subStatus string, order, orderBy string, offset, limit int) ([]map[string]interface{}, int, error) {
+	exps, err := c.subColumnExps(cols)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// There's an arbitrary query condition.
+	cond := ""
+	if query != "" {
+		cond = " AND " + query
+	}
+
+	// Sort params.
+	if !strSliceContains(orderBy, subQuerySortFields) {
+		orderBy = "subscribers.id"
+	}
+	if order != SortAsc && order != SortDesc {
+		order = SortDesc
+	}
+
+	// Required for pq.Array()
+	if listIDs == nil {
+		listIDs = []int{}
+	}
+
+	total, err := c.getSubscriberCount(cond, subStatus, listIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := []map[string]interface{}{}
+	if total == 0 {
+		return out, 0, nil
+	}
+
+	stmt := strings.ReplaceAll(c.q.QuerySubscribers, "%query%", cond)
+	stmt = strings.ReplaceAll(stmt, "%order%", orderBy+" "+order)
+	stmt = strings.ReplaceAll(stmt, "%columns%", strings.Join(exps, ", "))
+
+	tx, err := c.db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		c.log.Printf("error preparing subscriber query: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusBadRequest, c.i18n.Ts("subscribers.errorPreparingQuery", "error", pqErrMsg(err)))
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Queryx(stmt, pq.Array(listIDs), subStatus, offset, limit)
+	if err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+	defer rows.Close()
+
+	out, err = scanSubscriberColumns(rows)
+	if err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, total, nil
+}
+
+// GetSubscriberColumnsBatch returns the given columns of the subscribers with
+// the given UUIDs or e-mails (case-insensitive) in one go. Unknown UUIDs and
+// e-mails are skipped.
+func (c *Core) GetSubscriberColumnsBatch(cols []string, uuids, emails []string) ([]map[string]interface{}, error) {
+	exps, err := c.subColumnExps(cols)
+	if err != nil {
+		return nil, err
+	}
+
+	// Required for pq.Array()
+	if uuids == nil {
+		uuids = []string{}
+	}
+	for i, e := range emails {
+		emails[i] = strings.ToLower(e)
+	}
+	if emails == nil {
+		emails = []string{}
+	}
+
+	stmt := strings.ReplaceAll(c.q.GetSubscribersBatch, "%columns%", strings.Join(exps, ", "))
+	rows, err := c.db.Queryx(stmt, pq.Array(uuids), pq.Array(emails))
+	if err != nil {
+		c.log.Printf("error fetching subscribers: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+	defer rows.Close()
+
+	out, err := scanSubscriberColumns(rows)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// scanSubscriberColumns scans rows of selected subscriber columns into maps.
+func scanSubscriberColumns(rows *sqlx.Rows) ([]map[string]interface{}, error) {
+	out := []map[string]interface{}{}
+	for rows.Next() {
+		row := map[string]interface{}{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+
+		// Lists and attribute values are JSON. Text values are scanned as bytes.
+		for k, v := range row {
+			b, ok := v.([]byte)
+			if !ok {
+				continue
+			}
+			if k == "lists" || strings.HasPrefix(k, "attribs.") {
+				row[k] = json.RawMessage(b)
+			} else {
+				row[k] = string(b)
+			}
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// subColumnExps validates the given subscriber columns and returns their
+// SQL select expressions. The subscriber's id is always selected.
+func (c *Core) subColumnExps(cols []string) ([]string, error) {
+	if len(cols) > maxSubscriberColumns {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.invalidFields", "name", "columns"))
+	}
+
+	out := []string{"subscribers.id"}
+	seen := map[string]bool{"id": true}
+	for _, col := range cols {
+		if seen[col] {
+			continue
+		}
+		seen[col] = true
+
+		if exp, ok := subColumnFields[col]; ok {
+			out = append(out, exp+" AS "+col)
+			continue
+		}
+
+		// Attribute path. The path's keys are validated and safe to be used as literals.
+		if !regexSubAttribColumn.MatchString(col) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest,
+				c.i18n.Ts("subscribers.invalidColumn", "name", col))
+		}
+		path := strings.Split(col, ".")[1:]
+		out = append(out, fmt.Sprintf(`subscribers.attribs #> '{"%s"}' AS %s`, strings.Join(path, `","`), pq.QuoteIdentifier(col)))
+	}
+
+	return out, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/knadh/listmonk/models"
@@ -103,6 +104,7 @@ func (c *Core) QuerySubscribers(query string, listIDs []int, subStatus string, o
 	stmt := fmt.Sprintf(c.q.QuerySubscribersCount, cond)
 	stmt = strings.ReplaceAll(c.q.QuerySubscribers, "%query%", cond)
 	stmt = strings.ReplaceAll(stmt, "%order%", orderBy+" "+order)
+	stmt = strings.ReplaceAll(stmt, "%columns%", "subscribers.*")
 
 	tx, err := c.db.BeginTxx(context.Background(), &sql.TxOptions{ReadOnly: true})
 	if err != nil {
@@ -126,6 +128,36 @@ func (c *Core) QuerySubscribers(query string, listIDs []int, subStatus string, o
 	return out, total, nil
 }
 
+// SearchSubscribers does a typo tolerant full-text search of subscribers' names, e-mails,
+// and attributes and returns paginated results ranked by relevance including the total count.
+func (c *Core) SearchSubscribers(searchStr string, listIDs []int, subStatus string, offset, limit int) (models.Subscribers, int, error) {
+	searchStr = strings.TrimSpace(searchStr)
+
+	// Required for pq.Array()
+	if listIDs == nil {
+		listIDs = []int{}
+	}
+
+	out := models.Subscribers{}
+	if err := c.q.SearchSubscribers.Select(&out, searchStr, likeEscaper.Replace(searchStr), pq.Array(listIDs), subStatus, offset, limit); err != nil {
+		c.log.Printf("error searching subscribers: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+	if len(out) == 0 {
+		return out, 0, nil
+	}
+
+	// Lazy load lists for each subscriber.
+	if err := out.LoadLists(c.q.GetSubscriberListsLazy); err != nil {
+		c.log.Printf("error fetching subscriber lists: %v", err)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, out[0].Total, nil
+}
+
 // GetSubscriberLists returns a subscriber's lists based on the given conditions.
 func (c *Core) GetSubscriberLists(subID int, uuid string, listIDs []int, listUUIDs []string, subStatus string, listType string) ([]models.List, error) {
 	if listIDs == nil {
@@ -171,6 +203,81 @@ func (c *Core) GetSubscriberProfileForExport(id int, uuid string) (models.Subscr
 	return out, nil
 }
 
+// CreateSubscriberExport records a subscriber's data export that can be
+// downloaded until the given expiry and returns its UUID.
+func (c *Core) CreateSubscriberExport(subUUID string, data []byte, expiresAt time.Time) (string, error) {
+	uu, err := uuid.NewV4()
+	if err != nil {
+		c.log.Printf("error generating UUID: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUUID", "error", err.Error()))
+	}
+
+	if _, err := c.q.CreateSubscriberExport.Exec(uu.String(), subUUID, data, expiresAt); err != nil {
+		c.log.Printf("error creating subscriber export: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.subscriber}", "error", pqErrMsg(err)))
+	}
+
+	return uu.String(), nil
+}
+
+// GetSubscriberExport returns the data of an unexpired subscriber data export.
+func (c *Core) GetSubscriberExport(exportUUID string) ([]byte, error) {
+	var out []byte
+	if err := c.q.GetSubscriberExport.Get(&out, exportUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, echo.NewHTTPError(http.StatusNotFound,
+				c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.subscriber}"))
+		}
+
+		c.log.Printf("error fetching subscriber export: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscriber}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// CreateEmailChange records a pending e-mail change of a subscriber and returns
+// the UUID of the change with which it's confirmed.
+func (c *Core) CreateEmailChange(subID int, email string, expiresAt time.Time) (string, error) {
+	uu, err := uuid.NewV4()
+	if err != nil {
+		c.log.Printf("error generating UUID: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUUID", "error", err.Error()))
+	}
+
+	if _, err := c.q.CreateEmailChange.Exec(uu.String(), subID, email, expiresAt); err != nil {
+		c.log.Printf("error creating e-mail change: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.subscriber}", "error", pqErrMsg(err)))
+	}
+
+	return uu.String(), nil
+}
+
+// ConfirmEmailChange applies a pending e-mail change and returns the subscriber's ID.
+func (c *Core) ConfirmEmailChange(changeUUID string) (int, error) {
+	var id int
+	if err := c.q.ConfirmEmailChange.Get(&id, changeUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, echo.NewHTTPError(http.StatusNotFound,
+				c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.subscriber}"))
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return 0, echo.NewHTTPError(http.StatusConflict, c.i18n.T("subscribers.emailExists"))
+		}
+
+		c.log.Printf("error confirming e-mail change: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscriber}", "error", pqErrMsg(err)))
+	}
+
+	return id, nil
+}
+
 // ExportSubscribers returns an iterator function that provides lists of subscribers based
 // on the given criteria in an exportable form. The iterator function returned can be called
 // repeatedly until there are nil subscribers. It's an iterator because exports can be extremely
@@ -235,8 +342,9 @@ func (c *Core) ExportSubscribers(query string, subIDs, listIDs []int, subStatus
 
 // InsertSubscriber inserts a subscriber and returns the ID. The first bool indicates if
 // it was a new subscriber, and the second bool indicates if the subscriber was sent an optin confirmation.
-// bool = optinSent?
-func (c *Core) InsertSubscriber(sub models.Subscriber, listIDs []int, listUUIDs []string, preconfirm bool) (models.Subscriber, bool, error) {
+// bool = optinSent? If preconfirm is set, confirmedBy, the source that pre-confirmed the subscriptions,
+// is recorded in the subscriptions' meta. source is where the subscriptions came from (eg: form, admin).
+func (c *Core) InsertSubscriber(sub models.Subscriber, listIDs []int, listUUIDs []string, preconfirm bool, confirmedBy, source string) (models.Subscriber, bool, error) {
 	uu, err := uuid.NewV4()
 	if err != nil {
 		c.log.Printf("error generating UUID: %v", err)
@@ -269,7 +377,9 @@ func (c *Core) InsertSubscriber(sub models.Subscriber, listIDs []int, listUUIDs
 		sub.Attribs,
 		pq.Array(listIDs),
 		pq.Array(listUUIDs),
-		subStatus); err != nil {
+		subStatus,
+		makeConfirmMeta(preconfirm, confirmedBy),
+		source); err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "subscribers_email_key" {
 			return models.Subscriber{}, false, echo.NewHTTPError(http.StatusConflict, c.i18n.T("subscribers.emailExists"))
 		} else {
@@ -333,8 +443,9 @@ func (c *Core) UpdateSubscriber(id int, sub models.Subscriber) (models.Subscribe
 
 // UpdateSubscriberWithLists updates a subscriber's properties.
 // If deleteLists is set to true, all existing subscriptions are deleted and only
-// the ones provided are added or retained.
-func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs []int, listUUIDs []string, preconfirm, deleteLists bool) (models.Subscriber, bool, error) {
+// the ones provided are added or retained. If preconfirm is set, confirmedBy is recorded
+// in the meta of the subscriptions that get confirmed. source is recorded on new subscriptions.
+func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs []int, listUUIDs []string, preconfirm bool, confirmedBy, source string, deleteLists bool) (models.Subscriber, bool, error) {
 	subStatus := models.SubscriptionStatusUnconfirmed
 	if preconfirm {
 		subStatus = models.SubscriptionStatusConfirmed
@@ -360,7 +471,9 @@ func (c *Core) UpdateSubscriberWithLists(id int, sub models.Subscriber, listIDs
 		pq.Array(listIDs),
 		pq.Array(listUUIDs),
 		subStatus,
-		deleteLists)
+		deleteLists,
+		makeConfirmMeta(preconfirm, confirmedBy),
+		source)
 	if err != nil {
 		c.log.Printf("error updating subscriber: %v", err)
 		return models.Subscriber{}, false, echo.NewHTTPError(http.StatusInternalServerError,
@@ -445,6 +558,57 @@ func (c *Core) UnsubscribeByCampaign(subUUID, campUUID string, blocklist bool) e
 	return nil
 }
 
+// UnsubscribeSubscriber unsubscribes a given subscriber from all lists, optionally blocklisting them.
+func (c *Core) UnsubscribeSubscriber(subUUID string, blocklist bool) error {
+	if _, err := c.q.UnsubscribeSubscriber.Exec(subUUID, blocklist); err != nil {
+		c.log.Printf("error unsubscribing: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// SnoozeSubscription snoozes a subscriber's subscriptions to the lists in a campaign,
+// or to all lists if campUUID is empty, for the given number of days.
+func (c *Core) SnoozeSubscription(subUUID, campUUID string, days int) error {
+	if _, err := c.q.SnoozeSubscription.Exec(subUUID, campUUID, days); err != nil {
+		c.log.Printf("error snoozing subscription: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return nil
+}
+
+// ResumeSnoozedSubscriptions restores the subscriptions whose snooze has ended
+// and returns the number of subscriptions restored.
+func (c *Core) ResumeSnoozedSubscriptions() (int, error) {
+	res, err := c.q.ResumeSnoozedSubscriptions.Exec()
+	if err != nil {
+		c.log.Printf("error resuming snoozed subscriptions: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// RefreshSubscriberSendHours recomputes the best send hours of subscribers from
+// their campaign views in the last given number of days.
+func (c *Core) RefreshSubscriberSendHours(days int) (int, error) {
+	res, err := c.q.RefreshSubscriberSendHours.Exec(days)
+	if err != nil {
+		c.log.Printf("error refreshing subscriber send hours: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
 // ConfirmOptionSubscription confirms a subscriber's optin subscription.
 func (c *Core) ConfirmOptionSubscription(subUUID string, listUUIDs []string, meta models.JSON) error {
 	if meta == nil {
@@ -535,3 +699,16 @@ func (c *Core) getSubscriberCount(cond, subStatus string, listIDs []int) (int, e
 
 	return total, nil
 }
+
+// makeConfirmMeta returns the subscription meta that records the source
+// that pre-confirmed a subscription for auditing.
+func makeConfirmMeta(preconfirm bool, confirmedBy string) models.JSON {
+	if !preconfirm || confirmedBy == "" {
+		return models.JSON{}
+	}
+
+	return models.JSON{
+		"confirmed_by": confirmedBy,
+		"confirmed_at": time.Now(),
+	}
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// EnrollSunsetSubscribers enrolls subscribers who haven't engaged (viewed or clicked)
+// in the last inactiveDays into the sunset (win-back) flow, optionally adding them
+// to the given re-engagement list. It returns the number of subscribers enrolled.
+func (c *Core) EnrollSunsetSubscribers(inactiveDays, listID int) (int, error) {
+	n := 0
+	if err := c.q.EnrollSunsetSubscribers.Get(&n, inactiveDays, listID); err != nil {
+		c.log.Printf("error enrolling sunset subscribers: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// RecoverSunsetSubscribers marks enrolled subscribers who have engaged since enrolment
+// as recovered and removes them from the re-engagement list. It returns the number
+// of subscribers recovered.
+func (c *Core) RecoverSunsetSubscribers(listID int) (int, error) {
+	n := 0
+	if err := c.q.RecoverSunsetSubscribers.Get(&n, listID); err != nil {
+		c.log.Printf("error recovering sunset subscribers: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// RemoveSunsetSubscribers unsubscribes (or blocklists, depending on the action) enrolled
+// subscribers who haven't engaged within graceDays of enrolment. It returns the number
+// of subscribers removed.
+func (c *Core) RemoveSunsetSubscribers(graceDays int, action string) (int, error) {
+	n := 0
+	if err := c.q.RemoveSunsetSubscribers.Get(&n, graceDays, action); err != nil {
+		c.log.Printf("error removing sunset subscribers: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
+
+// GetSunsetStats returns the counts of enrolled, recovered, and removed subscribers
+// in the sunset flow. If since is non-zero, only state changes on or after it are counted.
+func (c *Core) GetSunsetStats(since time.Time) (models.SunsetStats, error) {
+	var t sql.NullTime
+	if !since.IsZero() {
+		t = sql.NullTime{Time: since, Valid: true}
+	}
+
+	var out models.SunsetStats
+	if err := c.q.GetSunsetStats.Get(&out, t); err != nil {
+		c.log.Printf("error fetching sunset stats: %v", err)
+		return out, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{globals.terms.subscribers}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
+)
+
+// RegisterSurveyResponse records a subscriber's answer to a survey question (key) in
+// a campaign. subUUID may be empty to record an anonymous answer. score is set for
+// rating questions.
+func (c *Core) RegisterSurveyResponse(campUUID, subUUID, key, answer string, score null.Int) error {
+	res, err := c.q.RegisterSurveyResponse.Exec(campUUID, subUUID, key, answer, score)
+	if err != nil {
+		c.log.Printf("error registering survey response: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, c.i18n.Ts("public.errorProcessingRequest"))
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.campaign}"))
+	}
+
+	return nil
+}
+
+// GetCampaignSurveys returns the results of the survey questions in a campaign.
+func (c *Core) GetCampaignSurveys(campID int) ([]models.CampaignSurvey, error) {
+	out := []models.CampaignSurvey{}
+	if err := c.q.GetCampaignSurveys.Select(&out, campID); err != nil {
+		c.log.Printf("error fetching campaign surveys: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{campaigns.surveys}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/knadh/listmonk/models"
 	"github.com/labstack/echo/v4"
+	"gopkg.in/volatiletech/null.v6"
 )
 
 // GetTemplates retrieves all templates.
@@ -36,9 +37,9 @@ func (c *Core) GetTemplate(id int, noBody bool) (models.Template, error) {
 }
 
 // CreateTemplate creates a new template.
-func (c *Core) CreateTemplate(name, typ, subject string, body []byte) (models.Template, error) {
+func (c *Core) CreateTemplate(name, typ, subject string, body []byte, headerPresetID null.Int) (models.Template, error) {
 	var newID int
-	if err := c.q.CreateTemplate.Get(&newID, name, typ, subject, body); err != nil {
+	if err := c.q.CreateTemplate.Get(&newID, name, typ, subject, body, headerPresetID); err != nil {
 		return models.Template{}, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorCreating", "name", "{globals.terms.template}", "error", pqErrMsg(err)))
 	}
@@ -47,14 +48,20 @@ func (c *Core) CreateTemplate(name, typ, subject string, body []byte) (models.Te
 }
 
 // UpdateTemplate updates a given template.
-func (c *Core) UpdateTemplate(id int, name, subject string, body []byte) (models.Template, error) {
-	res, err := c.q.UpdateTemplate.Exec(id, name, subject, body)
+// If version is > 0, the update only goes through if it matches the template's current version.
+func (c *Core) UpdateTemplate(id int, name, subject string, body []byte, headerPresetID null.Int, version int) (models.Template, error) {
+	res, err := c.q.UpdateTemplate.Exec(id, name, subject, body, version, headerPresetID)
 	if err != nil {
 		return models.Template{}, echo.NewHTTPError(http.StatusInternalServerError,
 			c.i18n.Ts("globals.messages.errorUpdating", "name", "{globals.terms.template}", "error", pqErrMsg(err)))
 	}
 
 	if n, _ := res.RowsAffected(); n == 0 {
+		if version > 0 {
+			return models.Template{}, echo.NewHTTPError(http.StatusConflict,
+				c.i18n.Ts("globals.messages.versionConflict", "name", "{globals.terms.template}"))
+		}
+
 		return models.Template{}, echo.NewHTTPError(http.StatusBadRequest,
 			c.i18n.Ts("globals.messages.notFound", "name", "{globals.terms.template}"))
 	}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	"github.com/labstack/echo/v4"
+)
+
+// GetTrash returns the trashed campaigns, lists, and templates.
+func (c *Core) GetTrash() ([]models.TrashItem, error) {
+	out := []models.TrashItem{}
+	if err := c.q.GetTrash.Select(&out); err != nil {
+		c.log.Printf("error fetching trash: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorFetching", "name", "{maintenance.trash.name}", "error", pqErrMsg(err)))
+	}
+
+	return out, nil
+}
+
+// RestoreTrash restores a trashed campaign, list, or template.
+func (c *Core) RestoreTrash(typ string, id int) error {
+	var n int
+	if err := c.q.RestoreTrash.Get(&n, typ, id); err != nil {
+		c.log.Printf("error restoring %s %d: %v", typ, id, err)
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorUpdating", "name", "{maintenance.trash.name}", "error", pqErrMsg(err)))
+	}
+
+	if n == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			c.i18n.Ts("globals.messages.notFound", "name", "{maintenance.trash.name}"))
+	}
+
+	return nil
+}
+
+// PurgeTrash permanently deletes campaigns, lists, and templates that were
+// trashed before the given date and returns the number of items deleted.
+func (c *Core) PurgeTrash(before time.Time) (int, error) {
+	var n int
+	if err := c.q.PurgeTrash.Get(&n, before); err != nil {
+		c.log.Printf("error purging trash: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError,
+			c.i18n.Ts("globals.messages.errorDeleting", "name", "{maintenance.trash.name}", "error", pqErrMsg(err)))
+	}
+
+	return n, nil
+}
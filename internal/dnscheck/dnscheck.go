@@ -0,0 +1,268 @@
+// Package dnscheck validates the DNS records of sending domains that affect
+// e-mail deliverability: SPF, DKIM, DMARC, MX, and the tracking domain CNAME.
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	TypeSPF   = "spf"
+	TypeDKIM  = "dkim"
+	TypeDMARC = "dmarc"
+	TypeMX    = "mx"
+	TypeCNAME = "cname"
+	TypeTXT   = "txt"
+
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Result is the result of a single record check.
+type Result struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+// Checker runs DNS checks.
+type Checker struct {
+	timeout  time.Duration
+	resolver *net.Resolver
+}
+
+// New returns a new instance of the DNS checker.
+func New(timeout time.Duration) *Checker {
+	if timeout < time.Second {
+		timeout = time.Second * 5
+	}
+
+	return &Checker{timeout: timeout, resolver: net.DefaultResolver}
+}
+
+// CheckDomain checks the SPF, DMARC, MX, and the DKIM records of the given
+// selectors of a sending domain.
+func (c *Checker) CheckDomain(domain string, dkimSelectors []string) []Result {
+	out := []Result{c.checkSPF(domain), c.checkDMARC(domain), c.checkMX(domain)}
+	for _, s := range dkimSelectors {
+		out = append(out, c.checkDKIM(domain, s))
+	}
+
+	return out
+}
+
+// CheckCNAME checks that the tracking host resolves, and if a target is given,
+// that it's a CNAME to the target.
+func (c *Checker) CheckCNAME(host, target string) Result {
+	r := Result{Type: TypeCNAME, Name: host}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cname, err := c.resolver.LookupCNAME(ctx, host)
+	if err != nil {
+		return fail(r, err)
+	}
+	r.Value = strings.TrimSuffix(cname, ".")
+
+	if target == "" {
+		r.Status = StatusPass
+		return r
+	}
+
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	if !strings.EqualFold(r.Value, target) {
+		r.Status = StatusFail
+		r.Message = fmt.Sprintf("points to %s instead of %s", r.Value, target)
+		return r
+	}
+
+	r.Status = StatusPass
+	return r
+}
+
+func (c *Checker) checkSPF(domain string) Result {
+	r := Result{Type: TypeSPF, Name: domain}
+
+	recs, err := c.lookupTXT(domain, "v=spf1")
+	if err != nil {
+		return fail(r, err)
+	}
+
+	switch {
+	case len(recs) == 0:
+		r.Status = StatusFail
+		r.Message = "no SPF record"
+	case len(recs) > 1:
+		r.Status = StatusFail
+		r.Value = strings.Join(recs, " | ")
+		r.Message = "multiple SPF records"
+	default:
+		r.Value = recs[0]
+		r.Status = StatusPass
+
+		f := strings.Fields(strings.ToLower(recs[0]))
+		switch f[len(f)-1] {
+		case "+all", "all":
+			r.Status = StatusFail
+			r.Message = "allows any server to send (+all)"
+		case "?all":
+			r.Status = StatusWarn
+			r.Message = "neutral policy (?all)"
+		}
+	}
+
+	return r
+}
+
+func (c *Checker) checkDMARC(domain string) Result {
+	r := Result{Type: TypeDMARC, Name: "_dmarc." + domain}
+
+	recs, err := c.lookupTXT(r.Name, "v=dmarc1")
+	if err != nil {
+		return fail(r, err)
+	}
+
+	switch {
+	case len(recs) == 0:
+		r.Status = StatusFail
+		r.Message = "no DMARC record"
+	case len(recs) > 1:
+		r.Status = StatusFail
+		r.Value = strings.Join(recs, " | ")
+		r.Message = "multiple DMARC records"
+	default:
+		r.Value = recs[0]
+		r.Status = StatusPass
+
+		for _, t := range strings.Split(recs[0], ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(t), "="); ok && strings.EqualFold(k, "p") && strings.EqualFold(v, "none") {
+				r.Status = StatusWarn
+				r.Message = "monitoring only policy (p=none)"
+			}
+		}
+	}
+
+	return r
+}
+
+func (c *Checker) checkMX(domain string) Result {
+	r := Result{Type: TypeMX, Name: domain}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	mxs, err := c.resolver.LookupMX(ctx, domain)
+	if err != nil {
+		return fail(r, err)
+	}
+	if len(mxs) == 0 {
+		r.Status = StatusFail
+		r.Message = "no MX records"
+		return r
+	}
+
+	hosts := make([]string, 0, len(mxs))
+	for _, m := range mxs {
+		hosts = append(hosts, fmt.Sprintf("%d %s", m.Pref, strings.TrimSuffix(m.Host, ".")))
+	}
+	r.Value = strings.Join(hosts, ", ")
+	r.Status = StatusPass
+
+	return r
+}
+
+func (c *Checker) checkDKIM(domain, selector string) Result {
+	r := Result{Type: TypeDKIM, Name: selector + "._domainkey." + domain}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	txts, err := c.resolver.LookupTXT(ctx, r.Name)
+	if err != nil {
+		return fail(r, err)
+	}
+
+	for _, t := range txts {
+		for _, tag := range strings.Split(t, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(tag), "=")
+			if !ok || strings.TrimSpace(k) != "p" {
+				continue
+			}
+
+			r.Value = t
+			if strings.TrimSpace(v) == "" {
+				r.Status = StatusFail
+				r.Message = "key is revoked (empty p=)"
+				return r
+			}
+
+			r.Status = StatusPass
+			return r
+		}
+	}
+
+	r.Status = StatusFail
+	r.Message = "no DKIM public key"
+	return r
+}
+
+// CheckTXT checks that a name has a TXT record with the given value, eg: a domain
+// ownership verification token.
+func (c *Checker) CheckTXT(name, value string) Result {
+	r := Result{Type: TypeTXT, Name: name}
+
+	txts, err := c.lookupTXT(name, "")
+	if err != nil {
+		return fail(r, err)
+	}
+
+	for _, t := range txts {
+		if t == value {
+			r.Status = StatusPass
+			r.Value = t
+			return r
+		}
+	}
+
+	r.Status = StatusFail
+	r.Message = "TXT record not found"
+	return r
+}
+
+// lookupTXT returns the TXT records of a name that start with the given prefix.
+func (c *Checker) lookupTXT(name, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	txts, err := c.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		// No records.
+		if e, ok := err.(*net.DNSError); ok && e.IsNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	out := []string{}
+	for _, t := range txts {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(t)), prefix) {
+			out = append(out, strings.TrimSpace(t))
+		}
+	}
+
+	return out, nil
+}
+
+func fail(r Result, err error) Result {
+	r.Status = StatusFail
+	r.Message = err.Error()
+	return r
+}
@@ -0,0 +1,113 @@
+// Package entitlement implements a client for external entitlement hooks that
+// gate subscriptions to premium lists. A hook is an HTTP endpoint (eg: on a
+// billing system) that receives a list and a batch of subscribers and returns
+// whether each subscriber is entitled to the list.
+package entitlement
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRespSize is the maximum size of a hook's response body.
+const maxRespSize = 1024 * 1024
+
+// Opt represents the entitlement client options.
+type Opt struct {
+	Timeout time.Duration
+}
+
+// List is the list whose entitlement is checked.
+type List struct {
+	ID   int    `json:"id"`
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// Subscriber is a subscriber whose entitlement is checked. UUID is empty for
+// new subscribers.
+type Subscriber struct {
+	UUID    string          `json:"uuid"`
+	Email   string          `json:"email"`
+	Name    string          `json:"name"`
+	Attribs json.RawMessage `json:"attribs,omitempty"`
+}
+
+type hookReq struct {
+	List        List         `json:"list"`
+	Subscribers []Subscriber `json:"subscribers"`
+}
+
+type hookResp struct {
+	// Map of subscriber e-mails to whether they're entitled.
+	Entitled map[string]bool `json:"entitled"`
+}
+
+// Client is the entitlement hook client.
+type Client struct {
+	o      Opt
+	client *http.Client
+}
+
+// New returns a new instance of the entitlement hook client.
+func New(o Opt) *Client {
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 5
+	}
+
+	return &Client{
+		o: o,
+		client: &http.Client{
+			Timeout: o.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost:   10,
+				MaxConnsPerHost:       100,
+				ResponseHeaderTimeout: o.Timeout,
+				IdleConnTimeout:       o.Timeout,
+			},
+		}}
+}
+
+// Check posts a list and a batch of subscribers to a hook URL and returns the
+// (lowercased) e-mails of the subscribers that are entitled to the list.
+// Subscribers missing from the hook's response are not entitled.
+func (c *Client) Check(hookURL string, l List, subs []Subscriber) (map[string]bool, error) {
+	b, err := json.Marshal(hookReq{List: l, Subscribers: subs})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Post(hookURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hook returned %d", resp.StatusCode)
+	}
+
+	var r hookResp
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error parsing hook response: %v", err)
+	}
+
+	out := make(map[string]bool, len(r.Entitled))
+	for email, ok := range r.Entitled {
+		if ok {
+			out[strings.ToLower(email)] = true
+		}
+	}
+
+	return out, nil
+}
@@ -11,7 +11,8 @@ import (
 )
 
 const (
-	TypeError = "error"
+	TypeError  = "error"
+	TypeChange = "change"
 )
 
 // Event represents a single event in the system.
@@ -0,0 +1,226 @@
+// Package feed implements a minimal parser for RSS 2.0 and Atom feeds whose
+// entries are rendered into campaigns that are sourced from a feed.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// Date formats found in the wild in RSS (RFC 822 and variants) and Atom (RFC 3339).
+var dateFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+type link struct {
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Value string `xml:",chardata"`
+}
+
+type rss struct {
+	Items []struct {
+		Title       string `xml:"title"`
+		Links       []link `xml:"link"`
+		Description string `xml:"description"`
+		Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		Author      string `xml:"author"`
+		Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		PubDate     string `xml:"pubDate"`
+		Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	} `xml:"channel>item"`
+}
+
+type atom struct {
+	Entries []struct {
+		Title   string `xml:"title"`
+		Links   []link `xml:"link"`
+		Summary string `xml:"summary"`
+		Content string `xml:"content"`
+		Author  struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// Parse parses an RSS 2.0 or Atom feed and returns its entries in the order
+// in which they appear in the feed.
+func Parse(b []byte) (models.FeedItems, error) {
+	root, err := rootElement(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var f rss
+		if err := decode(b, &f); err != nil {
+			return nil, err
+		}
+
+		out := make(models.FeedItems, 0, len(f.Items))
+		for _, i := range f.Items {
+			author := i.Author
+			if author == "" {
+				author = i.Creator
+			}
+			date := i.PubDate
+			if date == "" {
+				date = i.Date
+			}
+
+			// RSS items have a text <link>, but may also have atom:link elements.
+			var u string
+			for _, l := range i.Links {
+				if v := strings.TrimSpace(l.Value); v != "" {
+					u = v
+					break
+				}
+			}
+
+			out = append(out, models.FeedItem{
+				Title:       strings.TrimSpace(i.Title),
+				Link:        u,
+				Description: strings.TrimSpace(i.Description),
+				Content:     strings.TrimSpace(i.Content),
+				Author:      strings.TrimSpace(author),
+				Published:   parseDate(date),
+			})
+		}
+		return out, nil
+
+	case "feed":
+		var f atom
+		if err := decode(b, &f); err != nil {
+			return nil, err
+		}
+
+		out := make(models.FeedItems, 0, len(f.Entries))
+		for _, e := range f.Entries {
+			date := e.Published
+			if date == "" {
+				date = e.Updated
+			}
+
+			// The entry's URL is the "alternate" link, which is the default rel.
+			var u string
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					u = strings.TrimSpace(l.Href)
+					break
+				}
+			}
+
+			out = append(out, models.FeedItem{
+				Title:       strings.TrimSpace(e.Title),
+				Link:        u,
+				Description: strings.TrimSpace(e.Summary),
+				Content:     strings.TrimSpace(e.Content),
+				Author:      strings.TrimSpace(e.Author.Name),
+				Published:   parseDate(date),
+			})
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unknown feed format: %s", root)
+}
+
+// Since returns the entries published after the given time. Entries without
+// a (parseable) date are skipped as it's not known whether they're new.
+func Since(items models.FeedItems, t time.Time) models.FeedItems {
+	out := make(models.FeedItems, 0, len(items))
+	for _, i := range items {
+		if i.Published.Valid && i.Published.Time.After(t) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// rootElement returns the local name of the document's root element.
+func rootElement(b []byte) (string, error) {
+	d := newDecoder(b)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return "", errors.New("feed has no root element")
+			}
+			return "", err
+		}
+
+		if e, ok := t.(xml.StartElement); ok {
+			return e.Name.Local, nil
+		}
+	}
+}
+
+func decode(b []byte, v interface{}) error {
+	return newDecoder(b).Decode(v)
+}
+
+func newDecoder(b []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charsetReader
+	return d
+}
+
+// charsetReader converts single byte Latin charsets to UTF-8. encoding/xml
+// only supports UTF-8 by itself.
+func charsetReader(charset string, r io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "us-ascii":
+	default:
+		return nil, fmt.Errorf("unsupported feed charset: %s", charset)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return bytes.NewReader(out), nil
+}
+
+func parseDate(s string) null.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return null.Time{}
+	}
+
+	for _, f := range dateFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return null.TimeFrom(t)
+		}
+	}
+	return null.Time{}
+}
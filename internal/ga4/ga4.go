@@ -0,0 +1,200 @@
+// Package ga4 forwards campaign open and click events to Google Analytics 4
+// with the Measurement Protocol. The events carry the campaign's attribution
+// parameters (source, medium, campaign), which lets web analytics sessions be
+// attributed to e-mail sends without any client-side tagging.
+package ga4
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	EventOpen  = "email_open"
+	EventClick = "email_click"
+
+	defaultEndpoint = "https://www.google-analytics.com/mp/collect"
+	defaultTimeout  = time.Second * 10
+
+	// queueSize is the number of events that can be waiting to be forwarded.
+	// Events are dropped when the queue is full.
+	queueSize = 10000
+
+	// maxNameCache is the number of campaign names that are cached.
+	maxNameCache = 1000
+)
+
+// Opt represents GA4 options.
+type Opt struct {
+	// Measurement ID (G-XXXX) of the GA4 web data stream and the API secret
+	// created for the Measurement Protocol in it.
+	MeasurementID string
+	APISecret     string
+
+	// Attribution source and medium of the events, eg: listmonk, email.
+	Source string
+	Medium string
+
+	// Optional Measurement Protocol endpoint, eg: the EU endpoint or the
+	// validation endpoint (/debug/mp/collect).
+	Endpoint string
+	Timeout  time.Duration
+
+	// CampaignNameCB returns the name of a campaign by its UUID.
+	CampaignNameCB func(uuid string) (string, error)
+}
+
+// Event represents an open or click on a campaign.
+type Event struct {
+	Name string
+
+	// ClientID is the pseudonymous ID of the subscriber that groups their events.
+	ClientID string
+
+	CampaignUUID string
+
+	// URL is the destination of a clicked link.
+	URL  string
+	Time time.Time
+}
+
+// GA4 forwards events to the Measurement Protocol.
+type GA4 struct {
+	opt   Opt
+	h     *http.Client
+	q     chan Event
+	names map[string]string
+	log   *log.Logger
+}
+
+type payload struct {
+	ClientID           string         `json:"client_id"`
+	TimestampMicros    int64          `json:"timestamp_micros"`
+	NonPersonalizedAds bool           `json:"non_personalized_ads"`
+	Events             []payloadEvent `json:"events"`
+}
+
+type payloadEvent struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// New returns a new instance of the GA4 forwarder.
+func New(o Opt, lo *log.Logger) (*GA4, error) {
+	if o.MeasurementID == "" || o.APISecret == "" {
+		return nil, errors.New("GA4 measurement ID and API secret are required")
+	}
+
+	if o.Endpoint == "" {
+		o.Endpoint = defaultEndpoint
+	}
+	if o.Timeout < time.Second {
+		o.Timeout = defaultTimeout
+	}
+
+	return &GA4{
+		opt:   o,
+		h:     &http.Client{Timeout: o.Timeout},
+		q:     make(chan Event, queueSize),
+		names: make(map[string]string),
+		log:   lo,
+	}, nil
+}
+
+// Push queues an event to be forwarded without blocking. It returns false
+// if the queue is full and the event was dropped.
+func (g *GA4) Push(e Event) bool {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	select {
+	case g.q <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run forwards queued events indefinitely.
+func (g *GA4) Run() {
+	for e := range g.q {
+		if err := g.send(e); err != nil {
+			g.log.Printf("error forwarding %s event to GA4: %v", e.Name, err)
+		}
+	}
+}
+
+func (g *GA4) send(e Event) error {
+	params := map[string]interface{}{
+		"campaign_id": e.CampaignUUID,
+		"campaign":    g.campaignName(e.CampaignUUID),
+		"source":      g.opt.Source,
+		"medium":      g.opt.Medium,
+
+		// Events without an engagement time aren't shown in GA4's realtime reports.
+		"engagement_time_msec": 1,
+	}
+	if e.URL != "" {
+		params["link_url"] = e.URL
+	}
+
+	b, err := json.Marshal(payload{
+		ClientID:           e.ClientID,
+		TimestampMicros:    e.Time.UnixMicro(),
+		NonPersonalizedAds: true,
+		Events:             []payloadEvent{{Name: e.Name, Params: params}},
+	})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s?measurement_id=%s&api_secret=%s", g.opt.Endpoint,
+		url.QueryEscape(g.opt.MeasurementID), url.QueryEscape(g.opt.APISecret))
+	resp, err := g.h.Post(u, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	// The Measurement Protocol responds with a 2xx even for invalid events.
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("non-OK response from GA4: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// campaignName returns the (cached) name of a campaign. Names are only
+// accessed from the Run() goroutine.
+func (g *GA4) campaignName(uuid string) string {
+	if n, ok := g.names[uuid]; ok {
+		return n
+	}
+
+	if g.opt.CampaignNameCB == nil {
+		return ""
+	}
+
+	n, err := g.opt.CampaignNameCB(uuid)
+	if err != nil {
+		return ""
+	}
+
+	if len(g.names) >= maxNameCache {
+		g.names = make(map[string]string)
+	}
+	g.names[uuid] = n
+
+	return n
+}
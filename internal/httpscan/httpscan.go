@@ -0,0 +1,88 @@
+// Package httpscan implements a client for HTTP content scanners. The file is
+// POSTed as the raw request body and the scanner responds with a JSON verdict,
+// eg: {"infected": true, "signature": "Eicar-Signature"}.
+package httpscan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxRespSize is the maximum size of a scanner's response body.
+const maxRespSize = 64 * 1024
+
+// Opt represents the HTTP scanner client options.
+type Opt struct {
+	URL string
+
+	// Token, if set, is sent as a bearer token in the Authorization header.
+	Token   string
+	Timeout time.Duration
+}
+
+// Result is the result of a scan.
+type Result struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature"`
+}
+
+// Client is the HTTP scanner client.
+type Client struct {
+	o      Opt
+	client *http.Client
+}
+
+// New returns a new instance of the HTTP scanner client.
+func New(o Opt) (*Client, error) {
+	if o.URL == "" {
+		return nil, errors.New("httpscan: no URL")
+	}
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 30
+	}
+
+	return &Client{
+		o: o,
+		client: &http.Client{
+			Timeout: o.Timeout,
+		},
+	}, nil
+}
+
+// Scan posts the given data to the scanner and returns the scan result.
+func (c *Client) Scan(r io.Reader) (Result, error) {
+	req, err := http.NewRequest(http.MethodPost, c.o.URL, r)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if c.o.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.o.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return Result{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("scanner returned %d", resp.StatusCode)
+	}
+
+	var out Result
+	if err := json.Unmarshal(body, &out); err != nil {
+		return Result{}, fmt.Errorf("error parsing scanner response: %v", err)
+	}
+
+	return out, nil
+}
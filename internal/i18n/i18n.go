@@ -12,8 +12,8 @@ import (
 
 // I18n offers translation functions over a language map.
 type I18n struct {
-	code    string `json:"code"`
-	name    string `json:"name"`
+	code    string
+	name    string
 	langMap map[string]string
 }
 
@@ -0,0 +1,130 @@
+// Package ipfilter implements CIDR based allow and deny lists for client IPs.
+// The lists can be replaced at runtime, eg: when settings change.
+package ipfilter
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Opt represents the filter's lists of CIDR ranges or single IPs.
+type Opt struct {
+	// Deny is checked before the allowlists and blocks all access.
+	Deny []string
+
+	// Allow is the allowlist of the admin. If it's empty, all IPs that aren't
+	// denied are allowed.
+	Allow []string
+
+	// APIAllow is the allowlist of API users. If it's empty, Allow applies.
+	APIAllow []string
+}
+
+// Filter checks IPs against the allow and deny lists.
+type Filter struct {
+	mu       sync.RWMutex
+	deny     []*net.IPNet
+	allow    []*net.IPNet
+	apiAllow []*net.IPNet
+}
+
+// New returns a new Filter.
+func New(o Opt) (*Filter, error) {
+	f := &Filter{}
+	if err := f.Set(o); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Set parses and replaces the filter's lists.
+func (f *Filter) Set(o Opt) error {
+	deny, err := ParseCIDRs(o.Deny)
+	if err != nil {
+		return err
+	}
+	allow, err := ParseCIDRs(o.Allow)
+	if err != nil {
+		return err
+	}
+	apiAllow, err := ParseCIDRs(o.APIAllow)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.deny, f.allow, f.apiAllow = deny, allow, apiAllow
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Allowed returns whether an IP is allowed for the admin, or if api is true,
+// for API users.
+func (f *Filter) Allowed(ip string, api bool) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if contains(f.deny, addr) {
+		return false
+	}
+
+	list := f.allow
+	if api && len(f.apiAllow) > 0 {
+		list = f.apiAllow
+	}
+
+	return len(list) == 0 || contains(list, addr)
+}
+
+// ParseCIDRs parses a list of CIDR ranges. Single IPs are treated as /32 (or
+// /128 for IPv6) ranges.
+func ParseCIDRs(list []string) ([]*net.IPNet, error) {
+	out := make([]*net.IPNet, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP: %s", s)
+			}
+
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR: %s", s)
+		}
+		out = append(out, n)
+	}
+
+	return out, nil
+}
+
+func contains(list []*net.IPNet, ip net.IP) bool {
+	for _, n := range list {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+// Package lockout implements in-memory brute-force protection for logins. Failed
+// attempts are counted per key (eg: an IP or a username) and a key is locked out
+// after a number of failures. Every subsequent lockout of the key doubles the
+// lockout duration up to a maximum.
+package lockout
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Opt represents the lockout options.
+type Opt struct {
+	// Number of failed attempts within Window after which a key is locked out.
+	MaxAttempts int
+	Window      time.Duration
+
+	// Duration of the first lockout, and the max. duration of the doubling lockouts.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+// Lockout represents a key's failed attempts and lockout.
+type Lockout struct {
+	Key         string    `json:"key"`
+	Attempts    int       `json:"attempts"`
+	Lockouts    int       `json:"lockouts"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Guard tracks failed attempts and lockouts.
+type Guard struct {
+	opt Opt
+
+	mu      sync.Mutex
+	entries map[string]*Lockout
+}
+
+// Max. number of tracked keys after which expired entries are pruned.
+const pruneSize = 10000
+
+// New returns a new Guard.
+func New(o Opt) *Guard {
+	return &Guard{
+		opt:     o,
+		entries: make(map[string]*Lockout),
+	}
+}
+
+// Locked returns whether a key is locked out and the remaining lockout duration.
+func (g *Guard) Locked(key string) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.entries[key]
+	if !ok {
+		return 0, false
+	}
+
+	if d := time.Until(e.LockedUntil); d > 0 {
+		return d, true
+	}
+
+	return 0, false
+}
+
+// Fail records a failed attempt for a key and returns true if it results in a lockout.
+func (g *Guard) Fail(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if len(g.entries) >= pruneSize {
+		g.prune(now)
+	}
+
+	e, ok := g.entries[key]
+	if !ok {
+		e = &Lockout{Key: key}
+		g.entries[key] = e
+	}
+
+	// Attempts older than the window don't count.
+	if now.Sub(e.LastAttempt) > g.opt.Window {
+		e.Attempts = 0
+	}
+	e.Attempts++
+	e.LastAttempt = now
+
+	if e.Attempts < g.opt.MaxAttempts {
+		return false
+	}
+
+	// Lock out with exponential backoff.
+	d := g.opt.Lockout << e.Lockouts
+	if d > g.opt.MaxLockout || d <= 0 {
+		d = g.opt.MaxLockout
+	}
+	e.Lockouts++
+	e.Attempts = 0
+	e.LockedUntil = now.Add(d)
+
+	return true
+}
+
+// Success clears the failed attempts of a key. The lockout count (backoff) is
+// retained until the key is unlocked or its entry expires.
+func (g *Guard) Success(key string) {
+	g.mu.Lock()
+	if e, ok := g.entries[key]; ok {
+		e.Attempts = 0
+	}
+	g.mu.Unlock()
+}
+
+// Unlock clears the failed attempts and lockouts of a key.
+func (g *Guard) Unlock(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.entries[key]; !ok {
+		return false
+	}
+	delete(g.entries, key)
+
+	return true
+}
+
+// GetAll returns all keys with failed attempts or lockouts, most recent first.
+func (g *Guard) GetAll() []Lockout {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(time.Now())
+
+	out := make([]Lockout, 0, len(g.entries))
+	for _, e := range g.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].LastAttempt.After(out[j].LastAttempt)
+	})
+
+	return out
+}
+
+// prune removes the entries of keys that aren't locked and haven't had
+// an attempt within the window or the max. lockout duration.
+func (g *Guard) prune(now time.Time) {
+	ttl := g.opt.Window
+	if g.opt.MaxLockout > ttl {
+		ttl = g.opt.MaxLockout
+	}
+
+	for k, e := range g.entries {
+		if now.After(e.LockedUntil) && now.Sub(e.LastAttempt) > ttl {
+			delete(g.entries, k)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"errors"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+)
+
+// deferredMessage is a campaign or an arbitrary message that a messenger
+// couldn't send right now.
+type deferredMessage struct {
+	camp *CampaignMessage
+	msg  *models.Message
+}
+
+// deferQueue holds the messages deferred by a messenger's limit (eg: a
+// recipient domain's rate limit) in order and releases them back to the
+// workers one at a time, spaced by the limit's last retry interval, so that
+// they're not all retried at once.
+type deferQueue struct {
+	msgs []deferredMessage
+
+	// at is when the next message is released and step, the gap
+	// between releases.
+	at   time.Time
+	step time.Duration
+}
+
+// deferMessage queues a message deferred by a messenger's limit (key) to be
+// retried after the given interval. It returns false if the limit's queue is
+// full (BatchSize messages), in which case the message isn't queued.
+func (m *Manager) deferMessage(key string, msg deferredMessage, after time.Duration) bool {
+	m.deferredMut.Lock()
+	defer m.deferredMut.Unlock()
+
+	q, ok := m.deferred[key]
+	if ok && len(q.msgs) >= m.cfg.BatchSize {
+		return false
+	}
+	if !ok {
+		q = &deferQueue{}
+		m.deferred[key] = q
+		go m.releaseDeferred(key, q)
+	}
+
+	// The latest retry interval is when the limit's next window opens.
+	q.msgs = append(q.msgs, msg)
+	q.at = time.Now().Add(after)
+	q.step = after
+
+	return true
+}
+
+// releaseDeferred is a blocking function that releases the messages of a
+// deferQueue back to the workers as the limit's windows open, until the
+// queue is empty.
+func (m *Manager) releaseDeferred(key string, q *deferQueue) {
+	for {
+		m.deferredMut.Lock()
+		if len(q.msgs) == 0 {
+			delete(m.deferred, key)
+			m.deferredMut.Unlock()
+			return
+		}
+
+		// The window may have moved while waiting. Check again.
+		if wait := time.Until(q.at); wait > 0 {
+			m.deferredMut.Unlock()
+			time.Sleep(wait)
+			continue
+		}
+
+		msg := q.msgs[0]
+		q.msgs = q.msgs[1:]
+		q.at = time.Now().Add(q.step)
+		m.deferredMut.Unlock()
+
+		if msg.camp != nil {
+			m.campMsgQ <- *msg.camp
+		} else {
+			m.msgQ <- *msg.msg
+		}
+	}
+}
+
+// pushWait pushes a message to a messenger, waiting for as long as the
+// messenger defers it.
+func pushWait(msgr Messenger, msg models.Message, retry *models.RetryError) error {
+	for {
+		time.Sleep(retry.After)
+
+		err := msgr.Push(msg)
+		if !errors.As(err, &retry) {
+			return err
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package manager
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
@@ -8,11 +10,13 @@ import (
 	"net/textproto"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/knadh/listmonk/internal/i18n"
 	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
 )
 
 const (
@@ -33,10 +37,26 @@ type Store interface {
 	GetCampaign(campID int) (*models.Campaign, error)
 	GetAttachment(mediaID int) (models.Attachment, error)
 	UpdateCampaignStatus(campID int, status string) error
+	FreezeCampaignContent(c *models.Campaign) error
+	FreezeCampaignFeed(c *models.Campaign) error
 	UpdateCampaignCounts(campID int, toSend int, sent int, lastSubID int) error
+	QueueCampaignSubscribers(campID int, subIDs []int64) error
+	SetCampaignSTOQueued(campID int) error
+	NextQueuedSubscribers(campID, limit int) ([]models.Subscriber, error)
+	GetCampaignQueueCount(campID int) (int, error)
 	CreateLink(url string) (string, error)
+	CreateLinkSlug(linkUUID, campUUID string) (string, error)
 	BlocklistSubscriber(id int64) error
 	DeleteSubscriber(id int64) error
+	LogCampaignSends(sends []models.CampaignSend) error
+	ArchiveSentMessages(msgs []models.SentMessage) error
+	GetCampaignVariants(campID int) ([]models.CampaignVariant, error)
+	SetCampaignABWaiting(campID int) error
+	NextSequenceMessages(limit int) ([]models.SequenceMessage, error)
+	AdvanceSequenceSubscribers(msgs []models.SequenceMessage) error
+	DeferSequenceSubscribers(msgs []models.SequenceMessage, after time.Duration) error
+	HoldMessage(msg models.Message) error
+	NextHeldMessages(limit int) ([]models.Message, error)
 }
 
 // Messenger is an interface for a generic messaging backend,
@@ -48,6 +68,13 @@ type Messenger interface {
 	Close() error
 }
 
+// AttachmentMessenger is an optional interface implemented by messengers to declare
+// whether they can deliver attachments. Messengers that don't implement it are
+// assumed to support attachments.
+type AttachmentMessenger interface {
+	SupportsAttachments() bool
+}
+
 // CampStats contains campaign stats like per minute send rate.
 type CampStats struct {
 	SendRate int
@@ -76,10 +103,34 @@ type Manager struct {
 	links    map[string]string
 	linksMut sync.RWMutex
 
+	// Short link slugs of links in campaigns (link UUID + campaign UUID).
+	// They share linksMut.
+	slugs map[string]string
+
 	nextPipes chan *pipe
 	campMsgQ  chan CampaignMessage
 	msgQ      chan models.Message
 
+	// Queueing and delivery statuses of campaign messages that are
+	// written to the send log in batches. Resuming campaigns relies on the
+	// send log, so the workers wait if the queue is full. The archive of sent
+	// messages isn't, and archived messages are dropped (and counted) instead.
+	sendLogQ       chan models.CampaignSend
+	archiveQ       chan models.SentMessage
+	archiveDropped atomic.Int64
+
+	// When paused (the global kill switch), campaigns are halted and no new
+	// campaigns are picked up, and arbitrary (transactional) messages are held
+	// in the DB until sending is resumed. holdMut orders holding a message
+	// against resuming so that no message is held after the held ones are released.
+	paused  atomic.Bool
+	holdMut sync.Mutex
+
+	// Messages deferred by messengers (eg: a recipient domain's rate limit)
+	// that are waiting to be retried, by messenger and RetryError key.
+	deferred    map[string]*deferQueue
+	deferredMut sync.Mutex
+
 	// Sliding window keeps track of the total number of messages sent in a period
 	// and on reaching the specified limit, waits until the window is over before
 	// sending further messages.
@@ -101,6 +152,13 @@ type CampaignMessage struct {
 	body     []byte
 	altBody  []byte
 	unsubURL string
+	queuedAt time.Time
+
+	// Whether the message is a retry of a failed send, queued outside a campaign's pipe.
+	requeued bool
+
+	// Whether the message is a step of a drip sequence, queued outside a campaign's pipe.
+	sequenced bool
 
 	pipe *pipe
 }
@@ -118,15 +176,31 @@ type Config struct {
 	RequeueOnError        bool
 	FromEmail             string
 	IndividualTracking    bool
+	AnonymousLinks        bool
 	LinkTrackURL          string
+	ShortLinkURL          string
+	ShortLinks            bool
 	UnsubURL              string
 	OptinURL              string
 	MessageURL            string
 	ViewTrackURL          string
+	SurveyURL             string
 	ArchiveURL            string
 	RootURL               string
 	UnsubHeader           bool
 
+	// Minify the rendered HTML bodies of campaign messages.
+	MinifyHTML bool
+
+	// SentArchive is the mode (models.SentArchive*) in which the content of
+	// the campaign messages sent to subscribers is archived.
+	SentArchive string
+
+	// UTM parameters (source, medium) appended to the links in campaigns
+	// if UTMEnabled or the campaign has it enabled.
+	UTMEnabled bool
+	UTM        models.UTM
+
 	// Interval to scan the DB for active campaign checkpoints.
 	ScanInterval time.Duration
 
@@ -145,6 +219,12 @@ type msgError struct {
 
 var pushTimeout = time.Second * 3
 
+// ErrPaused is returned when a message is pushed while sending is paused.
+var ErrPaused = errors.New("sending is paused")
+
+// sendLogInterval is the interval at which the send log is flushed to the DB.
+const sendLogInterval = time.Second
+
 // New returns a new instance of Mailer.
 func New(cfg Config, store Store, notifCB models.AdminNotifCallback, i *i18n.I18n, l *log.Logger) *Manager {
 	if cfg.BatchSize < 1 {
@@ -167,9 +247,13 @@ func New(cfg Config, store Store, notifCB models.AdminNotifCallback, i *i18n.I18
 		pipes:        make(map[int]*pipe),
 		tpls:         make(map[int]*models.Template),
 		links:        make(map[string]string),
+		slugs:        make(map[string]string),
+		deferred:     make(map[string]*deferQueue),
 		nextPipes:    make(chan *pipe, 1000),
 		campMsgQ:     make(chan CampaignMessage, cfg.Concurrency*cfg.MessageRate*2),
 		msgQ:         make(chan models.Message, cfg.Concurrency*cfg.MessageRate*2),
+		sendLogQ:     make(chan models.CampaignSend, cfg.BatchSize*10),
+		archiveQ:     make(chan models.SentMessage, cfg.BatchSize*10),
 		slidingStart: time.Now(),
 	}
 	m.tplFuncs = m.makeGnericFuncMap()
@@ -188,8 +272,20 @@ func (m *Manager) AddMessenger(msg Messenger) error {
 }
 
 // PushMessage pushes an arbitrary non-campaign Message to be sent out by the workers.
-// It times out if the queue is busy.
+// It times out if the queue is busy. While sending is paused, the message is held
+// in the DB and sent on resuming.
 func (m *Manager) PushMessage(msg models.Message) error {
+	m.holdMut.Lock()
+	if m.paused.Load() {
+		err := m.store.HoldMessage(msg)
+		m.holdMut.Unlock()
+		if err != nil {
+			m.log.Printf("error holding message '%s': %v", msg.Subject, err)
+		}
+		return err
+	}
+	m.holdMut.Unlock()
+
 	t := time.NewTicker(pushTimeout)
 	defer t.Stop()
 
@@ -222,12 +318,136 @@ func (m *Manager) PushCampaignMessage(msg CampaignMessage) error {
 	return nil
 }
 
+// RequeueCampaignMessages renders and queues the messages of a campaign to the
+// given subscribers again, eg: to retry failed sends once the cause of the failures
+// is fixed. The messages are queued in the background and their delivery statuses
+// are recorded in the send log. The campaign's template should be compiled.
+func (m *Manager) RequeueCampaignMessages(c *models.Campaign, subs []models.Subscriber) error {
+	if m.paused.Load() {
+		return ErrPaused
+	}
+	if _, ok := m.messengers[c.Messenger]; !ok {
+		return fmt.Errorf("unknown messenger %s on campaign %s", c.Messenger, c.Name)
+	}
+
+	// Load any media/attachments.
+	if err := m.attachMedia(c); err != nil {
+		return err
+	}
+
+	msgs := make([]CampaignMessage, 0, len(subs))
+	for _, s := range subs {
+		msg, err := m.NewCampaignMessage(c, s)
+		if err != nil {
+			return err
+		}
+		msg.requeued = true
+		msgs = append(msgs, msg)
+	}
+
+	go func() {
+		for _, msg := range msgs {
+			msg.queuedAt = time.Now()
+			m.queueSendLog(models.CampaignSend{
+				CampaignID:   c.ID,
+				SubscriberID: msg.Subscriber.ID,
+				Status:       models.SendStatusQueued,
+				QueuedAt:     null.TimeFrom(msg.queuedAt),
+			})
+			m.campMsgQ <- msg
+		}
+	}()
+
+	return nil
+}
+
+// Pause halts all sending. Running campaigns are stopped (and their queued
+// messages discarded) without changing their status, and running or scheduled
+// campaigns are not picked up until sending is resumed. Campaigns resume from
+// their checkpoint, which is moved back before the discarded messages.
+// Arbitrary messages are held in the DB until sending is resumed.
+func (m *Manager) Pause() {
+	if m.paused.Swap(true) {
+		return
+	}
+
+	m.pipesMut.RLock()
+	for _, p := range m.pipes {
+		p.Stop(false)
+	}
+	m.pipesMut.RUnlock()
+
+	m.log.Println("paused all sending")
+}
+
+// Resume resumes sending after a Pause() and sends the messages held while paused.
+func (m *Manager) Resume() {
+	m.holdMut.Lock()
+	ok := m.paused.Swap(false)
+	m.holdMut.Unlock()
+	if !ok {
+		return
+	}
+
+	m.log.Println("resumed sending")
+	go m.releaseHeld()
+}
+
+// releaseHeld pushes the arbitrary messages held while sending was paused to the
+// workers in the order they were held. If sending is paused again meanwhile, the
+// messages that haven't been pushed yet are held again.
+func (m *Manager) releaseHeld() {
+	for {
+		msgs, err := m.store.NextHeldMessages(m.cfg.BatchSize)
+		if err != nil {
+			m.log.Printf("error fetching held messages: %v", err)
+			return
+		}
+
+		for i, msg := range msgs {
+			if m.paused.Load() {
+				for _, h := range msgs[i:] {
+					if err := m.store.HoldMessage(h); err != nil {
+						m.log.Printf("error holding message '%s': %v", h.Subject, err)
+					}
+				}
+				return
+			}
+
+			m.msgQ <- msg
+		}
+
+		if len(msgs) < m.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// IsPaused returns whether sending is paused.
+func (m *Manager) IsPaused() bool {
+	return m.paused.Load()
+}
+
 // HasMessenger checks if a given messenger is registered.
 func (m *Manager) HasMessenger(id string) bool {
 	_, ok := m.messengers[id]
 	return ok
 }
 
+// MessengerSupportsAttachments checks if a messenger can deliver attachments.
+func (m *Manager) MessengerSupportsAttachments(id string) bool {
+	msgr, ok := m.messengers[id]
+	if !ok {
+		return false
+	}
+
+	if a, ok := msgr.(AttachmentMessenger); ok {
+		return a.SupportsAttachments()
+	}
+
+	return true
+}
+
 // HasRunningCampaigns checks if there are any active campaigns.
 func (m *Manager) HasRunningCampaigns() bool {
 	m.pipesMut.Lock()
@@ -259,6 +479,16 @@ func (m *Manager) Run() {
 		// Periodically scan campaigns and push running campaigns to nextPipes
 		// to fetch subscribers from the campaign.
 		go m.scanCampaigns(m.cfg.ScanInterval)
+
+		// Periodically send the steps of drip sequences that are due.
+		go m.scanSequences(sequenceScanInterval)
+	}
+
+	go m.logSends()
+
+	// Send the messages held by an earlier run that was paused.
+	if !m.paused.Load() {
+		go m.releaseHeld()
 	}
 
 	// Spawn N message workers.
@@ -269,6 +499,22 @@ func (m *Manager) Run() {
 	// Indefinitely wait on the pipe queue to fetch the next set of subscribers
 	// for any active campaigns.
 	for p := range m.nextPipes {
+		// The campaign has been stopped (eg: sending paused). Don't fetch further batches.
+		if p.stopped.Load() {
+			p.wg.Done()
+			continue
+		}
+
+		// A throttled campaign that has sent its messages for the minute waits for
+		// the next one without holding up the other campaigns.
+		if d := p.throttleWait(); d > 0 {
+			go func(p *pipe) {
+				time.Sleep(d)
+				m.nextPipes <- p
+			}(p)
+			continue
+		}
+
 		has, err := p.NextSubscribers()
 		if err != nil {
 			m.log.Printf("error processing campaign batch (%s): %v", p.camp.Name, err)
@@ -316,13 +562,19 @@ func (m *Manager) GetTpl(id int) (*models.Template, error) {
 	return tpl, nil
 }
 
+// SetLinkUTM sets the UTM parameters that are appended to the links in a
+// campaign when it's compiled as per the global and the campaign's settings.
+func (m *Manager) SetLinkUTM(c *models.Campaign) {
+	c.LinkUTM = c.UTMParams(m.cfg.UTMEnabled, m.cfg.UTM)
+}
+
 // TemplateFuncs returns the template functions to be applied into
 // compiled campaign templates.
 func (m *Manager) TemplateFuncs(c *models.Campaign) template.FuncMap {
 	f := template.FuncMap{
 		"TrackLink": func(url string, msg *CampaignMessage) string {
 			subUUID := msg.Subscriber.UUID
-			if !m.cfg.IndividualTracking {
+			if !m.cfg.IndividualTracking || m.cfg.AnonymousLinks {
 				subUUID = dummyUUID
 			}
 
@@ -354,6 +606,22 @@ func (m *Manager) TemplateFuncs(c *models.Campaign) template.FuncMap {
 		"ArchiveURL": func() string {
 			return m.cfg.ArchiveURL
 		},
+		"SurveyRating": func(key string, msg *CampaignMessage) template.HTML {
+			return m.surveyRating(key, msg)
+		},
+		"SurveyChoice": func(key, options string, msg *CampaignMessage) template.HTML {
+			return m.surveyChoice(key, options, msg)
+		},
+		"SurveyURL": func(key, answer string, msg *CampaignMessage) string {
+			return m.surveyURL(key, "a", answer, msg)
+		},
+		"Feed": func(limit ...int) []models.FeedItem {
+			// Optionally, only the first n entries.
+			if len(limit) > 0 && limit[0] >= 0 && limit[0] < len(c.FeedItems) {
+				return c.FeedItems[:limit[0]]
+			}
+			return c.FeedItems
+		},
 		"RootURL": func() string {
 			return m.cfg.RootURL
 		},
@@ -396,6 +664,11 @@ func (m *Manager) scanCampaigns(tick time.Duration) {
 		select {
 		// Periodically scan the data source for campaigns to process.
 		case <-t.C:
+			// Sending is paused. Don't pick up campaigns.
+			if m.paused.Load() {
+				continue
+			}
+
 			ids, counts := m.getCurrentCampaigns()
 			campaigns, err := m.store.NextCampaigns(ids, counts)
 			if err != nil {
@@ -404,6 +677,11 @@ func (m *Manager) scanCampaigns(tick time.Duration) {
 			}
 
 			for _, c := range campaigns {
+				// Campaigns outside their send windows wait for the next one.
+				if !c.InSendWindow(time.Now()) {
+					continue
+				}
+
 				// Create a new pipe that'll handle this campaign's states.
 				p, err := m.newPipe(c)
 				if err != nil {
@@ -437,12 +715,21 @@ func (m *Manager) worker() {
 				return
 			}
 
-			// If the campaign has ended, ignore the message.
-			if msg.pipe != nil && msg.pipe.stopped.Load() {
+			// If the campaign has ended or sending is paused, ignore the message.
+			if msg.pipe != nil && (msg.pipe.stopped.Load() || m.paused.Load()) {
+				msg.pipe.Stop(false)
+				msg.pipe.skip(msg.Subscriber.ID)
 				msg.pipe.wg.Done()
 				continue
 			}
 
+			// Requeued and sequence messages aren't sent while paused either.
+			// They're logged as deferred so that they can be retried.
+			if m.paused.Load() {
+				m.logSend(msg, ErrPaused)
+				continue
+			}
+
 			// Pause on hitting the message rate.
 			if numMsg >= m.cfg.MessageRate {
 				time.Sleep(time.Second)
@@ -473,22 +760,50 @@ func (m *Manager) worker() {
 				h.Set("List-Unsubscribe", `<`+msg.unsubURL+`>`)
 			}
 
-			// Attach any custom headers.
-			if len(msg.Campaign.Headers) > 0 {
-				for _, set := range msg.Campaign.Headers {
+			// Attach the headers of the campaign's header preset, if any,
+			// followed by its custom headers.
+			for _, hdrs := range []models.Headers{msg.Campaign.PresetHeaders, msg.Campaign.Headers} {
+				for _, set := range hdrs {
 					for hdr, val := range set {
 						h.Add(hdr, val)
 					}
 				}
 			}
 
+			// The campaign's Reply-To overrides any custom Reply-To header.
+			if msg.Campaign.ReplyTo != "" {
+				h.Set(models.EmailHeaderReplyTo, msg.Campaign.ReplyToAddress())
+			}
+
+			// The campaign's (or its lists') return path is set as the envelope sender.
+			if rp := msg.Campaign.ReturnPathAddress(); rp != "" {
+				h.Set(models.EmailHeaderReturnPath, rp)
+			}
+
 			out.Headers = h
 
-			err := m.messengers[msg.Campaign.Messenger].Push(out)
+			msgr := m.messengers[msg.Campaign.Messenger]
+			err := msgr.Push(out)
+
+			// The messenger can't send the message right now (eg: a recipient domain's
+			// rate limit). Defer it to be retried, or if too many messages are already
+			// waiting on the same limit, wait for it here, which holds up the campaigns.
+			var retry *models.RetryError
+			if errors.As(err, &retry) {
+				if m.deferMessage(msgr.Name()+":"+retry.Key, deferredMessage{camp: &msg}, retry.After) {
+					continue
+				}
+				err = pushWait(msgr, out, retry)
+			}
+
 			if err != nil {
 				m.log.Printf("error sending message in campaign %s: subscriber %d: %v", msg.Campaign.Name, msg.Subscriber.ID, err)
 			}
 
+			if msg.pipe != nil || msg.requeued || msg.sequenced {
+				m.logSend(msg, err)
+			}
+
 			// Increment the send rate or the error counter if there was an error.
 			if msg.pipe != nil {
 				// Mark the message as done.
@@ -512,7 +827,17 @@ func (m *Manager) worker() {
 				return
 			}
 
-			err := m.messengers[msg.Messenger].Push(msg)
+			msgr := m.messengers[msg.Messenger]
+			err := msgr.Push(msg)
+
+			var retry *models.RetryError
+			if errors.As(err, &retry) {
+				if m.deferMessage(msgr.Name()+":"+retry.Key, deferredMessage{msg: &msg}, retry.After) {
+					continue
+				}
+				err = pushWait(msgr, msg, retry)
+			}
+
 			if err != nil {
 				m.log.Printf("error sending message '%s': %v", msg.Subject, err)
 			}
@@ -571,7 +896,7 @@ func (m *Manager) trackLink(url, campUUID, subUUID string) string {
 	m.linksMut.RLock()
 	if uu, ok := m.links[url]; ok {
 		m.linksMut.RUnlock()
-		return fmt.Sprintf(m.cfg.LinkTrackURL, uu, campUUID, subUUID)
+		return m.trackURL(uu, campUUID, subUUID)
 	}
 	m.linksMut.RUnlock()
 
@@ -588,9 +913,13 @@ func (m *Manager) trackLink(url, campUUID, subUUID string) string {
 	m.links[url] = uu
 	m.linksMut.Unlock()
 
-	return fmt.Sprintf(m.cfg.LinkTrackURL, uu, campUUID, subUUID)
+	return m.trackURL(uu, campUUID, subUUID)
 }
 
+// NotifReasonSendErrors is the reason in the notification of a campaign
+// that's paused because of too many messenger errors.
+const NotifReasonSendErrors = "Too many errors"
+
 // sendNotif sends a notification to registered admin e-mails.
 func (m *Manager) sendNotif(c *models.Campaign, status, reason string) error {
 	var (
@@ -661,3 +990,119 @@ func MakeAttachmentHeader(filename, encoding, contentType string) textproto.MIME
 	h.Set("Content-Transfer-Encoding", encoding)
 	return h
 }
+
+// logSend queues the delivery status of a campaign message to be
+// written to the send log.
+func (m *Manager) logSend(msg CampaignMessage, err error) {
+	s := models.CampaignSend{
+		CampaignID:   msg.Campaign.ID,
+		SubscriberID: msg.Subscriber.ID,
+		Status:       models.SendStatusSent,
+		QueuedAt:     null.TimeFrom(msg.queuedAt),
+		SentAt:       null.TimeFrom(time.Now()),
+	}
+	if err != nil {
+		s.Status = models.SendStatusDeferred
+		s.Response = err.Error()
+	}
+
+	m.queueSendLog(s)
+
+	if err == nil && m.cfg.SentArchive == models.SentArchiveContent {
+		select {
+		case m.archiveQ <- m.makeSentMessage(msg, s.SentAt.Time):
+		default:
+			m.archiveDropped.Add(1)
+		}
+	}
+}
+
+// queueSendLog queues a send log entry to be written. If the queue is full,
+// eg: the DB is slow or down, it blocks until the entry can be queued, which
+// holds up sending instead of losing the entries that resuming campaigns relies on.
+func (m *Manager) queueSendLog(s models.CampaignSend) {
+	m.sendLogQ <- s
+}
+
+// makeSentMessage returns the archive entry of a sent campaign message.
+func (m *Manager) makeSentMessage(msg CampaignMessage, sentAt time.Time) models.SentMessage {
+	h := sha256.Sum256(msg.body)
+
+	return models.SentMessage{
+		CampaignID:      null.IntFrom(msg.Campaign.ID),
+		SubscriberID:    msg.Subscriber.ID,
+		Email:           msg.Subscriber.Email,
+		Subject:         msg.subject,
+		ContentType:     msg.Campaign.ContentType,
+		Body:            string(msg.body),
+		AltBody:         string(msg.altBody),
+		BodyHash:        hex.EncodeToString(h[:]),
+		CampaignVersion: msg.Campaign.Version,
+		SentAt:          null.TimeFrom(sentAt),
+	}
+}
+
+// logSends is a blocking function that collects queued and sent statuses
+// of campaign messages and periodically writes them to the send log in batches,
+// and the same for the archive of sent messages.
+func (m *Manager) logSends() {
+	var (
+		buf  = make([]models.CampaignSend, 0, m.cfg.BatchSize)
+		arch = make([]models.SentMessage, 0, m.cfg.BatchSize)
+		t    = time.NewTicker(sendLogInterval)
+	)
+	defer t.Stop()
+
+	// Entries that can't be written are kept and retried on the next flush.
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		if err := m.store.LogCampaignSends(buf); err != nil {
+			m.log.Printf("error writing %d entries to the campaign send log: %v", len(buf), err)
+			return
+		}
+		buf = buf[:0]
+	}
+
+	flushArchive := func() {
+		if len(arch) == 0 {
+			return
+		}
+		if err := m.store.ArchiveSentMessages(arch); err != nil {
+			m.log.Printf("error archiving %d sent messages: %v", len(arch), err)
+		}
+		arch = arch[:0]
+	}
+
+	for {
+		// While a full batch can't be written, stop taking entries so that the
+		// queue fills up and the workers wait.
+		q := m.sendLogQ
+		if len(buf) >= m.cfg.BatchSize {
+			q = nil
+		}
+
+		select {
+		case s := <-q:
+			buf = append(buf, s)
+			if len(buf) >= m.cfg.BatchSize {
+				flush()
+			}
+
+		case a := <-m.archiveQ:
+			arch = append(arch, a)
+			if len(arch) >= m.cfg.BatchSize {
+				flushArchive()
+			}
+
+		case <-t.C:
+			flush()
+			flushArchive()
+
+			if n := m.archiveDropped.Swap(0); n > 0 {
+				m.log.Printf("sent message archive queue is full. dropped %d messages", n)
+			}
+		}
+	}
+}
@@ -25,6 +25,10 @@ func (m *Manager) NewCampaignMessage(c *models.Campaign, s models.Subscriber) (C
 		return msg, err
 	}
 
+	if m.cfg.MinifyHTML && c.ContentType != models.CampaignContentTypePlain {
+		msg.body = MinifyHTML(msg.body)
+	}
+
 	return msg, nil
 }
 
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	// Blocks whose whitespace is significant and is left untouched.
+	regexpMinifyKeep = regexp.MustCompile(`(?is)<(pre|textarea)\b.*?</(pre|textarea)>`)
+
+	regexpMinifyComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	regexpMinifySpace   = regexp.MustCompile(`[ \t\r\n]+`)
+)
+
+// MinifyHTML does a conservative minification pass on a rendered HTML body to
+// keep it under size limits such as Gmail's ~102KB clipping. It removes comments
+// (except Outlook conditional comments) and collapses runs of whitespace. Runs
+// containing a line break are collapsed to a single line break to keep lines short.
+func MinifyHTML(b []byte) []byte {
+	out := bytes.Buffer{}
+	out.Grow(len(b))
+
+	for len(b) > 0 {
+		loc := regexpMinifyKeep.FindIndex(b)
+		if loc == nil {
+			out.Write(minifyChunk(b))
+			break
+		}
+
+		out.Write(minifyChunk(b[:loc[0]]))
+		out.Write(b[loc[0]:loc[1]])
+		b = b[loc[1]:]
+	}
+
+	return out.Bytes()
+}
+
+func minifyChunk(b []byte) []byte {
+	b = regexpMinifyComment.ReplaceAllFunc(b, func(c []byte) []byte {
+		// <!--[if mso]> ... <![endif]--> and <!--<![endif]-->.
+		if bytes.HasPrefix(c, []byte("<!--[")) || bytes.HasPrefix(c, []byte("<!--<")) {
+			return c
+		}
+		return nil
+	})
+
+	return regexpMinifySpace.ReplaceAllFunc(b, func(s []byte) []byte {
+		if bytes.IndexByte(s, '\n') >= 0 {
+			return []byte("\n")
+		}
+		return []byte(" ")
+	})
+}
@@ -8,10 +8,12 @@ import (
 
 	"github.com/knadh/listmonk/models"
 	"github.com/paulbellamy/ratecounter"
+	null "gopkg.in/volatiletech/null.v6"
 )
 
 type pipe struct {
 	camp       *models.Campaign
+	variants   []*models.Campaign
 	rate       *ratecounter.RateCounter
 	wg         *sync.WaitGroup
 	sent       atomic.Int64
@@ -20,6 +22,19 @@ type pipe struct {
 	stopped    atomic.Bool
 	withErrors atomic.Bool
 
+	// Set when the pipe has ended as the campaign is outside its send windows.
+	outsideWindow atomic.Bool
+
+	// Subscribers whose queued messages were discarded as the campaign was
+	// stopped (eg: sending paused). They're not lost on ending the pipe.
+	skipped    []int64
+	skippedMut sync.Mutex
+
+	// Messages pushed in the current minute for the campaign's throttle. They're
+	// only accessed by the manager's pipe loop.
+	rateStart time.Time
+	rateCount int
+
 	m *Manager
 }
 
@@ -31,24 +46,78 @@ func (m *Manager) newPipe(c *models.Campaign) (*pipe, error) {
 		return nil, fmt.Errorf("unknown messenger %s on campaign %s", c.Messenger, c.Name)
 	}
 
+	// If the body is sourced from a remote URL, fetch and freeze it on the first run.
+	// Resumed campaigns already have a checksum and continue with the frozen body.
+	if c.ContentURL != "" && c.ContentChecksum == "" {
+		if err := m.store.FreezeCampaignContent(c); err != nil {
+			m.store.UpdateCampaignStatus(c.ID, models.CampaignStatusPaused)
+			m.sendNotif(c, models.CampaignStatusPaused, err.Error())
+			return nil, fmt.Errorf("error fetching content for campaign %s: %v", c.Name, err)
+		}
+	}
+
+	// Likewise, the entries of a feed are fetched and frozen on the first run.
+	if c.FeedURL != "" && c.FeedItems == nil {
+		if err := m.store.FreezeCampaignFeed(c); err != nil {
+			m.store.UpdateCampaignStatus(c.ID, models.CampaignStatusPaused)
+			m.sendNotif(c, models.CampaignStatusPaused, err.Error())
+			return nil, fmt.Errorf("error fetching feed for campaign %s: %v", c.Name, err)
+		}
+
+		// A run of a recurring campaign with no new entries since the previous
+		// run has nothing to send.
+		if c.FeedSince.Valid && len(c.FeedItems) == 0 {
+			m.store.UpdateCampaignStatus(c.ID, models.CampaignStatusCancelled)
+			m.sendNotif(c, models.CampaignStatusCancelled, m.i18n.T("campaigns.noNewFeedItems"))
+			return nil, fmt.Errorf("no new feed entries for campaign %s", c.Name)
+		}
+	}
+
+	// Load the variants of the campaign's A/B test.
+	vars, err := m.loadVariants(c)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load the template.
+	m.SetLinkUTM(c)
 	if err := c.CompileTemplate(m.TemplateFuncs(c)); err != nil {
 		return nil, err
 	}
 
 	// Load any media/attachments.
+	if len(c.MediaIDs) > 0 && !m.MessengerSupportsAttachments(c.Messenger) {
+		return nil, fmt.Errorf("messenger %s doesn't support attachments", c.Messenger)
+	}
 	if err := m.attachMedia(c); err != nil {
 		return nil, err
 	}
 
+	// The variants of an A/B test being sent are copies of the campaign
+	// with the variants' subjects and bodies.
+	variants := make([]*models.Campaign, 0, len(vars))
+	for _, v := range vars {
+		vc := *c
+		applyVariant(&vc, v)
+		if err := vc.CompileTemplate(m.TemplateFuncs(&vc)); err != nil {
+			return nil, fmt.Errorf("error compiling variant %s of campaign %s: %v", v.Name, c.Name, err)
+		}
+		variants = append(variants, &vc)
+	}
+
 	// Add the campaign to the active map.
 	p := &pipe{
-		camp: c,
-		rate: ratecounter.NewRateCounter(time.Minute),
-		wg:   &sync.WaitGroup{},
-		m:    m,
+		camp:     c,
+		variants: variants,
+		rate:     ratecounter.NewRateCounter(time.Minute),
+		wg:       &sync.WaitGroup{},
+		m:        m,
 	}
 
+	// Start from the campaign's checkpoint so that the checkpoint is retained
+	// if the campaign is stopped before any message is sent.
+	p.lastID.Store(uint64(c.LastSubscriberID))
+
 	// Increment the waitgroup so that Wait() blocks immediately. This is necessary
 	// as a campaign pipe is created first and subscribers/messages under it are
 	// fetched asynchronolusly later. The messages each add to the wg and that
@@ -67,6 +136,12 @@ func (m *Manager) newPipe(c *models.Campaign) (*pipe, error) {
 	m.pipesMut.Lock()
 	m.pipes[c.ID] = p
 	m.pipesMut.Unlock()
+
+	// Sending was paused while the pipe was being created.
+	if m.paused.Load() {
+		p.Stop(false)
+	}
+
 	return p, nil
 }
 
@@ -75,8 +150,21 @@ func (m *Manager) newPipe(c *models.Campaign) (*pipe, error) {
 // in the current batch or not. A false indicates that all subscribers
 // have been processed, or that a campaign has been paused or cancelled.
 func (p *pipe) NextSubscribers() (bool, error) {
+	// Outside the campaign's send windows, stop fetching subscribers. The campaign
+	// remains running and is resumed in its next window.
+	if !p.camp.InSendWindow(time.Now()) {
+		p.outsideWindow.Store(true)
+		return false, nil
+	}
+
+	// Campaigns with send-time optimization or sent at local time queue
+	// their subscribers and are sent to the ones whose send time is due.
+	if p.isQueued() {
+		return p.nextQueuedSubscribers()
+	}
+
 	// Fetch a batch of subscribers.
-	subs, err := p.m.store.NextSubscribers(p.camp.ID, p.m.cfg.BatchSize)
+	subs, err := p.m.store.NextSubscribers(p.camp.ID, p.batchSize())
 	if err != nil {
 		return false, fmt.Errorf("error fetching campaign subscribers (%s): %v", p.camp.Name, err)
 	}
@@ -86,6 +174,95 @@ func (p *pipe) NextSubscribers() (bool, error) {
 		return false, nil
 	}
 
+	p.push(subs)
+	return true, nil
+}
+
+// nextQueuedSubscribers queues the next batch of subscribers of a campaign with
+// send-time optimization or sent at local time. Once all the subscribers are queued, it processes the
+// next batch of queued subscribers whose send time is due.
+func (p *pipe) nextQueuedSubscribers() (bool, error) {
+	if !p.camp.STOQueuedAt.Valid {
+		subs, err := p.m.store.NextSubscribers(p.camp.ID, p.m.cfg.BatchSize)
+		if err != nil {
+			return false, fmt.Errorf("error fetching campaign subscribers (%s): %v", p.camp.Name, err)
+		}
+
+		if len(subs) > 0 {
+			ids := make([]int64, 0, len(subs))
+			for _, s := range subs {
+				ids = append(ids, int64(s.ID))
+			}
+
+			if err := p.m.store.QueueCampaignSubscribers(p.camp.ID, ids); err != nil {
+				return false, fmt.Errorf("error queueing campaign subscribers (%s): %v", p.camp.Name, err)
+			}
+
+			// The checkpoint has moved past the queued subscribers. Retain it
+			// so that they're not queued again when the pipe ends.
+			if id := uint64(subs[len(subs)-1].ID); id > p.lastID.Load() {
+				p.lastID.Store(id)
+			}
+			return true, nil
+		}
+
+		if err := p.m.store.SetCampaignSTOQueued(p.camp.ID); err != nil {
+			return false, fmt.Errorf("error updating campaign (%s): %v", p.camp.Name, err)
+		}
+		p.camp.STOQueuedAt = null.TimeFrom(time.Now())
+	}
+
+	subs, err := p.m.store.NextQueuedSubscribers(p.camp.ID, p.batchSize())
+	if err != nil {
+		return false, fmt.Errorf("error fetching queued campaign subscribers (%s): %v", p.camp.Name, err)
+	}
+
+	if len(subs) == 0 {
+		return false, nil
+	}
+
+	p.push(subs)
+	return true, nil
+}
+
+// batchSize returns the number of subscribers to fetch in the next batch, which
+// is limited by the messages left in the current minute of the campaign's throttle.
+func (p *pipe) batchSize() int {
+	if p.camp.SendRate < 1 {
+		return p.m.cfg.BatchSize
+	}
+
+	n := p.camp.SendRate - p.rateCount
+	if n > p.m.cfg.BatchSize {
+		return p.m.cfg.BatchSize
+	}
+	return n
+}
+
+// throttleWait returns the duration to wait for the next minute if the campaign
+// has pushed all the messages its throttle allows in the current one.
+func (p *pipe) throttleWait() time.Duration {
+	if p.camp.SendRate < 1 {
+		return 0
+	}
+
+	since := time.Since(p.rateStart)
+	if since >= time.Minute {
+		p.rateStart = time.Now()
+		p.rateCount = 0
+		return 0
+	}
+
+	if p.rateCount >= p.camp.SendRate {
+		return time.Minute - since
+	}
+	return 0
+}
+
+// push renders and pushes messages for a batch of subscribers to the message queue.
+func (p *pipe) push(subs []models.Subscriber) {
+	p.rateCount += len(subs)
+
 	// Is there a sliding window limit configured?
 	hasSliding := p.m.cfg.SlidingWindow &&
 		p.m.cfg.SlidingWindowRate > 0 &&
@@ -130,8 +307,6 @@ func (p *pipe) NextSubscribers() (bool, error) {
 			}
 		}
 	}
-
-	return true, nil
 }
 
 func (p *pipe) OnError() {
@@ -149,6 +324,12 @@ func (p *pipe) OnError() {
 	p.m.log.Printf("error count exceeded %d. pausing campaign %s", p.m.cfg.MaxSendErrors, p.camp.Name)
 }
 
+// isQueued returns true if the campaign's subscribers are queued to be sent
+// at their own send times (send-time optimization or local time sending).
+func (p *pipe) isQueued() bool {
+	return p.camp.STOWindowHours > 0 || p.camp.SendAtLocal
+}
+
 // Stop "marks" a campaign as stopped. It doesn't actually stop the processing
 // of messages. That happens when every queued message in the campaign is processed,
 // marking .wg, the waitgroup counter as done. That triggers cleanup().
@@ -165,15 +346,68 @@ func (p *pipe) Stop(withErrors bool) {
 	p.stopped.Store(true)
 }
 
+// skip records a subscriber whose queued message was discarded.
+func (p *pipe) skip(subID int) {
+	p.skippedMut.Lock()
+	p.skipped = append(p.skipped, int64(subID))
+	p.skippedMut.Unlock()
+}
+
+// checkpoint returns the subscriber ID that the campaign resumes after. Messages
+// discarded after stopping may be below the last sent subscriber's ID, in which case
+// the checkpoint is moved back before them. The subscribers in between that were sent
+// messages are skipped on resuming by their send log entries (next-campaign-subscribers).
+// Campaigns with queued subscribers have the discarded ones queued again instead.
+func (p *pipe) checkpoint() int {
+	p.skippedMut.Lock()
+	skipped := p.skipped
+	p.skippedMut.Unlock()
+
+	lastID := int(p.lastID.Load())
+	if len(skipped) == 0 {
+		return lastID
+	}
+
+	if p.isQueued() {
+		if err := p.m.store.QueueCampaignSubscribers(p.camp.ID, skipped); err != nil {
+			p.m.log.Printf("error queueing discarded subscribers of campaign (%s): %v", p.camp.Name, err)
+		}
+		return lastID
+	}
+
+	for _, id := range skipped {
+		if int(id)-1 < lastID {
+			lastID = int(id) - 1
+		}
+	}
+	return lastID
+}
+
 func (p *pipe) newMessage(s models.Subscriber) (CampaignMessage, error) {
-	msg, err := p.m.NewCampaignMessage(p.camp, s)
+	// Subscribers in the test group of an A/B test get one of the variants.
+	c := p.camp
+	if len(p.variants) > 0 {
+		if n, ok := models.ABTestVariant(s.ID, c.ABTestPercent, len(p.variants)); ok {
+			c = p.variants[n]
+		}
+	}
+
+	msg, err := p.m.NewCampaignMessage(c, s)
 	if err != nil {
 		return msg, err
 	}
 
 	msg.pipe = p
+	msg.queuedAt = time.Now()
 	p.wg.Add(1)
 
+	p.m.queueSendLog(models.CampaignSend{
+		CampaignID:   p.camp.ID,
+		SubscriberID: s.ID,
+		Status:       models.SendStatusQueued,
+		QueuedAt:     null.TimeFrom(msg.queuedAt),
+	})
+
 	return msg, nil
 }
 
@@ -185,7 +419,7 @@ func (p *pipe) cleanup() {
 	}()
 
 	// Update campaign's "sent" count.
-	if err := p.m.store.UpdateCampaignCounts(p.camp.ID, 0, int(p.sent.Load()), int(p.lastID.Load())); err != nil {
+	if err := p.m.store.UpdateCampaignCounts(p.camp.ID, 0, int(p.sent.Load()), p.checkpoint()); err != nil {
 		p.m.log.Printf("error updating campaign counts (%s): %v", p.camp.Name, err)
 	}
 
@@ -197,7 +431,14 @@ func (p *pipe) cleanup() {
 			p.m.log.Printf("set campaign (%s) to %s", p.camp.Name, models.CampaignStatusPaused)
 		}
 
-		_ = p.m.sendNotif(p.camp, models.CampaignStatusPaused, "Too many errors")
+		_ = p.m.sendNotif(p.camp, models.CampaignStatusPaused, NotifReasonSendErrors)
+		return
+	}
+
+	// Sending was paused. The campaign remains running and resumes from
+	// its checkpoint when sending is resumed.
+	if p.m.paused.Load() {
+		p.m.log.Printf("halted campaign (%s) as sending is paused", p.camp.Name)
 		return
 	}
 
@@ -208,6 +449,38 @@ func (p *pipe) cleanup() {
 		return
 	}
 
+	// A running campaign that has exhausted the test group of its A/B test waits
+	// for a winner to be picked before it's sent to the rest of the subscribers.
+	if c.Status == models.CampaignStatusRunning && c.ABPhase == models.CampaignABPhaseTesting {
+		if err := p.m.store.SetCampaignABWaiting(p.camp.ID); err != nil {
+			p.m.log.Printf("error updating campaign (%s) A/B test: %v", p.camp.Name, err)
+		} else {
+			p.m.log.Printf("sent A/B test of campaign (%s). waiting %d hour(s) to pick a winner", p.camp.Name, c.ABWaitHours)
+		}
+		return
+	}
+
+	// A running campaign that has stopped outside its send windows waits for the
+	// next one. next-campaigns picks it up again and it's resumed in the window.
+	if c.Status == models.CampaignStatusRunning && p.outsideWindow.Load() {
+		p.m.log.Printf("campaign (%s) waiting for its next send window", p.camp.Name)
+		return
+	}
+
+	// A running campaign with queued subscribers that aren't due yet waits
+	// for them. next-campaigns picks it up again when they are.
+	if c.Status == models.CampaignStatusRunning && p.isQueued() {
+		n, err := p.m.store.GetCampaignQueueCount(p.camp.ID)
+		if err != nil {
+			p.m.log.Printf("error fetching queue of campaign (%s): %v", p.camp.Name, err)
+			return
+		}
+		if n > 0 {
+			p.m.log.Printf("campaign (%s) waiting to send to %d queued subscriber(s)", p.camp.Name, n)
+			return
+		}
+	}
+
 	// If a running campaign has exhausted subscribers, it's finished.
 	if c.Status == models.CampaignStatusRunning {
 		c.Status = models.CampaignStatusFinished
@@ -223,3 +496,45 @@ func (p *pipe) cleanup() {
 	// Notify the admin.
 	_ = p.m.sendNotif(c, c.Status, "")
 }
+
+// loadVariants returns the variants of a campaign's A/B test that's to be sent.
+// If the test's winner has been picked, the campaign's subject and body are
+// replaced with the winner's instead.
+func (m *Manager) loadVariants(c *models.Campaign) ([]models.CampaignVariant, error) {
+	if c.ABTestPercent < 1 || (c.ABPhase != "" && c.ABPhase != models.CampaignABPhaseTesting && c.ABPhase != models.CampaignABPhaseWinner) {
+		return nil, nil
+	}
+
+	vars, err := m.store.GetCampaignVariants(c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching variants of campaign %s: %v", c.Name, err)
+	}
+
+	if c.ABPhase == models.CampaignABPhaseWinner {
+		for _, v := range vars {
+			if v.IsWinner {
+				applyVariant(c, v)
+				break
+			}
+		}
+		return nil, nil
+	}
+
+	// A test needs at least two variants. next-campaigns starts
+	// the test under the same condition.
+	if len(vars) < 2 {
+		return nil, nil
+	}
+	c.ABPhase = models.CampaignABPhaseTesting
+
+	return vars, nil
+}
+
+// applyVariant sets the subject and body of a variant on a campaign.
+// A variant without a body uses the campaign's body.
+func applyVariant(c *models.Campaign, v models.CampaignVariant) {
+	c.Subject = v.Subject
+	if v.Body != "" {
+		c.Body = v.Body
+	}
+}
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+const (
+	// sequenceScanInterval is the interval at which drip sequences are scanned
+	// for steps that are due to be sent to subscribers.
+	sequenceScanInterval = time.Minute
+
+	// sequenceRetryInterval is the interval after which steps that failed to be
+	// queued, eg: because their campaign couldn't be loaded, are retried.
+	sequenceRetryInterval = time.Minute * 10
+)
+
+// scanSequences is a blocking function that periodically advances subscribers
+// through drip sequences. Every step that's due is sent to its subscriber with the
+// step campaign's content and the subscriber moves on to the next step once the
+// message is queued. Steps that fail to be queued are parked and retried after
+// sequenceRetryInterval so that they don't hold up the other steps that are due.
+func (m *Manager) scanSequences(tick time.Duration) {
+	t := time.NewTicker(tick)
+	defer t.Stop()
+
+	for range t.C {
+		// Sending is paused. Steps remain due until it's resumed.
+		if m.paused.Load() {
+			continue
+		}
+
+		// Step campaigns are loaded once per scan.
+		camps := map[int]*models.Campaign{}
+		for {
+			msgs, err := m.store.NextSequenceMessages(m.cfg.BatchSize)
+			if err != nil {
+				m.log.Printf("error fetching sequence messages: %v", err)
+				break
+			}
+
+			var (
+				queued = make([]models.SequenceMessage, 0, len(msgs))
+				failed []models.SequenceMessage
+			)
+			for _, s := range msgs {
+				c, ok := camps[s.CampaignID]
+				if !ok {
+					c, err = m.loadSequenceCampaign(s.CampaignID)
+					if err != nil {
+						m.log.Printf("error loading campaign %d of sequence %d: %v", s.CampaignID, s.SequenceID, err)
+					}
+					camps[s.CampaignID] = c
+				}
+				if c == nil {
+					failed = append(failed, s)
+					continue
+				}
+
+				msg, err := m.NewCampaignMessage(c, s.Subscriber)
+				if err != nil {
+					m.log.Printf("error rendering message (%s) (%s): %v", c.Name, s.Email, err)
+					failed = append(failed, s)
+					continue
+				}
+				msg.sequenced = true
+				msg.queuedAt = time.Now()
+
+				m.queueSendLog(models.CampaignSend{
+					CampaignID:   c.ID,
+					SubscriberID: s.ID,
+					Status:       models.SendStatusQueued,
+					QueuedAt:     null.TimeFrom(msg.queuedAt),
+				})
+				m.campMsgQ <- msg
+				queued = append(queued, s)
+			}
+
+			if len(queued) > 0 {
+				if err := m.store.AdvanceSequenceSubscribers(queued); err != nil {
+					m.log.Printf("error advancing sequence subscribers: %v", err)
+					break
+				}
+			}
+
+			// Park the steps that failed so that the next batch moves on to the other due steps.
+			if len(failed) > 0 {
+				if err := m.store.DeferSequenceSubscribers(failed, sequenceRetryInterval); err != nil {
+					m.log.Printf("error deferring sequence subscribers: %v", err)
+					break
+				}
+			}
+
+			if len(msgs) < m.cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// loadSequenceCampaign fetches a step campaign of a sequence and compiles
+// its templates for sending.
+func (m *Manager) loadSequenceCampaign(id int) (*models.Campaign, error) {
+	c, err := m.store.GetCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := m.messengers[c.Messenger]; !ok {
+		return nil, fmt.Errorf("unknown messenger %s on campaign %s", c.Messenger, c.Name)
+	}
+
+	if c.ContentURL != "" && c.ContentChecksum == "" {
+		if err := m.store.FreezeCampaignContent(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.FeedURL != "" && c.FeedItems == nil {
+		if err := m.store.FreezeCampaignFeed(c); err != nil {
+			return nil, err
+		}
+	}
+
+	m.SetLinkUTM(c)
+	if err := c.CompileTemplate(m.TemplateFuncs(c)); err != nil {
+		return nil, err
+	}
+
+	if err := m.attachMedia(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// shortUUIDLen is the length of a base62 encoded 128 bit UUID.
+	shortUUIDLen = 22
+)
+
+var errShortUUID = errors.New("invalid short UUID")
+
+// ShortUUID encodes a UUID string into a compact, fixed length base62 string
+// for use in short links.
+func ShortUUID(u string) (string, error) {
+	uu, err := uuid.FromString(u)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		n    = new(big.Int).SetBytes(uu.Bytes())
+		base = big.NewInt(int64(len(base62Chars)))
+		mod  = new(big.Int)
+		out  = make([]byte, shortUUIDLen)
+	)
+	for i := shortUUIDLen - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62Chars[mod.Int64()]
+	}
+
+	return string(out), nil
+}
+
+// ParseShortUUID decodes a base62 string encoded by ShortUUID into a UUID string.
+func ParseShortUUID(s string) (string, error) {
+	if len(s) != shortUUIDLen {
+		return "", errShortUUID
+	}
+
+	var (
+		n    = new(big.Int)
+		base = big.NewInt(int64(len(base62Chars)))
+	)
+	for _, c := range s {
+		i := strings.IndexRune(base62Chars, c)
+		if i < 0 {
+			return "", errShortUUID
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(i)))
+	}
+
+	b := n.Bytes()
+	if len(b) > 16 {
+		return "", errShortUUID
+	}
+
+	// Left pad to 16 bytes.
+	var raw [16]byte
+	copy(raw[16-len(b):], b)
+
+	return uuid.UUID(raw).String(), nil
+}
+
+// trackURL returns the tracking URL of a registered link (UUID) in a campaign,
+// which is a short link if they're enabled.
+func (m *Manager) trackURL(linkUUID, campUUID, subUUID string) string {
+	if m.cfg.ShortLinks && campUUID != dummyUUID {
+		return m.shortLink(linkUUID, campUUID, subUUID)
+	}
+
+	return fmt.Sprintf(m.cfg.LinkTrackURL, linkUUID, campUUID, subUUID)
+}
+
+// shortLink returns the short URL of a tracked link (UUID) in a campaign,
+// eg: root.com/l/{slug}/{short_subscriber_uuid}. The subscriber is omitted
+// when it's not tracked (dummy). On errors, the long tracking URL is returned.
+func (m *Manager) shortLink(linkUUID, campUUID, subUUID string) string {
+	long := fmt.Sprintf(m.cfg.LinkTrackURL, linkUUID, campUUID, subUUID)
+
+	key := linkUUID + campUUID
+	m.linksMut.RLock()
+	slug, ok := m.slugs[key]
+	m.linksMut.RUnlock()
+
+	if !ok {
+		s, err := m.store.CreateLinkSlug(linkUUID, campUUID)
+		if err != nil {
+			m.log.Printf("error creating short link for link %s: %v", linkUUID, err)
+			return long
+		}
+		slug = s
+
+		m.linksMut.Lock()
+		m.slugs[key] = slug
+		m.linksMut.Unlock()
+	}
+
+	if subUUID == dummyUUID {
+		return fmt.Sprintf(m.cfg.ShortLinkURL, slug)
+	}
+
+	sub, err := ShortUUID(subUUID)
+	if err != nil {
+		return long
+	}
+
+	return fmt.Sprintf(m.cfg.ShortLinkURL, slug) + "/" + sub
+}
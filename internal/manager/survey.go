@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// surveyMaxScore is the highest score of rating questions that are scored 1 to n.
+	surveyMaxScore = 10
+
+	surveyBtnStyle = `display: inline-block; min-width: 24px; padding: 6px 8px; margin: 2px; ` +
+		`border: 1px solid #ccc; border-radius: 3px; text-align: center; text-decoration: none;`
+)
+
+// surveyURL returns the URL that records the given answer to a survey question (key).
+// Rating questions record a score with the `s` param and choices an answer with `a`.
+func (m *Manager) surveyURL(key, param, answer string, msg *CampaignMessage) string {
+	subUUID := msg.Subscriber.UUID
+	if !m.cfg.IndividualTracking {
+		subUUID = dummyUUID
+	}
+
+	return fmt.Sprintf(m.cfg.SurveyURL, msg.Campaign.UUID, subUUID, url.PathEscape(key)) +
+		"?" + param + "=" + url.QueryEscape(answer)
+}
+
+// surveyRating renders a survey question (key) as a row of 1-10 score links.
+func (m *Manager) surveyRating(key string, msg *CampaignMessage) template.HTML {
+	var b strings.Builder
+	b.WriteString(`<table class="survey survey-rating" role="presentation" cellpadding="0" cellspacing="0"><tr>`)
+	for i := 1; i <= surveyMaxScore; i++ {
+		n := strconv.Itoa(i)
+		fmt.Fprintf(&b, `<td><a href="%s" style="%s">%s</a></td>`,
+			html.EscapeString(m.surveyURL(key, "s", n, msg)), surveyBtnStyle, n)
+	}
+	b.WriteString(`</tr></table>`)
+
+	return template.HTML(b.String())
+}
+
+// surveyChoice renders a survey question (key) as a list of links for the
+// given pipe separated options, eg: "Daily|Weekly|Monthly".
+func (m *Manager) surveyChoice(key, options string, msg *CampaignMessage) template.HTML {
+	var b strings.Builder
+	b.WriteString(`<div class="survey survey-choice">`)
+	for _, o := range strings.Split(options, "|") {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+
+		fmt.Fprintf(&b, `<a href="%s" style="%s">%s</a> `,
+			html.EscapeString(m.surveyURL(key, "a", o, msg)), surveyBtnStyle, html.EscapeString(o))
+	}
+	b.WriteString(`</div>`)
+
+	return template.HTML(b.String())
+}
@@ -23,6 +23,18 @@ type Media struct {
 	Total int `db:"total" json:"-"`
 }
 
+// Quarantined represents an uploaded object that was flagged by the scanner
+// and is held in the DB until it's released to the store or deleted.
+type Quarantined struct {
+	ID          int       `db:"id" json:"id"`
+	Filename    string    `db:"filename" json:"filename"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Signature   string    `db:"signature" json:"signature"`
+	Size        int       `db:"size" json:"size"`
+	Body        []byte    `db:"body" json:"-"`
+	CreatedAt   null.Time `db:"created_at" json:"created_at"`
+}
+
 // Store represents functions to store and retrieve media (files).
 type Store interface {
 	Put(string, string, io.ReadSeeker) (string, error)
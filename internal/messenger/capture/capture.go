@@ -0,0 +1,79 @@
+// Package capture implements a messenger that stores fully rendered outbound
+// messages instead of delivering them, for testing messages end-to-end in
+// staging environments.
+package capture
+
+import (
+	"encoding/json"
+
+	"github.com/knadh/listmonk/internal/messenger/email"
+	"github.com/knadh/listmonk/models"
+	null "gopkg.in/volatiletech/null.v6"
+)
+
+// Name is the name of the built-in capture messenger.
+const Name = "capture"
+
+// StoreFunc stores a captured message.
+type StoreFunc func(models.CapturedMessage) error
+
+// Capture is a messenger that captures messages.
+type Capture struct {
+	name  string
+	store StoreFunc
+}
+
+// New returns a new capture messenger with the given name. In capture mode,
+// messengers are replaced with capture messengers with the same names.
+func New(name string, store StoreFunc) *Capture {
+	return &Capture{name: name, store: store}
+}
+
+// Name returns the messenger's name.
+func (c *Capture) Name() string {
+	return c.name
+}
+
+// Push renders a message as an e-mail and stores it.
+func (c *Capture) Push(m models.Message) error {
+	em := email.MakeEmail(m, nil)
+	raw, err := em.Bytes()
+	if err != nil {
+		return err
+	}
+
+	hdr, err := json.Marshal(em.Headers)
+	if err != nil {
+		return err
+	}
+
+	out := models.CapturedMessage{
+		Messenger:   c.name,
+		FromEmail:   m.From,
+		To:          m.To,
+		Subject:     m.Subject,
+		ContentType: m.ContentType,
+		Body:        string(m.Body),
+		AltBody:     string(m.AltBody),
+		Headers:     hdr,
+		Raw:         string(raw),
+	}
+	if m.Campaign != nil {
+		out.CampaignID = null.IntFrom(m.Campaign.ID)
+	}
+	if m.Subscriber.ID > 0 {
+		out.SubscriberID = null.IntFrom(m.Subscriber.ID)
+	}
+
+	return c.store(out)
+}
+
+// Flush is a no-op.
+func (c *Capture) Flush() error {
+	return nil
+}
+
+// Close is a no-op.
+func (c *Capture) Close() error {
+	return nil
+}
@@ -21,6 +21,14 @@ const (
 
 // Server represents an SMTP server's credentials.
 type Server struct {
+	// Name, if set, registers the server as a separate messenger, email-{name},
+	// in addition to the default pool of all servers.
+	Name string `json:"name"`
+
+	// SourceIP is the outbound IP the server (relay) delivers from. It's only
+	// used to label the server's sending volume.
+	SourceIP string `json:"source_ip"`
+
 	Username      string            `json:"username"`
 	Password      string            `json:"password"`
 	AuthProtocol  string            `json:"auth_protocol"`
@@ -28,21 +36,31 @@ type Server struct {
 	TLSSkipVerify bool              `json:"tls_skip_verify"`
 	EmailHeaders  map[string]string `json:"email_headers"`
 
+	// DomainLimits are the optional per-recipient-domain sending limits.
+	DomainLimits []DomainLimit `json:"domain_limits"`
+
 	// Rest of the options are embedded directly from the smtppool lib.
 	// The JSON tag is for config unmarshal to work.
 	smtppool.Opt `json:",squash"`
 
-	pool *smtppool.Pool
+	pool   *smtppool.Pool
+	limits map[string]*domainLimiter
 }
 
 // Emailer is the SMTP e-mail messenger.
 type Emailer struct {
+	name    string
 	servers []*Server
+
+	// shared indicates that the servers' pools belong to another Emailer
+	// and aren't closed by this one.
+	shared bool
 }
 
 // New returns an SMTP e-mail Messenger backend with the given SMTP servers.
 func New(servers ...Server) (*Emailer, error) {
 	e := &Emailer{
+		name:    emName,
 		servers: make([]*Server, 0, len(servers)),
 	}
 
@@ -83,15 +101,47 @@ func New(servers ...Server) (*Emailer, error) {
 		}
 
 		s.pool = pool
+		s.limits = newDomainLimiters(s.Host, s.DomainLimits)
 		e.servers = append(e.servers, &s)
 	}
 
 	return e, nil
 }
 
+// Named returns a messenger named email-{name} for each of the named servers.
+// They share the servers' connection pools with e.
+func (e *Emailer) Named() []*Emailer {
+	var out []*Emailer
+	for _, s := range e.servers {
+		if s.Name == "" {
+			continue
+		}
+
+		out = append(out, &Emailer{
+			name:    emName + "-" + s.Name,
+			servers: []*Server{s},
+			shared:  true,
+		})
+	}
+
+	return out
+}
+
+// SourceIPs returns the source IPs of the messenger's servers.
+func (e *Emailer) SourceIPs() []string {
+	out := make([]string, 0, len(e.servers))
+	for _, s := range e.servers {
+		if s.SourceIP != "" {
+			out = append(out, s.SourceIP)
+		}
+	}
+
+	return out
+}
+
 // Name returns the Server's name.
 func (e *Emailer) Name() string {
-	return emName
+	return e.name
 }
 
 // Push pushes a message to the server.
@@ -108,18 +158,34 @@ func (e *Emailer) Push(m models.Message) error {
 		srv = e.servers[0]
 	}
 
-	// Are there attachments?
+	// If the recipient domain's rate or connection limit has been reached,
+	// don't wait (and hold up messages to other domains). The message is deferred
+	// to be pushed again.
+	if l := srv.limiter(m.To); l != nil {
+		wait, ok := l.reserve()
+		if !ok {
+			return &models.RetryError{After: wait, Key: l.key}
+		}
+		defer l.release()
+	}
+
+	return srv.pool.Send(MakeEmail(m, srv.EmailHeaders))
+}
+
+// MakeEmail converts a message into an e-mail with the given additional
+// headers (eg: SMTP server level headers), ready to be sent or serialized.
+func MakeEmail(m models.Message, headers map[string]string) smtppool.Email {
+	// Are there attachments? Their content is shared by all the messages of a
+	// campaign and is only read (encoded) into the MIME message as it's written.
 	var files []smtppool.Attachment
 	if m.Attachments != nil {
 		files = make([]smtppool.Attachment, 0, len(m.Attachments))
 		for _, f := range m.Attachments {
-			a := smtppool.Attachment{
+			files = append(files, smtppool.Attachment{
 				Filename: f.Name,
 				Header:   f.Header,
-				Content:  make([]byte, len(f.Content)),
-			}
-			copy(a.Content, f.Content)
-			files = append(files, a)
+				Content:  f.Content,
+			})
 		}
 	}
 
@@ -133,7 +199,7 @@ func (e *Emailer) Push(m models.Message) error {
 	em.Headers = textproto.MIMEHeader{}
 
 	// Attach SMTP level headers.
-	for k, v := range srv.EmailHeaders {
+	for k, v := range headers {
 		em.Headers.Set(k, v)
 	}
 
@@ -175,7 +241,7 @@ func (e *Emailer) Push(m models.Message) error {
 		}
 	}
 
-	return srv.pool.Send(em)
+	return em
 }
 
 // Flush flushes the message queue to the server.
@@ -185,6 +251,10 @@ func (e *Emailer) Flush() error {
 
 // Close closes the SMTP pools.
 func (e *Emailer) Close() error {
+	if e.shared {
+		return nil
+	}
+
 	for _, s := range e.servers {
 		s.pool.Close()
 	}
@@ -0,0 +1,114 @@
+package email
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainLimit caps the sending rate and the concurrent sends to a group of
+// recipient domains (eg: gmail.com, googlemail.com) on a server. Large
+// providers defer mail that arrives too fast from a single source.
+type DomainLimit struct {
+	Domains []string `json:"domains"`
+
+	// Rate is the max number of messages sent per minute. 0 is unlimited.
+	Rate int `json:"rate"`
+
+	// MaxConns is the max number of messages that are sent concurrently,
+	// ie, the connections in use. 0 is unlimited.
+	MaxConns int `json:"max_conns"`
+}
+
+// connRetryInterval is the interval after which a message to a domain whose
+// connection slots are all in use is retried.
+const connRetryInterval = time.Millisecond * 100
+
+// domainLimiter enforces a DomainLimit. It's shared by all the domains
+// in the limit's group.
+type domainLimiter struct {
+	// key identifies the limiter in the RetryErrors of the messages it defers.
+	key string
+
+	// interval is the minimum gap between two messages.
+	interval time.Duration
+	sem      chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newDomainLimiters returns a map of domain => limiter for the given limits
+// of a server (host).
+func newDomainLimiters(host string, limits []DomainLimit) map[string]*domainLimiter {
+	out := make(map[string]*domainLimiter)
+	for _, l := range limits {
+		if (l.Rate <= 0 && l.MaxConns <= 0) || len(l.Domains) == 0 {
+			continue
+		}
+
+		d := &domainLimiter{key: host + "/" + strings.ToLower(strings.TrimSpace(l.Domains[0]))}
+		if l.Rate > 0 {
+			d.interval = time.Minute / time.Duration(l.Rate)
+		}
+		if l.MaxConns > 0 {
+			d.sem = make(chan struct{}, l.MaxConns)
+		}
+
+		for _, dom := range l.Domains {
+			out[strings.ToLower(strings.TrimSpace(dom))] = d
+		}
+	}
+
+	return out
+}
+
+// reserve takes a connection slot and the next send at the limiter's rate if
+// both are available right away. It doesn't block. Otherwise, it returns the
+// duration after which to try again. release() should be called after sending.
+func (d *domainLimiter) reserve() (time.Duration, bool) {
+	if d.sem != nil {
+		select {
+		case d.sem <- struct{}{}:
+		default:
+			return connRetryInterval, false
+		}
+	}
+
+	if d.interval > 0 {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		now := time.Now()
+		if d.next.After(now) {
+			d.release()
+			return d.next.Sub(now), false
+		}
+		d.next = now.Add(d.interval)
+	}
+
+	return 0, true
+}
+
+// release frees the connection slot taken by reserve().
+func (d *domainLimiter) release() {
+	if d.sem != nil {
+		<-d.sem
+	}
+}
+
+// limiter returns the limiter for the domain of the given recipient
+// address (eg: "a@b.com" or "Name <a@b.com>"), if there's one.
+func (s *Server) limiter(to []string) *domainLimiter {
+	if len(s.limits) == 0 || len(to) == 0 {
+		return nil
+	}
+
+	addr := to[0]
+	i := strings.LastIndexByte(addr, '@')
+	if i < 0 {
+		return nil
+	}
+
+	return s.limits[strings.ToLower(strings.TrimRight(addr[i+1:], "> "))]
+}
@@ -56,6 +56,10 @@ type Options struct {
 	MaxConns int           `json:"max_conns"`
 	Retries  int           `json:"retries"`
 	Timeout  time.Duration `json:"timeout"`
+
+	// Attachments indicates whether the server accepts attachments. If it
+	// doesn't, campaigns with attachments can't be sent with the messenger.
+	Attachments bool `json:"attachments"`
 }
 
 // Postback represents an HTTP Message server.
@@ -93,6 +97,11 @@ func (p *Postback) Name() string {
 	return p.o.Name
 }
 
+// SupportsAttachments returns true if the server accepts attachments.
+func (p *Postback) SupportsAttachments() bool {
+	return p.o.Attachments
+}
+
 // Push pushes a message to the server.
 func (p *Postback) Push(m models.Message) error {
 	pb := postback{
@@ -119,16 +128,14 @@ func (p *Postback) Push(m models.Message) error {
 		}
 	}
 
-	if len(m.Attachments) > 0 {
+	if len(m.Attachments) > 0 && p.o.Attachments {
 		files := make([]attachment, 0, len(m.Attachments))
 		for _, f := range m.Attachments {
-			a := attachment{
+			files = append(files, attachment{
 				Name:    f.Name,
 				Header:  f.Header,
-				Content: make([]byte, len(f.Content)),
-			}
-			copy(a.Content, f.Content)
-			files = append(files, a)
+				Content: f.Content,
+			})
 		}
 		pb.Attachments = files
 	}
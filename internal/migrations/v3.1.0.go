@@ -0,0 +1,1228 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/knadh/koanf/v2"
+	"github.com/knadh/stuffbin"
+)
+
+// V3_1_0 performs the DB migrations.
+func V3_1_0(db *sqlx.DB, fs stuffbin.FileSystem, ko *koanf.Koanf, lo *log.Logger) error {
+	// Insert new preference settings.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('sunset.enabled', 'false'),
+		('sunset.inactive_days', '180'),
+		('sunset.grace_days', '30'),
+		('sunset.list_id', '0'),
+		('sunset.action', '"unsubscribe"'),
+		('sunset.interval', '"0 4 * * *"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Sunset (win-back) enrolments.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'sunset_status') THEN
+				CREATE TYPE sunset_status AS ENUM ('enrolled', 'recovered', 'removed');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS subscriber_sunset (
+			id               BIGSERIAL PRIMARY KEY,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			status           sunset_status NOT NULL DEFAULT 'enrolled',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_sunset_sub_enrolled ON subscriber_sunset(subscriber_id) WHERE status = 'enrolled';
+		CREATE INDEX IF NOT EXISTS idx_sunset_status ON subscriber_sunset(status);
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS content_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS content_checksum TEXT NOT NULL DEFAULT '';
+	`); err != nil {
+		return err
+	}
+
+	// Content QA hooks.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('content_qa.enabled', 'false'),
+		('content_qa.url', '""'),
+		('content_qa.timeout', '"5s"')
+		ON CONFLICT DO NOTHING;
+
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS content_qa_url TEXT NOT NULL DEFAULT '';
+	`); err != nil {
+		return err
+	}
+
+	// Per-campaign reply-to and reply tracking.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS reply_to TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS reply_tracking BOOLEAN NOT NULL DEFAULT false;
+
+		CREATE TABLE IF NOT EXISTS campaign_replies (
+			id               BIGSERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			message_id       TEXT NOT NULL DEFAULT '',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_replies_camp_id ON campaign_replies(campaign_id);
+		CREATE INDEX IF NOT EXISTS idx_replies_subscriber_id ON campaign_replies(subscriber_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_replies_msg_id ON campaign_replies(campaign_id, message_id) WHERE message_id != '';
+	`); err != nil {
+		return err
+	}
+
+	// List hygiene reports.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('hygiene.enabled', 'false'),
+		('hygiene.interval', '"0 3 * * 0"'),
+		('hygiene.check_domains', 'true'),
+		('hygiene.soft_bounce_count', '3'),
+		('hygiene.soft_bounce_days', '30')
+		ON CONFLICT DO NOTHING;
+
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'hygiene_category') THEN
+				CREATE TYPE hygiene_category AS ENUM ('invalid_syntax', 'role_account', 'dead_domain', 'soft_bouncer');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS list_hygiene (
+			list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			category         hygiene_category NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_list_hygiene_list ON list_hygiene(list_id, category);
+		CREATE INDEX IF NOT EXISTS idx_list_hygiene_sub ON list_hygiene(subscriber_id);
+	`); err != nil {
+		return err
+	}
+
+	// Role account and spam-trap address filter.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('privacy.role_accounts', '["abuse","admin","administrator","billing","compliance","contact","devnull","dns","ftp","help","hostmaster","info","inoc","ispfeedback","ispsupport","list","list-request","mail","mailer-daemon","marketing","media","news","no-reply","noc","noreply","null","office","phish","phishing","postmaster","privacy","registrar","root","sales","security","spam","support","sysadmin","tech","undisclosed-recipients","unsubscribe","usenet","uucp","webmaster","www"]'),
+		('privacy.spamtrap_patterns', '["(^|[._+-])spam-?trap", "(^|[._+-])honey-?pot", "@(.+\\.)?example\\.(com|net|org)$", "\\.(test|invalid|example|localhost)$"]')
+		ON CONFLICT DO NOTHING;
+
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'list_address_filter') THEN
+				CREATE TYPE list_address_filter AS ENUM ('none', 'flag', 'reject');
+			END IF;
+		END$$;
+
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS address_filter list_address_filter NOT NULL DEFAULT 'none';
+	`); err != nil {
+		return err
+	}
+
+	// Re-permission runs for converting single opt-in lists to double opt-in.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'repermission_status') THEN
+				CREATE TYPE repermission_status AS ENUM ('running', 'finished', 'cancelled');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS list_repermissions (
+			id               SERIAL PRIMARY KEY,
+			list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			status           repermission_status NOT NULL DEFAULT 'running',
+			deadline         TIMESTAMP WITH TIME ZONE NOT NULL,
+			total            INTEGER NOT NULL DEFAULT 0,
+			unsubscribed     INTEGER NOT NULL DEFAULT 0,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_repermissions_list_running ON list_repermissions(list_id) WHERE status = 'running';
+	`); err != nil {
+		return err
+	}
+
+	// Campaign goals and funnels.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'goal_type') THEN
+				CREATE TYPE goal_type AS ENUM ('link', 'pixel', 'conversion');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS campaign_goals (
+			id               SERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			position         INTEGER NOT NULL DEFAULT 0,
+			name             TEXT NOT NULL,
+			type             goal_type NOT NULL,
+			url              TEXT NOT NULL DEFAULT '',
+			key              TEXT NOT NULL DEFAULT '',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_goals_camp_id ON campaign_goals(campaign_id, position);
+
+		CREATE TABLE IF NOT EXISTS campaign_goal_events (
+			id               BIGSERIAL PRIMARY KEY,
+			goal_id          INTEGER NOT NULL REFERENCES campaign_goals(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_goal_events_goal_id ON campaign_goal_events(goal_id);
+		CREATE INDEX IF NOT EXISTS idx_goal_events_subscriber_id ON campaign_goal_events(subscriber_id);
+	`); err != nil {
+		return err
+	}
+
+	// Key for signing subscriber links in transactional messages. It's generated
+	// on startup if empty.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES ('security.signing_key', '""')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES ('privacy.optin_reply_address', '""')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// E-mail client preview screenshots.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS campaign_previews (
+			id               SERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			client           TEXT NOT NULL,
+			content_type     TEXT NOT NULL DEFAULT 'image/png',
+			image            BYTEA NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+			UNIQUE(campaign_id, client)
+		);
+
+		INSERT INTO settings (key, value) VALUES
+		('previews.enabled', 'false'),
+		('previews.url', '""'),
+		('previews.api_key', '""'),
+		('previews.clients', '["gmail-web", "outlook-desktop", "apple-mail", "iphone-mail"]'),
+		('previews.timeout', '"60s"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Pre-send spam check.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('spam_check.enabled', 'false'),
+		('spam_check.type', '"rspamd"'),
+		('spam_check.url', '"http://localhost:11333"'),
+		('spam_check.password', '""'),
+		('spam_check.threshold', '5'),
+		('spam_check.timeout', '"10s"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// DNS deliverability checks.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS dns_checks (
+			id               SERIAL PRIMARY KEY,
+			domain           TEXT NOT NULL,
+			status           TEXT NOT NULL,
+			results          JSONB NOT NULL DEFAULT '[]',
+			checked_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		INSERT INTO settings (key, value) VALUES
+		('deliverability.domains', '[]'),
+		('deliverability.tracking_cname', '""'),
+		('deliverability.recheck', 'false'),
+		('deliverability.recheck_interval', '"0 */6 * * *"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Sender reputation metrics.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS reputation_metrics (
+			id               SERIAL PRIMARY KEY,
+			provider         TEXT NOT NULL,
+			source           TEXT NOT NULL,
+			date             DATE NOT NULL,
+			reputation       TEXT NOT NULL DEFAULT '',
+			spam_rate        FLOAT NOT NULL DEFAULT 0,
+			delivery_errors  JSONB NOT NULL DEFAULT '[]',
+			meta             JSONB NOT NULL DEFAULT '{}',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+			UNIQUE(provider, source, date)
+		);
+		CREATE INDEX IF NOT EXISTS idx_reputation_date ON reputation_metrics(date);
+
+		INSERT INTO settings (key, value) VALUES
+		('reputation.enabled', 'false'),
+		('reputation.interval', '"0 6 * * *"'),
+		('reputation.postmaster_enabled', 'false'),
+		('reputation.postmaster_client_id', '""'),
+		('reputation.postmaster_client_secret', '""'),
+		('reputation.postmaster_refresh_token', '""'),
+		('reputation.postmaster_domains', '[]'),
+		('reputation.snds_enabled', 'false'),
+		('reputation.snds_key', '""'),
+		('reputation.alert_reputation', '"medium"'),
+		('reputation.alert_spam_rate', '0.003')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Per list and campaign envelope sender (Return-Path).
+	if _, err := db.Exec(`
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS return_path TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS return_path TEXT NOT NULL DEFAULT '';
+	`); err != nil {
+		return err
+	}
+
+	// Attachment scanning and message size budget.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('attachments.clamav_enabled', 'false'),
+		('attachments.clamav_address', '"/var/run/clamav/clamd.ctl"'),
+		('attachments.clamav_timeout', '"30s"'),
+		('attachments.max_message_size', '0'),
+		('attachments.size_action', '"warn"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Subscriber self-service data export links.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriber_exports (
+			id               SERIAL PRIMARY KEY,
+			uuid             uuid NOT NULL UNIQUE,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			data             BYTEA NOT NULL,
+			expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_sub_exports_expires_at ON subscriber_exports(expires_at);
+
+		INSERT INTO settings (key, value) VALUES ('privacy.export_link_expiry', '"48h"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Trusted sources that pre-confirm subscriptions.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES ('privacy.trusted_sources', '[]')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Trash (soft delete) for campaigns, lists, and templates.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE templates ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE NULL;
+		CREATE INDEX IF NOT EXISTS idx_camps_deleted_at ON campaigns(deleted_at) WHERE deleted_at IS NOT NULL;
+		CREATE INDEX IF NOT EXISTS idx_lists_deleted_at ON lists(deleted_at) WHERE deleted_at IS NOT NULL;
+
+		INSERT INTO settings (key, value) VALUES ('app.trash_retention_days', '30')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Versions for optimistic locking of campaign and template updates.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+		ALTER TABLE templates ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+	`); err != nil {
+		return err
+	}
+
+	// Trigram index for full-text subscriber search. Installing the extension may require
+	// a superuser (Postgres < 13), without which the search falls back to ILIKE.
+	if _, err := db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_trgm`); err != nil {
+		lo.Printf("WARNING: could not install the pg_trgm extension: %v. Subscriber search falls back to ILIKE. "+
+			"Install it as a superuser and create the idx_subs_search index (see docs) for typo tolerant search.", err)
+	} else if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_subs_search ON subscribers USING GIN (LOWER(name || ' ' || email || ' ' || attribs::TEXT) gin_trgm_ops);`); err != nil {
+		return err
+	}
+
+	// Index for e-mail prefix (autocomplete) lookups.
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_subs_email_prefix ON subscribers(LOWER(email) text_pattern_ops);`); err != nil {
+		return err
+	}
+
+	// Saved views (filters) of admin collections.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'saved_view_collection') THEN
+				CREATE TYPE saved_view_collection AS ENUM ('subscribers', 'campaigns', 'bounces');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS saved_views (
+			id               SERIAL PRIMARY KEY,
+			name             TEXT NOT NULL,
+			collection       saved_view_collection NOT NULL,
+			params           JSONB NOT NULL DEFAULT '{}',
+			created_by       TEXT NOT NULL DEFAULT '',
+			shared           BOOLEAN NOT NULL DEFAULT false,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_views_name ON saved_views(collection, created_by, LOWER(name));
+	`); err != nil {
+		return err
+	}
+
+	// Notification center.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'notification_type') THEN
+				CREATE TYPE notification_type AS ENUM ('campaign', 'import', 'bounce', 'messenger');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS notifications (
+			id               SERIAL PRIMARY KEY,
+			type             notification_type NOT NULL,
+			title            TEXT NOT NULL,
+			body             TEXT NOT NULL DEFAULT '',
+			data             JSONB NOT NULL DEFAULT '{}',
+			read_by          TEXT[] NOT NULL DEFAULT '{}',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at);
+
+		CREATE TABLE IF NOT EXISTS notification_prefs (
+			username         TEXT NOT NULL PRIMARY KEY,
+			email            TEXT NOT NULL DEFAULT '',
+			events           JSONB NOT NULL DEFAULT '{}',
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		INSERT INTO settings (key, value) VALUES
+			('notifications.slack_enabled', 'false'),
+			('notifications.slack_webhook_url', '""'),
+			('notifications.slack_events', '["campaign", "import", "bounce", "messenger"]'),
+			('notifications.bounce_threshold', '0')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Campaign comments. ADD VALUE can't run in a transaction block and is run separately.
+	if _, err := db.Exec(`ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'mention'`); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`
+		ALTER TABLE notifications ADD COLUMN IF NOT EXISTS username TEXT NULL;
+
+		CREATE TABLE IF NOT EXISTS campaign_comments (
+			id               SERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			parent_id        INTEGER NULL REFERENCES campaign_comments(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			author           TEXT NOT NULL,
+			body             TEXT NOT NULL,
+			mentions         TEXT[] NOT NULL DEFAULT '{}',
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_comments_camp_id ON campaign_comments(campaign_id, created_at);
+	`); err != nil {
+		return err
+	}
+
+	// Global send pause (kill switch).
+	if _, err := db.Exec(`INSERT INTO settings (key, value) VALUES ('app.sending_paused', 'false') ON CONFLICT DO NOTHING`); err != nil {
+		return err
+	}
+
+	// Sending domain registry.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS sending_domains (
+			id               SERIAL PRIMARY KEY,
+			domain           TEXT NOT NULL UNIQUE,
+			addresses        TEXT[] NOT NULL DEFAULT '{}',
+			token            TEXT NOT NULL,
+			verified         BOOLEAN NOT NULL DEFAULT false,
+			verified_at      TIMESTAMP WITH TIME ZONE NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+	`); err != nil {
+		return err
+	}
+
+	// Per-user sending and subscriber quotas.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quotas (
+			id                   SERIAL PRIMARY KEY,
+			username             TEXT NOT NULL UNIQUE,
+			campaigns_per_month  INT NOT NULL DEFAULT 0,
+			messages_per_day     INT NOT NULL DEFAULT 0,
+			max_subscribers      INT NOT NULL DEFAULT 0,
+			created_at           TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at           TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS quota_usage (
+			id               BIGSERIAL PRIMARY KEY,
+			username         TEXT NOT NULL,
+			campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			messages         INT NOT NULL DEFAULT 0,
+			created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_quota_usage ON quota_usage(username, created_at);
+	`); err != nil {
+		return err
+	}
+
+	// White-label branding.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+			('appearance.branding.product_name', '""'),
+			('appearance.branding.logo_url', '""'),
+			('appearance.branding.color', '""'),
+			('appearance.branding.footer', '""')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Subscription sources for growth analytics.
+	if _, err := db.Exec(`
+		ALTER TABLE subscriber_lists ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS idx_sub_lists_growth ON subscriber_lists(list_id, created_at);
+	`); err != nil {
+		return err
+	}
+
+	// Per-recipient campaign send log.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'send_status') THEN
+				CREATE TYPE send_status AS ENUM ('queued', 'sent', 'deferred', 'bounced');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS campaign_sends (
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			status           send_status NOT NULL DEFAULT 'queued',
+			response         TEXT NOT NULL DEFAULT '',
+			queued_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			sent_at          TIMESTAMP WITH TIME ZONE NULL,
+			deferred_at      TIMESTAMP WITH TIME ZONE NULL,
+			bounced_at       TIMESTAMP WITH TIME ZONE NULL,
+			opened_at        TIMESTAMP WITH TIME ZONE NULL,
+			clicked_at       TIMESTAMP WITH TIME ZONE NULL,
+
+			PRIMARY KEY (campaign_id, subscriber_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_camp_sends_sub_id ON campaign_sends(subscriber_id);
+		CREATE INDEX IF NOT EXISTS idx_camp_sends_status ON campaign_sends(campaign_id, status);
+	`); err != nil {
+		return err
+	}
+
+	// Capture messenger and capture mode.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS captured_messages (
+			id               SERIAL PRIMARY KEY,
+			messenger        TEXT NOT NULL,
+			campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			from_email       TEXT NOT NULL,
+			to_emails        TEXT[] NOT NULL,
+			subject          TEXT NOT NULL,
+			content_type     TEXT NOT NULL DEFAULT '',
+			body             TEXT NOT NULL,
+			alt_body         TEXT NOT NULL DEFAULT '',
+			headers          JSONB NOT NULL DEFAULT '{}',
+			raw              TEXT NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_captured_messages_camp_id ON captured_messages(campaign_id);
+
+		INSERT INTO settings (key, value) VALUES ('app.capture_mode', 'false') ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Subscriber e-mail change confirmation.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriber_email_changes (
+			id               SERIAL PRIMARY KEY,
+			uuid             uuid NOT NULL UNIQUE,
+			subscriber_id    INTEGER NOT NULL UNIQUE REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			email            TEXT NOT NULL,
+			expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		INSERT INTO settings (key, value) VALUES ('privacy.confirm_email_change', 'false') ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Campaign body size and image weight budgets.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('attachments.max_body_size', '0'),
+		('attachments.max_image_weight', '0'),
+		('attachments.minify_html', 'false')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Nested lists.
+	if _, err := db.Exec(`
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS parent_id INTEGER NULL REFERENCES lists(id) ON DELETE SET NULL ON UPDATE CASCADE;
+		CREATE INDEX IF NOT EXISTS idx_lists_parent_id ON lists(parent_id) WHERE parent_id IS NOT NULL;
+	`); err != nil {
+		return err
+	}
+
+	// List folders and archiving.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'list_status') THEN
+				CREATE TYPE list_status AS ENUM ('active', 'archived');
+			END IF;
+		END$$;
+
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS folder TEXT NOT NULL DEFAULT '';
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS status list_status NOT NULL DEFAULT 'active';
+		CREATE INDEX IF NOT EXISTS idx_lists_folder ON lists(folder);
+	`); err != nil {
+		return err
+	}
+
+	// Login lockout.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('security.login_max_attempts', '10'),
+		('security.login_lockout', '"1m"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// List blackout dates.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS list_blackouts (
+			id               SERIAL PRIMARY KEY,
+			list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			name             TEXT NOT NULL DEFAULT '',
+			starts_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+			ends_at          TIMESTAMP WITH TIME ZONE NOT NULL CHECK (ends_at > starts_at),
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_list_blackouts_list ON list_blackouts(list_id, ends_at);
+	`); err != nil {
+		return err
+	}
+
+	// Premium list entitlement hooks and subscriber caps.
+	if _, err := db.Exec(`
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS entitlement_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE lists ADD COLUMN IF NOT EXISTS max_subscribers INTEGER NOT NULL DEFAULT 0;
+
+		INSERT INTO settings (key, value) VALUES
+		('entitlement.enabled', 'false'),
+		('entitlement.interval', '"0 */6 * * *"'),
+		('entitlement.timeout', '"5s"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Stripe subscription sync.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS stripe_customers (
+			id               TEXT NOT NULL PRIMARY KEY,
+			email            TEXT NOT NULL DEFAULT '',
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS stripe_subscriptions (
+			id               TEXT NOT NULL,
+			product_id       TEXT NOT NULL,
+			customer_id      TEXT NOT NULL,
+			status           TEXT NOT NULL,
+
+			-- Status that was last applied to the product's lists.
+			synced_status    TEXT NOT NULL DEFAULT '',
+			latest_invoice   TEXT NOT NULL DEFAULT '',
+
+			-- Timestamp of the Stripe event the record was last updated by, for discarding out of order events.
+			event_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+			PRIMARY KEY (id, product_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_stripe_subs_customer ON stripe_subscriptions(customer_id);
+		CREATE INDEX IF NOT EXISTS idx_stripe_subs_invoice ON stripe_subscriptions(latest_invoice);
+
+		INSERT INTO settings (key, value) VALUES
+		('stripe.enabled', 'false'),
+		('stripe.webhook_secret', '""'),
+		('stripe.products', '[]')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Header presets.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS header_presets (
+			id              SERIAL PRIMARY KEY,
+			name            TEXT NOT NULL UNIQUE,
+			headers         JSONB NOT NULL DEFAULT '[]',
+			created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS header_preset_id INTEGER NULL REFERENCES header_presets(id) ON DELETE SET NULL;
+		ALTER TABLE templates ADD COLUMN IF NOT EXISTS header_preset_id INTEGER NULL REFERENCES header_presets(id) ON DELETE SET NULL;
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS media_quarantine (
+			id               SERIAL PRIMARY KEY,
+			filename         TEXT NOT NULL,
+			content_type     TEXT NOT NULL DEFAULT 'application/octet-stream',
+			signature        TEXT NOT NULL DEFAULT '',
+			size             INT NOT NULL DEFAULT 0,
+			body             BYTEA NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		INSERT INTO settings (key, value) VALUES
+		('attachments.scanner', '"clamav"'),
+		('attachments.scanner_url', '""'),
+		('attachments.scanner_token', '""')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id               SERIAL PRIMARY KEY,
+			name             TEXT NOT NULL,
+			username         TEXT NOT NULL,
+			token_hash       TEXT NOT NULL UNIQUE,
+			scopes           TEXT[] NOT NULL DEFAULT '{}',
+			expires_at       TIMESTAMP WITH TIME ZONE NULL,
+			last_used_at     TIMESTAMP WITH TIME ZONE NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_tokens_username ON api_tokens(username);
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id               BIGSERIAL PRIMARY KEY,
+			actor            TEXT NOT NULL DEFAULT '',
+			action           TEXT NOT NULL,
+			entity_type      TEXT NOT NULL DEFAULT '',
+			entity_id        INT NULL,
+			before_data      JSONB NULL,
+			after_data       JSONB NULL,
+			changes          TEXT[] NOT NULL DEFAULT '{}',
+			ip               TEXT NOT NULL DEFAULT '',
+			created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+	`); err != nil {
+		return err
+	}
+
+	// Admin and API IP allow and deny lists.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('security.ip_allowlist', '[]'),
+		('security.ip_denylist', '[]'),
+		('security.api_ip_allowlist', '[]')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// SMTP TLS reports.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS tls_reports (
+			id               SERIAL PRIMARY KEY,
+			report_id        TEXT NOT NULL,
+			organization     TEXT NOT NULL DEFAULT '',
+			policy_domain    TEXT NOT NULL,
+			policy_type      TEXT NOT NULL DEFAULT '',
+			start_date       TIMESTAMP WITH TIME ZONE NOT NULL,
+			end_date         TIMESTAMP WITH TIME ZONE NOT NULL,
+			success_count    INT NOT NULL DEFAULT 0,
+			failure_count    INT NOT NULL DEFAULT 0,
+			failures         JSONB NOT NULL DEFAULT '[]',
+			created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE(report_id, policy_domain)
+		);
+		CREATE INDEX IF NOT EXISTS idx_tls_reports_end_date ON tls_reports(end_date);
+	`); err != nil {
+		return err
+	}
+
+	// API rate limits.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('security.api_rate_limit', '0'),
+		('security.api_rate_burst', '0'),
+		('security.api_rate_limit_read_only', '0'),
+		('security.api_rate_burst_read_only', '0'),
+		('security.api_rate_limits', '[]')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Subscriber status transition rules.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS status_rules (
+			id               SERIAL PRIMARY KEY,
+			name             TEXT NOT NULL,
+			enabled          BOOLEAN NOT NULL DEFAULT false,
+
+			-- unconfirmed: subscriptions unconfirmed for more than threshold days.
+			-- bounces: threshold or more bounces (of bounce_type, if set) across campaigns within window_days (0 = all time).
+			condition        TEXT NOT NULL,
+			threshold        INT NOT NULL,
+			bounce_type      TEXT NOT NULL DEFAULT '',
+			window_days      INT NOT NULL DEFAULT 0,
+
+			-- unsubscribe or blocklist.
+			action           TEXT NOT NULL,
+
+			last_run_at      TIMESTAMP WITH TIME ZONE NULL,
+			last_affected    INT NOT NULL DEFAULT 0,
+			created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+	`); err != nil {
+		return err
+	}
+
+	// Unsubscribe snoozing.
+	if _, err := db.Exec(`
+		ALTER TABLE subscriber_lists ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP WITH TIME ZONE NULL;
+		ALTER TABLE subscriber_lists ADD COLUMN IF NOT EXISTS snooze_status subscription_status NULL;
+		CREATE INDEX IF NOT EXISTS idx_sub_lists_snoozed ON subscriber_lists(snoozed_until) WHERE snoozed_until IS NOT NULL;
+
+		INSERT INTO settings (key, value) VALUES ('privacy.allow_snooze', 'false')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Per-list subscriber attribute fields on public forms.
+	if _, err := db.Exec(`ALTER TABLE lists ADD COLUMN IF NOT EXISTS form_fields JSONB NOT NULL DEFAULT '[]'`); err != nil {
+		return err
+	}
+
+	// Per-user subscribers table columns.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriber_columns (
+			username         TEXT NOT NULL PRIMARY KEY,
+			columns          TEXT[] NOT NULL DEFAULT '{}',
+			updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+	`); err != nil {
+		return err
+	}
+
+	// Campaign A/B tests.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ab_test_percent INT NOT NULL DEFAULT 0;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ab_metric TEXT NOT NULL DEFAULT 'opens';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ab_wait_hours INT NOT NULL DEFAULT 4;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ab_phase TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS ab_phase_at TIMESTAMP WITH TIME ZONE NULL;
+
+		CREATE TABLE IF NOT EXISTS campaign_variants (
+			id               SERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			name             TEXT NOT NULL,
+			subject          TEXT NOT NULL,
+			body             TEXT NOT NULL DEFAULT '',
+			is_winner        BOOLEAN NOT NULL DEFAULT false,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_camp_variants_camp_id ON campaign_variants(campaign_id);
+	`); err != nil {
+		return err
+	}
+
+	// Explicit time zones of campaign schedules, user display time zones, and the reporting time zone.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS send_at_timezone TEXT NOT NULL DEFAULT '';
+
+		CREATE TABLE IF NOT EXISTS user_preferences (
+			username         TEXT NOT NULL PRIMARY KEY,
+			timezone         TEXT NOT NULL DEFAULT '',
+			updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		INSERT INTO settings (key, value) VALUES ('app.reporting_timezone', '"UTC"') ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Campaign dependencies.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS depends_on INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS depends_delay_mins INT NOT NULL DEFAULT 0;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP WITH TIME ZONE NULL;
+	`); err != nil {
+		return err
+	}
+
+	// Drip (automation) sequences.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'sequence_status') THEN
+				CREATE TYPE sequence_status AS ENUM ('active', 'paused');
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'sequence_subscriber_status') THEN
+				CREATE TYPE sequence_subscriber_status AS ENUM ('active', 'finished', 'exited');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS sequences (
+			id               SERIAL PRIMARY KEY,
+			name             TEXT NOT NULL,
+			list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			status           sequence_status NOT NULL DEFAULT 'active',
+			exit_on_click    BOOLEAN NOT NULL DEFAULT false,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS sequence_steps (
+			id               SERIAL PRIMARY KEY,
+			sequence_id      INTEGER NOT NULL REFERENCES sequences(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			position         INTEGER NOT NULL DEFAULT 0,
+			delay_mins       INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_sequence_steps_seq ON sequence_steps(sequence_id, position);
+
+		CREATE TABLE IF NOT EXISTS sequence_subscribers (
+			sequence_id      INTEGER NOT NULL REFERENCES sequences(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			status           sequence_subscriber_status NOT NULL DEFAULT 'active',
+			step             INTEGER NOT NULL DEFAULT 0,
+			last_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			PRIMARY KEY (sequence_id, subscriber_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_sequence_subs_active ON sequence_subscribers(sequence_id, last_at) WHERE status = 'active';
+	`); err != nil {
+		return err
+	}
+
+	// Recurring campaigns.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS recurrence_parent_id INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS idx_camps_recurrence_parent ON campaigns(recurrence_parent_id) WHERE recurrence_parent_id IS NOT NULL;
+	`); err != nil {
+		return err
+	}
+
+	// RSS/Atom feed campaigns.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS feed_url TEXT NOT NULL DEFAULT '';
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS feed_items JSONB NULL;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS feed_since TIMESTAMP WITH TIME ZONE NULL;
+	`); err != nil {
+		return err
+	}
+
+	// Scheduled maintenance.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('maintenance.enabled', 'false'),
+		('maintenance.interval', '"0 5 * * *"'),
+		('maintenance.media_days', '0'),
+		('maintenance.fix_integrity', 'false')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Anonymous link tracking.
+	if _, err := db.Exec(`INSERT INTO settings (key, value) VALUES ('privacy.anonymous_links', 'false') ON CONFLICT DO NOTHING;`); err != nil {
+		return err
+	}
+
+	// Send-time optimization.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS sto_window_hours INT NOT NULL DEFAULT 0;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS sto_queued_at TIMESTAMP WITH TIME ZONE NULL;
+
+		CREATE TABLE IF NOT EXISTS subscriber_send_hours (
+			subscriber_id    INTEGER NOT NULL PRIMARY KEY REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			hour             SMALLINT NOT NULL,
+			opens            INT NOT NULL DEFAULT 0,
+			updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS campaign_send_queue (
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			send_at          TIMESTAMP WITH TIME ZONE NOT NULL,
+			PRIMARY KEY (campaign_id, subscriber_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_camp_send_queue ON campaign_send_queue(campaign_id, send_at);
+	`); err != nil {
+		return err
+	}
+
+	// Resends of campaigns to non-openers.
+	if _, err := db.Exec(`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS resend_of INTEGER NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE;`); err != nil {
+		return err
+	}
+
+	// Google Analytics 4 event forwarding.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES
+		('ga4.enabled', 'false'),
+		('ga4.measurement_id', '""'),
+		('ga4.api_secret', '""'),
+		('ga4.source', '"listmonk"'),
+		('ga4.medium', '"email"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Read-only API token role.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'api_token_role') THEN
+				CREATE TYPE api_token_role AS ENUM ('full', 'read_only');
+			END IF;
+		END$$;
+
+		ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS role api_token_role NOT NULL DEFAULT 'full';
+	`); err != nil {
+		return err
+	}
+
+	// Sending campaigns at the subscribers' local time.
+	if _, err := db.Exec(`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS send_at_local BOOLEAN NOT NULL DEFAULT false;`); err != nil {
+		return err
+	}
+
+	// Post-campaign actions.
+	if _, err := db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'campaign_action_condition') THEN
+				CREATE TYPE campaign_action_condition AS ENUM ('opened', 'not_opened', 'clicked', 'not_clicked');
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'campaign_action_type') THEN
+				CREATE TYPE campaign_action_type AS ENUM ('add_tag', 'add_list', 'move_list');
+			END IF;
+		END$$;
+
+		CREATE TABLE IF NOT EXISTS campaign_actions (
+			id               SERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			condition        campaign_action_condition NOT NULL,
+			url              TEXT NOT NULL DEFAULT '',
+			action           campaign_action_type NOT NULL,
+			tag              TEXT NOT NULL DEFAULT '',
+			list_id          INTEGER NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			delay_hours      INTEGER NOT NULL DEFAULT 0,
+			affected         INTEGER NOT NULL DEFAULT 0,
+			ran_at           TIMESTAMP WITH TIME ZONE NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_camp_actions_camp_id ON campaign_actions(campaign_id);
+	`); err != nil {
+		return err
+	}
+
+	// Per-campaign throttle and send windows.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS send_rate INT NOT NULL DEFAULT 0;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS send_windows JSONB NOT NULL DEFAULT '[]';
+	`); err != nil {
+		return err
+	}
+
+	// Campaign surveys.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS survey_responses (
+			id               BIGSERIAL PRIMARY KEY,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			key              TEXT NOT NULL,
+			answer           TEXT NOT NULL,
+			score            INTEGER NULL CHECK (score BETWEEN 1 AND 10),
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+			UNIQUE(campaign_id, subscriber_id, key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_survey_responses_sub_id ON survey_responses(subscriber_id);
+	`); err != nil {
+		return err
+	}
+
+	// Short tracked links.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS link_slugs (
+			slug             TEXT NOT NULL PRIMARY KEY,
+			link_id          INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+			UNIQUE(link_id, campaign_id)
+		);
+		INSERT INTO settings (key, value) VALUES ('app.short_links', 'false') ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Campaign attachment guards. Existing postback messengers retain their
+	// support for attachments.
+	if _, err := db.Exec(`
+		INSERT INTO settings (key, value) VALUES ('attachments.max_size', '10240') ON CONFLICT DO NOTHING;
+		UPDATE settings SET value = (
+			SELECT COALESCE(JSONB_AGG(CASE WHEN m->'attachments' IS NULL THEN m || '{"attachments": true}'::JSONB ELSE m END), '[]'::JSONB)
+			FROM JSONB_ARRAY_ELEMENTS(value) m
+		) WHERE key = 'messengers';
+	`); err != nil {
+		return err
+	}
+
+	// Campaign audience exclusion lists.
+	if _, err := db.Exec(`ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS exclude_list_ids INT[] NOT NULL DEFAULT '{}';`); err != nil {
+		return err
+	}
+
+	// Automatic UTM tagging of campaign links.
+	if _, err := db.Exec(`
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS utm_enabled BOOLEAN NULL;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '';
+		INSERT INTO settings (key, value) VALUES
+		('utm.enabled', 'false'),
+		('utm.source', '"listmonk"'),
+		('utm.medium', '"email"')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Archive of the content of sent campaign messages.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS sent_messages (
+			id                  BIGSERIAL PRIMARY KEY,
+			campaign_id         INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+			campaign_name       TEXT NOT NULL DEFAULT '',
+			subscriber_id       INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+			email               TEXT NOT NULL,
+			subject             TEXT NOT NULL,
+			content_type        TEXT NOT NULL DEFAULT '',
+			body                TEXT NOT NULL DEFAULT '',
+			alt_body            TEXT NOT NULL DEFAULT '',
+			body_hash           TEXT NOT NULL,
+			campaign_version    INT NOT NULL DEFAULT 0,
+			template_id         INTEGER NULL,
+			template_updated_at TIMESTAMP WITH TIME ZONE NULL,
+			sent_at             TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_sent_messages_sub_id ON sent_messages(subscriber_id);
+		CREATE INDEX IF NOT EXISTS idx_sent_messages_camp_id ON sent_messages(campaign_id);
+		CREATE INDEX IF NOT EXISTS idx_sent_messages_sent_at ON sent_messages(sent_at);
+		INSERT INTO settings (key, value) VALUES
+		('sent_archive.mode', '"off"'),
+		('sent_archive.retention_days', '365')
+		ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Retention of the campaign send log.
+	if _, err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_camp_sends_queued_at ON campaign_sends(queued_at);
+		INSERT INTO settings (key, value) VALUES ('send_log.retention_days', '365') ON CONFLICT DO NOTHING;
+	`); err != nil {
+		return err
+	}
+
+	// Retries of sequence steps that fail to be queued.
+	if _, err := db.Exec(`
+		ALTER TABLE sequence_subscribers ADD COLUMN IF NOT EXISTS retry_at TIMESTAMP WITH TIME ZONE NULL;
+	`); err != nil {
+		return err
+	}
+
+	// Transactional messages held while sending is paused.
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS held_messages (
+			id               BIGSERIAL PRIMARY KEY,
+			message          JSONB NOT NULL,
+			created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+	`); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package passwd implements Argon2id password hashing. Hashes are encoded in the
+// PHC string format, eg: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>, where the
+// salt and the key are unpadded base64 strings.
+package passwd
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	prefix  = "$argon2id$"
+	saltLen = 16
+	keyLen  = 32
+)
+
+// Params represents the Argon2id cost parameters.
+type Params struct {
+	// Memory in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// DefaultParams are the cost parameters recommended by RFC 9106 for
+// memory constrained environments.
+var DefaultParams = Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 4}
+
+var errInvalidHash = errors.New("invalid argon2id hash")
+
+// Hash returns the Argon2id hash of a password with a random salt.
+func Hash(password string, p Params) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, keyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", prefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// IsHash checks whether a string is an Argon2id hash.
+func IsHash(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// Verify checks a password against an Argon2id hash and returns the
+// cost parameters the hash was created with.
+func Verify(password, hash string) (bool, Params, error) {
+	// $argon2id$v=19$m=65536,t=3,p=4$salt$key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || !IsHash(hash) {
+		return false, Params{}, errInvalidHash
+	}
+
+	var ver int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &ver); err != nil || ver != argon2.Version {
+		return false, Params{}, errInvalidHash
+	}
+
+	var p Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return false, Params{}, errInvalidHash
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return false, Params{}, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, Params{}, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, Params{}, errInvalidHash
+	}
+
+	k := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(k, key) == 1, p, nil
+}
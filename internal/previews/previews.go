@@ -0,0 +1,159 @@
+// Package previews implements a client for e-mail client preview (screenshot)
+// services. A service is an HTTP endpoint, either a self-hosted renderer or an
+// external API, that receives a rendered campaign and returns a screenshot of
+// it for each requested e-mail client.
+package previews
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRespSize is the maximum size of a service's response body.
+	maxRespSize = 50 * 1024 * 1024
+
+	defaultContentType = "image/png"
+)
+
+// imageTypes are the content types of the preview images that are stored
+// and served. As the images are served on the admin's origin, anything else,
+// eg: text/html, is rejected.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+}
+
+// Opt represents the preview client options.
+type Opt struct {
+	URL     string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// Message represents the rendered campaign that's posted to the service.
+type Message struct {
+	CampaignID int      `json:"campaign_id"`
+	UUID       string   `json:"uuid"`
+	Subject    string   `json:"subject"`
+	FromEmail  string   `json:"from_email"`
+	Body       string   `json:"body"`
+	Clients    []string `json:"clients"`
+}
+
+// Preview is a screenshot of the message in an e-mail client.
+type Preview struct {
+	Client      string
+	ContentType string
+	Image       []byte
+}
+
+type serviceResp struct {
+	Previews []struct {
+		Client      string `json:"client"`
+		ContentType string `json:"content_type"`
+		Image       string `json:"image"`
+	} `json:"previews"`
+}
+
+// Client is the preview service client.
+type Client struct {
+	o      Opt
+	client *http.Client
+}
+
+// New returns a new instance of the preview service client.
+func New(o Opt) *Client {
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 60
+	}
+
+	return &Client{
+		o: o,
+		client: &http.Client{
+			Timeout: o.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost:   10,
+				MaxConnsPerHost:       10,
+				ResponseHeaderTimeout: o.Timeout,
+				IdleConnTimeout:       o.Timeout,
+			},
+		}}
+}
+
+// Render posts the given message to the service and returns the previews
+// (base64 encoded images in the response) for each client.
+func (c *Client) Render(m Message) ([]Preview, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.o.URL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.o.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("preview service returned %d", resp.StatusCode)
+	}
+
+	var r serviceResp
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("error parsing preview service response: %v", err)
+	}
+
+	out := make([]Preview, 0, len(r.Previews))
+	for _, p := range r.Previews {
+		if p.Client == "" {
+			continue
+		}
+
+		img, err := base64.StdEncoding.DecodeString(p.Image)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding preview image for '%s': %v", p.Client, err)
+		}
+
+		ct := p.ContentType
+		if ct == "" {
+			ct = defaultContentType
+		}
+
+		out = append(out, Preview{Client: p.Client, ContentType: ct, Image: img})
+	}
+
+	return out, nil
+}
+
+// IsImageType checks whether a content type is one of the accepted image types.
+func IsImageType(ct string) bool {
+	t, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	return imageTypes[strings.ToLower(t)]
+}
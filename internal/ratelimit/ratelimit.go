@@ -0,0 +1,101 @@
+// Package ratelimit implements in-memory token bucket rate limiting per key
+// (eg: an API user). Every key has a bucket of Burst tokens that refills at
+// Rate tokens per minute, and each request takes a token.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limit is the rate limit of a key.
+type Limit struct {
+	// Rate is the number of requests per minute. 0 is unlimited.
+	Rate int `json:"rate"`
+
+	// Burst is the max. number of requests that can be made at once.
+	// If it's less than 1, it's the same as Rate.
+	Burst int `json:"burst"`
+}
+
+// Limiter tracks the token buckets of keys.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	lim    Limit
+}
+
+// Max. number of tracked keys after which full buckets are pruned.
+const pruneSize = 10000
+
+// New returns a new Limiter.
+func New() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from a key's bucket with the given limit. If the bucket
+// is empty, it returns false and the duration after which a token is available.
+func (l *Limiter) Allow(key string, lim Limit) (time.Duration, bool) {
+	if lim.Rate < 1 {
+		return 0, true
+	}
+
+	var (
+		burst = lim.burst()
+		rate  = float64(lim.Rate) / 60
+		now   = time.Now()
+	)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buckets) >= pruneSize {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.lim = lim
+
+	// Refill the bucket for the time since the last request.
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second)), false
+	}
+	b.tokens--
+
+	return 0, true
+}
+
+// prune deletes the buckets that would be full by now. They're the same as
+// new buckets.
+func (l *Limiter) prune(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*float64(b.lim.Rate)/60 >= b.lim.burst() {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// burst returns the size of the limit's bucket.
+func (lim Limit) burst() float64 {
+	if lim.Burst < 1 {
+		return float64(lim.Rate)
+	}
+	return float64(lim.Burst)
+}
@@ -0,0 +1,247 @@
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	postmasterURL      = "https://gmailpostmastertools.googleapis.com/v1"
+	postmasterTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// PostmasterOpt represents the Google Postmaster Tools connector options.
+// The OAuth2 refresh token should be issued for the
+// https://www.googleapis.com/auth/postmaster.readonly scope.
+type PostmasterOpt struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	Domains      []string
+	Timeout      time.Duration
+}
+
+// Postmaster fetches daily traffic stats of verified domains from the
+// Google Postmaster Tools API.
+type Postmaster struct {
+	o      PostmasterOpt
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+type pmTrafficStats struct {
+	TrafficStats []struct {
+		Name                  string  `json:"name"`
+		DomainReputation      string  `json:"domainReputation"`
+		UserReportedSpamRatio float64 `json:"userReportedSpamRatio"`
+		DeliveryErrors        []struct {
+			ErrorClass string  `json:"errorClass"`
+			ErrorType  string  `json:"errorType"`
+			ErrorRatio float64 `json:"errorRatio"`
+		} `json:"deliveryErrors"`
+		DKIMSuccessRatio    float64 `json:"dkimSuccessRatio"`
+		SPFSuccessRatio     float64 `json:"spfSuccessRatio"`
+		DMARCSuccessRatio   float64 `json:"dmarcSuccessRatio"`
+		InboundEncryptRatio float64 `json:"inboundEncryptionRatio"`
+	} `json:"trafficStats"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type pmMeta struct {
+	DKIMSuccessRatio    float64 `json:"dkim_success_ratio"`
+	SPFSuccessRatio     float64 `json:"spf_success_ratio"`
+	DMARCSuccessRatio   float64 `json:"dmarc_success_ratio"`
+	InboundEncryptRatio float64 `json:"inbound_encryption_ratio"`
+}
+
+// NewPostmaster returns a new Google Postmaster Tools connector.
+func NewPostmaster(o PostmasterOpt) (*Postmaster, error) {
+	if o.ClientID == "" || o.ClientSecret == "" || o.RefreshToken == "" {
+		return nil, errors.New("postmaster: client ID, client secret, and refresh token are required")
+	}
+	if len(o.Domains) == 0 {
+		return nil, errors.New("postmaster: no domains")
+	}
+
+	return &Postmaster{o: o, client: newHTTPClient(o.Timeout)}, nil
+}
+
+// Name returns the name of the provider.
+func (p *Postmaster) Name() string {
+	return ProviderPostmaster
+}
+
+// Fetch returns the daily traffic stats of all the configured domains
+// between the given dates (inclusive).
+func (p *Postmaster) Fetch(from, to time.Time) ([]Metric, error) {
+	tok, err := p.getToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Metric
+	for _, dom := range p.o.Domains {
+		m, err := p.fetchDomain(tok, dom, from, to)
+		if err != nil {
+			return out, fmt.Errorf("postmaster: %s: %v", dom, err)
+		}
+		out = append(out, m...)
+	}
+
+	return out, nil
+}
+
+func (p *Postmaster) fetchDomain(tok, domain string, from, to time.Time) ([]Metric, error) {
+	// The API's end date is exclusive.
+	to = to.AddDate(0, 0, 1)
+
+	q := url.Values{}
+	q.Set("startDate.year", fmt.Sprintf("%d", from.Year()))
+	q.Set("startDate.month", fmt.Sprintf("%d", from.Month()))
+	q.Set("startDate.day", fmt.Sprintf("%d", from.Day()))
+	q.Set("endDate.year", fmt.Sprintf("%d", to.Year()))
+	q.Set("endDate.month", fmt.Sprintf("%d", to.Month()))
+	q.Set("endDate.day", fmt.Sprintf("%d", to.Day()))
+	q.Set("pageSize", "100")
+
+	var out []Metric
+	for {
+		req, err := http.NewRequest(http.MethodGet,
+			fmt.Sprintf("%s/domains/%s/trafficStats?%s", postmasterURL, url.PathEscape(domain), q.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+
+		var res pmTrafficStats
+		if err := p.do(req, &res); err != nil {
+			return nil, err
+		}
+
+		for _, s := range res.TrafficStats {
+			// Name is in the format domains/{domain}/trafficStats/{YYYYMMDD}.
+			date, err := time.Parse("20060102", s.Name[strings.LastIndex(s.Name, "/")+1:])
+			if err != nil {
+				continue
+			}
+
+			m := Metric{
+				Provider:       ProviderPostmaster,
+				Source:         domain,
+				Date:           date,
+				Reputation:     pmReputation(s.DomainReputation),
+				SpamRate:       s.UserReportedSpamRatio,
+				DeliveryErrors: make([]DeliveryError, 0, len(s.DeliveryErrors)),
+			}
+			for _, e := range s.DeliveryErrors {
+				m.DeliveryErrors = append(m.DeliveryErrors, DeliveryError{Class: e.ErrorClass, Type: e.ErrorType, Ratio: e.ErrorRatio})
+			}
+			m.Meta, _ = json.Marshal(pmMeta{
+				DKIMSuccessRatio:    s.DKIMSuccessRatio,
+				SPFSuccessRatio:     s.SPFSuccessRatio,
+				DMARCSuccessRatio:   s.DMARCSuccessRatio,
+				InboundEncryptRatio: s.InboundEncryptRatio,
+			})
+
+			out = append(out, m)
+		}
+
+		if res.NextPageToken == "" {
+			break
+		}
+		q.Set("pageToken", res.NextPageToken)
+	}
+
+	return out, nil
+}
+
+// getToken returns a cached OAuth2 access token or exchanges the refresh token for a new one.
+func (p *Postmaster) getToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Before(p.expiry) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", p.o.ClientID)
+	form.Set("client_secret", p.o.ClientSecret)
+	form.Set("refresh_token", p.o.RefreshToken)
+
+	req, err := http.NewRequest(http.MethodPost, postmasterTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := p.do(req, &res); err != nil {
+		return "", fmt.Errorf("postmaster: error getting access token: %v", err)
+	}
+	if res.AccessToken == "" {
+		return "", errors.New("postmaster: empty access token")
+	}
+
+	p.accessToken = res.AccessToken
+	p.expiry = time.Now().Add(time.Duration(res.ExpiresIn)*time.Second - time.Minute)
+
+	return p.accessToken, nil
+}
+
+func (p *Postmaster) do(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(b, &e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("%d: %s", resp.StatusCode, e.Error.Message)
+		}
+		return fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// pmReputation normalizes a Postmaster Tools reputation category.
+func pmReputation(s string) string {
+	switch s {
+	case "HIGH":
+		return ReputationHigh
+	case "MEDIUM":
+		return ReputationMedium
+	case "LOW":
+		return ReputationLow
+	case "BAD":
+		return ReputationBad
+	}
+
+	return ReputationUnknown
+}
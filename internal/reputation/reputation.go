@@ -0,0 +1,85 @@
+// Package reputation implements connectors that pull daily sender reputation
+// metrics from Google Postmaster Tools (per domain) and Microsoft SNDS (per IP).
+package reputation
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	ProviderPostmaster = "postmaster"
+	ProviderSNDS       = "snds"
+
+	// Normalized reputation levels across providers.
+	ReputationHigh    = "high"
+	ReputationMedium  = "medium"
+	ReputationLow     = "low"
+	ReputationBad     = "bad"
+	ReputationUnknown = ""
+
+	// maxRespSize is the maximum size of a provider's response.
+	maxRespSize = 10 * 1024 * 1024
+)
+
+// DeliveryError is the ratio of a class of delivery errors on a given day.
+type DeliveryError struct {
+	Class string  `json:"class"`
+	Type  string  `json:"type"`
+	Ratio float64 `json:"ratio"`
+}
+
+// Metric is the reputation of a source (domain or IP) on a given day
+// as reported by a provider.
+type Metric struct {
+	Provider       string          `json:"provider"`
+	Source         string          `json:"source"`
+	Date           time.Time       `json:"date"`
+	Reputation     string          `json:"reputation"`
+	SpamRate       float64         `json:"spam_rate"`
+	DeliveryErrors []DeliveryError `json:"delivery_errors"`
+
+	// Provider specific data, eg: SNDS trap hits and message counts.
+	Meta json.RawMessage `json:"meta"`
+}
+
+// Provider is a reputation metrics provider.
+type Provider interface {
+	Name() string
+	Fetch(from, to time.Time) ([]Metric, error)
+}
+
+var reputationRanks = map[string]int{
+	ReputationBad:    1,
+	ReputationLow:    2,
+	ReputationMedium: 3,
+	ReputationHigh:   4,
+}
+
+// Rank returns the numeric rank of a normalized reputation level
+// (higher is better), or 0 if it's unknown.
+func Rank(rep string) int {
+	return reputationRanks[rep]
+}
+
+// IsValid checks whether the given string is a normalized reputation level.
+func IsValid(rep string) bool {
+	_, ok := reputationRanks[rep]
+	return ok
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout < time.Second {
+		timeout = time.Second * 30
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost:   2,
+			ResponseHeaderTimeout: timeout,
+			IdleConnTimeout:       timeout,
+		},
+	}
+}
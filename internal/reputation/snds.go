@@ -0,0 +1,159 @@
+package reputation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sndsURL = "https://sendersupport.olc.protection.outlook.com/snds/data.aspx"
+
+// SNDSOpt represents the Microsoft Smart Network Data Services connector options.
+type SNDSOpt struct {
+	// Automated data access key from the SNDS portal.
+	Key     string
+	Timeout time.Duration
+}
+
+// SNDS fetches daily IP data from Microsoft SNDS.
+type SNDS struct {
+	o      SNDSOpt
+	client *http.Client
+}
+
+type sndsMeta struct {
+	RCPTCommands int    `json:"rcpt_commands"`
+	DATACommands int    `json:"data_commands"`
+	Recipients   int    `json:"recipients"`
+	FilterResult string `json:"filter_result"`
+	TrapHits     int    `json:"trap_hits"`
+}
+
+// NewSNDS returns a new Microsoft SNDS connector.
+func NewSNDS(o SNDSOpt) (*SNDS, error) {
+	if o.Key == "" {
+		return nil, errors.New("snds: key is required")
+	}
+
+	return &SNDS{o: o, client: newHTTPClient(o.Timeout)}, nil
+}
+
+// Name returns the name of the provider.
+func (s *SNDS) Name() string {
+	return ProviderSNDS
+}
+
+// Fetch returns the daily data of all the IPs associated with the key
+// between the given dates (inclusive).
+func (s *SNDS) Fetch(from, to time.Time) ([]Metric, error) {
+	var out []Metric
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		m, err := s.fetchDay(d)
+		if err != nil {
+			return out, fmt.Errorf("snds: %s: %v", d.Format("2006-01-02"), err)
+		}
+		out = append(out, m...)
+	}
+
+	return out, nil
+}
+
+func (s *SNDS) fetchDay(date time.Time) ([]Metric, error) {
+	q := url.Values{}
+	q.Set("key", s.o.Key)
+	q.Set("date", date.Format("010206"))
+
+	resp, err := s.client.Get(sndsURL + "?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response: %d", resp.StatusCode)
+	}
+
+	return parseSNDS(io.LimitReader(resp.Body, maxRespSize), date)
+}
+
+// parseSNDS parses the SNDS CSV export which has no header and has the columns:
+// IP, activity start, activity end, RCPT commands, DATA commands, recipients,
+// filter result, complaint rate, trap period start, trap period end, trap hits,
+// sample HELO, sample MAIL FROM, comments.
+func parseSNDS(r io.Reader, date time.Time) ([]Metric, error) {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.TrimLeadingSpace = true
+
+	recs, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Metric, 0, len(recs))
+	for _, rec := range recs {
+		if len(rec) < 11 {
+			continue
+		}
+
+		meta := sndsMeta{
+			RCPTCommands: atoi(rec[3]),
+			DATACommands: atoi(rec[4]),
+			Recipients:   atoi(rec[5]),
+			FilterResult: strings.ToUpper(strings.TrimSpace(rec[6])),
+			TrapHits:     atoi(rec[10]),
+		}
+
+		m := Metric{
+			Provider:       ProviderSNDS,
+			Source:         strings.TrimSpace(rec[0]),
+			Date:           date,
+			Reputation:     sndsReputation(meta.FilterResult),
+			SpamRate:       parseComplaintRate(rec[7]),
+			DeliveryErrors: []DeliveryError{},
+		}
+		m.Meta, _ = json.Marshal(meta)
+
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+// sndsReputation normalizes an SNDS filter result. GREEN is < 10% of
+// messages filtered as spam, YELLOW is 10-90%, and RED is > 90%.
+func sndsReputation(s string) string {
+	switch s {
+	case "GREEN":
+		return ReputationHigh
+	case "YELLOW":
+		return ReputationLow
+	case "RED":
+		return ReputationBad
+	}
+
+	return ReputationUnknown
+}
+
+// parseComplaintRate parses an SNDS complaint rate, eg: "< 0.1%" or "0.3%", into a ratio.
+func parseComplaintRate(s string) float64 {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "<"))
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0
+	}
+
+	return f / 100
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
@@ -0,0 +1,234 @@
+// Package spamcheck implements clients for scoring messages with Rspamd
+// (HTTP API) and SpamAssassin (spamd protocol) before they're sent out.
+package spamcheck
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TypeRspamd       = "rspamd"
+	TypeSpamAssassin = "spamassassin"
+
+	// maxRespSize is the maximum size of a checker's response.
+	maxRespSize = 1024 * 1024
+)
+
+// Opt represents the spam checker options.
+type Opt struct {
+	Type string
+
+	// HTTP URL of the Rspamd controller or normal worker, eg: http://localhost:11333,
+	// or the host:port of spamd, eg: localhost:783.
+	URL      string
+	Password string
+	Timeout  time.Duration
+}
+
+// Rule is a spam rule (Rspamd symbol) triggered by a message.
+type Rule struct {
+	Name        string  `json:"name"`
+	Score       float64 `json:"score"`
+	Description string  `json:"description"`
+}
+
+// Result is the spam score of a message and the rules it triggered.
+type Result struct {
+	Score float64 `json:"score"`
+	Rules []Rule  `json:"rules"`
+}
+
+// Checker is the spam checker client.
+type Checker struct {
+	o      Opt
+	client *http.Client
+}
+
+var (
+	// SpamAssassin report line, eg: " 1.2 HTML_MESSAGE           BODY: HTML included in message"
+	reSAReportLine = regexp.MustCompile(`^\s*(-?\d+(?:\.\d+)?)\s+([A-Z0-9_]+)\s+(.*)$`)
+
+	// SpamAssassin spam header, eg: "Spam: True ; 15.0 / 5.0"
+	reSASpamHeader = regexp.MustCompile(`^Spam:\s*\w+\s*;\s*(-?\d+(?:\.\d+)?)\s*/`)
+)
+
+// New returns a new instance of the spam checker client.
+func New(o Opt) (*Checker, error) {
+	if o.Type != TypeRspamd && o.Type != TypeSpamAssassin {
+		return nil, fmt.Errorf("unknown spam checker type: %s", o.Type)
+	}
+	if o.Timeout < time.Second {
+		o.Timeout = time.Second * 10
+	}
+
+	return &Checker{
+		o: o,
+		client: &http.Client{
+			Timeout: o.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost:   10,
+				MaxConnsPerHost:       10,
+				ResponseHeaderTimeout: o.Timeout,
+				IdleConnTimeout:       o.Timeout,
+			},
+		}}, nil
+}
+
+// Check submits a raw RFC 5322 message to the checker and returns its score
+// and the rules that it triggered, sorted by score.
+func (c *Checker) Check(msg []byte) (Result, error) {
+	var (
+		res Result
+		err error
+	)
+	switch c.o.Type {
+	case TypeRspamd:
+		res, err = c.checkRspamd(msg)
+	case TypeSpamAssassin:
+		res, err = c.checkSpamAssassin(msg)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	sort.SliceStable(res.Rules, func(i, j int) bool {
+		return res.Rules[i].Score > res.Rules[j].Score
+	})
+
+	return res, nil
+}
+
+// checkRspamd submits the message to Rspamd's /checkv2 HTTP endpoint.
+func (c *Checker) checkRspamd(msg []byte) (Result, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(c.o.URL, "/")+"/checkv2", bytes.NewReader(msg))
+	if err != nil {
+		return Result{}, err
+	}
+	if c.o.Password != "" {
+		req.Header.Set("Password", c.o.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRespSize))
+	if err != nil {
+		return Result{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("rspamd returned %d", resp.StatusCode)
+	}
+
+	var r struct {
+		Score   float64 `json:"score"`
+		Symbols map[string]struct {
+			Name        string  `json:"name"`
+			Score       float64 `json:"score"`
+			Description string  `json:"description"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil {
+		return Result{}, fmt.Errorf("error parsing rspamd response: %v", err)
+	}
+
+	out := Result{Score: r.Score, Rules: make([]Rule, 0, len(r.Symbols))}
+	for name, s := range r.Symbols {
+		out.Rules = append(out.Rules, Rule{Name: name, Score: s.Score, Description: s.Description})
+	}
+
+	return out, nil
+}
+
+// checkSpamAssassin submits the message to spamd with the REPORT command
+// and parses the score and the rule table from the response.
+func (c *Checker) checkSpamAssassin(msg []byte) (Result, error) {
+	conn, err := net.DialTimeout("tcp", c.o.URL, c.o.Timeout)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.o.Timeout))
+
+	if _, err := fmt.Fprintf(conn, "REPORT SPAMC/1.5\r\nContent-length: %d\r\n\r\n", len(msg)); err != nil {
+		return Result{}, err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return Result{}, err
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.CloseWrite()
+	}
+
+	var (
+		out     = Result{Rules: []Rule{}}
+		sc      = bufio.NewScanner(io.LimitReader(conn, maxRespSize))
+		isFirst = true
+		isBody  = false
+		isTable = false
+		hasHdr  = false
+	)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+
+		// Response status line, eg: SPAMD/1.1 0 EX_OK
+		if isFirst {
+			isFirst = false
+			if f := strings.Fields(line); len(f) < 3 || f[1] != "0" {
+				return Result{}, fmt.Errorf("spamd returned: %s", line)
+			}
+			continue
+		}
+
+		// Response headers.
+		if !isBody {
+			if line == "" {
+				isBody = true
+				continue
+			}
+			if m := reSASpamHeader.FindStringSubmatch(line); m != nil {
+				out.Score, _ = strconv.ParseFloat(m[1], 64)
+				hasHdr = true
+			}
+			continue
+		}
+
+		// The rule table in the report starts after the "---- ----" separator.
+		if !isTable {
+			isTable = strings.HasPrefix(line, "----")
+			continue
+		}
+
+		if m := reSAReportLine.FindStringSubmatch(line); m != nil {
+			score, _ := strconv.ParseFloat(m[1], 64)
+			out.Rules = append(out.Rules, Rule{Name: m[2], Score: score, Description: strings.TrimSpace(m[3])})
+		} else if n := len(out.Rules); n > 0 && strings.TrimSpace(line) != "" {
+			// Wrapped description.
+			out.Rules[n-1].Description += " " + strings.TrimSpace(line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Result{}, err
+	}
+
+	if !hasHdr {
+		return Result{}, errors.New("spamd response has no score")
+	}
+
+	return out, nil
+}
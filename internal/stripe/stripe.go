@@ -0,0 +1,195 @@
+// Package stripe implements verification and parsing of Stripe webhook events
+// for syncing paid subscriptions to lists. It doesn't make any calls to the
+// Stripe API and only relies on the data in the webhook events.
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types that are processed.
+const (
+	EventCustomerCreated     = "customer.created"
+	EventCustomerUpdated     = "customer.updated"
+	EventCheckoutCompleted   = "checkout.session.completed"
+	EventInvoicePaid         = "invoice.paid"
+	EventSubscriptionCreated = "customer.subscription.created"
+	EventSubscriptionUpdated = "customer.subscription.updated"
+	EventSubscriptionDeleted = "customer.subscription.deleted"
+	EventChargeRefunded      = "charge.refunded"
+)
+
+// Subscription statuses. StatusRefunded is not a Stripe status and is recorded
+// when the latest invoice of a subscription is refunded.
+const (
+	StatusActive            = "active"
+	StatusTrialing          = "trialing"
+	StatusCanceled          = "canceled"
+	StatusUnpaid            = "unpaid"
+	StatusIncompleteExpired = "incomplete_expired"
+	StatusRefunded          = "refunded"
+)
+
+// DefaultTolerance is the max. age of a signed event that's accepted.
+const DefaultTolerance = time.Minute * 5
+
+// Event is a Stripe webhook event.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Customer is the object in customer.* events.
+type Customer struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+// CheckoutSession is the object in checkout.session.* events.
+type CheckoutSession struct {
+	Customer        string `json:"customer"`
+	CustomerEmail   string `json:"customer_email"`
+	CustomerDetails struct {
+		Email string `json:"email"`
+	} `json:"customer_details"`
+}
+
+// Invoice is the object in invoice.* events.
+type Invoice struct {
+	Customer      string `json:"customer"`
+	CustomerEmail string `json:"customer_email"`
+}
+
+// Subscription is the object in customer.subscription.* events.
+type Subscription struct {
+	ID            string `json:"id"`
+	Customer      string `json:"customer"`
+	Status        string `json:"status"`
+	LatestInvoice string `json:"latest_invoice"`
+	Items         struct {
+		Data []struct {
+			Price struct {
+				Product string `json:"product"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// Charge is the object in charge.* events.
+type Charge struct {
+	Customer string `json:"customer"`
+	Invoice  string `json:"invoice"`
+	Refunded bool   `json:"refunded"`
+}
+
+// Products returns the unique product IDs of a subscription's items.
+func (s Subscription) Products() []string {
+	var (
+		out  = make([]string, 0, len(s.Items.Data))
+		seen = map[string]bool{}
+	)
+	for _, it := range s.Items.Data {
+		if p := it.Price.Product; p != "" && !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+// IsEntitled returns true if a subscription status entitles the customer to the
+// subscription's products.
+func IsEntitled(status string) bool {
+	return status == StatusActive || status == StatusTrialing
+}
+
+// IsEnded returns true if a subscription status has ended the customer's entitlement.
+// Other statuses (eg: incomplete, past_due) don't change it.
+func IsEnded(status string) bool {
+	switch status {
+	case StatusCanceled, StatusUnpaid, StatusIncompleteExpired, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// ParseEvent verifies the Stripe-Signature header of a webhook payload against the
+// endpoint's signing secret and returns the event.
+func ParseEvent(b []byte, sigHeader, secret string, tolerance time.Duration) (Event, error) {
+	if err := verify(b, sigHeader, secret, tolerance); err != nil {
+		return Event{}, err
+	}
+
+	var ev Event
+	if err := json.Unmarshal(b, &ev); err != nil {
+		return Event{}, fmt.Errorf("error parsing event: %v", err)
+	}
+
+	return ev, nil
+}
+
+// verify checks the signature header (t=timestamp,v1=signature,...) where the
+// signature is the hex HMAC-SHA256 of "timestamp.payload" with the secret.
+func verify(b []byte, sigHeader, secret string, tolerance time.Duration) error {
+	if secret == "" {
+		return errors.New("no signing secret")
+	}
+
+	var (
+		ts   int64
+		sigs [][]byte
+	)
+	for _, p := range strings.Split(sigHeader, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			continue
+		}
+
+		switch k {
+		case "t":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return errors.New("invalid signature timestamp")
+			}
+			ts = n
+		case "v1":
+			if s, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, s)
+			}
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return errors.New("invalid signature header")
+	}
+
+	if d := time.Since(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return errors.New("signature timestamp outside the tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(b)
+	expected := mac.Sum(nil)
+
+	for _, s := range sigs {
+		if hmac.Equal(s, expected) {
+			return nil
+		}
+	}
+
+	return errors.New("invalid signature")
+}
@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/knadh/listmonk/internal/i18n"
@@ -48,6 +49,24 @@ const (
 	ModeBlocklist = "blocklist"
 )
 
+// Reasons for which an e-mail address is flagged by the address filter.
+const (
+	AddressFlagRoleAccount = "role_account"
+	AddressFlagSpamTrap    = "spam_trap"
+
+	// AttribAddressFlag is the subscriber attribute that records the reason
+	// for which an address was flagged.
+	AttribAddressFlag = "address_flag"
+
+	// AttribAddressFilterOverride is the subscriber attribute that, when true,
+	// exempts an address from the filter for legitimate cases.
+	AttribAddressFilterOverride = "address_filter_override"
+
+	// AttribTimezone is the subscriber attribute with the IANA time zone
+	// (eg: Asia/Kolkata) campaigns sent at local time are sent in.
+	AttribTimezone = "timezone"
+)
+
 // Importer represents the bulk CSV subscriber import system.
 type Importer struct {
 	opt                   Options
@@ -55,6 +74,8 @@ type Importer struct {
 	i18n                  *i18n.I18n
 	domainBlocklist       map[string]bool
 	hasBlocklistWildcards bool
+	roleAccounts          map[string]bool
+	spamTraps             []*regexp.Regexp
 
 	stop   chan bool
 	status Status
@@ -70,6 +91,11 @@ type Options struct {
 
 	// Lookup table for blocklisted domains.
 	DomainBlocklist []string
+
+	// Local parts of role addresses (postmaster, abuse ...) and regexp
+	// patterns of known spam-trap addresses for the address filter.
+	RoleAccounts     []string
+	SpamTrapPatterns []string
 }
 
 // Session represents a single import session.
@@ -89,6 +115,16 @@ type SessionOpt struct {
 	Overwrite bool   `json:"overwrite"`
 	Delim     string `json:"delim"`
 	ListIDs   []int  `json:"lists"`
+
+	// AddressFilter is the address filter mode (models.ListAddressFilter*) of
+	// the lists being imported into.
+	AddressFilter string `json:"-"`
+
+	// ConfirmedBy is the source recorded in the meta of confirmed subscriptions.
+	ConfirmedBy string `json:"-"`
+
+	// Source is recorded on new subscriptions, eg: import:{run}.
+	Source string `json:"-"`
 }
 
 // Status represents statistics from an ongoing import session.
@@ -135,6 +171,7 @@ func New(opt Options, db *sql.DB, i *i18n.I18n) *Importer {
 		db:              db,
 		i18n:            i,
 		domainBlocklist: make(map[string]bool, len(opt.DomainBlocklist)),
+		roleAccounts:    make(map[string]bool, len(opt.RoleAccounts)),
 		status:          Status{Status: StatusNone, logBuf: bytes.NewBuffer(nil)},
 		stop:            make(chan bool, 1),
 	}
@@ -152,6 +189,19 @@ func New(opt Options, db *sql.DB, i *i18n.I18n) *Importer {
 		}
 	}
 
+	// Address filter.
+	for _, r := range opt.RoleAccounts {
+		im.roleAccounts[strings.ToLower(strings.TrimSpace(r))] = true
+	}
+	for _, p := range opt.SpamTrapPatterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			log.Printf("ignoring invalid spam-trap pattern '%s': %v", p, err)
+			continue
+		}
+		im.spamTraps = append(im.spamTraps, re)
+	}
+
 	return &im
 }
 
@@ -270,6 +320,15 @@ func (s *Session) Start() {
 		listIDs[i] = v
 	}
 
+	// Record the source of confirmed subscriptions for auditing.
+	meta := []byte(`{}`)
+	if s.opt.SubStatus == models.SubscriptionStatusConfirmed && s.opt.ConfirmedBy != "" {
+		meta, _ = json.Marshal(map[string]interface{}{
+			"confirmed_by": s.opt.ConfirmedBy,
+			"confirmed_at": time.Now(),
+		})
+	}
+
 	for sub := range s.subQueue {
 		if cur == 0 {
 			// New transaction batch.
@@ -294,7 +353,7 @@ func (s *Session) Start() {
 		}
 
 		if s.opt.Mode == ModeSubscribe {
-			_, err = stmt.Exec(uu, sub.Email, sub.Name, sub.Attribs, pq.Array(listIDs), s.opt.SubStatus, s.opt.Overwrite)
+			_, err = stmt.Exec(uu, sub.Email, sub.Name, sub.Attribs, pq.Array(listIDs), s.opt.SubStatus, s.opt.Overwrite, meta, s.opt.Source)
 		} else if s.opt.Mode == ModeBlocklist {
 			_, err = stmt.Exec(uu, sub.Email, sub.Name, sub.Attribs)
 		}
@@ -561,6 +620,14 @@ func (s *Session) LoadCSV(srcPath string, delim rune) error {
 			}
 		}
 
+		// Flag or skip role and spam-trap addresses.
+		if s.opt.Mode == ModeSubscribe {
+			if sub, err = s.im.FilterAddress(sub, s.opt.AddressFilter); err != nil {
+				s.log.Printf("skipping line %d: %s: %v", i, sub.Email, err)
+				continue
+			}
+		}
+
 		// Send the subscriber to the queue.
 		s.subQueue <- sub
 	}
@@ -654,6 +721,84 @@ func (im *Importer) ValidateFields(s SubReq) (SubReq, error) {
 		s.Name = strings.Join(parts, " ")
 	}
 
+	if err := im.ValidateTimezone(s.Attribs); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// ValidateTimezone validates the optional time zone attribute of a subscriber.
+func (im *Importer) ValidateTimezone(attribs models.JSON) error {
+	v, ok := attribs[AttribTimezone]
+	if !ok {
+		return nil
+	}
+
+	tz, ok := v.(string)
+	if !ok || tz == "" || tz == "Local" {
+		return errors.New(im.i18n.T("subscribers.invalidTimezone"))
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return errors.New(im.i18n.T("subscribers.invalidTimezone"))
+	}
+
+	return nil
+}
+
+// CheckAddress checks whether an e-mail is a role address (postmaster@, abuse@ ...)
+// or matches a known spam-trap pattern and returns the reason (AddressFlag*).
+// An empty string is returned for addresses that aren't flagged.
+func (im *Importer) CheckAddress(email string) string {
+	local, _, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return ""
+	}
+
+	// Ignore +tags in the local part. eg: noreply+list@domain.com
+	local, _, _ = strings.Cut(local, "+")
+	if im.roleAccounts[local] {
+		return AddressFlagRoleAccount
+	}
+
+	for _, re := range im.spamTraps {
+		if re.MatchString(email) {
+			return AddressFlagSpamTrap
+		}
+	}
+
+	return ""
+}
+
+// FilterAddress applies an address filter mode (models.ListAddressFilter*) to a
+// subscriber. Flagged addresses get the reason recorded in their attributes in the
+// flag mode and an error is returned in the reject mode. Subscribers with the
+// override attribute set to true are let through as-is.
+func (im *Importer) FilterAddress(s SubReq, mode string) (SubReq, error) {
+	if mode == "" || mode == models.ListAddressFilterNone {
+		return s, nil
+	}
+	if v, ok := s.Attribs[AttribAddressFilterOverride].(bool); ok && v {
+		return s, nil
+	}
+
+	flag := im.CheckAddress(s.Email)
+	if flag == "" {
+		return s, nil
+	}
+
+	if mode == models.ListAddressFilterReject {
+		if flag == AddressFlagRoleAccount {
+			return s, errors.New(im.i18n.T("subscribers.roleAccountRejected"))
+		}
+		return s, errors.New(im.i18n.T("subscribers.spamTrapRejected"))
+	}
+
+	if s.Attribs == nil {
+		s.Attribs = models.JSON{}
+	}
+	s.Attribs[AttribAddressFlag] = flag
+
 	return s, nil
 }
 
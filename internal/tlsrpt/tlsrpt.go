@@ -0,0 +1,160 @@
+// Package tlsrpt parses SMTP TLS reports (TLS-RPT, RFC 8460). Reports are JSON,
+// usually gzipped, and are either uploaded as is or as the raw e-mail they were
+// delivered in.
+package tlsrpt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxReportSize is the max. uncompressed size of a report.
+const maxReportSize = 20 * 1024 * 1024
+
+// Report is an aggregate TLS report.
+type Report struct {
+	OrganizationName string `json:"organization-name"`
+	DateRange        struct {
+		Start time.Time `json:"start-datetime"`
+		End   time.Time `json:"end-datetime"`
+	} `json:"date-range"`
+	ContactInfo string   `json:"contact-info"`
+	ReportID    string   `json:"report-id"`
+	Policies    []Policy `json:"policies"`
+}
+
+// Policy is the result of the sessions with a policy domain.
+type Policy struct {
+	Policy struct {
+		Type   string   `json:"policy-type"`
+		String []string `json:"policy-string"`
+		Domain string   `json:"policy-domain"`
+		MXHost []string `json:"mx-host"`
+	} `json:"policy"`
+	Summary struct {
+		Successful int `json:"total-successful-session-count"`
+		Failed     int `json:"total-failure-session-count"`
+	} `json:"summary"`
+	FailureDetails []Failure `json:"failure-details"`
+}
+
+// Failure is a type of failed session with a receiving MX.
+type Failure struct {
+	ResultType          string `json:"result-type"`
+	SendingMTAIP        string `json:"sending-mta-ip,omitempty"`
+	ReceivingMXHostname string `json:"receiving-mx-hostname,omitempty"`
+	ReceivingMXHelo     string `json:"receiving-mx-helo,omitempty"`
+	ReceivingIP         string `json:"receiving-ip,omitempty"`
+	FailedSessionCount  int    `json:"failed-session-count"`
+	AdditionalInfo      string `json:"additional-information,omitempty"`
+	FailureReasonCode   string `json:"failure-reason-code,omitempty"`
+}
+
+var errNoReport = errors.New("no TLS report found")
+
+// Parse parses the reports in a JSON report, a gzipped JSON report, or a raw
+// e-mail with reports attached.
+func Parse(b []byte) ([]Report, error) {
+	switch {
+	// gzip magic bytes.
+	case len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		u, err := io.ReadAll(io.LimitReader(r, maxReportSize))
+		if err != nil {
+			return nil, err
+		}
+		return Parse(u)
+
+	case bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")):
+		var r Report
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("error parsing report: %v", err)
+		}
+		if r.ReportID == "" {
+			return nil, errors.New("report has no report-id")
+		}
+		return []Report{r}, nil
+	}
+
+	msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		return nil, errNoReport
+	}
+
+	out, err := parsePart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errNoReport
+	}
+
+	return out, nil
+}
+
+// parsePart parses the reports in a MIME part, recursing into multipart parts.
+func parsePart(contentType, encoding string, body io.Reader) ([]Report, error) {
+	typ, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(typ, "multipart/") {
+		var (
+			mr  = multipart.NewReader(body, params["boundary"])
+			out []Report
+		)
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			r, err := parsePart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r...)
+		}
+		return out, nil
+	}
+
+	// application/tlsrpt+gzip and application/tlsrpt+json.
+	if !strings.HasPrefix(typ, "application/tlsrpt+") {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(body, maxReportSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(b)
+}
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"net/mail"
 	"net/textproto"
+	"net/url"
 	"regexp"
 	"strings"
 	txttpl "text/template"
@@ -49,18 +51,79 @@ const (
 	CampaignContentTypeMarkdown = "markdown"
 	CampaignContentTypePlain    = "plain"
 
+	// Phases of a campaign's A/B test.
+	CampaignABPhaseTesting = "testing"
+	CampaignABPhaseWaiting = "waiting"
+	CampaignABPhaseWinner  = "winner"
+	CampaignABMetricOpens  = "opens"
+	CampaignABMetricClicks = "clicks"
+
 	// List.
 	ListTypePrivate = "private"
 	ListTypePublic  = "public"
 	ListOptinSingle = "single"
 	ListOptinDouble = "double"
 
+	// Address filter modes for role and spam-trap addresses.
+	ListAddressFilterNone   = "none"
+	ListAddressFilterFlag   = "flag"
+	ListAddressFilterReject = "reject"
+
+	// Types of the subscriber attribute fields of lists on public forms.
+	ListFieldText     = "text"
+	ListFieldNumber   = "number"
+	ListFieldCheckbox = "checkbox"
+	ListFieldSelect   = "select"
+
+	// List statuses. Archived lists are hidden from public forms and pages.
+	ListStatusActive   = "active"
+	ListStatusArchived = "archived"
+
+	// Bulk list actions.
+	ListActionTag       = "tag"
+	ListActionUntag     = "untag"
+	ListActionFolder    = "folder"
+	ListActionOptin     = "optin"
+	ListActionArchive   = "archive"
+	ListActionUnarchive = "unarchive"
+	ListActionDelete    = "delete"
+
+	// Campaign goal types.
+	GoalTypeLink       = "link"
+	GoalTypePixel      = "pixel"
+	GoalTypeConversion = "conversion"
+
+	// Post-campaign action conditions and actions.
+	CampaignActionOpened     = "opened"
+	CampaignActionNotOpened  = "not_opened"
+	CampaignActionClicked    = "clicked"
+	CampaignActionNotClicked = "not_clicked"
+	CampaignActionAddTag     = "add_tag"
+	CampaignActionAddList    = "add_list"
+	CampaignActionMoveList   = "move_list"
+
+	// List re-permission.
+	RepermissionStatusRunning   = "running"
+	RepermissionStatusFinished  = "finished"
+	RepermissionStatusCancelled = "cancelled"
+
+	// Drip sequences and the statuses of their subscribers.
+	SequenceStatusActive           = "active"
+	SequenceStatusPaused           = "paused"
+	SequenceSubscriberStatusActive = "active"
+	SequenceSubscriberFinished     = "finished"
+	SequenceSubscriberExited       = "exited"
+
 	// User.
 	UserTypeSuperadmin = "superadmin"
 	UserTypeUser       = "user"
 	UserStatusEnabled  = "enabled"
 	UserStatusDisabled = "disabled"
 
+	// API token roles. Read-only tokens can only access read scopes.
+	APITokenRoleFull     = "full"
+	APITokenRoleReadOnly = "read_only"
+
 	// BaseTpl is the name of the base template.
 	BaseTpl = "base"
 
@@ -78,16 +141,114 @@ const (
 	EmailHeaderMessageId   = "Message-Id"
 	EmailHeaderDeliveredTo = "Delivered-To"
 	EmailHeaderReceived    = "Received"
+	EmailHeaderTo          = "To"
+	EmailHeaderReplyTo     = "Reply-To"
+	EmailHeaderReturnPath  = "Return-Path"
 
 	BounceTypeHard      = "hard"
 	BounceTypeSoft      = "soft"
 	BounceTypeComplaint = "complaint"
 
+	// Campaign send log statuses.
+	SendStatusQueued   = "queued"
+	SendStatusSent     = "sent"
+	SendStatusDeferred = "deferred"
+	SendStatusBounced  = "bounced"
+
+	// Sent message archive modes.
+	SentArchiveOff     = "off"
+	SentArchiveContent = "content"
+
 	// Templates.
 	TemplateTypeCampaign = "campaign"
 	TemplateTypeTx       = "tx"
+
+	// Trashed item types.
+	TrashTypeCampaign = "campaign"
+	TrashTypeList     = "list"
+	TrashTypeTemplate = "template"
+
+	// Notification (alert) types.
+	NotificationCampaign  = "campaign"
+	NotificationImport    = "import"
+	NotificationBounce    = "bounce"
+	NotificationMessenger = "messenger"
+	NotificationMention   = "mention"
+
+	// Notification delivery channels.
+	NotificationChannelFeed  = "feed"
+	NotificationChannelEmail = "email"
+
+	// Collections that views can be saved for.
+	SavedViewSubscribers = "subscribers"
+	SavedViewCampaigns   = "campaigns"
+	SavedViewBounces     = "bounces"
+
+	// Autocomplete (typeahead) suggestion types.
+	AutocompleteSubscribers = "subscribers"
+	AutocompleteLists       = "lists"
+	AutocompleteTemplates   = "templates"
+	AutocompleteTags        = "tags"
+	AutocompleteCampaigns   = "campaigns"
+
+	// Audit log actions.
+	AuditSettingsUpdate    = "settings.update"
+	AuditCampaignStatus    = "campaign.status"
+	AuditSubscriberDelete  = "subscriber.delete"
+	AuditSubscriberDeleteQ = "subscriber.delete_query"
+	AuditAPITokenCreate    = "api_token.create"
+	AuditAPITokenRotate    = "api_token.rotate"
+	AuditAPITokenDelete    = "api_token.delete"
+
+	// Subscriber status rule conditions and actions.
+	StatusRuleUnconfirmed = "unconfirmed"
+	StatusRuleBounces     = "bounces"
+	StatusRuleUnsubscribe = "unsubscribe"
+	StatusRuleBlocklist   = "blocklist"
+
+	// Global search result types.
+	SearchCampaigns   = "campaigns"
+	SearchTemplates   = "templates"
+	SearchSubscribers = "subscribers"
+	SearchLists       = "lists"
+
+	// Sunset (win-back) actions.
+	SunsetActionUnsubscribe = "unsubscribe"
+	SunsetActionBlocklist   = "blocklist"
+
+	// List hygiene categories and actions.
+	HygieneCategoryInvalidSyntax = "invalid_syntax"
+	HygieneCategoryRoleAccount   = "role_account"
+	HygieneCategoryDeadDomain    = "dead_domain"
+	HygieneCategorySoftBouncer   = "soft_bouncer"
+	HygieneActionUnsubscribe     = "unsubscribe"
+	HygieneActionBlocklist       = "blocklist"
 )
 
+// UTM represents the UTM parameters that are appended to the links in
+// campaigns for attributing visits in web analytics.
+type UTM struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+}
+
+// LinkRedirect is the destination of a click on a tracked link in a campaign.
+type LinkRedirect struct {
+	URL string `db:"url"`
+
+	// The landing page URLs of the campaign's page visit and conversion goals
+	// to which the goal token of the campaign and subscriber is passed on.
+	GoalURLs     pq.StringArray `db:"goal_urls"`
+	CampaignID   int            `db:"campaign_id"`
+	SubscriberID null.Int       `db:"subscriber_id"`
+
+	// The campaign's UTM tagging. See Campaign.UTMParams().
+	CampaignName string    `db:"campaign_name"`
+	UTMEnabled   null.Bool `db:"utm_enabled"`
+	UTMCampaign  string    `db:"utm_campaign"`
+}
+
 // Headers represents an array of string maps used to represent SMTP, HTTP headers etc.
 // similar to url.Values{}
 type Headers []map[string]string
@@ -100,6 +261,9 @@ type regTplFunc struct {
 	replace string
 }
 
+// regexpLinkHref matches the http(s) URLs in the href attributes of links.
+var regexpLinkHref = regexp.MustCompile(`(?i)(href\s*=\s*["'])(https?://[^"'\s]+)`)
+
 var regTplFuncs = []regTplFunc{
 	// Regular expression for matching {{ TrackLink "http://link.com" }} in the template
 	// and substituting it with {{ Track "http://link.com" . }} (the dot context)
@@ -121,6 +285,13 @@ var regTplFuncs = []regTplFunc{
 		regExp:  regexp.MustCompile(`{{(\s+)?(TrackView|UnsubscribeURL|ManageURL|OptinURL|MessageURL)(\s+)?}}`),
 		replace: `{{ $2 . }}`,
 	},
+
+	// Survey blocks, eg: {{ SurveyRating "nps" }} or {{ SurveyChoice "freq" "Daily|Weekly" }}
+	// to {{ SurveyRating "nps" . }}.
+	{
+		regExp:  regexp.MustCompile(`{{(\s+)?(SurveyRating|SurveyChoice|SurveyURL)(\s+)(.+?)(\s+)?}}`),
+		replace: `{{ $2 $4 . }}`,
+	},
 }
 
 // AdminNotifCallback is a callback function that's called
@@ -165,6 +336,10 @@ type Subscriber struct {
 	Attribs JSON           `db:"attribs" json:"attribs"`
 	Status  string         `db:"status" json:"status"`
 	Lists   types.JSONText `db:"lists" json:"lists"`
+
+	// Pseudofield for getting the total number of results
+	// in a paginated query.
+	Total int `db:"total" json:"-"`
 }
 type subLists struct {
 	SubscriberID int            `db:"subscriber_id"`
@@ -218,20 +393,76 @@ type List struct {
 	Optin            string         `db:"optin" json:"optin"`
 	Tags             pq.StringArray `db:"tags" json:"tags"`
 	Description      string         `db:"description" json:"description"`
+	ContentQAURL     string         `db:"content_qa_url" json:"content_qa_url"`
+	EntitlementURL   string         `db:"entitlement_url" json:"entitlement_url"`
+	MaxSubscribers   int            `db:"max_subscribers" json:"max_subscribers"`
+	AddressFilter    string         `db:"address_filter" json:"address_filter"`
+	FormFields       ListFields     `db:"form_fields" json:"form_fields"`
+	ReturnPath       string         `db:"return_path" json:"return_path"`
+	ParentID         null.Int       `db:"parent_id" json:"parent_id"`
+	Folder           string         `db:"folder" json:"folder"`
+	Status           string         `db:"status" json:"status"`
 	SubscriberCount  int            `db:"-" json:"subscriber_count"`
 	SubscriberCounts StringIntMap   `db:"subscriber_statuses" json:"subscriber_statuses"`
 	SubscriberID     int            `db:"subscriber_id" json:"-"`
 
 	// This is only relevant when querying the lists of a subscriber.
-	SubscriptionStatus    string    `db:"subscription_status" json:"subscription_status,omitempty"`
-	SubscriptionCreatedAt null.Time `db:"subscription_created_at" json:"subscription_created_at,omitempty"`
-	SubscriptionUpdatedAt null.Time `db:"subscription_updated_at" json:"subscription_updated_at,omitempty"`
+	SubscriptionStatus       string    `db:"subscription_status" json:"subscription_status,omitempty"`
+	SubscriptionCreatedAt    null.Time `db:"subscription_created_at" json:"subscription_created_at,omitempty"`
+	SubscriptionUpdatedAt    null.Time `db:"subscription_updated_at" json:"subscription_updated_at,omitempty"`
+	SubscriptionSnoozedUntil null.Time `db:"subscription_snoozed_until" json:"subscription_snoozed_until,omitempty"`
 
 	// Pseudofield for getting the total number of subscribers
 	// in searches and queries.
 	Total int `db:"total" json:"-"`
 }
 
+// ListField is a subscriber attribute that's collected on the public
+// subscription form of a list, and if it's editable, on the preferences page.
+type ListField struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options,omitempty"`
+	Required bool     `json:"required"`
+	Editable bool     `json:"editable"`
+}
+
+// ListFields is the ordered set of attribute fields of a list.
+type ListFields []ListField
+
+// ListMerge is the result of merging a list into another: the number of
+// subscriptions merged and the number of campaigns retargeted.
+type ListMerge struct {
+	Found       bool `db:"found" json:"-"`
+	Subscribers int  `db:"subscribers" json:"subscribers"`
+	Campaigns   int  `db:"campaigns" json:"campaigns"`
+}
+
+// ListGrowth represents the new subscriptions to a list from a source in an interval.
+type ListGrowth struct {
+	Date   time.Time `db:"date" json:"date"`
+	Source string    `db:"source" json:"source"`
+	Count  int       `db:"count" json:"count"`
+
+	// Subscriptions that haven't unsubscribed since.
+	Active int `db:"active" json:"active"`
+}
+
+// ListChurn represents the subscriptions and unsubscriptions of a list in an interval.
+type ListChurn struct {
+	Date         time.Time `db:"date" json:"date"`
+	Subscribed   int       `db:"subscribed" json:"subscribed"`
+	Unsubscribed int       `db:"unsubscribed" json:"unsubscribed"`
+
+	// Subscribers at the start and the end of the interval.
+	StartTotal int `db:"start_total" json:"start_total"`
+	EndTotal   int `db:"-" json:"end_total"`
+
+	Net       int     `db:"-" json:"net"`
+	ChurnRate float64 `db:"-" json:"churn_rate"`
+}
+
 // Campaign represents an e-mail campaign.
 type Campaign struct {
 	Base
@@ -245,16 +476,100 @@ type Campaign struct {
 	Body              string          `db:"body" json:"body"`
 	AltBody           null.String     `db:"altbody" json:"altbody"`
 	SendAt            null.Time       `db:"send_at" json:"send_at"`
+	SendAtTimezone    string          `db:"send_at_timezone" json:"send_at_timezone"`
 	Status            string          `db:"status" json:"status"`
 	ContentType       string          `db:"content_type" json:"content_type"`
 	Tags              pq.StringArray  `db:"tags" json:"tags"`
 	Headers           Headers         `db:"headers" json:"headers"`
+	HeaderPresetID    null.Int        `db:"header_preset_id" json:"header_preset_id"`
 	TemplateID        int             `db:"template_id" json:"template_id"`
 	Messenger         string          `db:"messenger" json:"messenger"`
 	Archive           bool            `db:"archive" json:"archive"`
 	ArchiveSlug       null.String     `db:"archive_slug" json:"archive_slug"`
 	ArchiveTemplateID int             `db:"archive_template_id" json:"archive_template_id"`
 	ArchiveMeta       json.RawMessage `db:"archive_meta" json:"archive_meta"`
+	ContentURL        string          `db:"content_url" json:"content_url"`
+	ContentChecksum   string          `db:"content_checksum" json:"content_checksum"`
+	ReplyTo           string          `db:"reply_to" json:"reply_to"`
+	ReplyTracking     bool            `db:"reply_tracking" json:"reply_tracking"`
+	ReturnPath        string          `db:"return_path" json:"return_path"`
+
+	// Version is incremented on every update for optimistic locking.
+	Version int `db:"version" json:"version"`
+
+	// A/B test of the campaign's variants. ABTestPercent is the percentage of
+	// subscribers the variants are sent to, after which the winning variant is
+	// sent to the rest, ABWaitHours after the test is sent.
+	ABTestPercent int       `db:"ab_test_percent" json:"ab_test_percent"`
+	ABMetric      string    `db:"ab_metric" json:"ab_metric"`
+	ABWaitHours   int       `db:"ab_wait_hours" json:"ab_wait_hours"`
+	ABPhase       string    `db:"ab_phase" json:"ab_phase"`
+	ABPhaseAt     null.Time `db:"ab_phase_at" json:"ab_phase_at"`
+
+	// DependsOn is the campaign after which a scheduled campaign starts,
+	// DependsDelayMins after it has finished (FinishedAt).
+	DependsOn        null.Int  `db:"depends_on" json:"depends_on"`
+	DependsDelayMins int       `db:"depends_delay_mins" json:"depends_delay_mins"`
+	FinishedAt       null.Time `db:"finished_at" json:"finished_at"`
+
+	// Recurrence is the cron expression of a recurring campaign whose runs are
+	// cloned into campaigns with RecurrenceParentID set to it.
+	Recurrence         string   `db:"recurrence" json:"recurrence"`
+	RecurrenceParentID null.Int `db:"recurrence_parent_id" json:"recurrence_parent_id"`
+
+	// FeedURL is an RSS/Atom feed whose entries are fetched and frozen into
+	// FeedItems when the campaign starts. Runs of recurring campaigns only get
+	// the entries published after FeedSince (the previous run).
+	FeedURL   string    `db:"feed_url" json:"feed_url"`
+	FeedSince null.Time `db:"feed_since" json:"feed_since"`
+	FeedItems FeedItems `db:"feed_items" json:"-"`
+
+	// STOWindowHours, when set, sends the campaign to each subscriber at the
+	// hour they've historically opened campaigns the most, within the window.
+	// STOQueuedAt is set once all the subscribers have been queued.
+	STOWindowHours int       `db:"sto_window_hours" json:"sto_window_hours"`
+	STOQueuedAt    null.Time `db:"sto_queued_at" json:"sto_queued_at"`
+
+	// SendAtLocal sends the campaign to each subscriber at the wall-clock time
+	// of SendAt in their timezone attribute. Like with send-time optimization,
+	// the subscribers are queued and STOQueuedAt is set once they all are.
+	SendAtLocal bool `db:"send_at_local" json:"send_at_local"`
+
+	// SendRate is the max. number of messages sent per minute (0 for no limit)
+	// and SendWindows, the days and hours (in SendAtTimezone) that messages are
+	// sent in. Outside of them, the campaign waits and resumes in the next window.
+	SendRate    int         `db:"send_rate" json:"send_rate"`
+	SendWindows SendWindows `db:"send_windows" json:"send_windows"`
+
+	// ResendOf is the finished campaign of which this campaign is a follow-up
+	// that's only sent to the subscribers who didn't open it.
+	ResendOf null.Int `db:"resend_of" json:"resend_of"`
+
+	// ExcludeListIDs are the lists whose subscribers (and those of their
+	// child lists) aren't sent the campaign even if they're on its lists.
+	ExcludeListIDs pq.Int64Array `db:"exclude_list_ids" json:"exclude_lists"`
+
+	// UTMEnabled, when set, overrides the global setting for appending UTM
+	// parameters to the campaign's links. UTMCampaign is the utm_campaign
+	// value, which is the campaign's name if it's empty.
+	UTMEnabled  null.Bool `db:"utm_enabled" json:"utm_enabled"`
+	UTMCampaign string    `db:"utm_campaign" json:"utm_campaign"`
+
+	// LinkUTM, if set, is appended to the links in the campaign's body and
+	// template when it's compiled. See UTMParams().
+	LinkUTM *UTM `db:"-" json:"-"`
+
+	// LastSubscriberID is the checkpoint (ID of the last subscriber processed)
+	// of a running campaign.
+	LastSubscriberID int `db:"last_subscriber_id" json:"-"`
+
+	// PresetHeaders are the headers of the campaign's header preset, joined in
+	// by the next-campaigns and preview queries.
+	PresetHeaders Headers `db:"preset_headers" json:"-"`
+
+	// ListReturnPath is the return path of the first of the campaign's lists
+	// that has one, joined in by the next-campaigns query.
+	ListReturnPath string `db:"list_return_path" json:"-"`
 
 	// TemplateBody is joined in from templates by the next-campaigns query.
 	TemplateBody        string             `db:"template_body" json:"-"`
@@ -274,12 +589,38 @@ type Campaign struct {
 	Total int `db:"total" json:"-"`
 }
 
+// FeedItem represents an entry of the RSS/Atom feed of a campaign.
+type FeedItem struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description"`
+	Content     string    `json:"content"`
+	Author      string    `json:"author"`
+	Published   null.Time `json:"published"`
+}
+
+// FeedItems represents the frozen entries of a campaign's feed.
+type FeedItems []FeedItem
+
+// SendWindow is a time window (HH:MM, end exclusive) on the given days of the
+// week (0 = Sunday) in which a campaign's messages are sent. A window whose end
+// is before its start spans midnight.
+type SendWindow struct {
+	Days  []int  `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// SendWindows represents the send windows of a campaign.
+type SendWindows []SendWindow
+
 // CampaignMeta contains fields tracking a campaign's progress.
 type CampaignMeta struct {
 	CampaignID int `db:"campaign_id" json:"-"`
 	Views      int `db:"views" json:"views"`
 	Clicks     int `db:"clicks" json:"clicks"`
 	Bounces    int `db:"bounces" json:"bounces"`
+	Replies    int `db:"replies" json:"replies"`
 
 	// This is a list of {list_id, name} pairs unlike Subscriber.Lists[]
 	// because lists can be deleted after a campaign is finished, resulting
@@ -305,6 +646,15 @@ type CampaignStats struct {
 	NetRate   int       `json:"net_rate"`
 }
 
+// MessengerVolume represents the number of campaigns and messages sent by a
+// messenger, and the source IPs of its SMTP servers.
+type MessengerVolume struct {
+	Messenger string   `db:"messenger" json:"messenger"`
+	SourceIPs []string `db:"-" json:"source_ips"`
+	Campaigns int      `db:"campaigns" json:"campaigns"`
+	Sent      int      `db:"sent" json:"sent"`
+}
+
 type CampaignAnalyticsCount struct {
 	CampaignID int       `db:"campaign_id" json:"campaign_id"`
 	Count      int       `db:"count" json:"count"`
@@ -329,6 +679,11 @@ type Template struct {
 	Type      string `db:"type" json:"type"`
 	Body      string `db:"body" json:"body,omitempty"`
 	IsDefault bool   `db:"is_default" json:"is_default"`
+	Version   int    `db:"version" json:"version"`
+
+	// HeaderPresetID is the header preset attached to messages sent with
+	// the template. Only relevant to tx templates.
+	HeaderPresetID null.Int `db:"header_preset_id" json:"header_preset_id"`
 
 	// Only relevant to tx (transactional) templates.
 	SubjectTpl *txttpl.Template   `json:"-"`
@@ -356,6 +711,578 @@ type Bounce struct {
 	Total int `db:"total" json:"-"`
 }
 
+// CampaignReply represents a reply to a campaign received on a plus-tagged
+// reply-to address.
+type CampaignReply struct {
+	CampaignUUID string    `json:"campaign_uuid"`
+	Email        string    `json:"email"`
+	MessageID    string    `json:"message_id"`
+	Source       string    `json:"source"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ListHygiene represents the number of subscribers in a list
+// that fall into a list hygiene category.
+type ListHygiene struct {
+	ListID    int       `db:"list_id" json:"list_id"`
+	Category  string    `db:"category" json:"category"`
+	Count     int       `db:"count" json:"count"`
+	UpdatedAt null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CampaignGoal represents a step in a campaign's ordered set of goals (funnel).
+// Link goals are reached by clicking the tracked link URL in the campaign and
+// pixel (page visit) and conversion goals by hitting the goal endpoints with the key.
+// The URL of pixel and conversion goals is their (optional) landing page.
+type CampaignGoal struct {
+	ID         int       `db:"id" json:"id"`
+	CampaignID int       `db:"campaign_id" json:"campaign_id"`
+	Position   int       `db:"position" json:"position"`
+	Name       string    `db:"name" json:"name"`
+	Type       string    `db:"type" json:"type"`
+	URL        string    `db:"url" json:"url"`
+	Key        string    `db:"key" json:"key"`
+	CreatedAt  null.Time `db:"created_at" json:"created_at"`
+}
+
+// CampaignAction represents a post-campaign action that tags the subscribers
+// who match its condition or adds (moves) them to a list. It's run once,
+// DelayHours after the campaign finishes.
+type CampaignAction struct {
+	ID         int       `db:"id" json:"id"`
+	CampaignID int       `db:"campaign_id" json:"campaign_id"`
+	Condition  string    `db:"condition" json:"condition"`
+	URL        string    `db:"url" json:"url"`
+	Action     string    `db:"action" json:"action"`
+	Tag        string    `db:"tag" json:"tag"`
+	ListID     null.Int  `db:"list_id" json:"list_id"`
+	ListName   string    `db:"list_name" json:"list_name"`
+	DelayHours int       `db:"delay_hours" json:"delay_hours"`
+	Affected   int       `db:"affected" json:"affected"`
+	RanAt      null.Time `db:"ran_at" json:"ran_at"`
+	CreatedAt  null.Time `db:"created_at" json:"created_at"`
+}
+
+// CampaignSurvey represents the results of a survey question (key) in a campaign.
+type CampaignSurvey struct {
+	Key       string       `db:"key" json:"key"`
+	Responses int          `db:"responses" json:"responses"`
+	AvgScore  null.Float64 `db:"avg_score" json:"avg_score"`
+
+	// NPS is the net promoter score of rating questions, ie, the % of
+	// promoters (9-10) minus the % of detractors (1-6).
+	NPS null.Float64 `db:"nps" json:"nps"`
+
+	// Answers is the list of {answer, count} ordered by count.
+	Answers types.JSONText `db:"answers" json:"answers"`
+}
+
+// SendingDomain represents a domain in the registry of sending domains.
+type SendingDomain struct {
+	ID     int    `db:"id" json:"id"`
+	Domain string `db:"domain" json:"domain"`
+
+	// Allowed from addresses on the domain. Empty for any address.
+	Addresses pq.StringArray `db:"addresses" json:"addresses"`
+
+	// Ownership verification token that's published as a TXT record.
+	Token      string    `db:"token" json:"token"`
+	Verified   bool      `db:"verified" json:"verified"`
+	VerifiedAt null.Time `db:"verified_at" json:"verified_at"`
+	CreatedAt  null.Time `db:"created_at" json:"created_at"`
+	UpdatedAt  null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// StatusRule represents a rule that automatically transitions the status of
+// subscribers matching a condition, eg: unsubscribes subscriptions that are
+// unconfirmed for more than 30 days, or blocklists subscribers with 5 hard
+// bounces across campaigns.
+type StatusRule struct {
+	ID           int       `db:"id" json:"id"`
+	Name         string    `db:"name" json:"name"`
+	Enabled      bool      `db:"enabled" json:"enabled"`
+	Condition    string    `db:"condition" json:"condition"`
+	Threshold    int       `db:"threshold" json:"threshold"`
+	BounceType   string    `db:"bounce_type" json:"bounce_type"`
+	WindowDays   int       `db:"window_days" json:"window_days"`
+	Action       string    `db:"action" json:"action"`
+	LastRunAt    null.Time `db:"last_run_at" json:"last_run_at"`
+	LastAffected int       `db:"last_affected" json:"last_affected"`
+	CreatedAt    null.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Quota represents the sending and subscriber limits of a user. A quota
+// with an empty username is the default of users that don't have one.
+// Limits that are 0 are unlimited.
+type Quota struct {
+	ID                int       `db:"id" json:"id"`
+	Username          string    `db:"username" json:"username"`
+	CampaignsPerMonth int       `db:"campaigns_per_month" json:"campaigns_per_month"`
+	MessagesPerDay    int       `db:"messages_per_day" json:"messages_per_day"`
+	MaxSubscribers    int       `db:"max_subscribers" json:"max_subscribers"`
+	CreatedAt         null.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// QuotaUsage represents a user's usage against their quota.
+type QuotaUsage struct {
+	CampaignsMonth int `db:"campaigns_month" json:"campaigns_month"`
+	MessagesDay    int `db:"messages_day" json:"messages_day"`
+	Subscribers    int `db:"subscribers" json:"subscribers"`
+}
+
+// APIToken represents a scoped API token of a user. Token is the plaintext
+// token, which is only set when the token is created.
+type APIToken struct {
+	ID         int            `db:"id" json:"id"`
+	Name       string         `db:"name" json:"name"`
+	Username   string         `db:"username" json:"username"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	Role       string         `db:"role" json:"role"`
+	ExpiresAt  null.Time      `db:"expires_at" json:"expires_at"`
+	LastUsedAt null.Time      `db:"last_used_at" json:"last_used_at"`
+	CreatedAt  null.Time      `db:"created_at" json:"created_at"`
+
+	Token string `db:"-" json:"token,omitempty"`
+}
+
+// CampaignComment represents a review comment on a campaign.
+type CampaignComment struct {
+	ID         int      `db:"id" json:"id"`
+	CampaignID int      `db:"campaign_id" json:"campaign_id"`
+	ParentID   null.Int `db:"parent_id" json:"parent_id"`
+	Author     string   `db:"author" json:"author"`
+	Body       string   `db:"body" json:"body"`
+
+	// Usernames @mentioned in the body.
+	Mentions  pq.StringArray `db:"mentions" json:"mentions"`
+	CreatedAt null.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt null.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// CampaignGoalStep represents a goal in a campaign's funnel report.
+type CampaignGoalStep struct {
+	ID          int    `db:"id" json:"id"`
+	Position    int    `db:"position" json:"position"`
+	Name        string `db:"name" json:"name"`
+	Type        string `db:"type" json:"type"`
+	URL         string `db:"url" json:"url"`
+	Key         string `db:"key" json:"key"`
+	Events      int    `db:"events" json:"events"`
+	Subscribers int    `db:"subscribers" json:"subscribers"`
+}
+
+// CampaignPreview represents a screenshot of a campaign in an e-mail client.
+type CampaignPreview struct {
+	ID          int       `db:"id" json:"id"`
+	CampaignID  int       `db:"campaign_id" json:"campaign_id"`
+	Client      string    `db:"client" json:"client"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	CreatedAt   null.Time `db:"created_at" json:"created_at"`
+
+	// URL of the image. Not in the DB.
+	URL string `db:"-" json:"url"`
+
+	Image []byte `db:"image" json:"-"`
+}
+
+// CampaignVariant represents an A/B test variant of a campaign's subject and body.
+type CampaignVariant struct {
+	ID         int       `db:"id" json:"id"`
+	CampaignID int       `db:"campaign_id" json:"campaign_id"`
+	Name       string    `db:"name" json:"name"`
+	Subject    string    `db:"subject" json:"subject"`
+	Body       string    `db:"body" json:"body"`
+	IsWinner   bool      `db:"is_winner" json:"is_winner"`
+	CreatedAt  null.Time `db:"created_at" json:"created_at"`
+
+	// Stats of the test sends of the variant.
+	Sent   int `db:"sent" json:"sent"`
+	Opens  int `db:"opens" json:"opens"`
+	Clicks int `db:"clicks" json:"clicks"`
+}
+
+// CampaignABTest represents the A/B test settings and variants of a campaign.
+type CampaignABTest struct {
+	Percent   int               `json:"ab_test_percent"`
+	Metric    string            `json:"ab_metric"`
+	WaitHours int               `json:"ab_wait_hours"`
+	Phase     string            `json:"ab_phase"`
+	PhaseAt   null.Time         `json:"ab_phase_at"`
+	Variants  []CampaignVariant `json:"variants"`
+}
+
+// ABTestVariant returns the index of the variant (of n variants) of an A/B test
+// that's sent to a subscriber, and whether the subscriber is in the test group
+// that's pct percent of subscribers. The get-campaign-variants and
+// next-campaign-subscribers queries compute the same.
+func ABTestVariant(subID, pct, n int) (int, bool) {
+	if n < 1 {
+		return 0, false
+	}
+
+	return (subID%100 + subID/100) % n, subID%100 < pct
+}
+
+// CampaignSend represents the delivery status of a campaign message to a subscriber.
+type CampaignSend struct {
+	CampaignID     int       `db:"campaign_id" json:"campaign_id"`
+	CampaignName   string    `db:"campaign_name" json:"campaign_name"`
+	SubscriberID   int       `db:"subscriber_id" json:"subscriber_id"`
+	SubscriberUUID string    `db:"subscriber_uuid" json:"subscriber_uuid"`
+	Email          string    `db:"email" json:"email"`
+	Status         string    `db:"status" json:"status"`
+	Response       string    `db:"response" json:"response"`
+	QueuedAt       null.Time `db:"queued_at" json:"queued_at"`
+	SentAt         null.Time `db:"sent_at" json:"sent_at"`
+	DeferredAt     null.Time `db:"deferred_at" json:"deferred_at"`
+	BouncedAt      null.Time `db:"bounced_at" json:"bounced_at"`
+	OpenedAt       null.Time `db:"opened_at" json:"opened_at"`
+	ClickedAt      null.Time `db:"clicked_at" json:"clicked_at"`
+
+	// Pseudofield for getting the total number of results
+	// in a paginated query.
+	Total int `db:"total" json:"-"`
+}
+
+// CapturedMessage represents an outbound message stored by the capture messenger
+// instead of being delivered.
+type CapturedMessage struct {
+	ID           int            `db:"id" json:"id"`
+	Messenger    string         `db:"messenger" json:"messenger"`
+	CampaignID   null.Int       `db:"campaign_id" json:"campaign_id"`
+	SubscriberID null.Int       `db:"subscriber_id" json:"subscriber_id"`
+	FromEmail    string         `db:"from_email" json:"from_email"`
+	To           pq.StringArray `db:"to_emails" json:"to"`
+	Subject      string         `db:"subject" json:"subject"`
+	ContentType  string         `db:"content_type" json:"content_type"`
+	Body         string         `db:"body" json:"body,omitempty"`
+	AltBody      string         `db:"alt_body" json:"alt_body,omitempty"`
+	Headers      types.JSONText `db:"headers" json:"headers"`
+	Raw          string         `db:"raw" json:"raw,omitempty"`
+	CreatedAt    null.Time      `db:"created_at" json:"created_at"`
+
+	// Pseudofield for getting the total number of results
+	// in a paginated query.
+	Total int `db:"total" json:"-"`
+}
+
+// SentMessage represents the archived content of a campaign message sent to a subscriber.
+type SentMessage struct {
+	ID           int64    `db:"id" json:"id"`
+	CampaignID   null.Int `db:"campaign_id" json:"campaign_id"`
+	CampaignName string   `db:"campaign_name" json:"campaign_name"`
+	SubscriberID int      `db:"subscriber_id" json:"subscriber_id"`
+	Email        string   `db:"email" json:"email"`
+	Subject      string   `db:"subject" json:"subject"`
+	ContentType  string   `db:"content_type" json:"content_type"`
+
+	// Body and AltBody are omitted in listings. BodyHash is the SHA-256 hash
+	// of the body for verifying that the archived body is unaltered.
+	Body     string `db:"body" json:"body,omitempty"`
+	AltBody  string `db:"alt_body" json:"alt_body,omitempty"`
+	BodyHash string `db:"body_hash" json:"body_hash"`
+
+	// The versions of the campaign and template that the body was rendered with.
+	CampaignVersion   int       `db:"campaign_version" json:"campaign_version"`
+	TemplateID        null.Int  `db:"template_id" json:"template_id"`
+	TemplateUpdatedAt null.Time `db:"template_updated_at" json:"template_updated_at"`
+	SentAt            null.Time `db:"sent_at" json:"sent_at"`
+
+	// Pseudofield for getting the total number of results
+	// in a paginated query.
+	Total int `db:"total" json:"-"`
+}
+
+// DNSCheck represents the latest DNS deliverability check results of a
+// sending domain or the tracking domain.
+type DNSCheck struct {
+	ID        int            `db:"id" json:"id"`
+	Domain    string         `db:"domain" json:"domain"`
+	Status    string         `db:"status" json:"status"`
+	Results   types.JSONText `db:"results" json:"results"`
+	CheckedAt null.Time      `db:"checked_at" json:"checked_at"`
+}
+
+// TLSReport represents a policy domain's results from an SMTP TLS report
+// (TLS-RPT) sent by a receiving organization.
+type TLSReport struct {
+	ID           int            `db:"id" json:"id"`
+	ReportID     string         `db:"report_id" json:"report_id"`
+	Organization string         `db:"organization" json:"organization"`
+	PolicyDomain string         `db:"policy_domain" json:"policy_domain"`
+	PolicyType   string         `db:"policy_type" json:"policy_type"`
+	StartDate    time.Time      `db:"start_date" json:"start_date"`
+	EndDate      time.Time      `db:"end_date" json:"end_date"`
+	SuccessCount int            `db:"success_count" json:"success_count"`
+	FailureCount int            `db:"failure_count" json:"failure_count"`
+	Failures     types.JSONText `db:"failures" json:"failures"`
+	CreatedAt    null.Time      `db:"created_at" json:"created_at"`
+}
+
+// ReputationMetric represents the daily reputation of a sending domain or IP
+// reported by Google Postmaster Tools or Microsoft SNDS.
+type ReputationMetric struct {
+	ID             int            `db:"id" json:"id"`
+	Provider       string         `db:"provider" json:"provider"`
+	Source         string         `db:"source" json:"source"`
+	Date           time.Time      `db:"date" json:"date"`
+	Reputation     string         `db:"reputation" json:"reputation"`
+	SpamRate       float64        `db:"spam_rate" json:"spam_rate"`
+	DeliveryErrors types.JSONText `db:"delivery_errors" json:"delivery_errors"`
+	Meta           types.JSONText `db:"meta" json:"meta"`
+	CreatedAt      null.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt      null.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Notification represents an admin alert in the in-app notification feed.
+type Notification struct {
+	ID        int       `db:"id" json:"id"`
+	Type      string    `db:"type" json:"type"`
+	Title     string    `db:"title" json:"title"`
+	Body      string    `db:"body" json:"body"`
+	Data      JSON      `db:"data" json:"data"`
+	CreatedAt null.Time `db:"created_at" json:"created_at"`
+
+	// User to whom the notification is addressed. Empty for all users.
+	Username string `db:"-" json:"-"`
+
+	// Whether the requesting user has read the notification.
+	Read bool `db:"read" json:"read"`
+
+	Total int `db:"total" json:"-"`
+}
+
+// NotificationPrefs represents a user's notification preferences.
+type NotificationPrefs struct {
+	Email string `db:"email" json:"email"`
+
+	// Channels (feed, email) of every notification type, eg: {"campaign": ["feed", "email"]}.
+	Events NotificationEvents `db:"events" json:"events"`
+}
+
+// NotificationEvents is the map of notification types to their delivery channels.
+type NotificationEvents map[string][]string
+
+// SavedView represents a named filter/sort configuration of an admin collection.
+type SavedView struct {
+	Base
+
+	Name       string `db:"name" json:"name"`
+	Collection string `db:"collection" json:"collection"`
+	Params     JSON   `db:"params" json:"params"`
+
+	// Username of the creator. Views that are not shared are only visible to them.
+	CreatedBy string `db:"created_by" json:"created_by"`
+	Shared    bool   `db:"shared" json:"shared"`
+}
+
+// SubscriberColumns represents the columns of the subscribers table chosen by a user.
+type SubscriberColumns struct {
+	// Field names and attribute paths, eg: ["email", "status", "attribs.city"].
+	Columns []string `json:"columns"`
+
+	// False if the user hasn't chosen any columns and Columns are the defaults.
+	Custom bool `json:"custom"`
+}
+
+// UserPreferences represents the preferences of a user in the admin.
+type UserPreferences struct {
+	// IANA time zone dates are displayed in. Empty for the browser's time zone.
+	Timezone string `db:"timezone" json:"timezone"`
+}
+
+// AutocompleteItem represents a typeahead suggestion for pickers.
+type AutocompleteItem struct {
+	ID   int    `db:"id" json:"id,omitempty"`
+	Name string `db:"name" json:"name"`
+
+	// Secondary info, eg: a subscriber's name or a list's type.
+	Extra string `db:"extra" json:"extra,omitempty"`
+}
+
+// AuditEntry represents an admin action in the audit log. Before and After are
+// JSON snapshots of the entity, and Changes are the top level keys that differ
+// between them.
+type AuditEntry struct {
+	ID         int64           `db:"id" json:"id"`
+	Actor      string          `db:"actor" json:"actor"`
+	Action     string          `db:"action" json:"action"`
+	EntityType string          `db:"entity_type" json:"entity_type"`
+	EntityID   null.Int        `db:"entity_id" json:"entity_id"`
+	Before     json.RawMessage `db:"before_data" json:"before"`
+	After      json.RawMessage `db:"after_data" json:"after"`
+	Changes    pq.StringArray  `db:"changes" json:"changes"`
+	IP         string          `db:"ip" json:"ip"`
+	CreatedAt  null.Time       `db:"created_at" json:"created_at"`
+
+	Total int `db:"total" json:"-"`
+}
+
+// SearchResult represents an item in the global admin search.
+type SearchResult struct {
+	Type string `db:"type" json:"type"`
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+
+	// Secondary info, eg: a campaign's subject or a subscriber's name.
+	Extra   string `db:"extra" json:"extra,omitempty"`
+	Trashed bool   `db:"trashed" json:"trashed"`
+}
+
+// TrashItem represents a trashed (soft deleted) campaign, list, or template.
+type TrashItem struct {
+	Type      string    `db:"type" json:"type"`
+	ID        int       `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	DeletedAt time.Time `db:"deleted_at" json:"deleted_at"`
+
+	// PurgeAt is when the item will be permanently deleted. It's null if
+	// trashed items are retained indefinitely.
+	PurgeAt null.Time `db:"-" json:"purge_at"`
+}
+
+// ExpiredTokens represents the counts of expired tokens deleted by maintenance.
+type ExpiredTokens struct {
+	APITokens         int `db:"api_tokens" json:"api_tokens"`
+	SubscriberExports int `db:"subscriber_exports" json:"subscriber_exports"`
+	EmailChanges      int `db:"email_changes" json:"email_changes"`
+}
+
+// IntegrityReport represents the counts of records with inconsistent counters
+// or states found (or fixed) by the integrity check.
+type IntegrityReport struct {
+	// Campaigns with negative counts or more messages sent than to be sent.
+	CampaignCounts int `db:"campaign_counts" json:"campaign_counts"`
+
+	// Campaigns whose sent count is behind the messages in their send log.
+	CampaignSends int `db:"campaign_sends" json:"campaign_sends"`
+
+	// Active sequence subscribers who are past the sequence's last step.
+	SequenceSteps int `db:"sequence_steps" json:"sequence_steps"`
+
+	// Subscriptions of blocklisted subscribers that aren't unsubscribed.
+	BlocklistedSubscriptions int `db:"blocklisted_subscriptions" json:"blocklisted_subscriptions"`
+}
+
+// ListRepermission represents a re-permission run that converts a single opt-in
+// list to double opt-in by asking its subscribers to confirm their subscriptions again.
+type ListRepermission struct {
+	ID           int       `db:"id" json:"id"`
+	ListID       int       `db:"list_id" json:"list_id"`
+	ListName     string    `db:"list_name" json:"list_name"`
+	CampaignID   null.Int  `db:"campaign_id" json:"campaign_id"`
+	Status       string    `db:"status" json:"status"`
+	Deadline     time.Time `db:"deadline" json:"deadline"`
+	Total        int       `db:"total" json:"total"`
+	Confirmed    int       `db:"confirmed" json:"confirmed"`
+	Pending      int       `db:"pending" json:"pending"`
+	Unsubscribed int       `db:"unsubscribed" json:"unsubscribed"`
+	CreatedAt    null.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Sequence represents a drip (automation) sequence: an ordered series of
+// campaigns that are sent to subscribers of a list after they subscribe.
+type Sequence struct {
+	Base
+
+	Name        string        `db:"name" json:"name"`
+	ListID      int           `db:"list_id" json:"list_id"`
+	ListName    string        `db:"list_name" json:"list_name"`
+	Status      string        `db:"status" json:"status"`
+	ExitOnClick bool          `db:"exit_on_click" json:"exit_on_click"`
+	Steps       SequenceSteps `db:"steps" json:"steps"`
+
+	// Number of subscribers in the sequence by status.
+	NumActive   int `db:"num_active" json:"num_active"`
+	NumFinished int `db:"num_finished" json:"num_finished"`
+	NumExited   int `db:"num_exited" json:"num_exited"`
+}
+
+// SequenceStep is a campaign of a sequence that's sent DelayMins after
+// the previous step (or after subscribing for the first step).
+type SequenceStep struct {
+	ID           int    `json:"id"`
+	CampaignID   int    `json:"campaign_id"`
+	CampaignName string `json:"campaign_name"`
+	DelayMins    int    `json:"delay_mins"`
+}
+
+// SequenceSteps represents the ordered steps of a sequence.
+type SequenceSteps []SequenceStep
+
+// SequenceMessage is a step of a sequence that's due to be sent to a subscriber.
+type SequenceMessage struct {
+	SequenceID int `db:"sequence_id"`
+	CampaignID int `db:"campaign_id"`
+	Step       int `db:"step"`
+	NumSteps   int `db:"num_steps"`
+
+	Subscriber
+}
+
+// HeaderPreset represents a reusable set of custom e-mail headers.
+type HeaderPreset struct {
+	Base
+
+	Name    string  `db:"name" json:"name"`
+	Headers Headers `db:"headers" json:"headers"`
+}
+
+// StripeSubscription represents the status of a product of a Stripe subscription.
+type StripeSubscription struct {
+	ID            string    `db:"id" json:"id"`
+	ProductID     string    `db:"product_id" json:"product_id"`
+	CustomerID    string    `db:"customer_id" json:"customer_id"`
+	Email         string    `db:"email" json:"email"`
+	Status        string    `db:"status" json:"status"`
+	SyncedStatus  string    `db:"synced_status" json:"synced_status"`
+	LatestInvoice string    `db:"latest_invoice" json:"latest_invoice"`
+	EventAt       time.Time `db:"event_at" json:"event_at"`
+	CreatedAt     null.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     null.Time `db:"updated_at" json:"updated_at"`
+}
+
+// StripeReconciliation is a discrepancy between Stripe subscriptions and list subscriptions.
+type StripeReconciliation struct {
+	Type      string `db:"type" json:"type"`
+	Email     string `db:"email" json:"email"`
+	ListID    int    `db:"list_id" json:"list_id"`
+	ListName  string `db:"list_name" json:"list_name"`
+	ProductID string `db:"product_id" json:"product_id"`
+	Status    string `db:"status" json:"status"`
+}
+
+// ListBlackout represents a date range during which campaigns scheduled to a list are held.
+type ListBlackout struct {
+	ID        int       `db:"id" json:"id"`
+	ListID    int       `db:"list_id" json:"list_id"`
+	ListName  string    `db:"list_name" json:"list_name"`
+	Name      string    `db:"name" json:"name"`
+	StartsAt  time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt    time.Time `db:"ends_at" json:"ends_at"`
+	CreatedAt null.Time `db:"created_at" json:"created_at"`
+}
+
+// OptinReply represents a reply to a double opt-in confirmation e-mail
+// received on a plus-tagged opt-in reply address.
+type OptinReply struct {
+	SubscriberUUID string    `json:"subscriber_uuid"`
+	Email          string    `json:"email"`
+	Source         string    `json:"source"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SunsetStats represents the number of subscribers in each state of the
+// sunset (win-back) flow.
+type SunsetStats struct {
+	Enrolled  int `db:"enrolled" json:"enrolled"`
+	Recovered int `db:"recovered" json:"recovered"`
+	Removed   int `db:"removed" json:"removed"`
+}
+
 // Message is the message pushed to a Messenger.
 type Message struct {
 	From        string
@@ -376,6 +1303,21 @@ type Message struct {
 	Messenger string
 }
 
+// RetryError is returned by a Messenger when a message can't be sent right now,
+// eg: a recipient domain's rate limit has been reached. The message should be
+// pushed again after the given duration.
+type RetryError struct {
+	After time.Duration
+
+	// Key identifies what's limited (eg: a group of recipient domains on a server)
+	// so that the messages deferred by it can be retried in order at its rate.
+	Key string
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("message deferred. retry after %v", e.After)
+}
+
 // Attachment represents a file or blob attachment that can be
 // sent along with a message by a Messenger.
 type Attachment struct {
@@ -400,6 +1342,9 @@ type TxMessage struct {
 	ContentType string                 `json:"content_type"`
 	Messenger   string                 `json:"messenger"`
 
+	// Add List-Unsubscribe headers with the subscriber's signed one-click opt-out URL.
+	UnsubscribeHeader bool `json:"unsubscribe_header"`
+
 	// File attachments added from multi-part form data.
 	Attachments []Attachment `json:"-"`
 
@@ -481,6 +1426,36 @@ func (s JSON) Scan(src interface{}) error {
 	return fmt.Errorf("could not not decode type %T -> %T", src, s)
 }
 
+// Value returns the JSON marshalled NotificationEvents.
+func (n NotificationEvents) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+// Scan unmarshals JSONB from the DB.
+func (n *NotificationEvents) Scan(src interface{}) error {
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, n)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, n)
+}
+
+// Has returns true if the given channel is enabled for the notification type.
+// Types that aren't in the map are only delivered to the feed.
+func (n NotificationEvents) Has(typ, channel string) bool {
+	chans, ok := n[typ]
+	if !ok {
+		return channel == NotificationChannelFeed
+	}
+
+	for _, c := range chans {
+		if c == channel {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Scan unmarshals JSONB from the DB.
 func (s StringIntMap) Scan(src interface{}) error {
 	if src == nil {
@@ -548,6 +1523,9 @@ func (c *Campaign) CompileTemplate(f template.FuncMap) error {
 
 	// Compile the base template.
 	body := c.TemplateBody
+	if c.LinkUTM != nil {
+		body = c.LinkUTM.TagLinks(body)
+	}
 	for _, r := range regTplFuncs {
 		body = r.regExp.ReplaceAllString(body, r.replace)
 	}
@@ -567,6 +1545,10 @@ func (c *Campaign) CompileTemplate(f template.FuncMap) error {
 		body = c.Body
 	}
 
+	if c.LinkUTM != nil {
+		body = c.LinkUTM.TagLinks(body)
+	}
+
 	// Compile the campaign message.
 	for _, r := range regTplFuncs {
 		body = r.regExp.ReplaceAllString(body, r.replace)
@@ -598,6 +1580,130 @@ func (c *Campaign) CompileTemplate(f template.FuncMap) error {
 	return nil
 }
 
+// ReplyToAddress returns the campaign's Reply-To address. If reply tracking
+// is enabled, the address is plus-tagged with the campaign's UUID, eg:
+// replies+$campaignUUID@site.com so that replies can be attributed to the campaign.
+func (c *Campaign) ReplyToAddress() string {
+	if !c.ReplyTracking || c.ReplyTo == "" {
+		return c.ReplyTo
+	}
+
+	addr, err := mail.ParseAddress(c.ReplyTo)
+	if err != nil {
+		return c.ReplyTo
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 {
+		return c.ReplyTo
+	}
+	addr.Address = addr.Address[:at] + "+" + c.UUID + addr.Address[at:]
+
+	return addr.String()
+}
+
+// UTMParams returns the UTM parameters to be appended to the campaign's links
+// with the globally configured source and medium (def), or nil if UTM tagging
+// is off for the campaign. enabled is the global setting.
+func (c *Campaign) UTMParams(enabled bool, def UTM) *UTM {
+	return makeUTM(c.Name, c.UTMCampaign, c.UTMEnabled, enabled, def)
+}
+
+// UTMParams returns the UTM parameters to be appended to the link's URL.
+// See Campaign.UTMParams().
+func (l LinkRedirect) UTMParams(enabled bool, def UTM) *UTM {
+	return makeUTM(l.CampaignName, l.UTMCampaign, l.UTMEnabled, enabled, def)
+}
+
+func makeUTM(campName, utmCampaign string, override null.Bool, enabled bool, def UTM) *UTM {
+	if override.Valid {
+		enabled = override.Bool
+	}
+	if !enabled {
+		return nil
+	}
+
+	out := def
+	out.Campaign = utmCampaign
+	if out.Campaign == "" {
+		out.Campaign = campName
+	}
+
+	return &out
+}
+
+// AppendTo appends the UTM parameters to a URL. Parameters that are already
+// in the URL are retained.
+func (u UTM) AppendTo(link string) string {
+	p, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	var (
+		cur = p.Query()
+		q   = url.Values{}
+	)
+	for _, v := range [][2]string{{"utm_source", u.Source}, {"utm_medium", u.Medium}, {"utm_campaign", u.Campaign}} {
+		if v[1] != "" && !cur.Has(v[0]) {
+			q.Set(v[0], v[1])
+		}
+	}
+	if len(q) == 0 {
+		return link
+	}
+
+	// Append to the existing query as-is without re-encoding it.
+	if p.RawQuery != "" {
+		p.RawQuery += "&"
+	}
+	p.RawQuery += q.Encode()
+
+	return p.String()
+}
+
+// TagLinks appends the UTM parameters to the http(s) links in an HTML body.
+// Links with template expressions are skipped, and so are tracked links,
+// which are only tagged when they're clicked so that the UTM parameters aren't
+// recorded in the tracked links.
+func (u UTM) TagLinks(body string) string {
+	return regexpLinkHref.ReplaceAllStringFunc(body, func(s string) string {
+		m := regexpLinkHref.FindStringSubmatch(s)
+		if strings.Contains(m[2], "{{") || strings.HasSuffix(m[2], "@TrackLink") {
+			return s
+		}
+
+		link := u.AppendTo(strings.ReplaceAll(m[2], "&amp;", "&"))
+		return m[1] + strings.ReplaceAll(link, "&", "&amp;")
+	})
+}
+
+// ReturnPathAddress returns the campaign's envelope sender (Return-Path) address,
+// which is the campaign's return path or that of its lists. A return path can
+// be a full address or a domain, eg: bounce.site.com, in which case, the local
+// part of the from address is used, eg: news@bounce.site.com.
+func (c *Campaign) ReturnPathAddress() string {
+	rp := c.ReturnPath
+	if rp == "" {
+		rp = c.ListReturnPath
+	}
+	if rp == "" || strings.Contains(rp, "@") {
+		return rp
+	}
+
+	addr, err := mail.ParseAddress(c.FromEmail)
+	if err != nil {
+		return ""
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return addr.Address[:at+1] + rp
+}
+
 // ConvertContent converts a campaign's body from one format to another,
 // for example, Markdown to HTML.
 func (c *Campaign) ConvertContent(from, to string) (string, error) {
@@ -702,6 +1808,58 @@ func (s Subscriber) LastName() string {
 	return s.Name
 }
 
+// Scan unmarshals JSONB from the DB.
+func (f *ListFields) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, f)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, f)
+}
+
+// Value returns the JSON marshalled ListFields.
+func (f ListFields) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(f)
+}
+
+// Scan unmarshals JSONB from the DB.
+func (s *SequenceSteps) Scan(src interface{}) error {
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, s)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, s)
+}
+
+// Scan unmarshals JSONB from the DB. NULL (feed not fetched yet) leaves
+// the items nil.
+func (f *FeedItems) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		*f = FeedItems{}
+		return json.Unmarshal(src, f)
+	case string:
+		*f = FeedItems{}
+		return json.Unmarshal([]byte(src), f)
+	case nil:
+		*f = nil
+		return nil
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, f)
+}
+
+// Value marshals the items to JSON for the DB.
+func (f FeedItems) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return json.Marshal(f)
+}
+
 // Scan implements the sql.Scanner interface.
 func (h *Headers) Scan(src interface{}) error {
 	var b []byte
@@ -738,3 +1896,74 @@ func (h Headers) Value() (driver.Value, error) {
 
 	return "[]", nil
 }
+
+// Allows returns true if the time (in the campaign's time zone) falls in any
+// of the windows. Empty windows allow all times.
+func (w SendWindows) Allows(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+
+	var (
+		now  = t.Format("15:04")
+		day  = int(t.Weekday())
+		prev = (day + 6) % 7
+	)
+	for _, s := range w {
+		if s.End > s.Start {
+			if s.hasDay(day) && now >= s.Start && now < s.End {
+				return true
+			}
+			continue
+		}
+
+		// The window spans midnight.
+		if (s.hasDay(day) && now >= s.Start) || (s.hasDay(prev) && now < s.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s SendWindow) hasDay(d int) bool {
+	for _, v := range s.Days {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan unmarshals JSONB from the DB.
+func (w *SendWindows) Scan(src interface{}) error {
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, w)
+	}
+	return fmt.Errorf("could not not decode type %T -> %T", src, w)
+}
+
+// Value returns the JSON marshalled SendWindows.
+func (w SendWindows) Value() (driver.Value, error) {
+	if w == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(w)
+}
+
+// InSendWindow returns true if the campaign's send windows allow sending at
+// the given time.
+func (c *Campaign) InSendWindow(t time.Time) bool {
+	if len(c.SendWindows) == 0 {
+		return true
+	}
+
+	loc := time.UTC
+	if c.SendAtTimezone != "" {
+		if l, err := time.LoadLocation(c.SendAtTimezone); err == nil {
+			loc = l
+		}
+	}
+
+	return c.SendWindows.Allows(t.In(loc))
+}
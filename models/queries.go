@@ -34,12 +34,16 @@ type Queries struct {
 	DeleteBlocklistedSubscribers    *sqlx.Stmt `query:"delete-blocklisted-subscribers"`
 	DeleteOrphanSubscribers         *sqlx.Stmt `query:"delete-orphan-subscribers"`
 	UnsubscribeByCampaign           *sqlx.Stmt `query:"unsubscribe-by-campaign"`
+	UnsubscribeSubscriber           *sqlx.Stmt `query:"unsubscribe-subscriber"`
+	SnoozeSubscription              *sqlx.Stmt `query:"snooze-subscription"`
+	ResumeSnoozedSubscriptions      *sqlx.Stmt `query:"resume-snoozed-subscriptions"`
 	ExportSubscriberData            *sqlx.Stmt `query:"export-subscriber-data"`
 
 	// Non-prepared arbitrary subscriber queries.
 	QuerySubscribers                       string     `query:"query-subscribers"`
 	QuerySubscribersCount                  string     `query:"query-subscribers-count"`
 	QuerySubscribersCountAll               *sqlx.Stmt `query:"query-subscribers-count-all"`
+	SearchSubscribers                      *sqlx.Stmt `query:"search-subscribers"`
 	QuerySubscribersForExport              string     `query:"query-subscribers-for-export"`
 	QuerySubscribersTpl                    string     `query:"query-subscribers-template"`
 	DeleteSubscribersByQuery               string     `query:"delete-subscribers-by-query"`
@@ -48,13 +52,19 @@ type Queries struct {
 	DeleteSubscriptionsByQuery             string     `query:"delete-subscriptions-by-query"`
 	UnsubscribeSubscribersFromListsByQuery string     `query:"unsubscribe-subscribers-from-lists-by-query"`
 
-	CreateList      *sqlx.Stmt `query:"create-list"`
-	QueryLists      string     `query:"query-lists"`
-	GetLists        *sqlx.Stmt `query:"get-lists"`
-	GetListsByOptin *sqlx.Stmt `query:"get-lists-by-optin"`
-	UpdateList      *sqlx.Stmt `query:"update-list"`
-	UpdateListsDate *sqlx.Stmt `query:"update-lists-date"`
-	DeleteLists     *sqlx.Stmt `query:"delete-lists"`
+	CreateList           *sqlx.Stmt `query:"create-list"`
+	QueryLists           string     `query:"query-lists"`
+	GetLists             *sqlx.Stmt `query:"get-lists"`
+	GetListsByOptin      *sqlx.Stmt `query:"get-lists-by-optin"`
+	UpdateList           *sqlx.Stmt `query:"update-list"`
+	GetListDescendantIDs *sqlx.Stmt `query:"get-list-descendant-ids"`
+	BulkUpdateLists      *sqlx.Stmt `query:"bulk-update-lists"`
+	MergeLists           *sqlx.Stmt `query:"merge-lists"`
+	GetListFolders       *sqlx.Stmt `query:"get-list-folders"`
+	UpdateListsDate      *sqlx.Stmt `query:"update-lists-date"`
+	DeleteLists          *sqlx.Stmt `query:"delete-lists"`
+	GetListGrowth        *sqlx.Stmt `query:"get-list-growth"`
+	GetListChurn         *sqlx.Stmt `query:"get-list-churn"`
 
 	CreateCampaign        *sqlx.Stmt `query:"create-campaign"`
 	QueryCampaigns        string     `query:"query-campaigns"`
@@ -62,6 +72,13 @@ type Queries struct {
 	GetCampaignForPreview *sqlx.Stmt `query:"get-campaign-for-preview"`
 	GetCampaignStats      *sqlx.Stmt `query:"get-campaign-stats"`
 	GetCampaignStatus     *sqlx.Stmt `query:"get-campaign-status"`
+	GetStatusRules        *sqlx.Stmt `query:"get-status-rules"`
+	GetStatusRule         *sqlx.Stmt `query:"get-status-rule"`
+	CreateStatusRule      *sqlx.Stmt `query:"create-status-rule"`
+	UpdateStatusRule      *sqlx.Stmt `query:"update-status-rule"`
+	DeleteStatusRule      *sqlx.Stmt `query:"delete-status-rule"`
+	ApplyStatusRule       *sqlx.Stmt `query:"apply-status-rule"`
+	GetMessengerVolume    *sqlx.Stmt `query:"get-messenger-volume"`
 	GetArchivedCampaigns  *sqlx.Stmt `query:"get-archived-campaigns"`
 
 	// These two queries are read as strings and based on settings.individual_tracking=on/off,
@@ -76,18 +93,23 @@ type Queries struct {
 
 	NextCampaigns            *sqlx.Stmt `query:"next-campaigns"`
 	NextCampaignSubscribers  *sqlx.Stmt `query:"next-campaign-subscribers"`
+	GetCampaignAudience      *sqlx.Stmt `query:"get-campaign-audience"`
 	GetOneCampaignSubscriber *sqlx.Stmt `query:"get-one-campaign-subscriber"`
 	UpdateCampaign           *sqlx.Stmt `query:"update-campaign"`
 	UpdateCampaignStatus     *sqlx.Stmt `query:"update-campaign-status"`
+	UpdateCampaignContent    *sqlx.Stmt `query:"update-campaign-content"`
 	UpdateCampaignCounts     *sqlx.Stmt `query:"update-campaign-counts"`
 	UpdateCampaignArchive    *sqlx.Stmt `query:"update-campaign-archive"`
 	RegisterCampaignView     *sqlx.Stmt `query:"register-campaign-view"`
 	DeleteCampaign           *sqlx.Stmt `query:"delete-campaign"`
 
-	InsertMedia *sqlx.Stmt `query:"insert-media"`
-	GetMedia    *sqlx.Stmt `query:"get-media"`
-	QueryMedia  *sqlx.Stmt `query:"query-media"`
-	DeleteMedia *sqlx.Stmt `query:"delete-media"`
+	InsertMedia            *sqlx.Stmt `query:"insert-media"`
+	GetMedia               *sqlx.Stmt `query:"get-media"`
+	QueryMedia             *sqlx.Stmt `query:"query-media"`
+	DeleteMedia            *sqlx.Stmt `query:"delete-media"`
+	InsertQuarantinedMedia *sqlx.Stmt `query:"insert-quarantined-media"`
+	GetQuarantinedMedia    *sqlx.Stmt `query:"get-quarantined-media"`
+	DeleteQuarantinedMedia *sqlx.Stmt `query:"delete-quarantined-media"`
 
 	CreateTemplate     *sqlx.Stmt `query:"create-template"`
 	GetTemplates       *sqlx.Stmt `query:"get-templates"`
@@ -95,6 +117,81 @@ type Queries struct {
 	SetDefaultTemplate *sqlx.Stmt `query:"set-default-template"`
 	DeleteTemplate     *sqlx.Stmt `query:"delete-template"`
 
+	GetTrash     *sqlx.Stmt `query:"get-trash"`
+	RestoreTrash *sqlx.Stmt `query:"restore-trash"`
+	PurgeTrash   *sqlx.Stmt `query:"purge-trash"`
+
+	GetSavedViews   *sqlx.Stmt `query:"get-saved-views"`
+	GetSavedView    *sqlx.Stmt `query:"get-saved-view"`
+	CreateSavedView *sqlx.Stmt `query:"create-saved-view"`
+	UpdateSavedView *sqlx.Stmt `query:"update-saved-view"`
+	DeleteSavedView *sqlx.Stmt `query:"delete-saved-view"`
+
+	GetSubscriberColumns    *sqlx.Stmt `query:"get-subscriber-columns"`
+	UpsertSubscriberColumns *sqlx.Stmt `query:"upsert-subscriber-columns"`
+	GetUserPreferences      *sqlx.Stmt `query:"get-user-preferences"`
+	UpsertUserPreferences   *sqlx.Stmt `query:"upsert-user-preferences"`
+
+	InsertNotification       *sqlx.Stmt `query:"insert-notification"`
+	GetNotifications         *sqlx.Stmt `query:"get-notifications"`
+	CountUnreadNotifications *sqlx.Stmt `query:"count-unread-notifications"`
+	MarkNotificationsRead    *sqlx.Stmt `query:"mark-notifications-read"`
+	GetNotificationPrefs     *sqlx.Stmt `query:"get-notification-prefs"`
+	GetNotificationEmails    *sqlx.Stmt `query:"get-notification-emails"`
+	UpsertNotificationPrefs  *sqlx.Stmt `query:"upsert-notification-prefs"`
+	CountRecentBounces       *sqlx.Stmt `query:"count-recent-bounces"`
+
+	GetSendingDomains   *sqlx.Stmt `query:"get-sending-domains"`
+	GetSendingDomain    *sqlx.Stmt `query:"get-sending-domain"`
+	CreateSendingDomain *sqlx.Stmt `query:"create-sending-domain"`
+	UpdateSendingDomain *sqlx.Stmt `query:"update-sending-domain"`
+	VerifySendingDomain *sqlx.Stmt `query:"verify-sending-domain"`
+	DeleteSendingDomain *sqlx.Stmt `query:"delete-sending-domain"`
+
+	GetQuotas        *sqlx.Stmt `query:"get-quotas"`
+	GetUserQuota     *sqlx.Stmt `query:"get-user-quota"`
+	UpsertQuota      *sqlx.Stmt `query:"upsert-quota"`
+	DeleteQuota      *sqlx.Stmt `query:"delete-quota"`
+	GetQuotaUsage    *sqlx.Stmt `query:"get-quota-usage"`
+	InsertQuotaUsage *sqlx.Stmt `query:"insert-quota-usage"`
+
+	GetAPITokens   *sqlx.Stmt `query:"get-api-tokens"`
+	CreateAPIToken *sqlx.Stmt `query:"create-api-token"`
+	DeleteAPIToken *sqlx.Stmt `query:"delete-api-token"`
+	RotateAPIToken *sqlx.Stmt `query:"rotate-api-token"`
+	UseAPIToken    *sqlx.Stmt `query:"use-api-token"`
+
+	InsertAuditLog *sqlx.Stmt `query:"insert-audit-log"`
+	QueryAuditLog  *sqlx.Stmt `query:"query-audit-log"`
+
+	LogCampaignSends           *sqlx.Stmt `query:"log-campaign-sends"`
+	QueryCampaignSends         *sqlx.Stmt `query:"query-campaign-sends"`
+	QueueFailedSendSubscribers *sqlx.Stmt `query:"queue-failed-send-subscribers"`
+	DeferCampaignSends         *sqlx.Stmt `query:"defer-campaign-sends"`
+	DeleteCampaignSends        *sqlx.Stmt `query:"delete-campaign-sends"`
+
+	InsertCapturedMessage  *sqlx.Stmt `query:"insert-captured-message"`
+	QueryCapturedMessages  *sqlx.Stmt `query:"query-captured-messages"`
+	GetCapturedMessage     *sqlx.Stmt `query:"get-captured-message"`
+	DeleteCapturedMessages *sqlx.Stmt `query:"delete-captured-messages"`
+
+	GetCampaignComments   *sqlx.Stmt `query:"get-campaign-comments"`
+	GetCampaignComment    *sqlx.Stmt `query:"get-campaign-comment"`
+	InsertCampaignComment *sqlx.Stmt `query:"insert-campaign-comment"`
+	UpdateCampaignComment *sqlx.Stmt `query:"update-campaign-comment"`
+	DeleteCampaignComment *sqlx.Stmt `query:"delete-campaign-comment"`
+
+	AutocompleteSubscribers *sqlx.Stmt `query:"autocomplete-subscribers"`
+	AutocompleteLists       *sqlx.Stmt `query:"autocomplete-lists"`
+	AutocompleteTemplates   *sqlx.Stmt `query:"autocomplete-templates"`
+	AutocompleteTags        *sqlx.Stmt `query:"autocomplete-tags"`
+	AutocompleteCampaigns   *sqlx.Stmt `query:"autocomplete-campaigns"`
+
+	GlobalSearchCampaigns   *sqlx.Stmt `query:"global-search-campaigns"`
+	GlobalSearchTemplates   *sqlx.Stmt `query:"global-search-templates"`
+	GlobalSearchSubscribers *sqlx.Stmt `query:"global-search-subscribers"`
+	GlobalSearchLists       *sqlx.Stmt `query:"global-search-lists"`
+
 	CreateLink        *sqlx.Stmt `query:"create-link"`
 	RegisterLinkClick *sqlx.Stmt `query:"register-link-click"`
 
@@ -102,11 +199,114 @@ type Queries struct {
 	UpdateSettings *sqlx.Stmt `query:"update-settings"`
 
 	// GetStats *sqlx.Stmt `query:"get-stats"`
+	RecordCampaignReply       *sqlx.Stmt `query:"record-campaign-reply"`
 	RecordBounce              *sqlx.Stmt `query:"record-bounce"`
 	QueryBounces              string     `query:"query-bounces"`
 	DeleteBounces             *sqlx.Stmt `query:"delete-bounces"`
 	DeleteBouncesBySubscriber *sqlx.Stmt `query:"delete-bounces-by-subscriber"`
 	GetDBInfo                 string     `query:"get-db-info"`
+
+	EnrollSunsetSubscribers  *sqlx.Stmt `query:"enroll-sunset-subscribers"`
+	RecoverSunsetSubscribers *sqlx.Stmt `query:"recover-sunset-subscribers"`
+	RemoveSunsetSubscribers  *sqlx.Stmt `query:"remove-sunset-subscribers"`
+	GetSubscriberDomains     *sqlx.Stmt `query:"get-subscriber-domains"`
+	RefreshListHygiene       *sqlx.Stmt `query:"refresh-list-hygiene"`
+	GetListHygiene           *sqlx.Stmt `query:"get-list-hygiene"`
+	ApplyListHygieneAction   *sqlx.Stmt `query:"apply-list-hygiene-action"`
+	GetSunsetStats           *sqlx.Stmt `query:"get-sunset-stats"`
+	StartListRepermission    *sqlx.Stmt `query:"start-list-repermission"`
+	GetListRepermissions     *sqlx.Stmt `query:"get-list-repermissions"`
+	CancelListRepermission   *sqlx.Stmt `query:"cancel-list-repermission"`
+	FinishListRepermissions  *sqlx.Stmt `query:"finish-list-repermissions"`
+	GetListBlackouts         *sqlx.Stmt `query:"get-list-blackouts"`
+	GetListCapCounts         *sqlx.Stmt `query:"get-list-cap-counts"`
+	GetEntitledSubscribers   *sqlx.Stmt `query:"get-entitled-subscribers"`
+
+	UpsertStripeCustomer           *sqlx.Stmt `query:"upsert-stripe-customer"`
+	UpsertStripeSubscription       *sqlx.Stmt `query:"upsert-stripe-subscription"`
+	RefundStripeInvoice            *sqlx.Stmt `query:"refund-stripe-invoice"`
+	GetStripeCustomerSubscriptions *sqlx.Stmt `query:"get-stripe-customer-subscriptions"`
+	MarkStripeSubscriptionsSynced  *sqlx.Stmt `query:"mark-stripe-subscriptions-synced"`
+	GetStripeReconciliation        *sqlx.Stmt `query:"get-stripe-reconciliation"`
+
+	GetHeaderPresets          *sqlx.Stmt `query:"get-header-presets"`
+	CreateHeaderPreset        *sqlx.Stmt `query:"create-header-preset"`
+	UpdateHeaderPreset        *sqlx.Stmt `query:"update-header-preset"`
+	DeleteHeaderPreset        *sqlx.Stmt `query:"delete-header-preset"`
+	CreateListBlackout        *sqlx.Stmt `query:"create-list-blackout"`
+	DeleteListBlackout        *sqlx.Stmt `query:"delete-list-blackout"`
+	GetCampaignBlackouts      *sqlx.Stmt `query:"get-campaign-blackouts"`
+	GetCampaignGoals          *sqlx.Stmt `query:"get-campaign-goals"`
+	SetCampaignGoals          *sqlx.Stmt `query:"set-campaign-goals"`
+	RegisterCampaignGoalEvent *sqlx.Stmt `query:"register-campaign-goal-event"`
+	GetCampaignGoalFunnel     *sqlx.Stmt `query:"get-campaign-goal-funnel"`
+	GetCampaignPreviews       *sqlx.Stmt `query:"get-campaign-previews"`
+	GetCampaignPreviewImage   *sqlx.Stmt `query:"get-campaign-preview-image"`
+	SetCampaignPreviews       *sqlx.Stmt `query:"set-campaign-previews"`
+	GetDNSChecks              *sqlx.Stmt `query:"get-dns-checks"`
+	SetDNSChecks              *sqlx.Stmt `query:"set-dns-checks"`
+	InsertTLSReport           *sqlx.Stmt `query:"insert-tls-report"`
+	GetTLSReports             *sqlx.Stmt `query:"get-tls-reports"`
+	CreateSubscriberExport    *sqlx.Stmt `query:"create-subscriber-export"`
+	GetSubscriberExport       *sqlx.Stmt `query:"get-subscriber-export"`
+	CreateEmailChange         *sqlx.Stmt `query:"create-email-change"`
+	ConfirmEmailChange        *sqlx.Stmt `query:"confirm-email-change"`
+	GetReputationMetrics      *sqlx.Stmt `query:"get-reputation-metrics"`
+	UpsertReputationMetrics   *sqlx.Stmt `query:"upsert-reputation-metrics"`
+	GetCampaignVariants       *sqlx.Stmt `query:"get-campaign-variants"`
+	SetCampaignVariants       *sqlx.Stmt `query:"set-campaign-variants"`
+	SetCampaignABWaiting      *sqlx.Stmt `query:"set-campaign-ab-waiting"`
+	GetABTestDueCampaigns     *sqlx.Stmt `query:"get-ab-test-due-campaigns"`
+	SetCampaignABWinner       *sqlx.Stmt `query:"set-campaign-ab-winner"`
+
+	GetSequences               *sqlx.Stmt `query:"get-sequences"`
+	CreateSequence             *sqlx.Stmt `query:"create-sequence"`
+	UpdateSequence             *sqlx.Stmt `query:"update-sequence"`
+	DeleteSequence             *sqlx.Stmt `query:"delete-sequence"`
+	EnrollSequenceSubscribers  *sqlx.Stmt `query:"enroll-sequence-subscribers"`
+	ExitSequenceSubscribers    *sqlx.Stmt `query:"exit-sequence-subscribers"`
+	NextSequenceMessages       *sqlx.Stmt `query:"next-sequence-messages"`
+	AdvanceSequenceSubscribers *sqlx.Stmt `query:"advance-sequence-subscribers"`
+	DeferSequenceSubscribers   *sqlx.Stmt `query:"defer-sequence-subscribers"`
+	HoldMessage                *sqlx.Stmt `query:"hold-message"`
+	NextHeldMessages           *sqlx.Stmt `query:"next-held-messages"`
+
+	GetDueRecurringCampaigns *sqlx.Stmt `query:"get-due-recurring-campaigns"`
+	CreateCampaignRun        *sqlx.Stmt `query:"create-campaign-run"`
+	GetCampaignRuns          *sqlx.Stmt `query:"get-campaign-runs"`
+
+	GetSubscribersBatch string `query:"get-subscribers-batch"`
+
+	UpdateCampaignFeed *sqlx.Stmt `query:"update-campaign-feed"`
+
+	DeleteDanglingMedia *sqlx.Stmt `query:"delete-dangling-media"`
+	DeleteExpiredTokens *sqlx.Stmt `query:"delete-expired-tokens"`
+	GetIntegrityReport  *sqlx.Stmt `query:"get-integrity-report"`
+	FixIntegrity        *sqlx.Stmt `query:"fix-integrity"`
+
+	QueueCampaignSubscribers   *sqlx.Stmt `query:"queue-campaign-subscribers"`
+	SetCampaignSTOQueued       *sqlx.Stmt `query:"set-campaign-sto-queued"`
+	NextQueuedSubscribers      *sqlx.Stmt `query:"next-queued-subscribers"`
+	GetCampaignQueueCount      *sqlx.Stmt `query:"get-campaign-queue-count"`
+	RefreshSubscriberSendHours *sqlx.Stmt `query:"refresh-subscriber-send-hours"`
+
+	CreateCampaignResend *sqlx.Stmt `query:"create-campaign-resend"`
+
+	GetCampaignActions    *sqlx.Stmt `query:"get-campaign-actions"`
+	SetCampaignActions    *sqlx.Stmt `query:"set-campaign-actions"`
+	GetDueCampaignActions *sqlx.Stmt `query:"get-due-campaign-actions"`
+	RunCampaignAction     *sqlx.Stmt `query:"run-campaign-action"`
+
+	RegisterSurveyResponse *sqlx.Stmt `query:"register-survey-response"`
+	GetCampaignSurveys     *sqlx.Stmt `query:"get-campaign-surveys"`
+
+	CreateLinkSlug *sqlx.Stmt `query:"create-link-slug"`
+	GetLinkSlug    *sqlx.Stmt `query:"get-link-slug"`
+
+	InsertSentMessages *sqlx.Stmt `query:"insert-sent-messages"`
+	QuerySentMessages  *sqlx.Stmt `query:"query-sent-messages"`
+	GetSentMessage     *sqlx.Stmt `query:"get-sent-message"`
+	DeleteSentMessages *sqlx.Stmt `query:"delete-sent-messages"`
 }
 
 // CompileSubscriberQueryTpl takes an arbitrary WHERE expressions
@@ -13,7 +13,9 @@ type Settings struct {
 	EnablePublicArchiveRSSContent bool     `json:"app.enable_public_archive_rss_content"`
 	SendOptinConfirmation         bool     `json:"app.send_optin_confirmation"`
 	CheckUpdates                  bool     `json:"app.check_updates"`
+	CaptureMode                   bool     `json:"app.capture_mode"`
 	AppLang                       string   `json:"app.lang"`
+	AppShortLinks                 bool     `json:"app.short_links"`
 
 	AppBatchSize             int    `json:"app.batch_size"`
 	AppConcurrency           int    `json:"app.concurrency"`
@@ -21,24 +23,69 @@ type Settings struct {
 	AppMessageRate           int    `json:"app.message_rate"`
 	CacheSlowQueries         bool   `json:"app.cache_slow_queries"`
 	CacheSlowQueriesInterval string `json:"app.cache_slow_queries_interval"`
+	AppTrashRetentionDays    int    `json:"app.trash_retention_days"`
+	AppReportingTimezone     string `json:"app.reporting_timezone"`
 
 	AppMessageSlidingWindow         bool   `json:"app.message_sliding_window"`
 	AppMessageSlidingWindowDuration string `json:"app.message_sliding_window_duration"`
 	AppMessageSlidingWindowRate     int    `json:"app.message_sliding_window_rate"`
 
 	PrivacyIndividualTracking bool     `json:"privacy.individual_tracking"`
+	PrivacyAnonymousLinks     bool     `json:"privacy.anonymous_links"`
 	PrivacyUnsubHeader        bool     `json:"privacy.unsubscribe_header"`
 	PrivacyAllowBlocklist     bool     `json:"privacy.allow_blocklist"`
 	PrivacyAllowPreferences   bool     `json:"privacy.allow_preferences"`
 	PrivacyAllowExport        bool     `json:"privacy.allow_export"`
 	PrivacyAllowWipe          bool     `json:"privacy.allow_wipe"`
 	PrivacyExportable         []string `json:"privacy.exportable"`
+	PrivacyExportLinkExpiry   string   `json:"privacy.export_link_expiry"`
+	PrivacyConfirmEmailChange bool     `json:"privacy.confirm_email_change"`
 	PrivacyRecordOptinIP      bool     `json:"privacy.record_optin_ip"`
+	PrivacyAllowSnooze        bool     `json:"privacy.allow_snooze"`
+	PrivacyOptinReplyAddress  string   `json:"privacy.optin_reply_address"`
 	DomainBlocklist           []string `json:"privacy.domain_blocklist"`
+	PrivacyRoleAccounts       []string `json:"privacy.role_accounts"`
+	PrivacySpamTrapPatterns   []string `json:"privacy.spamtrap_patterns"`
+	PrivacyTrustedSources     []struct {
+		UUID  string `json:"uuid"`
+		Name  string `json:"name"`
+		Token string `json:"token,omitempty"`
+	} `json:"privacy.trusted_sources"`
 
-	SecurityEnableCaptcha bool   `json:"security.enable_captcha"`
-	SecurityCaptchaKey    string `json:"security.captcha_key"`
-	SecurityCaptchaSecret string `json:"security.captcha_secret"`
+	StripeEnabled       bool   `json:"stripe.enabled"`
+	StripeWebhookSecret string `json:"stripe.webhook_secret"`
+	StripeProducts      []struct {
+		ProductID string `json:"product_id"`
+		ListID    int    `json:"list_id"`
+	} `json:"stripe.products"`
+
+	GA4Enabled       bool   `json:"ga4.enabled"`
+	GA4MeasurementID string `json:"ga4.measurement_id"`
+	GA4APISecret     string `json:"ga4.api_secret"`
+	GA4Source        string `json:"ga4.source"`
+	GA4Medium        string `json:"ga4.medium"`
+
+	UTMEnabled bool   `json:"utm.enabled"`
+	UTMSource  string `json:"utm.source"`
+	UTMMedium  string `json:"utm.medium"`
+
+	SecurityEnableCaptcha    bool     `json:"security.enable_captcha"`
+	SecurityCaptchaKey       string   `json:"security.captcha_key"`
+	SecurityCaptchaSecret    string   `json:"security.captcha_secret"`
+	SecurityLoginMaxAttempts int      `json:"security.login_max_attempts"`
+	SecurityLoginLockout     string   `json:"security.login_lockout"`
+	SecurityIPAllowlist      []string `json:"security.ip_allowlist"`
+	SecurityIPDenylist       []string `json:"security.ip_denylist"`
+	SecurityAPIIPAllowlist   []string `json:"security.api_ip_allowlist"`
+	SecurityAPIRateLimit     int      `json:"security.api_rate_limit"`
+	SecurityAPIRateBurst     int      `json:"security.api_rate_burst"`
+	SecurityAPIRateLimitRO   int      `json:"security.api_rate_limit_read_only"`
+	SecurityAPIRateBurstRO   int      `json:"security.api_rate_burst_read_only"`
+	SecurityAPIRateLimits    []struct {
+		Username string `json:"username"`
+		Rate     int    `json:"rate"`
+		Burst    int    `json:"burst"`
+	} `json:"security.api_rate_limits"`
 
 	UploadProvider             string   `json:"upload.provider"`
 	UploadExtensions           []string `json:"upload.extensions"`
@@ -58,6 +105,8 @@ type Settings struct {
 	SMTP []struct {
 		UUID          string              `json:"uuid"`
 		Enabled       bool                `json:"enabled"`
+		Name          string              `json:"name"`
+		SourceIP      string              `json:"source_ip"`
 		Host          string              `json:"host"`
 		HelloHostname string              `json:"hello_hostname"`
 		Port          int                 `json:"port"`
@@ -71,6 +120,11 @@ type Settings struct {
 		WaitTimeout   string              `json:"wait_timeout"`
 		TLSType       string              `json:"tls_type"`
 		TLSSkipVerify bool                `json:"tls_skip_verify"`
+		DomainLimits  []struct {
+			Domains  []string `json:"domains"`
+			Rate     int      `json:"rate"`
+			MaxConns int      `json:"max_conns"`
+		} `json:"domain_limits"`
 	} `json:"smtp"`
 
 	Messengers []struct {
@@ -83,6 +137,7 @@ type Settings struct {
 		MaxConns      int    `json:"max_conns"`
 		Timeout       string `json:"timeout"`
 		MaxMsgRetries int    `json:"max_msg_retries"`
+		Attachments   bool   `json:"attachments"`
 	} `json:"messengers"`
 
 	BounceEnabled        bool `json:"bounce.enabled"`
@@ -114,8 +169,95 @@ type Settings struct {
 		ScanInterval  string `json:"scan_interval"`
 	} `json:"bounce.mailboxes"`
 
+	SunsetEnabled      bool   `json:"sunset.enabled"`
+	SunsetInactiveDays int    `json:"sunset.inactive_days"`
+	SunsetGraceDays    int    `json:"sunset.grace_days"`
+	SunsetListID       int    `json:"sunset.list_id"`
+	SunsetAction       string `json:"sunset.action"`
+	SunsetInterval     string `json:"sunset.interval"`
+
+	ContentQAEnabled bool   `json:"content_qa.enabled"`
+	ContentQAURL     string `json:"content_qa.url"`
+	ContentQATimeout string `json:"content_qa.timeout"`
+
+	EntitlementEnabled  bool   `json:"entitlement.enabled"`
+	EntitlementInterval string `json:"entitlement.interval"`
+	EntitlementTimeout  string `json:"entitlement.timeout"`
+
+	HygieneEnabled         bool   `json:"hygiene.enabled"`
+	HygieneInterval        string `json:"hygiene.interval"`
+	HygieneCheckDomains    bool   `json:"hygiene.check_domains"`
+	HygieneSoftBounceCount int    `json:"hygiene.soft_bounce_count"`
+	HygieneSoftBounceDays  int    `json:"hygiene.soft_bounce_days"`
+
+	MaintenanceEnabled      bool   `json:"maintenance.enabled"`
+	MaintenanceInterval     string `json:"maintenance.interval"`
+	MaintenanceMediaDays    int    `json:"maintenance.media_days"`
+	MaintenanceFixIntegrity bool   `json:"maintenance.fix_integrity"`
+
+	PreviewsEnabled bool     `json:"previews.enabled"`
+	PreviewsURL     string   `json:"previews.url"`
+	PreviewsAPIKey  string   `json:"previews.api_key"`
+	PreviewsClients []string `json:"previews.clients"`
+	PreviewsTimeout string   `json:"previews.timeout"`
+
+	SpamCheckEnabled   bool    `json:"spam_check.enabled"`
+	SpamCheckType      string  `json:"spam_check.type"`
+	SpamCheckURL       string  `json:"spam_check.url"`
+	SpamCheckPassword  string  `json:"spam_check.password"`
+	SpamCheckThreshold float64 `json:"spam_check.threshold"`
+	SpamCheckTimeout   string  `json:"spam_check.timeout"`
+
+	DeliverabilityDomains []struct {
+		Domain        string   `json:"domain"`
+		DKIMSelectors []string `json:"dkim_selectors"`
+	} `json:"deliverability.domains"`
+	DeliverabilityTrackingCNAME   string `json:"deliverability.tracking_cname"`
+	DeliverabilityRecheck         bool   `json:"deliverability.recheck"`
+	DeliverabilityRecheckInterval string `json:"deliverability.recheck_interval"`
+
+	ReputationEnabled                bool     `json:"reputation.enabled"`
+	ReputationInterval               string   `json:"reputation.interval"`
+	ReputationPostmasterEnabled      bool     `json:"reputation.postmaster_enabled"`
+	ReputationPostmasterClientID     string   `json:"reputation.postmaster_client_id"`
+	ReputationPostmasterClientSecret string   `json:"reputation.postmaster_client_secret"`
+	ReputationPostmasterRefreshToken string   `json:"reputation.postmaster_refresh_token"`
+	ReputationPostmasterDomains      []string `json:"reputation.postmaster_domains"`
+	ReputationSNDSEnabled            bool     `json:"reputation.snds_enabled"`
+	ReputationSNDSKey                string   `json:"reputation.snds_key"`
+	ReputationAlertReputation        string   `json:"reputation.alert_reputation"`
+	ReputationAlertSpamRate          float64  `json:"reputation.alert_spam_rate"`
+
+	AttachmentsClamAVEnabled  bool   `json:"attachments.clamav_enabled"`
+	AttachmentsClamAVAddress  string `json:"attachments.clamav_address"`
+	AttachmentsClamAVTimeout  string `json:"attachments.clamav_timeout"`
+	AttachmentsScanner        string `json:"attachments.scanner"`
+	AttachmentsScannerURL     string `json:"attachments.scanner_url"`
+	AttachmentsScannerToken   string `json:"attachments.scanner_token"`
+	AttachmentsMaxMessageSize int    `json:"attachments.max_message_size"`
+	AttachmentsSizeAction     string `json:"attachments.size_action"`
+	AttachmentsMaxBodySize    int    `json:"attachments.max_body_size"`
+	AttachmentsMaxImageWeight int    `json:"attachments.max_image_weight"`
+	AttachmentsMaxSize        int    `json:"attachments.max_size"`
+	AttachmentsMinifyHTML     bool   `json:"attachments.minify_html"`
+
+	NotificationsSlackEnabled    bool     `json:"notifications.slack_enabled"`
+	NotificationsSlackWebhookURL string   `json:"notifications.slack_webhook_url"`
+	NotificationsSlackEvents     []string `json:"notifications.slack_events"`
+	NotificationsBounceThreshold int      `json:"notifications.bounce_threshold"`
+
 	AdminCustomCSS  string `json:"appearance.admin.custom_css"`
 	AdminCustomJS   string `json:"appearance.admin.custom_js"`
 	PublicCustomCSS string `json:"appearance.public.custom_css"`
 	PublicCustomJS  string `json:"appearance.public.custom_js"`
+
+	BrandingProductName string `json:"appearance.branding.product_name"`
+	BrandingLogoURL     string `json:"appearance.branding.logo_url"`
+	BrandingColor       string `json:"appearance.branding.color"`
+	BrandingFooter      string `json:"appearance.branding.footer"`
+
+	SentArchiveMode          string `json:"sent_archive.mode"`
+	SentArchiveRetentionDays int    `json:"sent_archive.retention_days"`
+
+	SendLogRetentionDays int `json:"send_log.retention_days"`
 }
@@ -19,7 +19,7 @@ WITH sub AS (
 )
 SELECT * FROM lists
     LEFT JOIN subscriber_lists ON (lists.id = subscriber_lists.list_id)
-    WHERE subscriber_id = (SELECT id FROM sub)
+    WHERE subscriber_id = (SELECT id FROM sub) AND lists.deleted_at IS NULL
     -- Optional list IDs or UUIDs to filter.
     AND (CASE WHEN CARDINALITY($3::INT[]) > 0 THEN id = ANY($3::INT[])
           WHEN CARDINALITY($4::UUID[]) > 0 THEN uuid = ANY($4::UUID[])
@@ -43,13 +43,14 @@ WITH subs AS (
                     subscriber_lists.status AS subscription_status,
                     subscriber_lists.created_at AS subscription_created_at,
                     subscriber_lists.updated_at AS subscription_updated_at,
+                    subscriber_lists.snoozed_until AS subscription_snoozed_until,
                     subscriber_lists.meta AS subscription_meta,
                     lists.*
             ) l)
         )
     ) AS lists FROM lists
     LEFT JOIN subscriber_lists ON (subscriber_lists.list_id = lists.id)
-    WHERE subscriber_lists.subscriber_id = ANY($1)
+    WHERE subscriber_lists.subscriber_id = ANY($1) AND lists.deleted_at IS NULL
     GROUP BY subscriber_id
 )
 SELECT id as subscriber_id,
@@ -71,26 +72,32 @@ SELECT lists.*,
     subscriber_lists.meta as subscription_meta
     FROM lists LEFT JOIN subscriber_lists
     ON (subscriber_lists.list_id = lists.id AND subscriber_lists.subscriber_id = (SELECT id FROM sub))
-    WHERE CASE WHEN $3 = TRUE THEN TRUE ELSE subscriber_lists.status IS NOT NULL END
+    WHERE lists.deleted_at IS NULL AND CASE WHEN $3 = TRUE THEN TRUE ELSE subscriber_lists.status IS NOT NULL END
     ORDER BY subscriber_lists.status;
 
 -- name: insert-subscriber
-WITH sub AS (
+WITH RECURSIVE sub AS (
     INSERT INTO subscribers (uuid, email, name, status, attribs)
     VALUES($1, $2, $3, $4, $5)
     RETURNING id, status
 ),
 listIDs AS (
-    SELECT id FROM lists WHERE
+    SELECT id, parent_id FROM lists WHERE deleted_at IS NULL AND
         (CASE WHEN CARDINALITY($6::INT[]) > 0 THEN id=ANY($6)
               ELSE uuid=ANY($7::UUID[]) END)
+    UNION
+    -- Subscribing to a list implies its parent lists.
+    SELECT lists.id, lists.parent_id FROM lists INNER JOIN listIDs ON (lists.id = listIDs.parent_id)
+        WHERE lists.deleted_at IS NULL
 ),
 subs AS (
-    INSERT INTO subscriber_lists (subscriber_id, list_id, status)
+    INSERT INTO subscriber_lists (subscriber_id, list_id, status, meta, source)
     VALUES(
         (SELECT id FROM sub),
         UNNEST(ARRAY(SELECT id FROM listIDs)),
-        (CASE WHEN $4='blocklisted' THEN 'unsubscribed'::subscription_status ELSE $8::subscription_status END)
+        (CASE WHEN $4='blocklisted' THEN 'unsubscribed'::subscription_status ELSE $8::subscription_status END),
+        $9::JSONB,
+        $10
     )
     ON CONFLICT (subscriber_id, list_id) DO UPDATE
         SET updated_at=NOW(),
@@ -98,14 +105,16 @@ subs AS (
                 CASE WHEN $4='blocklisted' OR (SELECT status FROM sub)='blocklisted'
                 THEN 'unsubscribed'::subscription_status
                 ELSE $8::subscription_status END
-            )
+            ),
+            meta=subscriber_lists.meta || $9::JSONB
 )
 SELECT id from sub;
 
 -- name: upsert-subscriber
 -- Upserts a subscriber where existing subscribers get their names and attributes overwritten.
--- If $7 = true, update values, otherwise, skip.
-WITH sub AS (
+-- If $7 = true, update values, otherwise, skip. $8 is the subscription meta and $9, the source
+-- of new subscriptions.
+WITH RECURSIVE sub AS (
     INSERT INTO subscribers as s (uuid, email, name, attribs, status)
     VALUES($1, $2, $3, $4, 'enabled')
     ON CONFLICT (email)
@@ -115,11 +124,19 @@ WITH sub AS (
         updated_at=NOW()
     RETURNING uuid, id
 ),
+listIDs AS (
+    SELECT id, parent_id FROM lists WHERE id = ANY($5::INT[])
+    UNION
+    -- Subscribing to a list implies its parent lists.
+    SELECT lists.id, lists.parent_id FROM lists INNER JOIN listIDs ON (lists.id = listIDs.parent_id)
+        WHERE lists.deleted_at IS NULL
+),
 subs AS (
-    INSERT INTO subscriber_lists (subscriber_id, list_id, status)
-    VALUES((SELECT id FROM sub), UNNEST($5::INT[]), $6)
+    INSERT INTO subscriber_lists (subscriber_id, list_id, status, meta, source)
+    VALUES((SELECT id FROM sub), UNNEST(ARRAY(SELECT id FROM listIDs)), $6, $8::JSONB, $9)
     ON CONFLICT (subscriber_id, list_id) DO UPDATE
-    SET updated_at=NOW(), status=(CASE WHEN $7 THEN $6 ELSE subscriber_lists.status END)
+    SET updated_at=NOW(), status=(CASE WHEN $7 THEN $6 ELSE subscriber_lists.status END),
+        meta=(CASE WHEN $7 THEN subscriber_lists.meta || $8::JSONB ELSE subscriber_lists.meta END)
 )
 SELECT uuid, id from sub;
 
@@ -134,7 +151,7 @@ WITH sub AS (
     ON CONFLICT (email) DO UPDATE SET status='blocklisted', updated_at=NOW()
     RETURNING id
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
     WHERE subscriber_id = (SELECT id FROM sub);
 
 -- name: update-subscriber
@@ -148,8 +165,9 @@ WHERE id = $1;
 
 -- name: update-subscriber-with-lists
 -- Updates a subscriber's data, and given a list of list_ids, inserts subscriptions
--- for them while deleting existing subscriptions not in the list.
-WITH s AS (
+-- for them while deleting existing subscriptions not in the list. $10 is the meta
+-- merged into subscriptions that get confirmed and $11, the source of new subscriptions.
+WITH RECURSIVE s AS (
     UPDATE subscribers SET
         email=(CASE WHEN $2 != '' THEN $2 ELSE email END),
         name=(CASE WHEN $3 != '' THEN $3 ELSE name END),
@@ -159,21 +177,35 @@ WITH s AS (
     WHERE id = $1 RETURNING id
 ),
 listIDs AS (
-    SELECT id FROM lists WHERE
+    SELECT id, parent_id FROM lists WHERE deleted_at IS NULL AND
         (CASE WHEN CARDINALITY($6::INT[]) > 0 THEN id=ANY($6)
               ELSE uuid=ANY($7::UUID[]) END)
+    UNION
+    -- Subscribing to a list implies its parent lists.
+    SELECT lists.id, lists.parent_id FROM lists INNER JOIN listIDs ON (lists.id = listIDs.parent_id)
+        WHERE lists.deleted_at IS NULL
 ),
 d AS (
+    -- Subscriptions to trashed lists are retained so that they're intact if the list is restored.
     DELETE FROM subscriber_lists WHERE $9 = TRUE AND subscriber_id = $1 AND list_id != ALL(SELECT id FROM listIDs)
+        AND list_id NOT IN (SELECT id FROM lists WHERE deleted_at IS NOT NULL)
 )
-INSERT INTO subscriber_lists (subscriber_id, list_id, status)
+INSERT INTO subscriber_lists (subscriber_id, list_id, status, meta, source)
     VALUES(
         (SELECT id FROM s),
         UNNEST(ARRAY(SELECT id FROM listIDs)),
-        (CASE WHEN $4='blocklisted' THEN 'unsubscribed'::subscription_status ELSE $8::subscription_status END)
+        (CASE WHEN $4='blocklisted' THEN 'unsubscribed'::subscription_status ELSE $8::subscription_status END),
+        $10::JSONB,
+        $11
     )
     ON CONFLICT (subscriber_id, list_id) DO UPDATE
-    SET status = (
+    SET meta = (
+        CASE WHEN $4 != 'blocklisted' AND $9 = FALSE AND $8 = 'confirmed' AND subscriber_lists.status != 'confirmed'
+            THEN subscriber_lists.meta || $10::JSONB
+            ELSE subscriber_lists.meta
+        END
+    ),
+    status = (
         CASE
             WHEN $4='blocklisted' THEN 'unsubscribed'::subscription_status
             -- When subscriber is edited from the admin form, retain the status. Otherwise, a blocklisted
@@ -200,12 +232,19 @@ WITH b AS (
     UPDATE subscribers SET status='blocklisted', updated_at=NOW()
     WHERE id = ANY($1::INT[])
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
     WHERE subscriber_id = ANY($1::INT[]);
 
 -- name: add-subscribers-to-lists
-INSERT INTO subscriber_lists (subscriber_id, list_id, status)
-    (SELECT a, b, (CASE WHEN $3 != '' THEN $3::subscription_status ELSE 'unconfirmed' END) FROM UNNEST($1::INT[]) a, UNNEST($2::INT[]) b)
+WITH RECURSIVE listIDs AS (
+    SELECT id, parent_id FROM lists WHERE id = ANY($2::INT[])
+    UNION
+    -- Subscribing to a list implies its parent lists.
+    SELECT lists.id, lists.parent_id FROM lists INNER JOIN listIDs ON (lists.id = listIDs.parent_id)
+        WHERE lists.deleted_at IS NULL
+)
+INSERT INTO subscriber_lists (subscriber_id, list_id, status, source)
+    (SELECT a, b, (CASE WHEN $3 != '' THEN $3::subscription_status ELSE 'unconfirmed' END), 'admin' FROM UNNEST($1::INT[]) a, UNNEST(ARRAY(SELECT id FROM listIDs)) b)
     ON CONFLICT (subscriber_id, list_id) DO UPDATE SET status=(CASE WHEN $3 != '' THEN $3::subscription_status ELSE subscriber_lists.status END);
 
 -- name: delete-subscriptions
@@ -223,36 +262,79 @@ UPDATE subscriber_lists SET status='confirmed', meta=meta || $3, updated_at=NOW(
     WHERE subscriber_id = (SELECT id FROM subID) AND list_id = ANY(SELECT id FROM listIDs);
 
 -- name: unsubscribe-subscribers-from-lists
-WITH listIDs AS (
-    SELECT ARRAY(
-        SELECT id FROM lists WHERE
+WITH RECURSIVE listIDs AS (
+    SELECT id FROM lists WHERE
         (CASE WHEN CARDINALITY($2::INT[]) > 0 THEN id=ANY($2) ELSE uuid=ANY($3::UUID[]) END)
-    ) id
+    UNION
+    -- Unsubscribing from a list cascades down to its child lists.
+    SELECT lists.id FROM lists INNER JOIN listIDs ON (lists.parent_id = listIDs.id)
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
-    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST($1::INT[]) a, UNNEST((SELECT id FROM listIDs)) b);
+UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST($1::INT[]) a, UNNEST(ARRAY(SELECT id FROM listIDs)) b);
 
 -- name: unsubscribe-by-campaign
 -- Unsubscribes a subscriber given a campaign UUID (from all the lists in the campaign) and the subscriber UUID.
 -- If $3 is TRUE, then all subscriptions of the subscriber is blocklisted
 -- and all existing subscriptions, irrespective of lists, unsubscribed.
-WITH lists AS (
+WITH RECURSIVE campLists AS (
     SELECT list_id FROM campaign_lists
     LEFT JOIN campaigns ON (campaign_lists.campaign_id = campaigns.id)
     WHERE campaigns.uuid = $1
+    UNION
+    -- Campaigns are sent to the child lists of their lists.
+    SELECT lists.id FROM lists INNER JOIN campLists ON (lists.parent_id = campLists.list_id)
 ),
 sub AS (
     UPDATE subscribers SET status = (CASE WHEN $3 IS TRUE THEN 'blocklisted' ELSE status END)
     WHERE uuid = $2 RETURNING id
 )
-UPDATE subscriber_lists SET status = 'unsubscribed', updated_at=NOW() WHERE
-    subscriber_id = (SELECT id FROM sub) AND status != 'unsubscribed' AND
+UPDATE subscriber_lists SET status = 'unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW() WHERE
+    -- Snoozed subscriptions are unsubscribed for good.
+    subscriber_id = (SELECT id FROM sub) AND (status != 'unsubscribed' OR snoozed_until IS NOT NULL) AND
     -- If $3 is false, unsubscribe from the campaign's lists, otherwise all lists.
-    CASE WHEN $3 IS FALSE THEN list_id = ANY(SELECT list_id FROM lists) ELSE list_id != 0 END;
+    CASE WHEN $3 IS FALSE THEN list_id = ANY(SELECT list_id FROM campLists) ELSE list_id != 0 END;
+
+-- name: snooze-subscription
+-- Snoozes a subscriber's ($1) subscriptions to the lists of a campaign ($2), or to all
+-- lists if there's no campaign, for $3 days. Snoozed subscriptions are unsubscribed until
+-- they're resumed by resume-snoozed-subscriptions.
+WITH RECURSIVE campLists AS (
+    SELECT list_id FROM campaign_lists
+    LEFT JOIN campaigns ON (campaign_lists.campaign_id = campaigns.id)
+    WHERE campaigns.uuid = NULLIF($2, '')::UUID
+    UNION
+    -- Campaigns are sent to the child lists of their lists.
+    SELECT lists.id FROM lists INNER JOIN campLists ON (lists.parent_id = campLists.list_id)
+)
+UPDATE subscriber_lists SET snooze_status=status, status='unsubscribed',
+    snoozed_until=NOW() + MAKE_INTERVAL(days => $3::INT), updated_at=NOW()
+    WHERE subscriber_id = (SELECT id FROM subscribers WHERE uuid = $1) AND status != 'unsubscribed'
+    AND ($2 = '' OR list_id = ANY(SELECT list_id FROM campLists));
+
+-- name: resume-snoozed-subscriptions
+-- Restores the subscriptions whose snooze has ended. Subscriptions of subscribers who
+-- have since been blocklisted stay unsubscribed.
+UPDATE subscriber_lists SET status=(CASE WHEN s.status = 'blocklisted' THEN 'unsubscribed' ELSE snooze_status END),
+    snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    FROM subscribers s
+    WHERE s.id = subscriber_lists.subscriber_id AND snoozed_until <= NOW() AND snooze_status IS NOT NULL;
+
+-- name: unsubscribe-subscriber
+-- Unsubscribes a subscriber given the subscriber UUID from all lists.
+-- If $2 is TRUE, then the subscriber is also blocklisted.
+WITH sub AS (
+    UPDATE subscribers SET status = (CASE WHEN $2 IS TRUE THEN 'blocklisted' ELSE status END)
+    WHERE uuid = $1 RETURNING id
+)
+UPDATE subscriber_lists SET status = 'unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE subscriber_id = (SELECT id FROM sub) AND (status != 'unsubscribed' OR snoozed_until IS NOT NULL);
 
 -- name: delete-unconfirmed-subscriptions
+-- Lists with a running re-permission are skipped as their unconfirmed
+-- subscriptions are unsubscribed at the re-permission deadline.
 WITH optins AS (
     SELECT id FROM lists WHERE optin = 'double'
+    AND id NOT IN (SELECT list_id FROM list_repermissions WHERE status = 'running')
 )
 DELETE FROM subscriber_lists
     WHERE status = 'unconfirmed' AND list_id IN (SELECT id FROM optins) AND created_at < $1;
@@ -299,8 +381,9 @@ SELECT (SELECT email FROM prof) as email,
 -- there's a COUNT() OVER() that still returns the total result count
 -- for pagination in the frontend, albeit being a field that'll repeat
 -- with every resultant row.
--- %s = arbitrary expression, %s = order by field, %s = order direction
-SELECT subscribers.* FROM subscribers
+-- %columns% = selected columns (eg: subscribers.*), %query% = arbitrary expression,
+-- %order% = order by field and direction
+SELECT %columns% FROM subscribers
     LEFT JOIN subscriber_lists
     ON (
         -- Optional list filtering.
@@ -312,6 +395,14 @@ SELECT subscribers.* FROM subscribers
     %query%
     ORDER BY %order% OFFSET $3 LIMIT (CASE WHEN $4 < 1 THEN NULL ELSE $4 END);
 
+-- name: get-subscribers-batch
+-- raw: true
+-- Selected columns (%columns%) of the subscribers with the given UUIDs ($1) or
+-- lowercased e-mails ($2).
+SELECT %columns% FROM subscribers
+    WHERE subscribers.uuid = ANY($1::UUID[]) OR LOWER(subscribers.email) = ANY($2::TEXT[])
+    ORDER BY subscribers.id;
+
 -- name: query-subscribers-count
 -- Replica of query-subscribers for obtaining the results count.
 SELECT COUNT(*) AS total FROM subscribers
@@ -324,6 +415,38 @@ SELECT COUNT(*) AS total FROM subscribers
     )
     WHERE (CARDINALITY($1) = 0 OR subscriber_lists.list_id = ANY($1::INT[])) %s;
 
+-- name: search-subscribers
+-- Typo tolerant search of subscribers' names, e-mails, and attributes using the trigram
+-- index. $1 = search term, $2 = $1 escaped for LIKE. Results that contain the term are
+-- ranked first followed by the ones that are similar to it.
+WITH q AS (SELECT LOWER($1) AS q, '%' || LOWER($2) || '%' AS pattern)
+SELECT COUNT(*) OVER () AS total, subscribers.* FROM subscribers, q
+    WHERE (CARDINALITY($3::INT[]) = 0 OR subscribers.id IN (
+        SELECT subscriber_id FROM subscriber_lists WHERE list_id = ANY($3::INT[])
+        AND ($4 = '' OR subscriber_lists.status = $4::subscription_status)
+    ))
+    AND (
+        LOWER(subscribers.name || ' ' || subscribers.email || ' ' || subscribers.attribs::TEXT) LIKE q.pattern
+        OR q.q <% LOWER(subscribers.name || ' ' || subscribers.email || ' ' || subscribers.attribs::TEXT)
+    )
+    ORDER BY LOWER(subscribers.name || ' ' || subscribers.email || ' ' || subscribers.attribs::TEXT) LIKE q.pattern DESC,
+        WORD_SIMILARITY(q.q, LOWER(subscribers.name || ' ' || subscribers.email || ' ' || subscribers.attribs::TEXT)) DESC,
+        subscribers.id DESC
+    OFFSET $5 LIMIT (CASE WHEN $6 < 1 THEN NULL ELSE $6 END);
+
+-- name: search-subscribers-ilike
+-- Fallback of search-subscribers used in place of it when the pg_trgm extension isn't
+-- installed. Only the subscribers that contain the term are returned, exact e-mail matches first.
+WITH q AS (SELECT '%' || $2 || '%' AS pattern)
+SELECT COUNT(*) OVER () AS total, subscribers.* FROM subscribers, q
+    WHERE (CARDINALITY($3::INT[]) = 0 OR subscribers.id IN (
+        SELECT subscriber_id FROM subscriber_lists WHERE list_id = ANY($3::INT[])
+        AND ($4 = '' OR subscriber_lists.status = $4::subscription_status)
+    ))
+    AND (subscribers.name || ' ' || subscribers.email || ' ' || subscribers.attribs::TEXT) ILIKE q.pattern
+    ORDER BY LOWER(subscribers.email) = LOWER($1::TEXT) DESC, subscribers.id DESC
+    OFFSET $5 LIMIT (CASE WHEN $6 < 1 THEN NULL ELSE $6 END);
+
 -- name: query-subscribers-count-all
 -- Cached query for getting the "all" subscriber count without arbitrary conditions.
 SELECT COALESCE(SUM(subscriber_count), 0) AS total FROM mat_list_subscriber_stats
@@ -387,14 +510,21 @@ b AS (
     UPDATE subscribers SET status='blocklisted', updated_at=NOW()
     WHERE id = ANY(SELECT id FROM subs)
 )
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
+UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
     WHERE subscriber_id = ANY(SELECT id FROM subs);
 
 -- name: add-subscribers-to-lists-by-query
 -- raw: true
-WITH subs AS (%s)
-INSERT INTO subscriber_lists (subscriber_id, list_id, status)
-    (SELECT a, b, (CASE WHEN $4 != '' THEN $4::subscription_status ELSE 'unconfirmed' END) FROM UNNEST(ARRAY(SELECT id FROM subs)) a, UNNEST($3::INT[]) b)
+WITH RECURSIVE subs AS (%s),
+listIDs AS (
+    SELECT id, parent_id FROM lists WHERE id = ANY($3::INT[])
+    UNION
+    -- Subscribing to a list implies its parent lists.
+    SELECT lists.id, lists.parent_id FROM lists INNER JOIN listIDs ON (lists.id = listIDs.parent_id)
+        WHERE lists.deleted_at IS NULL
+)
+INSERT INTO subscriber_lists (subscriber_id, list_id, status, source)
+    (SELECT a, b, (CASE WHEN $4 != '' THEN $4::subscription_status ELSE 'unconfirmed' END), 'admin' FROM UNNEST(ARRAY(SELECT id FROM subs)) a, UNNEST(ARRAY(SELECT id FROM listIDs)) b)
     ON CONFLICT (subscriber_id, list_id) DO NOTHING;
 
 -- name: delete-subscriptions-by-query
@@ -405,14 +535,21 @@ DELETE FROM subscriber_lists
 
 -- name: unsubscribe-subscribers-from-lists-by-query
 -- raw: true
-WITH subs AS (%s)
-UPDATE subscriber_lists SET status='unsubscribed', updated_at=NOW()
-    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST(ARRAY(SELECT id FROM subs)) a, UNNEST($3::INT[]) b);
+WITH RECURSIVE subs AS (%s),
+listIDs AS (
+    SELECT id FROM lists WHERE id = ANY($3::INT[])
+    UNION
+    -- Unsubscribing from a list cascades down to its child lists.
+    SELECT lists.id FROM lists INNER JOIN listIDs ON (lists.parent_id = listIDs.id)
+)
+UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE (subscriber_id, list_id) = ANY(SELECT a, b FROM UNNEST(ARRAY(SELECT id FROM subs)) a, UNNEST(ARRAY(SELECT id FROM listIDs)) b);
 
 
 -- lists
 -- name: get-lists
-SELECT * FROM lists WHERE (CASE WHEN $1 = '' THEN 1=1 ELSE type=$1::list_type END)
+-- Archived lists are excluded when fetching lists by type (eg: public lists for public forms and pages).
+SELECT * FROM lists WHERE deleted_at IS NULL AND (CASE WHEN $1 = '' THEN 1=1 ELSE type=$1::list_type AND status='active' END)
     ORDER BY CASE WHEN $2 = 'id' THEN id END, CASE WHEN $2 = 'name' THEN name END;
 
 -- name: query-lists
@@ -427,6 +564,9 @@ WITH ls AS (
     AND ($4 = '' OR type = $4::list_type)
     AND ($5 = '' OR optin = $5::list_optin)
     AND (CARDINALITY($6::VARCHAR(100)[]) = 0 OR $6 <@ tags)
+    AND ($9 = '' OR folder = $9)
+    AND ($10 = '' OR status = $10::list_status)
+    AND deleted_at IS NULL
     OFFSET $7 LIMIT (CASE WHEN $8 < 1 THEN NULL ELSE $8 END)
 ),
 statuses AS (
@@ -441,13 +581,13 @@ SELECT ls.*, COALESCE(ss.subscriber_statuses, '{}') AS subscriber_statuses
 
 -- name: get-lists-by-optin
 -- Can have a list of IDs or a list of UUIDs.
-SELECT * FROM lists WHERE (CASE WHEN $1 != '' THEN optin=$1::list_optin ELSE TRUE END) AND
+SELECT * FROM lists WHERE deleted_at IS NULL AND (CASE WHEN $1 != '' THEN optin=$1::list_optin ELSE TRUE END) AND
     (CASE WHEN $2::INT[] IS NOT NULL THEN id = ANY($2::INT[])
           WHEN $3::UUID[] IS NOT NULL THEN uuid = ANY($3::UUID[])
     END) ORDER BY name;
 
 -- name: create-list
-INSERT INTO lists (uuid, name, type, optin, tags, description) VALUES($1, $2, $3, $4, $5, $6) RETURNING id;
+INSERT INTO lists (uuid, name, type, optin, tags, description, content_qa_url, address_filter, return_path, parent_id, folder, entitlement_url, max_subscribers, form_fields) VALUES($1, $2, $3, $4, $5, $6, $7, $8::list_address_filter, $9, $10, $11, $12, $13, $14) RETURNING id;
 
 -- name: update-list
 UPDATE lists SET
@@ -456,24 +596,257 @@ UPDATE lists SET
     optin=(CASE WHEN $4 != '' THEN $4::list_optin ELSE optin END),
     tags=$5::VARCHAR(100)[],
     description=(CASE WHEN $6 != '' THEN $6 ELSE description END),
+    content_qa_url=$7,
+    address_filter=(CASE WHEN $8 != '' THEN $8::list_address_filter ELSE address_filter END),
+    return_path=$9,
+    parent_id=$10,
+    folder=$11,
+    entitlement_url=$12,
+    max_subscribers=$13,
+    form_fields=$14,
     updated_at=NOW()
 WHERE id = $1;
 
+-- name: bulk-update-lists
+-- Applies a bulk action ($2) to lists: add or remove tags ($3), move to a folder ($4),
+-- change the opt-in mode ($5), archive, or unarchive.
+UPDATE lists SET
+    tags=(CASE
+        WHEN $2 = 'tag' THEN ARRAY(SELECT DISTINCT UNNEST(COALESCE(tags, '{}') || $3::VARCHAR(100)[]))
+        WHEN $2 = 'untag' THEN ARRAY(SELECT UNNEST(tags) EXCEPT SELECT UNNEST($3::VARCHAR(100)[]))
+        ELSE tags
+    END),
+    folder=(CASE WHEN $2 = 'folder' THEN $4 ELSE folder END),
+    optin=(CASE WHEN $2 = 'optin' AND $5 != '' THEN $5::list_optin ELSE optin END),
+    status=(CASE
+        WHEN $2 = 'archive' THEN 'archived'::list_status
+        WHEN $2 = 'unarchive' THEN 'active'::list_status
+        ELSE status
+    END),
+    updated_at=NOW()
+WHERE id = ANY($1::INT[]) AND deleted_at IS NULL;
+
+-- name: merge-lists
+-- Merges list $2 into list $1. Subscriptions to $2 are copied to $1 and for subscribers
+-- on both lists, the stronger status (confirmed > unconfirmed > unsubscribed) is kept.
+-- Campaigns that haven't finished are retargeted from $2 to $1, child lists of $2 are
+-- moved under $1, and $2 is archived.
+WITH target AS (
+    SELECT id, name FROM lists WHERE id = $1 AND deleted_at IS NULL
+),
+subs AS (
+    INSERT INTO subscriber_lists (subscriber_id, list_id, status, meta, source, created_at)
+        SELECT sl.subscriber_id, (SELECT id FROM target), sl.status, sl.meta, sl.source, sl.created_at
+        FROM subscriber_lists sl WHERE sl.list_id = $2 AND EXISTS (SELECT 1 FROM target)
+    ON CONFLICT (subscriber_id, list_id) DO UPDATE SET
+        status=(CASE
+            WHEN (CASE EXCLUDED.status WHEN 'confirmed' THEN 2 WHEN 'unconfirmed' THEN 1 ELSE 0 END) >
+                (CASE subscriber_lists.status WHEN 'confirmed' THEN 2 WHEN 'unconfirmed' THEN 1 ELSE 0 END)
+            THEN EXCLUDED.status ELSE subscriber_lists.status
+        END),
+        meta=EXCLUDED.meta || subscriber_lists.meta,
+        updated_at=NOW()
+    RETURNING subscriber_id
+),
+camps AS (
+    SELECT campaign_id FROM campaign_lists
+    INNER JOIN campaigns ON (campaigns.id = campaign_lists.campaign_id)
+    WHERE campaign_lists.list_id = $2 AND campaigns.status NOT IN ('finished', 'cancelled')
+        AND EXISTS (SELECT 1 FROM target)
+),
+retarget AS (
+    -- Campaigns that already target $1 only have $2 removed.
+    UPDATE campaign_lists SET list_id=(SELECT id FROM target), list_name=(SELECT name FROM target)
+    WHERE list_id = $2 AND campaign_id = ANY(SELECT campaign_id FROM camps)
+        AND NOT EXISTS (SELECT 1 FROM campaign_lists cl WHERE cl.campaign_id = campaign_lists.campaign_id AND cl.list_id = $1)
+),
+dedup AS (
+    DELETE FROM campaign_lists WHERE list_id = $2 AND campaign_id = ANY(SELECT campaign_id FROM camps)
+        AND EXISTS (SELECT 1 FROM campaign_lists cl WHERE cl.campaign_id = campaign_lists.campaign_id AND cl.list_id = $1)
+),
+children AS (
+    UPDATE lists SET
+        parent_id=(CASE WHEN id = $1 THEN (SELECT parent_id FROM lists WHERE id = $2) ELSE $1 END),
+        updated_at=NOW()
+    WHERE parent_id = $2 AND EXISTS (SELECT 1 FROM target)
+),
+archive AS (
+    UPDATE lists SET status='archived', updated_at=NOW() WHERE id = $2 AND EXISTS (SELECT 1 FROM target)
+)
+SELECT EXISTS (SELECT 1 FROM target) AS found,
+    (SELECT COUNT(*) FROM subs) AS subscribers,
+    (SELECT COUNT(*) FROM camps) AS campaigns;
+
+-- name: get-list-folders
+SELECT DISTINCT folder FROM lists WHERE folder != '' AND deleted_at IS NULL ORDER BY folder;
+
+-- name: get-list-descendant-ids
+-- Returns the IDs of a list and all its child lists, recursively.
+WITH RECURSIVE ids AS (
+    SELECT id FROM lists WHERE id = $1
+    UNION
+    SELECT lists.id FROM lists INNER JOIN ids ON (lists.parent_id = ids.id)
+)
+SELECT id FROM ids;
+
+-- name: start-list-repermission
+-- Converts a list to double opt-in, marks all its subscriptions (except unsubscribed ones)
+-- as unconfirmed so that subscribers have to confirm again, and records the re-permission run
+-- with its opt-in campaign ($3). It's a single statement, so either all or none of it is applied.
+WITH list AS (
+    UPDATE lists SET optin='double', updated_at=NOW() WHERE id = $1 RETURNING id
+),
+subs AS (
+    UPDATE subscriber_lists SET status='unconfirmed', updated_at=NOW()
+    WHERE list_id = (SELECT id FROM list) AND status != 'unsubscribed'
+    RETURNING subscriber_id
+)
+INSERT INTO list_repermissions (list_id, deadline, campaign_id, total)
+    SELECT id, $2, $3, (SELECT COUNT(*) FROM subs) FROM list
+    RETURNING id;
+
+-- name: get-list-repermissions
+-- Returns the re-permission runs of a list (or all lists if $1 = 0), latest first, along with
+-- the live number of confirmed and pending subscriptions. All subscriptions are unconfirmed
+-- when a run starts, so confirmed ones are the subscribers who have confirmed since.
+SELECT r.*, lists.name AS list_name,
+    COUNT(sl.subscriber_id) FILTER (WHERE sl.status = 'confirmed') AS confirmed,
+    COUNT(sl.subscriber_id) FILTER (WHERE sl.status = 'unconfirmed') AS pending
+    FROM list_repermissions r
+    LEFT JOIN lists ON (lists.id = r.list_id)
+    LEFT JOIN subscriber_lists sl ON (sl.list_id = r.list_id)
+    WHERE ($1 = 0 OR r.list_id = $1)
+    GROUP BY r.id, lists.name
+    ORDER BY r.created_at DESC;
+
+-- name: cancel-list-repermission
+UPDATE list_repermissions SET status='cancelled', updated_at=NOW() WHERE list_id = $1 AND status = 'running';
+
+-- name: finish-list-repermissions
+-- Unsubscribes the subscribers who haven't confirmed from the lists whose re-permission
+-- deadline has passed (or from the given list ID ($1) right away) and marks the runs as finished.
+-- Returns the number of subscriptions that were unsubscribed.
+WITH due AS (
+    SELECT id, list_id FROM list_repermissions WHERE status = 'running' AND
+    (CASE WHEN $1 > 0 THEN list_id = $1 ELSE deadline <= NOW() END)
+),
+unsub AS (
+    UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE list_id = ANY(SELECT list_id FROM due) AND status = 'unconfirmed'
+    RETURNING list_id
+),
+counts AS (
+    SELECT list_id, COUNT(*) AS num FROM unsub GROUP BY list_id
+),
+done AS (
+    UPDATE list_repermissions r SET status='finished',
+        unsubscribed=COALESCE((SELECT num FROM counts WHERE counts.list_id = r.list_id), 0),
+        updated_at=NOW()
+    WHERE r.id = ANY(SELECT id FROM due)
+)
+SELECT COUNT(*) FROM unsub;
+
+-- name: get-list-cap-counts
+-- Returns the number of subscriptions (except unsubscribed ones) of the given lists ($1)
+-- that have a subscriber cap, not counting the subscriber with the given e-mail ($2), who
+-- may already be subscribed.
+SELECT lists.id, COUNT(sl.subscriber_id) AS count FROM lists
+    LEFT JOIN subscriber_lists sl ON (sl.list_id = lists.id AND sl.status != 'unsubscribed'
+        AND sl.subscriber_id NOT IN (SELECT id FROM subscribers WHERE LOWER(email) = LOWER($2)))
+    WHERE lists.id = ANY($1::INT[]) AND lists.max_subscribers > 0
+    GROUP BY lists.id;
+
+-- name: get-entitled-subscribers
+-- Returns a page of subscribers ($3 per page) with subscriptions (except unsubscribed
+-- ones) to a list ($1) after the given subscriber ID ($2) for checking their entitlements.
+SELECT s.id, s.uuid, s.email, s.name, s.attribs FROM subscriber_lists sl
+    INNER JOIN subscribers s ON (s.id = sl.subscriber_id)
+    WHERE sl.list_id = $1 AND sl.status != 'unsubscribed' AND s.id > $2
+    ORDER BY s.id LIMIT $3;
+
+-- name: get-list-blackouts
+-- Returns the blackouts of a list (or all lists if $1 = 0) that haven't ended, or all
+-- blackouts if $2 = true.
+SELECT b.*, COALESCE(lists.name, '') AS list_name FROM list_blackouts b
+    LEFT JOIN lists ON (lists.id = b.list_id)
+    WHERE ($1 = 0 OR b.list_id = $1) AND ($2 OR b.ends_at > NOW())
+    ORDER BY b.starts_at;
+
+-- name: create-list-blackout
+INSERT INTO list_blackouts (list_id, name, starts_at, ends_at) VALUES($1, $2, $3, $4) RETURNING id;
+
+-- name: delete-list-blackout
+DELETE FROM list_blackouts WHERE id = $1 AND list_id = $2;
+
+-- name: get-campaign-blackouts
+-- Returns the blackouts of a campaign's lists that its send time (or now, if it
+-- isn't scheduled) falls in.
+WITH camp AS (
+    SELECT id, COALESCE(send_at, NOW()) AS send_at FROM campaigns WHERE id = $1
+)
+SELECT b.*, lists.name AS list_name FROM list_blackouts b
+    INNER JOIN campaign_lists cl ON (cl.list_id = b.list_id)
+    INNER JOIN camp ON (camp.id = cl.campaign_id)
+    LEFT JOIN lists ON (lists.id = b.list_id)
+    WHERE camp.send_at >= b.starts_at AND camp.send_at < b.ends_at
+    ORDER BY b.ends_at;
+
 -- name: update-lists-date
 UPDATE lists SET updated_at=NOW() WHERE id = ANY($1);
 
 -- name: delete-lists
-DELETE FROM lists WHERE id = ALL($1);
+-- Moves lists to the trash. They're purged permanently by purge-trash.
+UPDATE lists SET deleted_at=NOW(), updated_at=NOW() WHERE id = ANY($1) AND deleted_at IS NULL;
+
+-- name: get-list-growth
+-- New subscriptions to a list ($1) between two dates ($2, $3, inclusive) grouped by source
+-- and interval ($4 = day, week, month), with the number of them that are still subscribed.
+-- The dates and intervals are in the reporting time zone ($5).
+SELECT DATE_TRUNC($4, created_at, $5) AS date, source,
+    COUNT(*) AS count,
+    COUNT(*) FILTER (WHERE status != 'unsubscribed') AS active
+    FROM subscriber_lists
+    WHERE list_id = $1 AND created_at >= $2::DATE::TIMESTAMP AT TIME ZONE $5
+        AND created_at < ($3::DATE + 1)::TIMESTAMP AT TIME ZONE $5
+    GROUP BY 1, source ORDER BY 1, source;
+
+-- name: get-list-churn
+-- Subscriptions and unsubscriptions of a list ($1) in each of the last $2 intervals
+-- ($3 = week, month) including the current one, and the number of subscribers at the
+-- start of each interval. There's no unsubscription log, so the time an unsubscribed
+-- subscription was last updated is taken to be its unsubscription time. The intervals
+-- start in the reporting time zone ($4).
+WITH periods AS (
+    SELECT GENERATE_SERIES(
+        DATE_TRUNC($3, NOW(), $4) - ($2 - 1) * ('1 ' || $3)::INTERVAL,
+        DATE_TRUNC($3, NOW(), $4),
+        ('1 ' || $3)::INTERVAL
+    ) AS date
+)
+SELECT p.date,
+    (SELECT COUNT(*) FROM subscriber_lists sl WHERE sl.list_id = $1
+        AND sl.created_at >= p.date AND sl.created_at < p.date + ('1 ' || $3)::INTERVAL) AS subscribed,
+    (SELECT COUNT(*) FROM subscriber_lists sl WHERE sl.list_id = $1 AND sl.status = 'unsubscribed'
+        AND sl.updated_at >= p.date AND sl.updated_at < p.date + ('1 ' || $3)::INTERVAL) AS unsubscribed,
+    (SELECT COUNT(*) FROM subscriber_lists sl WHERE sl.list_id = $1 AND sl.created_at < p.date
+        AND (sl.status != 'unsubscribed' OR sl.updated_at >= p.date)) AS start_total
+    FROM periods p ORDER BY p.date;
 
 
 -- campaigns
 -- name: create-campaign
 -- This creates the campaign and inserts campaign_lists relationships.
-WITH campLists AS (
+WITH RECURSIVE campLists AS (
     -- Get the list_ids and their optin statuses for the campaigns found in the previous step.
     SELECT lists.id AS list_id, campaign_id, optin FROM lists
     INNER JOIN campaign_lists ON (campaign_lists.list_id = lists.id)
-    WHERE lists.id = ANY($14::INT[])
+    WHERE lists.id = ANY($14::INT[]) AND lists.deleted_at IS NULL
+),
+exclLists AS (
+    -- The excluded lists ($34) and their child lists.
+    SELECT id AS list_id FROM lists WHERE id = ANY($34::INT[]) AND deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists INNER JOIN exclLists ON (lists.parent_id = exclLists.list_id) WHERE lists.deleted_at IS NULL
 ),
 tpl AS (
     -- If there's no template_id given, use the default template.
@@ -494,13 +867,18 @@ counts AS (
     )
     WHERE subscriber_lists.list_id=ANY($14::INT[])
     AND subscribers.status='enabled'
+    -- Subscribers of the excluded lists aren't sent to.
+    AND NOT EXISTS (
+        SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = subscribers.id
+        AND sl.list_id IN (SELECT list_id FROM exclLists) AND sl.status != 'unsubscribed'
+    )
 ),
 camp AS (
-    INSERT INTO campaigns (uuid, type, name, subject, from_email, body, altbody, content_type, send_at, headers, tags, messenger, template_id, to_send, max_subscriber_id, archive, archive_slug, archive_template_id, archive_meta)
+    INSERT INTO campaigns (uuid, type, name, subject, from_email, body, altbody, content_type, send_at, headers, tags, messenger, template_id, to_send, max_subscriber_id, archive, archive_slug, archive_template_id, archive_meta, content_url, reply_to, reply_tracking, return_path, header_preset_id, send_at_timezone, depends_on, depends_delay_mins, recurrence, feed_url, sto_window_hours, send_at_local, send_rate, send_windows, exclude_list_ids, utm_enabled, utm_campaign)
         SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
             (SELECT id FROM tpl), (SELECT to_send FROM counts),
             (SELECT max_sub_id FROM counts), $15, $16,
-            (CASE WHEN $17 = 0 THEN (SELECT id FROM tpl) ELSE $17 END), $18
+            (CASE WHEN $17 = 0 THEN (SELECT id FROM tpl) ELSE $17 END), $18, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, COALESCE($34::INT[], '{}'), $35, $36
         RETURNING id
 ),
 med AS (
@@ -509,7 +887,7 @@ med AS (
 ),
 insLists AS (
     INSERT INTO campaign_lists (campaign_id, list_id, list_name)
-        SELECT (SELECT id FROM camp), id, name FROM lists WHERE id=ANY($14::INT[])
+        SELECT (SELECT id FROM camp), id, name FROM lists WHERE id=ANY($14::INT[]) AND deleted_at IS NULL
 )
 SELECT id FROM camp;
 
@@ -524,7 +902,7 @@ SELECT  c.id, c.uuid, c.name, c.subject, c.from_email,
         c.messenger, c.started_at, c.to_send, c.sent, c.type,
         c.body, c.altbody, c.send_at, c.headers, c.status, c.content_type, c.tags,
         c.template_id, c.archive, c.archive_slug, c.archive_template_id, c.archive_meta,
-        c.created_at, c.updated_at,
+        c.content_url, c.content_checksum, c.reply_to, c.reply_tracking, c.return_path, c.header_preset_id, c.send_at_timezone, c.depends_on, c.depends_delay_mins, c.finished_at, c.recurrence, c.recurrence_parent_id, c.feed_url, c.feed_since, c.sto_window_hours, c.sto_queued_at, c.send_at_local, c.send_rate, c.send_windows, c.resend_of, c.exclude_list_ids, c.utm_enabled, c.utm_campaign, c.version, c.created_at, c.updated_at,
         COUNT(*) OVER () AS total,
         (
             SELECT COALESCE(ARRAY_TO_JSON(ARRAY_AGG(l)), '[]') FROM (
@@ -535,6 +913,7 @@ SELECT  c.id, c.uuid, c.name, c.subject, c.from_email,
     ) AS lists
 FROM campaigns c
 WHERE ($1 = 0 OR id = $1)
+    AND c.deleted_at IS NULL
     AND (CARDINALITY($2::campaign_status[]) = 0 OR status = ANY($2))
     AND (CARDINALITY($3::VARCHAR(100)[]) = 0 OR $3 <@ tags)
     AND ($4 = '' OR TO_TSVECTOR(CONCAT(name, ' ', subject)) @@ TO_TSQUERY($4) OR CONCAT(c.name, ' ', c.subject) ILIKE $4)
@@ -552,7 +931,8 @@ SELECT campaigns.*,
             WHEN $1 > 0 THEN campaigns.id = $1
             WHEN $3 != '' THEN campaigns.archive_slug = $3
             ELSE uuid = $2
-          END;
+          END
+    AND campaigns.deleted_at IS NULL;
 
 -- name: get-archived-campaigns
 SELECT COUNT(*) OVER () AS total, campaigns.*,
@@ -563,6 +943,7 @@ SELECT COUNT(*) OVER () AS total, campaigns.*,
         ELSE templates.id = campaigns.archive_template_id END
     )
     WHERE campaigns.archive=true AND campaigns.type='regular' AND campaigns.status=ANY('{running, paused, finished}')
+    AND campaigns.deleted_at IS NULL
     ORDER by campaigns.created_at DESC OFFSET $1 LIMIT $2;
 
 -- name: get-campaign-stats
@@ -592,11 +973,17 @@ bounces AS (
     SELECT campaign_id, COUNT(campaign_id) as num FROM bounces
     WHERE campaign_id = ANY($1)
     GROUP BY campaign_id
+),
+replies AS (
+    SELECT campaign_id, COUNT(campaign_id) as num FROM campaign_replies
+    WHERE campaign_id = ANY($1)
+    GROUP BY campaign_id
 )
 SELECT id as campaign_id,
     COALESCE(v.num, 0) AS views,
     COALESCE(c.num, 0) AS clicks,
     COALESCE(b.num, 0) AS bounces,
+    COALESCE(r.num, 0) AS replies,
     COALESCE(l.lists, '[]') AS lists,
     COALESCE(m.media, '[]') AS media
 FROM (SELECT id FROM UNNEST($1) AS id) x
@@ -605,10 +992,12 @@ LEFT JOIN media AS m ON (m.campaign_id = id)
 LEFT JOIN views AS v ON (v.campaign_id = id)
 LEFT JOIN clicks AS c ON (c.campaign_id = id)
 LEFT JOIN bounces AS b ON (b.campaign_id = id)
+LEFT JOIN replies AS r ON (r.campaign_id = id)
 ORDER BY ARRAY_POSITION($1, id);
 
 -- name: get-campaign-for-preview
 SELECT campaigns.*, COALESCE(templates.body, (SELECT body FROM templates WHERE is_default = true LIMIT 1)) AS template_body,
+COALESCE((SELECT headers FROM header_presets WHERE id = campaigns.header_preset_id), '[]') AS preset_headers,
 (
 	SELECT COALESCE(ARRAY_TO_JSON(ARRAY_AGG(l)), '[]') FROM (
 		SELECT COALESCE(campaign_lists.list_id, 0) AS id,
@@ -625,6 +1014,14 @@ SELECT id, status, to_send, sent, started_at, updated_at
     FROM campaigns
     WHERE status=$1;
 
+-- name: get-messenger-volume
+-- Returns the number of campaigns and messages sent by each messenger for the
+-- campaigns started in the last $1 days.
+SELECT messenger, COUNT(*) AS campaigns, COALESCE(SUM(sent), 0) AS sent
+    FROM campaigns
+    WHERE started_at > NOW() - MAKE_INTERVAL(days => $1)
+    GROUP BY messenger ORDER BY sent DESC;
+
 -- name: next-campaigns
 -- Retreives campaigns that are running (or scheduled and the time's up) and need
 -- to be processed. It updates the to_send count and max_subscriber_id of the campaign,
@@ -632,19 +1029,65 @@ SELECT id, status, to_send, sent, started_at, updated_at
 -- Thus, it has a sideaffect.
 -- In addition, it finds the max_subscriber_id, the upper limit across all lists of
 -- a campaign. This is used to fetch and slice subscribers for the campaign in next-campaign-subscribers.
-WITH camps AS (
+WITH RECURSIVE camps AS (
     -- Get all running campaigns and their template bodies (if the template's deleted, the default template body instead)
-    SELECT campaigns.*, COALESCE(templates.body, (SELECT body FROM templates WHERE is_default = true LIMIT 1)) AS template_body
+    SELECT campaigns.*, COALESCE(templates.body, (SELECT body FROM templates WHERE is_default = true LIMIT 1)) AS template_body,
+        -- Fallback envelope sender from the campaign's lists.
+        COALESCE((
+            SELECT lists.return_path FROM lists
+            INNER JOIN campaign_lists cl ON (cl.list_id = lists.id)
+            WHERE cl.campaign_id = campaigns.id AND lists.return_path != ''
+            ORDER BY lists.id LIMIT 1
+        ), '') AS list_return_path,
+        COALESCE((SELECT headers FROM header_presets WHERE id = campaigns.header_preset_id), '[]') AS preset_headers
     FROM campaigns
     LEFT JOIN templates ON (templates.id = campaigns.template_id)
-    WHERE (status='running' OR (status='scheduled' AND NOW() >= campaigns.send_at))
+    WHERE (status='running' OR (status='scheduled'
+        AND (NOW() >= campaigns.send_at OR (campaigns.send_at IS NULL AND campaigns.depends_on IS NOT NULL)
+            -- Campaigns sent at the subscribers' local time start when the time's
+            -- up in the earliest time zone (UTC+14) and queue their subscribers.
+            OR (campaigns.send_at_local AND NOW() >= (campaigns.send_at AT TIME ZONE
+                COALESCE(NULLIF(campaigns.send_at_timezone, ''), 'UTC')) AT TIME ZONE 'Etc/GMT-14'))
+        -- Scheduled campaigns are held while any of their lists is in a blackout.
+        AND NOT EXISTS (
+            SELECT 1 FROM list_blackouts b INNER JOIN campaign_lists cl ON (cl.list_id = b.list_id)
+            WHERE cl.campaign_id = campaigns.id AND NOW() >= b.starts_at AND NOW() < b.ends_at
+        )
+        -- Campaigns that depend on another campaign are held until it has finished
+        -- and the delay after it has passed.
+        AND (campaigns.depends_on IS NULL OR EXISTS (
+            SELECT 1 FROM campaigns dep WHERE dep.id = campaigns.depends_on AND dep.status = 'finished'
+            AND NOW() >= COALESCE(dep.finished_at, dep.updated_at) + MAKE_INTERVAL(mins => campaigns.depends_delay_mins)
+        ))))
+    AND campaigns.deleted_at IS NULL
+    -- Recurring campaigns aren't sent themselves. Their runs are.
+    AND campaigns.recurrence = ''
+    -- Campaigns whose A/B test has been sent are held until a winner is picked.
+    AND campaigns.ab_phase != 'waiting'
+    -- Campaigns with send-time optimization (or sent at local time) whose subscribers
+    -- have all been queued are held until the send time of the next queued subscriber.
+    AND (campaigns.sto_queued_at IS NULL OR EXISTS (
+        SELECT 1 FROM campaign_send_queue q WHERE q.campaign_id = campaigns.id AND q.send_at <= NOW()
+    ))
     AND NOT(campaigns.id = ANY($1::INT[]))
 ),
 campLists AS (
     -- Get the list_ids and their optin statuses for the campaigns found in the previous step.
     SELECT lists.id AS list_id, campaign_id, optin FROM lists
     INNER JOIN campaign_lists ON (campaign_lists.list_id = lists.id)
-    WHERE campaign_lists.campaign_id = ANY(SELECT id FROM camps)
+    WHERE campaign_lists.campaign_id = ANY(SELECT id FROM camps) AND lists.deleted_at IS NULL
+    UNION
+    -- Campaigns are sent to the child lists of their lists.
+    SELECT lists.id, campLists.campaign_id, lists.optin FROM lists
+    INNER JOIN campLists ON (lists.parent_id = campLists.list_id) WHERE lists.deleted_at IS NULL
+),
+exclLists AS (
+    -- The lists (and their child lists) whose subscribers are excluded from the campaigns.
+    SELECT camps.id AS campaign_id, lists.id AS list_id FROM camps
+    INNER JOIN lists ON (lists.id = ANY(camps.exclude_list_ids)) WHERE lists.deleted_at IS NULL
+    UNION
+    SELECT exclLists.campaign_id, lists.id FROM lists
+    INNER JOIN exclLists ON (lists.parent_id = exclLists.list_id) WHERE lists.deleted_at IS NULL
 ),
 campMedia AS (
     -- Get the list_ids and their optin statuses for the campaigns found in the previous step.
@@ -672,7 +1115,16 @@ counts AS (
             -- For regular campaigns with non-double optin lists, e-mail everyone
             -- except unsubscribed subscribers.
             ELSE subscriber_lists.status != 'unsubscribed'
-        END)
+        END) AND
+        -- Resends are only sent to the subscribers who didn't open the original.
+        (camps.resend_of IS NULL OR subscriber_lists.subscriber_id IN (
+            SELECT subscriber_id FROM campaign_sends WHERE campaign_id = camps.resend_of AND status = 'sent' AND opened_at IS NULL
+        )) AND
+        -- Subscribers of the excluded lists aren't sent to.
+        NOT EXISTS (
+            SELECT 1 FROM subscriber_lists sl INNER JOIN exclLists ON (exclLists.list_id = sl.list_id)
+            WHERE exclLists.campaign_id = camps.id AND sl.subscriber_id = subscriber_lists.subscriber_id AND sl.status != 'unsubscribed'
+        )
     )
     GROUP BY camps.id
 ),
@@ -688,19 +1140,169 @@ u AS (
     SET to_send = co.to_send,
         status = (CASE WHEN status != 'running' THEN 'running' ELSE status END),
         max_subscriber_id = co.max_subscriber_id,
-        started_at=(CASE WHEN ca.started_at IS NULL THEN NOW() ELSE ca.started_at END)
+        started_at=(CASE WHEN ca.started_at IS NULL THEN NOW() ELSE ca.started_at END),
+        -- Campaigns with an A/B test and two or more variants start with sending the test.
+        ab_phase=(CASE WHEN ca.ab_phase = '' AND ca.ab_test_percent > 0
+            AND (SELECT COUNT(*) FROM campaign_variants WHERE campaign_id = ca.id) >= 2
+            THEN 'testing' ELSE ca.ab_phase END)
     FROM (SELECT * FROM counts) co
     WHERE ca.id = co.campaign_id
 )
 SELECT camps.*, campMedia.media_id FROM camps LEFT JOIN campMedia ON (campMedia.campaign_id = camps.id);
 
+-- name: queue-campaign-subscribers
+-- Queues subscribers ($2) of a campaign ($1) with send-time optimization to be sent
+-- at the next occurrence of their best hour after the campaign started, but within
+-- its window. Subscribers without a best hour are sent right away.
+-- For campaigns sent at local time, subscribers are queued to be sent at the campaign's
+-- scheduled wall-clock time in their timezone attribute. Subscribers without a (valid)
+-- time zone are sent at the time in the campaign's time zone.
+INSERT INTO campaign_send_queue (campaign_id, subscriber_id, send_at)
+    SELECT c.id, s.id,
+        (CASE
+            WHEN c.send_at_local THEN GREATEST(NOW(),
+                (c.send_at AT TIME ZONE COALESCE(NULLIF(c.send_at_timezone, ''), 'UTC'))
+                AT TIME ZONE COALESCE(tz.name, NULLIF(c.send_at_timezone, ''), 'UTC'))
+            WHEN h.hour IS NULL THEN NOW()
+            ELSE LEAST(
+                c.started_at + MAKE_INTERVAL(hours => (h.hour - EXTRACT(HOUR FROM c.started_at AT TIME ZONE 'UTC')::INT + 24) % 24),
+                c.started_at + MAKE_INTERVAL(hours => c.sto_window_hours)
+            )
+        END)
+    FROM campaigns c
+    CROSS JOIN UNNEST($2::INT[]) AS s(id)
+    LEFT JOIN subscriber_send_hours h ON (h.subscriber_id = s.id)
+    LEFT JOIN subscribers sub ON (sub.id = s.id)
+    LEFT JOIN pg_timezone_names tz ON (tz.name = sub.attribs->>'timezone')
+    WHERE c.id = $1
+    ON CONFLICT DO NOTHING;
+
+-- name: set-campaign-sto-queued
+-- Marks all the subscribers of a campaign with send-time optimization as queued.
+UPDATE campaigns SET sto_queued_at=NOW(), updated_at=NOW() WHERE id = $1 AND sto_queued_at IS NULL;
+
+-- name: next-queued-subscribers
+-- Dequeues a batch of a campaign's ($1) queued subscribers whose send time is due.
+-- Subscribers who have unsubscribed from the campaign's lists, have been blocklisted,
+-- or have joined one of its excluded lists since they were queued are skipped.
+WITH RECURSIVE campLists AS (
+    SELECT lists.id AS list_id FROM lists
+    INNER JOIN campaign_lists ON (campaign_lists.list_id = lists.id)
+    WHERE campaign_lists.campaign_id = $1 AND lists.deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists
+    INNER JOIN campLists ON (lists.parent_id = campLists.list_id) WHERE lists.deleted_at IS NULL
+),
+exclLists AS (
+    SELECT id AS list_id FROM lists WHERE id = ANY((SELECT exclude_list_ids FROM campaigns WHERE id = $1)::INT[]) AND deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists INNER JOIN exclLists ON (lists.parent_id = exclLists.list_id) WHERE lists.deleted_at IS NULL
+),
+due AS (
+    DELETE FROM campaign_send_queue WHERE campaign_id = $1 AND subscriber_id IN (
+        SELECT subscriber_id FROM campaign_send_queue
+        WHERE campaign_id = $1 AND send_at <= NOW()
+        ORDER BY send_at LIMIT $2
+    )
+    RETURNING subscriber_id
+)
+SELECT subscribers.* FROM subscribers
+    WHERE id IN (SELECT subscriber_id FROM due) AND status != 'blocklisted'
+    AND EXISTS (
+        SELECT 1 FROM subscriber_lists WHERE subscriber_id = subscribers.id
+        AND list_id IN (SELECT list_id FROM campLists) AND status != 'unsubscribed'
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM subscriber_lists WHERE subscriber_id = subscribers.id
+        AND list_id IN (SELECT list_id FROM exclLists) AND status != 'unsubscribed'
+    )
+    ORDER BY id;
+
+-- name: get-campaign-queue-count
+SELECT COUNT(*) FROM campaign_send_queue WHERE campaign_id = $1;
+
+-- name: refresh-subscriber-send-hours
+-- Recomputes the hour of the day (UTC) at which subscribers have opened the most
+-- campaigns in the last $1 days.
+WITH hours AS (
+    SELECT DISTINCT ON (subscriber_id) subscriber_id, hour, opens FROM (
+        SELECT subscriber_id, EXTRACT(HOUR FROM created_at AT TIME ZONE 'UTC')::SMALLINT AS hour, COUNT(*) AS opens
+        FROM campaign_views
+        WHERE subscriber_id IS NOT NULL AND created_at > NOW() - MAKE_INTERVAL(days => $1)
+        GROUP BY subscriber_id, hour
+    ) h
+    ORDER BY subscriber_id, opens DESC, hour
+)
+INSERT INTO subscriber_send_hours (subscriber_id, hour, opens, updated_at)
+    SELECT subscriber_id, hour, opens, NOW() FROM hours
+    ON CONFLICT (subscriber_id) DO UPDATE SET hour = EXCLUDED.hour, opens = EXCLUDED.opens, updated_at = NOW();
+
+-- name: get-campaign-variants
+-- Returns the A/B test variants of a campaign with the stats of their test sends.
+-- The variant sent to a subscriber is computed the same as models.ABTestVariant().
+WITH camp AS (
+    SELECT ab_test_percent FROM campaigns WHERE id = $1
+),
+v AS (
+    SELECT *, ROW_NUMBER() OVER (ORDER BY id) - 1 AS idx FROM campaign_variants WHERE campaign_id = $1
+),
+sends AS (
+    SELECT ((subscriber_id % 100) + (subscriber_id / 100)) % NULLIF((SELECT COUNT(*) FROM v), 0) AS idx,
+        COUNT(*) FILTER (WHERE status IN ('sent', 'bounced')) AS sent,
+        COUNT(opened_at) AS opens,
+        COUNT(clicked_at) AS clicks
+    FROM campaign_sends
+    WHERE campaign_id = $1 AND subscriber_id % 100 < (SELECT ab_test_percent FROM camp)
+    GROUP BY 1
+)
+SELECT v.id, v.campaign_id, v.name, v.subject, v.body, v.is_winner, v.created_at,
+    COALESCE(sends.sent, 0) AS sent, COALESCE(sends.opens, 0) AS opens, COALESCE(sends.clicks, 0) AS clicks
+    FROM v LEFT JOIN sends ON (sends.idx = v.idx) ORDER BY v.id;
+
+-- name: set-campaign-variants
+-- Replaces the A/B test variants ($2 names, $3 subjects, $4 bodies) and the test
+-- settings of a campaign whose test hasn't started.
+WITH camp AS (
+    UPDATE campaigns SET ab_test_percent=$5, ab_metric=$6, ab_wait_hours=$7, updated_at=NOW()
+    WHERE id = $1 AND ab_phase = '' RETURNING id
+),
+del AS (
+    DELETE FROM campaign_variants WHERE campaign_id = (SELECT id FROM camp)
+)
+INSERT INTO campaign_variants (campaign_id, name, subject, body)
+    SELECT (SELECT id FROM camp), v.name, v.subject, v.body
+    FROM UNNEST($2::TEXT[], $3::TEXT[], $4::TEXT[]) WITH ORDINALITY AS v(name, subject, body, n)
+    WHERE EXISTS (SELECT 1 FROM camp) ORDER BY v.n;
+
+-- name: set-campaign-ab-waiting
+-- Marks the A/B test of a campaign as sent and resets its checkpoint for sending
+-- the winning variant to the rest of the subscribers.
+UPDATE campaigns SET ab_phase='waiting', ab_phase_at=NOW(), last_subscriber_id=0, updated_at=NOW()
+    WHERE id = $1 AND ab_phase = 'testing';
+
+-- name: get-ab-test-due-campaigns
+-- Returns the IDs of running campaigns whose A/B test wait time is up.
+SELECT id FROM campaigns
+    WHERE ab_phase = 'waiting' AND status = 'running' AND deleted_at IS NULL
+    AND ab_phase_at + MAKE_INTERVAL(hours => ab_wait_hours) <= NOW();
+
+-- name: set-campaign-ab-winner
+-- Marks a variant ($2) as the winner of a campaign's ($1) A/B test, which resumes
+-- sending the campaign to the rest of the subscribers.
+WITH v AS (
+    UPDATE campaign_variants SET is_winner = (id = $2) WHERE campaign_id = $1
+)
+UPDATE campaigns SET ab_phase='winner', ab_phase_at=NOW(), updated_at=NOW()
+    WHERE id = $1 AND ab_phase = 'waiting'
+    AND EXISTS (SELECT 1 FROM campaign_variants WHERE id = $2 AND campaign_id = $1);
+
 -- name: get-campaign-analytics-unique-counts
 WITH intval AS (
     -- For intervals < a week, aggregate counts hourly, otherwise daily.
     SELECT CASE WHEN (EXTRACT (EPOCH FROM ($3::TIMESTAMP - $2::TIMESTAMP)) / 86400) >= 7 THEN 'day' ELSE 'hour' END
 ),
 uniqIDs AS (
-    SELECT DISTINCT ON(subscriber_id) subscriber_id, campaign_id, DATE_TRUNC((SELECT * FROM intval), created_at) AS "timestamp"
+    SELECT DISTINCT ON(subscriber_id) subscriber_id, campaign_id, DATE_TRUNC((SELECT * FROM intval), created_at, $4) AS "timestamp"
     FROM %s
     WHERE campaign_id=ANY($1) AND created_at >= $2 AND created_at <= $3
     ORDER BY subscriber_id, "timestamp"
@@ -714,7 +1316,7 @@ WITH intval AS (
     -- For intervals < a week, aggregate counts hourly, otherwise daily.
     SELECT CASE WHEN (EXTRACT (EPOCH FROM ($3::TIMESTAMP - $2::TIMESTAMP)) / 86400) >= 7 THEN 'day' ELSE 'hour' END
 )
-SELECT campaign_id, COUNT(*) AS "count", DATE_TRUNC((SELECT * FROM intval), created_at) AS "timestamp"
+SELECT campaign_id, COUNT(*) AS "count", DATE_TRUNC((SELECT * FROM intval), created_at, $4) AS "timestamp"
     FROM %s
     WHERE campaign_id=ANY($1) AND created_at >= $2 AND created_at <= $3
     GROUP BY campaign_id, "timestamp" ORDER BY "timestamp" ASC;
@@ -724,7 +1326,7 @@ WITH intval AS (
     -- For intervals < a week, aggregate counts hourly, otherwise daily.
     SELECT CASE WHEN (EXTRACT (EPOCH FROM ($3::TIMESTAMP - $2::TIMESTAMP)) / 86400) >= 7 THEN 'day' ELSE 'hour' END
 )
-SELECT campaign_id, COUNT(*) AS "count", DATE_TRUNC((SELECT * FROM intval), created_at) AS "timestamp"
+SELECT campaign_id, COUNT(*) AS "count", DATE_TRUNC((SELECT * FROM intval), created_at, $4) AS "timestamp"
     FROM bounces
     WHERE campaign_id=ANY($1) AND created_at >= $2 AND created_at <= $3
     GROUP BY campaign_id, "timestamp" ORDER BY "timestamp" ASC;
@@ -738,17 +1340,27 @@ SELECT COUNT(%s) AS "count", url
     WHERE campaign_id=ANY($1) AND link_clicks.created_at >= $2 AND link_clicks.created_at <= $3
     GROUP BY links.url ORDER BY "count" DESC LIMIT 50;
 
--- name: next-campaign-subscribers
--- Returns a batch of subscribers in a given campaign starting from the last checkpoint
--- (last_subscriber_id). Every fetch updates the checkpoint and the sent count, which means
--- every fetch returns a new batch of subscribers until all rows are exhausted.
-WITH camps AS (
-    SELECT last_subscriber_id, max_subscriber_id, type FROM campaigns WHERE id = $1 AND status='running'
-),
+-- name: campaign-audience
+-- raw: true
+-- The targeting of the subscribers a campaign ($1) is sent to. This is injected (%audience%)
+-- on boot into next-campaign-subscribers and get-campaign-audience so that the audience
+-- preview is exactly what's sent, and for that reason, it is not terminated with a semicolon.
+-- The queries that embed this should define a camps CTE before it with the campaign's
+-- targeting fields and the range of subscriber IDs (last_subscriber_id, max_subscriber_id]
+-- to target. The subscribers are returned by the audience CTE.
 campLists AS (
     SELECT lists.id AS list_id, optin FROM lists
     LEFT JOIN campaign_lists ON (campaign_lists.list_id = lists.id)
-    WHERE campaign_lists.campaign_id = $1
+    WHERE campaign_lists.campaign_id = $1 AND lists.deleted_at IS NULL
+    UNION
+    -- Campaigns are sent to the child lists of their lists.
+    SELECT lists.id, lists.optin FROM lists
+    INNER JOIN campLists ON (lists.parent_id = campLists.list_id) WHERE lists.deleted_at IS NULL
+),
+exclLists AS (
+    SELECT id AS list_id FROM lists WHERE id = ANY((SELECT exclude_list_ids FROM camps)::INT[]) AND deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists INNER JOIN exclLists ON (lists.parent_id = exclLists.list_id) WHERE lists.deleted_at IS NULL
 ),
 subIDs AS (
     SELECT DISTINCT ON (subscriber_lists.subscriber_id) subscriber_id, list_id, status FROM subscriber_lists
@@ -758,10 +1370,33 @@ subIDs AS (
         list_id = ANY((SELECT ARRAY_AGG(list_id) FROM campLists)::INT[]) AND
         status != 'unsubscribed' AND
         subscriber_id > (SELECT last_subscriber_id FROM camps) AND
-        subscriber_id <= (SELECT max_subscriber_id FROM camps)
-    ORDER BY subscriber_id LIMIT $2
+        subscriber_id <= (SELECT max_subscriber_id FROM camps) AND
+        -- An A/B test is sent to ab_test_percent of the subscribers (by ID) and the
+        -- winning variant to the rest. See models.ABTestVariant().
+        (CASE (SELECT ab_phase FROM camps)
+            WHEN 'testing' THEN subscriber_id % 100 < (SELECT ab_test_percent FROM camps)
+            WHEN 'winner' THEN subscriber_id % 100 >= (SELECT ab_test_percent FROM camps)
+            ELSE true
+        END) AND
+        -- Resends are only sent to the subscribers who didn't open the original.
+        ((SELECT resend_of FROM camps) IS NULL OR subscriber_id IN (
+            SELECT subscriber_id FROM campaign_sends
+            WHERE campaign_id = (SELECT resend_of FROM camps) AND status = 'sent' AND opened_at IS NULL
+        )) AND
+        -- Subscribers of the excluded lists aren't sent to.
+        NOT EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = subscriber_lists.subscriber_id
+            AND sl.list_id IN (SELECT list_id FROM exclLists) AND sl.status != 'unsubscribed'
+        ) AND
+        -- The checkpoint is moved back before messages discarded on pausing. Subscribers
+        -- after it that have already been sent to (or attempted) aren't sent to again.
+        NOT EXISTS (
+            SELECT 1 FROM campaign_sends cs WHERE cs.campaign_id = $1
+            AND cs.subscriber_id = subscriber_lists.subscriber_id AND cs.status != 'queued'
+        )
+    ORDER BY subscriber_id
 ),
-subs AS (
+audience AS (
     SELECT subscribers.* FROM subIDs
     LEFT JOIN campLists ON (campLists.list_id = subIDs.list_id)
     INNER JOIN subscribers ON (
@@ -780,6 +1415,19 @@ subs AS (
             ELSE subIDs.status != 'unsubscribed'
         END)
     )
+)
+
+-- name: next-campaign-subscribers
+-- Returns a batch of subscribers in a given campaign starting from the last checkpoint
+-- (last_subscriber_id). Every fetch updates the checkpoint and the sent count, which means
+-- every fetch returns a new batch of subscribers until all rows are exhausted.
+-- The targeting (%audience%) is the campaign-audience template.
+WITH RECURSIVE camps AS (
+    SELECT last_subscriber_id, max_subscriber_id, type, ab_phase, ab_test_percent, resend_of, exclude_list_ids FROM campaigns WHERE id = $1 AND status='running'
+),
+%audience%,
+subs AS (
+    SELECT * FROM audience ORDER BY id LIMIT $2
 ),
 u AS (
     UPDATE campaigns
@@ -788,6 +1436,18 @@ u AS (
 )
 SELECT * FROM subs;
 
+-- name: get-campaign-audience
+-- Returns the subscribers a campaign ($1) would be sent to right now (a sample of $2),
+-- with the total. The targeting (%audience%) is the campaign-audience template, the same
+-- as next-campaign-subscribers', over all the subscribers.
+WITH RECURSIVE camps AS (
+    SELECT 0 AS last_subscriber_id, (SELECT COALESCE(MAX(id), 0) FROM subscribers) AS max_subscriber_id,
+        type, ab_phase, ab_test_percent, resend_of, exclude_list_ids FROM campaigns WHERE id = $1
+),
+%audience%
+SELECT COUNT(*) OVER () AS total, id, uuid, email, name, status, created_at, updated_at FROM audience
+    ORDER BY id LIMIT $2;
+
 -- name: delete-campaign-views
 DELETE FROM campaign_views WHERE created_at < $1;
 
@@ -795,11 +1455,15 @@ DELETE FROM campaign_views WHERE created_at < $1;
 DELETE FROM link_clicks WHERE created_at < $1;
 
 -- name: get-one-campaign-subscriber
-SELECT * FROM subscribers
-LEFT JOIN subscriber_lists ON (subscribers.id = subscriber_lists.subscriber_id AND subscriber_lists.status != 'unsubscribed')
-WHERE subscriber_lists.list_id=ANY(
+WITH RECURSIVE campLists AS (
     SELECT list_id FROM campaign_lists where campaign_id=$1 AND list_id IS NOT NULL
+    UNION
+    -- Campaigns are sent to the child lists of their lists.
+    SELECT lists.id FROM lists INNER JOIN campLists ON (lists.parent_id = campLists.list_id)
 )
+SELECT * FROM subscribers
+LEFT JOIN subscriber_lists ON (subscribers.id = subscriber_lists.subscriber_id AND subscriber_lists.status != 'unsubscribed')
+WHERE subscriber_lists.list_id=ANY(SELECT list_id FROM campLists)
 ORDER BY RANDOM() LIMIT 1;
 
 -- name: update-campaign
@@ -821,25 +1485,165 @@ WITH camp AS (
         archive_slug=$16,
         archive_template_id=$17,
         archive_meta=$18,
+        content_url=$20,
+        -- If the content URL changes, the content has to be fetched again.
+        content_checksum=(CASE WHEN content_url != $20 THEN '' ELSE content_checksum END),
+        reply_to=$21,
+        reply_tracking=$22,
+        return_path=$23,
+        header_preset_id=$25,
+        send_at_timezone=$26,
+        depends_on=$27,
+        depends_delay_mins=$28,
+        recurrence=$29,
+        feed_url=$30,
+        -- If the feed URL changes, the entries have to be fetched again.
+        feed_items=(CASE WHEN feed_url != $30 THEN NULL ELSE feed_items END),
+        sto_window_hours=$31,
+        send_at_local=$32,
+        send_rate=$33,
+        send_windows=$34,
+        exclude_list_ids=COALESCE($35::INT[], '{}'),
+        utm_enabled=$36,
+        utm_campaign=$37,
+        version=version + 1,
         updated_at=NOW()
-    WHERE id = $1 RETURNING id
+    -- Optimistic locking. The update is skipped (and nothing's returned) if the
+    -- campaign's been updated since the version ($24) the editor started with.
+    WHERE id = $1 AND version = $24 RETURNING id
 ),
 clists AS (
     -- Reset list relationships
-    DELETE FROM campaign_lists WHERE campaign_id = $1 AND NOT(list_id = ANY($14))
+    -- Trashed lists are retained so that they're intact if the list is restored.
+    DELETE FROM campaign_lists WHERE campaign_id = (SELECT id FROM camp) AND NOT(list_id = ANY($14))
+        AND list_id NOT IN (SELECT id FROM lists WHERE deleted_at IS NOT NULL)
 ),
 med AS (
-    DELETE FROM campaign_media WHERE campaign_id = $1
+    DELETE FROM campaign_media WHERE campaign_id = (SELECT id FROM camp)
     AND ( media_id IS NULL or NOT(media_id = ANY($19))) RETURNING media_id
 ),
 medi AS (
     INSERT INTO campaign_media (campaign_id, media_id, filename)
-        (SELECT $1 AS campaign_id, id, filename FROM media WHERE id=ANY($19::INT[]))
+        (SELECT (SELECT id FROM camp) AS campaign_id, id, filename FROM media
+            WHERE id=ANY($19::INT[]) AND EXISTS (SELECT 1 FROM camp))
         ON CONFLICT (campaign_id, media_id) DO NOTHING
+),
+ins AS (
+    INSERT INTO campaign_lists (campaign_id, list_id, list_name)
+        (SELECT (SELECT id FROM camp) as campaign_id, id, name FROM lists
+            WHERE id=ANY($14::INT[]) AND deleted_at IS NULL AND EXISTS (SELECT 1 FROM camp))
+        ON CONFLICT (campaign_id, list_id) DO UPDATE SET list_name = EXCLUDED.list_name
 )
-INSERT INTO campaign_lists (campaign_id, list_id, list_name)
-    (SELECT $1 as campaign_id, id, name FROM lists WHERE id=ANY($14::INT[]))
-    ON CONFLICT (campaign_id, list_id) DO UPDATE SET list_name = EXCLUDED.list_name;
+SELECT id FROM camp;
+
+-- name: get-due-recurring-campaigns
+-- Scheduled recurring campaigns whose next run is due.
+SELECT id, name, recurrence, send_at, send_at_timezone FROM campaigns
+    WHERE status = 'scheduled' AND recurrence != '' AND NOW() >= send_at AND deleted_at IS NULL;
+
+-- name: create-campaign-run
+-- Creates a run of a due recurring campaign by cloning it with its lists, media,
+-- and goals into a campaign that's scheduled right away, and moves the recurring
+-- campaign's send_at to its next run ($3). The update of send_at ensures that a
+-- run is only created once. The content of runs with a content URL or a feed is
+-- fetched afresh when they start.
+WITH parent AS (
+    UPDATE campaigns SET send_at=$3, updated_at=NOW()
+    WHERE id = $1 AND status = 'scheduled' AND recurrence != '' AND NOW() >= send_at AND deleted_at IS NULL
+    RETURNING *
+),
+camp AS (
+    INSERT INTO campaigns (uuid, type, name, subject, from_email, body, altbody, content_type, send_at, send_at_timezone,
+        headers, header_preset_id, status, tags, messenger, template_id, archive, archive_slug, archive_template_id,
+        archive_meta, content_url, reply_to, reply_tracking, return_path, recurrence_parent_id, feed_url, feed_since, sto_window_hours,
+        send_rate, send_windows, exclude_list_ids, utm_enabled, utm_campaign)
+    SELECT $2, type, CONCAT(name, ' (', TO_CHAR(NOW() AT TIME ZONE COALESCE(NULLIF(send_at_timezone, ''), 'UTC'), 'YYYY-MM-DD HH24:MI'), ')'),
+        subject, from_email, body, altbody, content_type, NOW(), send_at_timezone,
+        headers, header_preset_id, 'scheduled', tags, messenger, template_id, archive,
+        (CASE WHEN archive_slug IS NULL THEN NULL ELSE CONCAT(archive_slug, '-', TO_CHAR(NOW(), 'YYYYMMDDHH24MI')) END),
+        archive_template_id, archive_meta, content_url, reply_to, reply_tracking, return_path, id, feed_url,
+        -- Runs with a feed only get the entries published since the previous run started.
+        (CASE WHEN feed_url = '' THEN NULL ELSE
+            (SELECT MAX(COALESCE(r.started_at, r.created_at)) FROM campaigns r WHERE r.recurrence_parent_id = parent.id)
+        END),
+        sto_window_hours, send_rate, send_windows, exclude_list_ids, utm_enabled, utm_campaign
+    FROM parent
+    RETURNING id
+),
+lists AS (
+    INSERT INTO campaign_lists (campaign_id, list_id, list_name)
+        SELECT (SELECT id FROM camp), list_id, list_name FROM campaign_lists
+        WHERE campaign_id = $1 AND list_id IS NOT NULL AND EXISTS (SELECT 1 FROM camp)
+),
+med AS (
+    INSERT INTO campaign_media (campaign_id, media_id, filename)
+        SELECT (SELECT id FROM camp), media_id, filename FROM campaign_media
+        WHERE campaign_id = $1 AND media_id IS NOT NULL AND EXISTS (SELECT 1 FROM camp)
+),
+goals AS (
+    INSERT INTO campaign_goals (campaign_id, position, name, type, url, key)
+        SELECT (SELECT id FROM camp), position, name, type, url, key FROM campaign_goals
+        WHERE campaign_id = $1 AND EXISTS (SELECT 1 FROM camp)
+)
+SELECT id FROM camp;
+
+-- name: create-campaign-resend
+-- Creates a follow-up of a finished campaign ($1) that's only sent to the subscribers
+-- the campaign was sent to who haven't opened it. The follow-up is a clone of the
+-- campaign (with the winning variant of its A/B test, if any) with its lists, media,
+-- and goals, optionally with another subject ($4), and is scheduled $5 days after
+-- the campaign finished, or right away if that's past.
+WITH parent AS (
+    SELECT c.*, v.subject AS winner_subject, v.body AS winner_body FROM campaigns c
+    LEFT JOIN campaign_variants v ON (v.campaign_id = c.id AND v.is_winner)
+    WHERE c.id = $1 AND c.status = 'finished' AND c.type = 'regular' AND c.deleted_at IS NULL
+),
+camp AS (
+    INSERT INTO campaigns (uuid, type, name, subject, from_email, body, altbody, content_type, send_at, send_at_timezone,
+        headers, header_preset_id, status, tags, messenger, template_id, archive_template_id, archive_meta,
+        reply_to, reply_tracking, return_path, feed_items, send_rate, send_windows, exclude_list_ids, utm_enabled, utm_campaign, resend_of)
+    SELECT $2, type, $3,
+        COALESCE(NULLIF($4, ''), winner_subject, subject),
+        from_email, COALESCE(NULLIF(winner_body, ''), body), altbody, content_type,
+        GREATEST(NOW(), COALESCE(finished_at, updated_at) + MAKE_INTERVAL(days => $5)), send_at_timezone,
+        headers, header_preset_id, 'scheduled', tags, messenger, template_id, archive_template_id, archive_meta,
+        reply_to, reply_tracking, return_path, feed_items, send_rate, send_windows, exclude_list_ids, utm_enabled, utm_campaign, id
+    FROM parent
+    RETURNING id
+),
+lists AS (
+    INSERT INTO campaign_lists (campaign_id, list_id, list_name)
+        SELECT (SELECT id FROM camp), list_id, list_name FROM campaign_lists
+        WHERE campaign_id = $1 AND list_id IS NOT NULL AND EXISTS (SELECT 1 FROM camp)
+),
+med AS (
+    INSERT INTO campaign_media (campaign_id, media_id, filename)
+        SELECT (SELECT id FROM camp), media_id, filename FROM campaign_media
+        WHERE campaign_id = $1 AND media_id IS NOT NULL AND EXISTS (SELECT 1 FROM camp)
+),
+goals AS (
+    INSERT INTO campaign_goals (campaign_id, position, name, type, url, key)
+        SELECT (SELECT id FROM camp), position, name, type, url, key FROM campaign_goals
+        WHERE campaign_id = $1 AND EXISTS (SELECT 1 FROM camp)
+)
+SELECT id FROM camp;
+
+-- name: get-campaign-runs
+-- Runs of a recurring campaign, latest first.
+SELECT  c.id, c.uuid, c.name, c.subject, c.status, c.type, c.messenger, c.to_send, c.sent,
+        c.send_at, c.send_at_timezone, c.started_at, c.finished_at, c.recurrence_parent_id,
+        c.tags, c.created_at, c.updated_at, COUNT(*) OVER () AS total
+FROM campaigns c
+WHERE c.recurrence_parent_id = $1 AND c.deleted_at IS NULL
+ORDER BY c.created_at DESC OFFSET $2 LIMIT (CASE WHEN $3 < 1 THEN NULL ELSE $3 END);
+
+-- name: update-campaign-content
+-- Freezes the body fetched from a campaign's content URL along with its checksum.
+UPDATE campaigns SET body=$2, content_checksum=$3, updated_at=NOW() WHERE id=$1;
+
+-- name: update-campaign-feed
+-- Freezes the entries fetched from a campaign's feed.
+UPDATE campaigns SET feed_items=$2, updated_at=NOW() WHERE id=$1;
 
 -- name: update-campaign-counts
 UPDATE campaigns SET
@@ -850,7 +1654,14 @@ UPDATE campaigns SET
 WHERE id=$1;
 
 -- name: update-campaign-status
-UPDATE campaigns SET status=$2, updated_at=NOW() WHERE id = $1;
+-- The send-time optimization queue of a campaign that's ended is cleared.
+WITH q AS (
+    DELETE FROM campaign_send_queue WHERE campaign_id = $1 AND $2 IN ('finished', 'cancelled')
+)
+UPDATE campaigns SET status=$2,
+    finished_at=(CASE WHEN $2 = 'finished' THEN NOW() ELSE finished_at END),
+    updated_at=NOW()
+WHERE id = $1;
 
 -- name: update-campaign-archive
 UPDATE campaigns SET
@@ -862,13 +1673,21 @@ UPDATE campaigns SET
     WHERE id=$1;
 
 -- name: delete-campaign
-DELETE FROM campaigns WHERE id=$1;
+-- Moves a campaign to the trash. Running campaigns are paused and scheduled ones
+-- are reverted to drafts so that they aren't sent if they're restored.
+UPDATE campaigns SET deleted_at=NOW(), updated_at=NOW(),
+    status=(CASE WHEN status='running' THEN 'paused' WHEN status='scheduled' THEN 'draft' ELSE status END)
+    WHERE id=$1 AND deleted_at IS NULL;
 
 -- name: register-campaign-view
 WITH view AS (
     SELECT campaigns.id as campaign_id, subscribers.id AS subscriber_id FROM campaigns
     LEFT JOIN subscribers ON (CASE WHEN $2::TEXT != '' THEN subscribers.uuid = $2::UUID ELSE FALSE END)
     WHERE campaigns.uuid = $1
+),
+sent AS (
+    UPDATE campaign_sends SET opened_at=COALESCE(opened_at, NOW())
+    WHERE campaign_id = (SELECT campaign_id FROM view) AND subscriber_id = (SELECT subscriber_id FROM view)
 )
 INSERT INTO campaign_views (campaign_id, subscriber_id)
     VALUES((SELECT campaign_id FROM view), (SELECT subscriber_id FROM view));
@@ -899,40 +1718,37 @@ DELETE FROM users WHERE $1 != 1 AND id=$1;
 -- name: get-templates
 -- Only if the second param ($2) is true, body is returned.
 SELECT id, name, type, subject, (CASE WHEN $2 = false THEN body ELSE '' END) as body,
-    is_default, created_at, updated_at
-    FROM templates WHERE ($1 = 0 OR id = $1) AND ($3 = '' OR type = $3::template_type)
+    is_default, header_preset_id, version, created_at, updated_at
+    FROM templates WHERE ($1 = 0 OR id = $1) AND ($3 = '' OR type = $3::template_type) AND deleted_at IS NULL
     ORDER BY created_at;
 
 -- name: create-template
-INSERT INTO templates (name, type, subject, body) VALUES($1, $2, $3, $4) RETURNING id;
+INSERT INTO templates (name, type, subject, body, header_preset_id) VALUES($1, $2, $3, $4, $5) RETURNING id;
 
 -- name: update-template
+-- If $5 (version) is > 0, the template is only updated if it hasn't been updated since.
 UPDATE templates SET
     name=(CASE WHEN $2 != '' THEN $2 ELSE name END),
     subject=(CASE WHEN $3 != '' THEN $3 ELSE name END),
     body=(CASE WHEN $4 != '' THEN $4 ELSE body END),
+    header_preset_id=$6,
+    version=version + 1,
     updated_at=NOW()
-WHERE id = $1;
+WHERE id = $1 AND ($5 = 0 OR version = $5) AND deleted_at IS NULL;
 
 -- name: set-default-template
 WITH u AS (
-    UPDATE templates SET is_default=true WHERE id=$1 AND type='campaign' RETURNING id
+    UPDATE templates SET is_default=true WHERE id=$1 AND type='campaign' AND deleted_at IS NULL RETURNING id
 )
 UPDATE templates SET is_default=false WHERE id != $1;
 
 -- name: delete-template
--- Delete a template as long as there's more than one. On deletion, set all campaigns
--- with that template to the default template instead.
-WITH tpl AS (
-    DELETE FROM templates WHERE id = $1 AND (SELECT COUNT(id) FROM templates) > 1 AND is_default = false RETURNING id
-),
-def AS (
-    SELECT id FROM templates WHERE is_default = true AND type='campaign' LIMIT 1
-),
-up AS (
-    UPDATE campaigns SET template_id = (SELECT id FROM def) WHERE (SELECT id FROM tpl) > 0 AND template_id = $1
-)
-SELECT id FROM tpl;
+-- Moves a template to the trash as long as there's more than one. Campaigns using
+-- the template are switched to the default template when it's purged by purge-trash.
+UPDATE templates SET deleted_at=NOW(), updated_at=NOW()
+    WHERE id = $1 AND is_default = false AND deleted_at IS NULL
+    AND (SELECT COUNT(id) FROM templates WHERE deleted_at IS NULL) > 1
+    RETURNING id;
 
 
 -- media
@@ -949,6 +1765,18 @@ SELECT * FROM media WHERE CASE WHEN $1 > 0 THEN id = $1 ELSE uuid = $2 END;
 -- name: delete-media
 DELETE FROM media WHERE id=$1 RETURNING filename;
 
+-- name: insert-quarantined-media
+INSERT INTO media_quarantine (filename, content_type, signature, size, body) VALUES($1, $2, $3, $4, $5) RETURNING id;
+
+-- name: get-quarantined-media
+-- Returns quarantined media without their bodies, or a single item with its body.
+SELECT id, filename, content_type, signature, size, created_at,
+    (CASE WHEN $1 > 0 THEN body ELSE NULL END) AS body
+    FROM media_quarantine WHERE ($1 = 0 OR id = $1) ORDER BY id DESC;
+
+-- name: delete-quarantined-media
+DELETE FROM media_quarantine WHERE id=$1;
+
 -- links
 -- name: create-link
 INSERT INTO links (uuid, url) VALUES($1, $2) ON CONFLICT (url) DO UPDATE SET url=EXCLUDED.url RETURNING uuid;
@@ -956,14 +1784,183 @@ INSERT INTO links (uuid, url) VALUES($1, $2) ON CONFLICT (url) DO UPDATE SET url
 -- name: register-link-click
 WITH link AS(
     SELECT id, url FROM links WHERE uuid = $1
+),
+camp AS (
+    SELECT id, name, utm_enabled, utm_campaign FROM campaigns WHERE uuid = $2
+),
+sent AS (
+    UPDATE campaign_sends SET clicked_at=COALESCE(clicked_at, NOW())
+    WHERE campaign_id = (SELECT id FROM campaigns WHERE uuid = $2)
+        AND subscriber_id = (SELECT id FROM subscribers WHERE
+            (CASE WHEN $3::TEXT != '' THEN subscribers.uuid = $3::UUID ELSE FALSE END)
+        )
 )
 INSERT INTO link_clicks (campaign_id, subscriber_id, link_id) VALUES(
-    (SELECT id FROM campaigns WHERE uuid = $2),
+    (SELECT id FROM camp),
     (SELECT id FROM subscribers WHERE
         (CASE WHEN $3::TEXT != '' THEN subscribers.uuid = $3::UUID ELSE FALSE END)
     ),
     (SELECT id FROM link)
-) RETURNING (SELECT url FROM link);
+) RETURNING (SELECT url FROM link) AS url, campaign_id, subscriber_id,
+    -- The landing pages of the campaign's page visit and conversion goals to which
+    -- the goal token is passed on.
+    ARRAY(SELECT g.url FROM campaign_goals g WHERE g.campaign_id = link_clicks.campaign_id
+        AND g.type != 'link' AND g.url != '') AS goal_urls,
+    -- The campaign's UTM tagging that's applied to the URL.
+    COALESCE((SELECT name FROM camp), '') AS campaign_name,
+    (SELECT utm_enabled FROM camp) AS utm_enabled,
+    COALESCE((SELECT utm_campaign FROM camp), '') AS utm_campaign;
+
+-- name: get-campaign-goals
+SELECT * FROM campaign_goals WHERE campaign_id = $1 ORDER BY position;
+
+-- name: set-campaign-goals
+-- Replaces the goals of a campaign ($1) with the given ordered set. Existing goals (ID in $2)
+-- are updated in place to retain their events, new goals (ID = 0) are inserted, and goals
+-- that aren't in the set are deleted.
+WITH goals AS (
+    SELECT * FROM UNNEST($2::INT[], $3::TEXT[], $4::goal_type[], $5::TEXT[], $6::TEXT[])
+        WITH ORDINALITY AS g(id, name, type, url, key, position)
+),
+del AS (
+    DELETE FROM campaign_goals WHERE campaign_id = $1 AND id != ALL(SELECT id FROM goals)
+),
+upd AS (
+    UPDATE campaign_goals c SET position=g.position, name=g.name, type=g.type, url=g.url, key=g.key
+        FROM goals g WHERE c.id = g.id AND c.campaign_id = $1
+)
+INSERT INTO campaign_goals (campaign_id, position, name, type, url, key)
+    SELECT $1, position, name, type, url, key FROM goals WHERE id = 0;
+
+-- name: register-campaign-goal-event
+-- Records a page visit or conversion event of a campaign ($1) goal (key $3) for
+-- a subscriber ($2), which is 0 when the subscriber isn't known.
+WITH goal AS (
+    SELECT g.id FROM campaign_goals g
+    WHERE g.campaign_id = $1 AND g.key = $3 AND g.type != 'link'
+    LIMIT 1
+)
+INSERT INTO campaign_goal_events (goal_id, subscriber_id)
+    SELECT id, (SELECT id FROM subscribers WHERE id = $2) FROM goal;
+
+-- name: get-campaign-goal-funnel
+-- Returns the funnel of a campaign's goals. For each goal, `events` is the total number of
+-- hits and `subscribers` is the number of subscribers who reached the goal and all the goals
+-- before it. Link goals are counted from the campaign's link clicks.
+WITH goals AS (
+    SELECT * FROM campaign_goals WHERE campaign_id = $1
+),
+hits AS (
+    SELECT g.id AS goal_id, lc.subscriber_id FROM goals g
+        INNER JOIN links l ON (g.type = 'link' AND l.url = g.url)
+        INNER JOIN link_clicks lc ON (lc.link_id = l.id AND lc.campaign_id = $1)
+    UNION ALL
+    SELECT g.id AS goal_id, e.subscriber_id FROM goals g
+        INNER JOIN campaign_goal_events e ON (g.type != 'link' AND e.goal_id = g.id)
+),
+reached AS (
+    SELECT DISTINCT goal_id, subscriber_id FROM hits WHERE subscriber_id IS NOT NULL
+)
+SELECT g.id, g.position, g.name, g.type, g.url, g.key,
+    (SELECT COUNT(*) FROM hits h WHERE h.goal_id = g.id) AS events,
+    (SELECT COUNT(*) FROM reached r WHERE r.goal_id = g.id AND NOT EXISTS (
+        SELECT 1 FROM goals p WHERE p.position < g.position AND NOT EXISTS (
+            SELECT 1 FROM reached rp WHERE rp.goal_id = p.id AND rp.subscriber_id = r.subscriber_id
+        )
+    )) AS subscribers
+FROM goals g ORDER BY g.position;
+
+-- name: get-campaign-actions
+SELECT a.*, COALESCE(l.name, '') AS list_name FROM campaign_actions a
+    LEFT JOIN lists l ON (l.id = a.list_id)
+    WHERE a.campaign_id = $1 ORDER BY a.id;
+
+-- name: set-campaign-actions
+-- Replaces the post-campaign actions of a campaign ($1) with the given set. Existing
+-- actions (ID in $2) are updated in place, new actions (ID = 0) are inserted, and
+-- actions that aren't in the set are deleted. Actions that have run aren't re-run.
+WITH acts AS (
+    SELECT * FROM UNNEST($2::INT[], $3::campaign_action_condition[], $4::TEXT[], $5::campaign_action_type[], $6::TEXT[], $7::INT[], $8::INT[])
+        AS a(id, condition, url, action, tag, list_id, delay_hours)
+),
+del AS (
+    DELETE FROM campaign_actions WHERE campaign_id = $1 AND id != ALL(SELECT id FROM acts)
+),
+upd AS (
+    UPDATE campaign_actions c SET condition=a.condition, url=a.url, action=a.action, tag=a.tag,
+        list_id=NULLIF(a.list_id, 0), delay_hours=a.delay_hours
+        FROM acts a WHERE c.id = a.id AND c.campaign_id = $1
+)
+INSERT INTO campaign_actions (campaign_id, condition, url, action, tag, list_id, delay_hours)
+    SELECT $1, condition, url, action, tag, NULLIF(list_id, 0), delay_hours FROM acts WHERE id = 0;
+
+-- name: get-due-campaign-actions
+-- Returns the actions of finished campaigns that haven't run and whose delay is up.
+SELECT a.*, '' AS list_name FROM campaign_actions a
+    INNER JOIN campaigns c ON (c.id = a.campaign_id)
+    WHERE a.ran_at IS NULL AND c.status = 'finished' AND c.deleted_at IS NULL
+    AND NOW() >= COALESCE(c.finished_at, c.updated_at) + MAKE_INTERVAL(hours => a.delay_hours)
+    ORDER BY a.id;
+
+-- name: run-campaign-action
+-- Runs a post-campaign action ($1) on the subscribers who match its condition and
+-- records the number of subscribers. "not_" conditions match the subscribers the campaign
+-- was sent to. Tags are added to the subscribers' "tags" attribute. Subscribers added
+-- (moved) to a list are subscribed as confirmed unless they already have a subscription
+-- to it, and moved subscribers are removed
+-- from the campaign's lists. Blocklisted subscribers are skipped.
+WITH act AS (
+    SELECT * FROM campaign_actions WHERE id = $1 AND ran_at IS NULL
+),
+clicks AS (
+    SELECT lc.subscriber_id FROM link_clicks lc
+        INNER JOIN links l ON (l.id = lc.link_id)
+        INNER JOIN act ON (lc.campaign_id = act.campaign_id AND (act.url = '' OR l.url = act.url))
+    WHERE lc.subscriber_id IS NOT NULL
+),
+views AS (
+    SELECT v.subscriber_id FROM campaign_views v
+        INNER JOIN act ON (v.campaign_id = act.campaign_id)
+    WHERE v.subscriber_id IS NOT NULL
+),
+matched AS (
+    SELECT subscriber_id FROM views WHERE (SELECT condition FROM act) = 'opened'
+    UNION
+    SELECT subscriber_id FROM clicks WHERE (SELECT condition FROM act) = 'clicked'
+    UNION
+    SELECT cs.subscriber_id FROM campaign_sends cs
+        INNER JOIN act ON (cs.campaign_id = act.campaign_id)
+    WHERE cs.status = 'sent' AND (
+        (act.condition = 'not_opened' AND cs.subscriber_id NOT IN (SELECT subscriber_id FROM views)) OR
+        (act.condition = 'not_clicked' AND cs.subscriber_id NOT IN (SELECT subscriber_id FROM clicks))
+    )
+),
+subs AS (
+    SELECT id, attribs FROM subscribers WHERE id IN (SELECT subscriber_id FROM matched) AND status != 'blocklisted'
+),
+tagged AS (
+    UPDATE subscribers s SET attribs = JSONB_SET(s.attribs, '{tags}',
+        (CASE WHEN JSONB_TYPEOF(s.attribs->'tags') = 'array' THEN s.attribs->'tags' ELSE '[]' END) || TO_JSONB(act.tag)),
+        updated_at=NOW()
+    FROM act WHERE act.action = 'add_tag' AND s.id IN (SELECT id FROM subs)
+        AND NOT (CASE WHEN JSONB_TYPEOF(s.attribs->'tags') = 'array' THEN s.attribs->'tags' ELSE '[]' END) @> JSONB_BUILD_ARRAY(act.tag)
+),
+added AS (
+    INSERT INTO subscriber_lists (subscriber_id, list_id, status, source)
+        SELECT subs.id, act.list_id, 'confirmed', CONCAT('campaign:', act.campaign_id) FROM subs, act
+        WHERE act.action IN ('add_list', 'move_list') AND act.list_id IS NOT NULL
+    -- Existing subscriptions, including unsubscriptions, are retained.
+    ON CONFLICT (subscriber_id, list_id) DO NOTHING
+),
+moved AS (
+    DELETE FROM subscriber_lists sl USING act
+        WHERE act.action = 'move_list' AND sl.subscriber_id IN (SELECT id FROM subs)
+        AND sl.list_id IN (SELECT list_id FROM campaign_lists WHERE campaign_id = act.campaign_id)
+        AND sl.list_id != act.list_id
+)
+UPDATE campaign_actions SET ran_at = NOW(), affected = (SELECT COUNT(*) FROM subs)
+    WHERE id = (SELECT id FROM act)
+    RETURNING affected;
 
 -- name: get-dashboard-charts
 SELECT data FROM mat_dashboard_charts;
@@ -997,9 +1994,13 @@ block1 AS (
     WHERE $9 = 'blocklist' AND (SELECT num FROM num) >= $8 AND id = (SELECT id FROM sub) AND (SELECT status FROM sub) != 'blocklisted'
 ),
 block2 AS (
-    UPDATE subscriber_lists SET status='unsubscribed'
+    UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL
     WHERE $9 = 'unsubscribe' AND (SELECT num FROM num) >= $8 AND subscriber_id = (SELECT id FROM sub) AND (SELECT status FROM sub) != 'blocklisted'
 ),
+sent AS (
+    UPDATE campaign_sends SET status='bounced', bounced_at=$7
+    WHERE campaign_id = (SELECT id FROM camp) AND subscriber_id = (SELECT id FROM sub)
+),
 bounce AS (
     -- Record the bounce if the subscriber is not already blocklisted;
     INSERT INTO bounces (subscriber_id, campaign_id, type, source, meta, created_at)
@@ -1010,6 +2011,14 @@ bounce AS (
 DELETE FROM subscribers
     WHERE $9 = 'delete' AND (SELECT num FROM num) >= $8 AND id = (SELECT id FROM sub);
 
+-- name: record-campaign-reply
+-- Records a reply to a campaign given the campaign UUID and the replying subscriber's e-mail.
+-- Replies are deduplicated by their Message-Id.
+INSERT INTO campaign_replies (campaign_id, subscriber_id, message_id, created_at)
+    SELECT c.id, (SELECT id FROM subscribers WHERE LOWER(email) = LOWER($2) LIMIT 1), $3, $4
+    FROM campaigns c WHERE c.uuid = $1::UUID
+    ON CONFLICT DO NOTHING;
+
 -- name: query-bounces
 SELECT COUNT(*) OVER () AS total,
     bounces.id,
@@ -1045,6 +2054,1154 @@ WITH sub AS (
 DELETE FROM bounces WHERE subscriber_id = (SELECT id FROM sub);
 
 
+-- sunset
+-- name: enroll-sunset-subscribers
+-- Enrols enabled subscribers with active subscriptions who haven't viewed or clicked
+-- anything in the last $1 days into the sunset (win-back) flow. If $2 (re-engagement list)
+-- is set, enrolled subscribers are added to it so that a re-engagement sequence can target them.
+WITH inactive AS (
+    SELECT s.id FROM subscribers s
+    WHERE s.status = 'enabled' AND s.created_at < NOW() - MAKE_INTERVAL(days => $1)
+    AND EXISTS (
+        SELECT 1 FROM subscriber_lists sl
+        WHERE sl.subscriber_id = s.id AND sl.list_id != $2 AND sl.status != 'unsubscribed'
+    )
+    AND NOT EXISTS (SELECT 1 FROM subscriber_sunset ss WHERE ss.subscriber_id = s.id AND ss.status = 'enrolled')
+    AND NOT EXISTS (
+        SELECT 1 FROM campaign_views v WHERE v.subscriber_id = s.id AND v.created_at > NOW() - MAKE_INTERVAL(days => $1)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM link_clicks c WHERE c.subscriber_id = s.id AND c.created_at > NOW() - MAKE_INTERVAL(days => $1)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM campaign_replies r WHERE r.subscriber_id = s.id AND r.created_at > NOW() - MAKE_INTERVAL(days => $1)
+    )
+),
+enrol AS (
+    INSERT INTO subscriber_sunset (subscriber_id) (SELECT id FROM inactive)
+    RETURNING subscriber_id
+),
+subs AS (
+    INSERT INTO subscriber_lists (subscriber_id, list_id, status)
+    (SELECT subscriber_id, $2, 'confirmed' FROM enrol WHERE $2 > 0)
+    ON CONFLICT (subscriber_id, list_id) DO UPDATE SET status='confirmed', updated_at=NOW()
+)
+SELECT COUNT(*) FROM enrol;
+
+-- name: recover-sunset-subscribers
+-- Marks enrolled subscribers who have viewed or clicked a campaign since enrolment
+-- as recovered and removes them from the re-engagement list ($1).
+WITH recovered AS (
+    UPDATE subscriber_sunset ss SET status='recovered', updated_at=NOW()
+    WHERE ss.status = 'enrolled' AND (
+        EXISTS (SELECT 1 FROM campaign_views v WHERE v.subscriber_id = ss.subscriber_id AND v.created_at > ss.created_at)
+        OR EXISTS (SELECT 1 FROM link_clicks c WHERE c.subscriber_id = ss.subscriber_id AND c.created_at > ss.created_at)
+        OR EXISTS (SELECT 1 FROM campaign_replies r WHERE r.subscriber_id = ss.subscriber_id AND r.created_at > ss.created_at)
+    )
+    RETURNING ss.subscriber_id
+),
+del AS (
+    DELETE FROM subscriber_lists WHERE $1 > 0 AND list_id = $1 AND subscriber_id = ANY(SELECT subscriber_id FROM recovered)
+)
+SELECT COUNT(*) FROM recovered;
+
+-- name: remove-sunset-subscribers
+-- Removes enrolled subscribers who still haven't engaged after the grace period of $1 days.
+-- All their subscriptions are unsubscribed, and if $2 = 'blocklist', they are blocklisted as well.
+WITH removed AS (
+    UPDATE subscriber_sunset SET status='removed', updated_at=NOW()
+    WHERE status = 'enrolled' AND created_at < NOW() - MAKE_INTERVAL(days => $1)
+    RETURNING subscriber_id
+),
+block AS (
+    UPDATE subscribers SET status='blocklisted', updated_at=NOW()
+    WHERE $2 = 'blocklist' AND id = ANY(SELECT subscriber_id FROM removed)
+),
+unsub AS (
+    UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE subscriber_id = ANY(SELECT subscriber_id FROM removed) AND status != 'unsubscribed'
+)
+SELECT COUNT(*) FROM removed;
+
+-- name: get-sunset-stats
+-- Returns the number of enrolled, recovered, and removed subscribers in the sunset flow,
+-- optionally limited to those whose state changed on or after $1.
+SELECT COUNT(*) FILTER (WHERE status = 'enrolled') AS enrolled,
+    COUNT(*) FILTER (WHERE status = 'recovered') AS recovered,
+    COUNT(*) FILTER (WHERE status = 'removed') AS removed
+    FROM subscriber_sunset WHERE ($1::TIMESTAMP WITH TIME ZONE IS NULL OR updated_at >= $1);
+
+
+-- list hygiene
+-- name: get-subscriber-domains
+-- Returns the distinct e-mail domains of all subscribers who aren't blocklisted.
+SELECT DISTINCT SPLIT_PART(LOWER(email), '@', 2) FROM subscribers
+    WHERE status != 'blocklisted' AND POSITION('@' IN email) > 0;
+
+-- name: refresh-list-hygiene
+-- Replaces the list hygiene report with the subscribers of every list that fall into
+-- one of the categories: invalid e-mail syntax, role accounts ($1 = local parts),
+-- dead domains ($2), and chronic soft-bouncers (>= $3 soft bounces in the last $4 days).
+WITH del AS (
+    DELETE FROM list_hygiene
+),
+subs AS (
+    SELECT sl.list_id, s.id AS subscriber_id, LOWER(s.email) AS email,
+        SPLIT_PART(LOWER(s.email), '@', 1) AS local, SPLIT_PART(LOWER(s.email), '@', 2) AS domain
+    FROM subscriber_lists sl
+    INNER JOIN subscribers s ON (s.id = sl.subscriber_id)
+    WHERE sl.status != 'unsubscribed' AND s.status != 'blocklisted'
+),
+soft AS (
+    SELECT subscriber_id FROM bounces
+    WHERE type = 'soft' AND created_at > NOW() - MAKE_INTERVAL(days => $4)
+    GROUP BY subscriber_id HAVING COUNT(*) >= $3
+),
+ins AS (
+    INSERT INTO list_hygiene (list_id, subscriber_id, category)
+        SELECT list_id, subscriber_id, 'invalid_syntax'::hygiene_category FROM subs
+            WHERE email !~ '^[^@\s]+@[^@\s]+\.[^@\s]+$'
+        UNION ALL
+        SELECT list_id, subscriber_id, 'role_account' FROM subs WHERE local = ANY($1::TEXT[])
+        UNION ALL
+        SELECT list_id, subscriber_id, 'dead_domain' FROM subs WHERE domain = ANY($2::TEXT[])
+        UNION ALL
+        SELECT list_id, subscriber_id, 'soft_bouncer' FROM subs WHERE subscriber_id IN (SELECT subscriber_id FROM soft)
+    RETURNING 1
+)
+SELECT COUNT(*) FROM ins;
+
+-- name: get-list-hygiene
+-- Returns the number of subscribers in each hygiene category of a list, or all lists if $1 = 0.
+SELECT list_id, category, COUNT(*) AS count, MAX(created_at) AS updated_at FROM list_hygiene
+    WHERE ($1 = 0 OR list_id = $1)
+    GROUP BY list_id, category ORDER BY list_id, category;
+
+-- name: apply-list-hygiene-action
+-- Unsubscribes the subscribers in a hygiene category ($2) of a list ($1) from the list,
+-- or if $3 = 'blocklist', blocklists them and unsubscribes them from all lists.
+WITH subs AS (
+    SELECT subscriber_id FROM list_hygiene WHERE list_id = $1 AND category = $2::hygiene_category
+),
+unsub AS (
+    UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE subscriber_id = ANY(SELECT subscriber_id FROM subs) AND (list_id = $1 OR $3 = 'blocklist')
+),
+block AS (
+    UPDATE subscribers SET status='blocklisted', updated_at=NOW()
+    WHERE $3 = 'blocklist' AND id = ANY(SELECT subscriber_id FROM subs)
+),
+del AS (
+    DELETE FROM list_hygiene
+    WHERE subscriber_id = ANY(SELECT subscriber_id FROM subs) AND (list_id = $1 OR $3 = 'blocklist')
+)
+SELECT COUNT(*) FROM subs;
+
 -- name: get-db-info
 SELECT JSON_BUILD_OBJECT('version', (SELECT VERSION()),
                         'size_mb', (SELECT ROUND(pg_database_size((SELECT CURRENT_DATABASE()))/(1024^2)))) AS info;
+
+-- name: get-campaign-previews
+SELECT id, campaign_id, client, content_type, created_at FROM campaign_previews
+    WHERE campaign_id = $1 ORDER BY client;
+
+-- name: get-campaign-preview-image
+SELECT id, campaign_id, client, content_type, image, created_at FROM campaign_previews
+    WHERE campaign_id = $1 AND client = $2;
+
+-- name: set-campaign-previews
+-- Replaces the preview images of a campaign. Previews of clients that are not
+-- in the given set ($2) are deleted.
+WITH del AS (
+    DELETE FROM campaign_previews WHERE campaign_id = $1 AND client != ALL($2::TEXT[])
+)
+INSERT INTO campaign_previews (campaign_id, client, content_type, image)
+    SELECT $1, p.client, p.content_type, p.image FROM UNNEST($2::TEXT[], $3::TEXT[], $4::BYTEA[]) AS p(client, content_type, image)
+    ON CONFLICT (campaign_id, client) DO UPDATE
+    SET content_type=EXCLUDED.content_type, image=EXCLUDED.image, created_at=NOW();
+
+-- name: get-dns-checks
+SELECT * FROM dns_checks ORDER BY id;
+
+-- name: set-dns-checks
+-- Replaces the stored DNS check results with the results of the latest run.
+WITH del AS (
+    DELETE FROM dns_checks
+)
+INSERT INTO dns_checks (domain, status, results)
+    SELECT * FROM UNNEST($1::TEXT[], $2::TEXT[], $3::JSONB[]);
+
+-- name: insert-tls-report
+-- Inserts a policy domain's results from a TLS report. Reports that were
+-- already imported are ignored.
+INSERT INTO tls_reports (report_id, organization, policy_domain, policy_type, start_date, end_date,
+    success_count, failure_count, failures)
+    VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    ON CONFLICT (report_id, policy_domain) DO NOTHING;
+
+-- name: get-tls-reports
+-- Returns the TLS reports whose period ended within the last $1 days.
+SELECT * FROM tls_reports WHERE end_date > NOW() - MAKE_INTERVAL(days => $1)
+    ORDER BY end_date DESC, policy_domain;
+
+-- name: create-subscriber-export
+-- Records a subscriber's data export and deletes the expired ones.
+WITH del AS (
+    DELETE FROM subscriber_exports WHERE expires_at < NOW()
+)
+INSERT INTO subscriber_exports (uuid, subscriber_id, data, expires_at)
+    VALUES($1, (SELECT id FROM subscribers WHERE uuid = $2), $3, $4);
+
+-- name: get-subscriber-export
+SELECT data FROM subscriber_exports WHERE uuid = $1 AND expires_at > NOW();
+
+-- name: create-email-change
+-- Records a pending e-mail change of a subscriber, replacing any earlier one,
+-- and deletes the expired ones.
+WITH del AS (
+    DELETE FROM subscriber_email_changes WHERE expires_at < NOW()
+)
+INSERT INTO subscriber_email_changes (uuid, subscriber_id, email, expires_at) VALUES($1, $2, $3, $4)
+    ON CONFLICT (subscriber_id) DO UPDATE SET uuid=$1, email=$3, expires_at=$4, created_at=NOW();
+
+-- name: confirm-email-change
+-- Applies an unexpired pending e-mail change and returns the subscriber's ID.
+WITH ch AS (
+    DELETE FROM subscriber_email_changes WHERE uuid = $1 AND expires_at > NOW() RETURNING subscriber_id, email
+)
+UPDATE subscribers SET email=(SELECT email FROM ch), updated_at=NOW()
+    WHERE id = (SELECT subscriber_id FROM ch) RETURNING id;
+
+-- name: get-reputation-metrics
+-- Returns the daily reputation metrics between two dates, optionally filtered by provider.
+SELECT * FROM reputation_metrics
+    WHERE ($1 = '' OR provider = $1) AND date >= $2::DATE AND date <= $3::DATE
+    ORDER BY date, provider, source;
+
+-- name: upsert-reputation-metrics
+INSERT INTO reputation_metrics (provider, source, date, reputation, spam_rate, delivery_errors, meta)
+    SELECT * FROM UNNEST($1::TEXT[], $2::TEXT[], $3::DATE[], $4::TEXT[], $5::FLOAT[], $6::JSONB[], $7::JSONB[])
+    ON CONFLICT (provider, source, date) DO UPDATE
+    SET reputation = EXCLUDED.reputation, spam_rate = EXCLUDED.spam_rate,
+        delivery_errors = EXCLUDED.delivery_errors, meta = EXCLUDED.meta, updated_at = NOW();
+
+-- trash
+-- name: get-trash
+-- Returns the trashed campaigns, lists, and templates, latest first.
+SELECT * FROM (
+    SELECT 'campaign' AS type, id, name, deleted_at FROM campaigns WHERE deleted_at IS NOT NULL
+    UNION ALL
+    SELECT 'list' AS type, id, name, deleted_at FROM lists WHERE deleted_at IS NOT NULL
+    UNION ALL
+    SELECT 'template' AS type, id, name, deleted_at FROM templates WHERE deleted_at IS NOT NULL
+) t ORDER BY deleted_at DESC;
+
+-- name: restore-trash
+-- Restores a trashed item of type $1 (campaign, list, template) with the ID $2.
+WITH camp AS (
+    UPDATE campaigns SET deleted_at=NULL, updated_at=NOW()
+    WHERE $1 = 'campaign' AND id = $2 AND deleted_at IS NOT NULL RETURNING id
+),
+ls AS (
+    UPDATE lists SET deleted_at=NULL, updated_at=NOW()
+    WHERE $1 = 'list' AND id = $2 AND deleted_at IS NOT NULL RETURNING id
+),
+tpl AS (
+    UPDATE templates SET deleted_at=NULL, updated_at=NOW()
+    WHERE $1 = 'template' AND id = $2 AND deleted_at IS NOT NULL RETURNING id
+)
+SELECT (SELECT COUNT(*) FROM camp) + (SELECT COUNT(*) FROM ls) + (SELECT COUNT(*) FROM tpl);
+
+-- name: purge-trash
+-- Permanently deletes campaigns, lists, and templates that were trashed before $1.
+-- Campaigns that use a purged template are switched to the default template.
+WITH camps AS (
+    DELETE FROM campaigns WHERE deleted_at < $1 RETURNING id
+),
+ls AS (
+    DELETE FROM lists WHERE deleted_at < $1 RETURNING id
+),
+tpls AS (
+    DELETE FROM templates WHERE deleted_at < $1 AND is_default = false RETURNING id
+),
+def AS (
+    SELECT id FROM templates WHERE is_default = true AND type='campaign' LIMIT 1
+),
+up AS (
+    UPDATE campaigns SET
+        template_id = (CASE WHEN template_id = ANY(SELECT id FROM tpls) THEN (SELECT id FROM def) ELSE template_id END),
+        archive_template_id = (CASE WHEN archive_template_id = ANY(SELECT id FROM tpls) THEN (SELECT id FROM def) ELSE archive_template_id END)
+    WHERE (deleted_at IS NULL OR deleted_at >= $1)
+        AND (template_id = ANY(SELECT id FROM tpls) OR archive_template_id = ANY(SELECT id FROM tpls))
+)
+SELECT (SELECT COUNT(*) FROM camps) + (SELECT COUNT(*) FROM ls) + (SELECT COUNT(*) FROM tpls);
+
+-- autocomplete
+-- All the queries take $1 = search term, $2 = $1 escaped for LIKE, $3 = limit.
+
+-- name: autocomplete-subscribers
+-- Subscribers whose e-mail starts with the term (uses idx_subs_email_prefix).
+-- Exact matches are ranked first followed by the shortest e-mails.
+SELECT id, email AS name, name AS extra FROM subscribers
+    WHERE LOWER(email) LIKE LOWER($2) || '%'
+    ORDER BY LOWER(email) = LOWER($1) DESC, LENGTH(email), id
+    LIMIT $3;
+
+-- name: autocomplete-lists
+-- Exact matches are ranked first followed by prefix matches and the shortest names.
+SELECT id, name, type::TEXT AS extra FROM lists
+    WHERE deleted_at IS NULL AND ($1 = '' OR name ILIKE '%' || $2 || '%')
+    ORDER BY LOWER(name) = LOWER($1) DESC, name ILIKE $2 || '%' DESC, LENGTH(name), name
+    LIMIT $3;
+
+-- name: autocomplete-templates
+-- $4 = optional template type.
+SELECT id, name, type::TEXT AS extra FROM templates
+    WHERE deleted_at IS NULL AND ($4 = '' OR type = $4::template_type)
+    AND ($1 = '' OR name ILIKE '%' || $2 || '%')
+    ORDER BY LOWER(name) = LOWER($1) DESC, name ILIKE $2 || '%' DESC, LENGTH(name), name
+    LIMIT $3;
+
+-- name: autocomplete-tags
+-- Tags of lists and campaigns. Matches are ranked by how often the tags are used.
+SELECT tag AS name FROM (
+    SELECT UNNEST(tags) AS tag FROM lists WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT UNNEST(tags) AS tag FROM campaigns WHERE deleted_at IS NULL
+) t
+    WHERE $1 = '' OR tag ILIKE '%' || $2 || '%'
+    GROUP BY tag
+    ORDER BY LOWER(tag) = LOWER($1) DESC, tag ILIKE $2 || '%' DESC, COUNT(*) DESC, tag
+    LIMIT $3;
+
+-- name: autocomplete-campaigns
+SELECT id, name, status::TEXT AS extra FROM campaigns
+    WHERE deleted_at IS NULL AND ($1 = '' OR name ILIKE '%' || $2 || '%')
+    ORDER BY LOWER(name) = LOWER($1) DESC, name ILIKE $2 || '%' DESC, LENGTH(name), name
+    LIMIT $3;
+
+-- global search
+-- All the queries take $1 = search term, $2 = $1 escaped for LIKE, $3 = limit, $4 = include trashed items.
+
+-- name: global-search-campaigns
+-- Campaigns whose name, subject, or body contain the term. Name matches are ranked first.
+SELECT 'campaigns' AS type, id, name, subject AS extra, deleted_at IS NOT NULL AS trashed FROM campaigns
+    WHERE ($4::BOOLEAN OR deleted_at IS NULL)
+    AND (name ILIKE '%' || $2 || '%' OR subject ILIKE '%' || $2 || '%' OR body ILIKE '%' || $2 || '%')
+    ORDER BY name ILIKE '%' || $2 || '%' DESC, deleted_at IS NOT NULL, updated_at DESC
+    LIMIT $3;
+
+-- name: global-search-templates
+SELECT 'templates' AS type, id, name, type::TEXT AS extra, deleted_at IS NOT NULL AS trashed FROM templates
+    WHERE ($4::BOOLEAN OR deleted_at IS NULL)
+    AND (name ILIKE '%' || $2 || '%' OR subject ILIKE '%' || $2 || '%')
+    ORDER BY LOWER(name) = LOWER($1) DESC, name ILIKE $2 || '%' DESC, deleted_at IS NOT NULL, LENGTH(name), name
+    LIMIT $3;
+
+-- name: global-search-subscribers
+-- Subscribers whose e-mail starts with the term (uses idx_subs_email_prefix).
+-- Subscribers aren't trashed, so this doesn't take $4.
+SELECT 'subscribers' AS type, id, email AS name, name AS extra, FALSE AS trashed FROM subscribers
+    WHERE LOWER(email) LIKE LOWER($2) || '%'
+    ORDER BY LOWER(email) = LOWER($1) DESC, LENGTH(email), id
+    LIMIT $3;
+
+-- name: global-search-lists
+SELECT 'lists' AS type, id, name, type::TEXT AS extra, deleted_at IS NOT NULL AS trashed FROM lists
+    WHERE ($4::BOOLEAN OR deleted_at IS NULL) AND name ILIKE '%' || $2 || '%'
+    ORDER BY LOWER(name) = LOWER($1) DESC, name ILIKE $2 || '%' DESC, deleted_at IS NOT NULL, LENGTH(name), name
+    LIMIT $3;
+
+-- saved views
+-- Views are visible to their creators (by username) and shared views to everyone.
+
+-- name: get-saved-views
+-- $1 = optional collection, $2 = username.
+SELECT * FROM saved_views
+    WHERE ($1 = '' OR collection = $1::saved_view_collection) AND (shared OR created_by = $2)
+    ORDER BY collection, LOWER(name);
+
+-- name: get-saved-view
+SELECT * FROM saved_views WHERE id = $1 AND (shared OR created_by = $2);
+
+-- name: create-saved-view
+INSERT INTO saved_views (name, collection, params, created_by, shared)
+    VALUES($1, $2, $3, $4, $5) RETURNING id;
+
+-- name: update-saved-view
+-- Only the creator can update a view.
+UPDATE saved_views SET name=$3, params=$4, shared=$5, updated_at=NOW()
+    WHERE id = $1 AND created_by = $2;
+
+-- name: delete-saved-view
+-- Only the creator can delete a view.
+DELETE FROM saved_views WHERE id = $1 AND created_by = $2;
+
+-- subscriber columns
+
+-- name: get-subscriber-columns
+SELECT columns FROM subscriber_columns WHERE username = $1;
+
+-- name: upsert-subscriber-columns
+-- An empty list of columns resets the user's columns to the defaults.
+WITH del AS (
+    DELETE FROM subscriber_columns WHERE username = $1 AND CARDINALITY($2::TEXT[]) = 0
+)
+INSERT INTO subscriber_columns (username, columns) SELECT $1, $2 WHERE CARDINALITY($2::TEXT[]) > 0
+    ON CONFLICT (username) DO UPDATE SET columns = $2, updated_at = NOW();
+
+-- name: get-user-preferences
+SELECT timezone FROM user_preferences WHERE username = $1;
+
+-- name: upsert-user-preferences
+INSERT INTO user_preferences (username, timezone) VALUES ($1, $2)
+    ON CONFLICT (username) DO UPDATE SET timezone = $2, updated_at = NOW();
+
+-- notifications
+
+-- name: insert-notification
+-- Notifications older than 90 days are cleaned up on every insert.
+WITH del AS (
+    DELETE FROM notifications WHERE created_at < NOW() - INTERVAL '90 days'
+)
+INSERT INTO notifications (type, title, body, data, username) VALUES($1, $2, $3, $4, NULLIF($5, '')) RETURNING id;
+
+-- name: get-notifications
+-- $1 = username, $2 = notification types to return.
+SELECT COUNT(*) OVER () AS total, id, type, title, body, data, created_at, $1 = ANY(read_by) AS read
+    FROM notifications WHERE type = ANY($2::notification_type[]) AND (username IS NULL OR username = $1)
+    ORDER BY created_at DESC OFFSET $3 LIMIT (CASE WHEN $4 < 1 THEN NULL ELSE $4 END);
+
+-- name: count-unread-notifications
+SELECT COUNT(*) FROM notifications WHERE type = ANY($2::notification_type[])
+    AND (username IS NULL OR username = $1) AND NOT ($1 = ANY(read_by));
+
+-- name: mark-notifications-read
+-- Marks the given notifications ($2), or all of them if $2 is empty, as read by the user ($1).
+UPDATE notifications SET read_by = ARRAY_APPEND(read_by, $1)
+    WHERE (CARDINALITY($2::INT[]) = 0 OR id = ANY($2::INT[]))
+    AND (username IS NULL OR username = $1) AND NOT ($1 = ANY(read_by));
+
+-- name: get-notification-prefs
+SELECT email, events FROM notification_prefs WHERE username = $1;
+
+-- name: get-notification-emails
+-- E-mail addresses of the users who have opted to receive a notification type ($1) by e-mail,
+-- optionally limited to the given usernames ($2).
+SELECT email FROM notification_prefs WHERE email != '' AND events->$1 @> '["email"]'
+    AND (CARDINALITY($2::TEXT[]) = 0 OR username = ANY($2::TEXT[]));
+
+-- name: upsert-notification-prefs
+INSERT INTO notification_prefs (username, email, events) VALUES($1, $2, $3)
+    ON CONFLICT (username) DO UPDATE SET email=$2, events=$3, updated_at=NOW();
+
+-- name: count-recent-bounces
+SELECT COUNT(*) FROM bounces WHERE created_at > $1;
+
+-- campaign comments
+
+-- name: get-campaign-comments
+-- Comments of a campaign ordered by thread, with replies following their thread's first comment.
+SELECT * FROM campaign_comments WHERE campaign_id = $1
+    ORDER BY COALESCE(parent_id, id), created_at;
+
+-- name: get-campaign-comment
+SELECT * FROM campaign_comments WHERE id = $1 AND campaign_id = $2;
+
+-- name: insert-campaign-comment
+-- Replies to a reply are added to the thread of the comment replied to.
+-- Nothing is inserted if the parent ($2) is not a comment of the campaign ($1).
+WITH parent AS (
+    SELECT COALESCE(parent_id, id) AS id FROM campaign_comments WHERE id = $2 AND campaign_id = $1
+)
+INSERT INTO campaign_comments (campaign_id, parent_id, author, body, mentions)
+    SELECT $1, (SELECT id FROM parent), $3, $4, $5
+    WHERE $2::INT IS NULL OR EXISTS (SELECT 1 FROM parent)
+    RETURNING id;
+
+-- name: update-campaign-comment
+-- Only the author ($3) can edit a comment.
+UPDATE campaign_comments SET body = $4, mentions = $5, updated_at = NOW()
+    WHERE id = $1 AND campaign_id = $2 AND author = $3;
+
+-- name: delete-campaign-comment
+-- Only the author ($3) can delete a comment. Deleting a thread's first comment deletes its replies.
+DELETE FROM campaign_comments WHERE id = $1 AND campaign_id = $2 AND author = $3;
+
+-- sending domains
+
+-- name: get-sending-domains
+SELECT * FROM sending_domains ORDER BY domain;
+
+-- name: get-sending-domain
+SELECT * FROM sending_domains WHERE id = $1;
+
+-- name: create-sending-domain
+INSERT INTO sending_domains (domain, addresses, token) VALUES($1, $2, $3) RETURNING id;
+
+-- name: update-sending-domain
+UPDATE sending_domains SET addresses = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: verify-sending-domain
+UPDATE sending_domains SET verified = $2,
+    verified_at = (CASE WHEN $2 THEN NOW() ELSE verified_at END), updated_at = NOW()
+    WHERE id = $1;
+
+-- name: delete-sending-domain
+DELETE FROM sending_domains WHERE id = $1;
+
+-- status rules
+-- name: get-status-rules
+SELECT * FROM status_rules ORDER BY id;
+
+-- name: get-status-rule
+SELECT * FROM status_rules WHERE id = $1;
+
+-- name: create-status-rule
+INSERT INTO status_rules (name, enabled, condition, threshold, bounce_type, window_days, action)
+    VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id;
+
+-- name: update-status-rule
+UPDATE status_rules SET name=$2, enabled=$3, condition=$4, threshold=$5, bounce_type=$6, window_days=$7,
+    action=$8, updated_at=NOW() WHERE id = $1;
+
+-- name: delete-status-rule
+DELETE FROM status_rules WHERE id = $1;
+
+-- name: apply-status-rule
+-- Applies a status rule's action ($5) to the matching subscribers and records the run of
+-- the rule ($6). If $7 (dry run) is true, nothing is changed. Returns a sample of $8 matching
+-- subscribers with the total, as they were before the rule was applied.
+-- $1 = condition, $2 = threshold, $3 = bounce type, $4 = window days.
+-- Unsubscribe rules skip subscribers who are already unsubscribed from all lists. Unsubscribing
+-- an unconfirmed rule's subscribers only unsubscribes their stale unconfirmed subscriptions.
+-- Blocklisting unsubscribes all subscriptions.
+WITH subs AS (
+    SELECT s.id FROM subscribers s WHERE
+    (CASE $1
+        WHEN 'unconfirmed' THEN EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = s.id AND sl.status = 'unconfirmed'
+            AND sl.created_at < NOW() - MAKE_INTERVAL(days => $2::INT)
+        )
+        WHEN 'bounces' THEN s.status != 'blocklisted' AND (
+            SELECT COUNT(DISTINCT b.campaign_id) FROM bounces b WHERE b.subscriber_id = s.id
+            AND ($3 = '' OR b.type::TEXT = $3)
+            AND ($4::INT = 0 OR b.created_at > NOW() - MAKE_INTERVAL(days => $4::INT))
+        ) >= $2::INT
+        AND ($5 = 'blocklist' OR EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = s.id AND sl.status != 'unsubscribed'
+        ))
+        ELSE FALSE
+    END)
+),
+bl AS (
+    UPDATE subscribers SET status='blocklisted', updated_at=NOW()
+    WHERE NOT $7::BOOLEAN AND $5 = 'blocklist' AND id IN (SELECT id FROM subs)
+),
+unsub AS (
+    UPDATE subscriber_lists SET status='unsubscribed', snoozed_until=NULL, snooze_status=NULL, updated_at=NOW()
+    WHERE NOT $7::BOOLEAN AND subscriber_id IN (SELECT id FROM subs) AND status != 'unsubscribed'
+    AND ($5 = 'blocklist' OR $1 != 'unconfirmed'
+        OR (status = 'unconfirmed' AND created_at < NOW() - MAKE_INTERVAL(days => $2::INT)))
+),
+total AS (
+    SELECT COUNT(*) AS n FROM subs
+),
+run AS (
+    UPDATE status_rules SET last_run_at=NOW(), last_affected=(SELECT n FROM total) WHERE NOT $7::BOOLEAN AND id = $6
+)
+SELECT (SELECT n FROM total) AS total, id, uuid, email, name, status, created_at, updated_at FROM subscribers
+    WHERE id IN (SELECT id FROM subs) ORDER BY id LIMIT $8;
+
+-- quotas
+-- name: get-quotas
+SELECT * FROM quotas ORDER BY username;
+
+-- name: get-user-quota
+-- The quota of a user ($1), or the default quota ('') if the user doesn't have one.
+SELECT * FROM quotas WHERE username = $1 OR username = '' ORDER BY username DESC LIMIT 1;
+
+-- name: upsert-quota
+INSERT INTO quotas (username, campaigns_per_month, messages_per_day, max_subscribers) VALUES($1, $2, $3, $4)
+    ON CONFLICT (username) DO UPDATE SET campaigns_per_month=$2, messages_per_day=$3, max_subscribers=$4, updated_at=NOW()
+    RETURNING id;
+
+-- name: delete-quota
+DELETE FROM quotas WHERE id = $1;
+
+-- name: get-quota-usage
+-- Campaigns started by a user ($1) this month, messages in the campaigns they started today,
+-- and the total number of subscribers.
+SELECT
+    (SELECT COUNT(*) FROM quota_usage WHERE username = $1 AND created_at >= DATE_TRUNC('month', NOW())) AS campaigns_month,
+    (SELECT COALESCE(SUM(messages), 0) FROM quota_usage WHERE username = $1 AND created_at >= DATE_TRUNC('day', NOW())) AS messages_day,
+    (SELECT COUNT(*) FROM subscribers) AS subscribers;
+
+-- name: insert-quota-usage
+INSERT INTO quota_usage (username, campaign_id, messages) VALUES($1, $2, $3);
+
+-- campaign send log
+-- name: log-campaign-sends
+-- Records the queueing and delivery attempts of campaign messages. The arrays are campaign IDs,
+-- subscriber IDs, statuses, responses, queued times, and attempt times. Bounced sends aren't overwritten.
+INSERT INTO campaign_sends (campaign_id, subscriber_id, status, response, queued_at, sent_at, deferred_at)
+    SELECT DISTINCT ON (c, s) c, s, st, r, q, (CASE WHEN st = 'sent' THEN t END), (CASE WHEN st = 'deferred' THEN t END)
+    FROM UNNEST($1::INT[], $2::INT[], $3::send_status[], $4::TEXT[], $5::TIMESTAMP WITH TIME ZONE[], $6::TIMESTAMP WITH TIME ZONE[])
+        AS x(c, s, st, r, q, t)
+    -- Skip subscribers deleted since the message was sent.
+    WHERE EXISTS (SELECT 1 FROM subscribers WHERE id = s)
+    ORDER BY c, s, t DESC
+    ON CONFLICT (campaign_id, subscriber_id) DO UPDATE SET
+        status=EXCLUDED.status,
+        response=EXCLUDED.response,
+        queued_at=EXCLUDED.queued_at,
+        sent_at=COALESCE(EXCLUDED.sent_at, campaign_sends.sent_at),
+        deferred_at=COALESCE(EXCLUDED.deferred_at, campaign_sends.deferred_at)
+    WHERE campaign_sends.status != 'bounced';
+
+-- name: query-campaign-sends
+-- Sends of a campaign ($1, or all campaigns if 0) optionally filtered by a subscriber e-mail
+-- substring ($2) and status ($3).
+SELECT COUNT(*) OVER () AS total, s.*, campaigns.name AS campaign_name,
+    subscribers.uuid AS subscriber_uuid, subscribers.email
+    FROM campaign_sends s
+    LEFT JOIN campaigns ON (campaigns.id = s.campaign_id)
+    LEFT JOIN subscribers ON (subscribers.id = s.subscriber_id)
+    WHERE ($1 = 0 OR s.campaign_id = $1)
+        AND ($2 = '' OR subscribers.email ILIKE '%' || $2 || '%')
+        AND ($3 = '' OR s.status = $3::send_status)
+    ORDER BY s.queued_at DESC, s.subscriber_id DESC OFFSET $4 LIMIT $5;
+
+-- name: queue-failed-send-subscribers
+-- Marks the failed (deferred) sends of a campaign ($1), optionally limited to the given
+-- subscriber IDs ($2), as queued and returns their subscribers for re-queueing. Like
+-- the campaign itself, blocklisted subscribers, those who have since unsubscribed from
+-- the campaign's lists, and those on its excluded lists are skipped. As the sends are
+-- marked queued, concurrent retries don't pick up the same subscribers.
+WITH RECURSIVE campLists AS (
+    SELECT lists.id AS list_id FROM lists
+    INNER JOIN campaign_lists ON (campaign_lists.list_id = lists.id)
+    WHERE campaign_lists.campaign_id = $1 AND lists.deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists
+    INNER JOIN campLists ON (lists.parent_id = campLists.list_id) WHERE lists.deleted_at IS NULL
+),
+exclLists AS (
+    SELECT id AS list_id FROM lists
+        WHERE id = ANY((SELECT exclude_list_ids FROM campaigns WHERE id = $1)::INT[]) AND deleted_at IS NULL
+    UNION
+    SELECT lists.id FROM lists INNER JOIN exclLists ON (lists.parent_id = exclLists.list_id) WHERE lists.deleted_at IS NULL
+),
+subIDs AS (
+    SELECT s.subscriber_id FROM campaign_sends s
+    INNER JOIN subscribers ON (subscribers.id = s.subscriber_id)
+    WHERE s.campaign_id = $1 AND s.status = 'deferred'
+        AND (CARDINALITY($2::INT[]) = 0 OR s.subscriber_id = ANY($2::INT[]))
+        AND subscribers.status != 'blocklisted'
+        AND EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = s.subscriber_id
+            AND sl.list_id IN (SELECT list_id FROM campLists) AND sl.status != 'unsubscribed'
+        )
+        AND NOT EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = s.subscriber_id
+            AND sl.list_id IN (SELECT list_id FROM exclLists) AND sl.status != 'unsubscribed'
+        )
+    ORDER BY s.subscriber_id LIMIT $3
+),
+queued AS (
+    UPDATE campaign_sends SET status='queued', queued_at=NOW()
+    WHERE campaign_id = $1 AND subscriber_id IN (SELECT subscriber_id FROM subIDs)
+        -- Re-checked after waiting on a concurrent retry's lock on the row.
+        AND status = 'deferred'
+    RETURNING subscriber_id
+)
+SELECT subscribers.* FROM queued
+    INNER JOIN subscribers ON (subscribers.id = queued.subscriber_id)
+    ORDER BY subscribers.id;
+
+-- name: defer-campaign-sends
+-- Marks the queued sends of a campaign ($1) to the given subscribers ($2) as failed (deferred)
+-- again, for instance, when their messages couldn't be re-queued.
+UPDATE campaign_sends SET status='deferred'
+    WHERE campaign_id = $1 AND subscriber_id = ANY($2::INT[]) AND status = 'queued';
+
+-- name: delete-campaign-sends
+-- Deletes the send log entries older than the retention period ($1), except those of
+-- campaigns that are still running or paused.
+DELETE FROM campaign_sends WHERE queued_at < $1
+    AND campaign_id NOT IN (SELECT id FROM campaigns WHERE status IN ('running', 'paused'));
+
+-- captured messages
+-- name: insert-captured-message
+INSERT INTO captured_messages (messenger, campaign_id, subscriber_id, from_email, to_emails, subject, content_type, body, alt_body, headers, raw)
+    VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: query-captured-messages
+-- Captured messages without their bodies, optionally filtered by a campaign ($1)
+-- and a subject or recipient substring ($2).
+SELECT COUNT(*) OVER () AS total, id, messenger, campaign_id, subscriber_id, from_email, to_emails,
+    subject, content_type, headers, created_at
+    FROM captured_messages
+    WHERE ($1 = 0 OR campaign_id = $1)
+        AND ($2 = '' OR subject ILIKE '%' || $2 || '%' OR ARRAY_TO_STRING(to_emails, ',') ILIKE '%' || $2 || '%')
+    ORDER BY id DESC OFFSET $3 LIMIT $4;
+
+-- name: get-captured-message
+SELECT * FROM captured_messages WHERE id = $1;
+
+-- name: delete-captured-messages
+DELETE FROM captured_messages WHERE CARDINALITY($1::INT[]) = 0 OR id = ANY($1);
+
+-- sent message archive
+-- name: insert-sent-messages
+-- Archives the content of sent campaign messages. The arrays are campaign IDs, subscriber IDs,
+-- e-mails, subjects, content types, bodies, alt bodies, body hashes, campaign versions,
+-- and sent times. The template's version is its updated_at.
+INSERT INTO sent_messages (campaign_id, campaign_name, subscriber_id, email, subject, content_type, body, alt_body,
+    body_hash, campaign_version, template_id, template_updated_at, sent_at)
+    SELECT x.c, COALESCE(campaigns.name, ''), x.s, x.e, x.subj, x.ct, x.b, x.ab, x.h, x.v,
+        campaigns.template_id, templates.updated_at, x.t
+    FROM UNNEST($1::INT[], $2::INT[], $3::TEXT[], $4::TEXT[], $5::TEXT[], $6::TEXT[], $7::TEXT[], $8::TEXT[],
+        $9::INT[], $10::TIMESTAMP WITH TIME ZONE[])
+        AS x(c, s, e, subj, ct, b, ab, h, v, t)
+    LEFT JOIN campaigns ON (campaigns.id = x.c)
+    LEFT JOIN templates ON (templates.id = campaigns.template_id)
+    -- Skip subscribers deleted since the message was sent.
+    WHERE EXISTS (SELECT 1 FROM subscribers WHERE id = x.s);
+
+-- name: query-sent-messages
+-- Archived sent messages without their bodies, optionally filtered by a subscriber ($1) and a campaign ($2).
+SELECT COUNT(*) OVER () AS total, id, campaign_id, campaign_name, subscriber_id, email, subject, content_type,
+    body_hash, campaign_version, template_id, template_updated_at, sent_at
+    FROM sent_messages
+    WHERE ($1 = 0 OR subscriber_id = $1) AND ($2 = 0 OR campaign_id = $2)
+    ORDER BY id DESC OFFSET $3 LIMIT $4;
+
+-- name: get-sent-message
+SELECT * FROM sent_messages WHERE id = $1;
+
+-- name: delete-sent-messages
+-- Deletes the archived sent messages older than the retention period ($1).
+DELETE FROM sent_messages WHERE sent_at < $1;
+
+-- stripe
+-- name: upsert-stripe-customer
+-- Records a Stripe customer's e-mail. An empty e-mail doesn't overwrite a known one.
+INSERT INTO stripe_customers (id, email) VALUES($1, LOWER($2))
+    ON CONFLICT (id) DO UPDATE SET
+    email=(CASE WHEN EXCLUDED.email != '' THEN EXCLUDED.email ELSE stripe_customers.email END),
+    updated_at=NOW();
+
+-- name: upsert-stripe-subscription
+-- Records the status of each product ($3) of a Stripe subscription ($1). Products that
+-- are no longer on the subscription are canceled. Records that have been updated by a
+-- newer event than the given event time ($6) are left as-is.
+WITH removed AS (
+    UPDATE stripe_subscriptions SET status='canceled', event_at=$6, updated_at=NOW()
+    WHERE id = $1 AND product_id != ALL($3::TEXT[]) AND event_at <= $6
+)
+INSERT INTO stripe_subscriptions (id, product_id, customer_id, status, latest_invoice, event_at)
+    SELECT $1, p, $2, $4, $5, $6 FROM UNNEST($3::TEXT[]) p
+    ON CONFLICT (id, product_id) DO UPDATE SET
+        customer_id=EXCLUDED.customer_id,
+        status=EXCLUDED.status,
+        latest_invoice=EXCLUDED.latest_invoice,
+        event_at=EXCLUDED.event_at,
+        updated_at=NOW()
+    WHERE stripe_subscriptions.event_at <= EXCLUDED.event_at;
+
+-- name: refund-stripe-invoice
+-- Marks the subscriptions whose latest invoice ($1) has been refunded and returns their customer IDs.
+UPDATE stripe_subscriptions SET status='refunded', updated_at=NOW()
+    WHERE latest_invoice = $1 AND latest_invoice != '' RETURNING customer_id;
+
+-- name: get-stripe-customer-subscriptions
+SELECT s.*, COALESCE(c.email, '') AS email FROM stripe_subscriptions s
+    LEFT JOIN stripe_customers c ON (c.id = s.customer_id)
+    WHERE s.customer_id = $1;
+
+-- name: mark-stripe-subscriptions-synced
+UPDATE stripe_subscriptions SET synced_status=status WHERE customer_id = $1;
+
+-- name: get-stripe-reconciliation
+-- Compares the Stripe subscriptions of the products ($1) mapped to lists ($2) with the
+-- subscriptions of the lists. 'missing' are entitled customers who aren't subscribed to
+-- the list, 'unpaid' are list subscribers who aren't entitled by any product mapped to
+-- the list (eg: complimentary or lapsed), and 'pending' are entitled subscriptions whose
+-- customer's e-mail isn't known yet.
+WITH map AS (
+    SELECT * FROM UNNEST($1::TEXT[], $2::INT[]) AS m(product_id, list_id)
+),
+subs AS (
+    SELECT COALESCE(c.email, '') AS email, s.product_id, map.list_id, s.status FROM stripe_subscriptions s
+    INNER JOIN map ON (map.product_id = s.product_id)
+    LEFT JOIN stripe_customers c ON (c.id = s.customer_id)
+    WHERE s.status IN ('active', 'trialing')
+)
+SELECT * FROM (
+    SELECT 'missing' AS type, subs.email, subs.list_id, COALESCE(lists.name, '') AS list_name, subs.product_id, subs.status FROM subs
+        LEFT JOIN lists ON (lists.id = subs.list_id)
+        LEFT JOIN subscribers sub ON (LOWER(sub.email) = subs.email)
+        LEFT JOIN subscriber_lists sl ON (sl.subscriber_id = sub.id AND sl.list_id = subs.list_id)
+        WHERE subs.email != '' AND (sl.subscriber_id IS NULL OR sl.status = 'unsubscribed')
+    UNION ALL
+    SELECT 'unpaid', LOWER(sub.email), sl.list_id, lists.name, '', '' FROM subscriber_lists sl
+        INNER JOIN subscribers sub ON (sub.id = sl.subscriber_id)
+        INNER JOIN lists ON (lists.id = sl.list_id)
+        WHERE sl.list_id IN (SELECT list_id FROM map) AND sl.status != 'unsubscribed'
+        AND NOT EXISTS (SELECT 1 FROM subs WHERE subs.email = LOWER(sub.email) AND subs.list_id = sl.list_id)
+    UNION ALL
+    SELECT 'pending', '', subs.list_id, COALESCE(lists.name, ''), subs.product_id, subs.status FROM subs
+        LEFT JOIN lists ON (lists.id = subs.list_id)
+        WHERE subs.email = ''
+) r ORDER BY type, list_id, email LIMIT $3;
+
+-- header presets
+-- name: get-header-presets
+SELECT * FROM header_presets WHERE ($1 = 0 OR id = $1) ORDER BY name;
+
+-- name: create-header-preset
+INSERT INTO header_presets (name, headers) VALUES($1, $2) RETURNING id;
+
+-- name: update-header-preset
+UPDATE header_presets SET name=$2, headers=$3, updated_at=NOW() WHERE id = $1;
+
+-- name: delete-header-preset
+DELETE FROM header_presets WHERE id = $1;
+
+-- sequences
+-- name: get-sequences
+-- Sequences with their steps and the number of subscribers in them by status.
+SELECT s.*, lists.name AS list_name,
+    COALESCE((
+        SELECT JSON_AGG(JSON_BUILD_OBJECT('id', st.id, 'campaign_id', st.campaign_id,
+            'campaign_name', c.name, 'delay_mins', st.delay_mins) ORDER BY st.position, st.id)
+        FROM sequence_steps st INNER JOIN campaigns c ON (c.id = st.campaign_id)
+        WHERE st.sequence_id = s.id
+    ), '[]') AS steps,
+    counts.num_active, counts.num_finished, counts.num_exited
+FROM sequences s
+INNER JOIN lists ON (lists.id = s.list_id)
+LEFT JOIN LATERAL (
+    SELECT COUNT(*) FILTER (WHERE status = 'active') AS num_active,
+        COUNT(*) FILTER (WHERE status = 'finished') AS num_finished,
+        COUNT(*) FILTER (WHERE status = 'exited') AS num_exited
+    FROM sequence_subscribers WHERE sequence_id = s.id
+) counts ON true
+WHERE ($1 = 0 OR s.id = $1)
+ORDER BY s.name;
+
+-- name: create-sequence
+-- $5, $6 = campaign IDs and delays (minutes) of the steps in order.
+WITH seq AS (
+    INSERT INTO sequences (name, list_id, status, exit_on_click) VALUES($1, $2, $3, $4) RETURNING id
+),
+steps AS (
+    INSERT INTO sequence_steps (sequence_id, campaign_id, delay_mins, position)
+        SELECT (SELECT id FROM seq), st.campaign_id, st.delay_mins, st.position
+        FROM UNNEST($5::INT[], $6::INT[]) WITH ORDINALITY AS st(campaign_id, delay_mins, position)
+)
+SELECT id FROM seq;
+
+-- name: update-sequence
+-- The steps are replaced. Subscribers continue from the number of steps they've been
+-- sent and those who've been sent all the (new) steps finish the sequence.
+WITH seq AS (
+    UPDATE sequences SET name=$2, list_id=$3, status=$4, exit_on_click=$5, updated_at=NOW()
+    WHERE id = $1 RETURNING id
+),
+del AS (
+    DELETE FROM sequence_steps WHERE sequence_id = (SELECT id FROM seq)
+),
+steps AS (
+    INSERT INTO sequence_steps (sequence_id, campaign_id, delay_mins, position)
+        SELECT (SELECT id FROM seq), st.campaign_id, st.delay_mins, st.position
+        FROM UNNEST($6::INT[], $7::INT[]) WITH ORDINALITY AS st(campaign_id, delay_mins, position)
+        WHERE EXISTS (SELECT 1 FROM seq)
+),
+fin AS (
+    UPDATE sequence_subscribers SET status='finished', updated_at=NOW()
+    WHERE sequence_id = (SELECT id FROM seq) AND status = 'active' AND step >= CARDINALITY($6::INT[])
+)
+SELECT id FROM seq;
+
+-- name: delete-sequence
+DELETE FROM sequences WHERE id = $1;
+
+-- name: enroll-sequence-subscribers
+-- Subscribers who've subscribed to the list of an active sequence since it was created
+-- enter it. For double opt-in lists, subscribers enter once they confirm.
+INSERT INTO sequence_subscribers (sequence_id, subscriber_id, last_at)
+    SELECT seq.id, sl.subscriber_id, (CASE WHEN lists.optin = 'double' THEN sl.updated_at ELSE sl.created_at END)
+    FROM sequences seq
+    INNER JOIN lists ON (lists.id = seq.list_id)
+    INNER JOIN subscriber_lists sl ON (sl.list_id = seq.list_id)
+    INNER JOIN subscribers ON (subscribers.id = sl.subscriber_id AND subscribers.status = 'enabled')
+    WHERE seq.status = 'active' AND sl.created_at >= seq.created_at
+    AND (CASE WHEN lists.optin = 'double' THEN sl.status = 'confirmed' ELSE sl.status != 'unsubscribed' END)
+ON CONFLICT (sequence_id, subscriber_id) DO NOTHING;
+
+-- name: exit-sequence-subscribers
+-- Subscribers exit sequences when they unsubscribe from (or are removed from) the
+-- sequence's list or are blocklisted, or if the sequence exits on clicks, when they
+-- click a link in any of its campaigns.
+UPDATE sequence_subscribers ss SET status='exited', updated_at=NOW()
+FROM sequences seq
+WHERE seq.id = ss.sequence_id AND ss.status = 'active'
+AND (
+    NOT EXISTS (
+        SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = ss.subscriber_id
+        AND sl.list_id = seq.list_id AND sl.status != 'unsubscribed'
+    )
+    OR EXISTS (SELECT 1 FROM subscribers WHERE id = ss.subscriber_id AND status = 'blocklisted')
+    OR (seq.exit_on_click AND EXISTS (
+        SELECT 1 FROM link_clicks lc INNER JOIN sequence_steps st ON (st.campaign_id = lc.campaign_id)
+        WHERE st.sequence_id = seq.id AND lc.subscriber_id = ss.subscriber_id AND lc.created_at >= ss.created_at
+    ))
+);
+
+-- name: next-sequence-messages
+-- Returns the steps of active sequences that are due to be sent to subscribers ($1 at most).
+-- The subscribers are advanced with advance-sequence-subscribers once the steps are queued.
+-- Steps with trashed or cancelled campaigns are skipped, and subscribers wait at steps whose
+-- campaigns are paused. Blocklisted subscribers and those who've unsubscribed from the
+-- sequence's list are never sent to, even before they're exited from the sequence.
+-- Steps that failed to be queued are skipped until their retry time.
+WITH steps AS (
+    SELECT st.sequence_id, st.campaign_id, st.delay_mins, campaigns.status AS campaign_status, sequences.list_id,
+        ROW_NUMBER() OVER (PARTITION BY st.sequence_id ORDER BY st.position, st.id) - 1 AS idx,
+        COUNT(*) OVER (PARTITION BY st.sequence_id) AS num
+    FROM sequence_steps st
+    INNER JOIN sequences ON (sequences.id = st.sequence_id AND sequences.status = 'active')
+    INNER JOIN campaigns ON (campaigns.id = st.campaign_id AND campaigns.deleted_at IS NULL AND campaigns.status != 'cancelled')
+),
+due AS (
+    SELECT ss.sequence_id, ss.subscriber_id, ss.step, steps.campaign_id, steps.num FROM sequence_subscribers ss
+    INNER JOIN steps ON (steps.sequence_id = ss.sequence_id AND steps.idx = ss.step)
+    WHERE ss.status = 'active' AND NOW() >= ss.last_at + MAKE_INTERVAL(mins => steps.delay_mins)
+        AND (ss.retry_at IS NULL OR NOW() >= ss.retry_at)
+        AND steps.campaign_status != 'paused'
+        AND EXISTS (
+            SELECT 1 FROM subscriber_lists sl WHERE sl.subscriber_id = ss.subscriber_id
+            AND sl.list_id = steps.list_id AND sl.status != 'unsubscribed'
+        )
+    ORDER BY ss.last_at
+    LIMIT $1
+)
+SELECT due.sequence_id, due.campaign_id, due.step, due.num AS num_steps, subscribers.* FROM due
+INNER JOIN subscribers ON (subscribers.id = due.subscriber_id AND subscribers.status != 'blocklisted');
+
+-- name: advance-sequence-subscribers
+-- Advances the subscribers ($2) of sequences ($1) past the steps ($3, of $4 steps) that have
+-- been queued for them. Subscribers who're sent the last step finish the sequence.
+UPDATE sequence_subscribers ss SET step = x.step + 1, last_at = NOW(), retry_at = NULL, updated_at = NOW(),
+    status = (CASE WHEN x.step + 1 >= x.num THEN 'finished' ELSE 'active' END)::sequence_subscriber_status
+FROM UNNEST($1::INT[], $2::INT[], $3::INT[], $4::INT[]) AS x(seq_id, sub_id, step, num)
+WHERE ss.sequence_id = x.seq_id AND ss.subscriber_id = x.sub_id AND ss.step = x.step AND ss.status = 'active';
+
+-- name: defer-sequence-subscribers
+-- Parks the subscribers ($2) of sequences ($1) at the steps ($3) that failed to be queued
+-- for them until they're retried after $4 minutes.
+UPDATE sequence_subscribers ss SET retry_at = NOW() + MAKE_INTERVAL(mins => $4), updated_at = NOW()
+FROM UNNEST($1::INT[], $2::INT[], $3::INT[]) AS x(seq_id, sub_id, step)
+WHERE ss.sequence_id = x.seq_id AND ss.subscriber_id = x.sub_id AND ss.step = x.step AND ss.status = 'active';
+
+-- held messages
+-- name: hold-message
+INSERT INTO held_messages (message) VALUES($1);
+
+-- name: next-held-messages
+-- Removes and returns the oldest $1 held messages in the order they were held.
+WITH del AS (
+    DELETE FROM held_messages WHERE id IN (
+        SELECT id FROM held_messages ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+    ) RETURNING id, message
+)
+SELECT message FROM del ORDER BY id;
+
+-- api tokens
+-- name: get-api-tokens
+SELECT id, name, username, scopes, role, expires_at, last_used_at, created_at FROM api_tokens ORDER BY username, id;
+
+-- name: create-api-token
+INSERT INTO api_tokens (name, username, token_hash, scopes, expires_at, role) VALUES($1, $2, $3, $4, $5, $6)
+    RETURNING id, name, username, scopes, role, expires_at, last_used_at, created_at;
+
+-- name: delete-api-token
+DELETE FROM api_tokens WHERE id = $1;
+
+-- name: rotate-api-token
+-- Replaces a token's hash ($2), which immediately invalidates the old token.
+UPDATE api_tokens SET token_hash = $2, last_used_at = NULL WHERE id = $1
+    RETURNING id, name, username, scopes, role, expires_at, last_used_at, created_at;
+
+-- name: use-api-token
+-- Returns an unexpired token of a user ($1) by its hash ($2) and records its use.
+-- last_used_at is only updated once a minute to avoid a write on every request.
+WITH tok AS (
+    SELECT * FROM api_tokens WHERE username = $1 AND token_hash = $2 AND (expires_at IS NULL OR expires_at > NOW())
+),
+u AS (
+    UPDATE api_tokens SET last_used_at = NOW()
+    WHERE id = (SELECT id FROM tok) AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+)
+SELECT id, name, username, scopes, role, expires_at, NOW() AS last_used_at, created_at FROM tok;
+
+-- audit log
+-- name: insert-audit-log
+INSERT INTO audit_log (actor, action, entity_type, entity_id, before_data, after_data, changes, ip)
+    VALUES($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: query-audit-log
+-- Optionally filtered by $1 = actor, $2 = action, $3 = entity type, $4 = entity ID.
+SELECT COUNT(*) OVER () AS total, * FROM audit_log
+    WHERE ($1 = '' OR actor = $1) AND ($2 = '' OR action = $2)
+    AND ($3 = '' OR entity_type = $3) AND ($4 = 0 OR entity_id = $4)
+    ORDER BY id DESC OFFSET $5 LIMIT $6;
+
+-- maintenance
+-- name: delete-dangling-media
+-- Media of the provider $2 uploaded before $1 that aren't attached to a campaign
+-- or referenced anywhere: the bodies of campaigns, A/B test variants, templates, and
+-- archived sent messages, list descriptions, and settings (eg: logos).
+DELETE FROM media m
+    WHERE m.provider = $2 AND m.created_at < $1
+    AND NOT EXISTS (SELECT 1 FROM campaign_media WHERE media_id = m.id)
+    AND NOT EXISTS (
+        SELECT 1 FROM campaigns
+        WHERE STRPOS(body, m.filename) > 0 OR STRPOS(COALESCE(altbody, ''), m.filename) > 0
+    )
+    AND NOT EXISTS (SELECT 1 FROM campaign_variants WHERE STRPOS(body, m.filename) > 0)
+    AND NOT EXISTS (SELECT 1 FROM templates WHERE STRPOS(body, m.filename) > 0)
+    AND NOT EXISTS (
+        SELECT 1 FROM sent_messages
+        WHERE STRPOS(body, m.filename) > 0 OR STRPOS(alt_body, m.filename) > 0
+    )
+    AND NOT EXISTS (SELECT 1 FROM lists WHERE STRPOS(description, m.filename) > 0)
+    AND NOT EXISTS (SELECT 1 FROM settings WHERE STRPOS(value::TEXT, m.filename) > 0)
+    RETURNING m.filename;
+
+-- name: delete-expired-tokens
+-- Expired API tokens, subscriber data export links, and e-mail change confirmations.
+WITH tokens AS (
+    DELETE FROM api_tokens WHERE expires_at IS NOT NULL AND expires_at < NOW() RETURNING 1
+),
+exports AS (
+    DELETE FROM subscriber_exports WHERE expires_at < NOW() RETURNING 1
+),
+changes AS (
+    DELETE FROM subscriber_email_changes WHERE expires_at < NOW() RETURNING 1
+)
+SELECT (SELECT COUNT(*) FROM tokens) AS api_tokens,
+    (SELECT COUNT(*) FROM exports) AS subscriber_exports,
+    (SELECT COUNT(*) FROM changes) AS email_changes;
+
+-- name: get-integrity-report
+-- Counts of records with inconsistent counters or states.
+SELECT
+    (SELECT COUNT(*) FROM campaigns WHERE sent < 0 OR to_send < 0 OR sent > to_send) AS campaign_counts,
+    (SELECT COUNT(*) FROM campaigns c WHERE c.sent <
+        (SELECT COUNT(*) FROM campaign_sends WHERE campaign_id = c.id AND status IN ('sent', 'bounced'))
+    ) AS campaign_sends,
+    (SELECT COUNT(*) FROM sequence_subscribers ss WHERE ss.status = 'active' AND ss.step >
+        (SELECT COUNT(*) FROM sequence_steps WHERE sequence_id = ss.sequence_id)
+    ) AS sequence_steps,
+    (SELECT COUNT(*) FROM subscriber_lists sl JOIN subscribers s ON (s.id = sl.subscriber_id)
+        WHERE s.status = 'blocklisted' AND sl.status != 'unsubscribed'
+    ) AS blocklisted_subscriptions;
+
+-- name: fix-integrity
+-- Fixes the inconsistencies reported by get-integrity-report and returns the
+-- number of records fixed.
+-- Campaigns whose sent count is behind their send log are updated once, by sends.
+WITH behind AS (
+    SELECT c.id, l.sent FROM campaigns c
+    JOIN (SELECT campaign_id, COUNT(*) AS sent FROM campaign_sends
+        WHERE status IN ('sent', 'bounced') GROUP BY campaign_id) l ON (l.campaign_id = c.id)
+    WHERE c.sent < l.sent
+),
+sends AS (
+    UPDATE campaigns c SET sent = behind.sent, to_send = GREATEST(c.to_send, behind.sent), updated_at = NOW()
+    FROM behind WHERE behind.id = c.id
+    RETURNING c.id
+),
+counts AS (
+    UPDATE campaigns SET sent = GREATEST(sent, 0), to_send = GREATEST(to_send, sent, 0), updated_at = NOW()
+    WHERE (sent < 0 OR to_send < 0 OR sent > to_send) AND id NOT IN (SELECT id FROM behind)
+    RETURNING id
+),
+steps AS (
+    UPDATE sequence_subscribers ss SET step = n.steps, status = 'finished', updated_at = NOW()
+    FROM (SELECT sequences.id, COUNT(sequence_steps.id) AS steps FROM sequences
+        LEFT JOIN sequence_steps ON (sequence_steps.sequence_id = sequences.id) GROUP BY sequences.id) n
+    WHERE n.id = ss.sequence_id AND ss.status = 'active' AND ss.step > n.steps
+    RETURNING 1
+),
+blocklisted AS (
+    UPDATE subscriber_lists sl SET status = 'unsubscribed', snoozed_until = NULL, snooze_status = NULL, updated_at = NOW()
+    FROM subscribers s WHERE s.id = sl.subscriber_id AND s.status = 'blocklisted' AND sl.status != 'unsubscribed'
+    RETURNING 1
+)
+SELECT (SELECT COUNT(*) FROM counts) AS campaign_counts,
+    (SELECT COUNT(*) FROM sends) AS campaign_sends,
+    (SELECT COUNT(*) FROM steps) AS sequence_steps,
+    (SELECT COUNT(*) FROM blocklisted) AS blocklisted_subscriptions;
+
+-- name: register-survey-response
+-- Records the answer ($4) and score ($5) of a subscriber (UUID $2) to a survey question (key $3)
+-- in a campaign (UUID $1). A subscriber's repeat answer replaces the previous one. Anonymous
+-- answers ($2 = '') are always recorded.
+INSERT INTO survey_responses (campaign_id, subscriber_id, key, answer, score)
+    SELECT c.id, (SELECT id FROM subscribers WHERE
+        (CASE WHEN $2::TEXT != '' THEN subscribers.uuid = $2::UUID ELSE FALSE END)
+    ), $3, $4, $5 FROM campaigns c WHERE c.uuid = $1::UUID
+    ON CONFLICT (campaign_id, subscriber_id, key) DO UPDATE SET answer = $4, score = $5, updated_at = NOW();
+
+-- name: get-campaign-surveys
+-- Returns the results of the survey questions in a campaign ($1). avg_score and nps
+-- (% of promoters scoring 9-10 minus % of detractors scoring 1-6) are for rating questions.
+WITH res AS (
+    SELECT * FROM survey_responses WHERE campaign_id = $1
+),
+answers AS (
+    SELECT key, JSON_AGG(JSON_BUILD_OBJECT('answer', answer, 'count', num) ORDER BY num DESC, answer) AS answers
+    FROM (SELECT key, answer, COUNT(*) AS num FROM res GROUP BY key, answer) a
+    GROUP BY key
+),
+stats AS (
+    SELECT key, COUNT(*) AS responses, ROUND(AVG(score), 2) AS avg_score,
+        (CASE WHEN COUNT(score) > 0 THEN
+            ROUND(100.0 * (COUNT(*) FILTER (WHERE score >= 9) - COUNT(*) FILTER (WHERE score <= 6)) / COUNT(score), 1)
+        END) AS nps
+    FROM res GROUP BY key
+)
+SELECT s.key, s.responses, s.avg_score, s.nps, a.answers FROM stats s
+    INNER JOIN answers a ON (a.key = s.key)
+    ORDER BY s.key;
+
+-- name: create-link-slug
+-- Returns the short link slug of a link (UUID $1) in a campaign (UUID $2), creating one with
+-- the slug $3 if there isn't one. Nothing is returned if $3 is taken so that it can be
+-- retried with another slug.
+WITH link AS (
+    SELECT id FROM links WHERE uuid = $1::UUID
+),
+camp AS (
+    SELECT id FROM campaigns WHERE uuid = $2::UUID
+),
+existing AS (
+    SELECT slug FROM link_slugs WHERE link_id = (SELECT id FROM link) AND campaign_id = (SELECT id FROM camp)
+),
+ins AS (
+    INSERT INTO link_slugs (slug, link_id, campaign_id)
+        SELECT $3, link.id, camp.id FROM link, camp WHERE NOT EXISTS (SELECT 1 FROM existing)
+    ON CONFLICT DO NOTHING
+    RETURNING slug
+)
+SELECT slug FROM existing UNION ALL SELECT slug FROM ins;
+
+-- name: get-link-slug
+SELECT l.uuid AS link_uuid, c.uuid AS campaign_uuid FROM link_slugs s
+    INNER JOIN links l ON (l.id = s.link_id)
+    INNER JOIN campaigns c ON (c.id = s.campaign_id)
+    WHERE s.slug = $1;
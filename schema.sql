@@ -1,5 +1,15 @@
+-- Trigram indexes for typo tolerant subscriber search. Installing the extension may require
+-- a superuser (Postgres < 13), without which the search falls back to ILIKE.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+    RAISE WARNING 'could not install the pg_trgm extension: %. Subscriber search falls back to ILIKE.', SQLERRM;
+END $$;
+
 DROP TYPE IF EXISTS list_type CASCADE; CREATE TYPE list_type AS ENUM ('public', 'private', 'temporary');
 DROP TYPE IF EXISTS list_optin CASCADE; CREATE TYPE list_optin AS ENUM ('single', 'double');
+DROP TYPE IF EXISTS list_status CASCADE; CREATE TYPE list_status AS ENUM ('active', 'archived');
 DROP TYPE IF EXISTS subscriber_status CASCADE; CREATE TYPE subscriber_status AS ENUM ('enabled', 'disabled', 'blocklisted');
 DROP TYPE IF EXISTS subscription_status CASCADE; CREATE TYPE subscription_status AS ENUM ('unconfirmed', 'confirmed', 'unsubscribed');
 DROP TYPE IF EXISTS campaign_status CASCADE; CREATE TYPE campaign_status AS ENUM ('draft', 'running', 'scheduled', 'paused', 'cancelled', 'finished');
@@ -7,6 +17,19 @@ DROP TYPE IF EXISTS campaign_type CASCADE; CREATE TYPE campaign_type AS ENUM ('r
 DROP TYPE IF EXISTS content_type CASCADE; CREATE TYPE content_type AS ENUM ('richtext', 'html', 'plain', 'markdown');
 DROP TYPE IF EXISTS bounce_type CASCADE; CREATE TYPE bounce_type AS ENUM ('soft', 'hard', 'complaint');
 DROP TYPE IF EXISTS template_type CASCADE; CREATE TYPE template_type AS ENUM ('campaign', 'tx');
+DROP TYPE IF EXISTS sunset_status CASCADE; CREATE TYPE sunset_status AS ENUM ('enrolled', 'recovered', 'removed');
+DROP TYPE IF EXISTS hygiene_category CASCADE; CREATE TYPE hygiene_category AS ENUM ('invalid_syntax', 'role_account', 'dead_domain', 'soft_bouncer');
+DROP TYPE IF EXISTS list_address_filter CASCADE; CREATE TYPE list_address_filter AS ENUM ('none', 'flag', 'reject');
+DROP TYPE IF EXISTS goal_type CASCADE; CREATE TYPE goal_type AS ENUM ('link', 'pixel', 'conversion');
+DROP TYPE IF EXISTS repermission_status CASCADE; CREATE TYPE repermission_status AS ENUM ('running', 'finished', 'cancelled');
+DROP TYPE IF EXISTS notification_type CASCADE; CREATE TYPE notification_type AS ENUM ('campaign', 'import', 'bounce', 'messenger', 'mention');
+DROP TYPE IF EXISTS saved_view_collection CASCADE; CREATE TYPE saved_view_collection AS ENUM ('subscribers', 'campaigns', 'bounces');
+DROP TYPE IF EXISTS send_status CASCADE; CREATE TYPE send_status AS ENUM ('queued', 'sent', 'deferred', 'bounced');
+DROP TYPE IF EXISTS sequence_status CASCADE; CREATE TYPE sequence_status AS ENUM ('active', 'paused');
+DROP TYPE IF EXISTS sequence_subscriber_status CASCADE; CREATE TYPE sequence_subscriber_status AS ENUM ('active', 'finished', 'exited');
+DROP TYPE IF EXISTS campaign_action_condition CASCADE; CREATE TYPE campaign_action_condition AS ENUM ('opened', 'not_opened', 'clicked', 'not_clicked');
+DROP TYPE IF EXISTS campaign_action_type CASCADE; CREATE TYPE campaign_action_type AS ENUM ('add_tag', 'add_list', 'move_list');
+DROP TYPE IF EXISTS api_token_role CASCADE; CREATE TYPE api_token_role AS ENUM ('full', 'read_only');
 
 -- subscribers
 DROP TABLE IF EXISTS subscribers CASCADE;
@@ -25,6 +48,16 @@ DROP INDEX IF EXISTS idx_subs_email; CREATE UNIQUE INDEX idx_subs_email ON subsc
 DROP INDEX IF EXISTS idx_subs_status; CREATE INDEX idx_subs_status ON subscribers(status);
 DROP INDEX IF EXISTS idx_subs_created_at; CREATE INDEX idx_subs_created_at ON subscribers(created_at);
 DROP INDEX IF EXISTS idx_subs_updated_at; CREATE INDEX idx_subs_updated_at ON subscribers(updated_at);
+DROP INDEX IF EXISTS idx_subs_email_prefix; CREATE INDEX idx_subs_email_prefix ON subscribers(LOWER(email) text_pattern_ops);
+
+-- Full-text search over the name, e-mail, and attributes. Queries have to use the exact same expression to use the index.
+DROP INDEX IF EXISTS idx_subs_search;
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX idx_subs_search ON subscribers USING GIN (LOWER(name || ' ' || email || ' ' || attribs::TEXT) gin_trgm_ops);
+    END IF;
+END $$;
 
 -- lists
 DROP TABLE IF EXISTS lists CASCADE;
@@ -36,6 +69,31 @@ CREATE TABLE lists (
     optin           list_optin NOT NULL DEFAULT 'single',
     tags            VARCHAR(100)[],
     description     TEXT NOT NULL DEFAULT '',
+    content_qa_url  TEXT NOT NULL DEFAULT '',
+    address_filter  list_address_filter NOT NULL DEFAULT 'none',
+
+    -- Ordered subscriber attributes collected on the public subscription form and,
+    -- if editable, on the preferences page. [{key, label, type, options, required, editable}]
+    form_fields     JSONB NOT NULL DEFAULT '[]',
+
+    -- Premium lists: public subscriptions are checked against the entitlement hook
+    -- and are rejected once the list has max_subscribers (0 = no cap) subscribers.
+    entitlement_url TEXT NOT NULL DEFAULT '',
+    max_subscribers INTEGER NOT NULL DEFAULT 0,
+
+    -- Envelope sender (Return-Path) domain or address of campaigns sent to the list.
+    return_path     TEXT NOT NULL DEFAULT '',
+
+    -- Subscribing to a list implies its parent list. Unsubscribing from a list cascades
+    -- down to its child lists, and campaigns sent to a list include its child lists.
+    parent_id       INTEGER NULL REFERENCES lists(id) ON DELETE SET NULL ON UPDATE CASCADE,
+
+    -- Folder for organising lists, and the status. Archived lists are hidden from public forms and pages.
+    folder          TEXT NOT NULL DEFAULT '',
+    status          list_status NOT NULL DEFAULT 'active',
+
+    -- Lists in the trash have deleted_at set and are purged after the retention period.
+    deleted_at      TIMESTAMP WITH TIME ZONE NULL,
 
     created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
@@ -44,7 +102,10 @@ DROP INDEX IF EXISTS idx_lists_type; CREATE INDEX idx_lists_type ON lists(type);
 DROP INDEX IF EXISTS idx_lists_optin; CREATE INDEX idx_lists_optin ON lists(optin);
 DROP INDEX IF EXISTS idx_lists_name; CREATE INDEX idx_lists_name ON lists(name);
 DROP INDEX IF EXISTS idx_lists_created_at; CREATE INDEX idx_lists_created_at ON lists(created_at);
+DROP INDEX IF EXISTS idx_lists_deleted_at; CREATE INDEX idx_lists_deleted_at ON lists(deleted_at) WHERE deleted_at IS NOT NULL;
 DROP INDEX IF EXISTS idx_lists_updated_at; CREATE INDEX idx_lists_updated_at ON lists(updated_at);
+DROP INDEX IF EXISTS idx_lists_folder; CREATE INDEX idx_lists_folder ON lists(folder);
+DROP INDEX IF EXISTS idx_lists_parent_id; CREATE INDEX idx_lists_parent_id ON lists(parent_id) WHERE parent_id IS NOT NULL;
 
 
 DROP TABLE IF EXISTS subscriber_lists CASCADE;
@@ -54,6 +115,14 @@ CREATE TABLE subscriber_lists (
     meta               JSONB NOT NULL DEFAULT '{}',
     status             subscription_status NOT NULL DEFAULT 'unconfirmed',
 
+    -- Where the subscription came from, eg: admin, form, form:{tag}, source:{trusted source}, import:{run}.
+    source             TEXT NOT NULL DEFAULT '',
+
+    -- A snoozed subscription is unsubscribed until snoozed_until, after which
+    -- it's restored to snooze_status.
+    snoozed_until      TIMESTAMP WITH TIME ZONE NULL,
+    snooze_status      subscription_status NULL,
+
     created_at         TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at         TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
 
@@ -61,7 +130,20 @@ CREATE TABLE subscriber_lists (
 );
 DROP INDEX IF EXISTS idx_sub_lists_sub_id; CREATE INDEX idx_sub_lists_sub_id ON subscriber_lists(subscriber_id);
 DROP INDEX IF EXISTS idx_sub_lists_list_id; CREATE INDEX idx_sub_lists_list_id ON subscriber_lists(list_id);
+DROP INDEX IF EXISTS idx_sub_lists_growth; CREATE INDEX idx_sub_lists_growth ON subscriber_lists(list_id, created_at);
 DROP INDEX IF EXISTS idx_sub_lists_status; CREATE INDEX idx_sub_lists_status ON subscriber_lists(status);
+DROP INDEX IF EXISTS idx_sub_lists_snoozed; CREATE INDEX idx_sub_lists_snoozed ON subscriber_lists(snoozed_until) WHERE snoozed_until IS NOT NULL;
+
+-- header presets
+-- Reusable sets of custom e-mail headers that can be attached to campaigns and tx templates.
+DROP TABLE IF EXISTS header_presets CASCADE;
+CREATE TABLE header_presets (
+    id              SERIAL PRIMARY KEY,
+    name            TEXT NOT NULL UNIQUE,
+    headers         JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
 
 -- templates
 DROP TABLE IF EXISTS templates CASCADE;
@@ -72,6 +154,13 @@ CREATE TABLE templates (
     subject         TEXT NOT NULL,
     body            TEXT NOT NULL,
     is_default      BOOLEAN NOT NULL DEFAULT false,
+    deleted_at      TIMESTAMP WITH TIME ZONE NULL,
+
+    -- Header preset attached to messages sent with the template (only tx).
+    header_preset_id INTEGER NULL REFERENCES header_presets(id) ON DELETE SET NULL,
+
+    -- Incremented on every update for optimistic locking.
+    version         INT NOT NULL DEFAULT 1,
 
     created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
@@ -91,7 +180,11 @@ CREATE TABLE campaigns (
     altbody          TEXT NULL,
     content_type     content_type NOT NULL DEFAULT 'richtext',
     send_at          TIMESTAMP WITH TIME ZONE,
+
+    -- IANA time zone (eg: Europe/Berlin) the campaign was scheduled in and send_at is shown in.
+    send_at_timezone TEXT NOT NULL DEFAULT '',
     headers          JSONB NOT NULL DEFAULT '[]',
+    header_preset_id INTEGER NULL REFERENCES header_presets(id) ON DELETE SET NULL,
     status           campaign_status NOT NULL DEFAULT 'draft',
     tags             VARCHAR(100)[],
 
@@ -115,6 +208,81 @@ CREATE TABLE campaigns (
     archive_template_id INTEGER REFERENCES templates(id) ON DELETE SET DEFAULT DEFAULT 1,
     archive_meta        JSONB NOT NULL DEFAULT '{}',
 
+    -- Remote URL from which the body is fetched and frozen when the campaign starts,
+    -- and the SHA-256 checksum of the fetched content.
+    content_url         TEXT NOT NULL DEFAULT '',
+    content_checksum    TEXT NOT NULL DEFAULT '',
+    reply_to            TEXT NOT NULL DEFAULT '',
+    reply_tracking      BOOLEAN NOT NULL DEFAULT false,
+
+    -- Envelope sender (Return-Path) domain or address. If empty, the first
+    -- list of the campaign that has one is used.
+    return_path         TEXT NOT NULL DEFAULT '',
+
+    -- Campaigns in the trash have deleted_at set and are purged after the retention period.
+    deleted_at          TIMESTAMP WITH TIME ZONE NULL,
+
+    -- Incremented on every update for optimistic locking.
+    version             INT NOT NULL DEFAULT 1,
+
+    -- A/B test of the campaign's variants. The variants are sent to ab_test_percent
+    -- of the subscribers (testing), and ab_wait_hours after (waiting), the variant with
+    -- the best ab_metric (opens, clicks) rate is sent to the rest (winner).
+    ab_test_percent     INT NOT NULL DEFAULT 0,
+    ab_metric           TEXT NOT NULL DEFAULT 'opens',
+    ab_wait_hours       INT NOT NULL DEFAULT 4,
+    ab_phase            TEXT NOT NULL DEFAULT '',
+    ab_phase_at         TIMESTAMP WITH TIME ZONE NULL,
+
+    -- A scheduled campaign with depends_on starts only after that campaign has
+    -- finished, and depends_delay_mins after it finished (finished_at).
+    depends_on          INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL,
+    depends_delay_mins  INT NOT NULL DEFAULT 0,
+    finished_at         TIMESTAMP WITH TIME ZONE NULL,
+
+    -- A scheduled campaign with a recurrence (cron expression) isn't sent itself.
+    -- At every send_at, it's cloned into a run (recurrence_parent_id) that's sent
+    -- and send_at moves to the next time in the recurrence.
+    recurrence            TEXT NOT NULL DEFAULT '',
+    recurrence_parent_id  INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL,
+
+    -- RSS/Atom feed whose entries are fetched and frozen (feed_items) when the
+    -- campaign starts. Runs of recurring campaigns only get the entries published
+    -- after feed_since (the previous run).
+    feed_url            TEXT NOT NULL DEFAULT '',
+    feed_items          JSONB NULL,
+    feed_since          TIMESTAMP WITH TIME ZONE NULL,
+
+    -- Send-time optimization. Subscribers are queued (campaign_send_queue) to be sent
+    -- at their best hour (subscriber_send_hours) within sto_window_hours of the start.
+    -- sto_queued_at is set once all of the campaign's subscribers have been queued.
+    sto_window_hours    INT NOT NULL DEFAULT 0,
+    sto_queued_at       TIMESTAMP WITH TIME ZONE NULL,
+
+    -- Send at the local time of each subscriber. The wall-clock time of send_at in
+    -- send_at_timezone is sent at in the subscribers' timezone attribute. Subscribers
+    -- are queued like with send-time optimization and sto_queued_at is set the same way.
+    send_at_local       BOOLEAN NOT NULL DEFAULT false,
+
+    -- Per-campaign throttle (messages per minute, 0 for no limit) and the days and hours
+    -- ([{"days": [1, 2, 3, 4, 5], "start": "09:00", "end": "17:00"}]) in send_at_timezone
+    -- messages are sent in.
+    send_rate           INT NOT NULL DEFAULT 0,
+    send_windows        JSONB NOT NULL DEFAULT '[]',
+
+    -- A follow-up of a finished campaign (resend_of) that's only sent to the
+    -- subscribers the campaign was sent to who didn't open it.
+    resend_of           INTEGER NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Lists whose subscribers (and those of their child lists) are excluded from the
+    -- campaign's audience even if they're subscribed to the campaign's lists.
+    exclude_list_ids    INT[] NOT NULL DEFAULT '{}',
+
+    -- Appending UTM parameters to the campaign's links. NULL uses the global setting (utm.enabled).
+    -- utm_campaign is the utm_campaign value, which is the campaign's name if it's empty.
+    utm_enabled         BOOLEAN NULL,
+    utm_campaign        TEXT NOT NULL DEFAULT '',
+
     started_at       TIMESTAMP WITH TIME ZONE,
     created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
@@ -123,6 +291,8 @@ DROP INDEX IF EXISTS idx_camps_status; CREATE INDEX idx_camps_status ON campaign
 DROP INDEX IF EXISTS idx_camps_name; CREATE INDEX idx_camps_name ON campaigns(name);
 DROP INDEX IF EXISTS idx_camps_created_at; CREATE INDEX idx_camps_created_at ON campaigns(created_at);
 DROP INDEX IF EXISTS idx_camps_updated_at; CREATE INDEX idx_camps_updated_at ON campaigns(updated_at);
+DROP INDEX IF EXISTS idx_camps_deleted_at; CREATE INDEX idx_camps_deleted_at ON campaigns(deleted_at) WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_camps_recurrence_parent; CREATE INDEX idx_camps_recurrence_parent ON campaigns(recurrence_parent_id) WHERE recurrence_parent_id IS NOT NULL;
 
 
 DROP TABLE IF EXISTS campaign_lists CASCADE;
@@ -152,6 +322,394 @@ DROP INDEX IF EXISTS idx_views_camp_id; CREATE INDEX idx_views_camp_id ON campai
 DROP INDEX IF EXISTS idx_views_subscriber_id; CREATE INDEX idx_views_subscriber_id ON campaign_views(subscriber_id);
 DROP INDEX IF EXISTS idx_views_date; CREATE INDEX idx_views_date ON campaign_views((TIMEZONE('UTC', created_at)::DATE));
 
+-- The hour of the day (UTC) at which subscribers have opened the most campaigns,
+-- refreshed periodically from campaign_views for send-time optimization.
+DROP TABLE IF EXISTS subscriber_send_hours CASCADE;
+CREATE TABLE subscriber_send_hours (
+    subscriber_id    INTEGER NOT NULL PRIMARY KEY REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    hour             SMALLINT NOT NULL,
+    opens            INT NOT NULL DEFAULT 0,
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Subscribers of running campaigns with send-time optimization and the times at
+-- which they're to be sent.
+DROP TABLE IF EXISTS campaign_send_queue CASCADE;
+CREATE TABLE campaign_send_queue (
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    send_at          TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (campaign_id, subscriber_id)
+);
+DROP INDEX IF EXISTS idx_camp_send_queue; CREATE INDEX idx_camp_send_queue ON campaign_send_queue(campaign_id, send_at);
+
+DROP TABLE IF EXISTS campaign_replies CASCADE;
+CREATE TABLE campaign_replies (
+    id               BIGSERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Subscribers may be deleted, but the reply counts should remain.
+    subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    message_id       TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_replies_camp_id; CREATE INDEX idx_replies_camp_id ON campaign_replies(campaign_id);
+DROP INDEX IF EXISTS idx_replies_subscriber_id; CREATE INDEX idx_replies_subscriber_id ON campaign_replies(subscriber_id);
+DROP INDEX IF EXISTS idx_replies_msg_id; CREATE UNIQUE INDEX idx_replies_msg_id ON campaign_replies(campaign_id, message_id) WHERE message_id != '';
+
+-- registry of verified sending domains and their allowed from addresses
+DROP TABLE IF EXISTS sending_domains CASCADE;
+CREATE TABLE sending_domains (
+    id               SERIAL PRIMARY KEY,
+    domain           TEXT NOT NULL UNIQUE,
+
+    -- Allowed from addresses on the domain. Empty for any address.
+    addresses        TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Ownership is verified by a TXT record with the token on _listmonk.{domain}.
+    token            TEXT NOT NULL,
+    verified         BOOLEAN NOT NULL DEFAULT false,
+    verified_at      TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- automatic subscriber status transition rules
+DROP TABLE IF EXISTS status_rules CASCADE;
+CREATE TABLE status_rules (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT NOT NULL,
+    enabled          BOOLEAN NOT NULL DEFAULT false,
+
+    -- unconfirmed: subscriptions unconfirmed for more than threshold days.
+    -- bounces: threshold or more bounces (of bounce_type, if set) across campaigns within window_days (0 = all time).
+    condition        TEXT NOT NULL,
+    threshold        INT NOT NULL,
+    bounce_type      TEXT NOT NULL DEFAULT '',
+    window_days      INT NOT NULL DEFAULT 0,
+
+    -- unsubscribe or blocklist.
+    action           TEXT NOT NULL,
+
+    last_run_at      TIMESTAMP WITH TIME ZONE NULL,
+    last_affected    INT NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- quotas
+DROP TABLE IF EXISTS quotas CASCADE;
+CREATE TABLE quotas (
+    id                   SERIAL PRIMARY KEY,
+
+    -- Username the quota applies to. '' is the default quota of users without one.
+    username             TEXT NOT NULL UNIQUE,
+
+    -- Limits. 0 is unlimited.
+    campaigns_per_month  INT NOT NULL DEFAULT 0,
+    messages_per_day     INT NOT NULL DEFAULT 0,
+    max_subscribers      INT NOT NULL DEFAULT 0,
+    created_at           TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at           TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Campaigns started by users that count against their quotas.
+DROP TABLE IF EXISTS quota_usage CASCADE;
+CREATE TABLE quota_usage (
+    id               BIGSERIAL PRIMARY KEY,
+    username         TEXT NOT NULL,
+    campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    messages         INT NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_quota_usage; CREATE INDEX idx_quota_usage ON quota_usage(username, created_at);
+
+-- api tokens. Tokens authenticate as their username (BasicAuth username:token)
+-- and are limited to their scopes. Only the SHA-256 hash of a token is stored.
+-- read_only tokens can only be granted :read scopes.
+DROP TABLE IF EXISTS api_tokens CASCADE;
+CREATE TABLE api_tokens (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT NOT NULL,
+    username         TEXT NOT NULL,
+    token_hash       TEXT NOT NULL UNIQUE,
+    scopes           TEXT[] NOT NULL DEFAULT '{}',
+    role             api_token_role NOT NULL DEFAULT 'full',
+    expires_at       TIMESTAMP WITH TIME ZONE NULL,
+    last_used_at     TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_api_tokens_username; CREATE INDEX idx_api_tokens_username ON api_tokens(username);
+
+-- audit log of admin actions. before_data and after_data are JSON snapshots of the
+-- entity and changes are the top level keys that differ between them.
+DROP TABLE IF EXISTS audit_log CASCADE;
+CREATE TABLE audit_log (
+    id               BIGSERIAL PRIMARY KEY,
+    actor            TEXT NOT NULL DEFAULT '',
+    action           TEXT NOT NULL,
+    entity_type      TEXT NOT NULL DEFAULT '',
+    entity_id        INT NULL,
+    before_data      JSONB NULL,
+    after_data       JSONB NULL,
+    changes          TEXT[] NOT NULL DEFAULT '{}',
+    ip               TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_audit_log_created; CREATE INDEX idx_audit_log_created ON audit_log(created_at);
+DROP INDEX IF EXISTS idx_audit_log_entity; CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+
+-- campaign review comments. Replies have parent_id set to the thread's first comment.
+DROP TABLE IF EXISTS campaign_comments CASCADE;
+CREATE TABLE campaign_comments (
+    id               SERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    parent_id        INTEGER NULL REFERENCES campaign_comments(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    author           TEXT NOT NULL,
+    body             TEXT NOT NULL,
+
+    -- Usernames @mentioned in the body.
+    mentions         TEXT[] NOT NULL DEFAULT '{}',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_comments_camp_id; CREATE INDEX idx_comments_camp_id ON campaign_comments(campaign_id, created_at);
+
+-- ordered campaign goals (funnel steps)
+DROP TABLE IF EXISTS campaign_goals CASCADE;
+CREATE TABLE campaign_goals (
+    id               SERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    position         INTEGER NOT NULL DEFAULT 0,
+    name             TEXT NOT NULL,
+    type             goal_type NOT NULL,
+
+    -- The tracked link URL for link goals, and the goal key and the optional landing page
+    -- URL (to which the goal token is passed on) for pixel and conversion goals.
+    url              TEXT NOT NULL DEFAULT '',
+    key              TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_goals_camp_id; CREATE INDEX idx_goals_camp_id ON campaign_goals(campaign_id, position);
+
+DROP TABLE IF EXISTS campaign_goal_events CASCADE;
+CREATE TABLE campaign_goal_events (
+    id               BIGSERIAL PRIMARY KEY,
+    goal_id          INTEGER NOT NULL REFERENCES campaign_goals(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Subscribers may be deleted, but the goal counts should remain.
+    subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_goal_events_goal_id; CREATE INDEX idx_goal_events_goal_id ON campaign_goal_events(goal_id);
+DROP INDEX IF EXISTS idx_goal_events_subscriber_id; CREATE INDEX idx_goal_events_subscriber_id ON campaign_goal_events(subscriber_id);
+
+-- post-campaign actions that tag subscribers or add (move) them to a list based on
+-- their interaction with a campaign. They're run once, delay_hours after the campaign finishes.
+DROP TABLE IF EXISTS campaign_actions CASCADE;
+CREATE TABLE campaign_actions (
+    id               SERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    condition        campaign_action_condition NOT NULL,
+
+    -- The link URL for click conditions. Empty for any link in the campaign.
+    url              TEXT NOT NULL DEFAULT '',
+    action           campaign_action_type NOT NULL,
+
+    -- The tag added to the subscribers' tags attribute or the list they're added (moved) to.
+    tag              TEXT NOT NULL DEFAULT '',
+    list_id          INTEGER NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    delay_hours      INTEGER NOT NULL DEFAULT 0,
+    affected         INTEGER NOT NULL DEFAULT 0,
+    ran_at           TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_camp_actions_camp_id; CREATE INDEX idx_camp_actions_camp_id ON campaign_actions(campaign_id);
+
+-- subscribers' answers to the survey questions (rating, choice) in campaigns.
+DROP TABLE IF EXISTS survey_responses CASCADE;
+CREATE TABLE survey_responses (
+    id               BIGSERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    -- Subscribers may be deleted, but the survey results should remain.
+    subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    key              TEXT NOT NULL,
+    answer           TEXT NOT NULL,
+
+    -- The 1-10 score of rating questions.
+    score            INTEGER NULL CHECK (score BETWEEN 1 AND 10),
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(campaign_id, subscriber_id, key)
+);
+DROP INDEX IF EXISTS idx_survey_responses_sub_id; CREATE INDEX idx_survey_responses_sub_id ON survey_responses(subscriber_id);
+
+-- A/B test variants of campaigns. An empty body is the campaign's body.
+DROP TABLE IF EXISTS campaign_variants CASCADE;
+CREATE TABLE campaign_variants (
+    id               SERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    name             TEXT NOT NULL,
+    subject          TEXT NOT NULL,
+    body             TEXT NOT NULL DEFAULT '',
+    is_winner        BOOLEAN NOT NULL DEFAULT false,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_camp_variants_camp_id; CREATE INDEX idx_camp_variants_camp_id ON campaign_variants(campaign_id);
+
+-- campaign e-mail client preview screenshots
+DROP TABLE IF EXISTS campaign_previews CASCADE;
+CREATE TABLE campaign_previews (
+    id               SERIAL PRIMARY KEY,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    client           TEXT NOT NULL,
+    content_type     TEXT NOT NULL DEFAULT 'image/png',
+    image            BYTEA NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(campaign_id, client)
+);
+
+-- per-recipient send log of campaigns
+DROP TABLE IF EXISTS campaign_sends CASCADE;
+CREATE TABLE campaign_sends (
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    status           send_status NOT NULL DEFAULT 'queued',
+
+    -- Response (or error) from the messenger / relay on the last delivery attempt.
+    response         TEXT NOT NULL DEFAULT '',
+
+    queued_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at          TIMESTAMP WITH TIME ZONE NULL,
+    deferred_at      TIMESTAMP WITH TIME ZONE NULL,
+    bounced_at       TIMESTAMP WITH TIME ZONE NULL,
+    opened_at        TIMESTAMP WITH TIME ZONE NULL,
+    clicked_at       TIMESTAMP WITH TIME ZONE NULL,
+
+    PRIMARY KEY (campaign_id, subscriber_id)
+);
+DROP INDEX IF EXISTS idx_camp_sends_sub_id; CREATE INDEX idx_camp_sends_sub_id ON campaign_sends(subscriber_id);
+DROP INDEX IF EXISTS idx_camp_sends_status; CREATE INDEX idx_camp_sends_status ON campaign_sends(campaign_id, status);
+DROP INDEX IF EXISTS idx_camp_sends_queued_at; CREATE INDEX idx_camp_sends_queued_at ON campaign_sends(queued_at);
+
+-- outbound messages stored by the capture messenger instead of being delivered
+DROP TABLE IF EXISTS captured_messages CASCADE;
+CREATE TABLE captured_messages (
+    id               SERIAL PRIMARY KEY,
+    messenger        TEXT NOT NULL,
+    campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    subscriber_id    INTEGER NULL REFERENCES subscribers(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    from_email       TEXT NOT NULL,
+    to_emails        TEXT[] NOT NULL,
+    subject          TEXT NOT NULL,
+    content_type     TEXT NOT NULL DEFAULT '',
+    body             TEXT NOT NULL,
+    alt_body         TEXT NOT NULL DEFAULT '',
+    headers          JSONB NOT NULL DEFAULT '{}',
+    raw              TEXT NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_captured_messages_camp_id; CREATE INDEX idx_captured_messages_camp_id ON captured_messages(campaign_id);
+
+-- archive of the campaign messages sent to subscribers (sent_archive.mode) for answering
+-- what exactly a subscriber received. Rows are deleted after sent_archive.retention_days.
+DROP TABLE IF EXISTS sent_messages CASCADE;
+CREATE TABLE sent_messages (
+    id                  BIGSERIAL PRIMARY KEY,
+    campaign_id         INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    campaign_name       TEXT NOT NULL DEFAULT '',
+    subscriber_id       INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    email               TEXT NOT NULL,
+    subject             TEXT NOT NULL,
+    content_type        TEXT NOT NULL DEFAULT '',
+
+    -- The rendered body, its SHA-256 hash, and the campaign (version) and template (updated_at)
+    -- it was rendered with.
+    body                TEXT NOT NULL DEFAULT '',
+    alt_body            TEXT NOT NULL DEFAULT '',
+    body_hash           TEXT NOT NULL,
+    campaign_version    INT NOT NULL DEFAULT 0,
+    template_id         INTEGER NULL,
+    template_updated_at TIMESTAMP WITH TIME ZONE NULL,
+    sent_at             TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_sent_messages_sub_id; CREATE INDEX idx_sent_messages_sub_id ON sent_messages(subscriber_id);
+DROP INDEX IF EXISTS idx_sent_messages_camp_id; CREATE INDEX idx_sent_messages_camp_id ON sent_messages(campaign_id);
+DROP INDEX IF EXISTS idx_sent_messages_sent_at; CREATE INDEX idx_sent_messages_sent_at ON sent_messages(sent_at);
+
+-- latest DNS deliverability check results of sending domains
+DROP TABLE IF EXISTS dns_checks CASCADE;
+CREATE TABLE dns_checks (
+    id               SERIAL PRIMARY KEY,
+    domain           TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    results          JSONB NOT NULL DEFAULT '[]',
+    checked_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- SMTP TLS reports (TLS-RPT) for the sending domains, one row per report and policy domain
+DROP TABLE IF EXISTS tls_reports CASCADE;
+CREATE TABLE tls_reports (
+    id               SERIAL PRIMARY KEY,
+    report_id        TEXT NOT NULL,
+    organization     TEXT NOT NULL DEFAULT '',
+    policy_domain    TEXT NOT NULL,
+    policy_type      TEXT NOT NULL DEFAULT '',
+    start_date       TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_date         TIMESTAMP WITH TIME ZONE NOT NULL,
+    success_count    INT NOT NULL DEFAULT 0,
+    failure_count    INT NOT NULL DEFAULT 0,
+    failures         JSONB NOT NULL DEFAULT '[]',
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    UNIQUE(report_id, policy_domain)
+);
+CREATE INDEX idx_tls_reports_end_date ON tls_reports(end_date);
+
+-- daily sender reputation metrics pulled from Google Postmaster Tools and Microsoft SNDS
+DROP TABLE IF EXISTS reputation_metrics CASCADE;
+CREATE TABLE reputation_metrics (
+    id               SERIAL PRIMARY KEY,
+    provider         TEXT NOT NULL,
+    source           TEXT NOT NULL,
+    date             DATE NOT NULL,
+    reputation       TEXT NOT NULL DEFAULT '',
+    spam_rate        FLOAT NOT NULL DEFAULT 0,
+    delivery_errors  JSONB NOT NULL DEFAULT '[]',
+    meta             JSONB NOT NULL DEFAULT '{}',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(provider, source, date)
+);
+DROP INDEX IF EXISTS idx_reputation_date; CREATE INDEX idx_reputation_date ON reputation_metrics(date);
+
+-- subscriber self-service data exports that are downloaded via signed, expiring links
+DROP TABLE IF EXISTS subscriber_exports CASCADE;
+CREATE TABLE subscriber_exports (
+    id               SERIAL PRIMARY KEY,
+    uuid             uuid NOT NULL UNIQUE,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    data             BYTEA NOT NULL,
+    expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_sub_exports_expires_at; CREATE INDEX idx_sub_exports_expires_at ON subscriber_exports(expires_at);
+
+-- pending subscriber e-mail changes that take effect when confirmed from the new address
+DROP TABLE IF EXISTS subscriber_email_changes CASCADE;
+CREATE TABLE subscriber_email_changes (
+    id               SERIAL PRIMARY KEY,
+    uuid             uuid NOT NULL UNIQUE,
+    subscriber_id    INTEGER NOT NULL UNIQUE REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    email            TEXT NOT NULL,
+    expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- media
 DROP TABLE IF EXISTS media CASCADE;
 CREATE TABLE media (
@@ -165,6 +723,20 @@ CREATE TABLE media (
     created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- media_quarantine
+-- Uploaded media flagged by the scanner. They're held here, out of the (public)
+-- media store, until they're released or deleted.
+DROP TABLE IF EXISTS media_quarantine CASCADE;
+CREATE TABLE media_quarantine (
+    id               SERIAL PRIMARY KEY,
+    filename         TEXT NOT NULL,
+    content_type     TEXT NOT NULL DEFAULT 'application/octet-stream',
+    signature        TEXT NOT NULL DEFAULT '',
+    size             INT NOT NULL DEFAULT 0,
+    body             BYTEA NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- campaign_media
 DROP TABLE IF EXISTS campaign_media CASCADE;
 CREATE TABLE campaign_media (
@@ -204,6 +776,17 @@ DROP INDEX IF EXISTS idx_clicks_link_id; CREATE INDEX idx_clicks_link_id ON link
 DROP INDEX IF EXISTS idx_clicks_sub_id; CREATE INDEX idx_clicks_sub_id ON link_clicks(subscriber_id);
 DROP INDEX IF EXISTS idx_clicks_date; CREATE INDEX idx_clicks_date ON link_clicks((TIMEZONE('UTC', created_at)::DATE));
 
+-- short slugs of the tracked links in campaigns, eg: /l/{slug}/{subscriber}
+DROP TABLE IF EXISTS link_slugs CASCADE;
+CREATE TABLE link_slugs (
+    slug             TEXT NOT NULL PRIMARY KEY,
+    link_id          INTEGER NOT NULL REFERENCES links(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE(link_id, campaign_id)
+);
+
 -- settings
 DROP TABLE IF EXISTS settings CASCADE;
 CREATE TABLE settings (
@@ -227,25 +810,61 @@ INSERT INTO settings (key, value) VALUES
     ('app.message_sliding_window_rate', '10000'),
     ('app.cache_slow_queries', 'false'),
     ('app.cache_slow_queries_interval', '"0 3 * * *"'),
+    ('app.reporting_timezone', '"UTC"'),
     ('app.enable_public_archive', 'true'),
     ('app.enable_public_subscription_page', 'true'),
     ('app.enable_public_archive_rss_content', 'true'),
     ('app.send_optin_confirmation', 'true'),
     ('app.check_updates', 'true'),
+    ('app.capture_mode', 'false'),
+    ('app.short_links', 'false'),
     ('app.notify_emails', '["admin1@mysite.com", "admin2@mysite.com"]'),
     ('app.lang', '"en"'),
     ('privacy.individual_tracking', 'false'),
+    ('privacy.anonymous_links', 'false'),
     ('privacy.unsubscribe_header', 'true'),
     ('privacy.allow_blocklist', 'true'),
     ('privacy.allow_export', 'true'),
     ('privacy.allow_wipe', 'true'),
     ('privacy.allow_preferences', 'true'),
     ('privacy.exportable', '["profile", "subscriptions", "campaign_views", "link_clicks"]'),
+    ('privacy.export_link_expiry', '"48h"'),
+    ('privacy.confirm_email_change', 'false'),
     ('privacy.domain_blocklist', '[]'),
+    ('privacy.role_accounts', '["abuse","admin","administrator","billing","compliance","contact","devnull","dns","ftp","help","hostmaster","info","inoc","ispfeedback","ispsupport","list","list-request","mail","mailer-daemon","marketing","media","news","no-reply","noc","noreply","null","office","phish","phishing","postmaster","privacy","registrar","root","sales","security","spam","support","sysadmin","tech","undisclosed-recipients","unsubscribe","usenet","uucp","webmaster","www"]'),
+    ('privacy.spamtrap_patterns', '["(^|[._+-])spam-?trap", "(^|[._+-])honey-?pot", "@(.+\\.)?example\\.(com|net|org)$", "\\.(test|invalid|example|localhost)$"]'),
     ('privacy.record_optin_ip', 'false'),
+    ('privacy.allow_snooze', 'false'),
+    ('privacy.trusted_sources', '[]'),
+    ('stripe.enabled', 'false'),
+    ('stripe.webhook_secret', '""'),
+    ('stripe.products', '[]'),
+    ('ga4.enabled', 'false'),
+    ('ga4.measurement_id', '""'),
+    ('ga4.api_secret', '""'),
+    ('ga4.source', '"listmonk"'),
+    ('ga4.medium', '"email"'),
+    ('utm.enabled', 'false'),
+    ('utm.source', '"listmonk"'),
+    ('utm.medium', '"email"'),
+    ('sent_archive.mode', '"off"'),
+    ('sent_archive.retention_days', '365'),
+    ('send_log.retention_days', '365'),
+    ('privacy.optin_reply_address', '""'),
     ('security.enable_captcha', 'false'),
     ('security.captcha_key', '""'),
     ('security.captcha_secret', '""'),
+    ('security.signing_key', '""'),
+    ('security.login_max_attempts', '10'),
+    ('security.login_lockout', '"1m"'),
+    ('security.ip_allowlist', '[]'),
+    ('security.ip_denylist', '[]'),
+    ('security.api_ip_allowlist', '[]'),
+    ('security.api_rate_limit', '0'),
+    ('security.api_rate_burst', '0'),
+    ('security.api_rate_limit_read_only', '0'),
+    ('security.api_rate_burst_read_only', '0'),
+    ('security.api_rate_limits', '[]'),
     ('upload.provider', '"filesystem"'),
     ('upload.max_file_size', '5000'),
     ('upload.extensions', '["jpg","jpeg","png","gif","svg","*"]'),
@@ -277,7 +896,76 @@ INSERT INTO settings (key, value) VALUES
     ('appearance.admin.custom_css', '""'),
     ('appearance.admin.custom_js', '""'),
     ('appearance.public.custom_css', '""'),
-    ('appearance.public.custom_js', '""');
+    ('appearance.public.custom_js', '""'),
+    ('appearance.branding.product_name', '""'),
+    ('appearance.branding.logo_url', '""'),
+    ('appearance.branding.color', '""'),
+    ('appearance.branding.footer', '""'),
+    ('sunset.enabled', 'false'),
+    ('sunset.inactive_days', '180'),
+    ('sunset.grace_days', '30'),
+    ('sunset.list_id', '0'),
+    ('sunset.action', '"unsubscribe"'),
+    ('sunset.interval', '"0 4 * * *"'),
+    ('content_qa.enabled', 'false'),
+    ('content_qa.url', '""'),
+    ('content_qa.timeout', '"5s"'),
+    ('entitlement.enabled', 'false'),
+    ('entitlement.interval', '"0 */6 * * *"'),
+    ('entitlement.timeout', '"5s"'),
+    ('hygiene.enabled', 'false'),
+    ('hygiene.interval', '"0 3 * * 0"'),
+    ('hygiene.check_domains', 'true'),
+    ('hygiene.soft_bounce_count', '3'),
+    ('hygiene.soft_bounce_days', '30'),
+    ('maintenance.enabled', 'false'),
+    ('maintenance.interval', '"0 5 * * *"'),
+    ('maintenance.media_days', '0'),
+    ('maintenance.fix_integrity', 'false'),
+    ('previews.enabled', 'false'),
+    ('previews.url', '""'),
+    ('previews.api_key', '""'),
+    ('previews.clients', '["gmail-web", "outlook-desktop", "apple-mail", "iphone-mail"]'),
+    ('previews.timeout', '"60s"'),
+    ('spam_check.enabled', 'false'),
+    ('spam_check.type', '"rspamd"'),
+    ('spam_check.url', '"http://localhost:11333"'),
+    ('spam_check.password', '""'),
+    ('spam_check.threshold', '5'),
+    ('spam_check.timeout', '"10s"'),
+    ('deliverability.domains', '[]'),
+    ('deliverability.tracking_cname', '""'),
+    ('deliverability.recheck', 'false'),
+    ('deliverability.recheck_interval', '"0 */6 * * *"'),
+    ('reputation.enabled', 'false'),
+    ('reputation.interval', '"0 6 * * *"'),
+    ('reputation.postmaster_enabled', 'false'),
+    ('reputation.postmaster_client_id', '""'),
+    ('reputation.postmaster_client_secret', '""'),
+    ('reputation.postmaster_refresh_token', '""'),
+    ('reputation.postmaster_domains', '[]'),
+    ('reputation.snds_enabled', 'false'),
+    ('reputation.snds_key', '""'),
+    ('reputation.alert_reputation', '"medium"'),
+    ('reputation.alert_spam_rate', '0.003'),
+    ('attachments.clamav_enabled', 'false'),
+    ('attachments.clamav_address', '"/var/run/clamav/clamd.ctl"'),
+    ('attachments.clamav_timeout', '"30s"'),
+    ('attachments.scanner', '"clamav"'),
+    ('attachments.scanner_url', '""'),
+    ('attachments.scanner_token', '""'),
+    ('attachments.max_message_size', '0'),
+    ('attachments.size_action', '"warn"'),
+    ('attachments.max_body_size', '0'),
+    ('attachments.max_image_weight', '0'),
+    ('attachments.max_size', '10240'),
+    ('attachments.minify_html', 'false'),
+    ('app.trash_retention_days', '30'),
+    ('notifications.slack_enabled', 'false'),
+    ('notifications.slack_webhook_url', '""'),
+    ('notifications.slack_events', '["campaign", "import", "bounce", "messenger"]'),
+    ('notifications.bounce_threshold', '0'),
+    ('app.sending_paused', 'false');
 
 -- bounces
 DROP TABLE IF EXISTS bounces CASCADE;
@@ -295,6 +983,212 @@ DROP INDEX IF EXISTS idx_bounces_camp_id; CREATE INDEX idx_bounces_camp_id ON bo
 DROP INDEX IF EXISTS idx_bounces_source; CREATE INDEX idx_bounces_source ON bounces(source);
 DROP INDEX IF EXISTS idx_bounces_date; CREATE INDEX idx_bounces_date ON bounces((TIMEZONE('UTC', created_at)::DATE));
 
+-- sunset (win-back) enrolments of inactive subscribers
+DROP TABLE IF EXISTS subscriber_sunset CASCADE;
+CREATE TABLE subscriber_sunset (
+    id               BIGSERIAL PRIMARY KEY,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    status           sunset_status NOT NULL DEFAULT 'enrolled',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- A subscriber can only have one open enrolment at a time.
+DROP INDEX IF EXISTS idx_sunset_sub_enrolled; CREATE UNIQUE INDEX idx_sunset_sub_enrolled ON subscriber_sunset(subscriber_id) WHERE status = 'enrolled';
+DROP INDEX IF EXISTS idx_sunset_status; CREATE INDEX idx_sunset_status ON subscriber_sunset(status);
+
+-- list hygiene report entries
+DROP TABLE IF EXISTS list_hygiene CASCADE;
+CREATE TABLE list_hygiene (
+    list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    category         hygiene_category NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_list_hygiene_list; CREATE INDEX idx_list_hygiene_list ON list_hygiene(list_id, category);
+DROP INDEX IF EXISTS idx_list_hygiene_sub; CREATE INDEX idx_list_hygiene_sub ON list_hygiene(subscriber_id);
+
+-- re-permission runs that convert single opt-in lists to double opt-in
+DROP TABLE IF EXISTS list_repermissions CASCADE;
+CREATE TABLE list_repermissions (
+    id               SERIAL PRIMARY KEY,
+    list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    campaign_id      INTEGER NULL REFERENCES campaigns(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    status           repermission_status NOT NULL DEFAULT 'running',
+    deadline         TIMESTAMP WITH TIME ZONE NOT NULL,
+    total            INTEGER NOT NULL DEFAULT 0,
+    unsubscribed     INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- A list can only have one running re-permission at a time.
+DROP INDEX IF EXISTS idx_repermissions_list_running; CREATE UNIQUE INDEX idx_repermissions_list_running ON list_repermissions(list_id) WHERE status = 'running';
+
+-- blackout date ranges of lists during which campaigns scheduled to them are held
+DROP TABLE IF EXISTS list_blackouts CASCADE;
+CREATE TABLE list_blackouts (
+    id               SERIAL PRIMARY KEY,
+    list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    name             TEXT NOT NULL DEFAULT '',
+    starts_at        TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at          TIMESTAMP WITH TIME ZONE NOT NULL CHECK (ends_at > starts_at),
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_list_blackouts_list; CREATE INDEX idx_list_blackouts_list ON list_blackouts(list_id, ends_at);
+
+-- drip (automation) sequences: ordered campaigns sent to subscribers of a list after they subscribe
+DROP TABLE IF EXISTS sequences CASCADE;
+CREATE TABLE sequences (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT NOT NULL,
+    list_id          INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    status           sequence_status NOT NULL DEFAULT 'active',
+
+    -- Subscribers who click a link in any of the sequence's campaigns exit it.
+    -- Subscribers who unsubscribe from the list or are blocklisted always exit.
+    exit_on_click    BOOLEAN NOT NULL DEFAULT false,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Campaigns of a sequence whose content is sent delay_mins after the previous step
+-- (or for the first step, after subscribing).
+DROP TABLE IF EXISTS sequence_steps CASCADE;
+CREATE TABLE sequence_steps (
+    id               SERIAL PRIMARY KEY,
+    sequence_id      INTEGER NOT NULL REFERENCES sequences(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    campaign_id      INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    position         INTEGER NOT NULL DEFAULT 0,
+    delay_mins       INTEGER NOT NULL DEFAULT 0
+);
+DROP INDEX IF EXISTS idx_sequence_steps_seq; CREATE INDEX idx_sequence_steps_seq ON sequence_steps(sequence_id, position);
+
+-- Progress of subscribers through sequences. step is the number of steps sent and
+-- last_at is when the last step was sent (or when the subscriber entered) and
+-- retry_at, when a step that failed to be queued is retried.
+DROP TABLE IF EXISTS sequence_subscribers CASCADE;
+CREATE TABLE sequence_subscribers (
+    sequence_id      INTEGER NOT NULL REFERENCES sequences(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    subscriber_id    INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    status           sequence_subscriber_status NOT NULL DEFAULT 'active',
+    step             INTEGER NOT NULL DEFAULT 0,
+    last_at          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    retry_at         TIMESTAMP WITH TIME ZONE NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (sequence_id, subscriber_id)
+);
+DROP INDEX IF EXISTS idx_sequence_subs_active; CREATE INDEX idx_sequence_subs_active ON sequence_subscribers(sequence_id, last_at) WHERE status = 'active';
+
+-- Stripe customers and the products of their subscriptions, synced from Stripe webhooks
+DROP TABLE IF EXISTS stripe_customers CASCADE;
+CREATE TABLE stripe_customers (
+    id               TEXT NOT NULL PRIMARY KEY,
+    email            TEXT NOT NULL DEFAULT '',
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+DROP TABLE IF EXISTS stripe_subscriptions CASCADE;
+CREATE TABLE stripe_subscriptions (
+    id               TEXT NOT NULL,
+    product_id       TEXT NOT NULL,
+    customer_id      TEXT NOT NULL,
+    status           TEXT NOT NULL,
+
+    -- Status that was last applied to the product's lists.
+    synced_status    TEXT NOT NULL DEFAULT '',
+    latest_invoice   TEXT NOT NULL DEFAULT '',
+
+    -- Timestamp of the Stripe event the record was last updated by, for discarding out of order events.
+    event_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (id, product_id)
+);
+DROP INDEX IF EXISTS idx_stripe_subs_customer; CREATE INDEX idx_stripe_subs_customer ON stripe_subscriptions(customer_id);
+DROP INDEX IF EXISTS idx_stripe_subs_invoice; CREATE INDEX idx_stripe_subs_invoice ON stripe_subscriptions(latest_invoice);
+
+-- named filter/sort configurations of admin collections
+DROP TABLE IF EXISTS saved_views CASCADE;
+CREATE TABLE saved_views (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT NOT NULL,
+    collection       saved_view_collection NOT NULL,
+
+    -- Filter and sort params of the collection, eg: {"query": "..", "order_by": "id"}
+    params           JSONB NOT NULL DEFAULT '{}',
+
+    -- Username of the creator. Views that are not shared are only visible to them.
+    created_by       TEXT NOT NULL DEFAULT '',
+    shared           BOOLEAN NOT NULL DEFAULT false,
+
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_saved_views_name; CREATE UNIQUE INDEX idx_saved_views_name ON saved_views(collection, created_by, LOWER(name));
+
+-- subscribers table columns chosen by each user in the admin
+DROP TABLE IF EXISTS subscriber_columns CASCADE;
+CREATE TABLE subscriber_columns (
+    -- BasicAuth username of the user.
+    username         TEXT NOT NULL PRIMARY KEY,
+
+    -- Field names and attribute paths, eg: {email, status, attribs.city}
+    columns          TEXT[] NOT NULL DEFAULT '{}',
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- preferences of each user in the admin
+DROP TABLE IF EXISTS user_preferences CASCADE;
+CREATE TABLE user_preferences (
+    -- BasicAuth username of the user.
+    username         TEXT NOT NULL PRIMARY KEY,
+
+    -- IANA time zone dates are displayed in. Empty for the browser's time zone.
+    timezone         TEXT NOT NULL DEFAULT '',
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- in-app notification feed of admin alerts
+DROP TABLE IF EXISTS notifications CASCADE;
+CREATE TABLE notifications (
+    id               SERIAL PRIMARY KEY,
+    type             notification_type NOT NULL,
+    title            TEXT NOT NULL,
+    body             TEXT NOT NULL DEFAULT '',
+    data             JSONB NOT NULL DEFAULT '{}',
+
+    -- User to whom the notification is addressed, eg: @mentions. NULL for all users.
+    username         TEXT NULL,
+
+    -- Usernames of the users who have read the notification.
+    read_by          TEXT[] NOT NULL DEFAULT '{}',
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+DROP INDEX IF EXISTS idx_notifications_created_at; CREATE INDEX idx_notifications_created_at ON notifications(created_at);
+
+-- per-user notification preferences
+DROP TABLE IF EXISTS notification_prefs CASCADE;
+CREATE TABLE notification_prefs (
+    username         TEXT NOT NULL PRIMARY KEY,
+
+    -- Address to which the notifications the user has opted to receive by e-mail are sent.
+    email            TEXT NOT NULL DEFAULT '',
+
+    -- Channels (feed, email) of every notification type, eg: {"campaign": ["feed", "email"]}
+    events           JSONB NOT NULL DEFAULT '{}',
+    updated_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- arbitrary (transactional) messages held while sending is paused
+DROP TABLE IF EXISTS held_messages CASCADE;
+CREATE TABLE held_messages (
+    id               BIGSERIAL PRIMARY KEY,
+    message          JSONB NOT NULL,
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 
 
 -- materialized views